@@ -0,0 +1,149 @@
+// Package affected works out which tests a change can break, so the verify
+// command can run those instead of the whole suite.
+package affected
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Placeholder is replaced with the selected test targets in the verify
+// command, e.g. "go test {tests}".
+const Placeholder = "{tests}"
+
+// Expand substitutes the test targets into a verify command.
+func Expand(command string, targets []string) string {
+	return strings.ReplaceAll(command, Placeholder, strings.Join(targets, " "))
+}
+
+// GoPackage is the part of `go list -json` output the analysis needs.
+type GoPackage struct {
+	ImportPath   string
+	Dir          string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+}
+
+// ListGo lists the packages of the Go module in dir. Canceling ctx kills
+// go list.
+func ListGo(ctx context.Context, dir string) ([]GoPackage, error) {
+	cmd := exec.CommandContext(ctx, "go", "list", "-e", "-json", "./...")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Go packages: %w\n%s", err, stderr.String())
+	}
+	return parseGoList(output)
+}
+
+// parseGoList decodes the stream of JSON objects `go list -json` prints.
+func parseGoList(output []byte) ([]GoPackage, error) {
+	var pkgs []GoPackage
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var p GoPackage
+		if err := dec.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse go list output: %w", err)
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+// GoAffected returns, sorted, the import paths of the packages whose tests
+// can be affected by the changed files: the packages containing them and
+// every package that imports one of those, directly or indirectly, from
+// its code or tests. Changed paths are relative to root. A change to
+// go.mod or go.sum affects every package.
+func GoAffected(pkgs []GoPackage, root string, changed []string) []string {
+	byDir := make(map[string]string, len(pkgs))
+	for _, p := range pkgs {
+		byDir[filepath.Clean(p.Dir)] = p.ImportPath
+	}
+
+	touched := make(map[string]bool)
+	for _, f := range changed {
+		if base := filepath.Base(f); base == "go.mod" || base == "go.sum" {
+			return importPaths(pkgs)
+		}
+		// A file belongs to the package of the nearest enclosing directory,
+		// so testdata and embedded files count too
+		for dir := filepath.Dir(filepath.Join(root, f)); ; dir = filepath.Dir(dir) {
+			if path, ok := byDir[dir]; ok {
+				touched[path] = true
+				break
+			}
+			if dir == root || dir == filepath.Dir(dir) {
+				break
+			}
+		}
+	}
+	if len(touched) == 0 {
+		return nil
+	}
+
+	imports := make(map[string][]string, len(pkgs))
+	for _, p := range pkgs {
+		imports[p.ImportPath] = p.Imports
+	}
+
+	// reaches reports whether path depends on a touched package
+	memo := make(map[string]bool)
+	var reaches func(path string, visiting map[string]bool) bool
+	reaches = func(path string, visiting map[string]bool) bool {
+		if touched[path] {
+			return true
+		}
+		if v, ok := memo[path]; ok {
+			return v
+		}
+		if visiting[path] {
+			return false
+		}
+		visiting[path] = true
+		result := false
+		for _, imp := range imports[path] {
+			if reaches(imp, visiting) {
+				result = true
+				break
+			}
+		}
+		memo[path] = result
+		return result
+	}
+
+	var affected []string
+	for _, p := range pkgs {
+		deps := append([]string{p.ImportPath}, p.TestImports...)
+		deps = append(deps, p.XTestImports...)
+		for _, d := range deps {
+			if reaches(d, map[string]bool{}) {
+				affected = append(affected, p.ImportPath)
+				break
+			}
+		}
+	}
+	sort.Strings(affected)
+	return affected
+}
+
+func importPaths(pkgs []GoPackage) []string {
+	paths := make([]string, 0, len(pkgs))
+	for _, p := range pkgs {
+		paths = append(paths, p.ImportPath)
+	}
+	sort.Strings(paths)
+	return paths
+}
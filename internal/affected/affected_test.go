@@ -0,0 +1,93 @@
+package affected
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGoAffected(t *testing.T) {
+	root := "/repo"
+	pkgs := []GoPackage{
+		{ImportPath: "m/cmd", Dir: "/repo/cmd", Imports: []string{"m/internal/cli"}},
+		{ImportPath: "m/internal/cli", Dir: "/repo/internal/cli", Imports: []string{"m/internal/config", "fmt"}},
+		{ImportPath: "m/internal/config", Dir: "/repo/internal/config", Imports: []string{"fmt"}},
+		{ImportPath: "m/internal/report", Dir: "/repo/internal/report", TestImports: []string{"m/internal/testutil"}},
+		{ImportPath: "m/internal/testutil", Dir: "/repo/internal/testutil", Imports: []string{"m/internal/config"}},
+		{ImportPath: "m/internal/ui", Dir: "/repo/internal/ui"},
+	}
+
+	tests := []struct {
+		name    string
+		changed []string
+		want    []string
+	}{
+		{"leaf package", []string{"internal/config/config.go"}, []string{"m/cmd", "m/internal/cli", "m/internal/config", "m/internal/report", "m/internal/testutil"}},
+		{"test helper via test imports", []string{"internal/testutil/util.go"}, []string{"m/internal/report", "m/internal/testutil"}},
+		{"testdata", []string{"internal/ui/testdata/golden.txt"}, []string{"m/internal/ui"}},
+		{"non-Go file", []string{"README.md"}, nil},
+		{"go.mod", []string{"go.mod"}, []string{"m/cmd", "m/internal/cli", "m/internal/config", "m/internal/report", "m/internal/testutil", "m/internal/ui"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GoAffected(pkgs, root, tt.changed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GoAffected(%v) = %v, want %v", tt.changed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGoList(t *testing.T) {
+	output := `{"ImportPath": "m/a", "Dir": "/repo/a", "Imports": ["fmt"]}
+{"ImportPath": "m/b", "Dir": "/repo/b", "TestImports": ["m/a"]}
+`
+	pkgs, err := parseGoList([]byte(output))
+	if err != nil {
+		t.Fatalf("parseGoList() unexpected error: %v", err)
+	}
+	if len(pkgs) != 2 || pkgs[1].ImportPath != "m/b" || pkgs[1].TestImports[0] != "m/a" {
+		t.Errorf("parseGoList() = %+v", pkgs)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	if got := Expand("go test {tests} -count=1", []string{"./a", "./b"}); got != "go test ./a ./b -count=1" {
+		t.Errorf("Expand() = %q", got)
+	}
+}
+
+func TestMappingTargets(t *testing.T) {
+	m, err := ParseMapping([]byte(`
+"services/api/**": ./services/api/...
+"web/src/":
+  - web/src
+  - web/e2e
+"*.proto": ./proto/...
+`))
+	if err != nil {
+		t.Fatalf("ParseMapping() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		changed []string
+		want    []string
+	}{
+		{"one rule", []string{"services/api/handler.go"}, []string{"./services/api/..."}},
+		{"list of targets", []string{"web/src/app/index.ts"}, []string{"web/e2e", "web/src"}},
+		{"pattern at any depth", []string{"shared/types/user.proto", "services/api/x.go"}, []string{"./proto/...", "./services/api/..."}},
+		{"unmatched", []string{"docs/index.md"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Targets(tt.changed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Targets(%v) = %v, want %v", tt.changed, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseMapping([]byte(`"a/**": {x: 1}`)); err == nil {
+		t.Error("ParseMapping() expected error for a non-string target")
+	}
+}
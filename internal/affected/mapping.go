@@ -0,0 +1,123 @@
+package affected
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule selects test targets when a changed file matches its pattern.
+type Rule struct {
+	Pattern string
+	Targets []string
+	re      *regexp.Regexp
+}
+
+// Mapping is a user-provided map from file patterns to test targets, for
+// projects the Go analysis doesn't cover.
+type Mapping []Rule
+
+// LoadMapping reads a YAML mapping of gitignore-style patterns to one or
+// more test targets:
+//
+//	"services/api/**": ./services/api/...
+//	"web/src/**":
+//	  - web/src
+//	  - web/e2e
+func LoadMapping(path string) (Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test map: %w", err)
+	}
+	return ParseMapping(data)
+}
+
+// ParseMapping parses the YAML form of a mapping.
+func ParseMapping(data []byte) (Mapping, error) {
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse test map: %w", err)
+	}
+
+	var m Mapping
+	for pattern, value := range raw {
+		rule := Rule{Pattern: pattern, re: patternRegexp(pattern)}
+		switch v := value.(type) {
+		case string:
+			rule.Targets = []string{v}
+		case []any:
+			for _, t := range v {
+				s, ok := t.(string)
+				if !ok {
+					return nil, fmt.Errorf("test map: targets of %q must be strings", pattern)
+				}
+				rule.Targets = append(rule.Targets, s)
+			}
+		default:
+			return nil, fmt.Errorf("test map: %q must map to a target or a list of targets", pattern)
+		}
+		m = append(m, rule)
+	}
+	sort.Slice(m, func(i, j int) bool { return m[i].Pattern < m[j].Pattern })
+	return m, nil
+}
+
+// Targets returns, sorted and without duplicates, the targets of the rules
+// matching any of the changed files. Files no rule matches select nothing.
+func (m Mapping) Targets(changed []string) []string {
+	seen := make(map[string]bool)
+	var targets []string
+	for _, f := range changed {
+		for _, r := range m {
+			if !r.re.MatchString(f) {
+				continue
+			}
+			for _, t := range r.Targets {
+				if !seen[t] {
+					seen[t] = true
+					targets = append(targets, t)
+				}
+			}
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// patternRegexp compiles a gitignore-style pattern: * and ? don't cross
+// directories, ** does, a pattern without a slash matches at any depth and
+// one ending in a slash matches everything below it.
+func patternRegexp(pattern string) *regexp.Regexp {
+	p := strings.TrimPrefix(pattern, "/")
+	if !strings.Contains(strings.TrimSuffix(p, "/"), "/") && !strings.HasPrefix(p, "**") {
+		p = "**/" + p
+	}
+	if strings.HasSuffix(p, "/") {
+		p += "**"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case p[i] == '*':
+			sb.WriteString("[^/]*")
+		case p[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
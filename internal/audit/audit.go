@@ -0,0 +1,292 @@
+// Package audit keeps a tamper-evident, append-only record of the external
+// actions taken during a run. Each entry carries the hash of the one
+// before it, so editing, removing or reordering entries breaks the chain.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the audit log inside a run directory.
+const FileName = "audit.jsonl"
+
+// Actions recorded by the orchestrator.
+const (
+	CommandRun       = "command_run"
+	CommitCreated    = "commit_created"
+	BranchPushed     = "branch_pushed"
+	BranchDeleted    = "branch_deleted"
+	PRCreated        = "pr_created"
+	PRUpdated        = "pr_updated"
+	PRMerged         = "pr_merged"
+	PRClosed         = "pr_closed"
+	ReleaseCreated   = "release_created"
+	SummaryPublished = "summary_published"
+	ReportPublished  = "report_published"
+)
+
+// Actions recorded by dclaude serve --team, each with the user who took it.
+const (
+	TaskSubmitted = "task_submitted"
+	TaskCanceled  = "task_canceled"
+	TaskBumped    = "task_bumped"
+	RunControlled = "run_controlled"
+	MergeApproved = "merge_approved"
+)
+
+// Entry is one action in the log. Hash covers every other field,
+// including Prev, the hash of the previous entry ("" for the first).
+type Entry struct {
+	Seq       int            `json:"seq"`
+	Time      time.Time      `json:"time"`
+	RunID     string         `json:"run_id"`
+	Iteration int            `json:"iteration,omitempty"`
+	Action    string         `json:"action"`
+	Data      map[string]any `json:"data,omitempty"`
+	Prev      string         `json:"prev"`
+	Hash      string         `json:"hash"`
+}
+
+// digest returns the hash of the entry with its Hash field left out.
+func (e Entry) digest() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Log appends entries to a JSONL file. A nil *Log discards them, so
+// callers don't need to check whether logging could be set up.
+type Log struct {
+	mu    sync.Mutex
+	file  *os.File
+	runID string
+	seq   int
+	head  string
+
+	// redact scrubs secrets from entry data before it is hashed
+	redact func(map[string]any) map[string]any
+}
+
+// Open opens the audit log at path for appending, creating its directory,
+// and continues the chain of the entries already in it. It fails if those
+// entries have been tampered with.
+func Open(path, runID string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	l := &Log{runID: runID}
+	if _, err := os.Stat(path); err == nil {
+		entries, err := Read(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := Verify(entries); err != nil {
+			return nil, fmt.Errorf("audit log %s: %w", path, err)
+		}
+		if n := len(entries); n > 0 {
+			l.seq, l.head = entries[n-1].Seq, entries[n-1].Hash
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l.file = file
+	return l, nil
+}
+
+// SetRedactor makes the log pass the data of every later entry through
+// redact before hashing and writing it.
+func (l *Log) SetRedactor(redact func(map[string]any) map[string]any) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.redact = redact
+}
+
+// Record appends an action to the log and syncs it to disk. Write errors
+// are ignored: the audit log must never interrupt a run.
+func (l *Log) Record(action string, iteration int, data map[string]any) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := Entry{
+		Seq:       l.seq + 1,
+		Time:      time.Now().UTC(),
+		RunID:     l.runID,
+		Iteration: iteration,
+		Action:    action,
+		Data:      data,
+		Prev:      l.head,
+	}
+	if l.redact != nil {
+		e.Data = l.redact(e.Data)
+	}
+	hash, err := e.digest()
+	if err != nil {
+		return
+	}
+	e.Hash = hash
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return
+	}
+	_ = l.file.Sync()
+	l.seq, l.head = e.Seq, e.Hash
+}
+
+// Head returns the hash of the latest entry, which vouches for the whole
+// log. Keeping it elsewhere also makes dropping entries from the end
+// detectable.
+func (l *Log) Head() string {
+	if l == nil {
+		return ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.head
+}
+
+// Close closes the underlying file.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// Read returns the entries of the audit log at path, which is either the
+// JSONL log itself or an export written by WriteJSON.
+func Read(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	var entries []Entry
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse audit log export: %w", err)
+		}
+		return entries, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse audit log line %d: %w", lineNum, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// Verify checks that the entries form an unbroken chain: numbered in
+// order, each linked to the one before, and each matching its hash.
+func Verify(entries []Entry) error {
+	prev := ""
+	for i, e := range entries {
+		if e.Seq != i+1 {
+			return fmt.Errorf("entry %d is numbered %d; entries were removed or reordered", i+1, e.Seq)
+		}
+		if e.Prev != prev {
+			return fmt.Errorf("entry %d does not follow entry %d; entries were removed or reordered", e.Seq, i)
+		}
+		hash, err := e.digest()
+		if err != nil {
+			return err
+		}
+		if hash != e.Hash {
+			return fmt.Errorf("entry %d does not match its hash; it was modified", e.Seq)
+		}
+		prev = e.Hash
+	}
+	return nil
+}
+
+// WriteJSON writes the entries as an indented JSON array. The entries
+// keep their hashes, so the export can be verified on its own.
+func WriteJSON(w io.Writer, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// WriteCSV writes the entries as CSV, with their data as key=value pairs.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"seq", "time", "run_id", "iteration", "action", "details", "prev", "hash"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{
+			strconv.Itoa(e.Seq),
+			e.Time.UTC().Format(time.RFC3339),
+			e.RunID,
+			strconv.Itoa(e.Iteration),
+			e.Action,
+			FormatData(e.Data),
+			e.Prev,
+			e.Hash,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// FormatData renders entry data as sorted key=value pairs.
+func FormatData(data map[string]any) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", k, data[k])
+	}
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeLog(t *testing.T, path string, actions ...string) {
+	t.Helper()
+	l, err := Open(path, "run-1")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	for i, action := range actions {
+		l.Record(action, i+1, map[string]any{"sha": "abc123", "files": 3, "args": []string{"a", "b"}})
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+}
+
+func TestRecordAndVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", FileName)
+	writeLog(t, path, CommitCreated, BranchPushed)
+	// Reopening continues the chain
+	writeLog(t, path, PRCreated)
+
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	if entries[0].Prev != "" || entries[2].Prev != entries[1].Hash || entries[2].Seq != 3 {
+		t.Errorf("entries are not chained: %+v", entries)
+	}
+	if err := Verify(entries); err != nil {
+		t.Errorf("Verify() error: %v", err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	writeLog(t, path, CommitCreated, BranchPushed, PRCreated)
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+
+	modified := append([]Entry(nil), entries...)
+	modified[1].Data = map[string]any{"sha": "def456"}
+	swapped := []Entry{entries[1], entries[0], entries[2]}
+
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    string
+	}{
+		{"modified", modified, "entry 2 does not match its hash"},
+		{"removed", []Entry{entries[0], entries[2]}, "entry 2 is numbered 3"},
+		{"reordered", swapped, "entry 1 is numbered 2"},
+		{"renumbered", append([]Entry{entries[0]}, renumber(entries[2], 2)), "entry 2 does not follow entry 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.entries)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Verify() = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func renumber(e Entry, seq int) Entry {
+	e.Seq = seq
+	return e
+}
+
+func TestOpenRejectsTamperedLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	writeLog(t, path, CommitCreated, BranchPushed)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, bytes.Replace(data, []byte("abc123"), []byte("abc124"), 1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, "run-1"); err == nil {
+		t.Error("Open() of a tampered log should fail")
+	}
+}
+
+func TestNilLog(t *testing.T) {
+	var l *Log
+	l.Record(CommandRun, 1, nil)
+	if l.Head() != "" || l.Close() != nil {
+		t.Error("nil Log should discard entries")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	writeLog(t, path, CommitCreated)
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, entries); err != nil {
+		t.Fatalf("WriteCSV() error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[0] != "seq,time,run_id,iteration,action,details,prev,hash" {
+		t.Fatalf("unexpected CSV:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[1], ",run-1,1,commit_created,args=[a b] files=3 sha=abc123,,"+entries[0].Hash) {
+		t.Errorf("unexpected CSV row: %s", lines[1])
+	}
+}
+
+func TestReadExport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	writeLog(t, path, CommitCreated, PRMerged)
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, entries); err != nil {
+		t.Fatalf("WriteJSON() error: %v", err)
+	}
+	export := filepath.Join(dir, "export.json")
+	if err := os.WriteFile(export, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := Read(export)
+	if err != nil {
+		t.Fatalf("Read() of export error: %v", err)
+	}
+	if len(exported) != 2 {
+		t.Fatalf("got %d entries, want 2", len(exported))
+	}
+	if err := Verify(exported); err != nil {
+		t.Errorf("Verify() of export error: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Package auth stores and validates the credentials Deep Claude needs:
+// the Anthropic API key used by Claude Code and the GitHub token used by gh.
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Credential describes one secret and the environment variable its
+// consumer reads it from.
+type Credential struct {
+	Name   string
+	EnvVar string
+	// Other variables that, when set, already provide the credential
+	Aliases []string
+}
+
+var (
+	// Anthropic is the API key Claude Code authenticates with. Claude Code
+	// can also use its own login, so this one is optional.
+	Anthropic = Credential{Name: "anthropic", EnvVar: "ANTHROPIC_API_KEY"}
+	// GitHub is the token gh authenticates with.
+	GitHub = Credential{Name: "github", EnvVar: "GH_TOKEN", Aliases: []string{"GITHUB_TOKEN"}}
+)
+
+// Credentials lists the credentials managed by "dclaude auth".
+var Credentials = []Credential{Anthropic, GitHub}
+
+// Lookup returns the credential with the given name.
+func Lookup(name string) (Credential, error) {
+	for _, c := range Credentials {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return Credential{}, fmt.Errorf("unknown credential %q (expected anthropic or github)", name)
+}
+
+// FromEnv returns the credential's value from the environment, if set.
+func (c Credential) FromEnv() (string, bool) {
+	for _, name := range append([]string{c.EnvVar}, c.Aliases...) {
+		if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Source says where a credential's value came from.
+type Source string
+
+const (
+	SourceEnv   Source = "environment"
+	SourceStore Source = "store"
+	SourceNone  Source = ""
+)
+
+// Resolve returns a credential's value, preferring the environment over
+// the store.
+func Resolve(store Store, c Credential) (string, Source, error) {
+	if v, ok := c.FromEnv(); ok {
+		return v, SourceEnv, nil
+	}
+	v, err := store.Get(c.Name)
+	if err != nil {
+		return "", SourceNone, err
+	}
+	if v == "" {
+		return "", SourceNone, nil
+	}
+	return v, SourceStore, nil
+}
+
+// Export sets the environment variable of every stored credential that
+// isn't already provided by the environment, so Claude Code and gh pick
+// them up.
+func Export(store Store) error {
+	for _, c := range Credentials {
+		value, source, err := Resolve(store, c)
+		if err != nil {
+			return fmt.Errorf("failed to read %s credential from %s: %w", c.Name, store.Name(), err)
+		}
+		if source == SourceStore {
+			os.Setenv(c.EnvVar, value)
+		}
+	}
+	return nil
+}
+
+// anthropicModelsURL is queried to check an API key. Tests point it at a
+// local server.
+var anthropicModelsURL = "https://api.anthropic.com/v1/models"
+
+// ValidateAnthropic checks an Anthropic API key against the API.
+func ValidateAnthropic(key string) error {
+	req, err := http.NewRequest(http.MethodGet, anthropicModelsURL+"?limit=1", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-api-key", key)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return &UnreachableError{err: err}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("Anthropic API key was rejected (%s)", resp.Status)
+	case resp.StatusCode >= 400:
+		return fmt.Errorf("Anthropic API returned %s while checking the key", resp.Status)
+	}
+	return nil
+}
+
+// UnreachableError means a credential couldn't be checked because the
+// service didn't respond, not because the credential is bad.
+type UnreachableError struct {
+	err error
+}
+
+func (e *UnreachableError) Error() string {
+	return fmt.Sprintf("could not reach the Anthropic API: %v", e.err)
+}
+
+func (e *UnreachableError) Unwrap() error {
+	return e.err
+}
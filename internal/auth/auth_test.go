@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "credentials.json")
+	store := NewFileStore(path)
+
+	if v, err := store.Get("github"); err != nil || v != "" {
+		t.Fatalf("Get() on empty store = %q, %v; want empty", v, err)
+	}
+	if err := store.Set("github", "ghp_test"); err != nil {
+		t.Fatalf("Set() unexpected error: %v", err)
+	}
+	if v, _ := NewFileStore(path).Get("github"); v != "ghp_test" {
+		t.Errorf("Get() = %q, want ghp_test", v)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("credentials file mode = %o, want 600", perm)
+	}
+
+	if err := store.Delete("github"); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	if v, _ := store.Get("github"); v != "" {
+		t.Errorf("Get() after Delete() = %q, want empty", v)
+	}
+}
+
+func TestMacKeychainSetKeepsSecretOutOfArgs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script standing in for security")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(dir, "args") + "\ncat > " + filepath.Join(dir, "stdin") + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "security"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if err := (&macKeychain{}).Set("github", "ghp_test"); err != nil {
+		t.Fatalf("Set() unexpected error: %v", err)
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	if strings.Contains(string(args), "ghp_test") || !strings.HasSuffix(strings.TrimSpace(string(args)), "-w") {
+		t.Errorf("security args = %q, want -w last and no secret", args)
+	}
+	if stdin, _ := os.ReadFile(filepath.Join(dir, "stdin")); string(stdin) != "ghp_test\nghp_test\n" {
+		t.Errorf("security stdin = %q, want the secret twice", stdin)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "credentials.json"))
+	if err := store.Set("github", "stored"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "")
+	if v, source, _ := Resolve(store, GitHub); v != "stored" || source != SourceStore {
+		t.Errorf("Resolve() = %q, %q; want stored value", v, source)
+	}
+
+	t.Setenv("GITHUB_TOKEN", "from-env")
+	if v, source, _ := Resolve(store, GitHub); v != "from-env" || source != SourceEnv {
+		t.Errorf("Resolve() = %q, %q; want environment to win", v, source)
+	}
+
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	if v, source, _ := Resolve(store, Anthropic); v != "" || source != SourceNone {
+		t.Errorf("Resolve() of missing credential = %q, %q; want none", v, source)
+	}
+}
+
+func TestExport(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "credentials.json"))
+	if err := store.Set("anthropic", "sk-ant-stored"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	if err := Export(store); err != nil {
+		t.Fatalf("Export() unexpected error: %v", err)
+	}
+	if got := os.Getenv("ANTHROPIC_API_KEY"); got != "sk-ant-stored" {
+		t.Errorf("ANTHROPIC_API_KEY = %q, want stored key", got)
+	}
+}
+
+func TestValidateAnthropic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	saved := anthropicModelsURL
+	anthropicModelsURL = server.URL
+	defer func() { anthropicModelsURL = saved }()
+
+	if err := ValidateAnthropic("good"); err != nil {
+		t.Errorf("ValidateAnthropic(good) unexpected error: %v", err)
+	}
+	if err := ValidateAnthropic("bad"); err == nil {
+		t.Error("ValidateAnthropic(bad) expected error")
+	}
+
+	server.Close()
+	var unreachable *UnreachableError
+	if err := ValidateAnthropic("good"); !errors.As(err, &unreachable) {
+		t.Errorf("ValidateAnthropic() with server down = %v, want UnreachableError", err)
+	}
+}
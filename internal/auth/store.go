@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/state"
+)
+
+// keychainService is the service name secrets are filed under.
+const keychainService = "deep-claude"
+
+// Store persists credentials.
+type Store interface {
+	// Name describes where secrets are kept.
+	Name() string
+	// Get returns a stored secret, or "" if there is none.
+	Get(name string) (string, error)
+	Set(name, secret string) error
+	Delete(name string) error
+}
+
+// DefaultStore returns the OS keychain when one is available (macOS
+// Keychain or the Secret Service via secret-tool), falling back to a
+// private file in the state directory.
+func DefaultStore() (Store, error) {
+	if runtime.GOOS == "darwin" {
+		if _, err := exec.LookPath("security"); err == nil {
+			return &macKeychain{}, nil
+		}
+	}
+	if _, err := exec.LookPath("secret-tool"); err == nil && os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+		return &secretService{}, nil
+	}
+
+	dir, err := state.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return NewFileStore(filepath.Join(dir, "credentials.json")), nil
+}
+
+// macKeychain stores secrets with the macOS security tool.
+type macKeychain struct{}
+
+func (k *macKeychain) Name() string { return "macOS Keychain" }
+
+func (k *macKeychain) Get(name string) (string, error) {
+	cmd := exec.Command("security", "find-generic-password", "-s", keychainService, "-a", name, "-w")
+	output, err := cmd.Output()
+	if err != nil {
+		// Exit status 44 means the item doesn't exist
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 44 {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read keychain: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Set passes the secret on stdin rather than in the arguments, where ps
+// would show it: with -w last, security prompts for the password and its
+// retyping, and reads them from stdin when it has no terminal.
+func (k *macKeychain) Set(name, secret string) error {
+	cmd := exec.Command("security", "add-generic-password", "-U", "-s", keychainService, "-a", name, "-w")
+	cmd.Stdin = strings.NewReader(secret + "\n" + secret + "\n")
+	detachTerminal(cmd)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write keychain: %w\n%s", err, output)
+	}
+	return nil
+}
+
+func (k *macKeychain) Delete(name string) error {
+	cmd := exec.Command("security", "delete-generic-password", "-s", keychainService, "-a", name)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 44 {
+			return nil
+		}
+		return fmt.Errorf("failed to delete from keychain: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// secretService stores secrets in the freedesktop Secret Service (GNOME
+// Keyring, KWallet) with secret-tool.
+type secretService struct{}
+
+func (s *secretService) Name() string { return "Secret Service keyring" }
+
+func (s *secretService) Get(name string) (string, error) {
+	cmd := exec.Command("secret-tool", "lookup", "service", keychainService, "account", name)
+	output, err := cmd.Output()
+	if err != nil {
+		// secret-tool exits 1 with no output when nothing matches
+		if _, ok := err.(*exec.ExitError); ok && len(output) == 0 {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read keyring: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (s *secretService) Set(name, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", "Deep Claude "+name, "service", keychainService, "account", name)
+	cmd.Stdin = strings.NewReader(secret)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write keyring: %w\n%s", err, output)
+	}
+	return nil
+}
+
+func (s *secretService) Delete(name string) error {
+	cmd := exec.Command("secret-tool", "clear", "service", keychainService, "account", name)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to delete from keyring: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// FileStore keeps secrets in a JSON file readable only by the user, for
+// systems without a keychain.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a store backed by the file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (f *FileStore) Name() string { return f.path }
+
+func (f *FileStore) Get(name string) (string, error) {
+	secrets, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	return secrets[name], nil
+}
+
+func (f *FileStore) Set(name, secret string) error {
+	secrets, err := f.load()
+	if err != nil {
+		return err
+	}
+	secrets[name] = secret
+	return f.save(secrets)
+}
+
+func (f *FileStore) Delete(name string) error {
+	secrets, err := f.load()
+	if err != nil {
+		return err
+	}
+	delete(secrets, name)
+	return f.save(secrets)
+}
+
+func (f *FileStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	secrets := map[string]string{}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &secrets); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", f.path, err)
+		}
+	}
+	return secrets, nil
+}
+
+func (f *FileStore) save(secrets map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	data, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package auth
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachTerminal starts cmd in a session of its own, without the
+// controlling terminal, so prompts read stdin instead of /dev/tty.
+func detachTerminal(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package auth
+
+import "os/exec"
+
+// detachTerminal is a no-op: the keychains that prompt aren't on Windows.
+func detachTerminal(cmd *exec.Cmd) {}
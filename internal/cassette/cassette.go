@@ -0,0 +1,251 @@
+// Package cassette records the git, gh and claude calls of a run to a
+// file and plays them back, for --record and --replay.
+package cassette
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is the cassette format version.
+const Version = 1
+
+// Header is the first line of a cassette.
+type Header struct {
+	Version    int       `json:"version"`
+	RecordedAt time.Time `json:"recorded_at"`
+	// Flags are the flags the recorded run was started with, so a replay
+	// can be started the same way
+	Flags map[string][]string `json:"flags,omitempty"`
+}
+
+// Interaction is one recorded call and what it returned.
+type Interaction struct {
+	Runner string          `json:"runner"`
+	Method string          `json:"method"`
+	Args   json.RawMessage `json:"args,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	// Updates are the statuses reported while waiting for checks
+	Updates []json.RawMessage `json:"updates,omitempty"`
+}
+
+// Cassette is a JSONL file of interactions: a Header line, then one line
+// per call. Recording appends each call as it returns, so a crashed run
+// still leaves its calls behind. Replaying serves the calls of each method
+// in the order they were recorded; calls to different methods may come in
+// any order, as they do when iterations overlap.
+type Cassette struct {
+	mu sync.Mutex
+
+	// Recording
+	file *os.File
+	enc  *json.Encoder
+	err  error
+
+	// Replaying
+	header  Header
+	queues  map[string][]Interaction
+	missing []string
+}
+
+// Record creates a cassette at path for a run started with the given flags.
+func Record(path string, flags map[string][]string) (*Cassette, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cassette: %w", err)
+	}
+	c := &Cassette{file: file, enc: json.NewEncoder(file)}
+	if err := c.enc.Encode(Header{Version: Version, RecordedAt: time.Now().UTC(), Flags: flags}); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return c, nil
+}
+
+// Load reads a cassette to replay.
+func Load(path string) (*Cassette, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette: %w", err)
+	}
+	defer file.Close()
+
+	c := &Cassette{queues: make(map[string][]Interaction)}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if line == 1 {
+			if err := json.Unmarshal(scanner.Bytes(), &c.header); err != nil {
+				return nil, fmt.Errorf("failed to parse cassette header: %w", err)
+			}
+			if c.header.Version != Version {
+				return nil, fmt.Errorf("unsupported cassette version %d (want %d)", c.header.Version, Version)
+			}
+			continue
+		}
+		var i Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &i); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette line %d: %w", line, err)
+		}
+		key := i.Runner + "." + i.Method
+		c.queues[key] = append(c.queues[key], i)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	if c.header.Version == 0 {
+		return nil, fmt.Errorf("cassette %s is empty", path)
+	}
+	return c, nil
+}
+
+// Header returns the header of a loaded cassette.
+func (c *Cassette) Header() Header {
+	return c.header
+}
+
+// Close finishes a recording, reporting the first call that could not be
+// written, or reports the calls a replay asked for that weren't recorded.
+func (c *Cassette) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file != nil {
+		err := c.file.Close()
+		if c.err != nil {
+			return c.err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write cassette: %w", err)
+		}
+		return nil
+	}
+	if len(c.missing) > 0 {
+		missing := append([]string{}, c.missing...)
+		sort.Strings(missing)
+		return fmt.Errorf("replay diverged from the cassette: no recorded %s call left", strings.Join(slices.Compact(missing), ", "))
+	}
+	return nil
+}
+
+// Unplayed returns how many recorded calls of each method a replay has
+// not asked for.
+func (c *Cassette) Unplayed() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	left := make(map[string]int)
+	for key, queue := range c.queues {
+		if len(queue) > 0 {
+			left[key] = len(queue)
+		}
+	}
+	return left
+}
+
+// FormatUnplayed lists unplayed calls as "gh.MergePR (2), git.Push (1)".
+func FormatUnplayed(left map[string]int) string {
+	keys := make([]string, 0, len(left))
+	for key := range left {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s (%d)", key, left[key])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (c *Cassette) replaying() bool {
+	return c.queues != nil
+}
+
+// record appends a call to the recording.
+func (c *Cassette) record(i Interaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	if err := c.enc.Encode(i); err != nil {
+		c.err = fmt.Errorf("failed to write cassette: %w", err)
+	}
+}
+
+// next takes the next recorded call of a method, or notes that there is
+// none.
+func (c *Cassette) next(runner, method string) (Interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := runner + "." + method
+	queue := c.queues[key]
+	if len(queue) == 0 {
+		c.missing = append(c.missing, key)
+		return Interaction{}, false
+	}
+	c.queues[key] = queue[1:]
+	return queue[0], true
+}
+
+// call records fn's result, or replays the recorded one without calling it.
+func call[T any](c *Cassette, runner, method string, args []any, fn func() (T, error)) (T, error) {
+	v, _, err := callWithUpdates(c, runner, method, args, func(func(json.RawMessage)) (T, error) { return fn() })
+	return v, err
+}
+
+// callWithUpdates is call for a method that reports progress: fn passes
+// each update to report as it happens. A replay returns the recorded
+// updates, for the caller to report in turn.
+func callWithUpdates[T any](c *Cassette, runner, method string, args []any, fn func(report func(json.RawMessage)) (T, error)) (T, []json.RawMessage, error) {
+	var v T
+	if c.replaying() {
+		i, ok := c.next(runner, method)
+		if !ok {
+			return v, nil, fmt.Errorf("cassette: no recorded %s.%s call left", runner, method)
+		}
+		if len(i.Result) > 0 {
+			if err := json.Unmarshal(i.Result, &v); err != nil {
+				return v, nil, fmt.Errorf("cassette: failed to decode %s.%s result: %w", runner, method, err)
+			}
+		}
+		if i.Error != "" {
+			return v, i.Updates, errors.New(i.Error)
+		}
+		return v, i.Updates, nil
+	}
+
+	var updates []json.RawMessage
+	v, err := fn(func(update json.RawMessage) { updates = append(updates, update) })
+	i := Interaction{Runner: runner, Method: method, Updates: updates}
+	if len(args) > 0 {
+		i.Args, _ = json.Marshal(args)
+	}
+	if result, _ := json.Marshal(v); string(result) != "null" {
+		i.Result = result
+	}
+	if err != nil {
+		i.Error = err.Error()
+	}
+	c.record(i)
+	return v, nil, err
+}
+
+// do is call for a method that only returns an error.
+func do(c *Cassette, runner, method string, args []any, fn func() error) error {
+	_, err := call(c, runner, method, args, func() (*struct{}, error) { return nil, fn() })
+	return err
+}
+
+// value is call for a method that can't fail.
+func value[T any](c *Cassette, runner, method string, args []any, fn func() T) T {
+	v, _ := call(c, runner, method, args, func() (T, error) { return fn(), nil })
+	return v
+}
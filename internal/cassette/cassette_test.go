@@ -0,0 +1,169 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guzus/deep-claude/internal/fake"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/orchestrator"
+)
+
+// run runs the orchestrator to completion with the given runners.
+func run(t *testing.T, dir string, g orchestrator.GitRunner, h orchestrator.GhRunner, c orchestrator.ClaudeRunner) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Prompt = "improve the tests"
+	cfg.MaxRuns = 3
+	cfg.RepoMapIterations = 0
+
+	newGitHub := func(owner, repo string) orchestrator.GhRunner { return h }
+	o, err := orchestrator.NewWithRunners(cfg, dir, g, newGitHub, c)
+	if err != nil {
+		t.Fatalf("NewWithRunners() unexpected error: %v", err)
+	}
+	if err := o.Run(t.Context()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	path := filepath.Join(dir, "run.cassette")
+
+	g := fake.NewGit(filepath.Join(dir, "repo"), "acme", "widgets", "main.go", "main_test.go")
+	h := fake.NewGitHub(g, "acme", "widgets")
+	h.Statuses = []*github.PRStatus{fake.Passing(), fake.Failing()}
+	c := fake.NewClaude(g,
+		fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Handle empty input", Cost: 0.5},
+		fake.Turn{Err: errors.New("process exited with status 1")},
+		fake.Turn{Edits: []string{"main_test.go"}, CommitMessage: "Break the build"},
+	)
+
+	recording, err := Record(path, map[string][]string{"prompt": {"improve the tests"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, dir, recording.Git(g), recording.GitHub(h), recording.Claude(c))
+	if err := recording.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	replay, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if flags := replay.Header().Flags; !reflect.DeepEqual(flags, map[string][]string{"prompt": {"improve the tests"}}) {
+		t.Errorf("Header().Flags = %v", flags)
+	}
+	var prs []string
+	run(t, dir, replay.Git(nil), &spy{GitHub: replay.GitHub(nil), opened: &prs}, replay.Claude(nil))
+	if err := replay.Close(); err != nil {
+		t.Fatalf("replay Close() unexpected error: %v", err)
+	}
+	if left := replay.Unplayed(); len(left) > 0 {
+		t.Errorf("replay left calls unplayed: %s", FormatUnplayed(left))
+	}
+
+	var opened, merged, closed []string
+	for _, pr := range h.PRs() {
+		opened = append(opened, pr.URL)
+		switch pr.State {
+		case "MERGED":
+			merged = append(merged, pr.Title)
+		case "CLOSED":
+			closed = append(closed, pr.Title)
+		}
+	}
+	if !reflect.DeepEqual(prs, opened) {
+		t.Errorf("replay opened %v, want %v", prs, opened)
+	}
+	if !reflect.DeepEqual(merged, []string{"Handle empty input"}) || !reflect.DeepEqual(closed, []string{"Break the build"}) {
+		t.Errorf("recorded run merged %v and closed %v", merged, closed)
+	}
+}
+
+// spy notes the PRs a replay opens.
+type spy struct {
+	*GitHub
+	opened *[]string
+}
+
+func (s *spy) CreatePR(ctx context.Context, title, body, base string) (string, error) {
+	url, err := s.GitHub.CreatePR(ctx, title, body, base)
+	if err == nil {
+		*s.opened = append(*s.opened, url)
+	}
+	return url, err
+}
+
+func TestReplayDiverges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.cassette")
+	recording, err := Record(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := recording.Git(fake.NewGit(t.TempDir(), "acme", "widgets"))
+	if err := g.Push(t.Context(), "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Pull(t.Context(), "missing"); err == nil {
+		t.Fatal("Pull() of a missing branch should fail")
+	}
+	if err := recording.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g = replay.Git(nil)
+	if err := g.Push(t.Context(), "main"); err != nil {
+		t.Errorf("replayed Push() unexpected error: %v", err)
+	}
+	if err := g.Pull(t.Context(), "missing"); err == nil {
+		t.Error("replayed Pull() should return the recorded error")
+	}
+	if err := g.Push(t.Context(), "main"); err == nil {
+		t.Error("Push() past the recording should fail")
+	}
+	if branch := g.GenerateBranchName("dc", "run", 1); branch != "" {
+		t.Errorf("GenerateBranchName() past the recording = %q, want empty", branch)
+	}
+	if err := replay.Close(); err == nil || !strings.Contains(err.Error(), "git.GenerateBranchName, git.Push") {
+		t.Errorf("Close() = %v, want the calls missing from the cassette", err)
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "empty", content: "", wantErr: "is empty"},
+		{name: "newer version", content: `{"version":2}` + "\n", wantErr: "unsupported cassette version 2"},
+		{name: "bad line", content: `{"version":1}` + "\nnot json\n", wantErr: "line 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "run.cassette")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,546 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/retry"
+	"github.com/guzus/deep-claude/pkg/orchestrator"
+)
+
+// Git records the calls to a git runner, or replays them when the
+// cassette was loaded, in which case it has no runner.
+type Git struct {
+	c *Cassette
+	r orchestrator.GitRunner
+}
+
+// Git wraps a git runner; r is nil when replaying.
+func (c *Cassette) Git(r orchestrator.GitRunner) *Git {
+	return &Git{c: c, r: r}
+}
+
+// IsRepo implements orchestrator.GitRunner.
+func (g *Git) IsRepo() bool {
+	return value(g.c, "git", "IsRepo", nil, func() bool { return g.r.IsRepo() })
+}
+
+// DetectGitHubRepo implements orchestrator.GitRunner.
+func (g *Git) DetectGitHubRepo() (string, string, error) {
+	repo, err := call(g.c, "git", "DetectGitHubRepo", nil, func() ([2]string, error) {
+		owner, repo, err := g.r.DetectGitHubRepo()
+		return [2]string{owner, repo}, err
+	})
+	return repo[0], repo[1], err
+}
+
+// DefaultBranch implements orchestrator.GitRunner.
+func (g *Git) DefaultBranch() (string, error) {
+	return call(g.c, "git", "DefaultBranch", nil, func() (string, error) { return g.r.DefaultBranch() })
+}
+
+// GitDir implements orchestrator.GitRunner.
+func (g *Git) GitDir() (string, error) {
+	return call(g.c, "git", "GitDir", nil, func() (string, error) { return g.r.GitDir() })
+}
+
+// ListFiles implements orchestrator.GitRunner.
+func (g *Git) ListFiles() ([]string, error) {
+	return call(g.c, "git", "ListFiles", nil, func() ([]string, error) { return g.r.ListFiles() })
+}
+
+// CurrentBranch implements orchestrator.GitRunner.
+func (g *Git) CurrentBranch() (string, error) {
+	return call(g.c, "git", "CurrentBranch", nil, func() (string, error) { return g.r.CurrentBranch() })
+}
+
+// GenerateBranchName implements orchestrator.GitRunner.
+func (g *Git) GenerateBranchName(prefix, runID string, iteration int) string {
+	return value(g.c, "git", "GenerateBranchName", []any{prefix, runID, iteration}, func() string {
+		return g.r.GenerateBranchName(prefix, runID, iteration)
+	})
+}
+
+// CreateBranch implements orchestrator.GitRunner.
+func (g *Git) CreateBranch(name string) error {
+	return do(g.c, "git", "CreateBranch", []any{name}, func() error { return g.r.CreateBranch(name) })
+}
+
+// CreateBranchFrom implements orchestrator.GitRunner.
+func (g *Git) CreateBranchFrom(name, startPoint string) error {
+	return do(g.c, "git", "CreateBranchFrom", []any{name, startPoint}, func() error { return g.r.CreateBranchFrom(name, startPoint) })
+}
+
+// SwitchBranch implements orchestrator.GitRunner.
+func (g *Git) SwitchBranch(name string) error {
+	return do(g.c, "git", "SwitchBranch", []any{name}, func() error { return g.r.SwitchBranch(name) })
+}
+
+// DeleteBranch implements orchestrator.GitRunner.
+func (g *Git) DeleteBranch(name string) error {
+	return do(g.c, "git", "DeleteBranch", []any{name}, func() error { return g.r.DeleteBranch(name) })
+}
+
+// HasChanges implements orchestrator.GitRunner.
+func (g *Git) HasChanges(excludes []string) (bool, error) {
+	return call(g.c, "git", "HasChanges", []any{excludes}, func() (bool, error) { return g.r.HasChanges(excludes) })
+}
+
+// GetStatus implements orchestrator.GitRunner.
+func (g *Git) GetStatus() (string, error) {
+	return call(g.c, "git", "GetStatus", nil, func() (string, error) { return g.r.GetStatus() })
+}
+
+// Stash implements orchestrator.GitRunner.
+func (g *Git) Stash(message string, excludes []string) (bool, error) {
+	return call(g.c, "git", "Stash", []any{message, excludes}, func() (bool, error) { return g.r.Stash(message, excludes) })
+}
+
+// StashPop implements orchestrator.GitRunner.
+func (g *Git) StashPop(message string) error {
+	return do(g.c, "git", "StashPop", []any{message}, func() error { return g.r.StashPop(message) })
+}
+
+// TakeSnapshot implements orchestrator.GitRunner.
+func (g *Git) TakeSnapshot() (git.Snapshot, error) {
+	return call(g.c, "git", "TakeSnapshot", nil, func() (git.Snapshot, error) { return g.r.TakeSnapshot() })
+}
+
+// ChangedSince implements orchestrator.GitRunner.
+func (g *Git) ChangedSince(before git.Snapshot) ([]string, error) {
+	return call(g.c, "git", "ChangedSince", []any{before}, func() ([]string, error) { return g.r.ChangedSince(before) })
+}
+
+// ChangedFilesSince implements orchestrator.GitRunner.
+func (g *Git) ChangedFilesSince(ref string) ([]string, error) {
+	return call(g.c, "git", "ChangedFilesSince", []any{ref}, func() ([]string, error) { return g.r.ChangedFilesSince(ref) })
+}
+
+// StagePaths implements orchestrator.GitRunner.
+func (g *Git) StagePaths(paths, excludes []string) error {
+	return do(g.c, "git", "StagePaths", []any{paths, excludes}, func() error { return g.r.StagePaths(paths, excludes) })
+}
+
+// StageAllExcept implements orchestrator.GitRunner.
+func (g *Git) StageAllExcept(excludes []string) error {
+	return do(g.c, "git", "StageAllExcept", []any{excludes}, func() error { return g.r.StageAllExcept(excludes) })
+}
+
+// HasStagedChanges implements orchestrator.GitRunner.
+func (g *Git) HasStagedChanges() (bool, error) {
+	return call(g.c, "git", "HasStagedChanges", nil, func() (bool, error) { return g.r.HasStagedChanges() })
+}
+
+// GetStagedShortStat implements orchestrator.GitRunner.
+func (g *Git) GetStagedShortStat() (git.DiffStat, error) {
+	return call(g.c, "git", "GetStagedShortStat", nil, func() (git.DiffStat, error) { return g.r.GetStagedShortStat() })
+}
+
+// GetStagedDiffStat implements orchestrator.GitRunner.
+func (g *Git) GetStagedDiffStat() (string, error) {
+	return call(g.c, "git", "GetStagedDiffStat", nil, func() (string, error) { return g.r.GetStagedDiffStat() })
+}
+
+// Commit implements orchestrator.GitRunner.
+func (g *Git) Commit(message string) error {
+	return do(g.c, "git", "Commit", []any{message}, func() error { return g.r.Commit(message) })
+}
+
+// UndoLastCommit implements orchestrator.GitRunner.
+func (g *Git) UndoLastCommit() error {
+	return do(g.c, "git", "UndoLastCommit", nil, func() error { return g.r.UndoLastCommit() })
+}
+
+// AmendCommit implements orchestrator.GitRunner.
+func (g *Git) AmendCommit() error {
+	return do(g.c, "git", "AmendCommit", nil, func() error { return g.r.AmendCommit() })
+}
+
+// CommitEmpty implements orchestrator.GitRunner.
+func (g *Git) CommitEmpty(message string) error {
+	return do(g.c, "git", "CommitEmpty", []any{message}, func() error { return g.r.CommitEmpty(message) })
+}
+
+// CommitTitlesSince implements orchestrator.GitRunner.
+func (g *Git) CommitTitlesSince(ref string) ([]string, error) {
+	return call(g.c, "git", "CommitTitlesSince", []any{ref}, func() ([]string, error) { return g.r.CommitTitlesSince(ref) })
+}
+
+// SquashSince implements orchestrator.GitRunner.
+func (g *Git) SquashSince(ref, message string) error {
+	return do(g.c, "git", "SquashSince", []any{ref, message}, func() error { return g.r.SquashSince(ref, message) })
+}
+
+// HeadSHA implements orchestrator.GitRunner.
+func (g *Git) HeadSHA() (string, error) {
+	return call(g.c, "git", "HeadSHA", nil, func() (string, error) { return g.r.HeadSHA() })
+}
+
+// GetLastCommitTitle implements orchestrator.GitRunner.
+func (g *Git) GetLastCommitTitle() (string, error) {
+	return call(g.c, "git", "GetLastCommitTitle", nil, func() (string, error) { return g.r.GetLastCommitTitle() })
+}
+
+// GetLastCommitMessage implements orchestrator.GitRunner.
+func (g *Git) GetLastCommitMessage() (string, error) {
+	return call(g.c, "git", "GetLastCommitMessage", nil, func() (string, error) { return g.r.GetLastCommitMessage() })
+}
+
+// BranchDiff implements orchestrator.GitRunner.
+func (g *Git) BranchDiff(base string) (string, error) {
+	return call(g.c, "git", "BranchDiff", []any{base}, func() (string, error) { return g.r.BranchDiff(base) })
+}
+
+// PatchID implements orchestrator.GitRunner.
+func (g *Git) PatchID(diff string) (string, error) {
+	return call(g.c, "git", "PatchID", nil, func() (string, error) { return g.r.PatchID(diff) })
+}
+
+// FormatPatch implements orchestrator.GitRunner.
+func (g *Git) FormatPatch(base string) (string, error) {
+	return call(g.c, "git", "FormatPatch", []any{base}, func() (string, error) { return g.r.FormatPatch(base) })
+}
+
+// CherryPick implements orchestrator.GitRunner.
+func (g *Git) CherryPick(sha string) ([]string, error) {
+	return call(g.c, "git", "CherryPick", []any{sha}, func() ([]string, error) { return g.r.CherryPick(sha) })
+}
+
+// CherryPickContinue implements orchestrator.GitRunner.
+func (g *Git) CherryPickContinue() error {
+	return do(g.c, "git", "CherryPickContinue", nil, func() error { return g.r.CherryPickContinue() })
+}
+
+// CherryPickAbort implements orchestrator.GitRunner.
+func (g *Git) CherryPickAbort() error {
+	return do(g.c, "git", "CherryPickAbort", nil, func() error { return g.r.CherryPickAbort() })
+}
+
+// Revert implements orchestrator.GitRunner.
+func (g *Git) Revert(sha string) error {
+	return do(g.c, "git", "Revert", []any{sha}, func() error { return g.r.Revert(sha) })
+}
+
+// Rebase implements orchestrator.GitRunner.
+func (g *Git) Rebase(onto string) ([]string, error) {
+	return call(g.c, "git", "Rebase", []any{onto}, func() ([]string, error) { return g.r.Rebase(onto) })
+}
+
+// UnresolvedConflicts implements orchestrator.GitRunner.
+func (g *Git) UnresolvedConflicts(paths []string) []string {
+	return value(g.c, "git", "UnresolvedConflicts", []any{paths}, func() []string { return g.r.UnresolvedConflicts(paths) })
+}
+
+// Push implements orchestrator.GitRunner.
+func (g *Git) Push(ctx context.Context, branch string) error {
+	return do(g.c, "git", "Push", []any{branch}, func() error { return g.r.Push(ctx, branch) })
+}
+
+// PushWithRetry implements orchestrator.GitRunner.
+func (g *Git) PushWithRetry(ctx context.Context, branch string, policy retry.Policy) error {
+	return do(g.c, "git", "PushWithRetry", []any{branch}, func() error { return g.r.PushWithRetry(ctx, branch, policy) })
+}
+
+// ForcePushWithLease implements orchestrator.GitRunner.
+func (g *Git) ForcePushWithLease(ctx context.Context, branch, expected string) error {
+	return do(g.c, "git", "ForcePushWithLease", []any{branch, expected}, func() error { return g.r.ForcePushWithLease(ctx, branch, expected) })
+}
+
+// PushTo implements orchestrator.GitRunner.
+func (g *Git) PushTo(ctx context.Context, branch string) error {
+	return do(g.c, "git", "PushTo", []any{branch}, func() error { return g.r.PushTo(ctx, branch) })
+}
+
+// Pull implements orchestrator.GitRunner.
+func (g *Git) Pull(ctx context.Context, branch string) error {
+	return do(g.c, "git", "Pull", []any{branch}, func() error { return g.r.Pull(ctx, branch) })
+}
+
+// Fetch implements orchestrator.GitRunner.
+func (g *Git) Fetch(ctx context.Context, branch string) error {
+	return do(g.c, "git", "Fetch", []any{branch}, func() error { return g.r.Fetch(ctx, branch) })
+}
+
+// RemoteBranches implements orchestrator.GitRunner.
+func (g *Git) RemoteBranches(ctx context.Context, prefix string) ([]string, error) {
+	return call(g.c, "git", "RemoteBranches", []any{prefix}, func() ([]string, error) { return g.r.RemoteBranches(ctx, prefix) })
+}
+
+// DeleteRemoteBranches implements orchestrator.GitRunner.
+func (g *Git) DeleteRemoteBranches(ctx context.Context, names ...string) error {
+	return do(g.c, "git", "DeleteRemoteBranches", []any{names}, func() error { return g.r.DeleteRemoteBranches(ctx, names...) })
+}
+
+// GitHub records the calls to a GitHub runner, or replays them.
+type GitHub struct {
+	c *Cassette
+	r orchestrator.GhRunner
+}
+
+// GitHub wraps a GitHub runner; r is nil when replaying.
+func (c *Cassette) GitHub(r orchestrator.GhRunner) *GitHub {
+	return &GitHub{c: c, r: r}
+}
+
+// SetLogger is passed on without being recorded.
+func (h *GitHub) SetLogger(l github.Logger) {
+	if h.r != nil {
+		h.r.SetLogger(l)
+	}
+}
+
+// SetCheckScope is passed on without being recorded.
+func (h *GitHub) SetCheckScope(names []string) {
+	if h.r != nil {
+		h.r.SetCheckScope(names)
+	}
+}
+
+// Owner implements orchestrator.GhRunner.
+func (h *GitHub) Owner() string {
+	return value(h.c, "gh", "Owner", nil, func() string { return h.r.Owner() })
+}
+
+// Repo implements orchestrator.GhRunner.
+func (h *GitHub) Repo() string {
+	return value(h.c, "gh", "Repo", nil, func() string { return h.r.Repo() })
+}
+
+// CheckAuth implements orchestrator.GhRunner.
+func (h *GitHub) CheckAuth(ctx context.Context) error {
+	return do(h.c, "gh", "CheckAuth", nil, func() error { return h.r.CheckAuth(ctx) })
+}
+
+// SetRetryPolicy is passed on without being recorded.
+func (h *GitHub) SetRetryPolicy(p retry.Policy) {
+	if h.r != nil {
+		h.r.SetRetryPolicy(p)
+	}
+}
+
+// LogRateLimit is passed on without being recorded.
+func (h *GitHub) LogRateLimit(ctx context.Context) {
+	if h.r != nil {
+		h.r.LogRateLimit(ctx)
+	}
+}
+
+// CreatePR implements orchestrator.GhRunner.
+func (h *GitHub) CreatePR(ctx context.Context, title, body, base string) (string, error) {
+	return call(h.c, "gh", "CreatePR", []any{title, body, base}, func() (string, error) { return h.r.CreatePR(ctx, title, body, base) })
+}
+
+// CreateDraftPR implements orchestrator.GhRunner.
+func (h *GitHub) CreateDraftPR(ctx context.Context, title, body, base string) (string, error) {
+	return call(h.c, "gh", "CreateDraftPR", []any{title, body, base}, func() (string, error) { return h.r.CreateDraftPR(ctx, title, body, base) })
+}
+
+// EditPR implements orchestrator.GhRunner.
+func (h *GitHub) EditPR(ctx context.Context, prNumber, title, body string) error {
+	return do(h.c, "gh", "EditPR", []any{prNumber, title, body}, func() error { return h.r.EditPR(ctx, prNumber, title, body) })
+}
+
+// GetPR implements orchestrator.GhRunner.
+func (h *GitHub) GetPR(ctx context.Context, prNumber string) (*github.PullRequest, error) {
+	return call(h.c, "gh", "GetPR", []any{prNumber}, func() (*github.PullRequest, error) { return h.r.GetPR(ctx, prNumber) })
+}
+
+// GetPRDiff implements orchestrator.GhRunner.
+func (h *GitHub) GetPRDiff(ctx context.Context, prNumber string) (string, error) {
+	return call(h.c, "gh", "GetPRDiff", []any{prNumber}, func() (string, error) { return h.r.GetPRDiff(ctx, prNumber) })
+}
+
+// GetPRStatus implements orchestrator.GhRunner.
+func (h *GitHub) GetPRStatus(ctx context.Context, prNumber string) (*github.PRStatus, error) {
+	return call(h.c, "gh", "GetPRStatus", []any{prNumber}, func() (*github.PRStatus, error) { return h.r.GetPRStatus(ctx, prNumber) })
+}
+
+// GetCommitStatus implements orchestrator.GhRunner.
+func (h *GitHub) GetCommitStatus(ctx context.Context, sha string) (*github.PRStatus, error) {
+	return call(h.c, "gh", "GetCommitStatus", []any{sha}, func() (*github.PRStatus, error) { return h.r.GetCommitStatus(ctx, sha) })
+}
+
+// GetDeploymentState implements orchestrator.GhRunner.
+func (h *GitHub) GetDeploymentState(ctx context.Context, sha, environment string) (string, error) {
+	return call(h.c, "gh", "GetDeploymentState", []any{sha, environment}, func() (string, error) { return h.r.GetDeploymentState(ctx, sha, environment) })
+}
+
+// CreateCheckRun implements orchestrator.GhRunner.
+func (h *GitHub) CreateCheckRun(ctx context.Context, run github.CheckRun) (string, error) {
+	return call(h.c, "gh", "CreateCheckRun", []any{run}, func() (string, error) { return h.r.CreateCheckRun(ctx, run) })
+}
+
+// SetCommitStatus implements orchestrator.GhRunner.
+func (h *GitHub) SetCommitStatus(ctx context.Context, status github.CommitStatus) error {
+	return do(h.c, "gh", "SetCommitStatus", []any{status}, func() error { return h.r.SetCommitStatus(ctx, status) })
+}
+
+// ListOpenPRs implements orchestrator.GhRunner.
+func (h *GitHub) ListOpenPRs(ctx context.Context, limit int) ([]github.PullRequest, error) {
+	return call(h.c, "gh", "ListOpenPRs", []any{limit}, func() ([]github.PullRequest, error) { return h.r.ListOpenPRs(ctx, limit) })
+}
+
+// ListPRs implements orchestrator.GhRunner.
+func (h *GitHub) ListPRs(ctx context.Context, limit int) ([]github.PullRequest, error) {
+	return call(h.c, "gh", "ListPRs", []any{limit}, func() ([]github.PullRequest, error) { return h.r.ListPRs(ctx, limit) })
+}
+
+// WaitForChecks records the statuses reported while waiting; a replay
+// reports them again, without waiting.
+func (h *GitHub) WaitForChecks(ctx context.Context, prNumber string, timeout time.Duration, onStatusChange func(*github.PRStatus)) (*github.PRStatus, error) {
+	status, updates, err := callWithUpdates(h.c, "gh", "WaitForChecks", []any{prNumber, timeout.String()}, func(report func(json.RawMessage)) (*github.PRStatus, error) {
+		return h.r.WaitForChecks(ctx, prNumber, timeout, func(s *github.PRStatus) {
+			if data, err := json.Marshal(s); err == nil {
+				report(data)
+			}
+			if onStatusChange != nil {
+				onStatusChange(s)
+			}
+		})
+	})
+	for _, update := range updates {
+		var s github.PRStatus
+		if json.Unmarshal(update, &s) == nil && onStatusChange != nil {
+			onStatusChange(&s)
+		}
+	}
+	return status, err
+}
+
+// MergePR implements orchestrator.GhRunner.
+func (h *GitHub) MergePR(ctx context.Context, prNumber, strategy string) error {
+	return do(h.c, "gh", "MergePR", []any{prNumber, strategy}, func() error { return h.r.MergePR(ctx, prNumber, strategy) })
+}
+
+// ClosePR implements orchestrator.GhRunner.
+func (h *GitHub) ClosePR(ctx context.Context, prNumber string, deleteBranch bool) error {
+	return do(h.c, "gh", "ClosePR", []any{prNumber, deleteBranch}, func() error { return h.r.ClosePR(ctx, prNumber, deleteBranch) })
+}
+
+// CreateLabel implements orchestrator.GhRunner.
+func (h *GitHub) CreateLabel(ctx context.Context, name string) error {
+	return do(h.c, "gh", "CreateLabel", []any{name}, func() error { return h.r.CreateLabel(ctx, name) })
+}
+
+// AddLabels implements orchestrator.GhRunner.
+func (h *GitHub) AddLabels(ctx context.Context, prNumber string, labels []string) error {
+	return do(h.c, "gh", "AddLabels", []any{prNumber, labels}, func() error { return h.r.AddLabels(ctx, prNumber, labels) })
+}
+
+// GetIssueState implements orchestrator.GhRunner.
+func (h *GitHub) GetIssueState(ctx context.Context, number string) (string, error) {
+	return call(h.c, "gh", "GetIssueState", []any{number}, func() (string, error) { return h.r.GetIssueState(ctx, number) })
+}
+
+// CommentOnIssue implements orchestrator.GhRunner.
+func (h *GitHub) CommentOnIssue(ctx context.Context, number, body string) (string, error) {
+	return call(h.c, "gh", "CommentOnIssue", []any{number, body}, func() (string, error) { return h.r.CommentOnIssue(ctx, number, body) })
+}
+
+// ListComments implements orchestrator.GhRunner.
+func (h *GitHub) ListComments(ctx context.Context, number string) ([]github.Comment, error) {
+	return call(h.c, "gh", "ListComments", []any{number}, func() ([]github.Comment, error) { return h.r.ListComments(ctx, number) })
+}
+
+// ReRequestReview implements orchestrator.GhRunner.
+func (h *GitHub) ReRequestReview(ctx context.Context, prNumber string) ([]string, error) {
+	return call(h.c, "gh", "ReRequestReview", []any{prNumber}, func() ([]string, error) { return h.r.ReRequestReview(ctx, prNumber) })
+}
+
+// CreateIssue implements orchestrator.GhRunner.
+func (h *GitHub) CreateIssue(ctx context.Context, title, body string) (string, error) {
+	return call(h.c, "gh", "CreateIssue", []any{title}, func() (string, error) {
+		return h.r.CreateIssue(ctx, title, body)
+	})
+}
+
+// CreateGist implements orchestrator.GhRunner.
+func (h *GitHub) CreateGist(ctx context.Context, filename, description, content string) (string, error) {
+	return call(h.c, "gh", "CreateGist", []any{filename, description}, func() (string, error) {
+		return h.r.CreateGist(ctx, filename, description, content)
+	})
+}
+
+// GetLatestRelease implements orchestrator.GhRunner.
+func (h *GitHub) GetLatestRelease(ctx context.Context, owner, repo string) (string, error) {
+	return call(h.c, "gh", "GetLatestRelease", []any{owner, repo}, func() (string, error) { return h.r.GetLatestRelease(ctx, owner, repo) })
+}
+
+// CreateRelease implements orchestrator.GhRunner.
+func (h *GitHub) CreateRelease(ctx context.Context, tag, title, notes, target string) (string, error) {
+	return call(h.c, "gh", "CreateRelease", []any{tag, title, notes, target}, func() (string, error) {
+		return h.r.CreateRelease(ctx, tag, title, notes, target)
+	})
+}
+
+// Claude records the calls to a Claude runner, or replays them.
+type Claude struct {
+	c       *Cassette
+	r       orchestrator.ClaudeRunner
+	pricing *claude.Pricing
+}
+
+// Claude wraps a Claude runner; r is nil when replaying.
+func (c *Cassette) Claude(r orchestrator.ClaudeRunner) *Claude {
+	return &Claude{c: c, r: r}
+}
+
+// CheckAvailable implements orchestrator.ClaudeRunner.
+func (cl *Claude) CheckAvailable() error {
+	return do(cl.c, "claude", "CheckAvailable", nil, func() error { return cl.r.CheckAvailable() })
+}
+
+// SetEnv is passed on without being recorded.
+func (cl *Claude) SetEnv(env []string) {
+	if cl.r != nil {
+		cl.r.SetEnv(env)
+	}
+}
+
+// SetPricing is passed on without being recorded.
+func (cl *Claude) SetPricing(p *claude.Pricing) {
+	cl.pricing = p
+	if cl.r != nil {
+		cl.r.SetPricing(p)
+	}
+}
+
+// SetRetryPolicy is passed on without being recorded.
+func (cl *Claude) SetRetryPolicy(p retry.Policy) {
+	if cl.r != nil {
+		cl.r.SetRetryPolicy(p)
+	}
+}
+
+// Pricing is not recorded: a replay returns what was set.
+func (cl *Claude) Pricing() *claude.Pricing {
+	if cl.r != nil {
+		return cl.r.Pricing()
+	}
+	return cl.pricing
+}
+
+// Run implements orchestrator.ClaudeRunner.
+func (cl *Claude) Run(ctx context.Context, prompt string) (*claude.Result, error) {
+	return call(cl.c, "claude", "Run", []any{prompt}, func() (*claude.Result, error) { return cl.r.Run(ctx, prompt) })
+}
+
+// RunCommit implements orchestrator.ClaudeRunner.
+func (cl *Claude) RunCommit(ctx context.Context, guidance string) (string, error) {
+	return call(cl.c, "claude", "RunCommit", []any{guidance}, func() (string, error) { return cl.r.RunCommit(ctx, guidance) })
+}
+
+// Evaluate implements orchestrator.ClaudeRunner.
+func (cl *Claude) Evaluate(ctx context.Context, goal string) (*claude.Evaluation, error) {
+	return call(cl.c, "claude", "Evaluate", []any{goal}, func() (*claude.Evaluation, error) { return cl.r.Evaluate(ctx, goal) })
+}
+
+var (
+	_ orchestrator.GitRunner    = (*Git)(nil)
+	_ orchestrator.GhRunner     = (*GitHub)(nil)
+	_ orchestrator.ClaudeRunner = (*Claude)(nil)
+)
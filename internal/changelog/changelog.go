@@ -0,0 +1,144 @@
+// Package changelog maintains CHANGELOG.md entries and release versions for autonomous runs.
+package changelog
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// UnreleasedHeading is the section new entries are added under.
+const UnreleasedHeading = "## [Unreleased]"
+
+// Bump is the kind of semantic version increment for a release.
+type Bump int
+
+const (
+	// BumpPatch increments the patch version.
+	BumpPatch Bump = iota
+	// BumpMinor increments the minor version.
+	BumpMinor
+	// BumpMajor increments the major version.
+	BumpMajor
+)
+
+// FormatEntry formats a changelog line for a merged PR.
+func FormatEntry(title, prNumber string) string {
+	if prNumber == "" {
+		return fmt.Sprintf("- %s", title)
+	}
+	return fmt.Sprintf("- %s (#%s)", title, prNumber)
+}
+
+// AddEntry adds a line to the Unreleased section of the changelog at path,
+// creating the file and section if needed.
+func AddEntry(path, entry string) error {
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read changelog: %w", err)
+	}
+
+	updated := Insert(string(content), entry)
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+		return fmt.Errorf("failed to write changelog: %w", err)
+	}
+	return nil
+}
+
+// Insert returns content with entry added to the top of the Unreleased section.
+func Insert(content, entry string) string {
+	if strings.TrimSpace(content) == "" {
+		content = "# CHANGELOG\n"
+	}
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+
+	idx := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == UnreleasedHeading {
+			idx = i
+			break
+		}
+	}
+
+	if idx == -1 {
+		// Add the section below the title, or at the very top without one
+		section := []string{UnreleasedHeading}
+		at := 0
+		if strings.HasPrefix(lines[0], "# ") {
+			section = []string{"", UnreleasedHeading}
+			at = 1
+		}
+		lines = append(lines[:at], append(section, lines[at:]...)...)
+		idx = at + len(section) - 1
+	}
+
+	// Entries go right below the heading, newest first
+	rest := lines[idx+1:]
+	for len(rest) > 0 && strings.TrimSpace(rest[0]) == "" {
+		rest = rest[1:]
+	}
+
+	out := append([]string{}, lines[:idx+1]...)
+	out = append(out, "", entry)
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "- ") {
+		out = append(out, "")
+	}
+	out = append(out, rest...)
+
+	return strings.Join(out, "\n") + "\n"
+}
+
+// DetermineBump picks the version bump from commit titles, following the
+// same conventional commit and gitmoji rules as the release workflow.
+func DetermineBump(titles []string) Bump {
+	majorRe := regexp.MustCompile(`(?i)^(breaking|BREAKING CHANGE|[a-z]+(\([^)]*\))?!:|:boom:)`)
+	minorRe := regexp.MustCompile(`(?i)^(feat|:sparkles:)`)
+
+	bump := BumpPatch
+	for _, title := range titles {
+		if majorRe.MatchString(title) {
+			return BumpMajor
+		}
+		if minorRe.MatchString(title) {
+			bump = BumpMinor
+		}
+	}
+	return bump
+}
+
+// NextVersion returns the version following latest for the given bump.
+// An empty latest version is treated as v0.0.0.
+func NextVersion(latest string, bump Bump) string {
+	v := strings.TrimPrefix(latest, "v")
+	v = strings.Split(v, "-")[0]
+
+	var parts [3]int
+	for i, p := range strings.SplitN(v, ".", 3) {
+		parts[i], _ = strconv.Atoi(p)
+	}
+
+	switch bump {
+	case BumpMajor:
+		parts = [3]int{parts[0] + 1, 0, 0}
+	case BumpMinor:
+		parts = [3]int{parts[0], parts[1] + 1, 0}
+	default:
+		parts[2]++
+	}
+
+	return fmt.Sprintf("v%d.%d.%d", parts[0], parts[1], parts[2])
+}
+
+// ReleaseNotes builds release notes from the changelog entries shipped in a release.
+func ReleaseNotes(entries []string) string {
+	var sb strings.Builder
+	sb.WriteString("## What's Changed\n\n")
+	for _, entry := range entries {
+		sb.WriteString(entry)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n---\n*This release was created automatically by Deep Claude.*\n")
+	return sb.String()
+}
@@ -0,0 +1,89 @@
+package changelog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "empty file",
+			content:  "",
+			expected: "# CHANGELOG\n\n## [Unreleased]\n\n- Add tests (#12)\n",
+		},
+		{
+			name:     "no unreleased section",
+			content:  "# CHANGELOG\n\n## [v0.1.0] - 2025-01-01\n\n- Old entry\n",
+			expected: "# CHANGELOG\n\n## [Unreleased]\n\n- Add tests (#12)\n\n## [v0.1.0] - 2025-01-01\n\n- Old entry\n",
+		},
+		{
+			name:     "existing unreleased entries",
+			content:  "# CHANGELOG\n\n## [Unreleased]\n\n- Earlier change (#11)\n",
+			expected: "# CHANGELOG\n\n## [Unreleased]\n\n- Add tests (#12)\n- Earlier change (#11)\n",
+		},
+		{
+			name:     "no title",
+			content:  "## [v0.1.0]\n",
+			expected: "## [Unreleased]\n\n- Add tests (#12)\n\n## [v0.1.0]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Insert(tt.content, FormatEntry("Add tests", "12"))
+			if result != tt.expected {
+				t.Errorf("Insert() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetermineBump(t *testing.T) {
+	tests := []struct {
+		titles   []string
+		expected Bump
+	}{
+		{[]string{"fix: handle nil"}, BumpPatch},
+		{[]string{"fix: handle nil", "feat: add flag"}, BumpMinor},
+		{[]string{":sparkles: New thing"}, BumpMinor},
+		{[]string{"feat!: drop old API"}, BumpMajor},
+		{[]string{"refactor(core)!: rename"}, BumpMajor},
+		{[]string{"feat: x", "BREAKING CHANGE: y"}, BumpMajor},
+		{nil, BumpPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.titles, ","), func(t *testing.T) {
+			if result := DetermineBump(tt.titles); result != tt.expected {
+				t.Errorf("DetermineBump(%v) = %d, want %d", tt.titles, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	tests := []struct {
+		latest   string
+		bump     Bump
+		expected string
+	}{
+		{"", BumpPatch, "v0.0.1"},
+		{"v0.1.3", BumpPatch, "v0.1.4"},
+		{"v0.1.3", BumpMinor, "v0.2.0"},
+		{"v0.1.3", BumpMajor, "v1.0.0"},
+		{"1.2.3-beta", BumpPatch, "v1.2.4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.latest+"_"+tt.expected, func(t *testing.T) {
+			if result := NextVersion(tt.latest, tt.bump); result != tt.expected {
+				t.Errorf("NextVersion(%q, %d) = %q, want %q", tt.latest, tt.bump, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package claude
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Finding is what one iteration of an analysis run found.
+type Finding struct {
+	Iteration int
+	Output    string
+}
+
+// AnalysisSection tells Claude that the run investigates the code rather
+// than changes it, so its findings are the work.
+func AnalysisSection() Section {
+	return Section{
+		Title: "ANALYSIS TASK",
+		Body: "This run is an investigation, not a change: nothing is committed and no PR is opened, so don't modify the code. " +
+			"Study it, run whatever helps that leaves it as it is (tests, benchmarks, profilers, git history), and report what you find with its evidence: " +
+			"file and line references, measurements, and the commands you ran with their results. " +
+			"Keep the findings so far, open questions and what to look into next in the notes; when the run ends, its report is written from them.",
+	}
+}
+
+// FormatFindings lists the findings of an analysis run by iteration.
+func FormatFindings(findings []Finding) string {
+	var sb strings.Builder
+	for _, f := range findings {
+		fmt.Fprintf(&sb, "### Iteration %d\n\n%s\n\n", f.Iteration, strings.TrimSpace(f.Output))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// ReportPrompt asks Claude for the report an analysis run delivers, from
+// its goal, notes and findings.
+func ReportPrompt(goal, notes string, findings []Finding) string {
+	var sb strings.Builder
+	sb.WriteString("You are writing the report of an investigation that is now finished. Do NOT modify any files.\n\n")
+	sb.WriteString("## GOAL\n\n")
+	sb.WriteString(strings.TrimSpace(goal))
+	sb.WriteString("\n\n")
+	if notes = strings.TrimSpace(notes); notes != "" {
+		sb.WriteString("## NOTES\n\n```\n")
+		sb.WriteString(notes)
+		sb.WriteString("\n```\n\n")
+	}
+	sb.WriteString("## FINDINGS\n\n")
+	sb.WriteString(FormatFindings(findings))
+	sb.WriteString("\n\n## REPORT\n\n")
+	sb.WriteString("Write the report in Markdown, starting with a `# ` title: a summary of the conclusions, then the findings by importance, each with its evidence, ")
+	sb.WriteString("then recommendations and open questions. Only state what the findings and notes support, and where later iterations corrected earlier ones, go with the later. ")
+	sb.WriteString("Respond with the report only.\n")
+	return sb.String()
+}
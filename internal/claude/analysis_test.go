@@ -0,0 +1,38 @@
+package claude
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReportPrompt(t *testing.T) {
+	findings := []Finding{
+		{Iteration: 1, Output: "  The cache is keyed by timestamp.\n"},
+		{Iteration: 3, Output: "Hit rate is 12% under load."},
+	}
+	tests := []struct {
+		name  string
+		notes string
+		want  []string
+		not   []string
+	}{
+		{"with notes", "- look at eviction next", []string{"## NOTES", "- look at eviction next", "### Iteration 1\n\nThe cache is keyed by timestamp.\n\n### Iteration 3"}, nil},
+		{"without notes", " \n", []string{"## GOAL\n\nAudit the cache", "### Iteration 3\n\nHit rate is 12% under load."}, []string{"## NOTES"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := ReportPrompt("Audit the cache\n", tt.notes, findings)
+			for _, want := range tt.want {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("prompt is missing %q:\n%s", want, prompt)
+				}
+			}
+			for _, not := range tt.not {
+				if strings.Contains(prompt, not) {
+					t.Errorf("prompt has %q:\n%s", not, prompt)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package claude
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// iterationHeadingRe matches the headings notes.AppendIteration writes.
+var iterationHeadingRe = regexp.MustCompile(`^## Iteration (\d+)`)
+
+// EstimateTokens approximates the token count of text at ~4 characters per token.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// BuildPromptWithBudget builds the prompt like BuildPrompt, trimming notes
+// and extra sections so the result stays within maxTokens. A budget of 0
+// disables trimming. The boolean reports whether anything was trimmed.
+func BuildPromptWithBudget(maxTokens int, userPrompt, notesContent string, signal Signal, iteration int, sections ...Section) (string, bool) {
+	full := BuildPrompt(userPrompt, notesContent, signal, iteration, sections...)
+	if maxTokens <= 0 || EstimateTokens(full) <= maxTokens {
+		return full, false
+	}
+
+	// Whatever the fixed parts of the prompt leave over is shared between
+	// the sections and the notes; notes also get anything sections don't use.
+	// Placeholder bodies account for the headings wrapped around each part.
+	placeholders := make([]Section, len(sections))
+	for i, section := range sections {
+		placeholders[i] = Section{Title: section.Title, Body: " "}
+	}
+	remaining := maxTokens - EstimateTokens(BuildPrompt(userPrompt, " ", signal, iteration, placeholders...))
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	share := remaining / (len(sections) + 1)
+	fitted := make([]Section, len(sections))
+	for i, section := range sections {
+		fitted[i] = Section{Title: section.Title, Body: TruncateTokens(section.Body, share)}
+		remaining -= EstimateTokens(fitted[i].Body)
+	}
+
+	return BuildPrompt(userPrompt, FitNotes(notesContent, remaining), signal, iteration, fitted...), true
+}
+
+// TruncateTokens cuts text to roughly maxTokens, marking what was dropped.
+func TruncateTokens(text string, maxTokens int) string {
+	if EstimateTokens(text) <= maxTokens {
+		return text
+	}
+	if maxTokens <= 0 {
+		return ""
+	}
+
+	runes := []rune(text)
+	keep := maxTokens * 4
+	return string(runes[:keep]) + fmt.Sprintf("\n... [truncated %d tokens]", EstimateTokens(string(runes[keep:])))
+}
+
+// omittedNote is appended to trimmed notes when sections had to be dropped.
+const omittedNote = "\n(%d older sections omitted to fit the context budget)\n"
+
+// notesSection is a "## " headed block of the notes file.
+type notesSection struct {
+	text      string
+	iteration int // 0 for sections that aren't iteration summaries
+}
+
+// FitNotes trims notes to roughly maxTokens. Sections that aren't iteration
+// summaries (current status, next steps) and the most recent iteration
+// summaries are kept in full; older summaries are reduced to their heading
+// and first line, and dropped entirely if even that doesn't fit.
+func FitNotes(content string, maxTokens int) string {
+	if EstimateTokens(content) <= maxTokens {
+		return content
+	}
+
+	sections := splitNotes(content)
+
+	// Priority order: non-iteration sections, then newest iterations first
+	order := make([]int, len(sections))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ia, ib := sections[order[a]].iteration, sections[order[b]].iteration
+		if (ia == 0) != (ib == 0) {
+			return ia == 0
+		}
+		return ia > ib
+	})
+
+	// Leave room for the omission note
+	kept := make([]string, len(sections))
+	budget := maxTokens - EstimateTokens(fmt.Sprintf(omittedNote, len(sections)))
+	omitted := 0
+	for _, idx := range order {
+		text := sections[idx].text
+		if tokens := EstimateTokens(text); tokens <= budget {
+			kept[idx] = text
+			budget -= tokens
+			continue
+		}
+		if summary := summarizeSection(text); EstimateTokens(summary) <= budget {
+			kept[idx] = summary
+			budget -= EstimateTokens(summary)
+			continue
+		}
+		omitted++
+	}
+
+	var sb strings.Builder
+	for _, text := range kept {
+		sb.WriteString(text)
+	}
+	if omitted > 0 {
+		sb.WriteString(fmt.Sprintf(omittedNote, omitted))
+	}
+	return sb.String()
+}
+
+// splitNotes splits notes into blocks that each start at a "## " heading.
+func splitNotes(content string) []notesSection {
+	var sections []notesSection
+	var current strings.Builder
+	iteration := 0
+
+	flush := func() {
+		if current.Len() > 0 {
+			sections = append(sections, notesSection{text: current.String(), iteration: iteration})
+			current.Reset()
+		}
+	}
+
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if strings.HasPrefix(line, "## ") {
+			flush()
+			iteration = 0
+			if m := iterationHeadingRe.FindStringSubmatch(line); m != nil {
+				iteration, _ = strconv.Atoi(m[1])
+			}
+		}
+		current.WriteString(line)
+	}
+	flush()
+
+	return sections
+}
+
+// summarizeSection reduces a section to its heading and first line of content.
+func summarizeSection(text string) string {
+	lines := strings.Split(text, "\n")
+	heading := strings.TrimRight(lines[0], " ")
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "---" {
+			continue
+		}
+		if len(line) > 120 {
+			// Don't cut a multi-byte character in half
+			cut := 120
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			line = line[:cut] + "..."
+		}
+		return heading + "\n" + line + " (summarized)\n\n"
+	}
+	return heading + "\n\n"
+}
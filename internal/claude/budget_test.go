@@ -0,0 +1,118 @@
+package claude
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abc", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{strings.Repeat("x", 400), 100},
+	}
+
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%d chars) = %d, want %d", len(tt.text), got, tt.want)
+		}
+	}
+}
+
+func TestTruncateTokens(t *testing.T) {
+	text := strings.Repeat("a", 100)
+
+	if got := TruncateTokens(text, 50); got != text {
+		t.Error("TruncateTokens() should leave text within budget unchanged")
+	}
+
+	got := TruncateTokens(text, 10)
+	if !strings.HasPrefix(got, strings.Repeat("a", 40)+"\n... [truncated 15 tokens]") {
+		t.Errorf("TruncateTokens() = %q", got)
+	}
+}
+
+func buildNotes(iterations int) string {
+	var sb strings.Builder
+	for i := iterations; i >= 1; i-- {
+		sb.WriteString(fmt.Sprintf("---\n\n## Iteration %d Summary (2025-01-01)\n\nDid work %d.\n%s\n\n", i, i, strings.Repeat("detail ", 40)))
+	}
+	sb.WriteString("# Shared Task Notes\n\n## Current Status\n- In progress\n")
+	return sb.String()
+}
+
+func TestSummarizeSectionKeepsRunesWhole(t *testing.T) {
+	// 119 bytes, then a 3-byte character across the 120-byte cut
+	line := strings.Repeat("a", 119) + strings.Repeat("€", 10)
+	got := summarizeSection("## Iteration 1 Summary\n" + line)
+	want := "## Iteration 1 Summary\n" + strings.Repeat("a", 119) + "... (summarized)\n\n"
+	if got != want {
+		t.Errorf("summarizeSection() = %q, want %q", got, want)
+	}
+	if !utf8.ValidString(got) {
+		t.Error("summarizeSection() cut a character in half")
+	}
+}
+
+func TestFitNotes(t *testing.T) {
+	notes := buildNotes(5)
+
+	if got := FitNotes(notes, EstimateTokens(notes)); got != notes {
+		t.Error("FitNotes() should leave notes within budget unchanged")
+	}
+
+	got := FitNotes(notes, 260)
+	if EstimateTokens(got) > 260 {
+		t.Errorf("FitNotes() result has %d tokens, want <= 260", EstimateTokens(got))
+	}
+	if !strings.Contains(got, "## Current Status\n- In progress") {
+		t.Error("FitNotes() should keep non-iteration sections")
+	}
+	if !strings.Contains(got, "Did work 5.\n"+strings.Repeat("detail ", 40)) {
+		t.Error("FitNotes() should keep the most recent iteration in full")
+	}
+	if !strings.Contains(got, "## Iteration 1 Summary (2025-01-01)\nDid work 1. (summarized)") {
+		t.Errorf("FitNotes() should summarize older iterations, got:\n%s", got)
+	}
+	if strings.Index(got, "Iteration 5") > strings.Index(got, "Iteration 1") {
+		t.Error("FitNotes() should preserve the original section order")
+	}
+}
+
+func TestFitNotesOmitsWhenTiny(t *testing.T) {
+	got := FitNotes(buildNotes(3), 10)
+	if !strings.Contains(got, "sections omitted to fit the context budget") {
+		t.Errorf("FitNotes() should note omitted sections, got:\n%s", got)
+	}
+}
+
+func TestBuildPromptWithBudget(t *testing.T) {
+	done := Signal{Phrases: []string{"DONE"}}
+	notes := buildNotes(10)
+	sections := []Section{{Title: "REPOSITORY MAP", Body: strings.Repeat("- file.go\n", 500)}}
+
+	full, trimmed := BuildPromptWithBudget(0, "Goal", notes, done, 11, sections...)
+	if trimmed || full != BuildPrompt("Goal", notes, done, 11, sections...) {
+		t.Error("BuildPromptWithBudget() with no budget should match BuildPrompt")
+	}
+
+	got, trimmed := BuildPromptWithBudget(1500, "Goal", notes, done, 11, sections...)
+	if !trimmed {
+		t.Error("BuildPromptWithBudget() should report trimming")
+	}
+	if EstimateTokens(got) > 1500 {
+		t.Errorf("prompt has %d tokens, want <= 1500", EstimateTokens(got))
+	}
+	for _, want := range []string{"PRIMARY GOAL", "Goal", "REPOSITORY MAP", "[truncated", "Did work 10."} {
+		if !strings.Contains(got, want) {
+			t.Errorf("trimmed prompt missing %q", want)
+		}
+	}
+}
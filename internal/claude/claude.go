@@ -3,24 +3,66 @@ package claude
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/guzus/deep-claude/internal/retry"
 )
 
 // Client handles Claude Code CLI operations.
 type Client struct {
 	workDir   string
 	extraArgs []string
+	env       []string
+	pricing   *Pricing
+	policy    retry.Policy
 }
 
 // Result represents the response from Claude Code.
 type Result struct {
 	Output    string
 	Cost      float64
+	Usage     Usage
 	IsError   bool
 	RawOutput string
+
+	// CostEstimated is set when Cost was computed from Usage because the
+	// run didn't report one
+	CostEstimated bool
+
+	// Retries is how many times the run was retried after a transient API
+	// error; Cost includes the failed attempts
+	Retries int
+}
+
+// transientMarkers are substrings of Claude Code errors caused by the API
+// being briefly unavailable, which are worth retrying.
+var transientMarkers = []string{
+	"overloaded",
+	"rate_limit_error",
+	"rate limit",
+	"internal server error",
+	"api_error",
+	"connection error",
+	"econnreset",
+	"socket hang up",
+}
+
+// IsTransient reports whether Claude Code output indicates a transient API
+// error rather than a problem with the task.
+func IsTransient(output string) bool {
+	lower := strings.ToLower(output)
+	for _, marker := range transientMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
 }
 
 // NewClient creates a new Claude Code client.
@@ -28,9 +70,48 @@ func NewClient(workDir string, extraArgs []string) *Client {
 	return &Client{
 		workDir:   workDir,
 		extraArgs: extraArgs,
+		policy:    retry.Defaults().Claude,
 	}
 }
 
+// SetEnv adds variables to the environment Claude runs with.
+func (c *Client) SetEnv(env []string) {
+	c.env = env
+}
+
+// SetPricing sets the prices used to estimate cost from token usage when
+// a run reports none.
+func (c *Client) SetPricing(p *Pricing) {
+	c.pricing = p
+}
+
+// SetRetryPolicy sets how runs that fail with a transient API error are
+// retried.
+func (c *Client) SetRetryPolicy(p retry.Policy) {
+	c.policy = p
+}
+
+// Pricing returns the prices set with SetPricing, or nil.
+func (c *Client) Pricing() *Pricing {
+	return c.pricing
+}
+
+// estimateCost fills in a missing cost from the token usage.
+func (c *Client) estimateCost(result *Result) {
+	if result.Cost == 0 && c.pricing != nil {
+		result.Cost = c.pricing.Cost(result.Usage)
+		result.CostEstimated = result.Cost > 0
+	}
+}
+
+// environ returns the environment for Claude processes, or nil to inherit ours.
+func (c *Client) environ() []string {
+	if len(c.env) == 0 {
+		return nil
+	}
+	return append(os.Environ(), c.env...)
+}
+
 // CheckAvailable verifies Claude Code CLI is available.
 func CheckAvailable() error {
 	cmd := exec.Command("claude", "--version")
@@ -40,8 +121,37 @@ func CheckAvailable() error {
 	return nil
 }
 
-// Run executes Claude Code with the given prompt.
-func (c *Client) Run(prompt string) (*Result, error) {
+// CheckAvailable is CheckAvailable, for callers holding a client.
+func (c *Client) CheckAvailable() error {
+	return CheckAvailable()
+}
+
+// errTransient marks a run to retry.
+var errTransient = errors.New("transient Claude API error")
+
+// Run executes Claude Code with the given prompt, retrying it after a
+// transient API error. Canceling ctx kills Claude Code and returns ctx's
+// error with what the run cost so far.
+func (c *Client) Run(ctx context.Context, prompt string) (*Result, error) {
+	var result *Result
+	var cost float64
+	attempts := 0
+	_ = c.policy.Do(ctx, func() error {
+		attempts++
+		result = c.run(ctx, prompt)
+		cost += result.Cost
+		if result.IsError && IsTransient(result.Output) {
+			return errTransient
+		}
+		return nil
+	}, nil, nil)
+	result.Cost = cost
+	result.Retries = attempts - 1
+	return result, ctx.Err()
+}
+
+// run executes Claude Code once.
+func (c *Client) run(ctx context.Context, prompt string) *Result {
 	args := []string{
 		"-p", prompt,
 		"--output-format", "json",
@@ -49,8 +159,9 @@ func (c *Client) Run(prompt string) (*Result, error) {
 	}
 	args = append(args, c.extraArgs...)
 
-	cmd := exec.Command("claude", args...)
+	cmd := exec.CommandContext(ctx, "claude", args...)
 	cmd.Dir = c.workDir
+	cmd.Env = c.environ()
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -70,6 +181,7 @@ func (c *Client) Run(prompt string) (*Result, error) {
 			result.Output = rawOutput
 		}
 	}
+	c.estimateCost(result)
 
 	// Check for errors
 	if err != nil {
@@ -77,14 +189,14 @@ func (c *Client) Run(prompt string) (*Result, error) {
 		if stderr.Len() > 0 {
 			result.Output = stderr.String()
 		}
-		return result, nil
 	}
-
-	return result, nil
+	return result
 }
 
 // RunCommit asks Claude to create a commit message and commit.
-func (c *Client) RunCommit() (string, error) {
+// Guidance, if non-empty, is appended to the instructions (e.g. commit
+// message rules or feedback on a rejected message).
+func (c *Client) RunCommit(ctx context.Context, guidance string) (string, error) {
 	prompt := `Review the staged changes and create an appropriate commit.
 
 Instructions:
@@ -93,6 +205,9 @@ Instructions:
 3. The message should explain WHAT changed and WHY, not just describe the diff
 4. Commit the changes with 'git commit -m "your message"'
 5. Return the commit message you used`
+	if guidance != "" {
+		prompt += "\n\nCommit message requirements:\n" + guidance
+	}
 
 	args := []string{
 		"-p", prompt,
@@ -101,8 +216,9 @@ Instructions:
 		"--allowedTools", "Bash(git commit:*),Bash(git diff:*),Bash(git status:*)",
 	}
 
-	cmd := exec.Command("claude", args...)
+	cmd := exec.CommandContext(ctx, "claude", args...)
 	cmd.Dir = c.workDir
+	cmd.Env = c.environ()
 
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
@@ -136,12 +252,14 @@ func parseClaudeOutput(output string, result *Result) error {
 				Type    string  `json:"type"`
 				Result  string  `json:"result"`
 				Cost    float64 `json:"total_cost_usd"`
+				Usage   Usage   `json:"usage"`
 				IsError bool    `json:"is_error"`
 			}
 			if err := json.Unmarshal(arrayResult[i], &item); err == nil {
 				if item.Type == "result" || item.Result != "" {
 					result.Output = item.Result
 					result.Cost = item.Cost
+					result.Usage = item.Usage
 					result.IsError = item.IsError
 					return nil
 				}
@@ -149,10 +267,12 @@ func parseClaudeOutput(output string, result *Result) error {
 		}
 		// If no result found, try to get cost from last item
 		var lastItem struct {
-			Cost float64 `json:"total_cost_usd"`
+			Cost  float64 `json:"total_cost_usd"`
+			Usage Usage   `json:"usage"`
 		}
 		_ = json.Unmarshal(arrayResult[len(arrayResult)-1], &lastItem)
 		result.Cost = lastItem.Cost
+		result.Usage = lastItem.Usage
 		return nil
 	}
 
@@ -160,11 +280,13 @@ func parseClaudeOutput(output string, result *Result) error {
 	var singleResult struct {
 		Result  string  `json:"result"`
 		Cost    float64 `json:"total_cost_usd"`
+		Usage   Usage   `json:"usage"`
 		IsError bool    `json:"is_error"`
 	}
 	if err := json.Unmarshal([]byte(output), &singleResult); err == nil {
 		result.Output = singleResult.Result
 		result.Cost = singleResult.Cost
+		result.Usage = singleResult.Usage
 		result.IsError = singleResult.IsError
 		return nil
 	}
@@ -172,8 +294,15 @@ func parseClaudeOutput(output string, result *Result) error {
 	return fmt.Errorf("could not parse output as JSON")
 }
 
+// Section is an extra block of context added to the prompt.
+type Section struct {
+	Title string
+	Body  string
+}
+
 // BuildPrompt constructs the full prompt with workflow context.
-func BuildPrompt(userPrompt, notesContent, completionSignal string, iteration int) string {
+// Extra sections are placed after the primary goal.
+func BuildPrompt(userPrompt, notesContent string, signal Signal, iteration int, sections ...Section) string {
 	var sb strings.Builder
 
 	sb.WriteString("## CONTINUOUS WORKFLOW CONTEXT\n\n")
@@ -185,13 +314,15 @@ func BuildPrompt(userPrompt, notesContent, completionSignal string, iteration in
 	sb.WriteString("\n")
 	sb.WriteString("- Focus on making incremental progress - you don't need to complete everything in one go\n")
 	sb.WriteString("- Your changes will be committed and a PR created automatically\n")
-	sb.WriteString("- The next iteration will continue your work based on the notes you leave\n\n")
+	sb.WriteString("- The next iteration will continue your work based on the notes you leave\n")
+	sb.WriteString("- If you cannot make progress without a human (e.g. missing credentials or ambiguous requirements), ")
+	sb.WriteString("end your response with the line \"" + StatusPrefix + " " + BlockedStatus + ": <what you need>\" and the loop will wait for an answer\n\n")
 
-	if completionSignal != "" {
+	if !signal.IsZero() {
 		sb.WriteString("**Project Completion Signal**: If you believe the ENTIRE project goal has been fully achieved ")
-		sb.WriteString("and no more iterations are needed, include this exact phrase in your response: \"")
-		sb.WriteString(completionSignal)
-		sb.WriteString("\"\n\n")
+		sb.WriteString("and no more iterations are needed, ")
+		sb.WriteString(signal.instruction())
+		sb.WriteString("\n\n")
 	}
 
 	sb.WriteString("---\n\n")
@@ -199,6 +330,16 @@ func BuildPrompt(userPrompt, notesContent, completionSignal string, iteration in
 	sb.WriteString(userPrompt)
 	sb.WriteString("\n\n")
 
+	for _, section := range sections {
+		if section.Body == "" {
+			continue
+		}
+		sb.WriteString("---\n\n")
+		sb.WriteString("## " + section.Title + "\n\n")
+		sb.WriteString(strings.TrimRight(section.Body, "\n"))
+		sb.WriteString("\n\n")
+	}
+
 	if notesContent != "" {
 		sb.WriteString("---\n\n")
 		sb.WriteString("## CONTEXT FROM PREVIOUS ITERATION\n\n")
@@ -219,11 +360,3 @@ func BuildPrompt(userPrompt, notesContent, completionSignal string, iteration in
 
 	return sb.String()
 }
-
-// ContainsCompletionSignal checks if the output contains the completion signal.
-func ContainsCompletionSignal(output, signal string) bool {
-	if signal == "" {
-		return false
-	}
-	return strings.Contains(output, signal)
-}
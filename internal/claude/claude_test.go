@@ -1,41 +1,69 @@
 package claude
 
 import (
+	"regexp"
 	"strings"
 	"testing"
 )
 
-func TestContainsCompletionSignal(t *testing.T) {
+func TestSignalMatches(t *testing.T) {
+	phrase := Signal{Phrases: []string{"DEEP_CLAUDE_PROJECT_COMPLETE"}}
 	tests := []struct {
+		name     string
 		output   string
-		signal   string
+		signal   Signal
 		expected bool
 	}{
-		{"The project is done. DEEP_CLAUDE_PROJECT_COMPLETE", "DEEP_CLAUDE_PROJECT_COMPLETE", true},
-		{"Normal output without signal", "DEEP_CLAUDE_PROJECT_COMPLETE", false},
-		{"", "DEEP_CLAUDE_PROJECT_COMPLETE", false},
-		{"DEEP_CLAUDE_PROJECT_COMPLETE", "", false},
-		{"", "", false},
-		{"Contains CUSTOM_SIGNAL in text", "CUSTOM_SIGNAL", true},
+		{"phrase", "The project is done. DEEP_CLAUDE_PROJECT_COMPLETE", phrase, true},
+		{"no phrase", "Normal output without signal", phrase, false},
+		{"empty output", "", phrase, false},
+		{"no signal", "DEEP_CLAUDE_PROJECT_COMPLETE", Signal{}, false},
+		{"empty phrase", "anything", Signal{Phrases: []string{""}}, false},
+		{"second phrase", "Contains ALL_BUGS_FIXED in text", Signal{Phrases: []string{"DONE!", "ALL_BUGS_FIXED"}}, true},
+		{"pattern", "Coverage is now 92%", Signal{Pattern: regexp.MustCompile(`coverage is now (9\d|100)%`)}, false},
+		{"pattern case", "Coverage is now 92%", Signal{Pattern: regexp.MustCompile(`(?i)coverage is now (9\d|100)%`)}, true},
+		{"summary", "Done.\n\nSTATUS: DEEP_CLAUDE_PROJECT_COMPLETE\n", Signal{Phrases: phrase.Phrases, InSummary: true}, true},
+		{"summary in bold", "Done.\n**STATUS: DEEP_CLAUDE_PROJECT_COMPLETE**", Signal{Phrases: phrase.Phrases, InSummary: true}, true},
+		{"phrase outside summary", "I won't say DEEP_CLAUDE_PROJECT_COMPLETE yet.\nSTATUS: in progress", Signal{Phrases: phrase.Phrases, InSummary: true}, false},
+		{"no summary", "DEEP_CLAUDE_PROJECT_COMPLETE", Signal{Phrases: phrase.Phrases, InSummary: true}, false},
+		{"summary pattern", "STATUS: complete", Signal{Pattern: regexp.MustCompile(`^complete$`), InSummary: true}, true},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.output[:min(20, len(tt.output))], func(t *testing.T) {
-			result := ContainsCompletionSignal(tt.output, tt.signal)
-			if result != tt.expected {
-				t.Errorf("ContainsCompletionSignal(%q, %q) = %v, want %v", tt.output, tt.signal, result, tt.expected)
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.signal.Matches(tt.output); got != tt.expected {
+				t.Errorf("Matches(%q) = %v, want %v", tt.output, got, tt.expected)
 			}
 		})
 	}
 }
 
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected bool
+	}{
+		{`API Error: 529 {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, true},
+		{"API Error: Connection error.", true},
+		{"Error: request failed: ECONNRESET", true},
+		{"Tests failed: 3 of 12", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsTransient(tt.output); got != tt.expected {
+			t.Errorf("IsTransient(%q) = %v, want %v", tt.output, got, tt.expected)
+		}
+	}
+}
+
 func TestBuildPrompt(t *testing.T) {
 	userPrompt := "Add test coverage"
 	notesContent := "Previous work completed"
 	completionSignal := "DONE"
 	iteration := 3
 
-	result := BuildPrompt(userPrompt, notesContent, completionSignal, iteration)
+	result := BuildPrompt(userPrompt, notesContent, Signal{Phrases: []string{completionSignal}}, iteration)
 
 	// Check that key elements are present
 	if !strings.Contains(result, "CONTINUOUS WORKFLOW CONTEXT") {
@@ -72,7 +100,7 @@ func TestBuildPrompt(t *testing.T) {
 }
 
 func TestBuildPromptWithoutNotes(t *testing.T) {
-	result := BuildPrompt("Test prompt", "", "COMPLETE", 1)
+	result := BuildPrompt("Test prompt", "", Signal{Phrases: []string{"COMPLETE"}}, 1)
 
 	// Should not contain previous iteration section if no notes
 	if strings.Contains(result, "CONTEXT FROM PREVIOUS ITERATION") {
@@ -81,10 +109,85 @@ func TestBuildPromptWithoutNotes(t *testing.T) {
 }
 
 func TestBuildPromptWithoutCompletionSignal(t *testing.T) {
-	result := BuildPrompt("Test prompt", "", "", 1)
+	result := BuildPrompt("Test prompt", "", Signal{}, 1)
 
 	// Should not contain completion signal section if empty
 	if strings.Contains(result, "Project Completion Signal") {
 		t.Error("prompt should not contain completion signal section when signal is empty")
 	}
 }
+
+func TestBuildPromptWithSummarySignal(t *testing.T) {
+	result := BuildPrompt("Test prompt", "", Signal{Phrases: []string{"DONE", "FINISHED"}, InSummary: true}, 1)
+	if !strings.Contains(result, `end your response with this exact line: "STATUS: DONE"`) {
+		t.Error("prompt should ask for the first phrase on the status line")
+	}
+
+	result = BuildPrompt("Test prompt", "", Signal{Pattern: regexp.MustCompile(`ALL \d+ TESTS PASS`)}, 1)
+	if !strings.Contains(result, "matching the regular expression `ALL \\d+ TESTS PASS`") {
+		t.Error("prompt should describe the pattern when there is no phrase")
+	}
+}
+
+func TestBuildPromptWithSections(t *testing.T) {
+	result := BuildPrompt("Test prompt", "", Signal{}, 1,
+		Section{Title: "REPOSITORY MAP", Body: "- internal/ (3 files)\n"},
+		Section{Title: "EMPTY", Body: ""},
+	)
+
+	if !strings.Contains(result, "## REPOSITORY MAP\n\n- internal/ (3 files)") {
+		t.Error("prompt should contain the extra section")
+	}
+	if strings.Contains(result, "## EMPTY") {
+		t.Error("prompt should skip sections with an empty body")
+	}
+	if strings.Index(result, "REPOSITORY MAP") < strings.Index(result, "PRIMARY GOAL") {
+		t.Error("extra sections should follow the primary goal")
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	tests := []struct {
+		output string
+		reason string
+		ok     bool
+	}{
+		{"Tried the API.\n\nSTATUS: BLOCKED: need a STRIPE_API_KEY", "need a STRIPE_API_KEY", true},
+		{"**STATUS: BLOCKED - should v1 keep the old format?**", "should v1 keep the old format?", true},
+		{"STATUS: BLOCKED", "no reason given", true},
+		{"STATUS: BLOCKEDNESS is fine", "", false},
+		{"I was BLOCKED for a while.\nSTATUS: in progress", "", false},
+		{"STATUS: BLOCKED: keys\nbut then I carried on", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		reason, ok := Blocked(tt.output)
+		if reason != tt.reason || ok != tt.ok {
+			t.Errorf("Blocked(%q) = %q, %v, want %q, %v", tt.output, reason, ok, tt.reason, tt.ok)
+		}
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	tests := []struct {
+		output string
+		value  float64
+		ok     bool
+	}{
+		{"Done.\nCONFIDENCE: 0.85\nSTATUS: in progress", 0.85, true},
+		{"**CONFIDENCE: 70%**", 0.7, true},
+		{"CONFIDENCE: 90", 0.9, true},
+		{"CONFIDENCE: 0.2\nThen I fixed it.\nCONFIDENCE: 0.9", 0.9, true},
+		{"CONFIDENCE: high", 0, false},
+		{"I have some confidence: 0.9", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		value, ok := Confidence(tt.output)
+		if value != tt.value || ok != tt.ok {
+			t.Errorf("Confidence(%q) = %v, %v, want %v, %v", tt.output, value, ok, tt.value, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package claude
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Evaluation is Claude's structured assessment of whether the goal is met.
+type Evaluation struct {
+	Complete      bool    `json:"complete"`
+	Confidence    float64 `json:"confidence"`
+	RemainingWork string  `json:"remaining_work"`
+	Cost          float64 `json:"-"`
+}
+
+// Evaluate asks Claude, in a separate read-only call, whether the project
+// goal has been fully achieved in the current state of the repository.
+func (c *Client) Evaluate(ctx context.Context, goal string) (*Evaluation, error) {
+	prompt := `You are evaluating the progress of an autonomous development loop. Do NOT modify any files.
+
+## PROJECT GOAL
+
+` + goal + `
+
+## TASK
+
+Inspect the repository (source files, git log, SHARED_TASK_NOTES.md) and decide whether the ENTIRE project goal above has been fully achieved, so that no further iterations are needed.
+
+Respond with ONLY a JSON object, no other text:
+{"complete": true|false, "confidence": <number between 0 and 1>, "remaining_work": "<short description of what is left, empty if complete>"}`
+
+	result, err := c.runReadOnly(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run Claude evaluation: %w", err)
+	}
+
+	eval, err := ParseEvaluation(result.Output)
+	if err != nil {
+		return nil, err
+	}
+	eval.Cost = result.Cost
+	return eval, nil
+}
+
+// readOnlyTools lets Claude inspect the repository without changing it.
+const readOnlyTools = "Read,Grep,Glob,Bash(git log:*),Bash(git diff:*),Bash(git status:*),Bash(git show:*)"
+
+// runReadOnly runs Claude with only read-only tools allowed, for calls that
+// assess the repository rather than work on it.
+func (c *Client) runReadOnly(ctx context.Context, prompt string) (*Result, error) {
+	args := []string{
+		"-p", prompt,
+		"--output-format", "json",
+		"--dangerously-skip-permissions",
+		"--allowedTools", readOnlyTools,
+	}
+
+	cmd := exec.CommandContext(ctx, "claude", args...)
+	cmd.Dir = c.workDir
+	cmd.Env = c.environ()
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	var result Result
+	if err := parseClaudeOutput(stdout.String(), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse output: %w", err)
+	}
+	c.estimateCost(&result)
+	return &result, nil
+}
+
+// ParseEvaluation extracts the evaluation JSON object from Claude's reply,
+// tolerating surrounding prose or a code fence.
+func ParseEvaluation(output string) (*Evaluation, error) {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no evaluation JSON found in output")
+	}
+
+	var eval Evaluation
+	if err := json.Unmarshal([]byte(output[start:end+1]), &eval); err != nil {
+		return nil, fmt.Errorf("failed to parse evaluation: %w", err)
+	}
+	if eval.Confidence < 0 || eval.Confidence > 1 {
+		return nil, fmt.Errorf("evaluation confidence %v is outside [0, 1]", eval.Confidence)
+	}
+	return &eval, nil
+}
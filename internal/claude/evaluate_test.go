@@ -0,0 +1,39 @@
+package claude
+
+import "testing"
+
+func TestParseEvaluation(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		complete   bool
+		confidence float64
+		remaining  string
+		wantErr    bool
+	}{
+		{"plain json", `{"complete": true, "confidence": 0.9, "remaining_work": ""}`, true, 0.9, "", false},
+		{"code fence", "```json\n{\"complete\": false, \"confidence\": 0.6, \"remaining_work\": \"add docs\"}\n```", false, 0.6, "add docs", false},
+		{"surrounding prose", `Here is my assessment: {"complete": false, "confidence": 0.3, "remaining_work": "tests"} Thanks.`, false, 0.3, "tests", false},
+		{"no json", "The project is done.", false, 0, "", true},
+		{"invalid json", `{"complete": yes}`, false, 0, "", true},
+		{"confidence out of range", `{"complete": true, "confidence": 90}`, false, 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eval, err := ParseEvaluation(tt.output)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseEvaluation() expected error, got %+v", eval)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseEvaluation() unexpected error: %v", err)
+			}
+			if eval.Complete != tt.complete || eval.Confidence != tt.confidence || eval.RemainingWork != tt.remaining {
+				t.Errorf("ParseEvaluation() = %+v", eval)
+			}
+		})
+	}
+}
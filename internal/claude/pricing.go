@@ -0,0 +1,125 @@
+package claude
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Usage is the token usage Claude reports for a run.
+type Usage struct {
+	InputTokens         int `json:"input_tokens"`
+	OutputTokens        int `json:"output_tokens"`
+	CacheCreationTokens int `json:"cache_creation_input_tokens"`
+	CacheReadTokens     int `json:"cache_read_input_tokens"`
+}
+
+// Pricing converts token usage to cost, in USD per million tokens.
+type Pricing struct {
+	Input      float64
+	Output     float64
+	CacheWrite float64
+	CacheRead  float64
+}
+
+// Prices for the model families, used when a run reports tokens but no cost.
+var modelPricing = map[string]Pricing{
+	"opus":   {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5},
+	"sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"haiku":  {Input: 0.8, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+}
+
+// Cost returns the cost of the usage in USD.
+func (p Pricing) Cost(u Usage) float64 {
+	return (float64(u.InputTokens)*p.Input +
+		float64(u.OutputTokens)*p.Output +
+		float64(u.CacheCreationTokens)*p.CacheWrite +
+		float64(u.CacheReadTokens)*p.CacheRead) / 1e6
+}
+
+// String renders the pricing in the format accepted by ParsePricing.
+func (p Pricing) String() string {
+	return fmt.Sprintf("input=%g,output=%g,cache_write=%g,cache_read=%g", p.Input, p.Output, p.CacheWrite, p.CacheRead)
+}
+
+// LookupPricing returns the built-in pricing for a model name such as
+// "sonnet" or "claude-opus-4-1", matched by model family.
+func LookupPricing(model string) (Pricing, bool) {
+	model = strings.ToLower(model)
+	for family, p := range modelPricing {
+		if strings.Contains(model, family) {
+			return p, true
+		}
+	}
+	return Pricing{}, false
+}
+
+// ParsePricing parses "input=3,output=15,cache_write=3.75,cache_read=0.3"
+// (USD per million tokens). input and output are required; cache prices
+// left out are charged at the input price.
+func ParsePricing(spec string) (Pricing, error) {
+	p := Pricing{CacheWrite: -1, CacheRead: -1}
+	fields := map[string]*float64{
+		"input":       &p.Input,
+		"output":      &p.Output,
+		"cache_write": &p.CacheWrite,
+		"cache_read":  &p.CacheRead,
+	}
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		key = strings.TrimSpace(key)
+		field, known := fields[key]
+		if !ok || !known {
+			return Pricing{}, fmt.Errorf("invalid pricing %q: expected key=price pairs with keys %s", spec, pricingKeys(fields))
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || price < 0 {
+			return Pricing{}, fmt.Errorf("invalid pricing %q: %s must be a non-negative number", spec, key)
+		}
+		*field = price
+		seen[key] = true
+	}
+
+	if !seen["input"] || !seen["output"] {
+		return Pricing{}, fmt.Errorf("invalid pricing %q: input and output prices are required", spec)
+	}
+	if p.CacheWrite < 0 {
+		p.CacheWrite = p.Input
+	}
+	if p.CacheRead < 0 {
+		p.CacheRead = p.Input
+	}
+	return p, nil
+}
+
+// ResolvePricing picks the pricing to estimate cost with: an explicit spec
+// wins over a model name. It returns nil when neither is given.
+func ResolvePricing(model, spec string) (*Pricing, error) {
+	if spec != "" {
+		p, err := ParsePricing(spec)
+		if err != nil {
+			return nil, err
+		}
+		return &p, nil
+	}
+	if model != "" {
+		p, ok := LookupPricing(model)
+		if !ok {
+			return nil, fmt.Errorf("no built-in pricing for model %q; set prices with --pricing", model)
+		}
+		return &p, nil
+	}
+	return nil, nil
+}
+
+func pricingKeys(fields map[string]*float64) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
@@ -0,0 +1,87 @@
+package claude
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPricingCost(t *testing.T) {
+	p := Pricing{Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3}
+	u := Usage{InputTokens: 1_000_000, OutputTokens: 100_000, CacheCreationTokens: 200_000, CacheReadTokens: 1_000_000}
+
+	if got, want := p.Cost(u), 3+1.5+0.75+0.3; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Cost() = %v, want %v", got, want)
+	}
+}
+
+func TestLookupPricing(t *testing.T) {
+	tests := []struct {
+		model string
+		input float64
+		found bool
+	}{
+		{"sonnet", 3, true},
+		{"claude-opus-4-1-20250805", 15, true},
+		{"Claude-Haiku", 0.8, true},
+		{"gpt-4o", 0, false},
+	}
+
+	for _, tt := range tests {
+		p, ok := LookupPricing(tt.model)
+		if ok != tt.found || p.Input != tt.input {
+			t.Errorf("LookupPricing(%q) = %+v, %v; want input %v, %v", tt.model, p, ok, tt.input, tt.found)
+		}
+	}
+}
+
+func TestParsePricing(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Pricing
+		wantErr bool
+	}{
+		{"input=3,output=15,cache_write=3.75,cache_read=0.3", Pricing{3, 15, 3.75, 0.3}, false},
+		{"input=2, output=8", Pricing{2, 8, 2, 2}, false},
+		{"output=8", Pricing{}, true},
+		{"input=2,output=8,tokens=1", Pricing{}, true},
+		{"input=abc,output=8", Pricing{}, true},
+		{"input=-1,output=8", Pricing{}, true},
+		{"", Pricing{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePricing(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePricing(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePricing(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestResolvePricing(t *testing.T) {
+	if p, err := ResolvePricing("", ""); p != nil || err != nil {
+		t.Errorf("ResolvePricing() with nothing set = %v, %v; want nil", p, err)
+	}
+	if p, err := ResolvePricing("sonnet", "input=1,output=2"); err != nil || p.Input != 1 {
+		t.Errorf("ResolvePricing() should prefer the explicit spec, got %v, %v", p, err)
+	}
+	if _, err := ResolvePricing("unknown-model", ""); err == nil {
+		t.Error("ResolvePricing() expected error for unknown model")
+	}
+}
+
+func TestParseClaudeOutputUsage(t *testing.T) {
+	output := `{"type":"result","result":"done","total_cost_usd":0,"usage":{"input_tokens":10,"output_tokens":20,"cache_creation_input_tokens":30,"cache_read_input_tokens":40}}`
+
+	var result Result
+	if err := parseClaudeOutput(output, &result); err != nil {
+		t.Fatalf("parseClaudeOutput() unexpected error: %v", err)
+	}
+	want := Usage{InputTokens: 10, OutputTokens: 20, CacheCreationTokens: 30, CacheReadTokens: 40}
+	if result.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", result.Usage, want)
+	}
+}
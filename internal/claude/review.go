@@ -0,0 +1,155 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// reviewDiffBudget caps the tokens of diff included in a review prompt.
+const reviewDiffBudget = 60000
+
+// Review verdicts.
+const (
+	VerdictApprove        = "approve"
+	VerdictComment        = "comment"
+	VerdictRequestChanges = "request_changes"
+)
+
+// PullRequest is what a review is given about the PR under review.
+type PullRequest struct {
+	Number string
+	Title  string
+	Body   string
+	Author string
+	Diff   string
+}
+
+// ReviewComment is one piece of feedback tied to a place in the diff.
+type ReviewComment struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Body     string `json:"body"`
+}
+
+// Review is Claude's structured feedback on a pull request.
+type Review struct {
+	Summary  string          `json:"summary"`
+	Verdict  string          `json:"verdict"`
+	Comments []ReviewComment `json:"comments"`
+	// Suggested PR description, when the current one is missing or thin
+	Description string  `json:"description"`
+	Cost        float64 `json:"-"`
+}
+
+// Review asks Claude, in a read-only call, to review a pull request. The
+// working directory gives Claude the surrounding code for context.
+func (c *Client) Review(ctx context.Context, pr PullRequest) (*Review, error) {
+	body := strings.TrimSpace(pr.Body)
+	if body == "" {
+		body = "(no description)"
+	}
+
+	prompt := `You are reviewing a pull request written by a human teammate. Do NOT modify any files.
+
+## PULL REQUEST #` + pr.Number + `: ` + pr.Title + `
+
+Author: ` + pr.Author + `
+
+` + body + `
+
+## DIFF
+
+` + "```diff\n" + TruncateTokens(pr.Diff, reviewDiffBudget) + "\n```" + `
+
+## TASK
+
+Review the change for correctness, bugs, security problems, missing tests and unclear code. Read the surrounding code in the repository where it helps. Only comment on things worth the author's time; don't restate the diff or nitpick formatting a linter would catch.
+
+If the description is missing or doesn't explain what the change does and why, write a better one.
+
+Respond with ONLY a JSON object, no other text:
+{"summary": "<2-4 sentence overall assessment>", "verdict": "approve"|"comment"|"request_changes", "comments": [{"file": "<path>", "line": <line in the new file, 0 if general>, "severity": "blocker"|"suggestion"|"nit", "body": "<feedback>"}], "description": "<suggested PR description in markdown, empty if the current one is fine>"}`
+
+	result, err := c.runReadOnly(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run Claude review: %w", err)
+	}
+
+	review, err := ParseReview(result.Output)
+	if err != nil {
+		return nil, err
+	}
+	review.Cost = result.Cost
+	return review, nil
+}
+
+// ParseReview extracts the review JSON object from Claude's reply,
+// tolerating surrounding prose or a code fence.
+func ParseReview(output string) (*Review, error) {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no review JSON found in output")
+	}
+
+	var review Review
+	if err := json.Unmarshal([]byte(output[start:end+1]), &review); err != nil {
+		return nil, fmt.Errorf("failed to parse review: %w", err)
+	}
+	switch review.Verdict {
+	case VerdictApprove, VerdictComment, VerdictRequestChanges:
+	default:
+		return nil, fmt.Errorf("unknown review verdict %q", review.Verdict)
+	}
+	return &review, nil
+}
+
+// severityOrder sorts blockers before suggestions before nits.
+var severityOrder = map[string]int{"blocker": 0, "suggestion": 1, "nit": 2}
+
+// Markdown renders the review as a PR comment.
+func (r *Review) Markdown() string {
+	var sb strings.Builder
+
+	verdict := map[string]string{
+		VerdictApprove:        "✅ Looks good",
+		VerdictComment:        "💬 Comments",
+		VerdictRequestChanges: "⚠️ Changes requested",
+	}[r.Verdict]
+	sb.WriteString(fmt.Sprintf("## 🤖 Deep Claude review: %s\n\n", verdict))
+	sb.WriteString(strings.TrimSpace(r.Summary))
+	sb.WriteString("\n")
+
+	if len(r.Comments) > 0 {
+		comments := append([]ReviewComment(nil), r.Comments...)
+		for i := 1; i < len(comments); i++ {
+			for j := i; j > 0 && severityOrder[comments[j].Severity] < severityOrder[comments[j-1].Severity]; j-- {
+				comments[j], comments[j-1] = comments[j-1], comments[j]
+			}
+		}
+
+		sb.WriteString("\n### Feedback\n\n")
+		for _, c := range comments {
+			where := c.File
+			if c.Line > 0 {
+				where = fmt.Sprintf("%s:%d", c.File, c.Line)
+			}
+			if where != "" {
+				where = fmt.Sprintf("`%s` ", where)
+			}
+			sb.WriteString(fmt.Sprintf("- **%s** %s— %s\n", c.Severity, where, strings.TrimSpace(c.Body)))
+		}
+	}
+
+	if d := strings.TrimSpace(r.Description); d != "" {
+		sb.WriteString("\n<details>\n<summary>Suggested PR description</summary>\n\n")
+		sb.WriteString(d)
+		sb.WriteString("\n\n</details>\n")
+	}
+
+	sb.WriteString("\n---\n*Automated review by [Deep Claude](https://github.com/guzus/deep-claude). It can be wrong; use your judgment.*\n")
+	return sb.String()
+}
@@ -0,0 +1,53 @@
+package claude
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseReview(t *testing.T) {
+	output := "Here is my review:\n```json\n" +
+		`{"summary":"Solid change.","verdict":"comment","comments":[{"file":"a.go","line":3,"severity":"nit","body":"Rename x"},{"file":"b.go","line":0,"severity":"blocker","body":"Nil deref"}],"description":""}` +
+		"\n```"
+
+	review, err := ParseReview(output)
+	if err != nil {
+		t.Fatalf("ParseReview() unexpected error: %v", err)
+	}
+	if review.Verdict != VerdictComment || len(review.Comments) != 2 || review.Comments[1].Severity != "blocker" {
+		t.Errorf("ParseReview() = %+v", review)
+	}
+
+	if _, err := ParseReview(`{"summary":"x","verdict":"lgtm"}`); err == nil {
+		t.Error("ParseReview() expected error for unknown verdict")
+	}
+	if _, err := ParseReview("no json here"); err == nil {
+		t.Error("ParseReview() expected error without JSON")
+	}
+}
+
+func TestReviewMarkdown(t *testing.T) {
+	review := &Review{
+		Summary: "Mostly fine.",
+		Verdict: VerdictRequestChanges,
+		Comments: []ReviewComment{
+			{File: "a.go", Line: 3, Severity: "nit", Body: "Rename x"},
+			{File: "b.go", Severity: "blocker", Body: "Nil deref"},
+		},
+		Description: "Adds retries to the client.",
+	}
+
+	md := review.Markdown()
+	for _, want := range []string{"Changes requested", "Mostly fine.", "**blocker** `b.go` — Nil deref", "**nit** `a.go:3` — Rename x", "Suggested PR description", "Adds retries"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Index(md, "blocker") > strings.Index(md, "nit") {
+		t.Error("Markdown() should list blockers before nits")
+	}
+
+	if md := (&Review{Summary: "Good.", Verdict: VerdictApprove}).Markdown(); strings.Contains(md, "Feedback") || strings.Contains(md, "Suggested") {
+		t.Errorf("Markdown() of a clean review should omit empty sections:\n%s", md)
+	}
+}
@@ -0,0 +1,126 @@
+package claude
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// StatusPrefix starts the status line that ends a response when a signal
+// must appear in the summary.
+const StatusPrefix = "STATUS:"
+
+// Signal is how Claude reports that the whole project is done.
+type Signal struct {
+	// Phrases each count as the signal; Claude is told to use the first
+	Phrases []string
+	// Pattern, if set, counts as the signal too when it matches
+	Pattern *regexp.Regexp
+	// InSummary only counts the signal on the status line that ends the
+	// response, so mentioning the phrase elsewhere doesn't count
+	InSummary bool
+}
+
+// IsZero reports whether there is no signal to look for.
+func (s Signal) IsZero() bool {
+	return len(s.Phrases) == 0 && s.Pattern == nil
+}
+
+// Matches reports whether output contains the signal.
+func (s Signal) Matches(output string) bool {
+	if s.InSummary {
+		status, ok := Status(output)
+		if !ok {
+			return false
+		}
+		output = status
+	}
+	for _, phrase := range s.Phrases {
+		if phrase != "" && strings.Contains(output, phrase) {
+			return true
+		}
+	}
+	return s.Pattern != nil && s.Pattern.MatchString(output)
+}
+
+// instruction tells Claude how to give the signal.
+func (s Signal) instruction() string {
+	if len(s.Phrases) > 0 {
+		if s.InSummary {
+			return "end your response with this exact line: \"" + StatusPrefix + " " + s.Phrases[0] + "\""
+		}
+		return "include this exact phrase in your response: \"" + s.Phrases[0] + "\""
+	}
+	if s.InSummary {
+		return "end your response with a line starting with \"" + StatusPrefix + "\" followed by text matching the regular expression `" + s.Pattern.String() + "`"
+	}
+	return "include text matching the regular expression `" + s.Pattern.String() + "` in your response"
+}
+
+// BlockedStatus starts the status line of a response that needs a human
+// to answer before work can go on.
+const BlockedStatus = "BLOCKED"
+
+// Blocked reports whether output ends with a blocked status line, and
+// what Claude said it needs.
+func Blocked(output string) (string, bool) {
+	status, ok := Status(output)
+	if !ok {
+		return "", false
+	}
+	rest, ok := strings.CutPrefix(status, BlockedStatus)
+	if !ok || (rest != "" && !strings.ContainsAny(rest[:1], ": -")) {
+		return "", false
+	}
+	if reason := strings.TrimSpace(strings.TrimLeft(rest, ": -")); reason != "" {
+		return reason, true
+	}
+	return "no reason given", true
+}
+
+// Status returns what follows the status prefix on the last non-empty
+// line of output, ignoring Markdown emphasis around it.
+func Status(output string) (string, bool) {
+	trimmed := strings.TrimSpace(output)
+	last := trimmed[strings.LastIndex(trimmed, "\n")+1:]
+	last = strings.Trim(strings.TrimSpace(last), "*_`")
+	rest, ok := strings.CutPrefix(last, StatusPrefix)
+	return strings.Trim(strings.TrimSpace(rest), "*_`"), ok
+}
+
+// ConfidencePrefix starts the line on which Claude rates its confidence in
+// an iteration's changes.
+const ConfidencePrefix = "CONFIDENCE:"
+
+// ConfidenceSection asks Claude to rate its confidence in its changes.
+func ConfidenceSection() Section {
+	return Section{
+		Title: "CONFIDENCE",
+		Body: "Before the end of your response, rate how confident you are that your changes are correct and complete enough to merge without a human looking at them, " +
+			"on a line of its own: \"" + ConfidencePrefix + " <0.0-1.0>\". Be honest: low confidence keeps the changes out of the main branch until someone reviews them.",
+	}
+}
+
+// Confidence returns the number on the last confidence line of output,
+// as a fraction between 0 and 1; "85%" reads as 0.85.
+func Confidence(output string) (float64, bool) {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.Trim(strings.TrimSpace(lines[i]), "*_`")
+		rest, ok := strings.CutPrefix(line, ConfidencePrefix)
+		if !ok {
+			continue
+		}
+		rest = strings.Trim(strings.TrimSpace(rest), "*_`")
+		percent := strings.HasSuffix(rest, "%")
+		value, err := strconv.ParseFloat(strings.TrimSuffix(rest, "%"), 64)
+		if err != nil {
+			return 0, false
+		}
+		if percent || value > 1 {
+			value /= 100
+		}
+		return min(max(value, 0), 1), true
+	}
+	return 0, false
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/guzus/deep-claude/internal/audit"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var auditFormat string
+
+var auditCmd = &cobra.Command{
+	Use:   "audit <run-id | file>",
+	Short: "Verify and export the audit log of a run",
+	Long: `Show the audit log of a run: every external action it took, such as commits,
+pushes, PR creations and merges, and the commands it ran. Each entry is chained
+to the one before by its hash, and the chain is verified first, so a log that
+was edited fails with the first tampered entry.
+
+The run ID may be a prefix, a session name or "latest". A file written by
+--format json can be verified too.
+
+  dclaude audit latest
+  dclaude audit 20250115 --format json > audit.json
+  dclaude audit audit.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := auditPath(args[0])
+		if err != nil {
+			return err
+		}
+		entries, err := audit.Read(path)
+		if err != nil {
+			return err
+		}
+		verr := audit.Verify(entries)
+
+		switch auditFormat {
+		case "csv":
+			if err := audit.WriteCSV(os.Stdout, entries); err != nil {
+				return err
+			}
+			return verr
+		case "json":
+			if err := audit.WriteJSON(os.Stdout, entries); err != nil {
+				return err
+			}
+			return verr
+		case "table":
+		default:
+			return fmt.Errorf("--format must be one of: table, csv, json")
+		}
+
+		if verr != nil {
+			return fmt.Errorf("audit log %s is not intact: %w", path, verr)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No actions recorded")
+			return nil
+		}
+		rows := make([][]string, len(entries))
+		for i, e := range entries {
+			rows[i] = []string{
+				strconv.Itoa(e.Seq),
+				e.Time.Local().Format("2006-01-02 15:04:05"),
+				strconv.Itoa(e.Iteration),
+				e.Action,
+				audit.FormatData(e.Data),
+			}
+		}
+		printer := ui.NewPrinter(false)
+		printer.Table([]string{"#", "TIME", "ITERATION", "ACTION", "DETAILS"}, rows)
+		fmt.Println()
+		printer.Success("Chain intact: %d entries, head %s", len(entries), entries[len(entries)-1].Hash)
+		return nil
+	},
+}
+
+// auditPath resolves a run to its audit log, or returns arg itself if it
+// is a file.
+func auditPath(arg string) (string, error) {
+	if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+		return arg, nil
+	}
+	runs, err := state.ListRuns()
+	if err != nil {
+		return "", err
+	}
+	runID, err := matchRun(runs, arg)
+	if err != nil {
+		return "", err
+	}
+	dir, err := state.RunDir(runID)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, audit.FileName), nil
+}
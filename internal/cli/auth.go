@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/auth"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage the Anthropic API key and GitHub token",
+	Long: `Store and check the credentials Deep Claude runs with. Secrets are kept in
+the OS keychain where available, otherwise in a private file in the state
+directory. Environment variables (ANTHROPIC_API_KEY, GH_TOKEN/GITHUB_TOKEN)
+take precedence over stored secrets.
+
+The Anthropic key is optional when Claude Code is already logged in.`,
+}
+
+var authSetCmd = &cobra.Command{
+	Use:   "set <anthropic|github>",
+	Short: "Store a credential (read from the terminal or stdin)",
+	Long: `Store a credential after checking it. The secret is read without echo from the
+terminal, or from stdin when piped:
+
+  dclaude auth set anthropic
+  gh auth token | dclaude auth set github`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cred, err := auth.Lookup(args[0])
+		if err != nil {
+			return err
+		}
+		store, err := auth.DefaultStore()
+		if err != nil {
+			return err
+		}
+
+		secret, err := readSecret(fmt.Sprintf("Enter %s for %s", cred.EnvVar, cred.Name))
+		if err != nil {
+			return err
+		}
+		if secret == "" {
+			return fmt.Errorf("no %s credential given", cred.Name)
+		}
+
+		printer := ui.NewPrinter(false)
+		if err := checkCredential(cmd.Context(), cred, secret); err != nil {
+			var unreachable *auth.UnreachableError
+			if !errors.As(err, &unreachable) {
+				return err
+			}
+			printer.Warning("Could not verify the key: %v", err)
+		}
+
+		if err := store.Set(cred.Name, secret); err != nil {
+			return err
+		}
+		printer.Success("Stored %s credential in %s", cred.Name, store.Name())
+		return nil
+	},
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show where each credential comes from and check it",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := auth.DefaultStore()
+		if err != nil {
+			return err
+		}
+
+		printer := ui.NewPrinter(false)
+		failed := false
+		for _, cred := range auth.Credentials {
+			secret, source, err := auth.Resolve(store, cred)
+			if err != nil {
+				printer.Error("%s: %v", cred.Name, err)
+				failed = true
+				continue
+			}
+
+			where := string(source)
+			if source == auth.SourceStore {
+				where = store.Name()
+			}
+			if source == auth.SourceNone {
+				if cred.Name == auth.Anthropic.Name {
+					printer.Info("%s: not set, using Claude Code's own login", cred.Name)
+				} else {
+					printer.Info("%s: not set, using gh's own login", cred.Name)
+				}
+				secret = ""
+			}
+
+			if err := checkCredential(cmd.Context(), cred, secret); err != nil {
+				var unreachable *auth.UnreachableError
+				if errors.As(err, &unreachable) {
+					printer.Warning("%s: set (from %s) but not verified: %v", cred.Name, where, err)
+					continue
+				}
+				printer.Error("%s: %v", cred.Name, err)
+				failed = true
+				continue
+			}
+			if source != auth.SourceNone {
+				printer.Success("%s: valid (from %s)", cred.Name, where)
+			}
+		}
+
+		if failed {
+			return fmt.Errorf("some credentials are missing or invalid")
+		}
+		return nil
+	},
+}
+
+var authDeleteCmd = &cobra.Command{
+	Use:   "delete <anthropic|github>",
+	Short: "Remove a stored credential",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cred, err := auth.Lookup(args[0])
+		if err != nil {
+			return err
+		}
+		store, err := auth.DefaultStore()
+		if err != nil {
+			return err
+		}
+		if err := store.Delete(cred.Name); err != nil {
+			return err
+		}
+		ui.NewPrinter(false).Success("Removed %s credential from %s", cred.Name, store.Name())
+		return nil
+	},
+}
+
+// checkCredential validates a secret with its service. An empty secret
+// checks the tool's own login instead.
+func checkCredential(ctx context.Context, cred auth.Credential, secret string) error {
+	switch cred.Name {
+	case auth.Anthropic.Name:
+		if secret == "" {
+			return nil
+		}
+		return auth.ValidateAnthropic(secret)
+	case auth.GitHub.Name:
+		if secret != "" {
+			os.Setenv(auth.GitHub.EnvVar, secret)
+		}
+		return github.NewClient("", "", "").CheckAuth(ctx)
+	}
+	return nil
+}
+
+// readSecret reads a secret without echo from a terminal, or a line from stdin.
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Printf("%s %s: ", ui.Blue("?"), prompt)
+		secret, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return strings.TrimSpace(string(secret)), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", nil
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// exportCredentials makes stored credentials visible to Claude Code and gh.
+func exportCredentials(printer ui.Printer) {
+	store, err := auth.DefaultStore()
+	if err == nil {
+		err = auth.Export(store)
+	}
+	if err != nil {
+		printer.Warning("Could not load stored credentials: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/guzus/deep-claude/internal/cassette"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/orchestrator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// unrecordedFlags are left out of a cassette: they concern the recording
+// itself, or their effect is already in the other flags, like --recipe's,
+// or in the recorded calls, like --simulate's.
+var unrecordedFlags = map[string]bool{"record": true, "replay": true, "recipe": true, "simulate": true, "detach": true}
+
+// loadCassette reads a cassette to replay and sets the flags it was
+// recorded with, unless given on the command line.
+func loadCassette(cmd *cobra.Command, path string) (*cassette.Cassette, error) {
+	tape, err := cassette.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range tape.Header().Flags {
+		if cmd.Flags().Lookup(name) == nil {
+			return nil, fmt.Errorf("cassette %s sets unknown flag --%s", path, name)
+		}
+		if cmd.Flags().Changed(name) {
+			continue
+		}
+		for _, value := range values {
+			if err := cmd.Flags().Set(name, value); err != nil {
+				return nil, fmt.Errorf("cassette %s: invalid value for --%s: %w", path, name, err)
+			}
+		}
+	}
+	return tape, nil
+}
+
+// recordedFlags returns the flags a run was started with, including those
+// set by recipes and config files.
+func recordedFlags(cmd *cobra.Command) map[string][]string {
+	flags := make(map[string][]string)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if unrecordedFlags[f.Name] {
+			return
+		}
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			flags[f.Name] = slice.GetSlice()
+			return
+		}
+		flags[f.Name] = []string{f.Value.String()}
+	})
+	return flags
+}
+
+// newOrchestrator creates the orchestrator for the given clients, first
+// wrapping them to record their calls, and the run's flags, with --record.
+// The returned func finishes the recording.
+func newOrchestrator(flags map[string][]string, printer ui.Printer, cfg *config.Config, workDir string, g orchestrator.GitRunner, newGitHub func(owner, repo string) orchestrator.GhRunner, c orchestrator.ClaudeRunner) (*orchestrator.Orchestrator, func(), error) {
+	if cfg.Record == "" {
+		orch, err := orchestrator.NewWithRunners(cfg, workDir, g, newGitHub, c)
+		return orch, func() {}, err
+	}
+
+	tape, err := cassette.Record(cfg.Record, flags)
+	if err != nil {
+		return nil, nil, err
+	}
+	finish := func() {
+		if err := tape.Close(); err != nil {
+			printer.Warning("Recording is incomplete: %v", err)
+			return
+		}
+		printer.Info("Recorded the run's calls to %s", cfg.Record)
+	}
+	recordGitHub := func(owner, repo string) orchestrator.GhRunner { return tape.GitHub(newGitHub(owner, repo)) }
+	orch, err := orchestrator.NewWithRunners(cfg, workDir, tape.Git(g), recordGitHub, tape.Claude(c))
+	if err != nil {
+		finish()
+		return nil, nil, err
+	}
+	return orch, finish, nil
+}
+
+// runReplay runs the loop on the calls recorded in a cassette, in a
+// scratch copy of the repository, and fails if the run asks for calls
+// that weren't recorded.
+func runReplay(cmd *cobra.Command, workDir string, cfg *config.Config, tape *cassette.Cassette) error {
+	printer := ui.NewPrinter(false)
+	s, err := newScratch(workDir)
+	if err != nil {
+		return err
+	}
+
+	printer.Info("Replaying %s in %s", cfg.Replay, s.root)
+	newGitHub := func(owner, repo string) orchestrator.GhRunner { return tape.GitHub(nil) }
+	orch, err := orchestrator.NewWithRunners(cfg, s.repoDir, tape.Git(nil), newGitHub, tape.Claude(nil))
+	if err != nil {
+		return err
+	}
+	ctx, stop := interruptible(cmd.Context())
+	defer stop()
+	err = orch.Run(ctx)
+
+	if left := tape.Unplayed(); len(left) > 0 {
+		printer.Warning("Recorded calls left unplayed: %s", cassette.FormatUnplayed(left))
+	}
+	if closeErr := tape.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
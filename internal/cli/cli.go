@@ -2,18 +2,33 @@
 package cli
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/guzus/deep-claude/internal/config"
+	"github.com/guzus/deep-claude/internal/cassette"
 	"github.com/guzus/deep-claude/internal/git"
 	"github.com/guzus/deep-claude/internal/github"
-	"github.com/guzus/deep-claude/internal/orchestrator"
+	"github.com/guzus/deep-claude/internal/i18n"
+	"github.com/guzus/deep-claude/internal/inbox"
+	"github.com/guzus/deep-claude/internal/ledger"
+	"github.com/guzus/deep-claude/internal/replay"
+	"github.com/guzus/deep-claude/internal/repro"
+	"github.com/guzus/deep-claude/internal/retry"
+	"github.com/guzus/deep-claude/internal/settings"
+	"github.com/guzus/deep-claude/internal/simulate"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/telemetry"
 	"github.com/guzus/deep-claude/internal/tmux"
 	"github.com/guzus/deep-claude/internal/ui"
 	"github.com/guzus/deep-claude/internal/version"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/events"
+	"github.com/guzus/deep-claude/pkg/orchestrator"
 	"github.com/spf13/cobra"
 )
 
@@ -29,6 +44,11 @@ func Execute(ver, buildDate, gitCommit string) error {
 	appBuildDate = buildDate
 	appGitCommit = gitCommit
 
+	// Messages are printed in the locale's language where they have been
+	// translated, and in English otherwise
+	if err := i18n.SetLanguage(i18n.Detect()); err != nil && os.Getenv(i18n.Env) != "" {
+		ui.NewPrinter(false).Warning("%s: %v", i18n.Env, err)
+	}
 	return rootCmd.Execute()
 }
 
@@ -53,70 +73,380 @@ var (
 	prompt string
 
 	// Limit flags (at least one required)
-	maxRuns     int
-	maxCost     float64
-	maxDuration string
+	maxRuns              int
+	maxCost              float64
+	maxDuration          string
+	maxIterationDuration string
 
 	// Optional flags
 	owner               string
 	repo                string
 	mergeStrategy       string
 	gitBranchPrefix     string
+	branchMode          string
+	squashCommits       bool
+	taskType            string
+	migrationCmd        string
+	migrationBatch      int
 	notesFile           string
 	disableCommits      bool
+	stageAll            bool
+	stageExcludes       []string
+	paths               []string
 	dryRun              bool
-	completionSignal    string
+	completionSignals   []string
+	completionRegex     string
+	completionInSummary bool
 	completionThreshold int
+	dirtyTree           string
+	completionMode      string
+	minConfidence       float64
+	stopOnNoProgress    int
+	recordResults       string
+	repoMapIterations   int
+	promptTokenBudget   int
+	verifyCmd           string
+	verifyStreak        int
+	affectedTests       bool
+	testMap             string
+	fullTests           string
+	fullTestEvery       int
+	coverageCmd         string
+	minCoverage         float64
+	minCoverageDelta    float64
+	mutationCmd         string
+	minMutationScore    float64
+	stopOnIssueClosed   string
+	pricingModel        string
+	pricing             string
+	recipeName          string
+	promptVars          []string
+	promptVarsFile      string
+	promptVarCmds       []string
+	deferPush           bool
+	pipeline            bool
+	pipelineDepth       int
+	resume              string
+	assignedRunID       string
+	gcBranches          bool
+	readOnly            bool
+	patchDir            string
+	scenarioFile        string
+	recordFile          string
+	replayFile          string
+	retrySpecs          []string
+	maxPRsPerHour       int
+	maxPRsPerDay        int
+	costTags            []string
+	labels              []string
+	deferPushWait       string
+	blockedWait         string
+	mergeConfidence     float64
+	draftConfidence     float64
+	revertOnMainFailure string
+	deployEnvironment   string
+	deployCheck         string
+	deployTimeout       string
+	checkRun            bool
+	commitStatuses      bool
+	commentCommands     bool
+	fixCommits          string
+	reviewWait          string
+	onReviewTimeout     string
+	mergeApproval       bool
+	publishSummary      string
+	publishReport       string
+	summaryIssue        string
 	worktree            string
 	worktreeBaseDir     string
 	cleanupWorktree     bool
+	worktreeCopy        []string
+	isolateResources    bool
+	portBase            int
+	portsPerWorker      int
+	setupCmd            string
+	upgradeDeps         bool
+	upgradePolicy       string
+	upgradeBatch        int
+	upgradeIgnore       []string
+	lintFix             bool
+	lintCmds            []string
+	lintBatch           int
+	backport            string
+	backportTo          []string
+	sharedCache         bool
+	partition           string
+	commitConvention    string
+	commitPattern       string
+	commitRetries       int
+	updateChangelog     bool
+	changelogFile       string
+	releaseEvery        int
+	releaseOnComplete   bool
 	autoUpdate          bool
 	disableUpdates      bool
+	updateChannel       string
+	updateInterval      string
+	updateFrom          string
+	updateChecksum      string
+	updateSignature     string
+	redactEnv           []string
 	detach              bool
+	detachedConfig      string
+	restartOnCrash      bool
+	maxRestarts         int
+	verbose             bool
+	outputMode          string
+	logFile             string
+	ciMode              bool
+	asciiMode           bool
+	showFullOutput      bool
+	outputLimit         int
+	pageOutput          bool
 )
 
 func init() {
 	// Required
-	rootCmd.Flags().StringVarP(&prompt, "prompt", "p", "", "Task description for Claude (required)")
+	rootCmd.Flags().StringVarP(&prompt, "prompt", "p", "", "Task description for Claude (required unless --recipe is given)")
+	rootCmd.Flags().StringVar(&recipeName, "recipe", "", "Run a built-in or user-defined recipe (see 'dclaude recipes'); -p then adds instructions")
+	rootCmd.Flags().StringArrayVar(&promptVars, "prompt-var", nil, "Value of a {{name}} variable in the prompt, as name=value (repeatable)")
+	rootCmd.Flags().StringVar(&promptVarsFile, "prompt-vars-file", "", "JSON file with values of the prompt's {{name}} variables")
+	rootCmd.Flags().StringArrayVar(&promptVarCmds, "prompt-var-cmd", nil, "Set a {{name}} variable in the prompt to a command's output, as name=command (repeatable)")
 
 	// Limits (at least one required)
 	rootCmd.Flags().IntVarP(&maxRuns, "max-runs", "m", 0, "Maximum number of iterations (0 = unlimited)")
 	rootCmd.Flags().Float64Var(&maxCost, "max-cost", 0, "Maximum cost in USD (0 = unlimited)")
 	rootCmd.Flags().StringVar(&maxDuration, "max-duration", "", "Maximum duration (e.g., '2h', '30m', '1h30m')")
+	rootCmd.Flags().StringVar(&maxIterationDuration, "max-iteration-duration", "", "Maximum duration of one iteration, including waiting for checks (e.g., '45m')")
 
 	// GitHub/Git options
 	rootCmd.Flags().StringVar(&owner, "owner", "", "GitHub repository owner (auto-detected)")
 	rootCmd.Flags().StringVar(&repo, "repo", "", "GitHub repository name (auto-detected)")
 	rootCmd.Flags().StringVar(&mergeStrategy, "merge-strategy", "squash", "PR merge strategy: squash, merge, rebase")
 	rootCmd.Flags().StringVar(&gitBranchPrefix, "git-branch-prefix", "deep-claude/", "Branch name prefix")
+	rootCmd.Flags().BoolVar(&squashCommits, "squash-commits", false, "Squash the commits an iteration makes, such as Claude's own and the changelog entry, into one before pushing")
+	rootCmd.Flags().StringVar(&branchMode, "branch-mode", "iteration", "Where iterations commit: iteration (a branch and PR each) or single (one branch and PR for the whole run, merged when it ends)")
+	rootCmd.Flags().StringVar(&taskType, "task-type", "code", "What the run delivers: code (changes shipped through PRs) or analysis (an investigation that commits nothing and ends with a report)")
+	rootCmd.Flags().StringVar(&migrationCmd, "migration-cmd", "", "Migration mode: command listing the files or modules still to migrate, one per line. They are tracked as a checklist in the notes, each iteration is assigned the next --migration-batch of them, and the run stops once the command lists none")
+	rootCmd.Flags().IntVar(&migrationBatch, "migration-batch", 5, "Checklist items of --migration-cmd assigned to each iteration")
 	rootCmd.Flags().StringVar(&notesFile, "notes-file", "SHARED_TASK_NOTES.md", "Path to notes file for context")
 
 	// Execution options
 	rootCmd.Flags().BoolVar(&disableCommits, "disable-commits", false, "Run without creating commits/PRs")
+	rootCmd.Flags().BoolVar(&stageAll, "stage-all", false, "Stage every change in the tree instead of only files Claude modified")
+	rootCmd.Flags().StringArrayVar(&paths, "path", nil, "Monorepo project to confine the work to: Claude's scope, staged files, verify command and CI checks (repeatable, e.g. packages/api)")
+	rootCmd.Flags().StringArrayVar(&stageExcludes, "exclude", nil, "Pattern of files never to stage, in addition to .gitignore (repeatable, e.g. '*.log', 'tmp/**')")
+	rootCmd.Flags().IntVar(&repoMapIterations, "repo-map-iterations", 3, "Include a repository map in the prompt for the first N iterations (0 = disabled)")
+	rootCmd.Flags().IntVar(&promptTokenBudget, "prompt-token-budget", 30000, "Approximate token budget for the prompt; older notes are summarized to fit (0 = unlimited)")
 	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Simulate without making changes")
-	rootCmd.Flags().StringVar(&completionSignal, "completion-signal", "DEEP_CLAUDE_PROJECT_COMPLETE", "Signal phrase for early stop")
+	rootCmd.Flags().StringArrayVar(&completionSignals, "completion-signal", []string{config.DefaultCompletionSignal}, "Signal phrase for early stop (repeatable; any of them counts)")
+	rootCmd.Flags().StringVar(&completionRegex, "completion-regex", "", "Regular expression whose match also counts as a completion signal")
+	rootCmd.Flags().BoolVar(&completionInSummary, "completion-in-summary", false, "Only count a completion signal on the STATUS line that ends Claude's response")
 	rootCmd.Flags().IntVar(&completionThreshold, "completion-threshold", 3, "Consecutive signals needed to stop")
+	rootCmd.Flags().StringVar(&completionMode, "completion-mode", "signal", "How completion is detected: signal (phrase in output) or evaluate (separate self-evaluation call)")
+	rootCmd.Flags().Float64Var(&minConfidence, "completion-confidence", 0.8, "Minimum self-evaluation confidence that counts as a completion signal (evaluate mode)")
+	rootCmd.Flags().IntVar(&stopOnNoProgress, "stop-on-no-progress", 0, "Stop after N consecutive iterations without changes (0 = disabled)")
+	rootCmd.Flags().StringVar(&recordResults, "record-results", "none", "How an iteration without code changes, as in research and analysis, records its results so it counts as progress: none, commit (an empty commit on the run's results branch) or comment (on --summary-issue)")
+	rootCmd.Flags().StringVar(&verifyCmd, "verify-cmd", "", "Stop when this shell command exits 0 (e.g. 'go test ./...')")
+	rootCmd.Flags().IntVar(&verifyStreak, "verify-streak", 1, "Consecutive iterations --verify-cmd must pass before stopping")
+	rootCmd.Flags().BoolVar(&affectedTests, "affected-tests", false, "Replace {tests} in --verify-cmd with only the tests affected by the changes (Go packages, or --test-map)")
+	rootCmd.Flags().StringVar(&testMap, "test-map", "", "YAML file mapping file patterns to test targets for --affected-tests")
+	rootCmd.Flags().StringVar(&fullTests, "full-tests", "./...", "Test targets substituted for {tests} when running the full suite")
+	rootCmd.Flags().IntVar(&fullTestEvery, "full-test-every", 5, "Run the full suite every N verify checks with --affected-tests (0 = only when needed)")
+	rootCmd.Flags().StringVar(&coverageCmd, "coverage-cmd", "", "Command whose output reports a coverage percentage, checked against --min-coverage")
+	rootCmd.Flags().Float64Var(&minCoverage, "min-coverage", 0, "Stop when --coverage-cmd reports at least this percentage")
+	rootCmd.Flags().Float64Var(&minCoverageDelta, "min-coverage-delta", 0, "Open a PR as a draft instead of merging it unless its changes raise --coverage-cmd's coverage by at least this many points (0 = disabled)")
+	rootCmd.Flags().StringVar(&mutationCmd, "mutation-cmd", "", "Mutation testing command whose output reports a mutation score, checked against --min-mutation-score after each iteration")
+	rootCmd.Flags().Float64Var(&minMutationScore, "min-mutation-score", 0, "Open a PR as a draft instead of merging it if --mutation-cmd reports a lower score than this percentage")
+	rootCmd.Flags().StringVar(&stopOnIssueClosed, "stop-on-issue-closed", "", "Stop when this GitHub issue number is closed")
+	rootCmd.Flags().StringVar(&dirtyTree, "dirty-tree", "stash", "Handling of uncommitted changes at start: stash, refuse, ignore")
+
+	// Cost estimation options
+	rootCmd.Flags().StringVar(&pricingModel, "pricing-model", "", "Model whose built-in prices (opus, sonnet, haiku) estimate cost when a run reports tokens but no cost")
+	rootCmd.Flags().StringVar(&pricing, "pricing", "", "Custom prices in USD per million tokens, e.g. 'input=3,output=15,cache_write=3.75,cache_read=0.3'")
+
+	// Dependency upgrade mode
+	rootCmd.Flags().BoolVar(&upgradeDeps, "upgrade-deps", false, "Upgrade outdated dependencies (Go modules, npm), one PR per upgrade; -p adds instructions")
+	rootCmd.Flags().StringVar(&upgradePolicy, "upgrade-policy", "minor", "Largest semver bump to take in --upgrade-deps mode: patch, minor, major")
+	rootCmd.Flags().IntVar(&upgradeBatch, "upgrade-batch", 1, "Dependencies upgraded together in each PR")
+	rootCmd.Flags().StringArrayVar(&upgradeIgnore, "upgrade-ignore", nil, "Dependency to leave at its current version (repeatable)")
+
+	// Lint-fix mode
+	rootCmd.Flags().BoolVar(&lintFix, "lint-fix", false, "Run linters and fix their findings in batches, one PR per batch; -p adds instructions")
+	rootCmd.Flags().StringArrayVar(&lintCmds, "lint-cmd", nil, "Linter command for --lint-fix (repeatable; default: golangci-lint and/or eslint, detected)")
+	rootCmd.Flags().IntVar(&lintBatch, "lint-batch", 20, "Maximum findings fixed in each PR")
+
+	// Backport mode
+	rootCmd.Flags().StringVar(&backport, "backport", "", "Merged PR number to cherry-pick onto each --backport-to branch, one PR per branch")
+	rootCmd.Flags().StringArrayVar(&backportTo, "backport-to", nil, "Branch to backport --backport to (repeatable)")
+
+	// Offline options
+	rootCmd.Flags().BoolVar(&pipeline, "pipeline", false, "Start the next iteration right after opening a PR and wait for its checks in the background")
+	rootCmd.Flags().IntVar(&pipelineDepth, "pipeline-depth", 2, "Maximum PRs awaiting checks while the next iteration runs with --pipeline")
+	rootCmd.Flags().StringVar(&resume, "resume", "", "Continue an earlier run (run ID, prefix or \"latest\"), counting what it already used against the limits")
+	rootCmd.Flags().StringVar(&assignedRunID, "run-id", "", "ID for the run instead of a generated one")
+	rootCmd.Flags().IntVar(&maxPRsPerHour, "max-prs-per-hour", 0, "Maximum PRs to open per hour; further iterations wait (0 = unlimited)")
+	rootCmd.Flags().IntVar(&maxPRsPerDay, "max-prs-per-day", 0, "Maximum PRs to open per day; further iterations wait (0 = unlimited)")
+	rootCmd.Flags().StringArrayVar(&costTags, "cost-tag", nil, "Tag recorded with the run's cost in the cost ledger (repeatable, e.g. team=payments)")
+	rootCmd.Flags().StringArrayVar(&labels, "label", nil, "Label the run's work, in its branch names, PRs, notices, cost ledger and history (repeatable, e.g. refactor-auth)")
+	rootCmd.Flags().BoolVar(&readOnly, "read-only", false, "Run the full loop but push nothing: record each iteration's commit as a patch file, plus a report")
+	rootCmd.Flags().StringVar(&patchDir, "patch-dir", "", "Directory for --read-only patches and report (default: the run's state directory)")
+	rootCmd.Flags().StringVar(&scenarioFile, "simulate", "", "Run against an in-memory forge and a scripted Claude from this YAML scenario file")
+	rootCmd.Flags().StringVar(&recordFile, "record", "", "Record every git, gh and claude call of the run, with its result, to this cassette file")
+	rootCmd.Flags().StringVar(&replayFile, "replay", "", "Replay a cassette written by --record instead of running git, gh and claude, with the flags it was recorded with")
+	rootCmd.Flags().BoolVar(&gcBranches, "gc-branches", false, "Delete remote branches with the branch prefix whose PRs are closed or merged before starting")
+	rootCmd.Flags().BoolVar(&deferPush, "defer-push", false, "When the network is down, keep commits locally and push/open PRs once it returns")
+	rootCmd.Flags().StringVar(&deferPushWait, "defer-push-wait", "30m", "How long to keep retrying deferred pushes when the run ends")
+	rootCmd.Flags().Float64Var(&mergeConfidence, "merge-confidence", 0, "Only auto-merge PRs Claude rates at least this confident (0-1; 0 = merge regardless)")
+	rootCmd.Flags().Float64Var(&draftConfidence, "draft-confidence", 0.5, "Below --merge-confidence, open a draft PR if Claude is at least this confident, otherwise keep the branch local and ask for guidance")
+	rootCmd.Flags().StringVar(&revertOnMainFailure, "revert-on-main-failure", "", "After merging, watch CI on the base branch and revert a merge that breaks it: pr (open a revert PR) or push (push the revert directly)")
+	rootCmd.Flags().StringVar(&deployEnvironment, "deploy-environment", "", "After merging, wait for the merge commit to deploy successfully to this GitHub environment (e.g. staging) before counting the iteration as done")
+	rootCmd.Flags().StringVar(&deployCheck, "deploy-check", "", "After merging, wait for the check with this name (e.g. a smoke test) to pass on the merge commit before counting the iteration as done")
+	rootCmd.Flags().StringVar(&deployTimeout, "deploy-timeout", "30m", "How long to wait for --deploy-environment and --deploy-check")
+	rootCmd.Flags().BoolVar(&checkRun, "check-run", false, "Publish a deep-claude check run on each PR summarizing the gates its changes went through (needs a GitHub App token)")
+	rootCmd.Flags().BoolVar(&commitStatuses, "commit-statuses", false, "Set deep-claude/gates, deep-claude/checks and deep-claude/merge commit statuses on each PR as the run progresses")
+	rootCmd.Flags().BoolVar(&commentCommands, "comment-commands", false, "Act on /deep-claude fix, explain and close comments left on the run's open PRs")
+	rootCmd.Flags().StringVar(&fixCommits, "fix-commits", "stack", "How a /deep-claude fix is committed: stack (a new commit), amend-ci (amend the PR's last commit if its checks are failing) or amend (always amend); amended commits are pushed with --force-with-lease")
+	rootCmd.Flags().StringVar(&reviewWait, "review-wait", "0", "How long a PR whose checks passed may wait for a required review before review is re-requested and the notifier pinged; after a second wait --on-review-timeout applies (0 = don't wait)")
+	rootCmd.Flags().BoolVar(&mergeApproval, "merge-approval", false, "Leave PRs whose checks passed open until their merge is approved in dclaude serve --team")
+	rootCmd.Flags().StringVar(&onReviewTimeout, "on-review-timeout", "continue", "What to do when a PR is still not reviewed after --review-wait twice: continue (leave it open and go on) or stop (stop the run)")
+	rootCmd.Flags().StringVar(&blockedWait, "blocked-wait", "24h", "How long to wait for instructions when Claude reports it is blocked before stopping the run (0 = stop right away)")
 
 	// Worktree options
 	rootCmd.Flags().StringVar(&worktree, "worktree", "", "Name for git worktree (parallel execution)")
 	rootCmd.Flags().StringVar(&worktreeBaseDir, "worktree-base-dir", "../deep-claude-worktrees", "Base directory for worktrees")
 	rootCmd.Flags().BoolVar(&cleanupWorktree, "cleanup-worktree", false, "Remove worktree after completion")
+	rootCmd.Flags().StringVar(&setupCmd, "setup-cmd", "", "Command that installs dependencies once per worktree before the first iteration (e.g. 'npm ci', 'go mod download')")
+	rootCmd.Flags().BoolVar(&sharedCache, "shared-cache", false, "Point Go, npm and pip caches at a directory shared by all worktrees")
+	rootCmd.Flags().StringVar(&partition, "partition", "", "Split the work into chunks, by dir (top-level directories), package (directories of files) or checklist (--migration-cmd items), and have each iteration claim chunks no other worker on the repository holds, so parallel runs don't open conflicting PRs")
+	rootCmd.Flags().BoolVar(&isolateResources, "isolate-resources", false, "Reserve a port range and temp dir for this worker, exposed to Claude and --verify-cmd")
+	rootCmd.Flags().IntVar(&portBase, "port-base", 20000, "First port handed out by --isolate-resources")
+	rootCmd.Flags().IntVar(&portsPerWorker, "ports-per-worker", 10, "Ports reserved for each worker by --isolate-resources")
+	rootCmd.Flags().StringArrayVar(&worktreeCopy, "worktree-copy", nil, "Untracked file or pattern to copy from the main tree into the worktree (repeatable, e.g. '.env*')")
+
+	// Commit message options
+	rootCmd.Flags().StringVar(&commitConvention, "commit-convention", "none", "Commit message convention to enforce: none, conventional")
+	rootCmd.Flags().StringVar(&commitPattern, "commit-pattern", "", "Regex the commit subject must match (e.g. '^[A-Z]+-[0-9]+ ')")
+	rootCmd.Flags().IntVar(&commitRetries, "commit-retries", 2, "Times to re-prompt Claude when its commit message is rejected")
+
+	// Changelog and release options
+	rootCmd.Flags().BoolVar(&updateChangelog, "update-changelog", false, "Add a changelog entry to each PR")
+	rootCmd.Flags().StringVar(&changelogFile, "changelog-file", "CHANGELOG.md", "Path to the changelog file")
+	rootCmd.Flags().IntVar(&releaseEvery, "release-every", 0, "Create a GitHub release after every N merged PRs (0 = disabled)")
+	rootCmd.Flags().BoolVar(&releaseOnComplete, "release-on-complete", false, "Create a GitHub release for unreleased merges when the run ends")
+
+	// Run summary options
+	rootCmd.Flags().StringVar(&publishSummary, "publish-summary", "", "Publish the run summary when the run ends: gist, comment")
+	rootCmd.Flags().StringVar(&summaryIssue, "summary-issue", "", "Issue or PR number to comment on with --publish-summary comment and --record-results comment")
+	rootCmd.Flags().StringVar(&publishReport, "publish-report", "", "Also publish the report of a --task-type analysis run: issue, gist")
 
 	// Update options
 	rootCmd.Flags().BoolVar(&autoUpdate, "auto-update", false, "Automatically install updates")
 	rootCmd.Flags().BoolVar(&disableUpdates, "disable-updates", false, "Skip update checks")
+	rootCmd.Flags().StringArrayVar(&retrySpecs, "retry", nil, "Retry policy for pushes, gh calls, update downloads and Claude runs, e.g. 'attempts=5,delay=1s'; prefix with push:, gh:, download: or claude: for one (repeatable)")
+	rootCmd.Flags().StringVar(&updateInterval, "update-interval", "24h", "Check for updates at most this often (e.g. '6h', '7d'; 0 = every run)")
+	rootCmd.Flags().StringVar(&updateChannel, "channel", string(version.ChannelStable), "Release channel to update from: stable, beta, nightly (remembered in your config)")
 
 	// Detach mode
 	rootCmd.Flags().BoolVarP(&detach, "detach", "d", false, "Run in background tmux session")
+	rootCmd.Flags().StringVar(&detachedConfig, "detached-config", "", "Config file a detached run's session reads instead of flags")
+	_ = rootCmd.Flags().MarkHidden("detached-config")
+	rootCmd.Flags().BoolVar(&restartOnCrash, "restart-on-crash", false, "Resume a detached run, with backoff, when its process dies without finishing")
+	rootCmd.Flags().IntVar(&maxRestarts, "max-restarts", 3, "Most times --restart-on-crash resumes a run")
+	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Print debug output, such as remaining GitHub API quota")
+	rootCmd.Flags().StringVar(&outputMode, "output", "terminal", "How to print the run: terminal, json (one object per line), silent")
+	rootCmd.Flags().StringVar(&logFile, "log-file", "", "Also log the run to this file as timestamped plain text")
+	rootCmd.Flags().BoolVar(&showFullOutput, "show-full-output", false, "Print Claude's whole output each iteration instead of its first --output-limit characters")
+	rootCmd.Flags().IntVar(&outputLimit, "output-limit", 500, "Characters of Claude's output printed each iteration (0 = all)")
+	rootCmd.Flags().BoolVar(&pageOutput, "page-output", false, "Open Claude's whole output in $PAGER after each iteration")
+	rootCmd.Flags().BoolVar(&asciiMode, "ascii", false, "Print plain-text symbols like [OK] and [WARN] instead of emoji and unicode ones (the default on terminals that can't show them)")
+	rootCmd.Flags().BoolVar(&ciMode, "ci", false, "Print timestamped progress lines instead of spinners (the default when not on a terminal)")
+	rootCmd.Flags().StringArrayVar(&redactEnv, "redact-env", nil, "Environment variable whose value is redacted from output, logs, transcripts and PRs (repeatable)")
 
 	// Add subcommands
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(listWorktreesCmd)
 	rootCmd.AddCommand(sessionsCmd)
+	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(attachCmd)
 	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.AddCommand(serveUserCmd)
+	serveUserCmd.AddCommand(serveUserAddCmd, serveUserListCmd, serveUserRemoveCmd)
+	rootCmd.AddCommand(queueCmd)
+	queueCmd.AddCommand(queueListCmd, queueBumpCmd, queueCancelCmd)
 	rootCmd.AddCommand(killCmd)
+	rootCmd.AddCommand(tellCmd)
+	rootCmd.AddCommand(eventsCmd)
+
+	rootCmd.AddCommand(replayCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(authCmd)
+	rootCmd.AddCommand(recipesCmd)
+	rootCmd.AddCommand(reviewCmd)
+	rootCmd.AddCommand(gcCmd)
+	rootCmd.AddCommand(prsCmd)
+	rootCmd.AddCommand(costsCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(reproCmd)
+	statusCmd.Flags().BoolVar(&statusAll, "all", false, "Show every run, including finished and older ones")
+	statusCmd.Flags().BoolVar(&statusRestart, "restart", false, "Resume the dead and hung runs, or the given ones, in new tmux sessions")
+	statusCmd.Flags().DurationVar(&statusHungAfter, "hung-after", time.Hour, "How long Claude may work on one request before the run is taken for hung")
+	reproCmd.Flags().BoolVar(&reproScript, "script", false, "Print a shell script that reproduces the run's setup")
+	configShowCmd.Flags().BoolVar(&configEffective, "effective", false, "Show every flag, with its built-in default if no config file sets it")
+	configCmd.AddCommand(configShowCmd, configValidateCmd, configSchemaCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(setupWizardCmd)
+	rootCmd.AddCommand(orgCmd)
+	orgCmd.AddCommand(orgRunCmd)
+	rootCmd.AddCommand(workspaceCmd)
+	workspaceCmd.AddCommand(workspaceListCmd, workspaceCleanCmd, workspaceUpdateCmd)
+	telemetryCmd.AddCommand(telemetryStatusCmd, telemetryEnableCmd, telemetryDisableCmd)
+	rootCmd.AddCommand(telemetryCmd)
+	historyCmd.AddCommand(historyExportCmd)
+	authCmd.AddCommand(authSetCmd, authStatusCmd, authDeleteCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+
+	logsCmd.Flags().BoolVar(&logsWeb, "web", false, "Serve a live, read-only view of the session over HTTP")
+	logsCmd.Flags().StringVar(&logsAddr, "addr", "127.0.0.1:8765", "Address for --web to listen on")
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8765", "Address to listen on")
+	serveCmd.Flags().BoolVar(&serveTeam, "team", false, "Serve the team API: sign-in, task queue, budgets and merge approvals")
+	serveCmd.Flags().BoolVar(&serveMergeApproval, "merge-approval", false, "With --team, have runs leave their PRs for a member to approve")
+	serveUserAddCmd.Flags().Float64Var(&serveUserBudget, "budget", 0, "Most the member's tasks may spend in total, in USD (0 = no cap)")
+	queueCmd.PersistentFlags().StringVar(&queueServer, "server", "http://127.0.0.1:8765", "Address of the team server, unless $"+serverEnv+" is set")
+	queueListCmd.Flags().BoolVar(&queueAll, "all", false, "Also list finished and canceled tasks")
+	queueBumpCmd.Flags().StringVar(&queuePriority, "priority", "", "Priority to set: low, normal, high or urgent (default one level up)")
+	eventsCmd.Flags().StringVar(&eventsType, "type", "", "Only print events of this type (e.g. pr_created)")
+	replayCmd.Flags().Float64Var(&replaySpeed, "speed", 10, "Playback speed relative to the original run (0 = instant)")
+	replayCmd.Flags().BoolVar(&replayFull, "full", false, "Show Claude's output in full instead of truncated")
+	reviewCmd.Flags().BoolVar(&reviewPrintOnly, "print", false, "Print the review instead of posting it")
+	gcCmd.Flags().StringVar(&gcPrefix, "prefix", "deep-claude/", "Only consider branches with this prefix")
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "List the stale branches without deleting them")
+	prsCmd.Flags().StringVar(&prsPrefix, "prefix", "deep-claude/", "Only consider PRs from branches with this prefix")
+	prsCmd.Flags().StringVar(&prsStale, "stale", "", "Only PRs without activity for this long (e.g., '7d', '36h')")
+	prsCmd.Flags().BoolVar(&prsClose, "close", false, "Close the stale PRs and delete their branches")
+	prsCmd.Flags().StringVar(&prsLabel, "label", "", "Add this label to the stale PRs")
+	costsCmd.Flags().StringArrayVar(&costsTags, "tag", nil, "Only runs with this cost tag (repeatable, e.g. team=payments)")
+	costsCmd.Flags().StringVar(&costsLabel, "label", "", "Only runs with this --label")
+	costsCmd.Flags().StringVar(&costsFormat, "format", "table", "Output format: table, csv, json")
+	auditCmd.Flags().StringVar(&auditFormat, "format", "table", "Output format: table, csv, json")
+	orgRunCmd.Flags().StringVar(&orgName, "org", "", "Organization or user whose repositories to run on")
+	orgRunCmd.Flags().StringVar(&orgTopic, "topic", "", "Only repositories tagged with this topic")
+	orgRunCmd.Flags().StringVarP(&orgPrompt, "prompt", "p", "", "Task to run against each repository")
+	orgRunCmd.Flags().IntVar(&orgLimit, "limit", 100, "Most repositories to run on")
+	orgRunCmd.Flags().StringVar(&orgWorkspace, "workspace", "", "Directory to clone the repositories into (default: the state directory)")
+	workspaceCleanCmd.Flags().StringVar(&workspaceOlderThan, "older-than", "", "Only repositories last run on longer ago than this, or never (e.g., '30d')")
+	workspaceCleanCmd.Flags().BoolVar(&workspaceDryRun, "dry-run", false, "List the repositories that would be removed without removing them")
+	workspaceCleanCmd.Flags().BoolVar(&workspaceYes, "yes", false, "Remove every owner's clones when no owner or repository is named")
+	historyCmd.PersistentFlags().StringVar(&historySince, "since", "", "Only runs started on or after this date (YYYY-MM-DD)")
+	historyCmd.PersistentFlags().StringVar(&historyLabel, "label", "", "Only runs with this --label")
+	historyExportCmd.Flags().StringVar(&historyFormat, "format", "csv", "Output format: csv, json")
+	updateCmd.Flags().StringVar(&updateFrom, "from", "", "Install from a downloaded release binary or .tar.gz instead of GitHub")
+	updateCmd.Flags().StringVar(&updateChecksum, "checksum", "", "SHA-256 checksum of the --from file")
+	updateCmd.Flags().StringVar(&updateSignature, "signature", "", "minisign signature of the --from file (default: <file>.minisig)")
+	updateCmd.Flags().StringVar(&updateChannel, "channel", string(version.ChannelStable), "Release channel to update from: stable, beta, nightly (remembered in your config)")
 }
 
 var versionCmd = &cobra.Command{
@@ -135,8 +465,30 @@ var updateCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		printer := ui.NewPrinter(false)
 
-		printer.Info("Checking for updates...")
-		latestVersion, hasUpdate, err := version.CheckForUpdates(appVersion)
+		if updateFrom != "" {
+			return updateFromFile(printer)
+		}
+		if updateChecksum != "" || updateSignature != "" {
+			return fmt.Errorf("--checksum and --signature require --from")
+		}
+
+		cwd, _ := os.Getwd()
+		given := cmd.Flags().Changed("channel")
+		if _, err := applySettings(cmd, cwd); err != nil {
+			return err
+		}
+		channel, err := resolveChannel(given)
+		if err != nil {
+			return err
+		}
+
+		printer.Info("Checking for updates on the %s channel...", channel)
+		path, err := updateCheckPath()
+		if err != nil {
+			return err
+		}
+		// Always check, refreshing the cache for later runs
+		latestVersion, hasUpdate, err := version.CheckForUpdatesCached(appVersion, channel, path, 0)
 		if err != nil {
 			return fmt.Errorf("failed to check for updates: %w", err)
 		}
@@ -148,13 +500,17 @@ var updateCmd = &cobra.Command{
 
 		printer.Info("New version available: %s (current: %s)", latestVersion, appVersion)
 
+		if version.PublicKey == "" {
+			printer.Warning("This build has no release signing key: only the checksum will be verified")
+		}
+
 		if !printer.Confirm("Would you like to update now?") {
 			printer.Info("Update cancelled")
 			return nil
 		}
 
 		printer.StartSpinner("Downloading update...")
-		tmpPath, err := version.DownloadUpdate(latestVersion)
+		tmpPath, err := version.DownloadUpdate(cmd.Context(), latestVersion)
 		printer.StopSpinner()
 
 		if err != nil {
@@ -213,6 +569,7 @@ Use arrow keys or j/k to navigate, Enter to attach, q to cancel.`,
 		if err != nil {
 			return err
 		}
+		warnDeadRuns(ui.NewPrinter(false))
 
 		if len(sessions) == 0 {
 			fmt.Println("No active sessions")
@@ -242,63 +599,40 @@ var attachCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		sessionName := args[0]
 
-		// Allow partial match - find session starting with the given name
-		sessions, err := tmux.ListSessions()
+		match, err := matchSession(sessionName)
 		if err != nil {
 			return err
 		}
 
-		var match string
-		for _, s := range sessions {
-			if s.Name == sessionName || strings.HasPrefix(s.Name, sessionName) {
-				if match != "" {
-					return fmt.Errorf("ambiguous session name '%s' - matches multiple sessions", sessionName)
-				}
-				match = s.Name
-			}
-		}
-
-		if match == "" {
-			fmt.Println("Session not found. Available sessions:")
-			for _, s := range sessions {
-				fmt.Printf("  %s\n", s.Name)
-			}
-			return fmt.Errorf("session '%s' not found", sessionName)
-		}
-
 		return tmux.AttachSession(match)
 	},
 }
 
+var (
+	logsWeb  bool
+	logsAddr string
+)
+
 var logsCmd = &cobra.Command{
 	Use:   "logs [session-name]",
 	Short: "View logs from a tmux session (read-only)",
-	Args:  cobra.ExactArgs(1),
+	Long: `Print the recent output of a tmux session.
+
+With --web, serve a live, read-only view of the session and its events
+over HTTP instead, for someone to watch it in a browser without access to
+your tmux server, e.g. through a tunnel:
+
+  dclaude logs dc-20250115-1430 --web --addr 127.0.0.1:8765`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		sessionName := args[0]
 
-		// Allow partial match
-		sessions, err := tmux.ListSessions()
+		match, err := matchSession(sessionName)
 		if err != nil {
 			return err
 		}
-
-		var match string
-		for _, s := range sessions {
-			if s.Name == sessionName || strings.HasPrefix(s.Name, sessionName) {
-				if match != "" {
-					return fmt.Errorf("ambiguous session name '%s' - matches multiple sessions", sessionName)
-				}
-				match = s.Name
-			}
-		}
-
-		if match == "" {
-			fmt.Println("Session not found. Available sessions:")
-			for _, s := range sessions {
-				fmt.Printf("  %s\n", s.Name)
-			}
-			return fmt.Errorf("session '%s' not found", sessionName)
+		if logsWeb {
+			return serveSession(cmd.Context(), match, logsAddr)
 		}
 
 		// Get last 1000 lines of logs
@@ -319,45 +653,261 @@ var killCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		sessionName := args[0]
 
-		// Allow partial match
-		sessions, err := tmux.ListSessions()
+		match, err := matchSession(sessionName)
 		if err != nil {
 			return err
 		}
 
-		var match string
-		for _, s := range sessions {
-			if s.Name == sessionName || strings.HasPrefix(s.Name, sessionName) {
-				if match != "" {
-					return fmt.Errorf("ambiguous session name '%s' - matches multiple sessions", sessionName)
-				}
-				match = s.Name
-			}
+		if err := tmux.KillSession(match); err != nil {
+			return err
 		}
 
-		if match == "" {
-			fmt.Println("Session not found. Available sessions:")
-			for _, s := range sessions {
-				fmt.Printf("  %s\n", s.Name)
+		fmt.Printf("Killed session: %s\n", match)
+		return nil
+	},
+}
+
+var tellCmd = &cobra.Command{
+	Use:   "tell [session-name] [message]",
+	Short: "Queue a message for the next iteration of a running session",
+	Long: `Queue a message that is added to the prompt of the next iteration of a
+running session, e.g.:
+
+  dclaude tell dc-250115-1430 "Focus on the API layer next"`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		match, err := matchSession(args[0])
+		if err != nil {
+			return err
+		}
+
+		dir, err := state.SessionInboxDir(match)
+		if err != nil {
+			return err
+		}
+		if _, err := inbox.New(dir).Add(strings.Join(args[1:], " ")); err != nil {
+			return err
+		}
+
+		fmt.Printf("Queued message for %s (delivered at the start of the next iteration)\n", match)
+		return nil
+	},
+}
+
+var eventsType string
+
+var eventsCmd = &cobra.Command{
+	Use:   "events [run-id]",
+	Short: "Print the event log of a run as JSON lines",
+	Long: `Print the machine-readable event log (events.jsonl) of a run.
+
+Without a run ID, lists recorded runs. The run ID may be a prefix or "latest".
+
+Example:
+  dclaude events latest --type pr_created`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runs, err := state.ListRuns()
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			if len(runs) == 0 {
+				fmt.Println("No runs recorded")
+				return nil
 			}
-			return fmt.Errorf("session '%s' not found", sessionName)
+			for _, id := range runs {
+				fmt.Println(id)
+			}
+			return nil
 		}
 
-		if err := tmux.KillSession(match); err != nil {
+		runID, err := matchRun(runs, args[0])
+		if err != nil {
+			return err
+		}
+		dir, err := state.RunDir(runID)
+		if err != nil {
 			return err
 		}
 
-		fmt.Printf("Killed session: %s\n", match)
+		list, err := events.Read(filepath.Join(dir, events.FileName), eventsType)
+		if err != nil {
+			return err
+		}
+		for _, e := range list {
+			line, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(line))
+		}
 		return nil
 	},
 }
 
+var (
+	replaySpeed float64
+	replayFull  bool
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay [run-id]",
+	Short: "Re-render a recorded run from its event log and transcripts",
+	Long: `Re-render a recorded run step by step from its event log and Claude's
+saved output, e.g. to walk through a post-mortem.
+
+The run ID may be a prefix or "latest". --speed divides the real time between
+events (pauses are capped at a few seconds); 0 replays instantly.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runs, err := state.ListRuns()
+		if err != nil {
+			return err
+		}
+		runID, err := matchRun(runs, args[0])
+		if err != nil {
+			return err
+		}
+		dir, err := state.RunDir(runID)
+		if err != nil {
+			return err
+		}
+
+		opts := replay.Options{Speed: replaySpeed, TranscriptLimit: 2000}
+		if replayFull {
+			opts.TranscriptLimit = 0
+		}
+		return replay.Replay(ui.NewPrinter(false), dir, opts)
+	},
+}
+
+// matchRun resolves a full or partial run ID, the name of the tmux session
+// the run was detached into, or "latest".
+func matchRun(runs []string, runID string) (string, error) {
+	if runID == "latest" {
+		if len(runs) == 0 {
+			return "", fmt.Errorf("no runs recorded")
+		}
+		return runs[len(runs)-1], nil
+	}
+
+	var match string
+	for _, id := range runs {
+		if strings.HasPrefix(id, runID) || strings.HasPrefix(runID+"-", tmux.SessionPrefix+id+"-") {
+			if match != "" {
+				return "", fmt.Errorf("ambiguous run ID '%s' - matches multiple runs", runID)
+			}
+			match = id
+		}
+	}
+	if match == "" {
+		return "", fmt.Errorf("run '%s' not found", runID)
+	}
+	return match, nil
+}
+
+// applyResume fills in the prompt and limits of the run being resumed,
+// unless they were given again on the command line.
+func applyResume(cmd *cobra.Command, cfg *config.Config, runID string) error {
+	runs, err := state.ListRuns()
+	if err != nil {
+		return err
+	}
+	if cfg.Resume, err = matchRun(runs, runID); err != nil {
+		return err
+	}
+	if cfg.RunID != "" && cfg.RunID != cfg.Resume {
+		return fmt.Errorf("--run-id can't be changed when resuming run %s", cfg.Resume)
+	}
+	cfg.RunID = cfg.Resume
+	run, err := state.LoadRunState(cfg.Resume)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Prompt == "" {
+		cfg.Prompt = run.Prompt
+	}
+	if !cmd.Flags().Changed("max-runs") {
+		cfg.MaxRuns = run.MaxRuns
+	}
+	if !cmd.Flags().Changed("max-cost") {
+		cfg.MaxCost = run.MaxCost
+	}
+	if !cmd.Flags().Changed("max-duration") {
+		cfg.MaxDuration = run.MaxDuration
+	}
+	if !cmd.Flags().Changed("label") {
+		cfg.Labels = run.Labels
+	}
+	return nil
+}
+
+// matchSession resolves a full or partial session name to a running session.
+func matchSession(sessionName string) (string, error) {
+	sessions, err := tmux.ListSessions()
+	if err != nil {
+		return "", err
+	}
+
+	var match string
+	for _, s := range sessions {
+		if s.Name == sessionName || strings.HasPrefix(s.Name, sessionName) {
+			if match != "" {
+				return "", fmt.Errorf("ambiguous session name '%s' - matches multiple sessions", sessionName)
+			}
+			match = s.Name
+		}
+	}
+
+	if match == "" {
+		fmt.Println("Session not found. Available sessions:")
+		for _, s := range sessions {
+			fmt.Printf("  %s\n", s.Name)
+		}
+		return "", fmt.Errorf("session '%s' not found", sessionName)
+	}
+	return match, nil
+}
+
 func runMain(cmd *cobra.Command, args []string) error {
 	// Get working directory
 	workDir, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("failed to get working directory: %w", err)
 	}
+	if detachedConfig != "" {
+		return runDetachedChild(cmd, workDir)
+	}
+
+	var tape *cassette.Cassette
+	if replayFile != "" {
+		if tape, err = loadCassette(cmd, replayFile); err != nil {
+			return err
+		}
+	}
+
+	channelGiven := cmd.Flags().Changed("channel")
+	if recipeName != "" {
+		if err := applyRecipe(cmd, recipeName); err != nil {
+			return err
+		}
+	}
+
+	// Defaults from the organization, user and repository config files
+	resolved, err := applySettings(cmd, workDir)
+	if err != nil {
+		return err
+	}
+	channel, err := resolveChannel(channelGiven)
+	if err != nil {
+		return err
+	}
+	if prompt, err = expandPrompt(cmd.Context(), workDir, prompt); err != nil {
+		return err
+	}
 
 	// Parse duration
 	duration, err := config.ParseDuration(maxDuration)
@@ -365,63 +915,293 @@ func runMain(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	iterationFor, err := config.ParseDuration(maxIterationDuration)
+	if err != nil {
+		return err
+	}
+
+	pushWait, err := config.ParseDuration(deferPushWait)
+	if err != nil {
+		return err
+	}
+
+	interval, err := config.ParseDuration(updateInterval)
+	if err != nil {
+		return err
+	}
+
+	reviewFor, err := config.ParseDuration(reviewWait)
+	if err != nil {
+		return err
+	}
+
+	blockedFor, err := config.ParseDuration(blockedWait)
+	if err != nil {
+		return err
+	}
+
+	deployFor, err := config.ParseDuration(deployTimeout)
+	if err != nil {
+		return err
+	}
+
+	tags, err := ledger.ParseTags(costTags)
+	if err != nil {
+		return err
+	}
+
+	policies := retry.Defaults()
+	for _, spec := range retrySpecs {
+		if err := policies.Apply(spec); err != nil {
+			return err
+		}
+	}
+	version.DownloadPolicy = policies.Download
+
 	// Build config
 	cfg := &config.Config{
 		Prompt:              prompt,
 		MaxRuns:             maxRuns,
 		MaxCost:             maxCost,
 		MaxDuration:         duration,
+		IterationTimeout:    iterationFor,
 		Owner:               owner,
 		Repo:                repo,
 		MergeStrategy:       mergeStrategy,
 		GitBranchPrefix:     gitBranchPrefix,
+		BranchMode:          branchMode,
+		SquashCommits:       squashCommits,
+		TaskType:            taskType,
+		MigrationCmd:        migrationCmd,
+		MigrationBatch:      migrationBatch,
+		GCBranches:          gcBranches,
+		ReadOnly:            readOnly,
+		PatchDir:            patchDir,
+		Simulate:            scenarioFile,
+		Record:              recordFile,
+		Replay:              replayFile,
 		NotesFile:           notesFile,
 		DisableCommits:      disableCommits,
+		StageAll:            stageAll,
+		StageExcludes:       stageExcludes,
+		Paths:               paths,
 		DryRun:              dryRun,
-		CompletionSignal:    completionSignal,
+		CompletionSignals:   completionSignals,
+		CompletionPattern:   completionRegex,
+		CompletionInSummary: completionInSummary,
 		CompletionThreshold: completionThreshold,
+		DirtyTree:           dirtyTree,
+		CompletionMode:      completionMode,
+		MinConfidence:       minConfidence,
+		StopOnNoProgress:    stopOnNoProgress,
+		RecordResults:       recordResults,
+		RepoMapIterations:   repoMapIterations,
+		PromptTokenBudget:   promptTokenBudget,
+		VerifyCmd:           verifyCmd,
+		VerifyStreak:        verifyStreak,
+		AffectedTests:       affectedTests,
+		TestMap:             testMap,
+		FullTests:           fullTests,
+		FullTestEvery:       fullTestEvery,
+		CoverageCmd:         coverageCmd,
+		MinCoverage:         minCoverage,
+		MinCoverageDelta:    minCoverageDelta,
+		MutationCmd:         mutationCmd,
+		MinMutationScore:    minMutationScore,
+		StopOnIssueClosed:   stopOnIssueClosed,
+		CostTags:            tags,
+		Labels:              labels,
+		PricingModel:        pricingModel,
+		Pricing:             pricing,
+		DeferPush:           deferPush,
+		MaxPRsPerHour:       maxPRsPerHour,
+		MaxPRsPerDay:        maxPRsPerDay,
+		Pipeline:            pipeline,
+		PipelineDepth:       pipelineDepth,
+		DeferPushWait:       pushWait,
+		BlockedWait:         blockedFor,
+		MergeConfidence:     mergeConfidence,
+		DraftConfidence:     draftConfidence,
+		RevertOnMainFailure: revertOnMainFailure,
+		DeployEnvironment:   deployEnvironment,
+		DeployCheck:         deployCheck,
+		DeployTimeout:       deployFor,
+		CheckRun:            checkRun,
+		CommitStatuses:      commitStatuses,
+		CommentCommands:     commentCommands,
+		FixCommits:          fixCommits,
+		ReviewWait:          reviewFor,
+		OnReviewTimeout:     onReviewTimeout,
+		MergeApproval:       mergeApproval,
+		PublishSummary:      publishSummary,
+		SummaryIssue:        summaryIssue,
+		PublishReport:       publishReport,
 		Worktree:            worktree,
 		WorktreeBaseDir:     worktreeBaseDir,
 		CleanupWorktree:     cleanupWorktree,
+		WorktreeCopy:        worktreeCopy,
+		IsolateResources:    isolateResources,
+		PortBase:            portBase,
+		PortsPerWorker:      portsPerWorker,
+		SetupCmd:            setupCmd,
+		UpgradeDeps:         upgradeDeps,
+		UpgradePolicy:       upgradePolicy,
+		UpgradeBatch:        upgradeBatch,
+		UpgradeIgnore:       upgradeIgnore,
+		LintFix:             lintFix,
+		LintCmds:            lintCmds,
+		LintBatch:           lintBatch,
+		Backport:            strings.TrimPrefix(backport, "#"),
+		BackportTo:          backportTo,
+		SharedCache:         sharedCache,
+		Partition:           partition,
+		CommitConvention:    commitConvention,
+		CommitPattern:       commitPattern,
+		CommitRetries:       commitRetries,
+		UpdateChangelog:     updateChangelog,
+		ChangelogFile:       changelogFile,
+		ReleaseEvery:        releaseEvery,
+		ReleaseOnComplete:   releaseOnComplete,
 		AutoUpdate:          autoUpdate,
 		DisableUpdates:      disableUpdates,
+		UpdateInterval:      interval,
+		Retry:               policies,
+		Detach:              detach,
+		RestartOnCrash:      restartOnCrash,
+		MaxRestarts:         maxRestarts,
+		Verbose:             verbose,
+		Output:              outputMode,
+		LogFile:             logFile,
+		CI:                  ciMode,
+		ASCII:               asciiMode,
+		ShowFullOutput:      showFullOutput,
+		OutputLimit:         outputLimit,
+		PageOutput:          pageOutput,
+		RedactEnv:           redactEnv,
+		RunID:               assignedRunID,
 		ExtraClaudeArgs:     args, // Pass remaining args to Claude
 	}
+	cfg.SessionName = tmux.CurrentSession()
+
+	if resume != "" {
+		if err := applyResume(cmd, cfg, resume); err != nil {
+			return err
+		}
+	}
+	if cfg.RunID == "" {
+		cfg.RunID = state.NewRunID()
+	}
+	if err := resolved.ApplyCaps(cfg); err != nil {
+		return err
+	}
+
+	var scenario *simulate.Scenario
+	if cfg.Simulate != "" {
+		if scenario, err = simulate.Load(cfg.Simulate); err != nil {
+			return err
+		}
+		// Without a limit, a simulation stops when its scenario runs out of turns
+		if !hasLimit(cfg) {
+			cfg.MaxRuns = max(len(scenario.Turns), 1)
+		}
+	}
+	// and a replay when the recorded Claude runs run out
+	if tape != nil && !hasLimit(cfg) {
+		cfg.MaxRuns = max(tape.Unplayed()["claude.Run"], 1)
+	}
 
 	// Validate config
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
 
-	// Handle detach mode - spawn tmux session and exit
-	if detach {
-		return runDetached(workDir, cfg)
+	if tape != nil {
+		return runReplay(cmd, workDir, cfg, tape)
+	}
+	if scenario != nil {
+		return runSimulation(cmd, workDir, cfg, scenario)
 	}
 
-	printer := ui.NewPrinter(false)
-	createdRepo, err := ensureGitHubRepo(printer, workDir)
+	printer, err := ui.Open(ui.Options{Output: cfg.Output, LogFile: cfg.LogFile, CI: cfg.CI, ASCII: cfg.ASCII})
 	if err != nil {
 		return err
 	}
-	if err := ensureInitialCommitAndPush(printer, workDir, createdRepo); err != nil {
-		return err
+	defer printer.Close()
+	exportCredentials(printer)
+	// A read-only run must not create the repository or push to it
+	if !cfg.ReadOnly {
+		createdRepo, err := ensureGitHubRepo(cmd.Context(), printer, workDir)
+		if err != nil {
+			return err
+		}
+		if err := ensureInitialCommitAndPush(cmd.Context(), printer, workDir, createdRepo); err != nil {
+			return err
+		}
 	}
 
 	// Check for updates (unless disabled)
 	if !cfg.DisableUpdates {
-		checkUpdates(cfg.AutoUpdate)
+		checkUpdates(cmd.Context(), cfg.AutoUpdate, channel, cfg.UpdateInterval)
+	}
+
+	run := detachedRun{
+		Config:    cfg,
+		Flags:     recordedFlags(cmd),
+		Features:  usedFlags(cmd),
+		Telemetry: telemetry.Resolve(resolved).Enabled,
+	}
+	// Handle detach mode - spawn tmux session and exit
+	if detach {
+		return runDetached(workDir, run)
+	}
+	return runForeground(cmd.Context(), printer, workDir, run)
+}
+
+// runForeground runs the loop in this process, in a worktree if the run
+// has one. A detached run's session gets here with the run its parent
+// validated.
+func runForeground(ctx context.Context, printer ui.Printer, workDir string, run detachedRun) error {
+	cfg := run.Config
+	runDir := workDir
+	if cfg.Worktree != "" {
+		var err error
+		if runDir, err = prepareWorktree(ctx, printer, workDir, cfg); err != nil {
+			return err
+		}
+		if cfg.CleanupWorktree {
+			defer removeWorktree(printer, workDir, runDir)
+		}
+	}
+
+	if dir, err := state.RunDir(cfg.RunID); err == nil {
+		if err := repro.Save(dir, repro.Capture(ctx, workDir, appVersion, run.Flags, cfg)); err != nil {
+			printer.Warning("Could not record the run's environment: %v", err)
+		}
 	}
 
 	// Create and run orchestrator
-	orch, err := orchestrator.New(cfg, workDir)
+	g, newGitHub, c := orchestrator.Runners(cfg, runDir)
+	orch, finish, err := newOrchestrator(run.Flags, printer, cfg, runDir, g, newGitHub, c)
 	if err != nil {
 		return err
 	}
+	defer finish()
 
-	return orch.Run()
+	ctx, stop := interruptible(ctx)
+	defer stop()
+	err = orch.Run(ctx)
+	if run.Telemetry {
+		usage := orch.Usage()
+		if err != nil {
+			usage.Outcome = "error"
+			usage.Failures[telemetry.Categorize(err)]++
+		}
+		sendTelemetry(usage, run.Features, cfg.Verbose)
+	}
+	return err
 }
 
-func ensureGitHubRepo(printer *ui.Printer, workDir string) (bool, error) {
+func ensureGitHubRepo(ctx context.Context, printer ui.Printer, workDir string) (bool, error) {
 	gitClient := git.NewClient(workDir)
 	if gitClient.IsRepo() {
 		return false, nil
@@ -446,13 +1226,13 @@ func ensureGitHubRepo(printer *ui.Printer, workDir string) (bool, error) {
 	private := !printer.Confirm("Create repository as public?")
 
 	ghClient := github.NewClient("", "", workDir)
-	if err := ghClient.CheckAuth(); err != nil {
+	if err := ghClient.CheckAuth(ctx); err != nil {
 		return false, err
 	}
 	if err := gitClient.InitRepo(); err != nil {
 		return false, err
 	}
-	if err := ghClient.CreateRepo(repoName, private, owner); err != nil {
+	if err := ghClient.CreateRepo(ctx, repoName, private, owner); err != nil {
 		return false, err
 	}
 
@@ -460,7 +1240,7 @@ func ensureGitHubRepo(printer *ui.Printer, workDir string) (bool, error) {
 	return true, nil
 }
 
-func ensureInitialCommitAndPush(printer *ui.Printer, workDir string, skipConfirm bool) error {
+func ensureInitialCommitAndPush(ctx context.Context, printer ui.Printer, workDir string, skipConfirm bool) error {
 	gitClient := git.NewClient(workDir)
 	if gitClient.HasCommits() {
 		return nil
@@ -488,7 +1268,7 @@ func ensureInitialCommitAndPush(printer *ui.Printer, workDir string, skipConfirm
 	if err != nil {
 		return err
 	}
-	if err := gitClient.Push(branch); err != nil {
+	if err := gitClient.Push(ctx, branch); err != nil {
 		return err
 	}
 
@@ -496,10 +1276,65 @@ func ensureInitialCommitAndPush(printer *ui.Printer, workDir string, skipConfirm
 	return nil
 }
 
-func checkUpdates(autoInstall bool) {
+// resolveChannel validates --channel and, if it was given on the command
+// line, remembers it in the user config for later runs and updates.
+func resolveChannel(given bool) (version.Channel, error) {
+	channel, err := version.ParseChannel(updateChannel)
+	if err != nil {
+		return "", err
+	}
+	if given {
+		if err := settings.SetUserFlag("channel", string(channel)); err != nil {
+			return "", fmt.Errorf("failed to remember update channel: %w", err)
+		}
+	}
+	return channel, nil
+}
+
+// updateFromFile installs an update from a downloaded artifact, for
+// machines that can't reach GitHub.
+func updateFromFile(printer ui.Printer) error {
+	if updateChecksum == "" {
+		return fmt.Errorf("--from requires --checksum")
+	}
+	if version.PublicKey == "" {
+		printer.Warning("This build has no release signing key: only the checksum will be verified")
+	}
+
+	tmpPath, err := version.LocalUpdate(updateFrom, updateChecksum, updateSignature)
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", updateFrom, err)
+	}
+
+	printer.StartSpinner("Installing update...")
+	if err := version.InstallUpdate(tmpPath); err != nil {
+		printer.StopSpinner()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to install update: %w", err)
+	}
+	printer.StopSpinner()
+
+	printer.Success("Updated from %s", updateFrom)
+	return nil
+}
+
+// updateCheckPath returns where update checks are cached.
+func updateCheckPath() (string, error) {
+	dir, err := state.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, version.CheckFile), nil
+}
+
+func checkUpdates(ctx context.Context, autoInstall bool, channel version.Channel, interval time.Duration) {
 	printer := ui.NewPrinter(false)
 
-	latestVersion, hasUpdate, err := version.CheckForUpdates(appVersion)
+	path, err := updateCheckPath()
+	if err != nil {
+		return
+	}
+	latestVersion, hasUpdate, err := version.CheckForUpdatesCached(appVersion, channel, path, interval)
 	if err != nil {
 		// Silently ignore update check errors
 		return
@@ -509,9 +1344,11 @@ func checkUpdates(autoInstall bool) {
 		return
 	}
 
-	if autoInstall {
+	if autoInstall && version.PublicKey == "" {
+		printer.Warning("Not installing %s automatically: this build has no release signing key to verify it (run 'dclaude update' to install)", latestVersion)
+	} else if autoInstall {
 		printer.Info("Installing update %s...", latestVersion)
-		tmpPath, err := version.DownloadUpdate(latestVersion)
+		tmpPath, err := version.DownloadUpdate(ctx, latestVersion)
 		if err != nil {
 			printer.Warning("Failed to download update: %v", err)
 			return
@@ -530,7 +1367,9 @@ func checkUpdates(autoInstall bool) {
 }
 
 // runDetached spawns a tmux session running dclaude and returns immediately.
-func runDetached(workDir string, cfg *config.Config) error {
+// The session's dclaude reads the validated run from a file rather than
+// flags, so it runs exactly what a foreground run would.
+func runDetached(workDir string, run detachedRun) error {
 	printer := ui.NewPrinter(false)
 
 	// Check tmux availability
@@ -539,10 +1378,12 @@ func runDetached(workDir string, cfg *config.Config) error {
 	}
 
 	// Generate session name
-	sessionName := tmux.GenerateSessionName(cfg.Prompt)
+	sessionName := tmux.GenerateSessionName(run.Config.RunID, run.Config.Prompt)
 
-	// Build command arguments (same as current, but without -d)
-	cmdArgs := buildCommandArgs(cfg)
+	path, err := writeDetachedRun(run)
+	if err != nil {
+		return err
+	}
 
 	// Get the executable path
 	executable, err := os.Executable()
@@ -550,11 +1391,9 @@ func runDetached(workDir string, cfg *config.Config) error {
 		return fmt.Errorf("failed to get executable path: %w", err)
 	}
 
-	// Build full command
-	fullCmd := append([]string{executable}, cmdArgs...)
-
 	// Create tmux session
-	if err := tmux.CreateSession(sessionName, fullCmd, workDir); err != nil {
+	if err := tmux.CreateSession(sessionName, []string{executable, "--detached-config", path}, workDir); err != nil {
+		os.Remove(path)
 		return fmt.Errorf("failed to create tmux session: %w", err)
 	}
 
@@ -565,77 +1404,3 @@ func runDetached(workDir string, cfg *config.Config) error {
 
 	return nil
 }
-
-// buildCommandArgs builds the command line arguments from config (excluding -d flag).
-func buildCommandArgs(cfg *config.Config) []string {
-	var args []string
-
-	// Required
-	args = append(args, "-p", cfg.Prompt)
-
-	// Limits
-	if cfg.MaxRuns > 0 {
-		args = append(args, "-m", fmt.Sprintf("%d", cfg.MaxRuns))
-	}
-	if cfg.MaxCost > 0 {
-		args = append(args, "--max-cost", fmt.Sprintf("%.2f", cfg.MaxCost))
-	}
-	if cfg.MaxDuration > 0 {
-		args = append(args, "--max-duration", config.FormatDuration(cfg.MaxDuration))
-	}
-
-	// GitHub/Git options
-	if cfg.Owner != "" {
-		args = append(args, "--owner", cfg.Owner)
-	}
-	if cfg.Repo != "" {
-		args = append(args, "--repo", cfg.Repo)
-	}
-	if cfg.MergeStrategy != "squash" {
-		args = append(args, "--merge-strategy", cfg.MergeStrategy)
-	}
-	if cfg.GitBranchPrefix != "deep-claude/" {
-		args = append(args, "--git-branch-prefix", cfg.GitBranchPrefix)
-	}
-	if cfg.NotesFile != "SHARED_TASK_NOTES.md" {
-		args = append(args, "--notes-file", cfg.NotesFile)
-	}
-
-	// Execution options
-	if cfg.DisableCommits {
-		args = append(args, "--disable-commits")
-	}
-	if cfg.DryRun {
-		args = append(args, "--dry-run")
-	}
-	if cfg.CompletionSignal != "DEEP_CLAUDE_PROJECT_COMPLETE" {
-		args = append(args, "--completion-signal", cfg.CompletionSignal)
-	}
-	if cfg.CompletionThreshold != 3 {
-		args = append(args, "--completion-threshold", fmt.Sprintf("%d", cfg.CompletionThreshold))
-	}
-
-	// Worktree options
-	if cfg.Worktree != "" {
-		args = append(args, "--worktree", cfg.Worktree)
-	}
-	if cfg.WorktreeBaseDir != "../deep-claude-worktrees" {
-		args = append(args, "--worktree-base-dir", cfg.WorktreeBaseDir)
-	}
-	if cfg.CleanupWorktree {
-		args = append(args, "--cleanup-worktree")
-	}
-
-	// Update options
-	if cfg.AutoUpdate {
-		args = append(args, "--auto-update")
-	}
-	if cfg.DisableUpdates {
-		args = append(args, "--disable-updates")
-	}
-
-	// Extra Claude args
-	args = append(args, cfg.ExtraClaudeArgs...)
-
-	return args
-}
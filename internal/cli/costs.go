@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/ledger"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	costsTags   []string
+	costsLabel  string
+	costsFormat string
+)
+
+var costsCmd = &cobra.Command{
+	Use:   "costs",
+	Short: "Show or export the cost ledger of all runs",
+	Long: `Show what each run spent, with the tags given by --cost-tag, or export the
+ledger for attributing spend to teams and projects. --tag keeps only runs
+with that tag, and --label only runs given that --label.
+
+  dclaude costs
+  dclaude costs --tag team=payments
+  dclaude costs --label refactor-auth
+  dclaude costs --format csv > costs.csv`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := ledger.ParseTags(costsTags)
+		if err != nil {
+			return err
+		}
+		path, err := state.LedgerPath()
+		if err != nil {
+			return err
+		}
+		entries, err := ledger.Read(path)
+		if err != nil {
+			return err
+		}
+		entries = ledger.Filter(entries, filter)
+		if costsLabel != "" {
+			entries = ledger.WithLabel(entries, costsLabel)
+		}
+
+		switch costsFormat {
+		case "csv":
+			return ledger.WriteCSV(os.Stdout, entries)
+		case "json":
+			return ledger.WriteJSON(os.Stdout, entries)
+		case "table":
+		default:
+			return fmt.Errorf("--format must be one of: table, csv, json")
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No costs recorded")
+			return nil
+		}
+		rows := make([][]string, len(entries))
+		for i, e := range entries {
+			rows[i] = []string{
+				e.RunID,
+				e.Repo,
+				fmt.Sprintf("%d", e.Iterations),
+				fmt.Sprintf("$%.4f", e.Cost),
+				strings.Join(ledger.FormatTags(e.Tags), " "),
+			}
+		}
+		printer := ui.NewPrinter(false)
+		printer.Table([]string{"RUN", "REPO", "ITERATIONS", "COST", "TAGS"}, rows)
+		fmt.Printf("\nTotal: $%.4f over %d run(s)\n", ledger.Total(entries), len(entries))
+		return nil
+	},
+}
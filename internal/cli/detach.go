@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/retry"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/tmux"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/events"
+	"github.com/spf13/cobra"
+)
+
+// detachedRun is a validated run, as a detached run's session reads it
+// instead of rebuilding it from flags.
+type detachedRun struct {
+	Config *config.Config `json:"config"`
+	// Flags the run was given, for --record
+	Flags map[string][]string `json:"flags"`
+	// Names of the flags, for telemetry
+	Features  []string `json:"features"`
+	Telemetry bool     `json:"telemetry"`
+}
+
+// writeDetachedRun saves a run to a private temporary file and returns
+// its path.
+func writeDetachedRun(run detachedRun) (string, error) {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode run: %w", err)
+	}
+	f, err := os.CreateTemp("", "dclaude-run-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to create run file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write run file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// readDetachedRun loads a run saved by writeDetachedRun and removes its
+// file, which is only read once.
+func readDetachedRun(path string) (detachedRun, error) {
+	var run detachedRun
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return run, fmt.Errorf("failed to read run file: %w", err)
+	}
+	os.Remove(path)
+	if err := json.Unmarshal(data, &run); err != nil {
+		return run, fmt.Errorf("failed to parse run file %s: %w", path, err)
+	}
+	if run.Config == nil {
+		return run, fmt.Errorf("run file %s has no config", path)
+	}
+	return run, nil
+}
+
+// runDetachedChild runs a detached run in its tmux session. Its parent
+// already bootstrapped the repository and checked for updates.
+func runDetachedChild(cmd *cobra.Command, workDir string) error {
+	run, err := readDetachedRun(detachedConfig)
+	if err != nil {
+		return err
+	}
+	cfg := run.Config
+	supervise := cfg.RestartOnCrash
+	cfg.Detach = false
+	cfg.RestartOnCrash = false
+	cfg.SessionName = tmux.CurrentSession()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	printer, err := ui.Open(ui.Options{Output: cfg.Output, LogFile: cfg.LogFile, CI: cfg.CI, ASCII: cfg.ASCII})
+	if err != nil {
+		return err
+	}
+	defer printer.Close()
+	exportCredentials(printer)
+	if supervise {
+		return superviseRun(cmd.Context(), printer, workDir, run)
+	}
+	return runForeground(cmd.Context(), printer, workDir, run)
+}
+
+// crashBackoff is how long a supervised run's session waits before each
+// restart.
+var crashBackoff = retry.Policy{Delay: 30 * time.Second, Multiplier: 2, MaxDelay: 10 * time.Minute, Jitter: 0.1}
+
+// superviseRun runs a detached run in a child process, and resumes it in
+// another each time the process dies without the run exiting, e.g. killed
+// for memory, up to cfg.MaxRestarts times. Ctrl-C in the session stops the
+// run cleanly by itself, so it is not restarted.
+func superviseRun(ctx context.Context, printer ui.Printer, workDir string, run detachedRun) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	cfg := run.Config
+	for restarts := 0; ; restarts++ {
+		path, err := writeDetachedRun(run)
+		if err != nil {
+			return err
+		}
+		child := exec.Command(executable, "--detached-config", path)
+		child.Dir = workDir
+		child.Stdin, child.Stdout, child.Stderr = os.Stdin, os.Stdout, os.Stderr
+		err = child.Run()
+		os.Remove(path)
+		var exit *exec.ExitError
+		if err != nil && !errors.As(err, &exit) {
+			return fmt.Errorf("failed to start run: %w", err)
+		}
+
+		s, loadErr := state.LoadRunState(cfg.RunID)
+		if loadErr != nil || s.Exited {
+			// Finished, stopped, or failed before it got going; the run
+			// reported which itself
+			return nil
+		}
+		cause := "exited"
+		if err != nil {
+			cause = err.Error()
+		}
+		if restarts >= cfg.MaxRestarts {
+			noticeCrash(ctx, printer, workDir, cfg, fmt.Sprintf("Deep Claude run `%s` crashed (%s) after %d restart(s); not restarting it again. Resume it with `dclaude --resume %s`.", cfg.RunID, cause, restarts, cfg.RunID),
+				map[string]any{"error": cause, "restarts": restarts, "gave_up": true})
+			return fmt.Errorf("run %s crashed %d time(s)", cfg.RunID, restarts+1)
+		}
+
+		wait := crashBackoff.Wait(restarts)
+		noticeCrash(ctx, printer, workDir, cfg, fmt.Sprintf("Deep Claude run `%s` crashed (%s) in iteration %d; resuming it in %s (restart %d of %d).", cfg.RunID, cause, s.Iterations+1, config.FormatDuration(wait.Round(time.Second)), restarts+1, cfg.MaxRestarts),
+			map[string]any{"error": cause, "restarts": restarts + 1, "wait_sec": wait.Seconds()})
+		select {
+		case <-time.After(wait):
+		case <-interrupts:
+			printer.Info("Not restarting; resume the run with dclaude --resume %s", cfg.RunID)
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		cfg.Resume = cfg.RunID
+	}
+}
+
+// noticeCrash tells of a crash in the session and the run's event log,
+// and, like the run's other notices, on its summary issue.
+func noticeCrash(ctx context.Context, printer ui.Printer, workDir string, cfg *config.Config, message string, data map[string]any) {
+	printer.Warning("%s", message)
+	if dir, err := state.RunDir(cfg.RunID); err == nil {
+		if log, err := events.Create(filepath.Join(dir, events.FileName), cfg.RunID); err == nil {
+			log.Emit(events.RunCrashed, 0, data)
+			log.Close()
+		}
+	}
+
+	if cfg.PublishSummary != "comment" {
+		return
+	}
+	if len(cfg.Labels) > 0 {
+		message += "\n\nLabels: `" + strings.Join(cfg.Labels, "`, `") + "`"
+	}
+	url, err := github.NewClient(cfg.Owner, cfg.Repo, workDir).CommentOnIssue(ctx, strings.TrimPrefix(cfg.SummaryIssue, "#"), message)
+	if err != nil {
+		printer.Warning("Could not post notice to %s: %v", cfg.SummaryIssue, err)
+		return
+	}
+	printer.Info("Posted notice: %s", url)
+}
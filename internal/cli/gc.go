@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/gc"
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcPrefix string
+	gcDryRun bool
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete remote branches whose PRs are closed or merged",
+	Long: `Find branches on origin with the branch prefix whose pull requests are all
+closed or merged, and delete them. Branches without a PR, or with an open
+one, are kept.
+
+  dclaude gc --dry-run   # list the branches that would be deleted
+  dclaude gc`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workDir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		gitClient := git.NewClient(workDir)
+		if !gitClient.IsRepo() {
+			return fmt.Errorf("not in a git repository")
+		}
+		if strings.TrimSpace(gcPrefix) == "" {
+			return fmt.Errorf("--prefix must not be empty")
+		}
+
+		printer := ui.NewPrinter(false)
+		exportCredentials(printer)
+
+		printer.StartSpinner("Finding stale branches...")
+		stale, err := gc.Find(cmd.Context(), gitClient, github.NewClient("", "", workDir), gcPrefix)
+		printer.StopSpinner()
+		if err != nil {
+			return err
+		}
+		if len(stale) == 0 {
+			printer.Info("No stale branches with prefix %s", gcPrefix)
+			return nil
+		}
+
+		rows := make([][]string, len(stale))
+		for i, b := range stale {
+			rows[i] = []string{b.Name, fmt.Sprintf("#%d", b.PR), strings.ToLower(b.State)}
+		}
+		printer.Table([]string{"BRANCH", "PR", "STATE"}, rows)
+
+		if gcDryRun {
+			printer.Info("Dry run, %d branch(es) would be deleted", len(stale))
+			return nil
+		}
+		if err := gc.Delete(cmd.Context(), gitClient, stale); err != nil {
+			return err
+		}
+		printer.Success("Deleted %d branch(es)", len(stale))
+		return nil
+	},
+}
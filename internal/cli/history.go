@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/guzus/deep-claude/internal/history"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historySince  string
+	historyLabel  string
+	historyFormat string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List recorded runs with their cost and outcome",
+	Long: `List the recorded runs with their iterations, cost, outcome and PRs, built
+from their event logs. Use "history export" for spreadsheet-ready data.
+
+  dclaude history --since 2025-01-01
+  dclaude history --label refactor-auth`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runs, err := loadHistory()
+		if err != nil {
+			return err
+		}
+		if len(runs) == 0 {
+			fmt.Println("No runs recorded")
+			return nil
+		}
+
+		var total float64
+		rows := make([][]string, len(runs))
+		for i, r := range runs {
+			total += r.Cost
+			rows[i] = []string{
+				r.RunID,
+				r.Repo,
+				fmt.Sprintf("%d", r.Iterations),
+				fmt.Sprintf("$%.4f", r.Cost),
+				fmt.Sprintf("%d/%d", r.Merged(), len(r.PRs)),
+				r.Outcome,
+			}
+		}
+		ui.NewPrinter(false).Table([]string{"RUN", "REPO", "ITERATIONS", "COST", "MERGED", "OUTCOME"}, rows)
+		fmt.Printf("\nTotal: $%.4f over %d run(s)\n", total, len(runs))
+		return nil
+	},
+}
+
+var historyExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export recorded runs as CSV or JSON",
+	Long: `Export one record per run (iterations, cost, outcome, PRs opened and merged,
+PR URLs) for reporting.
+
+  dclaude history export --format csv --since 2025-01-01 > runs.csv
+  dclaude history export --format json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if historyFormat != "csv" && historyFormat != "json" {
+			return fmt.Errorf("--format must be one of: csv, json")
+		}
+		runs, err := loadHistory()
+		if err != nil {
+			return err
+		}
+		if historyFormat == "json" {
+			return history.WriteJSON(os.Stdout, runs)
+		}
+		return history.WriteCSV(os.Stdout, runs)
+	},
+}
+
+func loadHistory() ([]history.Run, error) {
+	since, err := history.ParseSince(historySince)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := history.Load(since)
+	if err != nil || historyLabel == "" {
+		return runs, err
+	}
+	var labeled []history.Run
+	for _, r := range runs {
+		if r.HasLabel(historyLabel) {
+			labeled = append(labeled, r)
+		}
+	}
+	return labeled, nil
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/guzus/deep-claude/internal/observe"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/tmux"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// observeLines is how much of a session's scrollback the web view shows.
+const observeLines = 1000
+
+// serveSession serves a read-only live view of a session on addr until
+// Ctrl-C.
+func serveSession(ctx context.Context, session, addr string) error {
+	src := observe.Source{
+		Session: session,
+		Pane:    func() (string, error) { return tmux.GetSessionLogs(session, observeLines) },
+	}
+	if runs, err := state.ListRuns(); err == nil {
+		if runID, err := matchRun(runs, session); err == nil {
+			if dir, err := state.RunDir(runID); err == nil {
+				src.Events = filepath.Join(dir, events.FileName)
+			}
+		}
+	}
+	return listenAndServe(ctx, addr, observe.Handler(src), "a read-only view of "+session)
+}
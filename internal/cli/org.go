@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/history"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/guzus/deep-claude/pkg/events"
+	"github.com/spf13/cobra"
+)
+
+var (
+	orgName      string
+	orgTopic     string
+	orgPrompt    string
+	orgLimit     int
+	orgWorkspace string
+)
+
+var orgCmd = &cobra.Command{
+	Use:   "org",
+	Short: "Run a task across the repositories of a GitHub organization",
+}
+
+var orgRunCmd = &cobra.Command{
+	Use:   "run [-- run flags]",
+	Short: "Run a task against every repository of an organization matching a filter",
+	Long: `Run the same task against each repository of an organization (or user) that
+isn't archived or a fork, optionally only those tagged with --topic. Each
+repository is cloned into a workspace, or updated if it was cloned before,
+and gets a run of its own, one after another. Flags after -- are passed to
+every run. A table of the runs is printed at the end, and saved as JSON in
+the workspace.
+
+  dclaude org run --org myorg --topic needs-migration -p "Migrate to the v2 SDK"
+  dclaude org run --org myorg -p "Fix lint warnings" -- -m 3 --max-cost 5
+
+Ctrl-C stops the current run cleanly and skips the remaining repositories.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if orgName == "" || orgPrompt == "" {
+			return fmt.Errorf("--org and --prompt are required")
+		}
+		workspace, err := state.WorkspaceDir(orgName)
+		if err != nil {
+			return err
+		}
+		if orgWorkspace != "" {
+			if workspace, err = filepath.Abs(orgWorkspace); err != nil {
+				return fmt.Errorf("failed to resolve workspace: %w", err)
+			}
+		}
+		if err := os.MkdirAll(workspace, 0755); err != nil {
+			return fmt.Errorf("failed to create workspace: %w", err)
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to get executable path: %w", err)
+		}
+
+		printer := ui.NewPrinter(false)
+		exportCredentials(printer)
+		ghClient := github.NewClient("", "", workspace)
+		repos, err := ghClient.ListRepos(cmd.Context(), orgName, orgTopic, orgLimit)
+		if err != nil {
+			return err
+		}
+		if len(repos) == 0 {
+			printer.Info("No repositories of %s match", orgName)
+			return nil
+		}
+		printer.Info("Running on %d repositories of %s in %s", len(repos), orgName, workspace)
+
+		// Runs stop cleanly on Ctrl-C by themselves, so they aren't killed;
+		// the remaining repositories are skipped instead
+		ctx, stop := interruptible(cmd.Context())
+		defer stop()
+		runChild := func(ctx context.Context, dir, runID string) error {
+			child := exec.Command(executable, append([]string{"-p", orgPrompt, "--run-id", runID, "--disable-updates"}, args...)...)
+			child.Dir = dir
+			child.Stdin, child.Stdout, child.Stderr = os.Stdin, os.Stdout, os.Stderr
+			return child.Run()
+		}
+		var runs []history.Run
+		var failed int
+		for i, repo := range repos {
+			if ctx.Err() != nil {
+				printer.Warning("Skipping the remaining %d repositories", len(repos)-i)
+				break
+			}
+			printer.Header(fmt.Sprintf("%s (%d/%d)", repo.NameWithOwner, i+1, len(repos)))
+
+			run, err := runRepo(ctx, printer, ghClient, workspace, repo, orgPrompt, runChild)
+			if err != nil {
+				printer.Error("%s: %v", repo.NameWithOwner, err)
+				failed++
+			}
+			runs = append(runs, run)
+		}
+
+		printOrgReport(os.Stdout, printer, runs)
+		if path, err := saveOrgReport(workspace, runs); err != nil {
+			printer.Warning("Could not save the report: %v", err)
+		} else {
+			printer.Info("Report: %s", path)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d repositories failed", failed, len(runs))
+		}
+		return nil
+	},
+}
+
+// repoRunner runs the task in a clone as the run runID.
+type repoRunner func(ctx context.Context, dir, runID string) error
+
+// runRepo syncs a repository into the workspace and runs the task in it
+// with runner. The run is summarized from its event log, or from what is
+// known of it if it failed before writing one.
+func runRepo(ctx context.Context, printer ui.Printer, ghClient *github.Client, workspace string, repo github.Repository, prompt string, runner repoRunner) (history.Run, error) {
+	run := history.Run{RunID: state.NewRunID(), Repo: repo.NameWithOwner, Prompt: prompt, PRs: []history.PR{}}
+	dir, err := syncRepo(ctx, printer, ghClient, workspace, repo)
+	if err == nil {
+		err = runner(ctx, dir, run.RunID)
+	}
+	if recorded, ok := loadRun(run.RunID); ok {
+		recorded.Repo = repo.NameWithOwner
+		run = recorded
+	}
+	if err != nil {
+		run.Outcome = "failed: " + err.Error()
+	}
+	return run, err
+}
+
+// syncRepo clones a repository into the workspace, or brings an earlier
+// clone up to date with its default branch, and returns its directory.
+func syncRepo(ctx context.Context, printer ui.Printer, ghClient *github.Client, workspace string, repo github.Repository) (string, error) {
+	dir := filepath.Join(workspace, repo.Name)
+	if _, err := os.Stat(dir); err != nil {
+		printer.StartSpinner(fmt.Sprintf("Cloning %s...", repo.NameWithOwner))
+		err := ghClient.CloneRepo(ctx, repo.NameWithOwner, dir)
+		printer.StopSpinner()
+		if err != nil {
+			return dir, err
+		}
+		// So the workspace commands manage the clone, wherever it is
+		if err := recordClone(repo.NameWithOwner, dir); err != nil {
+			printer.Warning("Could not record the clone of %s: %v", repo.NameWithOwner, err)
+		}
+		return dir, nil
+	}
+
+	if err := checkOrigin(clone{repo: repo.NameWithOwner, dir: dir}); err != nil {
+		return "", fmt.Errorf("%s is in the way: %w", dir, err)
+	}
+	branch, err := updateClone(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+	printer.Info("Updated %s to the latest %s", dir, branch)
+	return dir, nil
+}
+
+// updateClone checks out the default branch of a clone and pulls it, and
+// returns the branch.
+func updateClone(ctx context.Context, dir string) (string, error) {
+	gitClient := git.NewClient(dir)
+	branch, err := gitClient.DefaultBranch()
+	if err != nil {
+		return "", err
+	}
+	if err := gitClient.SwitchBranch(branch); err != nil {
+		return "", err
+	}
+	if err := gitClient.Pull(ctx, branch); err != nil {
+		return "", err
+	}
+	return branch, nil
+}
+
+// loadRun summarizes a run from its event log, if it got far enough to
+// write one.
+func loadRun(runID string) (history.Run, bool) {
+	dir, err := state.RunDir(runID)
+	if err != nil {
+		return history.Run{}, false
+	}
+	list, err := events.Read(filepath.Join(dir, events.FileName), "")
+	if err != nil || len(list) == 0 {
+		return history.Run{}, false
+	}
+	return history.Summarize(runID, list), true
+}
+
+// printOrgReport prints one row per repository with its run's outcome,
+// and the totals to w.
+func printOrgReport(w io.Writer, printer ui.Printer, runs []history.Run) {
+	var cost float64
+	var prs, merged int
+	rows := make([][]string, len(runs))
+	for i, r := range runs {
+		cost += r.Cost
+		prs += len(r.PRs)
+		merged += r.Merged()
+		rows[i] = []string{
+			r.Repo,
+			r.RunID,
+			fmt.Sprintf("%d", r.Iterations),
+			fmt.Sprintf("$%.4f", r.Cost),
+			fmt.Sprintf("%d/%d", r.Merged(), len(r.PRs)),
+			r.Outcome,
+		}
+	}
+	fmt.Fprintln(w)
+	printer.Table([]string{"REPO", "RUN", "ITERATIONS", "COST", "MERGED", "OUTCOME"}, rows)
+	fmt.Fprintf(w, "\nTotal: $%.4f over %d repositories, %d of %d PR(s) merged\n", cost, len(runs), merged, prs)
+}
+
+// saveOrgReport writes the runs as JSON to the workspace and returns the
+// file's path.
+func saveOrgReport(workspace string, runs []history.Run) (string, error) {
+	path := filepath.Join(workspace, "report-"+time.Now().Format("20060102-150405")+".json")
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create report: %w", err)
+	}
+	defer f.Close()
+	if err := history.WriteJSON(f, runs); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,270 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/history"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// runGit runs git in dir, failing the test if it fails.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newClone creates a repository acme/name with a commit on main and clones
+// it into workspace/name. It returns the origin's directory and the
+// clone's.
+func newClone(t *testing.T, workspace, name string) (string, string) {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	origin := filepath.Join(t.TempDir(), "acme", name)
+	if err := os.MkdirAll(origin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, origin, "init", "-b", "main")
+	runGit(t, origin, "commit", "--allow-empty", "-m", "Initial commit")
+	clone := filepath.Join(workspace, name)
+	runGit(t, workspace, "clone", origin, clone)
+	return origin, clone
+}
+
+// recordRun writes an event log for a run on owner/repo that opened and
+// merged a PR.
+func recordRun(t *testing.T, runID, owner, repo string) {
+	t.Helper()
+	dir, err := state.RunDir(runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, err := events.Create(filepath.Join(dir, events.FileName), runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Emit(events.RunStarted, 0, map[string]any{"prompt": "Fix lint warnings", "owner": owner, "repo": repo})
+	log.Emit(events.PRCreated, 1, map[string]any{"number": "7", "url": "https://github.com/" + owner + "/" + repo + "/pull/7"})
+	log.Emit(events.PRMerged, 1, map[string]any{"number": "7"})
+	log.Emit(events.RunFinished, 1, map[string]any{"iterations": 1, "total_cost": 0.5, "completed": true})
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrintOrgReport(t *testing.T) {
+	tests := []struct {
+		name      string
+		runs      []history.Run
+		wantRows  [][]string
+		wantTotal string
+	}{
+		{
+			name:      "no runs",
+			wantRows:  [][]string{},
+			wantTotal: "Total: $0.0000 over 0 repositories, 0 of 0 PR(s) merged",
+		},
+		{
+			name: "runs",
+			runs: []history.Run{
+				{RunID: "r1", Repo: "acme/widgets", Iterations: 3, Cost: 1.25, Outcome: "completed", PRs: []history.PR{{Number: "1", Outcome: "merged"}, {Number: "2", Outcome: "closed"}}},
+				{RunID: "r2", Repo: "acme/gadgets", Outcome: "failed: exit status 1", PRs: []history.PR{}},
+			},
+			wantRows: [][]string{
+				{"acme/widgets", "r1", "3", "$1.2500", "1/2", "completed"},
+				{"acme/gadgets", "r2", "0", "$0.0000", "0/0", "failed: exit status 1"},
+			},
+			wantTotal: "Total: $1.2500 over 2 repositories, 1 of 2 PR(s) merged",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, printed bytes.Buffer
+			printOrgReport(&out, ui.NewJSON(&printed, false), tt.runs)
+
+			var entry struct {
+				Type string `json:"type"`
+				Data struct {
+					Rows [][]string `json:"rows"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(printed.Bytes(), &entry); err != nil || entry.Type != "table" {
+				t.Fatalf("printed %q, want a table", printed.String())
+			}
+			if !reflect.DeepEqual(entry.Data.Rows, tt.wantRows) {
+				t.Errorf("rows = %v, want %v", entry.Data.Rows, tt.wantRows)
+			}
+			if got := strings.TrimSpace(out.String()); got != tt.wantTotal {
+				t.Errorf("total = %q, want %q", got, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestSaveOrgReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		missing bool
+		runs    []history.Run
+		want    string
+	}{
+		{name: "runs", runs: []history.Run{{RunID: "r1", Repo: "acme/widgets", Outcome: "completed", PRs: []history.PR{}}}},
+		{name: "no runs", want: "[]\n"},
+		{name: "missing workspace", missing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := t.TempDir()
+			if tt.missing {
+				workspace = filepath.Join(workspace, "gone")
+			}
+			path, err := saveOrgReport(workspace, tt.runs)
+			if tt.missing {
+				if err == nil {
+					t.Errorf("saveOrgReport() = %s, want an error for a missing workspace", path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("saveOrgReport() unexpected error: %v", err)
+			}
+			if filepath.Dir(path) != workspace || !strings.HasPrefix(filepath.Base(path), "report-") {
+				t.Errorf("saveOrgReport() = %s, want a report in %s", path, workspace)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != "" {
+				if string(data) != tt.want {
+					t.Errorf("report = %q, want %q", data, tt.want)
+				}
+				return
+			}
+			var got []history.Run
+			if err := json.Unmarshal(data, &got); err != nil || !reflect.DeepEqual(got, tt.runs) {
+				t.Errorf("report = %s (%v), want %+v", data, err, tt.runs)
+			}
+		})
+	}
+}
+
+func TestUpdateClone(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, origin, clone string)
+	}{
+		{"up to date", func(t *testing.T, origin, clone string) {}},
+		{"behind origin", func(t *testing.T, origin, clone string) {
+			runGit(t, origin, "commit", "--allow-empty", "-m", "Add a feature")
+		}},
+		{"on another branch", func(t *testing.T, origin, clone string) {
+			runGit(t, origin, "commit", "--allow-empty", "-m", "Add a feature")
+			runGit(t, clone, "checkout", "-b", "deep-claude/old")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origin, clone := newClone(t, t.TempDir(), "widgets")
+			tt.setup(t, origin, clone)
+
+			branch, err := updateClone(t.Context(), clone)
+			if err != nil || branch != "main" {
+				t.Fatalf("updateClone() = %q, %v; want main", branch, err)
+			}
+			if got, _ := git.NewClient(clone).CurrentBranch(); got != "main" {
+				t.Errorf("clone is on %s, want main", got)
+			}
+			if got, want := runGit(t, clone, "rev-parse", "HEAD"), runGit(t, origin, "rev-parse", "HEAD"); got != want {
+				t.Errorf("clone is at %s, want origin's %s", got, want)
+			}
+		})
+	}
+
+	if _, err := updateClone(t.Context(), t.TempDir()); err == nil {
+		t.Error("updateClone() of a directory that isn't a clone succeeded")
+	}
+}
+
+func TestRunRepo(t *testing.T) {
+	errRun := errors.New("exit status 1")
+	tests := []struct {
+		name string
+		// records has the fake run write an event log
+		records bool
+		err     error
+		want    history.Run
+	}{
+		{
+			name:    "recorded",
+			records: true,
+			want:    history.Run{Repo: "acme/widgets", Prompt: "Fix lint warnings", Iterations: 1, Cost: 0.5, Outcome: "completed", PRs: []history.PR{{Number: "7", URL: "https://github.com/acme/widgets/pull/7", Outcome: "merged"}}},
+		},
+		{
+			name:    "recorded then failed",
+			records: true,
+			err:     errRun,
+			want:    history.Run{Repo: "acme/widgets", Prompt: "Fix lint warnings", Iterations: 1, Cost: 0.5, Outcome: "failed: exit status 1", PRs: []history.PR{{Number: "7", URL: "https://github.com/acme/widgets/pull/7", Outcome: "merged"}}},
+		},
+		{
+			name: "failed without events",
+			err:  errRun,
+			want: history.Run{Repo: "acme/widgets", Prompt: "Fix lint warnings", Outcome: "failed: exit status 1", PRs: []history.PR{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_STATE_HOME", t.TempDir())
+			workspace := t.TempDir()
+			_, clone := newClone(t, workspace, "widgets")
+
+			var ranIn string
+			runner := func(ctx context.Context, dir, runID string) error {
+				ranIn = dir
+				if tt.records {
+					recordRun(t, runID, "acme", "widgets")
+				}
+				return tt.err
+			}
+			repo := github.Repository{Name: "widgets", NameWithOwner: "acme/widgets"}
+			run, err := runRepo(t.Context(), ui.NewSilent(), nil, workspace, repo, "Fix lint warnings", runner)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("runRepo() error = %v, want %v", err, tt.err)
+			}
+			if ranIn != clone {
+				t.Errorf("ran in %s, want the clone %s", ranIn, clone)
+			}
+			if run.RunID == "" {
+				t.Error("runRepo() gave the run no ID")
+			}
+			run.RunID, run.Started, run.Finished = "", tt.want.Started, tt.want.Finished
+			if !reflect.DeepEqual(run, tt.want) {
+				t.Errorf("runRepo() = %+v, want %+v", run, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/gc"
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	prsPrefix string
+	prsStale  string
+	prsClose  bool
+	prsLabel  string
+)
+
+// prsListLimit is how many open PRs are checked.
+const prsListLimit = 1000
+
+var prsCmd = &cobra.Command{
+	Use:   "prs",
+	Short: "List open PRs opened by runs, and close or label stale ones",
+	Long: `List the open pull requests whose branches have the branch prefix, i.e. that
+were opened by runs. With --stale, only those without activity for that long,
+which --close closes (deleting their branches) and --label labels.
+
+  dclaude prs
+  dclaude prs --stale 7d
+  dclaude prs --stale 7d --close
+  dclaude prs --stale 14d --label stale`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		maxAge, err := config.ParseDuration(prsStale)
+		if err != nil {
+			return err
+		}
+		if (prsClose || prsLabel != "") && maxAge == 0 {
+			return fmt.Errorf("--close and --label require --stale")
+		}
+
+		workDir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		if !git.NewClient(workDir).IsRepo() {
+			return fmt.Errorf("not in a git repository")
+		}
+
+		printer := ui.NewPrinter(false)
+		exportCredentials(printer)
+
+		ghClient := github.NewClient("", "", workDir)
+		open, err := ghClient.ListOpenPRs(cmd.Context(), prsListLimit)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		prs := gc.StalePRs(open, prsPrefix, maxAge, now)
+		if len(prs) == 0 {
+			if maxAge > 0 {
+				printer.Info("No open PRs from %s* without activity for %s", prsPrefix, config.FormatDuration(maxAge))
+			} else {
+				printer.Info("No open PRs from %s*", prsPrefix)
+			}
+			return nil
+		}
+
+		rows := make([][]string, len(prs))
+		for i, pr := range prs {
+			rows[i] = []string{fmt.Sprintf("#%d", pr.Number), pr.Title, pr.HeadRefName, formatAge(now.Sub(pr.UpdatedAt))}
+		}
+		printer.Table([]string{"PR", "TITLE", "BRANCH", "IDLE"}, rows)
+
+		var failed int
+		for _, pr := range prs {
+			number := strconv.Itoa(pr.Number)
+			if prsLabel != "" {
+				if err := ghClient.AddLabel(cmd.Context(), number, prsLabel); err != nil {
+					printer.Warning("%v", err)
+					failed++
+					continue
+				}
+			}
+			if prsClose {
+				note := fmt.Sprintf("Closing: no activity for %s.", formatAge(now.Sub(pr.UpdatedAt)))
+				if _, err := ghClient.CommentOnIssue(cmd.Context(), number, note); err != nil {
+					printer.Warning("%v", err)
+				}
+				if err := ghClient.ClosePR(cmd.Context(), number, true); err != nil {
+					printer.Warning("%v", err)
+					failed++
+					continue
+				}
+			}
+		}
+
+		switch {
+		case prsClose:
+			printer.Success("Closed %d of %d stale PR(s)", len(prs)-failed, len(prs))
+		case prsLabel != "":
+			printer.Success("Labeled %d of %d stale PR(s) %q", len(prs)-failed, len(prs), prsLabel)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d PR(s) could not be updated", failed)
+		}
+		return nil
+	},
+}
+
+// formatAge formats how long ago something happened in days or hours.
+func formatAge(d time.Duration) string {
+	if d >= 24*time.Hour {
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	return fmt.Sprintf("%dh", int(d.Hours()))
+}
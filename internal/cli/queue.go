@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/server"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// Environment variables the queue commands reach the team server with.
+const (
+	serverEnv = "DEEP_CLAUDE_SERVER"
+	tokenEnv  = "DEEP_CLAUDE_TOKEN"
+)
+
+var (
+	queueServer   string
+	queueAll      bool
+	queuePriority string
+)
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Manage the pending tasks of dclaude serve --team",
+	Long: `Manage the task queue of a team server. The commands sign in with the
+token in $DEEP_CLAUDE_TOKEN, and reach the server at --server, or
+$DEEP_CLAUDE_SERVER if it is set.
+
+Queued tasks run highest priority first (low, normal, high, urgent). An
+urgent task parks the running task after its current iteration, runs, and
+then the parked task's run is resumed where it stopped.
+
+  dclaude queue ls
+  dclaude queue bump 12 --priority urgent
+  dclaude queue cancel 12`,
+}
+
+var queueListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List the pending tasks, in the order they will run",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var tasks []server.Task
+		if err := callServer("GET", "/api/tasks", nil, &tasks); err != nil {
+			return err
+		}
+		var rows [][]string
+		for _, t := range server.Order(tasks) {
+			if !queueAll && !t.Pending() {
+				continue
+			}
+			prompt, _, _ := strings.Cut(t.Prompt, "\n")
+			rows = append(rows, []string{
+				strconv.Itoa(t.ID),
+				t.User,
+				t.Priority,
+				t.Status,
+				prompt,
+				strings.Join(t.Labels, ","),
+				t.RunID,
+				t.Submitted.Local().Format("2006-01-02 15:04:05"),
+			})
+		}
+		if len(rows) == 0 {
+			fmt.Println("No pending tasks")
+			return nil
+		}
+		ui.NewPrinter(false).Table([]string{"ID", "USER", "PRIORITY", "STATUS", "PROMPT", "LABELS", "RUN", "SUBMITTED"}, rows)
+		return nil
+	},
+}
+
+var queueBumpCmd = &cobra.Command{
+	Use:   "bump <id>",
+	Short: "Raise a pending task's priority one level, or to --priority",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var body any
+		if queuePriority != "" {
+			body = map[string]string{"priority": queuePriority}
+		}
+		var task server.Task
+		if err := callServer("POST", "/api/tasks/"+args[0]+"/bump", body, &task); err != nil {
+			return err
+		}
+		fmt.Printf("Task %d is now %s\n", task.ID, task.Priority)
+		return nil
+	},
+}
+
+var queueCancelCmd = &cobra.Command{
+	Use:   "cancel <id>",
+	Short: "Cancel a task, stopping its run after the current iteration",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := callServer("POST", "/api/tasks/"+args[0]+"/cancel", nil, nil); err != nil {
+			return err
+		}
+		fmt.Printf("Canceled task %s\n", args[0])
+		return nil
+	},
+}
+
+// callServer sends a request to the team server's API and decodes its
+// JSON answer into out, if given.
+func callServer(method, path string, body, out any) error {
+	token := os.Getenv(tokenEnv)
+	if token == "" {
+		return fmt.Errorf("set %s to your token from dclaude serve user add", tokenEnv)
+	}
+	base := queueServer
+	if env := os.Getenv(serverEnv); env != "" && !queueCmd.PersistentFlags().Changed("server") {
+		base = env
+	}
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+
+	var input io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		input = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(base, "/")+path, input)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the team server: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return fmt.Errorf("team server: %s", strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse the team server's answer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/guzus/deep-claude/internal/promptvar"
+	"github.com/guzus/deep-claude/internal/recipe"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// recipeVars are the defaults of the --recipe's prompt variables.
+var recipeVars map[string]string
+
+var recipesCmd = &cobra.Command{
+	Use:   "recipes",
+	Short: "List recipes usable with --recipe",
+	Long: `List the built-in recipes and those defined in the recipes directory.
+
+A recipe is a YAML file with a prompt and flag defaults, e.g.
+~/.config/deep-claude/recipes/docs.yaml:
+
+  description: Fill gaps in the documentation
+  prompt: |
+    Document every exported function that lacks a doc comment.
+  flags:
+    max-runs: 5
+    verify-cmd: go vet ./...
+
+Flags given on the command line override the recipe's. A prompt may use
+{{name}} variables, with defaults under vars: and values from --prompt-var,
+--prompt-vars-file or --prompt-var-cmd.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := recipe.UserDir()
+		if err != nil {
+			return err
+		}
+		recipes, err := recipe.List(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range recipes {
+			source := ""
+			if r.Source != "built-in" {
+				source = ui.Dim(" (" + r.Source + ")")
+			}
+			fmt.Printf("  %-16s %s%s\n", ui.Bold(r.Name), r.Description, source)
+		}
+		fmt.Printf("\nAdd your own in %s\n", dir)
+		return nil
+	},
+}
+
+// applyRecipe fills in the prompt and any flags the user didn't set
+// from the named recipe.
+func applyRecipe(cmd *cobra.Command, name string) error {
+	dir, err := recipe.UserDir()
+	if err != nil {
+		return err
+	}
+	r, err := recipe.Load(dir, name)
+	if err != nil {
+		return err
+	}
+
+	for flag, value := range r.Flags {
+		if cmd.Flags().Lookup(flag) == nil {
+			return fmt.Errorf("recipe %s sets unknown flag --%s", name, flag)
+		}
+		if cmd.Flags().Changed(flag) {
+			continue
+		}
+		if err := cmd.Flags().Set(flag, value); err != nil {
+			return fmt.Errorf("recipe %s: invalid value for --%s: %w", name, flag, err)
+		}
+	}
+
+	prompt = r.BuildPrompt(prompt)
+	recipeVars = r.Vars
+	return nil
+}
+
+// expandPrompt fills in the {{name}} variables of a prompt from the
+// recipe's defaults, --prompt-vars-file, --prompt-var-cmd and --prompt-var,
+// later ones winning. Without any of them the prompt is left as it is, so
+// one about templates may hold braces of its own.
+func expandPrompt(ctx context.Context, workDir, text string) (string, error) {
+	if len(recipeVars) == 0 && promptVarsFile == "" && len(promptVarCmds) == 0 && len(promptVars) == 0 {
+		return text, nil
+	}
+	var fileVars map[string]string
+	if promptVarsFile != "" {
+		vars, err := promptvar.LoadFile(promptVarsFile)
+		if err != nil {
+			return "", err
+		}
+		fileVars = vars
+	}
+	cmdVars, err := promptvar.Run(ctx, workDir, promptVarCmds)
+	if err != nil {
+		return "", err
+	}
+	given, err := promptvar.Parse(promptVars)
+	if err != nil {
+		return "", err
+	}
+	return promptvar.Expand(text, promptvar.Merge(recipeVars, fileVars, cmdVars, given))
+}
@@ -0,0 +1,29 @@
+package cli
+
+import "testing"
+
+func TestExpandPrompt(t *testing.T) {
+	const prompt = "Fix the {{name}} helper in the Handlebars templates"
+	tests := []struct {
+		name    string
+		vars    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "no variables", want: prompt},
+		{name: "given", vars: []string{"name=titleCase"}, want: "Fix the titleCase helper in the Handlebars templates"},
+		{name: "other given", vars: []string{"version=1.2.3"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promptVars = tt.vars
+			t.Cleanup(func() { promptVars = nil })
+
+			got, err := expandPrompt(t.Context(), t.TempDir(), prompt)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("expandPrompt() = %q, %v; want %q (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+
+	"github.com/guzus/deep-claude/internal/repro"
+	"github.com/guzus/deep-claude/internal/shell"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var reproScript bool
+
+var reproCmd = &cobra.Command{
+	Use:   "repro <run-id>",
+	Short: "Print the environment a run started in, and how to start it again",
+	Long: `Print what a run started with: the dclaude version and platform, the versions
+of git, gh, claude and tmux, the environment variables that affect them, the
+commit it started from and the flags it was given, including those set by
+recipes and config files. Tools whose installed version has changed since are
+marked. Secrets are never recorded.
+
+With --script, print a shell script that clones the repository at that
+commit, sets the variables and runs the same command, e.g. to attach to a
+bug report.
+
+The run ID may be a prefix, the run's tmux session name or "latest".
+
+  dclaude repro latest
+  dclaude repro 20261014-093000 --script > repro.sh`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runs, err := state.ListRuns()
+		if err != nil {
+			return err
+		}
+		runID, err := matchRun(runs, args[0])
+		if err != nil {
+			return err
+		}
+		dir, err := state.RunDir(runID)
+		if err != nil {
+			return err
+		}
+		env, err := repro.Load(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("run %s has no recorded environment; it was started by a dclaude that didn't record them", runID)
+		}
+		if err != nil {
+			return err
+		}
+
+		if reproScript {
+			fmt.Print(env.Script())
+			return nil
+		}
+		printEnvironment(cmd, ui.NewPrinter(false), env)
+		return nil
+	},
+}
+
+// printEnvironment prints a recorded environment next to the current
+// versions of the tools.
+func printEnvironment(cmd *cobra.Command, printer ui.Printer, env *repro.Environment) {
+	printer.Header("Run " + env.RunID)
+	printer.Info("Started: %s", env.Captured.Local().Format("2006-01-02 15:04:05"))
+	if env.Version != appVersion {
+		printer.Warning("dclaude: %s on %s (now %s)", env.Version, env.Platform, appVersion)
+	} else {
+		printer.Info("dclaude: %s on %s", env.Version, env.Platform)
+	}
+
+	now := repro.ToolVersions(cmd.Context())
+	var rows [][]string
+	for _, tool := range repro.Tools {
+		recorded, current := env.Tools[tool], now[tool]
+		if recorded == "" {
+			recorded = "not installed"
+		}
+		if current == "" {
+			current = "not installed"
+		}
+		changed := ""
+		if recorded != current {
+			changed = "changed: " + current
+		}
+		rows = append(rows, []string{tool, recorded, changed})
+	}
+	fmt.Println()
+	printer.Table([]string{"TOOL", "VERSION", "NOW"}, rows)
+
+	fmt.Println()
+	if env.Repo.Remote != "" {
+		printer.Info("Repository: %s", env.Repo.Remote)
+	}
+	printer.Info("Directory: %s", env.WorkDir)
+	if env.Repo.SHA != "" {
+		printer.Info("Commit: %s on %s", env.Repo.SHA, env.Repo.Branch)
+	}
+	if env.Repo.Dirty {
+		printer.Warning("The tree had uncommitted changes, which weren't recorded")
+	}
+
+	if len(env.Env) > 0 {
+		rows = rows[:0]
+		for _, name := range sortedEnv(env.Env) {
+			rows = append(rows, []string{name, env.Env[name]})
+		}
+		fmt.Println()
+		printer.Table([]string{"VARIABLE", "VALUE"}, rows)
+	}
+
+	fmt.Printf("\nCommand:\n  %s\n\n", shell.Join(env.Command()))
+	printer.Info("Print a script that sets it all up with: dclaude repro %s --script", env.RunID)
+}
+
+func sortedEnv(env map[string]string) []string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var reviewPrintOnly bool
+
+var reviewCmd = &cobra.Command{
+	Use:   "review <pr-number>",
+	Short: "Review a pull request and post the feedback as a comment",
+	Long: `Fetch a pull request's diff, have Claude review it read-only, and post the
+summary, findings and (if needed) a suggested description as a PR comment.
+
+Run it from a clone of the repository so Claude can read the surrounding code:
+
+  dclaude review 42
+  dclaude review 42 --print   # show the review without posting it`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		number := strings.TrimPrefix(args[0], "#")
+		if _, err := strconv.Atoi(number); err != nil {
+			return fmt.Errorf("invalid PR number: %s", args[0])
+		}
+
+		workDir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		if !git.NewClient(workDir).IsRepo() {
+			return fmt.Errorf("not in a git repository")
+		}
+
+		printer := ui.NewPrinter(false)
+		exportCredentials(printer)
+
+		ghClient := github.NewClient("", "", workDir)
+		pr, err := ghClient.GetPR(cmd.Context(), number)
+		if err != nil {
+			return err
+		}
+		diff, err := ghClient.GetPRDiff(cmd.Context(), number)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(diff) == "" {
+			return fmt.Errorf("PR #%s has no changes to review", number)
+		}
+
+		printer.Info("Reviewing PR #%d: %s", pr.Number, pr.Title)
+		printer.StartSpinner("Claude is reviewing the diff...")
+		review, err := claude.NewClient(workDir, nil).Review(cmd.Context(), claude.PullRequest{
+			Number: number,
+			Title:  pr.Title,
+			Body:   pr.Body,
+			Author: pr.Author.Login,
+			Diff:   diff,
+		})
+		printer.StopSpinner()
+		if err != nil {
+			return err
+		}
+
+		body := review.Markdown()
+		if reviewPrintOnly {
+			fmt.Print(body)
+			return nil
+		}
+
+		url, err := ghClient.CommentOnIssue(cmd.Context(), number, body)
+		if err != nil {
+			return err
+		}
+		printer.Success("Posted review (%d findings, $%.3f): %s", len(review.Comments), review.Cost, url)
+		return nil
+	},
+}
@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/dashboard"
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/server"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/tmux"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr          string
+	serveTeam          bool
+	serveMergeApproval bool
+	serveUserBudget    float64
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [-- run flags]",
+	Short: "Serve a local web dashboard of all runs",
+	Long: `Serve a web dashboard listing running and recorded runs. A run shows its
+timeline, cost over iterations, PRs and, when it runs in a detached
+session, its live output. Running runs can be paused, resumed and
+stopped; they act on it between iterations.
+
+  dclaude serve                    # http://127.0.0.1:8765
+  dclaude serve --addr :9000
+
+With --team, it is a service for a team instead: members sign in with a
+token from "dclaude serve user add", submit tasks, which run one after
+another in the current repository within each member's budget, and
+approve the merges of runs with --merge-approval. Flags after -- are
+passed to every run. Everything members do is recorded in the server's
+audit log.
+
+  dclaude serve --team --addr :8765 --merge-approval -- --max-runs 5`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !serveTeam {
+			if len(args) > 0 {
+				return fmt.Errorf("run flags are only used with --team")
+			}
+			handler := dashboard.Handler(dashboard.Options{Pane: runPane})
+			return listenAndServe(cmd.Context(), serveAddr, handler, "the dashboard")
+		}
+
+		dir, err := state.ServerDir()
+		if err != nil {
+			return err
+		}
+		workDir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		if !git.NewClient(workDir).IsRepo() {
+			return fmt.Errorf("%s is not a git repository; start the team server in the repository its tasks work on", workDir)
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to get executable path: %w", err)
+		}
+		exportCredentials(ui.NewPrinter(false))
+		srv, err := server.New(server.Options{
+			Dir:           dir,
+			WorkDir:       workDir,
+			Executable:    executable,
+			Args:          args,
+			MergeApproval: serveMergeApproval,
+			Pane:          runPane,
+		})
+		if err != nil {
+			return err
+		}
+		defer srv.Close()
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		done := make(chan struct{})
+		go func() {
+			srv.Work(ctx)
+			close(done)
+		}()
+		err = listenAndServe(ctx, serveAddr, srv.Handler(), "the team server for "+workDir)
+		stop()
+		// Let the current run stop cleanly before exiting
+		<-done
+		return err
+	},
+}
+
+var serveUserCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage the members of dclaude serve --team",
+}
+
+var serveUserAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a member and print their token",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		users, err := loadServerUsers()
+		if err != nil {
+			return err
+		}
+		token, err := users.Add(args[0], serveUserBudget)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Added %s. Their token, which is not shown again:\n\n  %s\n", args[0], token)
+		return nil
+	},
+}
+
+var serveUserListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the members",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		users, err := loadServerUsers()
+		if err != nil {
+			return err
+		}
+		list := users.List()
+		if len(list) == 0 {
+			fmt.Println("No users yet")
+			return nil
+		}
+		rows := make([][]string, len(list))
+		for i, u := range list {
+			budget := "none"
+			if u.Budget > 0 {
+				budget = fmt.Sprintf("$%.2f", u.Budget)
+			}
+			rows[i] = []string{u.Name, budget}
+		}
+		ui.NewPrinter(false).Table([]string{"NAME", "BUDGET"}, rows)
+		return nil
+	},
+}
+
+var serveUserRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a member, revoking their token",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		users, err := loadServerUsers()
+		if err != nil {
+			return err
+		}
+		if err := users.Remove(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", args[0])
+		return nil
+	},
+}
+
+// loadServerUsers loads the members of dclaude serve --team.
+func loadServerUsers() (*server.Users, error) {
+	dir, err := state.ServerDir()
+	if err != nil {
+		return nil, err
+	}
+	return server.LoadUsers(filepath.Join(dir, server.UsersFile))
+}
+
+// runPane returns the terminal of the detached session running runID.
+func runPane(runID string) (string, error) {
+	sessions, err := tmux.ListSessions()
+	if err != nil {
+		return "", err
+	}
+	for _, s := range sessions {
+		if strings.HasPrefix(s.Name+"-", tmux.SessionPrefix+runID+"-") {
+			return tmux.GetSessionLogs(s.Name, observeLines)
+		}
+	}
+	return "", fmt.Errorf("run %s has no session", runID)
+}
+
+// listenAndServe serves handler on addr until Ctrl-C, warning when addr
+// can be reached from other machines.
+func listenAndServe(ctx context.Context, addr string, handler http.Handler, what string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	if host, _, _ := net.SplitHostPort(addr); !isLoopback(host) {
+		fmt.Fprintf(os.Stderr, "Warning: %s is reachable from other machines; anyone who can reach it can use %s\n", addr, what)
+	}
+	fmt.Printf("Serving %s at http://%s (Ctrl-C to stop)\n", what, listener.Addr())
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	server := &http.Server{Handler: handler}
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+	return nil
+}
+
+// isLoopback reports whether host only accepts connections from this
+// machine.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
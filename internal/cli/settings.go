@@ -0,0 +1,319 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/settings"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/guzus/deep-claude/internal/version"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show the flag defaults from the config files",
+	Long: `Show the flag defaults read from the config files and where each comes from.
+Files are layered, later ones overriding earlier ones, and flags given on the
+command line or by a recipe override them all:
+
+  1. Organization: $` + settings.OrgEnv + ` (a path or an http(s) URL),
+     or ` + settings.DefaultOrgPath + `
+  2. User: ~/.config/deep-claude/config.yaml (or the OS equivalent)
+  3. Repository: ` + settings.RepoFile + `
+
+Each file has flags, by their long names. The organization config may also lock
+flags so they can't be changed, and cap the run limits:
+
+  flags:
+    merge-strategy: rebase
+    exclude: [".github/workflows/**", "infra/**"]
+  locked: [merge-strategy, exclude]
+  caps:
+    max-cost: 50
+    max-duration: 4h
+
+Check the files for misspelled keys and wrong values with
+"dclaude config validate", and see every flag's value and where it comes from
+with "dclaude config show --effective".`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return showConfig(false)
+	},
+}
+
+var configEffective bool
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the flag defaults from the config files",
+	Long: `Show the flag defaults read from the config files and where each comes from.
+With --effective, show every flag with the value a run gets when it isn't
+given on the command line, whether from a config file or the built-in default.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return showConfig(configEffective)
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config files against the config schema",
+	Long: `Check each config file against the config schema (see "dclaude config schema"),
+reporting unknown keys, such as a misspelled flag, and values of the wrong type
+by file, line and column. Then check that the files layer together and that
+each flag they set takes its value. Runs warn about the same problems.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workDir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		inputs, err := settings.Read(workDir)
+		if err != nil {
+			return err
+		}
+		if len(inputs) == 0 {
+			fmt.Println("No config files found")
+			return nil
+		}
+
+		schema := configSchema(rootCmd)
+		var problems int
+		for _, in := range inputs {
+			found := schema.Check(in)
+			for _, p := range found {
+				fmt.Println(p)
+			}
+			problems += len(found)
+		}
+		if problems > 0 {
+			return fmt.Errorf("found %d problem(s) in the config files", problems)
+		}
+
+		layers := make([]*settings.Layer, 0, len(inputs))
+		for _, in := range inputs {
+			layer, err := settings.Parse(in.Name, in.Source, in.Data)
+			if err != nil {
+				return err
+			}
+			layers = append(layers, layer)
+		}
+		resolved, err := settings.Merge(layers)
+		if err != nil {
+			return err
+		}
+		for name, s := range resolved.Settings {
+			for _, value := range s.Values {
+				if err := rootCmd.Flags().Set(name, value); err != nil {
+					return fmt.Errorf("%s config: invalid value for --%s: %w", s.From, name, err)
+				}
+			}
+		}
+
+		printer := ui.NewPrinter(false)
+		for _, in := range inputs {
+			printer.Success("%s config %s is valid", in.Name, in.Source)
+		}
+		return nil
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON schema of the config files",
+	Long: `Print the JSON schema of the config files, for editors that check YAML against
+one. The schema of the latest release is published at
+` + settings.SchemaID + `, which a config file can
+point the YAML language server to with a first line of:
+
+  # yaml-language-server: $schema=` + settings.SchemaID,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := json.MarshalIndent(configSchema(rootCmd), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode schema: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	},
+}
+
+// showConfig prints the config files and the flags they set, or with
+// effective, every flag and where its value comes from.
+func showConfig(effective bool) error {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	layers, err := settings.Load(workDir)
+	if err != nil {
+		return err
+	}
+	resolved, err := settings.Merge(layers)
+	if err != nil {
+		return err
+	}
+
+	if len(layers) == 0 && !effective {
+		fmt.Println("No config files found")
+		return nil
+	}
+	for _, layer := range layers {
+		fmt.Printf("  %-13s %s\n", layer.Name+":", layer.Source)
+	}
+	if len(layers) > 0 {
+		fmt.Println()
+	}
+
+	var names []string
+	if effective {
+		rootCmd.Flags().VisitAll(func(f *pflag.Flag) {
+			if !f.Hidden {
+				names = append(names, f.Name)
+			}
+		})
+	} else {
+		for name := range resolved.Settings {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		s, ok := resolved.Settings[name]
+		if !ok {
+			rows = append(rows, []string{"--" + name, rootCmd.Flags().Lookup(name).DefValue, "default"})
+			continue
+		}
+		from := s.From
+		if s.Locked {
+			from += " (locked)"
+		}
+		rows = append(rows, []string{"--" + name, strings.Join(s.Values, ", "), from})
+	}
+	if effective && resolved.Telemetry != nil {
+		from := resolved.TelemetryFrom
+		if resolved.TelemetryLocked {
+			from += " (locked)"
+		}
+		rows = append(rows, []string{settings.TelemetryKey, fmt.Sprint(*resolved.Telemetry), from})
+	}
+	caps := resolved.Caps
+	if caps.MaxRuns > 0 {
+		rows = append(rows, []string{"--max-runs", fmt.Sprintf("at most %d", caps.MaxRuns), "organization (cap)"})
+	}
+	if caps.MaxCost > 0 {
+		rows = append(rows, []string{"--max-cost", fmt.Sprintf("at most $%.2f", caps.MaxCost), "organization (cap)"})
+	}
+	if caps.MaxDuration != "" {
+		rows = append(rows, []string{"--max-duration", "at most " + caps.MaxDuration, "organization (cap)"})
+	}
+	ui.NewPrinter(false).Table([]string{"FLAG", "VALUE", "FROM"}, rows)
+	return nil
+}
+
+// configSchema describes the config files that can set the root
+// command's flags.
+func configSchema(root *cobra.Command) *settings.Schema {
+	var flags []settings.Flag
+	root.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Name == "help" || f.Name == "version" || f.Hidden {
+			return
+		}
+		flag := settings.Flag{Name: f.Name, Usage: f.Usage, Type: "string", Choices: config.Choices[f.Name]}
+		switch f.Value.Type() {
+		case "bool":
+			flag.Type = "boolean"
+		case "int":
+			flag.Type = "integer"
+		case "float64":
+			flag.Type = "number"
+		case "stringArray", "stringSlice":
+			flag.Repeatable = true
+		}
+		if f.Name == "channel" {
+			flag.Choices = []string{string(version.ChannelStable), string(version.ChannelBeta), string(version.ChannelNightly)}
+		}
+		flags = append(flags, flag)
+	})
+	return settings.NewSchema(flags)
+}
+
+// sliceValue is implemented by the values of repeatable flags.
+type sliceValue interface {
+	GetSlice() []string
+	Replace([]string) error
+}
+
+// applySettings fills in the flags the command line and recipe didn't set
+// from the config files, and rejects changes to flags the organization
+// locked.
+func applySettings(cmd *cobra.Command, workDir string) (*settings.Resolved, error) {
+	inputs, err := settings.Read(workDir)
+	if err != nil {
+		return nil, err
+	}
+	schema := configSchema(cmd.Root())
+	layers := make([]*settings.Layer, 0, len(inputs))
+	for _, in := range inputs {
+		// Unknown keys are otherwise ignored, so a typo would go unnoticed
+		for _, p := range schema.Check(in) {
+			ui.NewPrinter(false).Warning("Config: %s", p)
+		}
+		layer, err := settings.Parse(in.Name, in.Source, in.Data)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+	resolved, err := settings.Merge(layers)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, s := range resolved.Settings {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil {
+			// Subcommands only take the flags they share with the root
+			if cmd.HasParent() {
+				continue
+			}
+			return nil, fmt.Errorf("%s config sets unknown flag --%s", s.From, name)
+		}
+
+		if flag.Changed {
+			if !s.Locked {
+				continue
+			}
+			// Compare after parsing, so e.g. "10" and "10.0" are the same
+			var before, after any
+			if slice, ok := flag.Value.(sliceValue); ok {
+				before = slice.GetSlice()
+				err = slice.Replace(s.Values)
+				after = slice.GetSlice()
+			} else {
+				before = flag.Value.String()
+				err = flag.Value.Set(s.Values[len(s.Values)-1])
+				after = flag.Value.String()
+			}
+			if err != nil || !reflect.DeepEqual(before, after) {
+				return nil, fmt.Errorf("--%s is locked by your organization's config to %s", name, strings.Join(s.Values, ", "))
+			}
+			continue
+		}
+
+		for _, value := range s.Values {
+			if err := cmd.Flags().Set(name, value); err != nil {
+				return nil, fmt.Errorf("%s config: invalid value for --%s: %w", s.From, name, err)
+			}
+		}
+	}
+	return resolved, nil
+}
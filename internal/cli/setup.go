@@ -0,0 +1,395 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/auth"
+	"github.com/guzus/deep-claude/internal/settings"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var setupWizardCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Walk through credentials, limits, notices and protected paths, and write a config file",
+	Long: `Set Deep Claude up interactively: check that git, gh and claude are installed and
+signed in (offering to store a credential that is missing or invalid), then ask
+for default run limits, where notices and the run summary go, and which files
+must never be committed. The answers are written to your user config, for every
+repository, or to this repository's ` + settings.RepoFile + `, keeping what the
+file already has.
+
+Each question shows the current value from the config files in brackets; press
+Enter to keep it or enter - to clear it. Flags your organization locked are
+skipped.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workDir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		layers, err := settings.Load(workDir)
+		if err != nil {
+			return err
+		}
+		resolved, err := settings.Merge(layers)
+		if err != nil {
+			return err
+		}
+
+		w := &wizard{
+			in:       bufio.NewReader(os.Stdin),
+			printer:  ui.NewPrinter(false),
+			resolved: resolved,
+			flags:    make(map[string]settings.Values),
+		}
+		w.printer.Header("Tools and credentials")
+		w.checkTools()
+		if err := w.checkCredentials(cmd.Context()); err != nil {
+			return err
+		}
+
+		w.printer.Header("Config file")
+		target := w.choose("Save the settings for", []string{"user", "repository"}, "user")
+		path := filepath.Join(workDir, settings.RepoFile)
+		if target == "user" {
+			if path, err = settings.UserPath(); err != nil {
+				return err
+			}
+		}
+		w.printer.Info("Press Enter to keep the value in brackets, or enter - to clear it")
+
+		w.printer.Header("Default limits")
+		w.printer.Info("Runs stop at the first limit reached; each run needs at least one")
+		w.ask("max-runs", "Most iterations per run (0 = unlimited)", validateCount)
+		w.ask("max-cost", "Most a run may spend, in USD (0 = unlimited)", validateCost)
+		w.ask("max-duration", "Longest a run may take, e.g. 2h or 1d", func(v string) error {
+			_, err := config.ParseDuration(v)
+			return err
+		})
+
+		w.printer.Header("Notices")
+		w.printer.Info("comment posts notices (blocked, review waits, reverts) and the summary on an issue;")
+		w.printer.Info("gist publishes the summary as a secret gist; none keeps both in the terminal")
+		if !w.locked("publish-summary") {
+			current := w.current("publish-summary")
+			if current == "" {
+				current = "none"
+			}
+			switch w.choose("Notices and run summary", []string{"none", "comment", "gist"}, current) {
+			case "none":
+				w.set("publish-summary", "")
+				w.set("summary-issue", "")
+			case "comment":
+				w.set("publish-summary", "comment")
+				for {
+					w.ask("summary-issue", "Issue or PR number to comment on", func(v string) error {
+						if _, err := strconv.Atoi(strings.TrimPrefix(v, "#")); err != nil {
+							return fmt.Errorf("%q is not an issue number", v)
+						}
+						return nil
+					})
+					if w.value("summary-issue") != "" || w.locked("summary-issue") || w.eof {
+						break
+					}
+					w.printer.Error("comment needs an issue number")
+				}
+			case "gist":
+				w.set("publish-summary", "gist")
+			}
+		}
+
+		w.printer.Header("Protected paths")
+		w.printer.Info("Changes to files matching these patterns are never committed")
+		w.askList("exclude", "Patterns, comma-separated (e.g. .github/workflows/**, infra/**)")
+
+		if len(w.flags) == 0 {
+			w.printer.Info("Nothing changed; %s is left as it is", path)
+			return nil
+		}
+		w.printer.Header("Summary")
+		rows := make([][]string, 0, len(w.flags))
+		for _, name := range sortedKeys(w.flags) {
+			value := strings.Join(w.flags[name], ", ")
+			if value == "" {
+				value = "(cleared)"
+			}
+			rows = append(rows, []string{"--" + name, value})
+		}
+		w.printer.Table([]string{"FLAG", "VALUE"}, rows)
+		if !w.confirm(fmt.Sprintf("Write these to %s?", path), true) {
+			w.printer.Info("Nothing written")
+			return nil
+		}
+		if err := settings.SetFlags(path, w.flags); err != nil {
+			return err
+		}
+		w.printer.Success("Wrote %s", path)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		for _, p := range configSchema(rootCmd).Check(settings.Input{Name: target, Source: path, Data: data}) {
+			w.printer.Warning("Config: %s", p)
+		}
+		w.printer.Info("See the result with: dclaude config show")
+		return nil
+	},
+}
+
+// wizard asks the setup questions and collects the flags to write.
+type wizard struct {
+	in       *bufio.Reader
+	printer  ui.Printer
+	resolved *settings.Resolved
+	// Flags whose answers changed; empty values clear them
+	flags map[string]settings.Values
+	eof   bool
+}
+
+// line reads one answer, or "" at the end of input.
+func (w *wizard) line(prompt string) string {
+	fmt.Printf("%s %s: ", ui.Blue("?"), prompt)
+	answer, err := w.in.ReadString('\n')
+	if err != nil {
+		w.eof = true
+		fmt.Println()
+	}
+	return strings.TrimSpace(answer)
+}
+
+// current returns a flag's value from the config files, comma-joined.
+func (w *wizard) current(name string) string {
+	return strings.Join(w.resolved.Settings[name].Values, ", ")
+}
+
+// value returns a flag's value after the answers so far, comma-joined.
+func (w *wizard) value(name string) string {
+	if values, ok := w.flags[name]; ok {
+		return strings.Join(values, ", ")
+	}
+	return w.current(name)
+}
+
+// locked tells whether the organization locked a flag, saying so if it did.
+func (w *wizard) locked(name string) bool {
+	s := w.resolved.Settings[name]
+	if s.Locked {
+		w.printer.Info("--%s is locked by your organization's config to %s", name, strings.Join(s.Values, ", "))
+	}
+	return s.Locked
+}
+
+// set records a flag's new value, if it differs from the current one.
+func (w *wizard) set(name string, values ...string) {
+	values = slices.DeleteFunc(values, func(v string) bool { return v == "" })
+	if slices.Equal(values, w.resolved.Settings[name].Values) {
+		return
+	}
+	w.flags[name] = values
+}
+
+// ask asks for a flag's value until it is valid.
+func (w *wizard) ask(name, question string, validate func(string) error) {
+	if w.locked(name) {
+		return
+	}
+	current := w.current(name)
+	if current != "" {
+		question += " [" + current + "]"
+	}
+	for {
+		answer := w.line(question)
+		switch answer {
+		case "":
+			return
+		case "-":
+			w.set(name)
+			return
+		}
+		if err := validate(answer); err != nil {
+			w.printer.Error("%v", err)
+			continue
+		}
+		w.set(name, answer)
+		return
+	}
+}
+
+// askList asks for a repeatable flag's values, comma-separated.
+func (w *wizard) askList(name, question string) {
+	if w.locked(name) {
+		return
+	}
+	if current := w.current(name); current != "" {
+		question += " [" + current + "]"
+	}
+	switch answer := w.line(question); answer {
+	case "":
+	case "-":
+		w.set(name)
+	default:
+		var values []string
+		for _, v := range strings.Split(answer, ",") {
+			values = append(values, strings.TrimSpace(v))
+		}
+		w.set(name, values...)
+	}
+}
+
+// choose asks for one of choices, with a default.
+func (w *wizard) choose(question string, choices []string, current string) string {
+	for {
+		answer := w.line(fmt.Sprintf("%s (%s) [%s]", question, strings.Join(choices, ", "), current))
+		if answer == "" {
+			return current
+		}
+		if slices.Contains(choices, answer) {
+			return answer
+		}
+		w.printer.Error("Answer one of: %s", strings.Join(choices, ", "))
+	}
+}
+
+// confirm asks a yes or no question, with a default.
+func (w *wizard) confirm(question string, yes bool) bool {
+	hint := "y/N"
+	if yes {
+		hint = "Y/n"
+	}
+	switch strings.ToLower(w.line(question + " [" + hint + "]")) {
+	case "":
+		return yes
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// checkTools reports whether the programs runs need are installed.
+func (w *wizard) checkTools() {
+	tools := []struct{ name, purpose string }{
+		{"git", "required"},
+		{"gh", "required, to open and merge PRs"},
+		{"claude", "required, Claude Code"},
+		{"tmux", "optional, for -d background runs"},
+	}
+	for _, tool := range tools {
+		if path, err := exec.LookPath(tool.name); err == nil {
+			w.printer.Success("%s: %s", tool.name, path)
+		} else if strings.HasPrefix(tool.purpose, "optional") {
+			w.printer.Warning("%s: not found (%s)", tool.name, tool.purpose)
+		} else {
+			w.printer.Error("%s: not found (%s)", tool.name, tool.purpose)
+		}
+	}
+}
+
+// checkCredentials checks each credential like dclaude auth status, and
+// offers to store one that is missing or invalid.
+func (w *wizard) checkCredentials(ctx context.Context) error {
+	store, err := auth.DefaultStore()
+	if err != nil {
+		return err
+	}
+	for _, cred := range auth.Credentials {
+		secret, source, err := auth.Resolve(store, cred)
+		if err == nil {
+			err = checkCredential(ctx, cred, secret)
+		}
+		var unreachable *auth.UnreachableError
+		switch {
+		case err == nil && source == auth.SourceNone:
+			w.printer.Success("%s: using %s's own login", cred.Name, ownLogin(cred))
+			continue
+		case err == nil:
+			where := string(source)
+			if source == auth.SourceStore {
+				where = store.Name()
+			}
+			w.printer.Success("%s: valid (from %s)", cred.Name, where)
+			continue
+		case errors.As(err, &unreachable):
+			w.printer.Warning("%s: not verified: %v", cred.Name, err)
+			continue
+		}
+
+		w.printer.Error("%s: %v", cred.Name, err)
+		if !w.confirm(fmt.Sprintf("Store a %s credential now?", cred.Name), true) {
+			continue
+		}
+		secret, err = w.secret(fmt.Sprintf("Enter %s for %s", cred.EnvVar, cred.Name))
+		if err != nil {
+			return err
+		}
+		if secret == "" {
+			continue
+		}
+		if err := checkCredential(ctx, cred, secret); err != nil && !errors.As(err, &unreachable) {
+			w.printer.Error("%s: %v; not stored", cred.Name, err)
+			continue
+		}
+		if err := store.Set(cred.Name, secret); err != nil {
+			return err
+		}
+		w.printer.Success("Stored %s credential in %s", cred.Name, store.Name())
+	}
+	return nil
+}
+
+// secret reads a secret without echo from a terminal, or a line of input.
+func (w *wizard) secret(prompt string) (string, error) {
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Printf("%s %s: ", ui.Blue("?"), prompt)
+		secret, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return strings.TrimSpace(string(secret)), nil
+	}
+	return w.line(prompt), nil
+}
+
+// ownLogin names the tool whose login a credential falls back to.
+func ownLogin(cred auth.Credential) string {
+	if cred.Name == auth.Anthropic.Name {
+		return "Claude Code"
+	}
+	return "gh"
+}
+
+func validateCount(v string) error {
+	if n, err := strconv.Atoi(v); err != nil || n < 0 {
+		return fmt.Errorf("%q is not a number of iterations", v)
+	}
+	return nil
+}
+
+func validateCost(v string) error {
+	if n, err := strconv.ParseFloat(v, 64); err != nil || n < 0 {
+		return fmt.Errorf("%q is not an amount in USD", v)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]settings.Values) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
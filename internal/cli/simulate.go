@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/simulate"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+// scratch is a copy of the repository for simulated and replayed runs.
+type scratch struct {
+	root    string
+	repoDir string
+	files   []string
+}
+
+// newScratch copies the files tracked in workDir into a new scratch
+// directory. The run's state is kept there too, so the run leaves the
+// history and the cost ledger alone.
+func newScratch(workDir string) (*scratch, error) {
+	root, err := os.MkdirTemp("", "deep-claude-scratch-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	s := &scratch{root: root, repoDir: filepath.Join(root, "repo")}
+	if s.files, err = simulate.Workspace(workDir, s.repoDir); err != nil {
+		return nil, err
+	}
+	if err := os.Setenv("XDG_STATE_HOME", filepath.Join(root, "state")); err != nil {
+		return nil, fmt.Errorf("failed to set scratch state directory: %w", err)
+	}
+	return s, nil
+}
+
+// runSimulation runs the loop against the scenario's in-memory forge and
+// Claude, in a scratch copy of the repository.
+func runSimulation(cmd *cobra.Command, workDir string, cfg *config.Config, scenario *simulate.Scenario) error {
+	printer := ui.NewPrinter(false)
+	s, err := newScratch(workDir)
+	if err != nil {
+		return err
+	}
+
+	owner, name, err := git.NewClient(workDir).DetectGitHubRepo()
+	if err != nil {
+		owner, name = "simulated", filepath.Base(workDir)
+	}
+	forge := scenario.NewForge(s.repoDir, owner, name, s.files)
+
+	printer.Info("Simulating %s in %s", cfg.Simulate, s.root)
+	newGitHub := func(owner, repo string) orchestrator.GhRunner { return forge.GitHub }
+	orch, finish, err := newOrchestrator(recordedFlags(cmd), printer, cfg, s.repoDir, forge.Git, newGitHub, forge.Claude)
+	if err != nil {
+		return err
+	}
+	defer finish()
+	ctx, stop := interruptible(cmd.Context())
+	defer stop()
+	err = orch.Run(ctx)
+
+	printSimulation(printer, forge)
+	return err
+}
+
+// printSimulation lists what the simulated run did on the forge.
+func printSimulation(printer ui.Printer, forge *simulate.Forge) {
+	printer.Header("Simulation")
+	prs := forge.GitHub.PRs()
+	if len(prs) == 0 {
+		printer.Info("No PRs opened")
+	}
+	for _, pr := range prs {
+		printer.Info("PR #%d %s: %s", pr.Number, pr.State, pr.Title)
+	}
+	for _, tag := range forge.GitHub.Releases() {
+		printer.Info("Release %s", tag)
+	}
+	if comments := forge.GitHub.Comments(); len(comments) > 0 {
+		printer.Info("%d issue comments posted", len(comments))
+	}
+	printer.Info("%d commits merged into main", len(forge.Git.RemoteLog("main"))-1)
+}
+
+// hasLimit reports whether the run stops on its own.
+func hasLimit(cfg *config.Config) bool {
+	return cfg.HasMaxRuns() || cfg.HasMaxCost() || cfg.HasMaxDuration() || cfg.QueueMode()
+}
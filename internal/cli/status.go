@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/control"
+	"github.com/guzus/deep-claude/internal/repro"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/tmux"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// recentRuns is how far back dclaude status and sessions look for runs
+// that died, without --all.
+const recentRuns = 7 * 24 * time.Hour
+
+var (
+	statusAll       bool
+	statusRestart   bool
+	statusHungAfter time.Duration
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status [run-id...]",
+	Short: "Show whether runs are still going, and restart those that died",
+	Long: `Show the runs of the last week that haven't finished, and whether the process
+running each is still going, from the heartbeat it writes to the run's state
+file every 30 seconds:
+
+  running  the heartbeat is fresh
+  hung     the heartbeat is fresh, but Claude has been working on one request
+           for longer than --hung-after
+  dead     the heartbeat stopped without the run exiting: the process was
+           killed, crashed or its tmux pane died
+  stopped  the run was interrupted or blocked, and can be resumed
+
+--restart resumes the dead and hung runs, or the given ones, in a new tmux
+session with the flags they were started with: a hung run's session is
+killed first. The run ID may be a prefix or the run's tmux session name.
+
+  dclaude status
+  dclaude status --restart`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runs, err := state.ListRuns()
+		if err != nil {
+			return err
+		}
+		var wanted []string
+		for _, arg := range args {
+			runID, err := matchRun(runs, arg)
+			if err != nil {
+				return err
+			}
+			wanted = append(wanted, runID)
+		}
+
+		printer := ui.NewPrinter(false)
+		list := runStatuses(runs, statusHungAfter)
+		var rows [][]string
+		var restart []runStatus
+		for _, s := range list {
+			if len(wanted) > 0 && !slices.Contains(wanted, s.RunID) {
+				continue
+			}
+			if len(wanted) == 0 && !statusAll && (s.Liveness == state.Done || time.Since(s.Heartbeat) > recentRuns) {
+				continue
+			}
+			if s.Liveness == state.Dead || s.Liveness == state.Hung || slices.Contains(wanted, s.RunID) {
+				restart = append(restart, s)
+			}
+			rows = append(rows, []string{
+				s.RunID,
+				string(s.Liveness),
+				heartbeatAge(s.Heartbeat),
+				fmt.Sprintf("%d", s.Iterations),
+				fmt.Sprintf("$%.4f", s.TotalCost),
+				s.Session,
+				promptSummary(s.Prompt),
+			})
+		}
+		if len(rows) == 0 {
+			fmt.Println("No unfinished runs")
+			return nil
+		}
+		printer.Table([]string{"RUN", "STATE", "HEARTBEAT", "ITERATIONS", "COST", "SESSION", "PROMPT"}, rows)
+
+		if !statusRestart {
+			if len(restart) > 0 {
+				fmt.Println()
+				printer.Info("Resume the dead and hung runs with: dclaude status --restart")
+			}
+			return nil
+		}
+		fmt.Println()
+		var failed int
+		for _, s := range restart {
+			if err := restartRun(printer, s); err != nil {
+				printer.Error("%s: %v", s.RunID, err)
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d runs could not be restarted", failed, len(restart))
+		}
+		return nil
+	},
+}
+
+// runStatus is a run's state file with its liveness, and the tmux session
+// it runs in, if any.
+type runStatus struct {
+	*state.RunState
+	Liveness state.Liveness
+	Session  string
+}
+
+// runStatuses reads the state of each run, oldest first. Runs without a
+// state file are left out.
+func runStatuses(runs []string, hungAfter time.Duration) []runStatus {
+	sessions := make(map[string]string)
+	if tmux.IsAvailable() {
+		list, _ := tmux.ListSessions()
+		for _, s := range list {
+			for _, id := range runs {
+				if strings.HasPrefix(s.Name, tmux.SessionPrefix+id+"-") {
+					sessions[id] = s.Name
+				}
+			}
+		}
+	}
+
+	now := time.Now()
+	var list []runStatus
+	for _, id := range runs {
+		run, err := state.LoadRunState(id)
+		if err != nil {
+			continue
+		}
+		list = append(list, runStatus{RunState: run, Liveness: run.Liveness(now, hungAfter), Session: sessions[id]})
+	}
+	return list
+}
+
+// warnDeadRuns warns about the recent runs that died or hung, for the
+// listings of running sessions.
+func warnDeadRuns(printer ui.Printer) {
+	runs, err := state.ListRuns()
+	if err != nil {
+		return
+	}
+	var problems int
+	for _, s := range runStatuses(runs, time.Hour) {
+		if time.Since(s.Heartbeat) > recentRuns {
+			continue
+		}
+		switch s.Liveness {
+		case state.Dead:
+			printer.Warning("Run %s is dead: no heartbeat for %s", s.RunID, config.FormatDuration(time.Since(s.Heartbeat).Round(time.Second)))
+			problems++
+		case state.Hung:
+			printer.Warning("Run %s looks hung: Claude has been working on one request for %s", s.RunID, config.FormatDuration(time.Since(s.ClaudeSince).Round(time.Second)))
+			problems++
+		}
+	}
+	if problems > 0 {
+		printer.Info("See them with dclaude status, and resume them with dclaude status --restart")
+	}
+}
+
+// restartRun resumes a run in a new tmux session, from the directory and
+// with the flags recorded when it started.
+func restartRun(printer ui.Printer, s runStatus) error {
+	switch s.Liveness {
+	case state.Done:
+		return fmt.Errorf("run is finished")
+	case state.Running:
+		return fmt.Errorf("run is still going")
+	}
+	dir, err := state.RunDir(s.RunID)
+	if err != nil {
+		return err
+	}
+	env, err := repro.Load(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no recorded environment to restart it with; resume it by hand with dclaude --resume %s -d", s.RunID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if s.Session != "" {
+		if err := tmux.KillSession(s.Session); err != nil {
+			return err
+		}
+		printer.Info("Killed session %s", s.Session)
+		// Give the process a moment to exit on the hangup
+		for i := 0; i < 20 && control.Active(dir); i++ {
+			time.Sleep(250 * time.Millisecond)
+		}
+	}
+	if control.Active(dir) {
+		return fmt.Errorf("its process is still running; stop it first")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	args := append(env.Command()[1:], "--resume", s.RunID, "--detach", "--disable-updates")
+	child := exec.Command(executable, args...)
+	child.Dir = env.WorkDir
+	child.Stdin, child.Stdout, child.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := child.Run(); err != nil {
+		return fmt.Errorf("failed to restart: %w", err)
+	}
+	return nil
+}
+
+// promptSummary returns the first line of a prompt, cut short.
+func promptSummary(prompt string) string {
+	line, _, _ := strings.Cut(prompt, "\n")
+	if runes := []rune(line); len(runes) > 50 {
+		line = string(runes[:47]) + "..."
+	}
+	return line
+}
+
+func heartbeatAge(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return config.FormatDuration(time.Since(t).Round(time.Second)) + " ago"
+}
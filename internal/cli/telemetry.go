@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/guzus/deep-claude/internal/settings"
+	"github.com/guzus/deep-claude/internal/telemetry"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var telemetryCmd = &cobra.Command{
+	Use:   "telemetry",
+	Short: "Show or change whether anonymous usage reports are sent",
+	Long: `Telemetry is off unless you turn it on. When on, each run sends one anonymous
+report when it ends: a random install ID, the version and platform, the number
+of iterations and PRs, how the run ended, which subsystems failed (e.g. push or
+merge, without the error text) and the names of the flags used. It never
+contains code, prompts, commands, file paths or repository names.
+
+The switch is "telemetry: true" in a config file (see "dclaude config"), which
+these commands set in your user config. ` + telemetry.Env + `=0 or 1 overrides the
+config files. An organization config can lock it off; one that turns it on sends
+the same report from every member's runs, which each can still turn off here or
+with ` + telemetry.Env + `=0.`,
+}
+
+var telemetryStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether telemetry is on and the last report sent",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		status, err := telemetryStatus()
+		if err != nil {
+			return err
+		}
+
+		state := "off"
+		if status.Enabled {
+			state = "on"
+		}
+		if status.Locked {
+			state += " (locked)"
+		}
+		fmt.Printf("Telemetry: %s, from %s\n", state, status.Source)
+		if endpoint := telemetry.CurrentEndpoint(); endpoint != "" {
+			fmt.Printf("Endpoint:  %s\n", endpoint)
+		} else {
+			fmt.Println("Endpoint:  none in this build; reports are only kept locally")
+		}
+
+		last, err := telemetry.Last()
+		if err != nil {
+			return err
+		}
+		if last == nil {
+			fmt.Println("\nNo report yet")
+			return nil
+		}
+		data, err := json.MarshalIndent(last, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\nLast report:\n%s\n", data)
+		return nil
+	},
+}
+
+var telemetryEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Send anonymous usage reports",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setTelemetry(true)
+	},
+}
+
+var telemetryDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Stop sending anonymous usage reports",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setTelemetry(false)
+	},
+}
+
+func setTelemetry(enabled bool) error {
+	if err := settings.SetUserTelemetry(enabled); err != nil {
+		return err
+	}
+	status, err := telemetryStatus()
+	if err != nil {
+		return err
+	}
+
+	printer := ui.NewPrinter(false)
+	if status.Enabled != enabled {
+		printer.Warning("Saved in your user config, but %s overrides it", status.Source)
+		return nil
+	}
+	if enabled {
+		printer.Success("Telemetry on. Thank you! See what is sent with: dclaude telemetry status")
+	} else {
+		printer.Success("Telemetry off")
+	}
+	return nil
+}
+
+// telemetryStatus resolves the telemetry switch from the config files of
+// the current directory.
+func telemetryStatus() (telemetry.Status, error) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return telemetry.Status{}, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	layers, err := settings.Load(workDir)
+	if err != nil {
+		return telemetry.Status{}, err
+	}
+	resolved, err := settings.Merge(layers)
+	if err != nil {
+		return telemetry.Status{}, err
+	}
+	return telemetry.Resolve(resolved), nil
+}
+
+// usedFlags returns the names of the flags that were set, by the command
+// line, a recipe or a config file.
+func usedFlags(cmd *cobra.Command) []string {
+	var names []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		names = append(names, f.Name)
+	})
+	return names
+}
+
+// sendTelemetry reports the run's usage. Failures are only shown in
+// verbose mode: telemetry must never get in the way.
+func sendTelemetry(usage telemetry.Usage, features []string, verbose bool) {
+	report, err := telemetry.NewReport(appVersion, usage, features)
+	if err == nil {
+		err = telemetry.Send(report)
+	}
+	if err != nil && verbose {
+		ui.NewPrinter(true).Warning("Could not send telemetry: %v", err)
+	}
+}
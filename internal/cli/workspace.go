@@ -0,0 +1,312 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/history"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/ui"
+	wt "github.com/guzus/deep-claude/internal/worktree"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	workspaceOlderThan string
+	workspaceDryRun    bool
+	workspaceYes       bool
+)
+
+// clone is a repository cloned into the workspace by an org run.
+type clone struct {
+	// repo is owner/name
+	repo string
+	dir  string
+	// lastRun is the latest recorded run on the repository, if any
+	lastRun *history.Run
+}
+
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Manage the repositories cloned by org runs",
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:   "list [owner[/repo]]",
+	Short: "List the cloned repositories with their disk usage and last run",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clones, root, err := findClones(args)
+		if err != nil {
+			return err
+		}
+		if len(clones) == 0 {
+			fmt.Printf("No repositories cloned in %s\n", root)
+			return nil
+		}
+
+		var total int64
+		now := time.Now()
+		rows := make([][]string, len(clones))
+		for i, c := range clones {
+			size := wt.DirSize(c.dir)
+			total += size
+			last := "never"
+			if c.lastRun != nil {
+				last = fmt.Sprintf("%s ago (%s, %s)", formatAge(now.Sub(c.lastRun.Started)), c.lastRun.RunID, c.lastRun.Outcome)
+			}
+			rows[i] = []string{c.repo, formatSize(size), last}
+		}
+		ui.NewPrinter(false).Table([]string{"REPO", "SIZE", "LAST RUN"}, rows)
+		fmt.Printf("\n%d clone(s), %s in %s\n", len(clones), formatSize(total), root)
+		return nil
+	},
+}
+
+var workspaceCleanCmd = &cobra.Command{
+	Use:   "clean [owner[/repo]]",
+	Short: "Delete cloned repositories, or only those not run on recently",
+	Long: `Delete cloned repositories from the workspace. With --older-than, only those
+whose last run started longer ago than that, or that were never run on.
+The next org run clones them again. Cleaning every owner's clones at once
+takes --yes, and a clone whose origin is no longer its repository is left
+alone.
+
+  dclaude workspace clean --older-than 30d --dry-run
+  dclaude workspace clean myorg/legacy-api
+  dclaude workspace clean --yes`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		maxAge, err := config.ParseDuration(workspaceOlderThan)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 && !workspaceDryRun && !workspaceYes {
+			return fmt.Errorf("cleaning every clone takes --yes; name an owner or repository, or see what would go with --dry-run")
+		}
+		clones, root, err := findClones(args)
+		if err != nil {
+			return err
+		}
+
+		printer := ui.NewPrinter(false)
+		var size int64
+		var removed int
+		for _, c := range clones {
+			if maxAge > 0 && c.lastRun != nil && time.Since(c.lastRun.Started) < maxAge {
+				continue
+			}
+			if err := checkOrigin(c); err != nil {
+				printer.Warning("Leaving %s: %v", c.dir, err)
+				continue
+			}
+			dirSize := wt.DirSize(c.dir)
+			if workspaceDryRun {
+				printer.Info("Would remove %s (%s)", c.repo, formatSize(dirSize))
+			} else if err := os.RemoveAll(c.dir); err != nil {
+				printer.Warning("Could not remove %s: %v", c.dir, err)
+				continue
+			} else if err := forgetClone(c.dir); err != nil {
+				printer.Warning("Could not forget %s: %v", c.dir, err)
+			}
+			size += dirSize
+			removed++
+		}
+
+		switch {
+		case removed == 0:
+			printer.Info("Nothing to remove in %s", root)
+		case workspaceDryRun:
+			printer.Info("Dry run, %d clone(s) (%s) would be removed", removed, formatSize(size))
+		default:
+			printer.Success("Removed %d clone(s) (%s)", removed, formatSize(size))
+		}
+		return nil
+	},
+}
+
+var workspaceUpdateCmd = &cobra.Command{
+	Use:   "update [owner[/repo]]",
+	Short: "Pull the latest default branch into the cloned repositories",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clones, root, err := findClones(args)
+		if err != nil {
+			return err
+		}
+		if len(clones) == 0 {
+			fmt.Printf("No repositories cloned in %s\n", root)
+			return nil
+		}
+
+		printer := ui.NewPrinter(false)
+		exportCredentials(printer)
+		var failed int
+		for _, c := range clones {
+			if err := checkOrigin(c); err != nil {
+				printer.Warning("Leaving %s: %v", c.dir, err)
+				failed++
+				continue
+			}
+			printer.StartSpinner(fmt.Sprintf("Updating %s...", c.repo))
+			branch, err := updateClone(cmd.Context(), c.dir)
+			printer.StopSpinner()
+			if err != nil {
+				printer.Warning("%s: %v", c.repo, err)
+				failed++
+				continue
+			}
+			printer.Success("%s is on the latest %s", c.repo, branch)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d clone(s) could not be updated", failed, len(clones))
+		}
+		return nil
+	},
+}
+
+// clonesFile, in the workspace root, records the clones org runs made, by
+// directory, with the owner/name of their repository. Clones made with
+// --workspace are only found through it, so a directory holding other
+// repositories is never taken for a workspace.
+const clonesFile = "clones.json"
+
+// recordClone remembers that an org run cloned repo into dir, so the
+// workspace commands find it wherever it is.
+func recordClone(repo, dir string) error {
+	return updateClones(func(clones map[string]string) { clones[dir] = repo })
+}
+
+// forgetClone drops a clone the workspace commands removed.
+func forgetClone(dir string) error {
+	return updateClones(func(clones map[string]string) { delete(clones, dir) })
+}
+
+// updateClones rewrites the recorded clones with change applied.
+func updateClones(change func(clones map[string]string)) error {
+	root, err := state.WorkspaceDir("")
+	if err != nil {
+		return err
+	}
+	clones, err := recordedClones(root)
+	if err != nil {
+		return err
+	}
+	change(clones)
+	data, err := json.MarshalIndent(clones, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+	return os.WriteFile(filepath.Join(root, clonesFile), append(data, '\n'), 0644)
+}
+
+// recordedClones reads the clones recorded by recordClone.
+func recordedClones(root string) (map[string]string, error) {
+	clones := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(root, clonesFile))
+	if os.IsNotExist(err) {
+		return clones, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace: %w", err)
+	}
+	if err := json.Unmarshal(data, &clones); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", clonesFile, err)
+	}
+	return clones, nil
+}
+
+// checkOrigin makes sure a clone's origin is still its repository, so
+// the workspace commands don't delete or reset a repository that took
+// its place.
+func checkOrigin(c clone) error {
+	url, err := git.NewClient(c.dir).GetRemoteURL()
+	if err != nil {
+		return err
+	}
+	url = strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git"))
+	repo := strings.ToLower(c.repo)
+	if !strings.HasSuffix(url, "/"+repo) && !strings.HasSuffix(url, ":"+repo) {
+		return fmt.Errorf("its origin %s isn't %s", url, c.repo)
+	}
+	return nil
+}
+
+// findClones lists the repositories cloned in the workspace root and
+// those recorded elsewhere, those of one owner or a single one if filter
+// names them, and returns the workspace root too.
+func findClones(filter []string) ([]clone, string, error) {
+	root, err := state.WorkspaceDir("")
+	if err != nil {
+		return nil, "", err
+	}
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, root, nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read workspace: %w", err)
+	}
+
+	// Clones in the workspace root are named by their directories
+	found := make(map[string]string)
+	var dirs []string
+	for _, owner := range entries {
+		if !owner.IsDir() {
+			continue
+		}
+		repos, err := os.ReadDir(filepath.Join(root, owner.Name()))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read workspace: %w", err)
+		}
+		for _, repo := range repos {
+			dir := filepath.Join(root, owner.Name(), repo.Name())
+			found[dir] = owner.Name() + "/" + repo.Name()
+			dirs = append(dirs, dir)
+		}
+	}
+	recorded, err := recordedClones(root)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, dir := range slices.Sorted(maps.Keys(recorded)) {
+		if _, ok := found[dir]; !ok {
+			found[dir] = recorded[dir]
+			dirs = append(dirs, dir)
+		}
+	}
+
+	runs, err := history.Load(time.Time{})
+	if err != nil {
+		return nil, "", err
+	}
+	last := make(map[string]*history.Run)
+	for i := range runs {
+		// Runs are oldest first; GitHub names are case-insensitive
+		last[strings.ToLower(runs[i].Repo)] = &runs[i]
+	}
+
+	var clones []clone
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+			continue
+		}
+		name := found[dir]
+		if len(filter) > 0 && name != filter[0] && !strings.HasPrefix(name, strings.TrimSuffix(filter[0], "/")+"/") {
+			continue
+		}
+		clones = append(clones, clone{repo: name, dir: dir, lastRun: last[strings.ToLower(name)]})
+	}
+	return clones, root, nil
+}
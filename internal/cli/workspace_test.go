@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/guzus/deep-claude/internal/state"
+)
+
+// makeClones creates directories that look like clones under dir.
+func makeClones(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(name), ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindClonesFilter(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	root, err := state.WorkspaceDir("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	makeClones(t, root, "acme/widgets", "acme/gadgets", "acmecorp/tools")
+	if err := os.MkdirAll(filepath.Join(root, "acme", "notes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Cloned by org run --org other --workspace <custom>, next to the
+	// user's own repository
+	custom := t.TempDir()
+	makeClones(t, custom, "lib", "mine")
+	if err := recordClone("other/lib", filepath.Join(custom, "lib")); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordClone("other/lib", filepath.Join(custom, "lib")); err != nil {
+		t.Fatal(err)
+	}
+	// A recorded clone the user deleted since
+	if err := recordClone("other/gone", filepath.Join(custom, "gone")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter []string
+		want   []string
+	}{
+		{nil, []string{"acme/gadgets", "acme/widgets", "acmecorp/tools", "other/lib"}},
+		{[]string{"acme"}, []string{"acme/gadgets", "acme/widgets"}},
+		{[]string{"acme/"}, []string{"acme/gadgets", "acme/widgets"}},
+		{[]string{"acme/widgets"}, []string{"acme/widgets"}},
+		{[]string{"ac"}, nil},
+		{[]string{"acme/wid"}, nil},
+		{[]string{"other"}, []string{"other/lib"}},
+	}
+
+	for _, tt := range tests {
+		clones, gotRoot, err := findClones(tt.filter)
+		if err != nil {
+			t.Fatalf("findClones(%v) unexpected error: %v", tt.filter, err)
+		}
+		if gotRoot != root {
+			t.Errorf("findClones(%v) root = %s, want %s", tt.filter, gotRoot, root)
+		}
+		var got []string
+		for _, c := range clones {
+			got = append(got, c.repo)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("findClones(%v) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	clones, _, _ := findClones([]string{"other/lib"})
+	if len(clones) != 1 || clones[0].dir != filepath.Join(custom, "lib") {
+		t.Errorf("findClones(other/lib) = %+v, want the clone in the custom workspace", clones)
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		origin  string
+		wantErr bool
+	}{
+		{"https://github.com/acme/widgets.git", false},
+		{"https://github.com/Acme/Widgets", false},
+		{"git@github.com:acme/widgets.git", false},
+		{"ssh://git@github.com/acme/widgets/", false},
+		{"https://github.com/acme/widgets-fork.git", true},
+		{"https://github.com/me/widgets.git", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		runGit(t, dir, "init")
+		if tt.origin != "" {
+			runGit(t, dir, "remote", "add", "origin", tt.origin)
+		}
+		err := checkOrigin(clone{repo: "acme/widgets", dir: dir})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkOrigin() with origin %q = %v, want error %v", tt.origin, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWorkspaceClean(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	root, err := state.WorkspaceDir("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "acme"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	_, widgets := newClone(t, filepath.Join(root, "acme"), "widgets")
+	runGit(t, widgets, "remote", "set-url", "origin", "https://github.com/acme/widgets.git")
+	// A repository of the user's where a clone used to be
+	_, mine := newClone(t, filepath.Join(root, "acme"), "gadgets")
+	runGit(t, mine, "remote", "set-url", "origin", "https://github.com/me/gadgets.git")
+	t.Cleanup(func() { workspaceYes, workspaceDryRun = false, false })
+
+	if err := workspaceCleanCmd.RunE(workspaceCleanCmd, nil); err == nil {
+		t.Error("clean of every clone without --yes succeeded")
+	}
+	if _, err := os.Stat(widgets); err != nil {
+		t.Fatalf("clean without --yes removed the clones: %v", err)
+	}
+
+	workspaceYes = true
+	if err := workspaceCleanCmd.RunE(workspaceCleanCmd, nil); err != nil {
+		t.Fatalf("clean --yes unexpected error: %v", err)
+	}
+	if _, err := os.Stat(widgets); !os.IsNotExist(err) {
+		t.Errorf("clean --yes left the clone of acme/widgets: %v", err)
+	}
+	if _, err := os.Stat(mine); err != nil {
+		t.Errorf("clean --yes removed a repository with another origin: %v", err)
+	}
+}
+
+func TestFindClonesLastRun(t *testing.T) {
+	tests := []struct {
+		name string
+		// runs are recorded oldest first as owner, repo pairs
+		runs    [][2]string
+		wantRun string
+	}{
+		{"never run", nil, ""},
+		{"same case", [][2]string{{"acme", "widgets"}}, "20250101-000000-0000"},
+		{"other case", [][2]string{{"Acme", "Widgets"}}, "20250101-000000-0000"},
+		{"latest run", [][2]string{{"acme", "widgets"}, {"ACME", "widgets"}}, "20250101-000000-0001"},
+		{"other repo", [][2]string{{"acme", "gadgets"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_STATE_HOME", t.TempDir())
+			root, err := state.WorkspaceDir("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			makeClones(t, root, "acme/widgets")
+			for i, r := range tt.runs {
+				recordRun(t, fmt.Sprintf("20250101-000000-%04d", i), r[0], r[1])
+			}
+
+			clones, _, err := findClones(nil)
+			if err != nil || len(clones) != 1 {
+				t.Fatalf("findClones() = %v, %v; want one clone", clones, err)
+			}
+			got := ""
+			if clones[0].lastRun != nil {
+				got = clones[0].lastRun.RunID
+			}
+			if got != tt.wantRun {
+				t.Errorf("last run = %q, want %q", got, tt.wantRun)
+			}
+		})
+	}
+}
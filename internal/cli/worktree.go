@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/ui"
+	wt "github.com/guzus/deep-claude/internal/worktree"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// prepareWorktree creates or reuses the worktree named by --worktree and
+// returns the directory to run in.
+func prepareWorktree(ctx context.Context, printer ui.Printer, workDir string, cfg *config.Config) (string, error) {
+	path := wt.Path(workDir, cfg.WorktreeBaseDir, cfg.Worktree)
+	gitClient := git.NewClient(workDir)
+
+	if _, err := os.Stat(path); err == nil {
+		printer.Info("Reusing worktree: %s", path)
+	} else {
+		baseBranch, err := gitClient.DefaultBranch()
+		if err != nil {
+			return "", err
+		}
+		if err := gitClient.Fetch(ctx, baseBranch); err != nil {
+			printer.Warning("Could not fetch %s, using local copy: %v", baseBranch, err)
+		}
+
+		branch := cfg.GitBranchPrefix + "worktree/" + cfg.Worktree
+		printer.StartSpinner("Creating worktree...")
+		err = gitClient.WorktreeAddBranch(path, branch, "origin/"+baseBranch)
+		printer.StopSpinner()
+		if err != nil {
+			return "", err
+		}
+		printer.Success("Created worktree: %s", path)
+	}
+
+	copied, err := wt.CopyFiles(workDir, path, cfg.WorktreeCopy)
+	if err != nil {
+		printer.Warning("Could not copy files into worktree: %v", err)
+	}
+	if len(copied) > 0 {
+		printer.Info("Copied into worktree: %s", strings.Join(copied, ", "))
+	}
+
+	return path, nil
+}
+
+// removeWorktree deletes the worktree after the run for --cleanup-worktree.
+func removeWorktree(printer ui.Printer, workDir, path string) {
+	if err := git.NewClient(workDir).WorktreeRemove(path); err != nil {
+		printer.Warning("Could not remove worktree: %v", err)
+		return
+	}
+	printer.Success("Removed worktree: %s", path)
+}
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Show the download cache shared by worktrees (see --shared-cache)",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := state.CacheDir()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s (%s)\n", dir, formatSize(wt.DirSize(dir)))
+		return nil
+	},
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Delete the shared download cache",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := state.CacheDir()
+		if err != nil {
+			return err
+		}
+		size := wt.DirSize(dir)
+		if err := wt.RemoveCache(dir); err != nil {
+			return err
+		}
+		ui.NewPrinter(false).Success("Removed %s (%s)", dir, formatSize(size))
+		return nil
+	},
+}
+
+// formatSize renders a byte count for humans.
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,111 @@
+// Package commitmsg validates commit messages against a configured convention.
+package commitmsg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// conventionalRe matches a Conventional Commits subject line.
+var conventionalRe = regexp.MustCompile(`^(feat|fix|docs|style|refactor|perf|test|build|ci|chore|revert)(\([^)]+\))?!?: \S.*`)
+
+// Convention describes the rules a commit message must follow.
+type Convention struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// NewConvention creates a convention from its name ("none" or "conventional")
+// and an optional regex pattern the subject line must also match.
+func NewConvention(name, pattern string) (*Convention, error) {
+	if name == "" {
+		name = "none"
+	}
+	if name != "none" && name != "conventional" {
+		return nil, fmt.Errorf("unknown commit convention %q (use none or conventional)", name)
+	}
+
+	c := &Convention{name: name}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid commit pattern: %w", err)
+		}
+		c.pattern = re
+	}
+	return c, nil
+}
+
+// IsEnforced reports whether the convention places any constraint on messages.
+func (c *Convention) IsEnforced() bool {
+	return c.name != "none" || c.pattern != nil
+}
+
+// Validate checks a full commit message against the convention.
+func (c *Convention) Validate(message string) error {
+	subject := Subject(message)
+	if subject == "" {
+		return fmt.Errorf("commit message is empty")
+	}
+
+	if c.name == "conventional" && !conventionalRe.MatchString(subject) {
+		return fmt.Errorf("subject %q is not a conventional commit (expected \"type(scope): description\")", subject)
+	}
+
+	if c.pattern != nil && !c.pattern.MatchString(subject) {
+		return fmt.Errorf("subject %q does not match required pattern %s", subject, c.pattern.String())
+	}
+
+	return nil
+}
+
+// Instructions returns prompt text describing the convention to Claude.
+func (c *Convention) Instructions() string {
+	var parts []string
+	if c.name == "conventional" {
+		parts = append(parts, "The subject line MUST follow Conventional Commits: \"type(optional scope): description\" where type is one of feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert.")
+	}
+	if c.pattern != nil {
+		parts = append(parts, fmt.Sprintf("The subject line MUST match the regular expression: %s", c.pattern.String()))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Subject returns the first non-empty line of a commit message.
+func Subject(message string) string {
+	for _, line := range strings.Split(message, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// Fallback builds a commit message locally when Claude could not commit,
+// from a template and a diffstat of the staged changes.
+func Fallback(iteration int, task, diffStat string) string {
+	summary := Subject(task)
+	if len(summary) > 60 {
+		// Don't cut a multi-byte character in half
+		cut := 60
+		for cut > 0 && !utf8.RuneStart(summary[cut]) {
+			cut--
+		}
+		summary = strings.TrimSpace(summary[:cut]) + "..."
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("chore(deep-claude): iteration %d progress\n\n", iteration))
+	if summary != "" {
+		sb.WriteString(fmt.Sprintf("Task: %s\n\n", summary))
+	}
+	if stat := strings.TrimRight(diffStat, "\n"); stat != "" {
+		sb.WriteString("Changes:\n")
+		sb.WriteString(stat)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("Commit message generated by Deep Claude because the Claude commit step failed.\n")
+	return sb.String()
+}
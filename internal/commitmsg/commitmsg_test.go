@@ -0,0 +1,84 @@
+package commitmsg
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		convention string
+		pattern    string
+		message    string
+		wantErr    bool
+	}{
+		{"none accepts anything", "none", "", "did stuff", false},
+		{"none rejects empty", "none", "", "\n\n", true},
+		{"conventional ok", "conventional", "", "feat: add flag", false},
+		{"conventional with scope", "conventional", "", "fix(git): handle detached HEAD\n\nbody", false},
+		{"conventional breaking", "conventional", "", "refactor!: drop API", false},
+		{"conventional rejects plain", "conventional", "", "Add flag", true},
+		{"conventional rejects unknown type", "conventional", "", "feature: add flag", true},
+		{"pattern ok", "none", `^[A-Z]+-\d+ `, "PAY-123 Add refunds", false},
+		{"pattern mismatch", "none", `^[A-Z]+-\d+ `, "Add refunds", true},
+		{"both must match", "conventional", `\(PAY-\d+\)`, "feat: add refunds", true},
+		{"both match", "conventional", `\(PAY-\d+\)`, "feat: add refunds (PAY-1)", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewConvention(tt.convention, tt.pattern)
+			if err != nil {
+				t.Fatalf("NewConvention() unexpected error: %v", err)
+			}
+			err = c.Validate(tt.message)
+			if tt.wantErr && err == nil {
+				t.Errorf("Validate(%q) expected error, got nil", tt.message)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Validate(%q) unexpected error: %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestNewConventionInvalid(t *testing.T) {
+	if _, err := NewConvention("gitmoji", ""); err == nil {
+		t.Error("NewConvention() expected error for unknown convention")
+	}
+	if _, err := NewConvention("none", "("); err == nil {
+		t.Error("NewConvention() expected error for invalid pattern")
+	}
+}
+
+func TestFallback(t *testing.T) {
+	msg := Fallback(4, "Add tests for the parser\nand more", " parser_test.go | 20 ++++\n")
+
+	c, _ := NewConvention("conventional", "")
+	if err := c.Validate(msg); err != nil {
+		t.Errorf("Fallback() should produce a conventional commit, got error: %v", err)
+	}
+	if Subject(msg) != "chore(deep-claude): iteration 4 progress" {
+		t.Errorf("Fallback() subject = %q", Subject(msg))
+	}
+	if !strings.Contains(msg, "Task: Add tests for the parser") {
+		t.Error("Fallback() should include the task summary")
+	}
+	if !strings.Contains(msg, "parser_test.go | 20 ++++") {
+		t.Error("Fallback() should include the diffstat")
+	}
+}
+
+func TestFallbackKeepsRunesWhole(t *testing.T) {
+	// 59 bytes, then 3-byte characters across the 60-byte cut
+	task := strings.Repeat("a", 59) + strings.Repeat("테", 10)
+	msg := Fallback(1, task, "")
+	if !utf8.ValidString(msg) {
+		t.Errorf("Fallback() cut a character in half: %q", msg)
+	}
+	if want := "Task: " + strings.Repeat("a", 59) + "...\n"; !strings.Contains(msg, want) {
+		t.Errorf("Fallback() = %q, want it to contain %q", msg, want)
+	}
+}
@@ -0,0 +1,85 @@
+// Package control lets other processes pause, resume and stop a run
+// through files in its run directory: the run claims the directory with
+// its PID while it is going, and checks for a request between iterations.
+package control
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/process"
+)
+
+const (
+	// PIDFile holds the PID of the process running the run.
+	PIDFile = "run.pid"
+	// RequestFile holds the pending request, if any.
+	RequestFile = "control"
+)
+
+// Requests a run acts on.
+const (
+	Pause = "pause"
+	Stop  = "stop"
+)
+
+// StopReason is the stop reason a run records when it stops on request.
+const StopReason = "stop requested"
+
+// Claim marks the run in dir as going on in this process. Call release
+// when the run is over.
+func Claim(dir string) (release func(), err error) {
+	path := filepath.Join(dir, PIDFile)
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return nil, fmt.Errorf("failed to claim run: %w", err)
+	}
+	return func() { _ = os.Remove(path) }, nil
+}
+
+// Active reports whether a live process is running the run in dir.
+func Active(dir string) bool {
+	content, err := os.ReadFile(filepath.Join(dir, PIDFile))
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || pid <= 0 {
+		return false
+	}
+	return process.Alive(pid)
+}
+
+// Request asks the run in dir to pause or stop; "" withdraws the request,
+// which resumes a paused run.
+func Request(dir, action string) error {
+	path := filepath.Join(dir, RequestFile)
+	switch action {
+	case "":
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to withdraw request: %w", err)
+		}
+		return nil
+	case Pause, Stop:
+		if err := os.WriteFile(path, []byte(action+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to request %s: %w", action, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown request %q (use %s or %s)", action, Pause, Stop)
+}
+
+// Requested returns the pending request for the run in dir, "" if none.
+func Requested(dir string) string {
+	content, err := os.ReadFile(filepath.Join(dir, RequestFile))
+	if err != nil {
+		return ""
+	}
+	switch action := strings.TrimSpace(string(content)); action {
+	case Pause, Stop:
+		return action
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package control
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClaim(t *testing.T) {
+	dir := t.TempDir()
+	if Active(dir) {
+		t.Fatal("Active() = true before the run was claimed")
+	}
+	release, err := Claim(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Active(dir) {
+		t.Error("Active() = false while claimed")
+	}
+	release()
+	if Active(dir) {
+		t.Error("Active() = true after release")
+	}
+
+	// A PID that can't be a live process
+	if err := os.WriteFile(filepath.Join(dir, PIDFile), []byte("0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if Active(dir) {
+		t.Error("Active() = true for PID 0")
+	}
+}
+
+func TestRequest(t *testing.T) {
+	dir := t.TempDir()
+	steps := []struct {
+		action, want string
+		wantErr      bool
+	}{
+		{Pause, Pause, false},
+		{"", "", false},
+		{"", "", false},
+		{Stop, Stop, false},
+		{"restart", Stop, true},
+	}
+
+	for _, s := range steps {
+		err := Request(dir, s.action)
+		if (err != nil) != s.wantErr {
+			t.Errorf("Request(%q) error = %v, wantErr %v", s.action, err, s.wantErr)
+		}
+		if got := Requested(dir); got != s.want {
+			t.Errorf("after Request(%q), Requested() = %q, want %q", s.action, got, s.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Package dashboard serves a local web UI over the recorded runs: their
+// history, timelines, costs and live output, with buttons to pause,
+// resume and stop the ones still going.
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/control"
+	"github.com/guzus/deep-claude/internal/history"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// RequestHeader must be set on requests that change a run. Browsers only
+// send custom headers from the page itself, so other sites can't pause or
+// stop runs through a visitor's browser.
+const RequestHeader = "X-Deep-Claude"
+
+// Run is a recorded run as the dashboard lists it.
+type Run struct {
+	history.Run
+	// Active is whether a process is still running it
+	Active bool `json:"active"`
+	// Request is the pause or stop it was asked for and hasn't acted on
+	Request string `json:"request,omitempty"`
+}
+
+// Options configure the dashboard.
+type Options struct {
+	// Pane returns the terminal of the session a run is going on in
+	Pane func(runID string) (string, error)
+	// Team shows the sign-in, the task queue and merge approvals of
+	// dclaude serve --team, which serves their API
+	Team bool
+}
+
+// Handler returns the dashboard's page at / and its API under /api:
+//
+//	GET  /api/runs                runs, newest first
+//	GET  /api/runs/{id}/events    a run's events
+//	GET  /api/runs/{id}/pane      a run's live output
+//	POST /api/runs/{id}/{action}  pause, resume or stop an active run
+func Handler(opts Options) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = page.Execute(w, map[string]any{"Header": RequestHeader, "Team": opts.Team})
+	})
+	mux.HandleFunc("GET /api/runs", func(w http.ResponseWriter, r *http.Request) {
+		runs, err := loadRuns()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, runs)
+	})
+	mux.HandleFunc("GET /api/runs/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+		dir, status, err := runDir(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		list, err := events.Read(filepath.Join(dir, events.FileName), "")
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if list == nil {
+			list = []events.Event{}
+		}
+		writeJSON(w, list)
+	})
+	mux.HandleFunc("GET /api/runs/{id}/pane", func(w http.ResponseWriter, r *http.Request) {
+		if _, status, err := runDir(r.PathValue("id")); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if opts.Pane == nil {
+			http.Error(w, "live output is not available", http.StatusNotFound)
+			return
+		}
+		text, err := opts.Pane(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(text))
+	})
+	mux.HandleFunc("POST /api/runs/{id}/{action}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(RequestHeader) == "" {
+			http.Error(w, "missing "+RequestHeader+" header", http.StatusForbidden)
+			return
+		}
+		dir, status, err := runDir(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		var action string
+		switch r.PathValue("action") {
+		case "pause":
+			action = control.Pause
+		case "stop":
+			action = control.Stop
+		case "resume":
+		default:
+			http.Error(w, "unknown action "+r.PathValue("action"), http.StatusNotFound)
+			return
+		}
+		if !control.Active(dir) {
+			http.Error(w, "run "+r.PathValue("id")+" is not running", http.StatusConflict)
+			return
+		}
+		if err := control.Request(dir, action); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// loadRuns lists the recorded runs, newest first, with whether they are
+// still going.
+func loadRuns() ([]Run, error) {
+	recorded, err := history.Load(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]Run, 0, len(recorded))
+	for _, r := range slices.Backward(recorded) {
+		run := Run{Run: r}
+		if dir, err := state.RunDir(r.RunID); err == nil {
+			run.Active = control.Active(dir)
+			if run.Active {
+				run.Request = control.Requested(dir)
+			}
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// runDir returns the directory of a recorded run, or the status to answer
+// with if id isn't one.
+func runDir(id string) (string, int, error) {
+	ids, err := state.ListRuns()
+	if err != nil {
+		return "", http.StatusInternalServerError, err
+	}
+	if !slices.Contains(ids, id) {
+		return "", http.StatusNotFound, fmt.Errorf("run %s not found", id)
+	}
+	dir, err := state.RunDir(id)
+	if err != nil {
+		return "", http.StatusInternalServerError, err
+	}
+	return dir, http.StatusOK, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
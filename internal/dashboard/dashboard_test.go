@@ -0,0 +1,102 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/guzus/deep-claude/internal/control"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// record writes a run with a started event, and a finished one if done.
+func record(t *testing.T, runID string, done bool) string {
+	t.Helper()
+	dir, err := state.RunDir(runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, err := events.Create(filepath.Join(dir, events.FileName), runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Emit(events.RunStarted, 0, map[string]any{"prompt": "add tests", "owner": "acme", "repo": "widgets"})
+	if done {
+		log.Emit(events.RunFinished, 0, map[string]any{"iterations": 2, "total_cost": 1.5, "completed": true})
+	}
+	_ = log.Close()
+	return dir
+}
+
+func TestHandler(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	record(t, "20250115-143000-ab12", true)
+	active := record(t, "20250116-090000-cd34", false)
+	release, err := control.Claim(active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	handler := Handler(Options{Pane: func(runID string) (string, error) { return "Starting iteration #3\n", nil }})
+	serve := func(method, target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("GET", "/", nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), RequestHeader) {
+		t.Errorf("page = %d", rec.Code)
+	}
+
+	var runs []Run
+	if err := json.Unmarshal(serve("GET", "/api/runs", nil).Body.Bytes(), &runs); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].RunID != "20250116-090000-cd34" || !runs[0].Active || runs[1].Active || runs[1].Outcome != "completed" {
+		t.Errorf("runs = %+v, want the active run first, then the completed one", runs)
+	}
+
+	var list []events.Event
+	if err := json.Unmarshal(serve("GET", "/api/runs/20250115-143000-ab12/events", nil).Body.Bytes(), &list); err != nil || len(list) != 2 {
+		t.Errorf("events = %v (%v), want 2", list, err)
+	}
+	if rec := serve("GET", "/api/runs/20250116-090000-cd34/pane", nil); !strings.Contains(rec.Body.String(), "iteration #3") {
+		t.Errorf("pane = %d %q", rec.Code, rec.Body.String())
+	}
+
+	allowed := map[string]string{RequestHeader: "1"}
+	tests := []struct {
+		name    string
+		target  string
+		headers map[string]string
+		status  int
+		request string
+	}{
+		{"pause", "/api/runs/20250116-090000-cd34/pause", allowed, http.StatusNoContent, control.Pause},
+		{"resume", "/api/runs/20250116-090000-cd34/resume", allowed, http.StatusNoContent, ""},
+		{"without the header", "/api/runs/20250116-090000-cd34/stop", nil, http.StatusForbidden, ""},
+		{"stop", "/api/runs/20250116-090000-cd34/stop", allowed, http.StatusNoContent, control.Stop},
+		{"unknown action", "/api/runs/20250116-090000-cd34/restart", allowed, http.StatusNotFound, control.Stop},
+		{"finished run", "/api/runs/20250115-143000-ab12/stop", allowed, http.StatusConflict, control.Stop},
+		{"unknown run", "/api/runs/..%2F..%2Fetc/stop", allowed, http.StatusNotFound, control.Stop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve("POST", tt.target, tt.headers); rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if got := control.Requested(active); got != tt.request {
+				t.Errorf("request = %q, want %q", got, tt.request)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package dashboard
+
+import "html/template"
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Deep Claude</title>
+<style>
+body { margin: 0; font-family: sans-serif; font-size: 14px; background: #111; color: #ddd; display: flex; height: 100vh; }
+nav { width: 45%; overflow: auto; padding: 1em; border-right: 1px solid #333; }
+main { flex: 1; overflow: auto; padding: 1em; }
+h1, h2 { font-size: 1em; color: #888; margin: 0 0 0.6em; }
+h2 { margin-top: 1.2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.3em 0.5em; border-bottom: 1px solid #222; white-space: nowrap; }
+td.prompt { white-space: normal; max-width: 16em; }
+tr.run { cursor: pointer; }
+tr.run:hover, tr.selected { background: #1d2633; }
+.active { color: #6c6; }
+.paused { color: #dc6; }
+pre { margin: 0; font-family: monospace; white-space: pre-wrap; background: #0a0a0a; padding: 0.6em; max-height: 40vh; overflow: auto; }
+ol { padding-left: 1.4em; margin: 0; }
+time { color: #888; }
+button { margin-right: 0.4em; }
+svg { display: block; }
+.label { color: #8ab4f8; margin-right: 0.4em; }
+.bar { fill: #4a7ab5; }
+.bar.selected { fill: #8ab4f8; }
+.line { fill: none; stroke: #8ab4f8; stroke-width: 2; }
+.axis { fill: #888; font-size: 11px; }
+textarea { width: 100%; box-sizing: border-box; height: 5em; background: #0a0a0a; color: #ddd; border: 1px solid #333; }
+input { background: #0a0a0a; color: #ddd; border: 1px solid #333; width: 6em; }
+#team { margin-bottom: 1.2em; }
+</style>
+</head>
+<body>
+<nav>
+{{if .Team}}<section id="team"></section>
+{{end}}<h1>Runs</h1>
+<svg id="costs" width="100%" height="90"></svg>
+<table>
+<thead><tr><th></th><th>Run</th><th>Repo</th><th>Prompt</th><th>Iterations</th><th>Cost</th><th>Merged</th><th>Outcome</th></tr></thead>
+<tbody id="runs"></tbody>
+</table>
+</nav>
+<main id="detail"><h1>Select a run</h1></main>
+<script>
+const header = {{.Header}};
+const team = {{.Team}};
+let runs = [];
+let selected = null;
+
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs || {})) node.setAttribute(k, v);
+  node.append(...children);
+  return node;
+}
+
+function svg(tag, attrs) {
+  const node = document.createElementNS("http://www.w3.org/2000/svg", tag);
+  for (const [k, v] of Object.entries(attrs)) node.setAttribute(k, v);
+  return node;
+}
+
+// api fetches from the dashboard's API, signed in with the team token
+async function api(path, init) {
+  init = init || {};
+  init.headers = Object.assign({[header]: "1"}, init.headers);
+  const token = localStorage.getItem("deep-claude-token");
+  if (team && token) init.headers["Authorization"] = "Bearer " + token;
+  const res = await fetch("api/" + path, init);
+  if (res.status === 401 && team) {
+    localStorage.removeItem("deep-claude-token");
+    drawSignIn();
+    throw new Error("not signed in");
+  }
+  return res;
+}
+
+function drawSignIn() {
+  const token = el("input", {type: "password", placeholder: "dct_…", style: "width: 20em"});
+  const button = el("button", {}, "Sign in");
+  button.addEventListener("click", () => {
+    localStorage.setItem("deep-claude-token", token.value.trim());
+    refresh();
+  });
+  document.getElementById("team").replaceChildren(el("h1", {}, "Sign in"), el("p", {}, "Token from dclaude serve user add: ", token, " ", button));
+}
+
+// drawTeam shows who is signed in, the task form and the queue. The form
+// is only built once so refreshing doesn't wipe what is being typed.
+async function drawTeam() {
+  const me = await (await api("me")).json();
+  const tasks = await (await api("tasks")).json();
+  const section = document.getElementById("team");
+  if (!document.getElementById("tasks")) {
+    const prompt = el("textarea", {placeholder: "What should Claude do?"});
+    const maxRuns = el("input", {type: "number", min: 0, value: 5});
+    const maxCost = el("input", {type: "number", min: 0, step: "0.5", placeholder: "budget"});
+    const labels = el("input", {placeholder: "labels, comma-separated"});
+    const priority = el("select", {}, ...["low", "normal", "high", "urgent"].map(p => el("option", p === "normal" ? {selected: ""} : {}, p)));
+    const submit = el("button", {}, "Submit");
+    submit.addEventListener("click", async () => {
+      const res = await api("tasks", {method: "POST", headers: {"Content-Type": "application/json"},
+        body: JSON.stringify({prompt: prompt.value, max_runs: Number(maxRuns.value), max_cost: Number(maxCost.value), priority: priority.value,
+          labels: labels.value.split(",").map(l => l.trim()).filter(l => l)})});
+      if (res.ok) prompt.value = ""; else alert(await res.text());
+      refresh();
+    });
+    section.replaceChildren(el("h1", {id: "me"}), prompt, el("p", {}, "Max iterations ", maxRuns, " Max cost ", maxCost, " Priority ", priority, " ", labels, " ", submit),
+      el("h1", {}, "Tasks"),
+      el("table", {}, el("thead", {}, el("tr", {}, ...["", "User", "Priority", "Status", "Prompt", "Run", "Cost", ""].map(h => el("th", {}, h)))), el("tbody", {id: "tasks"})));
+  }
+
+  const budget = me.budget > 0 ? "$" + me.spent.toFixed(2) + " of $" + me.budget.toFixed(2) + " spent" : "$" + me.spent.toFixed(2) + " spent";
+  document.getElementById("me").textContent = "Signed in as " + me.name + " (" + budget + ")";
+  document.getElementById("tasks").replaceChildren(...tasks.slice().reverse().map(t => {
+    const actions = el("td", {});
+    const action = (label, path) => {
+      const b = el("button", {}, label);
+      b.addEventListener("click", async () => {
+        const res = await api("tasks/" + t.id + "/" + path, {method: "POST"});
+        if (!res.ok) alert(await res.text());
+        refresh();
+      });
+      actions.append(b, " ");
+    };
+    if ((t.status === "queued" || t.status === "parked") && t.priority !== "urgent") action("Bump", "bump");
+    if (t.status === "queued" || t.status === "running" || t.status === "parked") action("Cancel", "cancel");
+    const run = el("td", {}, t.run_id || "");
+    if (t.run_id) run.addEventListener("click", () => select(t.run_id));
+    return el("tr", {class: t.run_id ? "run" : ""}, el("td", {}, "#" + t.id), el("td", {}, t.user), el("td", {}, t.priority), el("td", {}, t.status),
+      el("td", {class: "prompt", title: t.error || ""}, ...labelsOf(t), t.prompt), run, el("td", {}, "$" + t.cost.toFixed(4)), actions);
+  }));
+}
+
+function status(run) {
+  if (!run.active) return el("span", {}, "");
+  if (run.request === "pause") return el("span", {class: "paused", title: "paused"}, "⏸");
+  return el("span", {class: "active", title: "running"}, "●");
+}
+
+function drawCosts() {
+  const chart = document.getElementById("costs");
+  chart.replaceChildren();
+  const shown = runs.slice(0, 40).reverse();
+  const max = Math.max(0.01, ...shown.map(r => r.cost));
+  const width = chart.clientWidth || 400, height = 80, w = width / Math.max(shown.length, 1);
+  shown.forEach((r, i) => {
+    const h = Math.max(1, r.cost / max * (height - 14));
+    const bar = svg("rect", {x: i * w + 1, y: height - h, width: Math.max(1, w - 2), height: h, class: "bar" + (r.run_id === selected ? " selected" : "")});
+    bar.append(svg("title", {}));
+    bar.firstChild.textContent = r.run_id + ": $" + r.cost.toFixed(4);
+    bar.addEventListener("click", () => select(r.run_id));
+    chart.append(bar);
+  });
+  const label = svg("text", {x: 0, y: 10, class: "axis"});
+  label.textContent = "Cost per run (max $" + max.toFixed(2) + ")";
+  chart.append(label);
+}
+
+function labelsOf(item) {
+  return (item.labels || []).map(l => el("span", {class: "label"}, l));
+}
+
+function drawRuns() {
+  const body = document.getElementById("runs");
+  body.replaceChildren(...runs.map(r => {
+    const row = el("tr", {class: "run" + (r.run_id === selected ? " selected" : "")},
+      el("td", {}, status(r)),
+      el("td", {}, r.run_id),
+      el("td", {}, r.repo),
+      el("td", {class: "prompt"}, ...labelsOf(r), r.prompt.length > 80 ? r.prompt.slice(0, 80) + "…" : r.prompt),
+      el("td", {}, String(r.iterations)),
+      el("td", {}, "$" + r.cost.toFixed(4)),
+      el("td", {}, r.prs.filter(p => p.outcome === "merged").length + "/" + r.prs.length),
+      el("td", {}, r.active ? (r.request === "pause" ? "paused" : "running") : r.outcome));
+    row.addEventListener("click", () => select(r.run_id));
+    return row;
+  }));
+}
+
+async function refreshRuns() {
+  runs = await (await api("runs")).json();
+  drawRuns();
+  drawCosts();
+}
+
+async function act(id, action) {
+  const res = await api("runs/" + id + "/" + action, {method: "POST"});
+  if (!res.ok) alert(await res.text());
+  await refreshRuns();
+  await refreshDetail();
+}
+
+function drawTimeline(list) {
+  const costs = list.filter(e => e.type === "claude_finished").map(e => e.data.total_cost || 0);
+  const chart = svg("svg", {width: "100%", height: 80});
+  if (costs.length > 0) {
+    const max = Math.max(0.01, ...costs), step = 100 / Math.max(costs.length - 1, 1);
+    const points = costs.map((c, i) => (costs.length === 1 ? 50 : i * step) + "," + (75 - c / max * 65));
+    chart.setAttribute("viewBox", "0 0 100 80");
+    chart.setAttribute("preserveAspectRatio", "none");
+    chart.append(svg("polyline", {points: points.join(" "), class: "line", "vector-effect": "non-scaling-stroke"}));
+  }
+  const items = list.map(e => {
+    let detail = "";
+    if (e.data) detail = e.data.title || e.data.url || e.data.reason || e.data.error || e.data.stop_reason || "";
+    return el("li", {}, el("time", {}, new Date(e.time).toLocaleString() + " "),
+      e.type + (e.iteration ? " #" + e.iteration : "") + (detail ? ": " + detail : ""));
+  });
+  return [el("h2", {}, "Cost over iterations"), chart, el("h2", {}, "Timeline"), el("ol", {}, ...items)];
+}
+
+async function refreshDetail() {
+  if (!selected) return;
+  const run = runs.find(r => r.run_id === selected);
+  if (!run) return;
+  const detail = document.getElementById("detail");
+  const parts = [el("h1", {}, run.run_id + " " + (run.repo ? "(" + run.repo + ")" : "")), el("p", {}, ...labelsOf(run), run.prompt)];
+
+  if (run.active) {
+    const buttons = el("p", {});
+    const button = (label, action) => {
+      const b = el("button", {}, label);
+      b.addEventListener("click", () => act(run.run_id, action));
+      buttons.append(b);
+    };
+    if (run.request === "pause") button("Resume", "resume"); else button("Pause", "pause");
+    button("Stop", "stop");
+    buttons.append(" Takes effect between iterations.");
+    parts.push(buttons);
+
+    const pane = await api("runs/" + run.run_id + "/pane");
+    if (pane.ok) {
+      const log = el("pre", {}, await pane.text());
+      parts.push(el("h2", {}, "Live output"), log);
+      requestAnimationFrame(() => { log.scrollTop = log.scrollHeight; });
+    }
+  }
+  for (const pr of run.prs) {
+    const line = el("div", {}, el("a", {href: pr.url, target: "_blank"}, "PR #" + pr.number), " " + pr.outcome + " ");
+    if (team && pr.outcome === "awaiting approval") {
+      const b = el("button", {}, "Approve and merge");
+      b.addEventListener("click", () => act(run.run_id, "prs/" + pr.number + "/approve"));
+      line.append(b);
+    }
+    parts.push(line);
+  }
+
+  const list = await (await api("runs/" + run.run_id + "/events")).json();
+  parts.push(...drawTimeline(list));
+  detail.replaceChildren(...parts);
+}
+
+async function select(id) {
+  selected = id;
+  drawRuns();
+  drawCosts();
+  await refreshDetail();
+}
+
+async function refresh() {
+  try {
+    if (team) {
+      if (!localStorage.getItem("deep-claude-token")) return drawSignIn();
+      await drawTeam();
+    }
+    await refreshRuns();
+    await refreshDetail();
+  } catch (err) {
+    console.error(err);
+  }
+}
+
+refresh();
+setInterval(refresh, 3000);
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,137 @@
+// Package deps finds outdated dependencies and decides which upgrades a
+// semver policy allows, for the dependency upgrade mode.
+package deps
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dependency is a direct dependency with a newer version available.
+type Dependency struct {
+	Manager string
+	Name    string
+	Current string
+	Latest  string
+}
+
+// Bump sizes, smallest first.
+const (
+	Patch = "patch"
+	Minor = "minor"
+	Major = "major"
+)
+
+var bumpRank = map[string]int{Patch: 0, Minor: 1, Major: 2}
+
+// Bump classifies the upgrade as patch, minor or major. Below 1.0 a minor
+// bump may break the API, so it counts as major.
+func (d Dependency) Bump() string {
+	cur, latest := parseVersion(d.Current), parseVersion(d.Latest)
+	switch {
+	case cur[0] != latest[0]:
+		return Major
+	case cur[0] == 0 && cur[1] != latest[1]:
+		return Major
+	case cur[1] != latest[1]:
+		return Minor
+	default:
+		return Patch
+	}
+}
+
+func (d Dependency) String() string {
+	return fmt.Sprintf("%s %s → %s (%s)", d.Name, d.Current, d.Latest, d.Bump())
+}
+
+// parseVersion returns the major, minor and patch numbers of a version
+// such as "v1.2.3" or "1.2.3-beta.1". Missing parts are 0.
+func parseVersion(v string) [3]int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+
+	var parts [3]int
+	for i, s := range strings.SplitN(v, ".", 3) {
+		parts[i], _ = strconv.Atoi(s)
+	}
+	return parts
+}
+
+// Filter returns the dependencies whose bump is within policy (the largest
+// bump allowed) and that aren't in ignore.
+func Filter(outdated []Dependency, policy string, ignore []string) []Dependency {
+	skip := make(map[string]bool, len(ignore))
+	for _, name := range ignore {
+		skip[name] = true
+	}
+
+	var allowed []Dependency
+	for _, d := range outdated {
+		if skip[d.Name] || bumpRank[d.Bump()] > bumpRank[policy] {
+			continue
+		}
+		allowed = append(allowed, d)
+	}
+	return allowed
+}
+
+// Batches splits dependencies into groups of at most size.
+func Batches(list []Dependency, size int) [][]Dependency {
+	if size < 1 {
+		size = 1
+	}
+	var batches [][]Dependency
+	for len(list) > 0 {
+		n := min(size, len(list))
+		batches = append(batches, list[:n])
+		list = list[n:]
+	}
+	return batches
+}
+
+// Goal describes the upgrade of a batch for Claude's prompt. instructions,
+// if non-empty, are the user's own additions.
+func Goal(batch []Dependency, instructions string) string {
+	var sb strings.Builder
+	if len(batch) == 1 {
+		sb.WriteString("Upgrade this dependency:\n\n")
+	} else {
+		sb.WriteString("Upgrade these dependencies together:\n\n")
+	}
+	for _, d := range batch {
+		sb.WriteString(fmt.Sprintf("- %s\n", d))
+	}
+
+	sb.WriteString("\nUse the package manager to change the version (")
+	sb.WriteString(upgradeHint(batch[0].Manager))
+	sb.WriteString("), then fix every compile error, deprecation warning and test failure the upgrade causes. ")
+	sb.WriteString("Read the dependency's changelog or migration guide for major upgrades. ")
+	sb.WriteString("Don't upgrade anything else, and keep the build and tests passing.")
+
+	if strings.TrimSpace(instructions) != "" {
+		sb.WriteString("\n\nAdditional instructions:\n")
+		sb.WriteString(strings.TrimSpace(instructions))
+	}
+	return sb.String()
+}
+
+// Title summarizes a batch for a commit or PR title.
+func Title(batch []Dependency) string {
+	if len(batch) == 1 {
+		return fmt.Sprintf("Upgrade %s to %s", batch[0].Name, batch[0].Latest)
+	}
+	return fmt.Sprintf("Upgrade %s and %d more", batch[0].Name, len(batch)-1)
+}
+
+func upgradeHint(manager string) string {
+	switch manager {
+	case "go":
+		return "`go get <module>@<version>` followed by `go mod tidy`"
+	case "npm":
+		return "`npm install <package>@<version>`"
+	}
+	return "its upgrade command"
+}
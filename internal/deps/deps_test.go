@@ -0,0 +1,115 @@
+package deps
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBump(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            string
+	}{
+		{"v1.2.3", "v1.2.4", Patch},
+		{"v1.2.3", "v1.3.0", Minor},
+		{"v1.2.3", "v2.0.0", Major},
+		{"v0.14.0", "v0.15.0", Major},
+		{"v0.14.0", "v0.14.1", Patch},
+		{"4.17.20", "4.17.21", Patch},
+		{"1.0.0-beta.1", "1.1.0", Minor},
+		{"v0.0.0-20230101000000-abcdef", "v0.0.0-20240101000000-123456", Patch},
+	}
+
+	for _, tt := range tests {
+		d := Dependency{Name: "x", Current: tt.current, Latest: tt.latest}
+		if got := d.Bump(); got != tt.want {
+			t.Errorf("Bump(%s → %s) = %s, want %s", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	outdated := []Dependency{
+		{Name: "a", Current: "v1.0.0", Latest: "v1.0.1"},
+		{Name: "b", Current: "v1.0.0", Latest: "v1.1.0"},
+		{Name: "c", Current: "v1.0.0", Latest: "v2.0.0"},
+		{Name: "d", Current: "v1.0.0", Latest: "v1.0.2"},
+	}
+
+	names := func(list []Dependency) string {
+		var s []string
+		for _, d := range list {
+			s = append(s, d.Name)
+		}
+		return strings.Join(s, ",")
+	}
+
+	if got := names(Filter(outdated, Patch, nil)); got != "a,d" {
+		t.Errorf("Filter(patch) = %s, want a,d", got)
+	}
+	if got := names(Filter(outdated, Minor, []string{"d"})); got != "a,b" {
+		t.Errorf("Filter(minor, ignore d) = %s, want a,b", got)
+	}
+	if got := names(Filter(outdated, Major, nil)); got != "a,b,c,d" {
+		t.Errorf("Filter(major) = %s, want a,b,c,d", got)
+	}
+}
+
+func TestBatches(t *testing.T) {
+	list := []Dependency{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+
+	if got := Batches(list, 2); len(got) != 2 || len(got[0]) != 2 || len(got[1]) != 1 {
+		t.Errorf("Batches(3, 2) = %v", got)
+	}
+	if got := Batches(list, 0); len(got) != 3 {
+		t.Errorf("Batches(3, 0) = %d batches, want one per dependency", len(got))
+	}
+}
+
+func TestParseGoList(t *testing.T) {
+	output := `{"Path":"example.com/app","Main":true}
+{"Path":"github.com/spf13/cobra","Version":"v1.8.0","Update":{"Version":"v1.9.1"}}
+{"Path":"golang.org/x/sys","Version":"v0.14.0","Indirect":true,"Update":{"Version":"v0.30.0"}}
+{"Path":"github.com/fatih/color","Version":"v1.18.0"}
+`
+	list, err := ParseGoList([]byte(output))
+	if err != nil {
+		t.Fatalf("ParseGoList() unexpected error: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "github.com/spf13/cobra" || list[0].Latest != "v1.9.1" {
+		t.Errorf("ParseGoList() = %+v, want only the direct cobra update", list)
+	}
+}
+
+func TestParseNpmOutdated(t *testing.T) {
+	output := `{
+  "react": {"current": "18.2.0", "wanted": "18.3.1", "latest": "19.0.0"},
+  "lodash": {"current": "4.17.20", "wanted": "4.17.21", "latest": "4.17.21"},
+  "missing": {"wanted": "1.0.0", "latest": "1.0.0"}
+}`
+	list, err := ParseNpmOutdated([]byte(output))
+	if err != nil {
+		t.Fatalf("ParseNpmOutdated() unexpected error: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "lodash" || list[1].Name != "react" || list[1].Latest != "19.0.0" {
+		t.Errorf("ParseNpmOutdated() = %+v", list)
+	}
+
+	if list, err := ParseNpmOutdated(nil); err != nil || list != nil {
+		t.Errorf("ParseNpmOutdated(empty) = %v, %v", list, err)
+	}
+}
+
+func TestGoal(t *testing.T) {
+	batch := []Dependency{{Manager: "go", Name: "github.com/spf13/cobra", Current: "v1.8.0", Latest: "v1.9.1"}}
+
+	got := Goal(batch, "Keep the CLI flags unchanged")
+	for _, want := range []string{"github.com/spf13/cobra v1.8.0 → v1.9.1 (minor)", "go get", "Keep the CLI flags unchanged"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Goal() missing %q:\n%s", want, got)
+		}
+	}
+	if title := Title(batch); title != "Upgrade github.com/spf13/cobra to v1.9.1" {
+		t.Errorf("Title() = %q", title)
+	}
+}
@@ -0,0 +1,120 @@
+package deps
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+)
+
+// Outdated lists outdated direct dependencies of every package manager
+// used in dir (Go modules and npm). Canceling ctx kills the listing.
+func Outdated(ctx context.Context, dir string) ([]Dependency, error) {
+	var all []Dependency
+	found := false
+
+	if fileExists(filepath.Join(dir, "go.mod")) {
+		found = true
+		cmd := exec.CommandContext(ctx, "go", "list", "-u", "-m", "-json", "all")
+		cmd.Dir = dir
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Go modules: %w%s", err, stderrOf(err))
+		}
+		list, err := ParseGoList(output)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, list...)
+	}
+
+	if fileExists(filepath.Join(dir, "package.json")) {
+		found = true
+		cmd := exec.CommandContext(ctx, "npm", "outdated", "--json")
+		cmd.Dir = dir
+		// npm exits 1 when anything is outdated, so only the output matters
+		output, err := cmd.Output()
+		if err != nil && len(bytes.TrimSpace(output)) == 0 {
+			return nil, fmt.Errorf("failed to list npm packages: %w%s", err, stderrOf(err))
+		}
+		list, err := ParseNpmOutdated(output)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, list...)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no supported package manifest (go.mod, package.json) in %s", dir)
+	}
+	return all, nil
+}
+
+// ParseGoList parses the stream of JSON objects printed by
+// "go list -u -m -json all", keeping direct dependencies with an update.
+func ParseGoList(output []byte) ([]Dependency, error) {
+	var list []Dependency
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var m struct {
+			Path     string
+			Version  string
+			Main     bool
+			Indirect bool
+			Update   *struct{ Version string }
+		}
+		if err := dec.Decode(&m); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse go list output: %w", err)
+		}
+		if m.Main || m.Indirect || m.Update == nil {
+			continue
+		}
+		list = append(list, Dependency{Manager: "go", Name: m.Path, Current: m.Version, Latest: m.Update.Version})
+	}
+	return list, nil
+}
+
+// ParseNpmOutdated parses the output of "npm outdated --json".
+func ParseNpmOutdated(output []byte) ([]Dependency, error) {
+	if len(bytes.TrimSpace(output)) == 0 {
+		return nil, nil
+	}
+
+	var packages map[string]struct {
+		Current string `json:"current"`
+		Latest  string `json:"latest"`
+	}
+	if err := json.Unmarshal(output, &packages); err != nil {
+		return nil, fmt.Errorf("failed to parse npm outdated output: %w", err)
+	}
+
+	var list []Dependency
+	for name, p := range packages {
+		// Packages that aren't installed have no current version
+		if p.Current == "" || p.Current == p.Latest {
+			continue
+		}
+		list = append(list, Dependency{Manager: "npm", Name: name, Current: p.Current, Latest: p.Latest})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func stderrOf(err error) string {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return "\n" + string(exitErr.Stderr)
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/retry"
+)
+
+// Turn is a scripted answer to one iteration's prompt.
+type Turn struct {
+	// Output is what Claude prints, e.g. containing the completion signal
+	Output string
+	Cost   float64
+	// Edits are the files changed in the working tree
+	Edits []string
+	// CommitMessage is used when Claude commits the edits; by default one
+	// is made up from the files
+	CommitMessage string
+	// Commits are the messages of commits Claude makes while it works,
+	// each of the next of the edits, before the rest are committed
+	Commits []string
+	// IsError marks the output as an error; Err fails the run outright
+	IsError bool
+	Err     error
+}
+
+// Claude is a scripted Claude Code for a fake repository. Each iteration
+// plays the next Turn; once they run out, Claude changes nothing.
+type Claude struct {
+	mu sync.Mutex
+
+	git     *Git
+	env     []string
+	pricing *claude.Pricing
+	commit  string
+
+	// Turns are played in order, one per iteration
+	Turns []Turn
+	// Evaluations answer --completion-mode evaluate in order; once they
+	// run out, the goal is not complete
+	Evaluations []claude.Evaluation
+	// Prompts holds every iteration prompt received
+	Prompts []string
+}
+
+// NewClaude returns a Claude that edits the fake repository.
+func NewClaude(g *Git, turns ...Turn) *Claude {
+	return &Claude{git: g, Turns: turns}
+}
+
+// CheckAvailable implements ClaudeRunner.
+func (c *Claude) CheckAvailable() error { return nil }
+
+// SetEnv implements ClaudeRunner.
+func (c *Claude) SetEnv(env []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.env = env
+}
+
+// SetPricing implements ClaudeRunner.
+func (c *Claude) SetPricing(p *claude.Pricing) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pricing = p
+}
+
+// SetRetryPolicy implements ClaudeRunner.
+func (c *Claude) SetRetryPolicy(p retry.Policy) {}
+
+// Pricing implements ClaudeRunner.
+func (c *Claude) Pricing() *claude.Pricing {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pricing
+}
+
+// Run implements ClaudeRunner by playing the next turn, unless ctx is
+// done.
+func (c *Claude) Run(ctx context.Context, prompt string) (*claude.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.Prompts = append(c.Prompts, prompt)
+	turn := Turn{Output: "Nothing left to change."}
+	if len(c.Turns) > 0 {
+		turn, c.Turns = c.Turns[0], c.Turns[1:]
+	}
+	c.commit = turn.CommitMessage
+	c.mu.Unlock()
+
+	if turn.Err != nil {
+		return nil, turn.Err
+	}
+	edits := turn.Edits
+	for _, message := range turn.Commits {
+		if len(edits) == 0 {
+			break
+		}
+		c.git.Edit(edits[0])
+		if err := c.git.StagePaths(edits[:1], nil); err != nil {
+			return nil, err
+		}
+		if err := c.git.Commit(message); err != nil {
+			return nil, err
+		}
+		edits = edits[1:]
+	}
+	c.git.Edit(edits...)
+	return &claude.Result{Output: turn.Output, Cost: turn.Cost, IsError: turn.IsError, RawOutput: turn.Output}, nil
+}
+
+// RunCommit implements ClaudeRunner by committing what is staged.
+func (c *Claude) RunCommit(ctx context.Context, guidance string) (string, error) {
+	c.mu.Lock()
+	message := c.commit
+	c.mu.Unlock()
+
+	if message == "" {
+		status, _ := c.git.GetStatus()
+		var files []string
+		for _, line := range strings.Split(status, "\n") {
+			if strings.HasPrefix(line, "M  ") {
+				files = append(files, strings.TrimPrefix(line, "M  "))
+			}
+		}
+		message = fmt.Sprintf("Update %s", strings.Join(files, ", "))
+	}
+	if err := c.git.Commit(message); err != nil {
+		return "", err
+	}
+	return message, nil
+}
+
+// Evaluate implements ClaudeRunner by giving the next evaluation.
+func (c *Claude) Evaluate(ctx context.Context, goal string) (*claude.Evaluation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	evaluation := claude.Evaluation{RemainingWork: "unknown"}
+	if len(c.Evaluations) > 0 {
+		evaluation, c.Evaluations = c.Evaluations[0], c.Evaluations[1:]
+	}
+	return &evaluation, nil
+}
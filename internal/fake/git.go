@@ -0,0 +1,796 @@
+// Package fake provides in-memory stand-ins for the git, gh and claude
+// clients, so whole runs of the orchestrator can be driven in tests
+// without the real binaries, a network or an API key.
+package fake
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/retry"
+)
+
+// Commit is a commit in a fake repository.
+type Commit struct {
+	SHA     string
+	Message string
+	Files   []string
+}
+
+// Title returns the first line of the commit message.
+func (c Commit) Title() string {
+	title, _, _ := strings.Cut(c.Message, "\n")
+	return title
+}
+
+// stash is a set of working tree changes put aside.
+type stash struct {
+	message string
+	changed map[string]int
+}
+
+// Git is an in-memory repository with an origin. Changes are tracked by
+// path only: editing a file bumps its version, which is what snapshots
+// compare.
+type Git struct {
+	mu sync.Mutex
+
+	owner, repo string
+	base        string
+	dir         string
+	files       []string
+
+	branch   string
+	branches map[string][]Commit
+	remote   map[string][]Commit
+	changed  map[string]int
+	staged   []string
+	stashes  []stash
+	nextSHA  int
+
+	// PushErrors are returned by the next push attempts, in order
+	PushErrors []error
+	// AmendErr, if set, fails amending the last commit
+	AmendErr error
+}
+
+// NewGit returns a repository for github.com/owner/repo with a main
+// branch holding one commit, pushed to origin. dir is where its .git
+// directory is reported to be.
+func NewGit(dir, owner, repo string, files ...string) *Git {
+	g := &Git{
+		owner:    owner,
+		repo:     repo,
+		base:     "main",
+		dir:      dir,
+		files:    append([]string{}, files...),
+		branch:   "main",
+		branches: make(map[string][]Commit),
+		remote:   make(map[string][]Commit),
+		changed:  make(map[string]int),
+	}
+	initial := Commit{SHA: g.newSHA(), Message: "Initial commit", Files: append([]string{}, files...)}
+	g.branches["main"] = []Commit{initial}
+	g.remote["main"] = []Commit{initial}
+	return g
+}
+
+// Edit changes files in the working tree, as Claude would.
+func (g *Git) Edit(paths ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, p := range paths {
+		g.changed[p]++
+		g.track(p)
+	}
+}
+
+// Log returns the commits of a local branch, oldest first.
+func (g *Git) Log(branch string) []Commit {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Commit{}, g.branches[branch]...)
+}
+
+// RemoteLog returns the commits of a branch on origin, oldest first.
+func (g *Git) RemoteLog(branch string) []Commit {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Commit{}, g.remote[branch]...)
+}
+
+// Branches returns the local branches.
+func (g *Git) Branches() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return sortedKeys(g.branches)
+}
+
+// IsRepo implements GitRunner.
+func (g *Git) IsRepo() bool { return true }
+
+// DetectGitHubRepo implements GitRunner.
+func (g *Git) DetectGitHubRepo() (string, string, error) { return g.owner, g.repo, nil }
+
+// DefaultBranch implements GitRunner.
+func (g *Git) DefaultBranch() (string, error) { return g.base, nil }
+
+// GitDir implements GitRunner.
+func (g *Git) GitDir() (string, error) { return filepath.Join(g.dir, ".git"), nil }
+
+// ListFiles implements GitRunner.
+func (g *Git) ListFiles() ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string{}, g.files...), nil
+}
+
+// CurrentBranch implements GitRunner.
+func (g *Git) CurrentBranch() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.branch, nil
+}
+
+// GenerateBranchName implements GitRunner, without the random suffix.
+func (g *Git) GenerateBranchName(prefix, runID string, iteration int) string {
+	return fmt.Sprintf("%s%s/iteration-%d", prefix, runID, iteration)
+}
+
+// CreateBranch implements GitRunner.
+func (g *Git) CreateBranch(name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.createBranch(name, g.branches[g.branch])
+}
+
+// CreateBranchFrom implements GitRunner. startPoint may be origin/<branch>.
+func (g *Git) CreateBranchFrom(name, startPoint string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	commits, ok := g.branches[startPoint]
+	if remote, found := strings.CutPrefix(startPoint, "origin/"); found {
+		commits, ok = g.remote[remote]
+	}
+	if !ok {
+		return fmt.Errorf("fake git: unknown start point %s", startPoint)
+	}
+	delete(g.branches, name)
+	return g.createBranch(name, commits)
+}
+
+func (g *Git) createBranch(name string, commits []Commit) error {
+	if _, exists := g.branches[name]; exists {
+		return fmt.Errorf("fake git: branch %s already exists", name)
+	}
+	g.branches[name] = append([]Commit{}, commits...)
+	g.branch = name
+	return nil
+}
+
+// SwitchBranch implements GitRunner. Working tree changes come along.
+func (g *Git) SwitchBranch(name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.branches[name]; !ok {
+		return fmt.Errorf("fake git: no branch %s", name)
+	}
+	g.branch = name
+	return nil
+}
+
+// DeleteBranch implements GitRunner.
+func (g *Git) DeleteBranch(name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if name == g.branch {
+		return fmt.Errorf("fake git: cannot delete the current branch %s", name)
+	}
+	if _, ok := g.branches[name]; !ok {
+		return fmt.Errorf("fake git: no branch %s", name)
+	}
+	delete(g.branches, name)
+	return nil
+}
+
+// HasChanges implements GitRunner.
+func (g *Git) HasChanges(excludes []string) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, p := range append(sortedKeys(g.changed), g.staged...) {
+		if !excluded(p, excludes) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetStatus implements GitRunner.
+func (g *Git) GetStatus() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var b strings.Builder
+	for _, p := range g.staged {
+		fmt.Fprintf(&b, "M  %s\n", p)
+	}
+	for _, p := range sortedKeys(g.changed) {
+		fmt.Fprintf(&b, " M %s\n", p)
+	}
+	return b.String(), nil
+}
+
+// Stash implements GitRunner.
+func (g *Git) Stash(message string, excludes []string) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, p := range g.staged {
+		g.changed[p]++
+	}
+	g.staged = nil
+	stashed := make(map[string]int)
+	for p, v := range g.changed {
+		if !excluded(p, excludes) {
+			stashed[p] = v
+			delete(g.changed, p)
+		}
+	}
+	if len(stashed) == 0 {
+		return false, nil
+	}
+	g.stashes = append(g.stashes, stash{message: message, changed: stashed})
+	return true, nil
+}
+
+// StashPop implements GitRunner.
+func (g *Git) StashPop(message string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i := len(g.stashes) - 1; i >= 0; i-- {
+		if g.stashes[i].message != message {
+			continue
+		}
+		for p, v := range g.stashes[i].changed {
+			g.changed[p] += v
+		}
+		g.stashes = append(g.stashes[:i], g.stashes[i+1:]...)
+		return nil
+	}
+	return fmt.Errorf("fake git: no stash %q", message)
+}
+
+// TakeSnapshot implements GitRunner.
+func (g *Git) TakeSnapshot() (git.Snapshot, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	snapshot := make(git.Snapshot, len(g.changed))
+	for p, v := range g.changed {
+		snapshot[p] = strconv.Itoa(v)
+	}
+	return snapshot, nil
+}
+
+// ChangedSince implements GitRunner.
+func (g *Git) ChangedSince(before git.Snapshot) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var paths []string
+	for _, p := range sortedKeys(g.changed) {
+		if before[p] != strconv.Itoa(g.changed[p]) {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// ChangedFilesSince implements GitRunner.
+func (g *Git) ChangedFilesSince(ref string) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	seen := make(map[string]bool)
+	commits := g.branches[g.branch]
+	for i := len(commits) - 1; i >= 0 && commits[i].SHA != ref; i-- {
+		for _, f := range commits[i].Files {
+			seen[f] = true
+		}
+	}
+	for p := range g.changed {
+		seen[p] = true
+	}
+	return sortedKeys(seen), nil
+}
+
+// StagePaths implements GitRunner.
+func (g *Git) StagePaths(paths, excludes []string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, p := range paths {
+		if _, ok := g.changed[p]; ok && !excluded(p, excludes) {
+			g.stage(p)
+		}
+	}
+	return nil
+}
+
+// StageAllExcept implements GitRunner.
+func (g *Git) StageAllExcept(excludes []string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, p := range sortedKeys(g.changed) {
+		if !excluded(p, excludes) {
+			g.stage(p)
+		}
+	}
+	return nil
+}
+
+func (g *Git) stage(p string) {
+	delete(g.changed, p)
+	for _, s := range g.staged {
+		if s == p {
+			return
+		}
+	}
+	g.staged = append(g.staged, p)
+}
+
+// HasStagedChanges implements GitRunner.
+func (g *Git) HasStagedChanges() (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.staged) > 0, nil
+}
+
+// GetStagedShortStat implements GitRunner, counting one added line per file.
+func (g *Git) GetStagedShortStat() (git.DiffStat, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return git.DiffStat{FilesChanged: len(g.staged), Insertions: len(g.staged)}, nil
+}
+
+// GetStagedDiffStat implements GitRunner.
+func (g *Git) GetStagedDiffStat() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var b strings.Builder
+	for _, p := range g.staged {
+		fmt.Fprintf(&b, " %s | 1 +\n", p)
+	}
+	fmt.Fprintf(&b, " %d files changed, %d insertions(+)\n", len(g.staged), len(g.staged))
+	return b.String(), nil
+}
+
+// Commit implements GitRunner.
+func (g *Git) Commit(message string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.staged) == 0 {
+		return fmt.Errorf("fake git: nothing to commit")
+	}
+	g.branches[g.branch] = append(g.branches[g.branch], Commit{SHA: g.newSHA(), Message: message, Files: g.staged})
+	g.staged = nil
+	return nil
+}
+
+// UndoLastCommit implements GitRunner, keeping the changes staged.
+func (g *Git) UndoLastCommit() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	commits := g.branches[g.branch]
+	if len(commits) < 2 {
+		return fmt.Errorf("fake git: no commit to undo")
+	}
+	last := commits[len(commits)-1]
+	g.branches[g.branch] = commits[:len(commits)-1]
+	for _, f := range last.Files {
+		g.stage(f)
+	}
+	return nil
+}
+
+// AmendCommit implements GitRunner.
+func (g *Git) AmendCommit() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.AmendErr != nil {
+		return g.AmendErr
+	}
+	commits := g.branches[g.branch]
+	if len(commits) < 2 {
+		return fmt.Errorf("fake git: no commit to amend")
+	}
+	last := commits[len(commits)-1]
+	files := append([]string{}, last.Files...)
+	for _, f := range g.staged {
+		if !slices.Contains(files, f) {
+			files = append(files, f)
+		}
+	}
+	n := len(commits) - 1
+	g.branches[g.branch] = append(commits[:n:n], Commit{SHA: g.newSHA(), Message: last.Message, Files: files})
+	g.staged = nil
+	return nil
+}
+
+// CommitEmpty implements GitRunner.
+func (g *Git) CommitEmpty(message string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.branches[g.branch] = append(g.branches[g.branch], Commit{SHA: g.newSHA(), Message: message})
+	return nil
+}
+
+// CommitTitlesSince implements GitRunner.
+func (g *Git) CommitTitlesSince(ref string) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var titles []string
+	for _, c := range g.after(ref) {
+		titles = append(titles, c.Title())
+	}
+	return titles, nil
+}
+
+// SquashSince implements GitRunner.
+func (g *Git) SquashSince(ref, message string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	squashed := g.after(ref)
+	if len(squashed) == 0 {
+		return fmt.Errorf("fake git: no commits since %s", ref)
+	}
+	seen := make(map[string]bool)
+	var files []string
+	for _, c := range squashed {
+		for _, f := range c.Files {
+			if !seen[f] {
+				seen[f] = true
+				files = append(files, f)
+			}
+		}
+	}
+	commits := g.branches[g.branch]
+	n := len(commits) - len(squashed)
+	g.branches[g.branch] = append(commits[:n:n], Commit{SHA: g.newSHA(), Message: message, Files: files})
+	return nil
+}
+
+// after returns the commits on the current branch after ref.
+func (g *Git) after(ref string) []Commit {
+	commits := g.branches[g.branch]
+	i := len(commits)
+	for i > 0 && commits[i-1].SHA != ref {
+		i--
+	}
+	return commits[i:]
+}
+
+// HeadSHA implements GitRunner.
+func (g *Git) HeadSHA() (string, error) {
+	head, err := g.head()
+	return head.SHA, err
+}
+
+// GetLastCommitTitle implements GitRunner.
+func (g *Git) GetLastCommitTitle() (string, error) {
+	head, err := g.head()
+	return head.Title(), err
+}
+
+// GetLastCommitMessage implements GitRunner.
+func (g *Git) GetLastCommitMessage() (string, error) {
+	head, err := g.head()
+	return head.Message, err
+}
+
+func (g *Git) head() (Commit, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	commits := g.branches[g.branch]
+	if len(commits) == 0 {
+		return Commit{}, fmt.Errorf("fake git: branch %s has no commits", g.branch)
+	}
+	return commits[len(commits)-1], nil
+}
+
+// BranchDiff implements GitRunner.
+func (g *Git) BranchDiff(base string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return diff(since(g.branches[g.branch], g.remote[base])), nil
+}
+
+// PatchID implements GitRunner.
+func (g *Git) PatchID(diff string) (string, error) {
+	if diff == "" {
+		return "", nil
+	}
+	sum := sha256.Sum256([]byte(diff))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// FormatPatch implements GitRunner.
+func (g *Git) FormatPatch(base string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	commits, ok := g.branches[base]
+	if !ok {
+		commits = g.remote[strings.TrimPrefix(base, "origin/")]
+	}
+	var b strings.Builder
+	for _, c := range since(g.branches[g.branch], commits) {
+		fmt.Fprintf(&b, "From %s\nSubject: [PATCH] %s\n\n%s", c.SHA, c.Message, diff([]Commit{c}))
+	}
+	return b.String(), nil
+}
+
+// CherryPick implements GitRunner. Picks never conflict.
+func (g *Git) CherryPick(sha string) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, commits := range g.remote {
+		for _, c := range commits {
+			if c.SHA == sha {
+				g.branches[g.branch] = append(g.branches[g.branch], Commit{SHA: g.newSHA(), Message: c.Message, Files: c.Files})
+				return nil, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("fake git: no commit %s", sha)
+}
+
+// Revert implements GitRunner, undoing the files of a commit on origin.
+func (g *Git) Revert(sha string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, commits := range g.remote {
+		for _, c := range commits {
+			if c.SHA == sha {
+				message := fmt.Sprintf("Revert %q\n\nThis reverts commit %s.", c.Title(), sha)
+				g.branches[g.branch] = append(g.branches[g.branch], Commit{SHA: g.newSHA(), Message: message, Files: c.Files})
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("fake git: no commit %s", sha)
+}
+
+// CherryPickContinue implements GitRunner.
+func (g *Git) CherryPickContinue() error { return nil }
+
+// CherryPickAbort implements GitRunner.
+func (g *Git) CherryPickAbort() error { return nil }
+
+// UnresolvedConflicts implements GitRunner.
+func (g *Git) UnresolvedConflicts(paths []string) []string { return nil }
+
+// Rebase implements GitRunner. The branch's commits conflict with those
+// of onto that change the same files.
+func (g *Git) Rebase(onto string) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	target, ok := g.branches[onto]
+	if !ok {
+		return nil, fmt.Errorf("fake git: no branch %s", onto)
+	}
+	added := since(target, g.branches[g.branch])
+	if len(added) == 0 {
+		return nil, nil
+	}
+	own := since(g.branches[g.branch], target)
+	theirs := make(map[string]bool)
+	for _, c := range added {
+		for _, f := range c.Files {
+			theirs[f] = true
+		}
+	}
+	var conflicts []string
+	for _, c := range own {
+		for _, f := range c.Files {
+			if theirs[f] && !slices.Contains(conflicts, f) {
+				conflicts = append(conflicts, f)
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return conflicts, nil
+	}
+	rebased := append([]Commit{}, target...)
+	for _, c := range own {
+		rebased = append(rebased, Commit{SHA: g.newSHA(), Message: c.Message, Files: c.Files})
+	}
+	g.branches[g.branch] = rebased
+	return nil, nil
+}
+
+// Push implements GitRunner.
+func (g *Git) Push(ctx context.Context, branch string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.PushErrors) > 0 {
+		err := g.PushErrors[0]
+		g.PushErrors = g.PushErrors[1:]
+		if err != nil {
+			return err
+		}
+	}
+	commits, ok := g.branches[branch]
+	if !ok {
+		return fmt.Errorf("fake git: no branch %s", branch)
+	}
+	g.remote[branch] = append([]Commit{}, commits...)
+	return nil
+}
+
+// PushWithRetry implements GitRunner, retrying network errors as the
+// policy says but without waiting.
+func (g *Git) PushWithRetry(ctx context.Context, branch string, policy retry.Policy) error {
+	policy.Delay, policy.Jitter = 0, 0
+	return policy.Do(ctx, func() error { return g.Push(ctx, branch) }, git.IsNetworkError, nil)
+}
+
+// ForcePushWithLease implements GitRunner, refusing to push if the remote
+// branch isn't at expected.
+func (g *Git) ForcePushWithLease(ctx context.Context, branch, expected string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	remote := g.remote[branch]
+	if len(remote) == 0 || remote[len(remote)-1].SHA != expected {
+		return fmt.Errorf("fake git: stale info, %s is no longer at %s", branch, expected)
+	}
+	g.remote[branch] = append([]Commit{}, g.branches[g.branch]...)
+	return nil
+}
+
+// PushTo implements GitRunner.
+func (g *Git) PushTo(ctx context.Context, branch string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.remote[branch] = append([]Commit{}, g.branches[g.branch]...)
+	return nil
+}
+
+// Pull implements GitRunner, fast-forwarding to origin.
+func (g *Git) Pull(ctx context.Context, branch string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	commits, ok := g.remote[branch]
+	if !ok {
+		return fmt.Errorf("fake git: no branch %s on origin", branch)
+	}
+	g.branches[g.branch] = append([]Commit{}, commits...)
+	return nil
+}
+
+// Fetch implements GitRunner. Origin is always up to date.
+func (g *Git) Fetch(ctx context.Context, branch string) error { return nil }
+
+// RemoteBranches implements GitRunner.
+func (g *Git) RemoteBranches(ctx context.Context, prefix string) ([]string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var names []string
+	for _, name := range sortedKeys(g.remote) {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// DeleteRemoteBranches implements GitRunner.
+func (g *Git) DeleteRemoteBranches(ctx context.Context, names ...string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, name := range names {
+		delete(g.remote, name)
+	}
+	return nil
+}
+
+// merge adds a PR's commits on origin's head to base, as one commit when
+// squashed. It returns the merge commit's SHA.
+func (g *Git) merge(head, base, title, strategy string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	commits, ok := g.remote[head]
+	if !ok {
+		return "", fmt.Errorf("fake git: no branch %s on origin", head)
+	}
+	added := since(commits, g.remote[base])
+	if strategy == "squash" {
+		var files []string
+		for _, c := range added {
+			files = append(files, c.Files...)
+		}
+		added = []Commit{{SHA: g.newSHA(), Message: title, Files: files}}
+	}
+	g.remote[base] = append(g.remote[base], added...)
+	delete(g.remote, head)
+	return g.remote[base][len(g.remote[base])-1].SHA, nil
+}
+
+// remoteDiff is the diff of a branch on origin against base.
+func (g *Git) remoteDiff(head, base string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return diff(since(g.remote[head], g.remote[base]))
+}
+
+func (g *Git) newSHA() string {
+	g.nextSHA++
+	sum := sha256.Sum256([]byte(strconv.Itoa(g.nextSHA)))
+	return hex.EncodeToString(sum[:20])
+}
+
+func (g *Git) track(p string) {
+	for _, f := range g.files {
+		if f == p {
+			return
+		}
+	}
+	g.files = append(g.files, p)
+}
+
+// since returns the commits of a that aren't in b.
+func since(a, b []Commit) []Commit {
+	in := make(map[string]bool, len(b))
+	for _, c := range b {
+		in[c.SHA] = true
+	}
+	var out []Commit
+	for _, c := range a {
+		if !in[c.SHA] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// diff renders the files changed by commits, stable across SHAs so equal
+// work has equal patch IDs.
+func diff(commits []Commit) string {
+	var b strings.Builder
+	for _, c := range commits {
+		for _, f := range c.Files {
+			fmt.Fprintf(&b, "diff --git a/%s b/%s\n+%s\n", f, f, c.Title())
+		}
+	}
+	return b.String()
+}
+
+// excluded reports whether p matches an exclude pattern: a directory
+// ("/prompts/"), a path or a glob.
+func excluded(p string, excludes []string) bool {
+	for _, pattern := range excludes {
+		pattern = strings.TrimPrefix(pattern, "/")
+		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(p, pattern) {
+			return true
+		}
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(p)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
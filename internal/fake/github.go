@@ -0,0 +1,533 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/retry"
+)
+
+// Passing is the status of a PR whose checks passed and that can merge.
+func Passing() *github.PRStatus {
+	return &github.PRStatus{AllChecksPassed: true, IsMergeable: true, ReviewDecision: "APPROVED"}
+}
+
+// Failing is the status of a PR with a failed check.
+func Failing() *github.PRStatus {
+	return &github.PRStatus{
+		Checks:          []github.PRCheck{{Name: "test", State: "FAILURE", Bucket: "fail"}},
+		HasFailedChecks: true,
+		IsMergeable:     true,
+	}
+}
+
+// ReviewRequired is the status of a PR whose checks passed but that
+// nobody has reviewed yet.
+func ReviewRequired() *github.PRStatus {
+	return &github.PRStatus{AllChecksPassed: true, ReviewDecision: "REVIEW_REQUIRED"}
+}
+
+// ChangesRequested is the status of a PR whose checks passed but whose
+// review blocks the merge.
+func ChangesRequested() *github.PRStatus {
+	return &github.PRStatus{AllChecksPassed: true, ReviewDecision: "CHANGES_REQUESTED"}
+}
+
+// GitHub is an in-memory forge for a fake repository: PRs are opened from
+// the branches pushed to its origin and merged into them.
+type GitHub struct {
+	mu sync.Mutex
+
+	git         *Git
+	owner, repo string
+	prs         []*github.PullRequest
+	releases    []string
+	comments    []string
+	gists       []string
+	opened      []string
+	merges      []string
+	checkRuns   []github.CheckRun
+	statuses    []github.CommitStatus
+	threads     map[string][]github.Comment
+	lastComment int64
+	labels      []string
+	prLabels    map[string][]string
+
+	// Statuses are the checks and reviews of PRs by the order they're
+	// opened: the first PR gets Statuses[0]. A nil status times out
+	// waiting for checks, and PRs past the end pass.
+	Statuses []*github.PRStatus
+	// PendingPolls are how many polls of their status the PRs answer with
+	// pending checks before their scripted status, by the order they're
+	// opened, like checks that take a while to finish
+	PendingPolls []int
+	polls        map[string]int
+	// MainStatuses are the checks of the commits PRs merge into their base,
+	// by the order they merge; commits past the end pass
+	MainStatuses []*github.PRStatus
+	// Deployments are the states of the deployments of those commits, by
+	// the order they merge; commits past the end deploy successfully
+	Deployments []string
+	// Issues maps issue numbers to their state; unknown issues are open
+	Issues map[string]string
+	// OnReRequestReview is called with the PR's number when review of a PR
+	// is re-requested, e.g. to have a reviewer approve it
+	OnReRequestReview func(prNumber string)
+	reRequested       []string
+}
+
+// NewGitHub returns a forge hosting the fake repository.
+func NewGitHub(g *Git, owner, repo string) *GitHub {
+	return &GitHub{git: g, owner: owner, repo: repo, Issues: make(map[string]string), threads: make(map[string][]github.Comment), prLabels: make(map[string][]string), polls: make(map[string]int)}
+}
+
+// PRs returns the PRs opened so far, oldest first.
+func (h *GitHub) PRs() []github.PullRequest {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	prs := make([]github.PullRequest, len(h.prs))
+	for i, pr := range h.prs {
+		prs[i] = *pr
+	}
+	return prs
+}
+
+// Polls returns how many times a PR's status was polled.
+func (h *GitHub) Polls(prNumber string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.polls[prNumber]
+}
+
+// Releases returns the tags released so far.
+func (h *GitHub) Releases() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string{}, h.releases...)
+}
+
+// Comments returns the issue comments posted so far.
+func (h *GitHub) Comments() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string{}, h.comments...)
+}
+
+// OpenedIssues returns the bodies of the issues opened so far.
+func (h *GitHub) OpenedIssues() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string{}, h.opened...)
+}
+
+// Labels returns the labels of a PR.
+func (h *GitHub) Labels(prNumber string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string{}, h.prLabels[prNumber]...)
+}
+
+// AddComment posts a comment on an issue or PR as someone else, such as
+// a reviewer.
+func (h *GitHub) AddComment(number, author, association, body string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.addComment(number, author, association, body)
+}
+
+func (h *GitHub) addComment(number, author, association, body string) {
+	h.lastComment++
+	comment := github.Comment{ID: h.lastComment, Author: author, Association: association, Body: body, CreatedAt: time.Now()}
+	h.threads[number] = append(h.threads[number], comment)
+}
+
+// SetLogger implements GhRunner.
+func (h *GitHub) SetLogger(l github.Logger) {}
+
+// SetCheckScope implements GhRunner.
+func (h *GitHub) SetCheckScope(names []string) {}
+
+// SetRetryPolicy implements GhRunner.
+func (h *GitHub) SetRetryPolicy(p retry.Policy) {}
+
+// Owner implements GhRunner.
+func (h *GitHub) Owner() string { return h.owner }
+
+// Repo implements GhRunner.
+func (h *GitHub) Repo() string { return h.repo }
+
+// CheckRuns returns the check runs published so far, oldest first.
+func (h *GitHub) CheckRuns() []github.CheckRun {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]github.CheckRun{}, h.checkRuns...)
+}
+
+// CommitStatuses returns the commit statuses set so far, oldest first.
+func (h *GitHub) CommitStatuses() []github.CommitStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]github.CommitStatus{}, h.statuses...)
+}
+
+// CheckAuth implements GhRunner.
+func (h *GitHub) CheckAuth(ctx context.Context) error { return nil }
+
+// LogRateLimit implements GhRunner.
+func (h *GitHub) LogRateLimit(ctx context.Context) {}
+
+// CreatePR implements GhRunner, from the current branch, which must have
+// been pushed.
+func (h *GitHub) CreatePR(ctx context.Context, title, body, base string) (string, error) {
+	return h.createPR(title, body, base, false)
+}
+
+// CreateDraftPR implements GhRunner.
+func (h *GitHub) CreateDraftPR(ctx context.Context, title, body, base string) (string, error) {
+	return h.createPR(title, body, base, true)
+}
+
+func (h *GitHub) createPR(title, body, base string, draft bool) (string, error) {
+	head, _ := h.git.CurrentBranch()
+	if len(h.git.RemoteLog(head)) == 0 {
+		return "", fmt.Errorf("fake gh: branch %s was not pushed", head)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pr := &github.PullRequest{
+		Number:      len(h.prs) + 1,
+		Title:       title,
+		Body:        body,
+		BaseRefName: base,
+		HeadRefName: head,
+		State:       "OPEN",
+		IsDraft:     draft,
+		UpdatedAt:   time.Now(),
+	}
+	pr.URL = fmt.Sprintf("https://github.com/%s/%s/pull/%d", h.owner, h.repo, pr.Number)
+	pr.Author.Login = "deep-claude"
+	h.prs = append(h.prs, pr)
+	return pr.URL, nil
+}
+
+// EditPR implements GhRunner.
+func (h *GitHub) EditPR(ctx context.Context, prNumber, title, body string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pr, err := h.find(prNumber)
+	if err != nil {
+		return err
+	}
+	pr.Title, pr.Body, pr.UpdatedAt = title, body, time.Now()
+	return nil
+}
+
+// GetPR implements GhRunner.
+func (h *GitHub) GetPR(ctx context.Context, prNumber string) (*github.PullRequest, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pr, err := h.find(prNumber)
+	if err != nil {
+		return nil, err
+	}
+	copied := *pr
+	return &copied, nil
+}
+
+// GetPRDiff implements GhRunner.
+func (h *GitHub) GetPRDiff(ctx context.Context, prNumber string) (string, error) {
+	pr, err := h.GetPR(ctx, prNumber)
+	if err != nil {
+		return "", err
+	}
+	return h.git.remoteDiff(pr.HeadRefName, pr.BaseRefName), nil
+}
+
+// GetPRStatus implements GhRunner. A PR scripted to time out reports
+// pending checks.
+func (h *GitHub) GetPRStatus(ctx context.Context, prNumber string) (*github.PRStatus, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, err := h.find(prNumber); err != nil {
+		return nil, err
+	}
+	h.polls[prNumber]++
+	if n, _ := strconv.Atoi(prNumber); n <= len(h.PendingPolls) && h.polls[prNumber] <= h.PendingPolls[n-1] {
+		return &github.PRStatus{HasPendingChecks: true}, nil
+	}
+	if status := h.status(prNumber); status != nil {
+		return status, nil
+	}
+	return &github.PRStatus{HasPendingChecks: true}, nil
+}
+
+// WaitForChecks implements GhRunner without waiting: the scripted status
+// is reported at once, or the wait times out.
+func (h *GitHub) WaitForChecks(ctx context.Context, prNumber string, timeout time.Duration, onStatusChange func(*github.PRStatus)) (*github.PRStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	_, err := h.find(prNumber)
+	status := h.status(prNumber)
+	h.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if status == nil {
+		return nil, fmt.Errorf("timeout waiting for checks after %s", timeout)
+	}
+	if onStatusChange != nil {
+		onStatusChange(status)
+	}
+	return status, nil
+}
+
+// MergePR implements GhRunner, deleting the head branch.
+func (h *GitHub) MergePR(ctx context.Context, prNumber, strategy string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pr, err := h.find(prNumber)
+	if err != nil {
+		return err
+	}
+	if pr.State != "OPEN" {
+		return fmt.Errorf("fake gh: PR #%s is %s", prNumber, pr.State)
+	}
+	if status := h.status(prNumber); status == nil || !status.IsMergeable {
+		return fmt.Errorf("fake gh: PR #%s is not mergeable", prNumber)
+	}
+	sha, err := h.git.merge(pr.HeadRefName, pr.BaseRefName, pr.Title, strategy)
+	if err != nil {
+		return err
+	}
+	pr.State, pr.MergeCommit.Oid, pr.UpdatedAt = "MERGED", sha, time.Now()
+	h.merges = append(h.merges, sha)
+	return nil
+}
+
+// GetCommitStatus implements GhRunner with the scripted status of the
+// merge commit; other commits pass.
+func (h *GitHub) GetCommitStatus(ctx context.Context, sha string) (*github.PRStatus, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, merged := range h.merges {
+		if merged == sha && i < len(h.MainStatuses) {
+			return h.MainStatuses[i], nil
+		}
+	}
+	return Passing(), nil
+}
+
+// GetDeploymentState implements GhRunner with the scripted state of the
+// merge commit's deployment to any environment.
+func (h *GitHub) GetDeploymentState(ctx context.Context, sha, environment string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, merged := range h.merges {
+		if merged == sha && i < len(h.Deployments) {
+			return h.Deployments[i], nil
+		}
+	}
+	return "success", nil
+}
+
+// ClosePR implements GhRunner.
+func (h *GitHub) ClosePR(ctx context.Context, prNumber string, deleteBranch bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pr, err := h.find(prNumber)
+	if err != nil {
+		return err
+	}
+	pr.State, pr.UpdatedAt = "CLOSED", time.Now()
+	if deleteBranch {
+		return h.git.DeleteRemoteBranches(ctx, pr.HeadRefName)
+	}
+	return nil
+}
+
+// CreateLabel implements GhRunner.
+func (h *GitHub) CreateLabel(ctx context.Context, name string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !slices.Contains(h.labels, name) {
+		h.labels = append(h.labels, name)
+	}
+	return nil
+}
+
+// AddLabels implements GhRunner, failing for labels that don't exist.
+func (h *GitHub) AddLabels(ctx context.Context, prNumber string, labels []string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, err := h.find(prNumber); err != nil {
+		return err
+	}
+	for _, label := range labels {
+		if !slices.Contains(h.labels, label) {
+			return fmt.Errorf("label %s not found", label)
+		}
+		if !slices.Contains(h.prLabels[prNumber], label) {
+			h.prLabels[prNumber] = append(h.prLabels[prNumber], label)
+		}
+	}
+	return nil
+}
+
+// CreateCheckRun implements GhRunner.
+func (h *GitHub) CreateCheckRun(ctx context.Context, run github.CheckRun) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkRuns = append(h.checkRuns, run)
+	return fmt.Sprintf("https://github.com/%s/%s/runs/%d", h.owner, h.repo, len(h.checkRuns)), nil
+}
+
+// SetCommitStatus implements GhRunner.
+func (h *GitHub) SetCommitStatus(ctx context.Context, status github.CommitStatus) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.statuses = append(h.statuses, status)
+	return nil
+}
+
+// ListOpenPRs implements GhRunner.
+func (h *GitHub) ListOpenPRs(ctx context.Context, limit int) ([]github.PullRequest, error) {
+	var open []github.PullRequest
+	for _, pr := range h.newestFirst(0) {
+		if pr.State == "OPEN" && (limit <= 0 || len(open) < limit) {
+			if log := h.git.RemoteLog(pr.HeadRefName); len(log) > 0 {
+				pr.HeadRefOid = log[len(log)-1].SHA
+			}
+			open = append(open, pr)
+		}
+	}
+	return open, nil
+}
+
+// ListPRs implements GhRunner.
+func (h *GitHub) ListPRs(ctx context.Context, limit int) ([]github.PullRequest, error) {
+	return h.newestFirst(limit), nil
+}
+
+// GetIssueState implements GhRunner.
+func (h *GitHub) GetIssueState(ctx context.Context, number string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if state, ok := h.Issues[number]; ok {
+		return state, nil
+	}
+	return "OPEN", nil
+}
+
+// CommentOnIssue implements GhRunner.
+func (h *GitHub) CommentOnIssue(ctx context.Context, number, body string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.comments = append(h.comments, body)
+	h.addComment(number, "deep-claude", "MEMBER", body)
+	return fmt.Sprintf("https://github.com/%s/%s/issues/%s#issuecomment-%d", h.owner, h.repo, number, len(h.comments)), nil
+}
+
+// ListComments implements GhRunner.
+func (h *GitHub) ListComments(ctx context.Context, number string) ([]github.Comment, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]github.Comment{}, h.threads[number]...), nil
+}
+
+// ReRequestReview implements GhRunner; "octocat" is asked to review.
+func (h *GitHub) ReRequestReview(ctx context.Context, prNumber string) ([]string, error) {
+	h.mu.Lock()
+	if _, err := h.find(prNumber); err != nil {
+		h.mu.Unlock()
+		return nil, err
+	}
+	h.reRequested = append(h.reRequested, prNumber)
+	h.mu.Unlock()
+	if h.OnReRequestReview != nil {
+		h.OnReRequestReview(prNumber)
+	}
+	return []string{"octocat"}, nil
+}
+
+// ReRequested returns the PRs whose review was re-requested, in order.
+func (h *GitHub) ReRequested() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string{}, h.reRequested...)
+}
+
+// CreateIssue implements GhRunner. Issues are numbered after the PRs
+// opened so far.
+func (h *GitHub) CreateIssue(ctx context.Context, title, body string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.opened = append(h.opened, body)
+	number := strconv.Itoa(len(h.prs) + len(h.opened))
+	return fmt.Sprintf("https://github.com/%s/%s/issues/%s", h.owner, h.repo, number), nil
+}
+
+// CreateGist implements GhRunner.
+func (h *GitHub) CreateGist(ctx context.Context, filename, description, content string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.gists = append(h.gists, content)
+	return fmt.Sprintf("https://gist.github.com/deep-claude/%d", len(h.gists)), nil
+}
+
+// GetLatestRelease implements GhRunner.
+func (h *GitHub) GetLatestRelease(ctx context.Context, owner, repo string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.releases) == 0 {
+		return "", nil
+	}
+	return h.releases[len(h.releases)-1], nil
+}
+
+// CreateRelease implements GhRunner.
+func (h *GitHub) CreateRelease(ctx context.Context, tag, title, notes, target string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.releases = append(h.releases, tag)
+	return fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", h.owner, h.repo, tag), nil
+}
+
+func (h *GitHub) find(prNumber string) (*github.PullRequest, error) {
+	n, err := strconv.Atoi(prNumber)
+	if err != nil || n < 1 || n > len(h.prs) {
+		return nil, fmt.Errorf("fake gh: no PR #%s", prNumber)
+	}
+	return h.prs[n-1], nil
+}
+
+// status returns the scripted status of a PR, nil if it times out.
+func (h *GitHub) status(prNumber string) *github.PRStatus {
+	n, _ := strconv.Atoi(prNumber)
+	if n > len(h.Statuses) {
+		return Passing()
+	}
+	return h.Statuses[n-1]
+}
+
+func (h *GitHub) newestFirst(limit int) []github.PullRequest {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var prs []github.PullRequest
+	for _, pr := range h.prs {
+		prs = append(prs, *pr)
+	}
+	sort.SliceStable(prs, func(i, j int) bool { return prs[i].Number > prs[j].Number })
+	if limit > 0 && len(prs) > limit {
+		prs = prs[:limit]
+	}
+	return prs
+}
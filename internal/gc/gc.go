@@ -0,0 +1,99 @@
+// Package gc finds branches and PRs left behind by earlier runs.
+package gc
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/github"
+)
+
+// prLimit is how many of the most recent PRs are checked. Branches whose
+// PRs are older than that are kept, since their state is unknown.
+const prLimit = 2000
+
+// Branch is a remote branch whose PRs are all closed or merged.
+type Branch struct {
+	Name string
+	// Number and state of the branch's latest PR
+	PR    int
+	State string
+}
+
+// Stale returns the branches whose PRs are all closed or merged. Branches
+// without a PR are kept: their PR may not have been opened yet.
+func Stale(branches []string, prs []github.PullRequest) []Branch {
+	latest := make(map[string]github.PullRequest)
+	open := make(map[string]bool)
+	for _, pr := range prs {
+		if pr.State == "OPEN" {
+			open[pr.HeadRefName] = true
+		}
+		if pr.Number > latest[pr.HeadRefName].Number {
+			latest[pr.HeadRefName] = pr
+		}
+	}
+
+	var stale []Branch
+	for _, name := range branches {
+		pr, ok := latest[name]
+		if !ok || open[name] {
+			continue
+		}
+		stale = append(stale, Branch{Name: name, PR: pr.Number, State: pr.State})
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Name < stale[j].Name })
+	return stale
+}
+
+// StalePRs returns the PRs from branches with the prefix, i.e. opened by
+// runs, that have seen no activity for maxAge, oldest first.
+func StalePRs(prs []github.PullRequest, prefix string, maxAge time.Duration, now time.Time) []github.PullRequest {
+	var stale []github.PullRequest
+	for _, pr := range prs {
+		if strings.HasPrefix(pr.HeadRefName, prefix) && now.Sub(pr.UpdatedAt) >= maxAge {
+			stale = append(stale, pr)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].UpdatedAt.Before(stale[j].UpdatedAt) })
+	return stale
+}
+
+// Remote lists and deletes branches on origin, like *git.Client.
+type Remote interface {
+	RemoteBranches(ctx context.Context, prefix string) ([]string, error)
+	DeleteRemoteBranches(ctx context.Context, names ...string) error
+}
+
+// PRLister lists the most recent PRs, like *github.Client.
+type PRLister interface {
+	ListPRs(ctx context.Context, limit int) ([]github.PullRequest, error)
+}
+
+// Find lists the stale remote branches with the given prefix.
+func Find(ctx context.Context, gitClient Remote, ghClient PRLister, prefix string) ([]Branch, error) {
+	branches, err := gitClient.RemoteBranches(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(branches) == 0 {
+		return nil, nil
+	}
+
+	prs, err := ghClient.ListPRs(ctx, prLimit)
+	if err != nil {
+		return nil, err
+	}
+	return Stale(branches, prs), nil
+}
+
+// Delete removes the branches from origin.
+func Delete(ctx context.Context, gitClient Remote, branches []Branch) error {
+	names := make([]string, len(branches))
+	for i, b := range branches {
+		names[i] = b.Name
+	}
+	return gitClient.DeleteRemoteBranches(ctx, names...)
+}
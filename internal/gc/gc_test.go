@@ -0,0 +1,73 @@
+package gc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/github"
+)
+
+func pr(number int, state, head string) github.PullRequest {
+	return github.PullRequest{Number: number, State: state, HeadRefName: head}
+}
+
+func TestStale(t *testing.T) {
+	branches := []string{
+		"deep-claude/run-b/iteration-2",
+		"deep-claude/run-a/iteration-1",
+		"deep-claude/run-a/iteration-2",
+		"deep-claude/run-c/iteration-1",
+		"deep-claude/reopened",
+	}
+	prs := []github.PullRequest{
+		pr(10, "MERGED", "deep-claude/run-a/iteration-1"),
+		pr(11, "CLOSED", "deep-claude/run-a/iteration-2"),
+		pr(12, "OPEN", "deep-claude/run-b/iteration-2"),
+		pr(13, "CLOSED", "deep-claude/reopened"),
+		pr(14, "OPEN", "deep-claude/reopened"),
+		pr(15, "MERGED", "feature/other"),
+	}
+
+	got := Stale(branches, prs)
+	want := []Branch{
+		{Name: "deep-claude/run-a/iteration-1", PR: 10, State: "MERGED"},
+		{Name: "deep-claude/run-a/iteration-2", PR: 11, State: "CLOSED"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Stale() = %+v, want %+v", got, want)
+	}
+}
+
+func TestStaleLatestPR(t *testing.T) {
+	got := Stale([]string{"deep-claude/x"}, []github.PullRequest{
+		pr(20, "MERGED", "deep-claude/x"),
+		pr(7, "CLOSED", "deep-claude/x"),
+	})
+	want := []Branch{{Name: "deep-claude/x", PR: 20, State: "MERGED"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Stale() = %+v, want %+v", got, want)
+	}
+}
+
+func TestStalePRs(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	updated := func(number int, head string, age time.Duration) github.PullRequest {
+		return github.PullRequest{Number: number, HeadRefName: head, UpdatedAt: now.Add(-age)}
+	}
+	prs := []github.PullRequest{
+		updated(1, "deep-claude/run-a/iteration-1", 8*24*time.Hour),
+		updated(2, "deep-claude/run-a/iteration-2", 2*24*time.Hour),
+		updated(3, "feature/manual", 30*24*time.Hour),
+		updated(4, "deep-claude/run-b/iteration-1", 10*24*time.Hour),
+	}
+
+	got := StalePRs(prs, "deep-claude/", 7*24*time.Hour, now)
+	var numbers []int
+	for _, pr := range got {
+		numbers = append(numbers, pr.Number)
+	}
+	if want := []int{4, 1}; !reflect.DeepEqual(numbers, want) {
+		t.Errorf("StalePRs() = %v, want %v", numbers, want)
+	}
+}
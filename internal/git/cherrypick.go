@@ -0,0 +1,142 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// CreateBranchFrom creates a branch at startPoint, resetting it if it
+// already exists, and switches to it.
+func (c *Client) CreateBranchFrom(name, startPoint string) error {
+	cmd := exec.Command("git", "checkout", "-B", name, startPoint)
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create branch %s from %s: %w\n%s", name, startPoint, err, output)
+	}
+	return nil
+}
+
+// ParentCount returns the number of parents of a commit.
+func (c *Client) ParentCount(sha string) (int, error) {
+	cmd := exec.Command("git", "rev-list", "--parents", "-n", "1", sha)
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read commit %s: %w", sha, err)
+	}
+	return len(strings.Fields(string(output))) - 1, nil
+}
+
+// CherryPick applies a commit onto the current branch, recording its origin
+// in the message. Merge commits are picked relative to their first parent.
+// When the pick stops on conflicts, the cherry-pick is left in progress
+// and the conflicted paths are returned.
+func (c *Client) CherryPick(sha string) ([]string, error) {
+	parents, err := c.ParentCount(sha)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"cherry-pick", "-x"}
+	if parents > 1 {
+		args = append(args, "-m", "1")
+	}
+	cmd := exec.Command("git", append(args, sha)...)
+	cmd.Dir = c.workDir
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil, nil
+	}
+
+	conflicts, cerr := c.ConflictedFiles()
+	if cerr != nil || len(conflicts) == 0 {
+		_ = c.CherryPickAbort()
+		return nil, fmt.Errorf("failed to cherry-pick %s: %w\n%s", sha, err, output)
+	}
+	return conflicts, nil
+}
+
+// ConflictedFiles returns the paths with unresolved merge conflicts.
+func (c *Client) ConflictedFiles() ([]string, error) {
+	cmd := exec.Command("git", "diff", "--name-only", "--diff-filter=U")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conflicts: %w", err)
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// UnresolvedConflicts returns the paths that still contain conflict markers.
+func (c *Client) UnresolvedConflicts(paths []string) []string {
+	var unresolved []string
+	for _, p := range paths {
+		content, err := os.ReadFile(filepath.Join(c.workDir, p))
+		if err != nil {
+			// Deleting the file is a valid resolution
+			continue
+		}
+		if hasConflictMarkers(string(content)) {
+			unresolved = append(unresolved, p)
+		}
+	}
+	return unresolved
+}
+
+// CherryPickContinue commits the in-progress cherry-pick with its original
+// message once the conflicts are resolved and staged.
+func (c *Client) CherryPickContinue() error {
+	cmd := exec.Command("git", "-c", "core.editor=true", "cherry-pick", "--continue")
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to continue cherry-pick: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// CherryPickAbort abandons the in-progress cherry-pick.
+func (c *Client) CherryPickAbort() error {
+	cmd := exec.Command("git", "cherry-pick", "--abort")
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to abort cherry-pick: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// Revert commits the inverse of sha, against its first parent if it is a
+// merge commit.
+func (c *Client) Revert(sha string) error {
+	parents, err := c.ParentCount(sha)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"revert", "--no-edit"}
+	if parents > 1 {
+		args = append(args, "-m", "1")
+	}
+	cmd := exec.Command("git", append(args, sha)...)
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		abort := exec.Command("git", "revert", "--abort")
+		abort.Dir = c.workDir
+		_ = abort.Run()
+		return fmt.Errorf("failed to revert %s: %w\n%s", sha, err, output)
+	}
+	return nil
+}
+
+// hasConflictMarkers reports whether content contains the start or end
+// marker of a conflict hunk.
+func hasConflictMarkers(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "<<<<<<< ") || strings.HasPrefix(line, ">>>>>>> ") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package git
+
+import "testing"
+
+func TestHasConflictMarkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"conflict", "a\n<<<<<<< HEAD\nb\n=======\nc\n>>>>>>> 1a2b3c4 (Fix bug)\n", true},
+		{"resolved", "a\nb\nc\n", false},
+		{"setext heading", "Title\n=======\n", false},
+		{"marker inside line", "x := \"<<<<<<< HEAD\"\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasConflictMarkers(tt.content); got != tt.want {
+				t.Errorf("hasConflictMarkers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,13 +3,16 @@ package git
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
 	"os/exec"
 	"regexp"
+	"strconv"
 	"strings"
-	"time"
+
+	"github.com/guzus/deep-claude/internal/retry"
 )
 
 // Client handles Git operations.
@@ -115,6 +118,94 @@ func (c *Client) DeleteBranch(name string) error {
 	return nil
 }
 
+// RemoteBranches lists the branches on origin whose names start with prefix.
+func (c *Client) RemoteBranches(ctx context.Context, prefix string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--heads", "origin")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote branches: %w", err)
+	}
+	return parseRemoteHeads(string(output), prefix), nil
+}
+
+// parseRemoteHeads extracts the branch names starting with prefix from
+// the output of git ls-remote --heads.
+func parseRemoteHeads(output, prefix string) []string {
+	var branches []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		name, ok := strings.CutPrefix(fields[1], "refs/heads/")
+		if ok && strings.HasPrefix(name, prefix) {
+			branches = append(branches, name)
+		}
+	}
+	return branches
+}
+
+// DeleteRemoteBranches deletes branches from origin in a single push.
+func (c *Client) DeleteRemoteBranches(ctx context.Context, names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "git", append([]string{"push", "origin", "--delete"}, names...)...)
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to delete remote branches: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// BranchDiff returns the changes of the current branch since it forked
+// from base.
+func (c *Client) BranchDiff(base string) (string, error) {
+	return c.Run("diff", base+"...HEAD")
+}
+
+// PatchID returns a stable ID for the changes in a diff, which is the same
+// for equivalent diffs regardless of line numbers and whitespace, or ""
+// for an empty diff.
+func (c *Client) PatchID(diff string) (string, error) {
+	cmd := exec.Command("git", "patch-id", "--stable")
+	cmd.Dir = c.workDir
+	cmd.Stdin = strings.NewReader(diff)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to compute patch ID: %w", err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], nil
+}
+
+// ForcePushWithLease replaces the remote branch with HEAD only if it is
+// still at expected, so that commits someone else pushed meanwhile aren't
+// lost.
+func (c *Client) ForcePushWithLease(ctx context.Context, branch, expected string) error {
+	lease := fmt.Sprintf("--force-with-lease=refs/heads/%s:%s", branch, expected)
+	cmd := exec.CommandContext(ctx, "git", "push", lease, "origin", "HEAD:refs/heads/"+branch)
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to push to %s: %w\n%s", branch, err, output)
+	}
+	return nil
+}
+
+// PushTo pushes HEAD to branch on origin, which must fast-forward.
+func (c *Client) PushTo(ctx context.Context, branch string) error {
+	cmd := exec.CommandContext(ctx, "git", "push", "origin", "HEAD:refs/heads/"+branch)
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to push to %s: %w\n%s", branch, err, output)
+	}
+	return nil
+}
+
 // StageAll stages all changes.
 func (c *Client) StageAll() error {
 	cmd := exec.Command("git", "add", ".")
@@ -125,9 +216,10 @@ func (c *Client) StageAll() error {
 	return nil
 }
 
-// HasChanges checks if there are staged or unstaged changes.
-func (c *Client) HasChanges() (bool, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
+// HasChanges checks if there are staged or unstaged changes, leaving out
+// paths matching the gitignore-style excludes.
+func (c *Client) HasChanges(excludes []string) (bool, error) {
+	cmd := exec.Command("git", append([]string{"status", "--porcelain", "--", "."}, ExcludePathspecs(excludes)...)...)
 	cmd.Dir = c.workDir
 	output, err := cmd.Output()
 	if err != nil {
@@ -136,6 +228,48 @@ func (c *Client) HasChanges() (bool, error) {
 	return len(strings.TrimSpace(string(output))) > 0, nil
 }
 
+// Stash stashes all local changes, including untracked files, under the
+// given message, except paths matching the gitignore-style excludes. It
+// reports whether anything was stashed.
+func (c *Client) Stash(message string, excludes []string) (bool, error) {
+	cmd := exec.Command("git", append([]string{"stash", "push", "--include-untracked", "-m", message, "--", "."}, ExcludePathspecs(excludes)...)...)
+	cmd.Dir = c.workDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("failed to stash changes: %w\n%s", err, output)
+	}
+	return !strings.Contains(string(output), "No local changes to save"), nil
+}
+
+// StashPop restores the most recent stash entry with the given message.
+func (c *Client) StashPop(message string) error {
+	cmd := exec.Command("git", "stash", "list", "--format=%gd %s")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to list stashes: %w", err)
+	}
+
+	var ref string
+	for _, line := range strings.Split(string(output), "\n") {
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) == 2 && strings.HasSuffix(parts[1], message) {
+			ref = parts[0]
+			break
+		}
+	}
+	if ref == "" {
+		return fmt.Errorf("stash %q not found", message)
+	}
+
+	cmd = exec.Command("git", "stash", "pop", ref)
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to restore stash %s: %w\n%s", ref, err, output)
+	}
+	return nil
+}
+
 // Commit creates a commit with the given message.
 func (c *Client) Commit(message string) error {
 	cmd := exec.Command("git", "commit", "-m", message)
@@ -146,45 +280,49 @@ func (c *Client) Commit(message string) error {
 	return nil
 }
 
-// Push pushes the current branch to origin.
-func (c *Client) Push(branch string) error {
-	cmd := exec.Command("git", "push", "-u", "origin", branch)
+// CommitEmpty creates a commit that changes no files.
+func (c *Client) CommitEmpty(message string) error {
+	cmd := exec.Command("git", "commit", "--allow-empty", "-m", message)
 	cmd.Dir = c.workDir
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to push: %w\n%s", err, output)
+		return fmt.Errorf("failed to commit: %w\n%s", err, output)
 	}
 	return nil
 }
 
-// PushWithRetry pushes with exponential backoff retry.
-func (c *Client) PushWithRetry(branch string, maxRetries int) error {
-	var lastErr error
-	backoff := 2 * time.Second
-
-	for i := 0; i <= maxRetries; i++ {
-		if i > 0 {
-			time.Sleep(backoff)
-			backoff *= 2
-		}
-
-		err := c.Push(branch)
-		if err == nil {
-			return nil
-		}
-		lastErr = err
+// AmendCommit adds the staged changes to the last commit, keeping its
+// message.
+func (c *Client) AmendCommit() error {
+	cmd := exec.Command("git", "commit", "--amend", "--no-edit")
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to amend commit: %w\n%s", err, output)
+	}
+	return nil
+}
 
-		// Only retry on network errors
-		if !isNetworkError(err) {
-			return err
-		}
+// Push pushes the current branch to origin.
+func (c *Client) Push(ctx context.Context, branch string) error {
+	cmd := exec.CommandContext(ctx, "git", "push", "-u", "origin", branch)
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to push: %w\n%s", err, output)
 	}
+	return nil
+}
 
-	return fmt.Errorf("push failed after %d retries: %w", maxRetries, lastErr)
+// PushWithRetry pushes, retrying network errors as the policy says.
+func (c *Client) PushWithRetry(ctx context.Context, branch string, policy retry.Policy) error {
+	err := policy.Do(ctx, func() error { return c.Push(ctx, branch) }, IsNetworkError, nil)
+	if IsNetworkError(err) && policy.Attempts > 1 {
+		return fmt.Errorf("push failed after %d retries: %w", policy.Attempts-1, err)
+	}
+	return err
 }
 
 // Pull pulls the latest changes from origin for the given branch.
-func (c *Client) Pull(branch string) error {
-	cmd := exec.Command("git", "pull", "origin", branch)
+func (c *Client) Pull(ctx context.Context, branch string) error {
+	cmd := exec.CommandContext(ctx, "git", "pull", "origin", branch)
 	cmd.Dir = c.workDir
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to pull: %w\n%s", err, output)
@@ -193,8 +331,8 @@ func (c *Client) Pull(branch string) error {
 }
 
 // Fetch fetches from origin.
-func (c *Client) Fetch(branch string) error {
-	cmd := exec.Command("git", "fetch", "origin", branch)
+func (c *Client) Fetch(ctx context.Context, branch string) error {
+	cmd := exec.CommandContext(ctx, "git", "fetch", "origin", branch)
 	cmd.Dir = c.workDir
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to fetch: %w\n%s", err, output)
@@ -235,11 +373,11 @@ func (c *Client) DetectGitHubRepo() (owner, repo string, err error) {
 	return "", "", fmt.Errorf("could not parse GitHub URL from: %s", url)
 }
 
-// GenerateBranchName generates a unique branch name for an iteration.
-func (c *Client) GenerateBranchName(prefix string, iteration int) string {
-	date := time.Now().Format("2006-01-02")
+// GenerateBranchName generates a unique branch name for an iteration of a
+// run, grouping the run's branches under its ID.
+func (c *Client) GenerateBranchName(prefix, runID string, iteration int) string {
 	hash := generateShortHash()
-	return fmt.Sprintf("%siteration-%d/%s-%s", prefix, iteration, date, hash)
+	return fmt.Sprintf("%s%s/iteration-%d-%s", prefix, runID, iteration, hash)
 }
 
 // GetDiff returns the diff of staged changes.
@@ -253,6 +391,86 @@ func (c *Client) GetDiff() (string, error) {
 	return string(output), nil
 }
 
+// DiffStat summarizes the size of a change.
+type DiffStat struct {
+	FilesChanged int
+	Insertions   int
+	Deletions    int
+}
+
+// IsEmpty reports whether the change touches no files.
+func (d DiffStat) IsEmpty() bool {
+	return d.FilesChanged == 0
+}
+
+// String formats the stat like git's --shortstat.
+func (d DiffStat) String() string {
+	return fmt.Sprintf("%d files changed, %d insertions(+), %d deletions(-)", d.FilesChanged, d.Insertions, d.Deletions)
+}
+
+// Add returns the sum of two stats.
+func (d DiffStat) Add(other DiffStat) DiffStat {
+	return DiffStat{
+		FilesChanged: d.FilesChanged + other.FilesChanged,
+		Insertions:   d.Insertions + other.Insertions,
+		Deletions:    d.Deletions + other.Deletions,
+	}
+}
+
+// GetStagedShortStat returns files changed, insertions and deletions of staged changes.
+func (c *Client) GetStagedShortStat() (DiffStat, error) {
+	cmd := exec.Command("git", "diff", "--staged", "--shortstat")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return DiffStat{}, fmt.Errorf("failed to get diff stat: %w", err)
+	}
+	return parseShortStat(string(output)), nil
+}
+
+// parseShortStat parses output like " 3 files changed, 10 insertions(+), 2 deletions(-)".
+func parseShortStat(output string) DiffStat {
+	var stat DiffStat
+	re := regexp.MustCompile(`(\d+) (files? changed|insertions?\(\+\)|deletions?\(-\))`)
+	for _, m := range re.FindAllStringSubmatch(output, -1) {
+		n, _ := strconv.Atoi(m[1])
+		switch {
+		case strings.HasPrefix(m[2], "file"):
+			stat.FilesChanged = n
+		case strings.HasPrefix(m[2], "insertion"):
+			stat.Insertions = n
+		case strings.HasPrefix(m[2], "deletion"):
+			stat.Deletions = n
+		}
+	}
+	return stat
+}
+
+// GetStagedDiffStat returns the diffstat summary of staged changes.
+func (c *Client) GetStagedDiffStat() (string, error) {
+	cmd := exec.Command("git", "diff", "--staged", "--stat")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff stat: %w", err)
+	}
+	return string(output), nil
+}
+
+// HasStagedChanges checks if there are changes staged for commit.
+func (c *Client) HasStagedChanges() (bool, error) {
+	cmd := exec.Command("git", "diff", "--staged", "--quiet")
+	cmd.Dir = c.workDir
+	err := cmd.Run()
+	if err == nil {
+		return false, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, fmt.Errorf("failed to check staged changes: %w", err)
+}
+
 // GetStatus returns the git status.
 func (c *Client) GetStatus() (string, error) {
 	cmd := exec.Command("git", "status")
@@ -286,6 +504,66 @@ func (c *Client) GetLastCommitTitle() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// HeadSHA returns the commit SHA of HEAD.
+func (c *Client) HeadSHA() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// FormatPatch returns the commits on HEAD since base as an mbox-style
+// patch that "git am" applies.
+func (c *Client) FormatPatch(base string) (string, error) {
+	output, err := c.Run("format-patch", "--stdout", base+"..HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to format patch: %w", err)
+	}
+	return output, nil
+}
+
+// UndoLastCommit removes the last commit while keeping its changes staged.
+func (c *Client) UndoLastCommit() error {
+	cmd := exec.Command("git", "reset", "--soft", "HEAD~1")
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to undo last commit: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// ListFiles returns the paths of all tracked files.
+func (c *Client) ListFiles() ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "-z")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	var files []string
+	for _, f := range strings.Split(string(output), "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// GitDir returns the absolute path of the repository's .git directory.
+func (c *Client) GitDir() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--absolute-git-dir")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get git directory: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 // WorktreeAdd creates a new worktree.
 func (c *Client) WorktreeAdd(path, branch string) error {
 	cmd := exec.Command("git", "worktree", "add", path, branch)
@@ -296,6 +574,17 @@ func (c *Client) WorktreeAdd(path, branch string) error {
 	return nil
 }
 
+// WorktreeAddBranch creates a new worktree on branch, resetting the branch
+// to startPoint.
+func (c *Client) WorktreeAddBranch(path, branch, startPoint string) error {
+	cmd := exec.Command("git", "worktree", "add", "-B", branch, path, startPoint)
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create worktree: %w\n%s", err, output)
+	}
+	return nil
+}
+
 // WorktreeRemove removes a worktree.
 func (c *Client) WorktreeRemove(path string) error {
 	cmd := exec.Command("git", "worktree", "remove", path, "--force")
@@ -344,7 +633,8 @@ func generateShortHash() string {
 	return hex.EncodeToString(b)
 }
 
-func isNetworkError(err error) bool {
+// IsNetworkError reports whether a git error was caused by lost connectivity.
+func IsNetworkError(err error) bool {
 	if err == nil {
 		return false
 	}
@@ -0,0 +1,79 @@
+package git
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseShortStat(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected DiffStat
+	}{
+		{" 3 files changed, 10 insertions(+), 2 deletions(-)\n", DiffStat{3, 10, 2}},
+		{" 1 file changed, 1 insertion(+)\n", DiffStat{1, 1, 0}},
+		{" 2 files changed, 5 deletions(-)\n", DiffStat{2, 0, 5}},
+		{"", DiffStat{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			if result := parseShortStat(tt.output); result != tt.expected {
+				t.Errorf("parseShortStat(%q) = %+v, want %+v", tt.output, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseRemoteHeads(t *testing.T) {
+	output := `1a2b3c	refs/heads/main
+4d5e6f	refs/heads/deep-claude/20250115-143000-ab12/iteration-1-be939873
+7a8b9c	refs/heads/deep-claude/backport-40-to-release-1.2
+0d1e2f	refs/heads/feature/deep-claude
+`
+	got := parseRemoteHeads(output, "deep-claude/")
+	want := []string{
+		"deep-claude/20250115-143000-ab12/iteration-1-be939873",
+		"deep-claude/backport-40-to-release-1.2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseRemoteHeads() = %v, want %v", got, want)
+	}
+
+	if got := parseRemoteHeads("", "deep-claude/"); got != nil {
+		t.Errorf("parseRemoteHeads(\"\") = %v, want nil", got)
+	}
+}
+
+func TestPatchID(t *testing.T) {
+	diff := func(line int) string {
+		return fmt.Sprintf(`diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -%d,3 +%d,4 @@ func main() {
+ 	a := 1
+ 	b := 2
++	c := 3
+ 	fmt.Println(a, b)
+`, line, line)
+	}
+
+	c := NewClient(t.TempDir())
+	first, err := c.PatchID(diff(10))
+	if err != nil {
+		t.Fatalf("PatchID() unexpected error: %v", err)
+	}
+	moved, err := c.PatchID(diff(42))
+	if err != nil {
+		t.Fatalf("PatchID() unexpected error: %v", err)
+	}
+	if first == "" || first != moved {
+		t.Errorf("PatchID() = %q and %q, want the same ID for equivalent diffs", first, moved)
+	}
+
+	if id, err := c.PatchID(""); err != nil || id != "" {
+		t.Errorf("PatchID(\"\") = %q, %v; want empty", id, err)
+	}
+}
@@ -0,0 +1,27 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Rebase replays the current branch's commits onto onto. When they
+// conflict with it, the rebase is abandoned, leaving the branch as it was,
+// and the conflicted paths are returned.
+func (c *Client) Rebase(onto string) ([]string, error) {
+	cmd := exec.Command("git", "rebase", onto)
+	cmd.Dir = c.workDir
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil, nil
+	}
+
+	conflicts, cerr := c.ConflictedFiles()
+	abort := exec.Command("git", "rebase", "--abort")
+	abort.Dir = c.workDir
+	_ = abort.Run()
+	if cerr != nil || len(conflicts) == 0 {
+		return nil, fmt.Errorf("failed to rebase onto %s: %w\n%s", onto, err, output)
+	}
+	return conflicts, nil
+}
@@ -0,0 +1,78 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRebase(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	c := NewClient(dir)
+	if err := c.InitRepo(); err != nil {
+		t.Fatal(err)
+	}
+	commit := func(name, content, message string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := exec.Command("git", "-C", dir, "add", name).CombinedOutput(); err != nil {
+			t.Fatalf("git add: %v\n%s", err, out)
+		}
+		if err := c.Commit(message); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit("a.txt", "a\n", "Initial commit")
+	base, err := c.CurrentBranch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateBranch("work"); err != nil {
+		t.Fatal(err)
+	}
+	commit("b.txt", "b\n", "Add b")
+	if err := c.SwitchBranch(base); err != nil {
+		t.Fatal(err)
+	}
+	commit("c.txt", "c\n", "Add c")
+	commit("a.txt", "a on base\n", "Change a")
+	if err := c.SwitchBranch("work"); err != nil {
+		t.Fatal(err)
+	}
+
+	conflicts, err := c.Rebase(base)
+	if err != nil || conflicts != nil {
+		t.Fatalf("Rebase() = %v, %v; want it clean", conflicts, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "c.txt")); err != nil {
+		t.Errorf("rebased branch lacks the base's files: %v", err)
+	}
+
+	commit("a.txt", "a on work\n", "Change a too")
+	head, _ := c.HeadSHA()
+	if err := c.SwitchBranch(base); err != nil {
+		t.Fatal(err)
+	}
+	commit("a.txt", "a on base again\n", "Change a again")
+	if err := c.SwitchBranch("work"); err != nil {
+		t.Fatal(err)
+	}
+	conflicts, err = c.Rebase(base)
+	if err != nil || !reflect.DeepEqual(conflicts, []string{"a.txt"}) {
+		t.Fatalf("Rebase() = %v, %v; want a conflict in a.txt", conflicts, err)
+	}
+	if got, _ := c.HeadSHA(); got != head {
+		t.Errorf("after a conflicted Rebase(), HEAD = %s, want it left at %s", got, head)
+	}
+	if changed, _ := c.HasChanges(nil); changed {
+		t.Error("a conflicted Rebase() left changes behind")
+	}
+}
@@ -0,0 +1,44 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CommitTitlesSince returns the titles of the commits on the current
+// branch after ref, oldest first.
+func (c *Client) CommitTitlesSince(ref string) ([]string, error) {
+	cmd := exec.Command("git", "log", "--reverse", "--format=%s", ref+"..HEAD")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits since %s: %w", ref, err)
+	}
+	var titles []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			titles = append(titles, line)
+		}
+	}
+	return titles, nil
+}
+
+// SquashSince replaces the commits on the current branch after ref with
+// one commit of their combined changes, without an interactive rebase:
+// the branch is reset softly to ref and the result committed. If the
+// commit fails, the branch is put back where it was.
+func (c *Client) SquashSince(ref, message string) error {
+	cmd := exec.Command("git", "reset", "--soft", ref)
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to squash commits since %s: %w\n%s", ref, err, output)
+	}
+	if err := c.Commit(message); err != nil {
+		restore := exec.Command("git", "reset", "--soft", "ORIG_HEAD")
+		restore.Dir = c.workDir
+		_ = restore.Run()
+		return fmt.Errorf("failed to squash commits since %s: %w", ref, err)
+	}
+	return nil
+}
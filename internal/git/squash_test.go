@@ -0,0 +1,59 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSquashSince(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	c := NewClient(dir)
+	if err := c.InitRepo(); err != nil {
+		t.Fatal(err)
+	}
+	commit := func(name, message string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := exec.Command("git", "-C", dir, "add", name).CombinedOutput(); err != nil {
+			t.Fatalf("git add: %v\n%s", err, out)
+		}
+		if err := c.Commit(message); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit("a.txt", "Initial commit")
+	base, err := c.HeadSHA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit("b.txt", "Add b")
+	commit("c.txt", "Add c")
+
+	titles, err := c.CommitTitlesSince(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Add b", "Add c"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("CommitTitlesSince() = %v, want %v", titles, want)
+	}
+
+	if err := c.SquashSince(base, "Add b and c"); err != nil {
+		t.Fatal(err)
+	}
+	titles, _ = c.CommitTitlesSince(base)
+	if want := []string{"Add b and c"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("after SquashSince(), commits = %v, want %v", titles, want)
+	}
+	if files, _ := c.ChangedFilesSince(base); !reflect.DeepEqual(files, []string{"b.txt", "c.txt"}) {
+		t.Errorf("squashed commit changed %v, want b.txt and c.txt", files)
+	}
+}
@@ -0,0 +1,187 @@
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Snapshot maps each dirty or untracked path to a fingerprint of its content.
+type Snapshot map[string]string
+
+// deletedFingerprint marks a path that is missing from the working tree.
+const deletedFingerprint = "deleted"
+
+// TakeSnapshot records the current content of every path git reports as
+// modified, deleted or untracked, so later changes can be attributed.
+func (c *Client) TakeSnapshot() (Snapshot, error) {
+	paths, err := c.dirtyPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	snap := make(Snapshot, len(paths))
+	for _, p := range paths {
+		snap[p] = fingerprint(filepath.Join(c.workDir, p))
+	}
+	return snap, nil
+}
+
+// ChangedSince returns the paths whose content differs from the snapshot,
+// i.e. the files modified after the snapshot was taken.
+func (c *Client) ChangedSince(before Snapshot) ([]string, error) {
+	after, err := c.TakeSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for p, fp := range after {
+		if prev, ok := before[p]; !ok || prev != fp {
+			changed = append(changed, p)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// ChangedFilesSince returns the paths that differ between ref and the
+// working tree, including uncommitted and untracked files.
+func (c *Client) ChangedFilesSince(ref string) ([]string, error) {
+	cmd := exec.Command("git", "diff", "--name-only", "-z", ref)
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff against %s: %w", ref, err)
+	}
+	dirty, err := c.dirtyPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	for _, p := range append(strings.Split(string(output), "\x00"), dirty...) {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// StagePaths stages the given paths, including deletions, skipping any
+// path matching one of the exclude patterns.
+func (c *Client) StagePaths(paths, excludes []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	args := append([]string{"add", "-A", "--"}, paths...)
+	args = append(args, ExcludePathspecs(excludes)...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to stage changes: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// StageAllExcept stages all changes except paths matching the exclude patterns.
+// Files ignored by .gitignore are never staged.
+func (c *Client) StageAllExcept(excludes []string) error {
+	args := append([]string{"add", "-A", "--", "."}, ExcludePathspecs(excludes)...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = c.workDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to stage changes: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// FilterPaths returns the paths that are one of dirs or inside one of
+// them. All paths are relative to the repository root.
+func FilterPaths(paths, dirs []string) []string {
+	var kept []string
+	for _, p := range paths {
+		for _, d := range dirs {
+			d = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(d)), "/")
+			if d == "." || p == d || strings.HasPrefix(p, d+"/") {
+				kept = append(kept, p)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// ExcludePathspecs converts gitignore-style patterns into git exclude pathspecs.
+// Patterns without a slash match at any depth, like in .gitignore.
+func ExcludePathspecs(patterns []string) []string {
+	var specs []string
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		p = strings.TrimPrefix(p, "/")
+		if !strings.Contains(strings.TrimSuffix(p, "/"), "/") && !strings.HasPrefix(p, "**") {
+			p = "**/" + p
+		}
+		if strings.HasSuffix(p, "/") {
+			p += "**"
+		}
+		specs = append(specs, ":(exclude,glob)"+p)
+	}
+	return specs
+}
+
+// dirtyPaths lists modified, deleted and untracked (non-ignored) paths.
+func (c *Client) dirtyPaths() ([]string, error) {
+	cmd := exec.Command("git", "status", "--porcelain", "-z", "--untracked-files=all")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check git status: %w", err)
+	}
+	return parsePorcelainZ(string(output)), nil
+}
+
+// parsePorcelainZ extracts paths from `git status --porcelain -z` output.
+// Renames and copies are followed by their source path, which is skipped.
+func parsePorcelainZ(output string) []string {
+	var paths []string
+	entries := strings.Split(output, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		paths = append(paths, entry[3:])
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+		}
+	}
+	return paths
+}
+
+func fingerprint(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return deletedFingerprint
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return deletedFingerprint
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
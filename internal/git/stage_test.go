@@ -0,0 +1,79 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePorcelainZ(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []string
+	}{
+		{"empty", "", nil},
+		{"modified and untracked", " M main.go\x00?? new file.txt\x00", []string{"main.go", "new file.txt"}},
+		{"rename skips source", "R  new.go\x00old.go\x00 D gone.go\x00", []string{"new.go", "gone.go"}},
+		{"staged added", "A  pkg/a.go\x00", []string{"pkg/a.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parsePorcelainZ(tt.output)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parsePorcelainZ(%q) = %v, want %v", tt.output, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExcludePathspecs(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"*.log", ":(exclude,glob)**/*.log"},
+		{"tmp/**", ":(exclude,glob)tmp/**"},
+		{"/build/out.bin", ":(exclude,glob)build/out.bin"},
+		{"node_modules/", ":(exclude,glob)**/node_modules/**"},
+		{"**/*.tmp", ":(exclude,glob)**/*.tmp"},
+		{"SHARED_TASK_NOTES.md", ":(exclude,glob)**/SHARED_TASK_NOTES.md"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			result := ExcludePathspecs([]string{tt.pattern})
+			if len(result) != 1 || result[0] != tt.expected {
+				t.Errorf("ExcludePathspecs(%q) = %v, want [%s]", tt.pattern, result, tt.expected)
+			}
+		})
+	}
+
+	if result := ExcludePathspecs([]string{"", "  "}); len(result) != 0 {
+		t.Errorf("ExcludePathspecs() should skip blank patterns, got %v", result)
+	}
+}
+
+func TestFilterPaths(t *testing.T) {
+	paths := []string{"packages/api/main.go", "packages/api-client/index.ts", "packages/web/app.ts", "go.mod", "SHARED_TASK_NOTES.md"}
+
+	tests := []struct {
+		name     string
+		dirs     []string
+		expected []string
+	}{
+		{"one project", []string{"packages/api"}, []string{"packages/api/main.go"}},
+		{"trailing slash", []string{"packages/api/"}, []string{"packages/api/main.go"}},
+		{"two projects and a file", []string{"packages/api", "packages/web", "SHARED_TASK_NOTES.md"}, []string{"packages/api/main.go", "packages/web/app.ts", "SHARED_TASK_NOTES.md"}},
+		{"root", []string{"."}, paths},
+		{"no match", []string{"services"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := FilterPaths(paths, tt.dirs); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("FilterPaths(%v) = %v, want %v", tt.dirs, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// maxAnnotations is the most annotations the Checks API takes per request.
+const maxAnnotations = 50
+
+// CheckRun is a completed check run to publish on a commit.
+type CheckRun struct {
+	Name    string
+	HeadSHA string
+	// Conclusion is success, neutral or failure
+	Conclusion  string
+	Title       string
+	Summary     string
+	Annotations []Annotation
+}
+
+// Annotation points a check run's finding at a file.
+type Annotation struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	// Level is notice, warning or failure
+	Level   string `json:"annotation_level"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+}
+
+// CreateCheckRun publishes a completed check run and returns its URL.
+// The Checks API only takes check runs from GitHub Apps, so gh must be
+// authenticated with an app installation token.
+func (c *Client) CreateCheckRun(ctx context.Context, run CheckRun) (string, error) {
+	input, err := json.Marshal(checkRunRequest(run))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode check run: %w", err)
+	}
+	path := fmt.Sprintf("repos/%s/%s/check-runs", c.owner, c.repo)
+	output, err := c.combinedOutput(ctx, string(input), "api", path, "--method", "POST", "--input", "-", "--jq", ".html_url")
+	if err != nil {
+		return "", fmt.Errorf("failed to create check run: %w\n%s", err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// checkRunRequest is the API request body for a check run, with at most
+// maxAnnotations annotations.
+func checkRunRequest(run CheckRun) map[string]any {
+	annotations := run.Annotations
+	if len(annotations) > maxAnnotations {
+		annotations = annotations[:maxAnnotations]
+	}
+	if annotations == nil {
+		annotations = []Annotation{}
+	}
+	return map[string]any{
+		"name":       run.Name,
+		"head_sha":   run.HeadSHA,
+		"status":     "completed",
+		"conclusion": run.Conclusion,
+		"output": map[string]any{
+			"title":       run.Title,
+			"summary":     run.Summary,
+			"annotations": annotations,
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package github
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCheckRunRequest(t *testing.T) {
+	run := CheckRun{Name: "deep-claude", HeadSHA: "abc123", Conclusion: "neutral", Title: "1 of 2 gates passed", Summary: "details"}
+	for range 60 {
+		run.Annotations = append(run.Annotations, Annotation{Path: "main.go", StartLine: 1, EndLine: 1, Level: "warning", Message: "outside --path"})
+	}
+
+	body, err := json.Marshal(checkRunRequest(run))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Status string `json:"status"`
+		Output struct {
+			Annotations []Annotation `json:"annotations"`
+		} `json:"output"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "completed" || len(got.Output.Annotations) != maxAnnotations {
+		t.Errorf("request has status %q and %d annotations, want completed and %d", got.Status, len(got.Output.Annotations), maxAnnotations)
+	}
+	if !strings.Contains(string(body), `"annotation_level":"warning"`) {
+		t.Errorf("annotations should use the API's field names: %s", body)
+	}
+
+	run.Annotations = nil
+	body, _ = json.Marshal(checkRunRequest(run))
+	if !strings.Contains(string(body), `"annotations":[]`) {
+		t.Errorf("a run without annotations should send an empty list: %s", body)
+	}
+}
@@ -0,0 +1,80 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SetCheckScope limits the checks that decide whether a PR can be merged
+// to those whose name mentions one of names, such as the projects of a
+// monorepo the run works on.
+func (c *Client) SetCheckScope(names []string) {
+	c.scope = names
+}
+
+// FilterChecks returns the checks whose name contains one of names,
+// ignoring case. If names is empty or no check matches, all checks are
+// returned, so a PR is never merged without the checks that did run.
+func FilterChecks(checks []PRCheck, names []string) []PRCheck {
+	if len(names) == 0 {
+		return checks
+	}
+
+	var kept []PRCheck
+	for _, check := range checks {
+		name := strings.ToLower(check.Name)
+		for _, n := range names {
+			if n != "" && strings.Contains(name, strings.ToLower(n)) {
+				kept = append(kept, check)
+				break
+			}
+		}
+	}
+	if len(kept) == 0 {
+		return checks
+	}
+	return kept
+}
+
+// summarizeChecks reports whether any of checks are pending or failed.
+func summarizeChecks(checks []PRCheck) *PRStatus {
+	status := &PRStatus{Checks: checks}
+	for _, check := range checks {
+		switch check.State {
+		case "SUCCESS", "NEUTRAL", "SKIPPED":
+			// OK
+		case "PENDING", "QUEUED", "IN_PROGRESS":
+			status.HasPendingChecks = true
+		case "FAILURE", "ERROR", "CANCELLED", "TIMED_OUT", "ACTION_REQUIRED":
+			status.HasFailedChecks = true
+		}
+	}
+	status.AllChecksPassed = len(checks) == 0 || (!status.HasPendingChecks && !status.HasFailedChecks)
+	return status
+}
+
+// parseCheckRuns reads the check runs gh api prints one JSON object per
+// line: the conclusion of completed runs, the status of the others.
+func parseCheckRuns(output []byte) ([]PRCheck, error) {
+	checks := []PRCheck{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var run struct {
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+		}
+		if err := json.Unmarshal([]byte(line), &run); err != nil {
+			return nil, fmt.Errorf("failed to parse check runs: %w", err)
+		}
+		state := strings.ToUpper(run.Status)
+		if run.Status == "completed" {
+			state = strings.ToUpper(run.Conclusion)
+		}
+		checks = append(checks, PRCheck{Name: run.Name, State: state})
+	}
+	return checks, nil
+}
@@ -0,0 +1,56 @@
+package github
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterChecks(t *testing.T) {
+	checks := []PRCheck{
+		{Name: "api / test", State: "SUCCESS"},
+		{Name: "web / test", State: "FAILURE"},
+		{Name: "API lint", State: "SUCCESS"},
+	}
+
+	tests := []struct {
+		name     string
+		names    []string
+		expected []PRCheck
+	}{
+		{"no scope", nil, checks},
+		{"one project", []string{"api"}, []PRCheck{checks[0], checks[2]}},
+		{"two projects", []string{"web", "api"}, checks},
+		{"no match keeps all", []string{"billing"}, checks},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := FilterChecks(checks, tt.names); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("FilterChecks(%v) = %v, want %v", tt.names, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseCheckRuns(t *testing.T) {
+	output := `{"name":"test","status":"completed","conclusion":"failure"}
+{"name":"lint","status":"completed","conclusion":"success"}
+{"name":"deploy","status":"in_progress","conclusion":null}
+`
+	checks, err := parseCheckRuns([]byte(output))
+	if err != nil {
+		t.Fatalf("parseCheckRuns() unexpected error: %v", err)
+	}
+	expected := []PRCheck{{Name: "test", State: "FAILURE"}, {Name: "lint", State: "SUCCESS"}, {Name: "deploy", State: "IN_PROGRESS"}}
+	if !reflect.DeepEqual(checks, expected) {
+		t.Errorf("parseCheckRuns() = %v, want %v", checks, expected)
+	}
+
+	status := summarizeChecks(checks)
+	if !status.HasFailedChecks || !status.HasPendingChecks || status.AllChecksPassed {
+		t.Errorf("summarizeChecks() = %+v, want failed and pending", status)
+	}
+	if status := summarizeChecks(nil); !status.AllChecksPassed {
+		t.Error("no checks should pass")
+	}
+}
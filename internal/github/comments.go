@@ -0,0 +1,55 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Comment is a comment on an issue or pull request.
+type Comment struct {
+	ID     int64  `json:"id"`
+	Author string `json:"author"`
+	// Association is the author's relation to the repository, such as
+	// OWNER, MEMBER, COLLABORATOR or NONE
+	Association string    `json:"association"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CanWrite reports whether the comment's author can push to the repository.
+func (c Comment) CanWrite() bool {
+	switch c.Association {
+	case "OWNER", "MEMBER", "COLLABORATOR":
+		return true
+	}
+	return false
+}
+
+// ListComments returns the comments on an issue or pull request, oldest first.
+func (c *Client) ListComments(ctx context.Context, number string) ([]Comment, error) {
+	path := fmt.Sprintf("repos/%s/%s/issues/%s/comments", c.owner, c.repo, number)
+	output, err := c.output(ctx, "api", path, "--paginate", "--jq", ".[] | {id, author: .user.login, association: .author_association, body, created_at}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments on #%s: %w", number, err)
+	}
+	return parseComments(output)
+}
+
+// parseComments reads the comments gh api prints one JSON object per line.
+func parseComments(output []byte) ([]Comment, error) {
+	var comments []Comment
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var comment Comment
+		if err := json.Unmarshal([]byte(line), &comment); err != nil {
+			return nil, fmt.Errorf("failed to parse comments: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
@@ -0,0 +1,22 @@
+package github
+
+import "testing"
+
+func TestParseComments(t *testing.T) {
+	output := `{"id":11,"author":"alice","association":"MEMBER","body":"/deep-claude fix use a map","created_at":"2026-10-01T12:00:00Z"}
+{"id":12,"author":"mallory","association":"NONE","body":"/deep-claude close","created_at":"2026-10-01T12:05:00Z"}
+`
+	comments, err := parseComments([]byte(output))
+	if err != nil {
+		t.Fatalf("parseComments() unexpected error: %v", err)
+	}
+	if len(comments) != 2 || comments[0].ID != 11 || comments[0].Author != "alice" || comments[1].Body != "/deep-claude close" {
+		t.Fatalf("parseComments() = %+v", comments)
+	}
+	if !comments[0].CanWrite() || comments[1].CanWrite() {
+		t.Error("only members, owners and collaborators can write")
+	}
+	if comments, err := parseComments(nil); err != nil || len(comments) != 0 {
+		t.Errorf("parseComments(nil) = %v, %v", comments, err)
+	}
+}
@@ -2,11 +2,14 @@
 package github
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
+	"net/url"
 	"strings"
 	"time"
+
+	"github.com/guzus/deep-claude/internal/retry"
 )
 
 // Client handles GitHub operations via the gh CLI.
@@ -14,6 +17,9 @@ type Client struct {
 	owner   string
 	repo    string
 	workDir string
+	logger  Logger
+	scope   []string
+	policy  retry.Policy
 }
 
 // PRCheck represents a CI/CD check on a PR.
@@ -39,21 +45,30 @@ func NewClient(owner, repo, workDir string) *Client {
 		owner:   owner,
 		repo:    repo,
 		workDir: workDir,
+		policy:  retry.Defaults().GitHub,
 	}
 }
 
+// Owner returns the repository owner.
+func (c *Client) Owner() string {
+	return c.owner
+}
+
+// Repo returns the repository name.
+func (c *Client) Repo() string {
+	return c.repo
+}
+
 // CheckAuth verifies GitHub CLI authentication.
-func (c *Client) CheckAuth() error {
-	cmd := exec.Command("gh", "auth", "status")
-	cmd.Dir = c.workDir
-	if output, err := cmd.CombinedOutput(); err != nil {
+func (c *Client) CheckAuth(ctx context.Context) error {
+	if output, err := c.combinedOutput(ctx, "", "auth", "status"); err != nil {
 		return fmt.Errorf("GitHub CLI not authenticated: %w\n%s", err, output)
 	}
 	return nil
 }
 
 // CreateRepo creates a new GitHub repository from the working directory.
-func (c *Client) CreateRepo(name string, private bool, owner string) error {
+func (c *Client) CreateRepo(ctx context.Context, name string, private bool, owner string) error {
 	args := []string{"repo", "create", name, "--source", ".", "--confirm"}
 	if owner != "" {
 		args = append(args, "--owner", owner)
@@ -64,24 +79,33 @@ func (c *Client) CreateRepo(name string, private bool, owner string) error {
 		args = append(args, "--public")
 	}
 
-	cmd := exec.Command("gh", args...)
-	cmd.Dir = c.workDir
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := c.combinedOutput(ctx, "", args...); err != nil {
 		return fmt.Errorf("failed to create GitHub repository: %w\n%s", err, output)
 	}
 	return nil
 }
 
 // CreatePR creates a new pull request.
-func (c *Client) CreatePR(title, body, base string) (string, error) {
+func (c *Client) CreatePR(ctx context.Context, title, body, base string) (string, error) {
+	return c.createPR(ctx, title, body, base, false)
+}
+
+// CreateDraftPR creates a new pull request as a draft, which can't be
+// merged until someone marks it ready for review.
+func (c *Client) CreateDraftPR(ctx context.Context, title, body, base string) (string, error) {
+	return c.createPR(ctx, title, body, base, true)
+}
+
+func (c *Client) createPR(ctx context.Context, title, body, base string, draft bool) (string, error) {
 	args := []string{"pr", "create", "--title", title, "--body", body}
 	if base != "" {
 		args = append(args, "--base", base)
 	}
+	if draft {
+		args = append(args, "--draft")
+	}
 
-	cmd := exec.Command("gh", args...)
-	cmd.Dir = c.workDir
-	output, err := cmd.CombinedOutput()
+	output, err := c.combinedOutput(ctx, "", args...)
 	if err != nil {
 		return "", fmt.Errorf("failed to create PR: %w\n%s", err, output)
 	}
@@ -102,10 +126,8 @@ func GetPRNumber(prURL string) string {
 
 // GetPRChecks returns the CI/CD checks for a PR.
 // Uses 'gh pr view --json statusCheckRollup' for broader gh CLI compatibility.
-func (c *Client) GetPRChecks(prNumber string) ([]PRCheck, error) {
-	cmd := exec.Command("gh", "pr", "view", prNumber, "--json", "statusCheckRollup")
-	cmd.Dir = c.workDir
-	output, err := cmd.CombinedOutput()
+func (c *Client) GetPRChecks(ctx context.Context, prNumber string) ([]PRCheck, error) {
+	output, err := c.combinedOutput(ctx, "", "pr", "view", prNumber, "--json", "statusCheckRollup")
 	if err != nil {
 		outputStr := string(output)
 		// If no checks configured, return empty list
@@ -160,10 +182,8 @@ func (c *Client) GetPRChecks(prNumber string) ([]PRCheck, error) {
 }
 
 // GetPRReviewDecision returns the review decision for a PR.
-func (c *Client) GetPRReviewDecision(prNumber string) (string, error) {
-	cmd := exec.Command("gh", "pr", "view", prNumber, "--json", "reviewDecision")
-	cmd.Dir = c.workDir
-	output, err := cmd.Output()
+func (c *Client) GetPRReviewDecision(ctx context.Context, prNumber string) (string, error) {
+	output, err := c.output(ctx, "pr", "view", prNumber, "--json", "reviewDecision")
 	if err != nil {
 		return "", fmt.Errorf("failed to get PR review status: %w", err)
 	}
@@ -179,103 +199,107 @@ func (c *Client) GetPRReviewDecision(prNumber string) (string, error) {
 }
 
 // GetPRStatus returns the full status of a PR.
-func (c *Client) GetPRStatus(prNumber string) (*PRStatus, error) {
-	checks, err := c.GetPRChecks(prNumber)
+func (c *Client) GetPRStatus(ctx context.Context, prNumber string) (*PRStatus, error) {
+	checks, err := c.GetPRChecks(ctx, prNumber)
 	if err != nil {
 		return nil, err
 	}
+	checks = FilterChecks(withoutOwnStatuses(checks), c.scope)
 
-	reviewDecision, err := c.GetPRReviewDecision(prNumber)
+	reviewDecision, err := c.GetPRReviewDecision(ctx, prNumber)
 	if err != nil {
 		return nil, err
 	}
 
-	status := &PRStatus{
-		Checks:         checks,
-		ReviewDecision: reviewDecision,
-	}
-
-	// Analyze checks
-	for _, check := range checks {
-		switch check.State {
-		case "SUCCESS", "NEUTRAL", "SKIPPED":
-			// OK
-		case "PENDING", "QUEUED", "IN_PROGRESS":
-			status.HasPendingChecks = true
-		case "FAILURE", "ERROR", "CANCELLED", "TIMED_OUT", "ACTION_REQUIRED":
-			status.HasFailedChecks = true
-		}
-	}
-
-	status.AllChecksPassed = len(checks) == 0 || (!status.HasPendingChecks && !status.HasFailedChecks)
+	status := summarizeChecks(checks)
+	status.ReviewDecision = reviewDecision
 	status.IsMergeable = status.AllChecksPassed &&
 		(reviewDecision == "" || reviewDecision == "APPROVED")
 
 	return status, nil
 }
 
-// WaitForChecks polls the PR checks until they complete or timeout.
-func (c *Client) WaitForChecks(prNumber string, timeout time.Duration, onStatusChange func(*PRStatus)) (*PRStatus, error) {
-	deadline := time.Now().Add(timeout)
-	pollInterval := 10 * time.Second
-	var lastStatus *PRStatus
+// GetCommitStatus returns the status of the check runs on a commit, such
+// as the merge commit of a PR on the base branch. Reviews don't apply, so
+// only the check fields are set.
+func (c *Client) GetCommitStatus(ctx context.Context, sha string) (*PRStatus, error) {
+	path := fmt.Sprintf("repos/%s/%s/commits/%s/check-runs", c.owner, c.repo, sha)
+	output, err := c.output(ctx, "api", path, "--paginate", "--jq", ".check_runs[] | {name, status, conclusion}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checks of %s: %w", sha, err)
+	}
+	checks, err := parseCheckRuns(output)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeChecks(FilterChecks(checks, c.scope)), nil
+}
 
-	for time.Now().Before(deadline) {
-		status, err := c.GetPRStatus(prNumber)
-		if err != nil {
-			return nil, err
-		}
+// GetDeploymentState returns the state of the latest deployment of a
+// commit to an environment, such as "success", "failure" or "in_progress",
+// or "" if the commit hasn't been deployed there.
+func (c *Client) GetDeploymentState(ctx context.Context, sha, environment string) (string, error) {
+	query := url.Values{"sha": {sha}, "environment": {environment}}
+	path := fmt.Sprintf("repos/%s/%s/deployments?%s", c.owner, c.repo, query.Encode())
+	output, err := c.output(ctx, "api", path, "--jq", ".[0].id // empty")
+	if err != nil {
+		return "", fmt.Errorf("failed to get deployments of %s: %w", sha, err)
+	}
+	id := strings.TrimSpace(string(output))
+	if id == "" {
+		return "", nil
+	}
 
-		// Notify on status change
-		if onStatusChange != nil && hasStatusChanged(lastStatus, status) {
-			onStatusChange(status)
-		}
-		lastStatus = status
+	path = fmt.Sprintf("repos/%s/%s/deployments/%s/statuses", c.owner, c.repo, id)
+	output, err = c.output(ctx, "api", path, "--jq", `.[0].state // "pending"`)
+	if err != nil {
+		return "", fmt.Errorf("failed to get status of deployment %s: %w", id, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// WaitForChecks polls the PR checks until they complete, timeout passes or
+// ctx is canceled, calling onStatusChange whenever their status changes.
+func (c *Client) WaitForChecks(ctx context.Context, prNumber string, timeout time.Duration, onStatusChange func(*PRStatus)) (*PRStatus, error) {
+	t := NewTracker(ctx, c, 10*time.Second)
+	defer t.Close()
 
-		// Check if we can return
-		if status.HasFailedChecks {
-			return status, nil
+	t.Watch(prNumber, timeout)
+	for u := range t.Updates() {
+		if onStatusChange != nil && u.Status != nil && u.Err == nil {
+			onStatusChange(u.Status)
 		}
-		if status.AllChecksPassed {
-			return status, nil
+		if u.Done {
+			return u.Status, u.Err
 		}
-
-		time.Sleep(pollInterval)
 	}
-
-	return lastStatus, fmt.Errorf("timeout waiting for PR checks after %s", timeout)
+	return nil, fmt.Errorf("stopped waiting for PR checks")
 }
 
 // MergePR merges the PR with the given strategy.
-func (c *Client) MergePR(prNumber, strategy string) error {
+func (c *Client) MergePR(ctx context.Context, prNumber, strategy string) error {
 	args := []string{"pr", "merge", prNumber, "--" + strategy, "--delete-branch"}
-	cmd := exec.Command("gh", args...)
-	cmd.Dir = c.workDir
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := c.combinedOutput(ctx, "", args...); err != nil {
 		return fmt.Errorf("failed to merge PR: %w\n%s", err, output)
 	}
 	return nil
 }
 
 // ClosePR closes a PR without merging.
-func (c *Client) ClosePR(prNumber string, deleteBranch bool) error {
+func (c *Client) ClosePR(ctx context.Context, prNumber string, deleteBranch bool) error {
 	args := []string{"pr", "close", prNumber}
 	if deleteBranch {
 		args = append(args, "--delete-branch")
 	}
-	cmd := exec.Command("gh", args...)
-	cmd.Dir = c.workDir
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := c.combinedOutput(ctx, "", args...); err != nil {
 		return fmt.Errorf("failed to close PR: %w\n%s", err, output)
 	}
 	return nil
 }
 
 // UpdatePRBranch updates the PR branch with the base branch.
-func (c *Client) UpdatePRBranch(prNumber string) error {
-	cmd := exec.Command("gh", "pr", "update-branch", prNumber)
-	cmd.Dir = c.workDir
-	output, err := cmd.CombinedOutput()
+func (c *Client) UpdatePRBranch(ctx context.Context, prNumber string) error {
+	output, err := c.combinedOutput(ctx, "", "pr", "update-branch", prNumber)
 	if err != nil {
 		// If already up to date, that's fine
 		if strings.Contains(string(output), "already up to date") {
@@ -286,12 +310,148 @@ func (c *Client) UpdatePRBranch(prNumber string) error {
 	return nil
 }
 
-// GetLatestRelease returns the latest release version.
-func (c *Client) GetLatestRelease(owner, repo string) (string, error) {
-	cmd := exec.Command("gh", "release", "view", "--repo", fmt.Sprintf("%s/%s", owner, repo), "--json", "tagName")
-	output, err := cmd.Output()
+// PullRequest describes a PR's metadata.
+type PullRequest struct {
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+	URL         string `json:"url"`
+	BaseRefName string `json:"baseRefName"`
+	HeadRefName string `json:"headRefName"`
+	HeadRefOid  string `json:"headRefOid"`
+	State       string `json:"state"`
+	IsDraft     bool   `json:"isDraft"`
+	Author      struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	MergeCommit struct {
+		Oid string `json:"oid"`
+	} `json:"mergeCommit"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// GetPR returns the metadata of a pull request.
+func (c *Client) GetPR(ctx context.Context, prNumber string) (*PullRequest, error) {
+	output, err := c.output(ctx, "pr", "view", prNumber, "--json", "number,title,body,url,baseRefName,headRefName,state,isDraft,author,mergeCommit")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR #%s: %w", prNumber, err)
+	}
+
+	var pr PullRequest
+	if err := json.Unmarshal(output, &pr); err != nil {
+		return nil, fmt.Errorf("failed to parse PR: %w", err)
+	}
+	return &pr, nil
+}
+
+// ListPRs returns the number, state and head branch of up to limit of the
+// repository's most recent PRs, open or not.
+func (c *Client) ListPRs(ctx context.Context, limit int) ([]PullRequest, error) {
+	output, err := c.output(ctx, "pr", "list", "--state", "all", "--limit", fmt.Sprintf("%d", limit), "--json", "number,state,headRefName")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PRs: %w", err)
+	}
+
+	var prs []PullRequest
+	if err := json.Unmarshal(output, &prs); err != nil {
+		return nil, fmt.Errorf("failed to parse PRs: %w", err)
+	}
+	return prs, nil
+}
+
+// ListOpenPRs returns up to limit open PRs, most recent first, with their
+// branches and when they were last updated.
+func (c *Client) ListOpenPRs(ctx context.Context, limit int) ([]PullRequest, error) {
+	output, err := c.output(ctx, "pr", "list", "--state", "open", "--limit", fmt.Sprintf("%d", limit), "--json", "number,title,body,url,baseRefName,headRefName,headRefOid,updatedAt")
 	if err != nil {
-		return "", fmt.Errorf("failed to get latest release: %w", err)
+		return nil, fmt.Errorf("failed to list PRs: %w", err)
+	}
+
+	var prs []PullRequest
+	if err := json.Unmarshal(output, &prs); err != nil {
+		return nil, fmt.Errorf("failed to parse PRs: %w", err)
+	}
+	return prs, nil
+}
+
+// EditPR replaces the title and body of a PR.
+func (c *Client) EditPR(ctx context.Context, prNumber, title, body string) error {
+	if output, err := c.combinedOutput(ctx, body, "pr", "edit", prNumber, "--title", title, "--body-file", "-"); err != nil {
+		return fmt.Errorf("failed to edit PR #%s: %w\n%s", prNumber, err, output)
+	}
+	return nil
+}
+
+// AddLabel adds a label to a PR. The label must exist in the repository.
+func (c *Client) AddLabel(ctx context.Context, prNumber, label string) error {
+	if output, err := c.combinedOutput(ctx, "", "pr", "edit", prNumber, "--add-label", label); err != nil {
+		return fmt.Errorf("failed to label PR #%s: %w\n%s", prNumber, err, output)
+	}
+	return nil
+}
+
+// GetPRDiff returns the unified diff of a pull request.
+func (c *Client) GetPRDiff(ctx context.Context, prNumber string) (string, error) {
+	output, err := c.combinedOutput(ctx, "", "pr", "diff", prNumber)
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff of PR #%s: %w\n%s", prNumber, err, output)
+	}
+	return string(output), nil
+}
+
+// GetIssueState returns the state of an issue (OPEN or CLOSED).
+func (c *Client) GetIssueState(ctx context.Context, number string) (string, error) {
+	output, err := c.output(ctx, "issue", "view", number, "--json", "state")
+	if err != nil {
+		return "", fmt.Errorf("failed to get issue #%s: %w", number, err)
+	}
+
+	var result struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return "", fmt.Errorf("failed to parse issue state: %w", err)
+	}
+
+	return result.State, nil
+}
+
+// CreateGist creates a secret gist with a single file and returns its URL.
+func (c *Client) CreateGist(ctx context.Context, filename, description, content string) (string, error) {
+	output, err := c.combinedOutput(ctx, content, "gist", "create", "--filename", filename, "--desc", description, "-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create gist: %w\n%s", err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CommentOnIssue adds a comment to an issue or pull request and returns its URL.
+func (c *Client) CommentOnIssue(ctx context.Context, number, body string) (string, error) {
+	output, err := c.combinedOutput(ctx, body, "issue", "comment", number, "--body-file", "-")
+	if err != nil {
+		return "", fmt.Errorf("failed to comment on #%s: %w\n%s", number, err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CreateIssue opens an issue and returns its URL.
+func (c *Client) CreateIssue(ctx context.Context, title, body string) (string, error) {
+	output, err := c.combinedOutput(ctx, body, "issue", "create", "--title", title, "--body-file", "-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create issue: %w\n%s", err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetLatestRelease returns the latest release version, or "" if the
+// repository has no releases.
+func (c *Client) GetLatestRelease(ctx context.Context, owner, repo string) (string, error) {
+	output, stderr, err := c.run(ctx, "", "release", "view", "--repo", fmt.Sprintf("%s/%s", owner, repo), "--json", "tagName")
+	if err != nil {
+		if strings.Contains(string(stderr), "release not found") {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get latest release: %w: %s", err, strings.TrimSpace(string(stderr)))
 	}
 
 	var result struct {
@@ -304,6 +464,20 @@ func (c *Client) GetLatestRelease(owner, repo string) (string, error) {
 	return result.TagName, nil
 }
 
+// CreateRelease creates a tagged release at target with the given notes and returns its URL.
+func (c *Client) CreateRelease(ctx context.Context, tag, title, notes, target string) (string, error) {
+	args := []string{"release", "create", tag, "--title", title, "--notes", notes}
+	if target != "" {
+		args = append(args, "--target", target)
+	}
+
+	output, err := c.combinedOutput(ctx, "", args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to create release: %w\n%s", err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 func hasStatusChanged(old, new *PRStatus) bool {
 	if old == nil {
 		return true
@@ -0,0 +1,48 @@
+package github
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+// fakeGh puts a gh on PATH that prints stdout and stderr and exits with
+// code.
+func fakeGh(t *testing.T, stdout, stderr string, code int) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script standing in for gh")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\nprintf '%s' '" + stdout + "'\nprintf '%s' '" + stderr + "' >&2\nexit " + strconv.Itoa(code) + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestGetLatestRelease(t *testing.T) {
+	tests := []struct {
+		name           string
+		stdout, stderr string
+		code           int
+		want           string
+		wantErr        bool
+	}{
+		{name: "released", stdout: `{"tagName":"v1.2.0"}`, want: "v1.2.0"},
+		{name: "no releases", stderr: "release not found", code: 1},
+		{name: "unreachable", stderr: "error connecting to api.github.com", code: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeGh(t, tt.stdout, tt.stderr, tt.code)
+			got, err := NewClient("acme", "widgets", t.TempDir()).GetLatestRelease(t.Context(), "acme", "widgets")
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("GetLatestRelease() = %q, %v; want %q (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
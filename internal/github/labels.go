@@ -0,0 +1,28 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// labelColor is the color of the labels deep-claude creates.
+const labelColor = "c5def5"
+
+// CreateLabel creates a label in the repository, leaving one that already
+// exists as it is.
+func (c *Client) CreateLabel(ctx context.Context, name string) error {
+	output, err := c.combinedOutput(ctx, "", "label", "create", name, "--color", labelColor, "--description", "Added by deep-claude")
+	if err != nil && !strings.Contains(string(output), "already exists") {
+		return fmt.Errorf("failed to create label %s: %w\n%s", name, err, output)
+	}
+	return nil
+}
+
+// AddLabels adds labels to a PR. They must exist in the repository.
+func (c *Client) AddLabels(ctx context.Context, prNumber string, labels []string) error {
+	if output, err := c.combinedOutput(ctx, "", "pr", "edit", prNumber, "--add-label", strings.Join(labels, ",")); err != nil {
+		return fmt.Errorf("failed to label PR #%s: %w\n%s", prNumber, err, output)
+	}
+	return nil
+}
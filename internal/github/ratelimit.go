@@ -0,0 +1,163 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/retry"
+)
+
+// rateLimitMarkers are substrings of gh/API errors caused by rate limiting.
+var rateLimitMarkers = []string{
+	"rate limit",
+	"abuse detection",
+	"http 429",
+	"too many requests",
+	"submitted too quickly",
+}
+
+// Logger receives rate-limit notices from the client.
+type Logger interface {
+	Warning(format string, args ...interface{})
+	Debug(format string, args ...interface{})
+}
+
+// RateLimit is the core API quota of the authenticated user.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// SetLogger sets where backoff and quota messages are reported.
+func (c *Client) SetLogger(l Logger) {
+	c.logger = l
+}
+
+// SetRetryPolicy sets how rate-limited calls are retried. Its MaxDelay
+// also caps a wait for the quota to reset.
+func (c *Client) SetRetryPolicy(p retry.Policy) {
+	c.policy = p
+}
+
+// IsRateLimited reports whether gh output indicates a rate-limit error,
+// including GitHub's secondary rate limits.
+func IsRateLimited(output string) bool {
+	lower := strings.ToLower(output)
+	for _, marker := range rateLimitMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// combinedOutput runs gh and returns its combined stdout and stderr.
+func (c *Client) combinedOutput(ctx context.Context, stdin string, args ...string) ([]byte, error) {
+	stdout, stderr, err := c.run(ctx, stdin, args...)
+	return append(stdout, stderr...), err
+}
+
+// output runs gh and returns its stdout.
+func (c *Client) output(ctx context.Context, args ...string) ([]byte, error) {
+	stdout, stderr, err := c.run(ctx, "", args...)
+	if err != nil && len(stderr) > 0 {
+		return stdout, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(stderr)))
+	}
+	return stdout, err
+}
+
+// run executes gh in the working directory. Calls that fail because of a
+// rate limit are retried with the client's backoff policy, or after the
+// quota resets when the primary limit is exhausted, instead of failing.
+// Canceling ctx kills gh and ends a wait early.
+func (c *Client) run(ctx context.Context, stdin string, args ...string) ([]byte, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		cmd := exec.CommandContext(ctx, "gh", args...)
+		cmd.Dir = c.workDir
+		if stdin != "" {
+			cmd.Stdin = strings.NewReader(stdin)
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		err := cmd.Run()
+		if err == nil || attempt+1 >= c.policy.Attempts || !IsRateLimited(stderr.String()+stdout.String()) {
+			return stdout.Bytes(), stderr.Bytes(), err
+		}
+
+		delay := c.rateLimitDelay(ctx, attempt)
+		c.warnf("GitHub rate limit hit (gh %s), retrying in %s (%d/%d)",
+			strings.Join(args[:min(2, len(args))], " "), delay, attempt+1, c.policy.Attempts-1)
+		if err := retry.Sleep(ctx, delay); err != nil {
+			return stdout.Bytes(), stderr.Bytes(), err
+		}
+	}
+}
+
+// rateLimitDelay waits for the quota reset when the primary limit is used
+// up, and otherwise backs off exponentially (secondary limits don't report
+// a reset time).
+func (c *Client) rateLimitDelay(ctx context.Context, attempt int) time.Duration {
+	if limit, err := c.RateLimit(ctx); err == nil && limit.Remaining == 0 {
+		wait := time.Until(limit.Reset) + 5*time.Second
+		if wait > 0 && (c.policy.MaxDelay == 0 || wait <= c.policy.MaxDelay) {
+			return wait
+		}
+	}
+	return c.policy.Wait(attempt)
+}
+
+// RateLimit returns the remaining core API quota. Querying it doesn't count
+// against the quota.
+func (c *Client) RateLimit(ctx context.Context) (*RateLimit, error) {
+	cmd := exec.CommandContext(ctx, "gh", "api", "rate_limit")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate limit: %w", err)
+	}
+
+	var result struct {
+		Resources struct {
+			Core struct {
+				Limit     int   `json:"limit"`
+				Remaining int   `json:"remaining"`
+				Reset     int64 `json:"reset"`
+			} `json:"core"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse rate limit: %w", err)
+	}
+
+	core := result.Resources.Core
+	return &RateLimit{Limit: core.Limit, Remaining: core.Remaining, Reset: time.Unix(core.Reset, 0)}, nil
+}
+
+// LogRateLimit reports the remaining quota at debug level.
+func (c *Client) LogRateLimit(ctx context.Context) {
+	if c.logger == nil {
+		return
+	}
+	limit, err := c.RateLimit(ctx)
+	if err != nil {
+		c.logger.Debug("Could not get GitHub API quota: %v", err)
+		return
+	}
+	c.logger.Debug("GitHub API quota: %d/%d remaining, resets at %s",
+		limit.Remaining, limit.Limit, limit.Reset.Format("15:04:05"))
+}
+
+func (c *Client) warnf(format string, args ...interface{}) {
+	if c.logger != nil {
+		c.logger.Warning(format, args...)
+	}
+}
@@ -0,0 +1,41 @@
+package github
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsRateLimited(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{"GraphQL: API rate limit exceeded for user ID 123.", true},
+		{"HTTP 403: You have exceeded a secondary rate limit.", true},
+		{"You have triggered an abuse detection mechanism.", true},
+		{"HTTP 429: Too Many Requests", true},
+		{"was submitted too quickly", true},
+		{"HTTP 404: Not Found", false},
+		{"pull request create failed: GraphQL: No commits between main and feature", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsRateLimited(tt.output); got != tt.want {
+			t.Errorf("IsRateLimited(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	c := NewClient("acme", "widgets", "")
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 15 * time.Minute, 15 * time.Minute}
+	for attempt, w := range want {
+		if got := c.policy.Backoff(attempt); got != w {
+			t.Errorf("policy.Backoff(%d) = %v, want %v", attempt, got, w)
+		}
+	}
+	if c.policy.Attempts != 5 {
+		t.Errorf("rate-limited calls are tried %d times, want 5", c.policy.Attempts)
+	}
+}
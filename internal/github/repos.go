@@ -0,0 +1,42 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Repository is a repository found by ListRepos.
+type Repository struct {
+	Name          string `json:"name"`
+	NameWithOwner string `json:"nameWithOwner"`
+	URL           string `json:"url"`
+}
+
+// ListRepos lists up to limit of an owner's repositories, leaving out
+// archived ones and forks. With topic set, only those tagged with it.
+func (c *Client) ListRepos(ctx context.Context, owner, topic string, limit int) ([]Repository, error) {
+	args := []string{"repo", "list", owner, "--no-archived", "--source", "--limit", strconv.Itoa(limit), "--json", "name,nameWithOwner,url"}
+	if topic != "" {
+		args = append(args, "--topic", topic)
+	}
+	output, err := c.output(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories of %s: %w", owner, err)
+	}
+
+	var repos []Repository
+	if err := json.Unmarshal(output, &repos); err != nil {
+		return nil, fmt.Errorf("failed to parse repositories: %w", err)
+	}
+	return repos, nil
+}
+
+// CloneRepo clones a repository, given as owner/name, into dir.
+func (c *Client) CloneRepo(ctx context.Context, nameWithOwner, dir string) error {
+	if output, err := c.combinedOutput(ctx, "", "repo", "clone", nameWithOwner, dir); err != nil {
+		return fmt.Errorf("failed to clone %s: %w\n%s", nameWithOwner, err, output)
+	}
+	return nil
+}
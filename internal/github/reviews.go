@@ -0,0 +1,89 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ReRequestReview asks a PR's pending reviewers, and those who reviewed it
+// without approving, to review it again, which notifies them anew. It
+// returns who was asked: user logins and team slugs.
+func (c *Client) ReRequestReview(ctx context.Context, prNumber string) ([]string, error) {
+	output, err := c.output(ctx, "pr", "view", prNumber, "--json", "reviewRequests,latestReviews")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reviewers of PR #%s: %w", prNumber, err)
+	}
+	users, teams, err := parseReviewers(output)
+	if err != nil {
+		return nil, err
+	}
+	if len(users)+len(teams) == 0 {
+		return nil, nil
+	}
+
+	var fields []string
+	for _, u := range users {
+		fields = append(fields, "-f", "reviewers[]="+u)
+	}
+	for _, t := range teams {
+		fields = append(fields, "-f", "team_reviewers[]="+t)
+	}
+	path := fmt.Sprintf("repos/%s/%s/pulls/%s/requested_reviewers", c.owner, c.repo, prNumber)
+	// Requesting a pending reviewer again doesn't notify them, so the
+	// request is dropped first
+	for _, method := range []string{"DELETE", "POST"} {
+		args := append([]string{"api", path, "--method", method, "--silent"}, fields...)
+		if output, err := c.combinedOutput(ctx, "", args...); err != nil {
+			return nil, fmt.Errorf("failed to re-request review of PR #%s: %w\n%s", prNumber, err, output)
+		}
+	}
+	return append(users, teams...), nil
+}
+
+// parseReviewers reads the users and teams gh pr view lists as requested
+// reviewers or as authors of reviews that aren't approvals.
+func parseReviewers(output []byte) (users, teams []string, err error) {
+	var pr struct {
+		ReviewRequests []struct {
+			Login string `json:"login"`
+			Slug  string `json:"slug"`
+		} `json:"reviewRequests"`
+		LatestReviews []struct {
+			Author struct {
+				Login string `json:"login"`
+			} `json:"author"`
+			State string `json:"state"`
+		} `json:"latestReviews"`
+	}
+	if err := json.Unmarshal(output, &pr); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse reviewers: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	add := func(list *[]string, name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			*list = append(*list, name)
+		}
+	}
+	for _, r := range pr.ReviewRequests {
+		if r.Slug != "" {
+			// gh prints team slugs as org/team
+			_, slug, _ := strings.Cut(r.Slug, "/")
+			if slug == "" {
+				slug = r.Slug
+			}
+			add(&teams, slug)
+			continue
+		}
+		add(&users, r.Login)
+	}
+	for _, r := range pr.LatestReviews {
+		if r.State != "APPROVED" {
+			add(&users, r.Author.Login)
+		}
+	}
+	return users, teams, nil
+}
@@ -0,0 +1,22 @@
+package github
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReviewers(t *testing.T) {
+	output := `{"reviewRequests":[{"__typename":"User","login":"alice"},{"__typename":"Team","name":"Core","slug":"acme/core"}],
+"latestReviews":[{"author":{"login":"bob"},"state":"COMMENTED"},{"author":{"login":"carol"},"state":"APPROVED"},{"author":{"login":"alice"},"state":"CHANGES_REQUESTED"}]}`
+	users, teams, err := parseReviewers([]byte(output))
+	if err != nil {
+		t.Fatalf("parseReviewers() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(users, []string{"alice", "bob"}) || !reflect.DeepEqual(teams, []string{"core"}) {
+		t.Errorf("parseReviewers() = %v, %v, want alice and bob, and core", users, teams)
+	}
+
+	if _, _, err := parseReviewers([]byte("not json")); err == nil {
+		t.Error("parseReviewers() should fail on bad output")
+	}
+}
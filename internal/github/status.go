@@ -0,0 +1,72 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// StatusPrefix starts the context of every commit status deep-claude
+// sets, such as deep-claude/gates.
+const StatusPrefix = "deep-claude/"
+
+// maxStatusDescription is the longest description the Statuses API takes.
+const maxStatusDescription = 140
+
+// CommitStatus is a status to set on a commit.
+type CommitStatus struct {
+	SHA     string
+	Context string
+	// State is pending, success, failure or error
+	State       string
+	Description string
+	TargetURL   string
+}
+
+// SetCommitStatus sets a status on a commit, replacing the one with the
+// same context. Unlike check runs, statuses can be set with any token
+// that can write to the repository.
+func (c *Client) SetCommitStatus(ctx context.Context, status CommitStatus) error {
+	input, err := json.Marshal(statusRequest(status))
+	if err != nil {
+		return fmt.Errorf("failed to encode commit status: %w", err)
+	}
+	path := fmt.Sprintf("repos/%s/%s/statuses/%s", c.owner, c.repo, status.SHA)
+	output, err := c.combinedOutput(ctx, string(input), "api", path, "--method", "POST", "--input", "-", "--silent")
+	if err != nil {
+		return fmt.Errorf("failed to set commit status %s: %w\n%s", status.Context, err, output)
+	}
+	return nil
+}
+
+// statusRequest is the API request body for a commit status, with the
+// description cut to maxStatusDescription characters.
+func statusRequest(status CommitStatus) map[string]any {
+	description := status.Description
+	if utf8.RuneCountInString(description) > maxStatusDescription {
+		description = string([]rune(description)[:maxStatusDescription-1]) + "…"
+	}
+	request := map[string]any{
+		"state":       status.State,
+		"context":     status.Context,
+		"description": description,
+	}
+	if status.TargetURL != "" {
+		request["target_url"] = status.TargetURL
+	}
+	return request
+}
+
+// withoutOwnStatuses drops the statuses deep-claude set, so that its own
+// progress never holds a PR's checks pending or fails them.
+func withoutOwnStatuses(checks []PRCheck) []PRCheck {
+	kept := checks[:0:0]
+	for _, check := range checks {
+		if !strings.HasPrefix(check.Name, StatusPrefix) {
+			kept = append(kept, check)
+		}
+	}
+	return kept
+}
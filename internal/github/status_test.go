@@ -0,0 +1,37 @@
+package github
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestStatusRequest(t *testing.T) {
+	got := statusRequest(CommitStatus{SHA: "abc123", Context: "deep-claude/gates", State: "success", Description: strings.Repeat("é", 200)})
+	description := got["description"].(string)
+	if n := utf8.RuneCountInString(description); n != maxStatusDescription || !strings.HasSuffix(description, "…") {
+		t.Errorf("description has %d characters, want %d ending in …", n, maxStatusDescription)
+	}
+	if _, ok := got["target_url"]; ok {
+		t.Error("a status without a target URL should leave it out")
+	}
+	if got["state"] != "success" || got["context"] != "deep-claude/gates" {
+		t.Errorf("request = %v", got)
+	}
+}
+
+func TestWithoutOwnStatuses(t *testing.T) {
+	checks := []PRCheck{
+		{Name: "build", State: "SUCCESS"},
+		{Name: "deep-claude/checks", State: "PENDING"},
+		{Name: "deep-claude", State: "NEUTRAL"},
+		{Name: "deep-claude/gates", State: "FAILURE"},
+	}
+	got := withoutOwnStatuses(checks)
+	if len(got) != 2 || got[0].Name != "build" || got[1].Name != "deep-claude" {
+		t.Errorf("withoutOwnStatuses = %+v, want build and the deep-claude check run", got)
+	}
+	if status := summarizeChecks(got); !status.AllChecksPassed {
+		t.Error("deep-claude's own statuses should not decide the checks")
+	}
+}
@@ -0,0 +1,230 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// trackerWorkers is how many PR status polls run at once.
+	trackerWorkers = 4
+	// trackerSpacing is the minimum gap between polls across all workers,
+	// so watching many PRs doesn't trip GitHub's secondary rate limits.
+	trackerSpacing = 500 * time.Millisecond
+)
+
+// Update reports a change in the checks of a watched PR.
+type Update struct {
+	Number string
+	Status *PRStatus
+	// Done marks the last update for the PR: its checks finished, or Err
+	// is set because polling failed or timed out.
+	Done bool
+	Err  error
+}
+
+// Tracker watches the checks of several PRs concurrently. A small pool of
+// workers polls them, sharing one rate limit, and status changes are
+// delivered on Updates.
+type Tracker struct {
+	ctx      context.Context
+	poll     func(ctx context.Context, number string) (*PRStatus, error)
+	interval time.Duration
+	limiter  *time.Ticker
+
+	watches chan watchRequest
+	jobs    chan string
+	results chan pollResult
+	updates chan Update
+	done    chan struct{}
+}
+
+type watchRequest struct {
+	number  string
+	timeout time.Duration
+}
+
+type pollResult struct {
+	number string
+	status *PRStatus
+	err    error
+}
+
+// watchedPR is the tracker loop's state for one PR.
+type watchedPR struct {
+	deadline time.Time
+	timeout  time.Duration
+	last     *PRStatus
+	polling  bool
+}
+
+// StatusGetter reports a PR's checks and review state, like *Client.
+type StatusGetter interface {
+	GetPRStatus(ctx context.Context, prNumber string) (*PRStatus, error)
+}
+
+// NewTracker starts a tracker that polls each watched PR every interval.
+// When ctx is canceled, every watched PR gets a Done update with ctx's
+// error. Close it when done.
+func NewTracker(ctx context.Context, c StatusGetter, interval time.Duration) *Tracker {
+	return newTracker(ctx, c.GetPRStatus, interval, trackerSpacing, trackerWorkers)
+}
+
+func newTracker(ctx context.Context, poll func(context.Context, string) (*PRStatus, error), interval, spacing time.Duration, workers int) *Tracker {
+	t := &Tracker{
+		ctx:      ctx,
+		poll:     poll,
+		interval: interval,
+		limiter:  time.NewTicker(spacing),
+		watches:  make(chan watchRequest),
+		jobs:     make(chan string),
+		results:  make(chan pollResult),
+		updates:  make(chan Update),
+		done:     make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		go t.worker()
+	}
+	go t.loop()
+	return t
+}
+
+// Watch starts tracking a PR until its checks finish or timeout passes.
+func (t *Tracker) Watch(number string, timeout time.Duration) {
+	select {
+	case t.watches <- watchRequest{number: number, timeout: timeout}:
+	case <-t.done:
+	}
+}
+
+// Updates delivers the status changes of the watched PRs, ending with one
+// Done update per PR.
+func (t *Tracker) Updates() <-chan Update {
+	return t.updates
+}
+
+// Close stops the tracker. PRs still watched get no further updates.
+func (t *Tracker) Close() {
+	close(t.done)
+	t.limiter.Stop()
+}
+
+func (t *Tracker) worker() {
+	for {
+		select {
+		case <-t.done:
+			return
+		case number := <-t.jobs:
+			select {
+			case <-t.limiter.C:
+			case <-t.done:
+				return
+			}
+			status, err := t.poll(t.ctx, number)
+			select {
+			case t.results <- pollResult{number: number, status: status, err: err}:
+			case <-t.done:
+				return
+			}
+		}
+	}
+}
+
+// loop owns the tracker state: it schedules polls, turns their results
+// into updates, and queues updates until they are received, so a slow
+// reader never stalls polling.
+func (t *Tracker) loop() {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	watched := make(map[string]*watchedPR)
+	var jobs []string
+	var updates []Update
+	canceled := t.ctx.Done()
+
+	for {
+		var jobsOut chan string
+		var nextJob string
+		if len(jobs) > 0 {
+			jobsOut, nextJob = t.jobs, jobs[0]
+		}
+		var updatesOut chan Update
+		var nextUpdate Update
+		if len(updates) > 0 {
+			updatesOut, nextUpdate = t.updates, updates[0]
+		}
+
+		select {
+		case <-t.done:
+			return
+
+		case <-canceled:
+			for number, w := range watched {
+				updates = append(updates, Update{Number: number, Status: w.last, Done: true, Err: t.ctx.Err()})
+			}
+			clear(watched)
+			jobs, canceled = nil, nil
+
+		case req := <-t.watches:
+			if err := t.ctx.Err(); err != nil {
+				updates = append(updates, Update{Number: req.number, Done: true, Err: err})
+				continue
+			}
+			watched[req.number] = &watchedPR{deadline: time.Now().Add(req.timeout), timeout: req.timeout, polling: true}
+			jobs = append(jobs, req.number)
+
+		case <-ticker.C:
+			for number, w := range watched {
+				if w.polling {
+					continue
+				}
+				if time.Now().After(w.deadline) {
+					updates = append(updates, timeoutUpdate(number, w))
+					delete(watched, number)
+					continue
+				}
+				w.polling = true
+				jobs = append(jobs, number)
+			}
+
+		case r := <-t.results:
+			w, ok := watched[r.number]
+			if !ok {
+				continue
+			}
+			w.polling = false
+
+			switch {
+			case r.err != nil:
+				updates = append(updates, Update{Number: r.number, Done: true, Err: r.err})
+				delete(watched, r.number)
+			case r.status.HasFailedChecks || r.status.AllChecksPassed:
+				updates = append(updates, Update{Number: r.number, Status: r.status, Done: true})
+				delete(watched, r.number)
+			case time.Now().After(w.deadline):
+				w.last = r.status
+				updates = append(updates, timeoutUpdate(r.number, w))
+				delete(watched, r.number)
+			case hasStatusChanged(w.last, r.status):
+				updates = append(updates, Update{Number: r.number, Status: r.status})
+				w.last = r.status
+			}
+
+		case jobsOut <- nextJob:
+			jobs = jobs[1:]
+
+		case updatesOut <- nextUpdate:
+			updates = updates[1:]
+		}
+	}
+}
+
+func timeoutUpdate(number string, w *watchedPR) Update {
+	return Update{
+		Number: number,
+		Status: w.last,
+		Done:   true,
+		Err:    fmt.Errorf("timeout waiting for PR checks after %s", w.timeout),
+	}
+}
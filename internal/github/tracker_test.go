@@ -0,0 +1,104 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStatuses returns a scripted sequence of statuses per PR, repeating the last.
+type fakeStatuses struct {
+	mu      sync.Mutex
+	scripts map[string][]*PRStatus
+	calls   map[string]int
+}
+
+func (f *fakeStatuses) poll(_ context.Context, number string) (*PRStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	script := f.scripts[number]
+	i := f.calls[number]
+	if i >= len(script) {
+		i = len(script) - 1
+	}
+	f.calls[number]++
+	return script[i], nil
+}
+
+func TestTracker(t *testing.T) {
+	pending := &PRStatus{HasPendingChecks: true}
+	passed := &PRStatus{AllChecksPassed: true, IsMergeable: true}
+	failed := &PRStatus{HasFailedChecks: true}
+
+	fake := &fakeStatuses{
+		scripts: map[string][]*PRStatus{
+			"1": {pending, pending, passed},
+			"2": {failed},
+			"3": {pending},
+		},
+		calls: map[string]int{},
+	}
+	tr := newTracker(context.Background(), fake.poll, 5*time.Millisecond, time.Millisecond, 2)
+	defer tr.Close()
+
+	tr.Watch("1", time.Minute)
+	tr.Watch("2", time.Minute)
+	tr.Watch("3", 30*time.Millisecond)
+
+	updates := map[string][]Update{}
+	for done := 0; done < 3; {
+		select {
+		case u := <-tr.Updates():
+			updates[u.Number] = append(updates[u.Number], u)
+			if u.Done {
+				done++
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for updates, got %v", updates)
+		}
+	}
+
+	// PR 1 reports pending once (unchanged polls are not repeated), then passes
+	if u := updates["1"]; len(u) != 2 || u[0].Status != pending || !u[1].Done || u[1].Status != passed || u[1].Err != nil {
+		t.Errorf("PR 1 updates = %+v", u)
+	}
+	if u := updates["2"]; len(u) != 1 || !u[0].Done || u[0].Status != failed {
+		t.Errorf("PR 2 updates = %+v", u)
+	}
+	if u := updates["3"]; len(u) != 2 || !u[1].Done || u[1].Err == nil || u[1].Status != pending {
+		t.Errorf("PR 3 updates = %+v, want pending then a timeout", u)
+	}
+}
+
+func TestTrackerCanceled(t *testing.T) {
+	fake := &fakeStatuses{
+		scripts: map[string][]*PRStatus{"1": {{HasPendingChecks: true}}},
+		calls:   map[string]int{},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	tr := newTracker(ctx, fake.poll, 5*time.Millisecond, time.Millisecond, 1)
+	defer tr.Close()
+
+	tr.Watch("1", time.Hour)
+	cancel()
+	tr.Watch("2", time.Hour)
+
+	done := map[string]error{}
+	for len(done) < 2 {
+		select {
+		case u := <-tr.Updates():
+			if u.Done {
+				done[u.Number] = u.Err
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for updates after cancel, got %v", done)
+		}
+	}
+	for number, err := range done {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("PR %s ended with %v, want context.Canceled", number, err)
+		}
+	}
+}
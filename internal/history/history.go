@@ -0,0 +1,222 @@
+// Package history summarizes recorded runs from their event logs for
+// reporting.
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// Run is the record of one run, across all the times it was resumed.
+type Run struct {
+	RunID      string    `json:"run_id"`
+	Repo       string    `json:"repo"`
+	Prompt     string    `json:"prompt"`
+	Labels     []string  `json:"labels,omitempty"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished,omitzero"`
+	Iterations int       `json:"iterations"`
+	Cost       float64   `json:"cost"`
+	Outcome    string    `json:"outcome"`
+	PRs        []PR      `json:"prs"`
+}
+
+// PR is a pull request opened by a run and what became of it.
+type PR struct {
+	Number  string `json:"number"`
+	URL     string `json:"url"`
+	Outcome string `json:"outcome"`
+}
+
+// Summarize builds the record of run runID from its events.
+func Summarize(runID string, list []events.Event) Run {
+	run := Run{RunID: runID, Outcome: "unfinished", PRs: []PR{}}
+	prs := make(map[string]int)
+
+	for _, e := range list {
+		switch e.Type {
+		case events.RunStarted:
+			if run.Started.IsZero() {
+				run.Started = e.Time
+				run.Prompt = str(e.Data, "prompt")
+				if owner, repo := str(e.Data, "owner"), str(e.Data, "repo"); owner != "" || repo != "" {
+					run.Repo = owner + "/" + repo
+				}
+			}
+			if labels := strs(e.Data, "labels"); labels != nil {
+				run.Labels = labels
+			}
+			// A resumed run is unfinished until it finishes again
+			run.Outcome = "unfinished"
+		case events.RunFinished:
+			run.Finished = e.Time
+			run.Iterations = int(num(e.Data, "iterations"))
+			run.Cost = num(e.Data, "total_cost")
+			switch {
+			case e.Data["completed"] == true:
+				run.Outcome = "completed"
+			case str(e.Data, "stop_reason") != "":
+				run.Outcome = "stopped: " + str(e.Data, "stop_reason")
+			default:
+				run.Outcome = "limit reached"
+			}
+		case events.PRCreated, events.PRUpdated:
+			number := str(e.Data, "number")
+			if _, ok := prs[number]; !ok {
+				prs[number] = len(run.PRs)
+				run.PRs = append(run.PRs, PR{Number: number, URL: str(e.Data, "url"), Outcome: "open"})
+			}
+		case events.MergePending:
+			if i, ok := prs[str(e.Data, "number")]; ok {
+				run.PRs[i].Outcome = "awaiting approval"
+			}
+		case events.PRMerged:
+			if i, ok := prs[str(e.Data, "number")]; ok {
+				run.PRs[i].Outcome = "merged"
+			}
+		case events.PRClosed:
+			if i, ok := prs[str(e.Data, "number")]; ok {
+				run.PRs[i].Outcome = "closed"
+			}
+		}
+	}
+	return run
+}
+
+// Load summarizes the recorded runs, oldest first, that started on or
+// after since. Runs whose event log can't be read are skipped.
+func Load(since time.Time) ([]Run, error) {
+	ids, err := state.ListRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []Run
+	for _, id := range ids {
+		dir, err := state.RunDir(id)
+		if err != nil {
+			return nil, err
+		}
+		list, err := events.Read(filepath.Join(dir, events.FileName), "")
+		if err != nil || len(list) == 0 {
+			continue
+		}
+		run := Summarize(id, list)
+		if run.Started.Before(since) {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// HasLabel reports whether the run was given a label with --label.
+func (r Run) HasLabel(label string) bool {
+	return slices.Contains(r.Labels, label)
+}
+
+// Merged returns how many of the run's PRs were merged.
+func (r Run) Merged() int {
+	var n int
+	for _, pr := range r.PRs {
+		if pr.Outcome == "merged" {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteCSV writes one row per run, with the PR URLs space-separated.
+func WriteCSV(w io.Writer, runs []Run) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"run_id", "repo", "prompt", "started", "finished", "iterations", "cost_usd", "outcome", "prs_opened", "prs_merged", "pr_urls", "labels"}); err != nil {
+		return err
+	}
+	for _, r := range runs {
+		var finished string
+		if !r.Finished.IsZero() {
+			finished = r.Finished.UTC().Format(time.RFC3339)
+		}
+		urls := make([]string, len(r.PRs))
+		for i, pr := range r.PRs {
+			urls[i] = pr.URL
+		}
+		if err := out.Write([]string{
+			r.RunID,
+			r.Repo,
+			r.Prompt,
+			r.Started.UTC().Format(time.RFC3339),
+			finished,
+			strconv.Itoa(r.Iterations),
+			strconv.FormatFloat(r.Cost, 'f', 4, 64),
+			r.Outcome,
+			strconv.Itoa(len(r.PRs)),
+			strconv.Itoa(r.Merged()),
+			strings.Join(urls, " "),
+			strings.Join(r.Labels, ","),
+		}); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteJSON writes the runs as a JSON array.
+func WriteJSON(w io.Writer, runs []Run) error {
+	if runs == nil {
+		runs = []Run{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(runs)
+}
+
+// ParseSince parses a YYYY-MM-DD date, in local time, or "" for no limit.
+func ParseSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD)", s)
+	}
+	return t, nil
+}
+
+func str(data map[string]any, key string) string {
+	switch v := data[key].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func strs(data map[string]any, key string) []string {
+	list, _ := data[key].([]any)
+	var values []string
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+func num(data map[string]any, key string) float64 {
+	v, _ := data[key].(float64)
+	return v
+}
@@ -0,0 +1,97 @@
+package history
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+var start = time.Date(2025, 1, 15, 14, 30, 0, 0, time.UTC)
+
+func event(minutes int, eventType string, data map[string]any) events.Event {
+	return events.Event{Time: start.Add(time.Duration(minutes) * time.Minute), Type: eventType, Data: data}
+}
+
+func resumedRun() []events.Event {
+	return []events.Event{
+		event(0, events.RunStarted, map[string]any{"prompt": "add tests", "owner": "o", "repo": "api"}),
+		event(5, events.PRCreated, map[string]any{"number": "12", "url": "https://github.com/o/api/pull/12"}),
+		event(10, events.PRMerged, map[string]any{"number": "12"}),
+		event(15, events.PRCreated, map[string]any{"number": "13", "url": "https://github.com/o/api/pull/13"}),
+		// Crashed, then resumed: the open PR is updated instead of duplicated
+		event(60, events.RunStarted, map[string]any{"prompt": "add tests", "owner": "o", "repo": "api", "labels": []any{"refactor-auth"}, "resumed": true}),
+		event(65, events.PRUpdated, map[string]any{"number": "13", "url": "https://github.com/o/api/pull/13"}),
+		event(70, events.PRClosed, map[string]any{"number": "13", "reason": "checks failed"}),
+		event(80, events.RunFinished, map[string]any{"iterations": float64(3), "total_cost": 1.5, "completed": false, "stop_reason": "reached max iterations (3)"}),
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	run := Summarize("20250115-143000-ab12", resumedRun())
+
+	if run.Repo != "o/api" || run.Prompt != "add tests" || !run.Started.Equal(start) || !run.Finished.Equal(start.Add(80*time.Minute)) {
+		t.Errorf("Summarize() = %+v", run)
+	}
+	if run.Iterations != 3 || run.Cost != 1.5 || run.Outcome != "stopped: reached max iterations (3)" {
+		t.Errorf("Summarize() totals = %d, %v, %q", run.Iterations, run.Cost, run.Outcome)
+	}
+	if len(run.PRs) != 2 || run.PRs[0].Outcome != "merged" || run.PRs[1].Outcome != "closed" || run.Merged() != 1 {
+		t.Errorf("Summarize() PRs = %+v", run.PRs)
+	}
+	if !run.HasLabel("refactor-auth") || run.HasLabel("billing") {
+		t.Errorf("Summarize() labels = %v", run.Labels)
+	}
+
+	unfinished := Summarize("x", resumedRun()[:5])
+	if unfinished.Outcome != "unfinished" || !unfinished.Finished.IsZero() {
+		t.Errorf("Summarize() of a running run = %+v", unfinished)
+	}
+}
+
+func TestLoadSince(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	for _, id := range []string{"20250115-143000-ab12", "20250301-090000-cd34"} {
+		dir, _ := state.RunDir(id)
+		log, err := events.Create(filepath.Join(dir, events.FileName), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		log.Emit(events.RunStarted, 0, map[string]any{"prompt": id})
+		log.Close()
+	}
+
+	runs, err := Load(time.Time{})
+	if err != nil || len(runs) != 2 {
+		t.Fatalf("Load() = %+v, %v", runs, err)
+	}
+
+	since, err := ParseSince(time.Now().Format("2006-01-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, err = Load(since.Add(24 * time.Hour))
+	if err != nil || len(runs) != 0 {
+		t.Errorf("Load(tomorrow) = %+v, %v", runs, err)
+	}
+
+	if _, err := ParseSince("01/15/2025"); err == nil {
+		t.Error("ParseSince() expected error for a non-ISO date")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, []Run{Summarize("20250115-143000-ab12", resumedRun())}); err != nil {
+		t.Fatalf("WriteCSV() unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := "20250115-143000-ab12,o/api,add tests,2025-01-15T14:30:00Z,2025-01-15T15:50:00Z,3,1.5000,stopped: reached max iterations (3),2,1,https://github.com/o/api/pull/12 https://github.com/o/api/pull/13,refactor-auth"
+	if len(lines) != 2 || lines[1] != want {
+		t.Errorf("WriteCSV() =\n%s\nwant row\n%s", buf.String(), want)
+	}
+}
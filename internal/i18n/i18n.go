@@ -0,0 +1,151 @@
+// Package i18n translates the messages the CLI prints. Messages are looked
+// up by their English text, format verbs included, in the catalog of the
+// chosen language; those missing from it are printed in English. Prompts
+// sent to Claude are never translated.
+package i18n
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed locales/*.yaml
+var builtinFS embed.FS
+
+// Env overrides the language detected from the locale.
+const Env = "DEEP_CLAUDE_LANG"
+
+var (
+	mu      sync.RWMutex
+	catalog map[string]string
+)
+
+// UserDir returns the directory holding user catalogs, which add to and
+// override the built-in ones: $XDG_CONFIG_HOME/deep-claude/locales, or
+// the OS equivalent.
+func UserDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "deep-claude", "locales"), nil
+}
+
+// Detect returns the language to print in from $DEEP_CLAUDE_LANG, or the
+// locale in $LC_ALL, $LC_MESSAGES or $LANG, e.g. "ko" for ko_KR.UTF-8.
+func Detect() string {
+	for _, name := range []string{Env, "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return Normalize(value)
+		}
+	}
+	return ""
+}
+
+// Normalize reduces a locale to its language code: "pt_BR.UTF-8" becomes
+// "pt", and "C" and "POSIX" become "" for English.
+func Normalize(locale string) string {
+	lang, _, _ := strings.Cut(locale, ".")
+	lang, _, _ = strings.Cut(lang, "@")
+	lang, _, _ = strings.Cut(strings.ReplaceAll(lang, "-", "_"), "_")
+	lang = strings.ToLower(lang)
+	if lang == "c" || lang == "posix" {
+		return ""
+	}
+	return lang
+}
+
+// Languages returns the languages with a built-in or user catalog, and
+// "en", sorted.
+func Languages(userDir string) []string {
+	langs := map[string]bool{"en": true}
+	entries, _ := fs.ReadDir(builtinFS, "locales")
+	if userDir != "" {
+		if user, err := os.ReadDir(userDir); err == nil {
+			entries = append(entries, user...)
+		}
+	}
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".yaml"); ok {
+			langs[name] = true
+		}
+	}
+	names := make([]string, 0, len(langs))
+	for name := range langs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load reads the catalog of a language, the user catalog in userDir over
+// the built-in one. English, or "", has an empty catalog.
+func Load(userDir, lang string) (map[string]string, error) {
+	lang = Normalize(lang)
+	if lang == "" || lang == "en" {
+		return map[string]string{}, nil
+	}
+	messages := make(map[string]string)
+	found := false
+	if data, err := builtinFS.ReadFile("locales/" + lang + ".yaml"); err == nil {
+		if err := yaml.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("failed to parse catalog %s: %w", lang, err)
+		}
+		found = true
+	}
+	if userDir != "" {
+		path := filepath.Join(userDir, lang+".yaml")
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := yaml.Unmarshal(data, &messages); err != nil {
+				return nil, fmt.Errorf("failed to parse catalog %s: %w", path, err)
+			}
+			found = true
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, fmt.Errorf("failed to read catalog %s: %w", path, err)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no messages in language %q (available: %s)", lang, strings.Join(Languages(userDir), ", "))
+	}
+	return messages, nil
+}
+
+// SetLanguage makes T translate to lang, or not at all for English.
+func SetLanguage(lang string) error {
+	userDir, _ := UserDir()
+	messages, err := Load(userDir, lang)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	catalog = messages
+	mu.Unlock()
+	return nil
+}
+
+// T returns the translation of an English message, or the message itself
+// if it has none.
+func T(message string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if translated, ok := catalog[message]; ok && translated != "" {
+		return translated
+	}
+	return message
+}
+
+// Sprintf formats the translation of an English format.
+func Sprintf(format string, args ...interface{}) string {
+	return fmt.Sprintf(T(format), args...)
+}
@@ -0,0 +1,102 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		locale, want string
+	}{
+		{"ko_KR.UTF-8", "ko"},
+		{"pt-BR", "pt"},
+		{"de_DE@euro", "de"},
+		{"EN", "en"},
+		{"C", ""},
+		{"POSIX", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.locale); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Setenv(Env, "")
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "ko_KR.UTF-8")
+	t.Setenv("LANG", "en_US.UTF-8")
+	if got := Detect(); got != "ko" {
+		t.Errorf("Detect() = %q, want ko", got)
+	}
+	t.Setenv(Env, "en")
+	if got := Detect(); got != "en" {
+		t.Errorf("Detect() with $%s = %q, want en", Env, got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	user := "\"Merged PR #%s\": \"PR #%s 병합됨\"\n\"Hello\": \"안녕하세요\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "ko.yaml"), []byte(user), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := Load(dir, "ko_KR.UTF-8")
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if got := messages["Merged PR #%s"]; got != "PR #%s 병합됨" {
+		t.Errorf("user catalog didn't override the built-in one: %q", got)
+	}
+	if messages["Hello"] == "" || messages["Run Summary"] == "" {
+		t.Errorf("Load() = %v, want the built-in and user messages", messages)
+	}
+
+	if messages, err := Load(dir, "en"); err != nil || len(messages) != 0 {
+		t.Errorf("Load(en) = %v, %v, want an empty catalog", messages, err)
+	}
+	if _, err := Load(dir, "xx"); err == nil {
+		t.Error("Load(xx) expected error, got nil")
+	}
+	if got := Languages(dir); !slices.Contains(got, "en") || !slices.Contains(got, "ko") {
+		t.Errorf("Languages() = %v", got)
+	}
+}
+
+func TestT(t *testing.T) {
+	defer func() { _ = SetLanguage("") }()
+	mu.Lock()
+	catalog = map[string]string{"Merged PR #%s": "PR #%s 병합 완료"}
+	mu.Unlock()
+	if got := Sprintf("Merged PR #%s", "7"); got != "PR #7 병합 완료" {
+		t.Errorf("Sprintf() = %q", got)
+	}
+	if got := T("Not translated"); got != "Not translated" {
+		t.Errorf("T() = %q, want the message itself", got)
+	}
+}
+
+// verbPattern matches a format verb, with its flags, width and precision.
+var verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+func TestCatalogsKeepVerbs(t *testing.T) {
+	for _, lang := range Languages("") {
+		messages, err := Load("", lang)
+		if err != nil {
+			t.Fatalf("Load(%s) unexpected error: %v", lang, err)
+		}
+		for message, translated := range messages {
+			want, got := verbPattern.FindAllString(message, -1), verbPattern.FindAllString(translated, -1)
+			if !slices.Equal(got, want) {
+				t.Errorf("%s: %q has verbs %v, want %v as in %q", lang, translated, got, want, message)
+			}
+		}
+	}
+}
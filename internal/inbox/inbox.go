@@ -0,0 +1,149 @@
+// Package inbox provides a directory-backed queue of human instructions
+// that are delivered to the next iteration's prompt.
+package inbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// archiveDirName is the subdirectory delivered messages are moved into.
+const archiveDirName = "archive"
+
+// Message is a single queued instruction.
+type Message struct {
+	Name string
+	Body string
+	path string
+}
+
+// Inbox is a directory of instruction files.
+type Inbox struct {
+	dir string
+}
+
+// New creates an inbox backed by dir. The directory need not exist yet.
+func New(dir string) *Inbox {
+	return &Inbox{dir: dir}
+}
+
+// Dir returns the inbox directory.
+func (b *Inbox) Dir() string {
+	return b.dir
+}
+
+// Pending returns queued messages ordered by their leading number, then name.
+// Hidden files, empty files and the archive directory are skipped.
+func (b *Inbox) Pending() ([]Message, error) {
+	entries, err := os.ReadDir(b.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inbox %s: %w", b.dir, err)
+	}
+
+	var messages []Message
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		path := filepath.Join(b.dir, name)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read inbox message %s: %w", name, err)
+		}
+		body := strings.TrimSpace(string(content))
+		if body == "" {
+			continue
+		}
+		messages = append(messages, Message{Name: name, Body: body, path: path})
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		ni, nj := leadingNumber(messages[i].Name), leadingNumber(messages[j].Name)
+		if ni != nj {
+			return ni < nj
+		}
+		return messages[i].Name < messages[j].Name
+	})
+	return messages, nil
+}
+
+// Add queues a message and returns the file name it was stored under.
+// The file is written under a temporary name first so a concurrent reader
+// never sees a partial message.
+func (b *Inbox) Add(body string) (string, error) {
+	if strings.TrimSpace(body) == "" {
+		return "", fmt.Errorf("message is empty")
+	}
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create inbox %s: %w", b.dir, err)
+	}
+
+	name := fmt.Sprintf("%d.md", time.Now().UnixNano())
+	tmp := filepath.Join(b.dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, []byte(body+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(b.dir, name)); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to queue message: %w", err)
+	}
+	return name, nil
+}
+
+// Archive moves delivered messages into the archive subdirectory, prefixed
+// with a timestamp so repeated names don't collide.
+func (b *Inbox) Archive(messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	archiveDir := filepath.Join(b.dir, archiveDirName)
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	stamp := time.Now().Format("20060102-150405")
+	for _, m := range messages {
+		dest := filepath.Join(archiveDir, stamp+"-"+m.Name)
+		if err := os.Rename(m.path, dest); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// Format renders messages as a single prompt section body.
+func Format(messages []Message) string {
+	var sb strings.Builder
+	for i, m := range messages {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(fmt.Sprintf("### %s\n\n%s", m.Name, m.Body))
+	}
+	return sb.String()
+}
+
+// leadingNumber parses the digits a file name starts with, or returns -1
+// so unnumbered files sort first.
+func leadingNumber(name string) int {
+	end := 0
+	for end < len(name) && name[end] >= '0' && name[end] <= '9' {
+		end++
+	}
+	n, err := strconv.Atoi(name[:end])
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,79 @@
+package inbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPendingOrder(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"10-last.md":   "last",
+		"2-second.md":  "second",
+		"01-first.txt": "first",
+		"notes.md":     "unnumbered",
+		"empty.md":     "  \n",
+		".hidden.md":   "hidden",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "archive"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := New(dir).Pending()
+	if err != nil {
+		t.Fatalf("Pending() unexpected error: %v", err)
+	}
+
+	want := []string{"notes.md", "01-first.txt", "2-second.md", "10-last.md"}
+	if len(messages) != len(want) {
+		t.Fatalf("Pending() returned %d messages, want %d", len(messages), len(want))
+	}
+	for i, name := range want {
+		if messages[i].Name != name {
+			t.Errorf("Pending()[%d] = %q, want %q", i, messages[i].Name, name)
+		}
+	}
+}
+
+func TestPendingMissingDir(t *testing.T) {
+	messages, err := New(filepath.Join(t.TempDir(), "missing")).Pending()
+	if err != nil || len(messages) != 0 {
+		t.Errorf("Pending() on missing dir = %v, %v; want no messages", messages, err)
+	}
+}
+
+func TestAddAndArchive(t *testing.T) {
+	b := New(filepath.Join(t.TempDir(), "inbox"))
+
+	if _, err := b.Add("Focus on the API layer next"); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	if _, err := b.Add("   "); err == nil {
+		t.Error("Add() expected error for empty message")
+	}
+
+	messages, err := b.Pending()
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("Pending() = %v, %v; want one message", messages, err)
+	}
+	if messages[0].Body != "Focus on the API layer next" {
+		t.Errorf("message body = %q", messages[0].Body)
+	}
+
+	if err := b.Archive(messages); err != nil {
+		t.Fatalf("Archive() unexpected error: %v", err)
+	}
+	if messages, _ := b.Pending(); len(messages) != 0 {
+		t.Errorf("Pending() after Archive() returned %d messages, want 0", len(messages))
+	}
+	archived, _ := os.ReadDir(filepath.Join(b.Dir(), "archive"))
+	if len(archived) != 1 {
+		t.Errorf("archive holds %d files, want 1", len(archived))
+	}
+}
@@ -0,0 +1,213 @@
+// Package ledger records what each run spent, tagged so the cost can be
+// attributed to teams and projects.
+package ledger
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var tagKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// LabelsTag is the tag holding a run's --label labels, comma-separated.
+const LabelsTag = "labels"
+
+// Entry is the spend of one run, or of one part of a resumed run.
+type Entry struct {
+	RunID      string            `json:"run_id"`
+	Repo       string            `json:"repo"`
+	Prompt     string            `json:"prompt"`
+	Started    time.Time         `json:"started"`
+	Finished   time.Time         `json:"finished"`
+	Iterations int               `json:"iterations"`
+	Cost       float64           `json:"cost"`
+	Tags       map[string]string `json:"tags,omitempty"`
+}
+
+// ParseTags parses key=value cost tags.
+func ParseTags(specs []string) (map[string]string, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	tags := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		key, value, ok := strings.Cut(spec, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || !tagKeyPattern.MatchString(key) || value == "" {
+			return nil, fmt.Errorf("invalid cost tag %q (use key=value, e.g. team=payments)", spec)
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
+
+// FormatTags renders tags as sorted key=value pairs.
+func FormatTags(tags map[string]string) []string {
+	pairs := make([]string, 0, len(tags))
+	for _, key := range sortedKeys(tags) {
+		pairs = append(pairs, key+"="+tags[key])
+	}
+	return pairs
+}
+
+// Append adds an entry to the ledger at path.
+func Append(path string, e Entry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create ledger directory: %w", err)
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open cost ledger: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write cost ledger: %w", err)
+	}
+	return nil
+}
+
+// Read returns the entries of the ledger at path, oldest first. A missing
+// ledger has no entries.
+func Read(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cost ledger: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse cost ledger line %d: %w", lineNum, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cost ledger: %w", err)
+	}
+	return entries, nil
+}
+
+// Filter returns the entries that have all the given tags.
+func Filter(entries []Entry, tags map[string]string) []Entry {
+	var matched []Entry
+	for _, e := range entries {
+		ok := true
+		for key, value := range tags {
+			if e.Tags[key] != value {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// WithLabel returns the entries of runs with the given label.
+func WithLabel(entries []Entry, label string) []Entry {
+	var matched []Entry
+	for _, e := range entries {
+		if slices.Contains(strings.Split(e.Tags[LabelsTag], ","), label) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// Total returns the summed cost of the entries.
+func Total(entries []Entry) float64 {
+	var total float64
+	for _, e := range entries {
+		total += e.Cost
+	}
+	return total
+}
+
+// WriteCSV writes the entries as CSV, with a column per tag key.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	keys := tagKeys(entries)
+
+	out := csv.NewWriter(w)
+	header := []string{"run_id", "repo", "started", "finished", "iterations", "cost_usd"}
+	for _, key := range keys {
+		header = append(header, "tag:"+key)
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		row := []string{
+			e.RunID,
+			e.Repo,
+			e.Started.UTC().Format(time.RFC3339),
+			e.Finished.UTC().Format(time.RFC3339),
+			strconv.Itoa(e.Iterations),
+			strconv.FormatFloat(e.Cost, 'f', 4, 64),
+		}
+		for _, key := range keys {
+			row = append(row, e.Tags[key])
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteJSON writes the entries as a JSON array.
+func WriteJSON(w io.Writer, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// tagKeys returns the tag keys used by any entry, sorted.
+func tagKeys(entries []Entry) []string {
+	seen := make(map[string]string)
+	for _, e := range entries {
+		for key := range e.Tags {
+			seen[key] = ""
+		}
+	}
+	return sortedKeys(seen)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,119 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		specs   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{nil, nil, false},
+		{[]string{"team=payments", "ticket=JIRA-123"}, map[string]string{"team": "payments", "ticket": "JIRA-123"}, false},
+		{[]string{" team = payments "}, map[string]string{"team": "payments"}, false},
+		{[]string{"url=https://x.test/a=b"}, map[string]string{"url": "https://x.test/a=b"}, false},
+		{[]string{"payments"}, nil, true},
+		{[]string{"team="}, nil, true},
+		{[]string{"my team=payments"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTags(tt.specs)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTags(%q) error = %v, wantErr %v", tt.specs, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTags(%q) = %v, want %v", tt.specs, got, tt.want)
+		}
+	}
+}
+
+func testEntries() []Entry {
+	started := time.Date(2025, 1, 15, 14, 30, 0, 0, time.UTC)
+	return []Entry{
+		{RunID: "r1", Repo: "o/api", Started: started, Finished: started.Add(time.Hour), Iterations: 3, Cost: 1.25, Tags: map[string]string{"team": "payments", "ticket": "JIRA-123"}},
+		{RunID: "r2", Repo: "o/web", Started: started, Finished: started.Add(time.Hour), Iterations: 1, Cost: 0.5, Tags: map[string]string{"team": "growth"}},
+		{RunID: "r3", Repo: "o/api", Started: started, Finished: started.Add(time.Hour), Iterations: 2, Cost: 2},
+	}
+}
+
+func TestAppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "costs.jsonl")
+	if entries, err := Read(path); err != nil || entries != nil {
+		t.Fatalf("Read() of missing ledger = %v, %v", entries, err)
+	}
+
+	for _, e := range testEntries() {
+		if err := Append(path, e); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+	}
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(entries, testEntries()) {
+		t.Errorf("Read() = %+v", entries)
+	}
+}
+
+func TestFilterAndTotal(t *testing.T) {
+	payments := Filter(testEntries(), map[string]string{"team": "payments"})
+	if len(payments) != 1 || payments[0].RunID != "r1" {
+		t.Errorf("Filter(team=payments) = %+v", payments)
+	}
+	if got := Total(Filter(testEntries(), nil)); got != 3.75 {
+		t.Errorf("Total() = %v, want 3.75", got)
+	}
+}
+
+func TestWithLabel(t *testing.T) {
+	entries := []Entry{
+		{RunID: "r1", Tags: map[string]string{LabelsTag: "refactor-auth,q3"}},
+		{RunID: "r2", Tags: map[string]string{LabelsTag: "refactor-auth-v2"}},
+		{RunID: "r3"},
+	}
+	labeled := WithLabel(entries, "refactor-auth")
+	if len(labeled) != 1 || labeled[0].RunID != "r1" {
+		t.Errorf("WithLabel(refactor-auth) = %+v, want only r1", labeled)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testEntries()); err != nil {
+		t.Fatalf("WriteCSV() unexpected error: %v", err)
+	}
+	want := `run_id,repo,started,finished,iterations,cost_usd,tag:team,tag:ticket
+r1,o/api,2025-01-15T14:30:00Z,2025-01-15T15:30:00Z,3,1.2500,payments,JIRA-123
+r2,o/web,2025-01-15T14:30:00Z,2025-01-15T15:30:00Z,1,0.5000,growth,
+r3,o/api,2025-01-15T14:30:00Z,2025-01-15T15:30:00Z,2,2.0000,,
+`
+	if buf.String() != want {
+		t.Errorf("WriteCSV() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, nil); err != nil || buf.String() != "[]\n" {
+		t.Errorf("WriteJSON(nil) = %q, %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, testEntries()); err != nil {
+		t.Fatalf("WriteJSON() unexpected error: %v", err)
+	}
+	var decoded []Entry
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 3 || decoded[0].Tags["ticket"] != "JIRA-123" {
+		t.Errorf("WriteJSON() = %s", buf.String())
+	}
+}
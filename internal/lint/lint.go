@@ -0,0 +1,254 @@
+// Package lint runs linters and splits their findings into batches small
+// enough for one iteration each.
+package lint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Finding is a single linter warning.
+type Finding struct {
+	File    string
+	Line    int
+	Column  int
+	Rule    string
+	Message string
+}
+
+func (f Finding) String() string {
+	loc := f.File
+	if f.Line > 0 {
+		loc += fmt.Sprintf(":%d", f.Line)
+		if f.Column > 0 {
+			loc += fmt.Sprintf(":%d", f.Column)
+		}
+	}
+	if f.Rule != "" {
+		return fmt.Sprintf("%s: %s (%s)", loc, f.Message, f.Rule)
+	}
+	return fmt.Sprintf("%s: %s", loc, f.Message)
+}
+
+// DefaultCommands returns linter commands for the projects found in dir:
+// golangci-lint for Go modules and eslint for npm packages.
+func DefaultCommands(dir string) []string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	var commands []string
+	if exists("go.mod") {
+		commands = append(commands, "golangci-lint run ./...")
+	}
+	if exists("package.json") {
+		commands = append(commands, "npx eslint -f json .")
+	}
+	return commands
+}
+
+// Run runs a linter command in dir and parses its findings. Linters exit
+// non-zero when they report findings, so that alone isn't an error.
+// Canceling ctx kills the linter.
+func Run(ctx context.Context, dir, command string) ([]Finding, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	findings := Parse(dir, stdout.String())
+	if err != nil && len(findings) == 0 {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, fmt.Errorf("failed to run %q: %w", command, err)
+		}
+		// Some linters print findings to stderr
+		findings = Parse(dir, stderr.String())
+		if len(findings) == 0 {
+			return nil, fmt.Errorf("%q failed without reporting findings: %w\n%s", command, err, strings.TrimSpace(stderr.String()))
+		}
+	}
+	return findings, nil
+}
+
+// Parse extracts findings from linter output: eslint's JSON format,
+// golangci-lint's JSON format, or "file:line[:col]: message" lines.
+// Absolute paths under dir are made relative to it.
+func Parse(dir, output string) []Finding {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil
+	}
+
+	findings, ok := parseESLint(output)
+	if !ok {
+		findings, ok = parseGolangCI(output)
+	}
+	if !ok {
+		findings = parseLines(output)
+	}
+
+	for i := range findings {
+		if rel, err := filepath.Rel(dir, findings[i].File); err == nil && filepath.IsAbs(findings[i].File) && !strings.HasPrefix(rel, "..") {
+			findings[i].File = rel
+		}
+	}
+	return findings
+}
+
+func parseESLint(output string) ([]Finding, bool) {
+	var files []struct {
+		FilePath string `json:"filePath"`
+		Messages []struct {
+			RuleID  string `json:"ruleId"`
+			Message string `json:"message"`
+			Line    int    `json:"line"`
+			Column  int    `json:"column"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal([]byte(output), &files); err != nil {
+		return nil, false
+	}
+
+	var findings []Finding
+	for _, f := range files {
+		for _, m := range f.Messages {
+			findings = append(findings, Finding{File: f.FilePath, Line: m.Line, Column: m.Column, Rule: m.RuleID, Message: m.Message})
+		}
+	}
+	return findings, true
+}
+
+func parseGolangCI(output string) ([]Finding, bool) {
+	var report struct {
+		Issues []struct {
+			FromLinter string `json:"FromLinter"`
+			Text       string `json:"Text"`
+			Pos        struct {
+				Filename string `json:"Filename"`
+				Line     int    `json:"Line"`
+				Column   int    `json:"Column"`
+			} `json:"Pos"`
+		} `json:"Issues"`
+	}
+	// golangci-lint may print a summary after the JSON object
+	if i := strings.LastIndex(output, "}"); i >= 0 && strings.HasPrefix(output, "{") {
+		output = output[:i+1]
+	}
+	if err := json.Unmarshal([]byte(output), &report); err != nil || report.Issues == nil {
+		return nil, false
+	}
+
+	var findings []Finding
+	for _, issue := range report.Issues {
+		findings = append(findings, Finding{
+			File: issue.Pos.Filename, Line: issue.Pos.Line, Column: issue.Pos.Column,
+			Rule: issue.FromLinter, Message: issue.Text,
+		})
+	}
+	return findings, true
+}
+
+// lineRe matches "path/to/file.go:12:5: message" with an optional column.
+var lineRe = regexp.MustCompile(`^([^\s:][^:]*):(\d+)(?::(\d+))?:\s*(.+)$`)
+
+// ruleRe matches a trailing "(rule)" as printed by golangci-lint.
+var ruleRe = regexp.MustCompile(`\s+\(([\w-]+)\)$`)
+
+func parseLines(output string) []Finding {
+	var findings []Finding
+	for _, line := range strings.Split(output, "\n") {
+		m := lineRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		f := Finding{File: m[1], Message: m[4]}
+		f.Line, _ = strconv.Atoi(m[2])
+		f.Column, _ = strconv.Atoi(m[3])
+		if r := ruleRe.FindStringSubmatch(f.Message); r != nil {
+			f.Rule = r[1]
+			f.Message = strings.TrimSuffix(f.Message, r[0])
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// Batch is a group of findings fixed in one iteration.
+type Batch struct {
+	Dir      string
+	Findings []Finding
+}
+
+// Batches groups findings by directory (a Go package, or a folder of
+// source files), splitting directories with more than max findings.
+// Batches are ordered by directory, and findings by file and line.
+func Batches(findings []Finding, max int) []Batch {
+	if max < 1 {
+		max = 1
+	}
+
+	sorted := append([]Finding(nil), findings...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].File != sorted[j].File {
+			return sorted[i].File < sorted[j].File
+		}
+		return sorted[i].Line < sorted[j].Line
+	})
+
+	var batches []Batch
+	for _, f := range sorted {
+		dir := filepath.Dir(f.File)
+		last := len(batches) - 1
+		if last < 0 || batches[last].Dir != dir || len(batches[last].Findings) >= max {
+			batches = append(batches, Batch{Dir: dir})
+			last++
+		}
+		batches[last].Findings = append(batches[last].Findings, f)
+	}
+	return batches
+}
+
+// Title summarizes a batch for a commit or PR title.
+func (b Batch) Title() string {
+	return fmt.Sprintf("Fix %d lint finding(s) in %s", len(b.Findings), b.Dir)
+}
+
+// Goal describes the batch for Claude's prompt. commands are the linters
+// to re-run; instructions, if non-empty, are the user's own additions.
+func (b Batch) Goal(commands []string, instructions string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Fix these linter findings in %s:\n\n", b.Dir))
+	for _, f := range b.Findings {
+		sb.WriteString(fmt.Sprintf("- %s\n", f))
+	}
+
+	sb.WriteString("\nFix the cause rather than silencing the linter, and don't change behavior. ")
+	sb.WriteString("Only add a suppression comment for a false positive, with the reason. ")
+	sb.WriteString("Leave findings outside this list for later iterations. ")
+	sb.WriteString("Check your fixes by re-running: ")
+	for i, c := range commands {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("`" + c + "`")
+	}
+	sb.WriteString(".")
+
+	if strings.TrimSpace(instructions) != "" {
+		sb.WriteString("\n\nAdditional instructions:\n")
+		sb.WriteString(strings.TrimSpace(instructions))
+	}
+	return sb.String()
+}
@@ -0,0 +1,115 @@
+package lint
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLines(t *testing.T) {
+	output := `internal/git/git.go:42:9: Error return value of ` + "`cmd.Run`" + ` is not checked (errcheck)
+internal/ui/ui.go:10: exported function Foo should have comment
+level=warning msg="[runner] deprecated"
+2 issues.`
+
+	findings := Parse("/repo", output)
+	if len(findings) != 2 {
+		t.Fatalf("Parse() returned %d findings, want 2: %+v", len(findings), findings)
+	}
+	if f := findings[0]; f.File != "internal/git/git.go" || f.Line != 42 || f.Column != 9 || f.Rule != "errcheck" {
+		t.Errorf("first finding = %+v", f)
+	}
+	if f := findings[1]; f.File != "internal/ui/ui.go" || f.Line != 10 || f.Column != 0 || f.Rule != "" {
+		t.Errorf("second finding = %+v", f)
+	}
+}
+
+func TestParseESLint(t *testing.T) {
+	output := `[{"filePath":"/repo/src/app.js","messages":[{"ruleId":"no-unused-vars","message":"'x' is unused","line":3,"column":7}]},
+{"filePath":"/repo/src/ok.js","messages":[]}]`
+
+	findings := Parse("/repo", output)
+	if len(findings) != 1 {
+		t.Fatalf("Parse() returned %d findings, want 1", len(findings))
+	}
+	if f := findings[0]; f.File != "src/app.js" || f.Rule != "no-unused-vars" || f.Line != 3 {
+		t.Errorf("finding = %+v", f)
+	}
+}
+
+func TestParseGolangCI(t *testing.T) {
+	output := `{"Issues":[{"FromLinter":"govet","Text":"unreachable code","Pos":{"Filename":"main.go","Line":5,"Column":2}}],"Report":{}}
+0 issues.`
+
+	findings := Parse("/repo", output)
+	if len(findings) != 1 || findings[0].Rule != "govet" || findings[0].File != "main.go" {
+		t.Errorf("Parse() = %+v", findings)
+	}
+}
+
+func TestBatches(t *testing.T) {
+	findings := []Finding{
+		{File: "b/y.go", Line: 1},
+		{File: "a/x.go", Line: 9},
+		{File: "a/x.go", Line: 2},
+		{File: "a/w.go", Line: 5},
+		{File: "b/z.go", Line: 1},
+	}
+
+	batches := Batches(findings, 2)
+	if len(batches) != 3 {
+		t.Fatalf("Batches() returned %d batches, want 3: %+v", len(batches), batches)
+	}
+	if batches[0].Dir != "a" || batches[0].Findings[0].File != "a/w.go" || batches[0].Findings[1].Line != 2 {
+		t.Errorf("first batch = %+v", batches[0])
+	}
+	if batches[1].Dir != "a" || len(batches[1].Findings) != 1 {
+		t.Errorf("second batch = %+v, want the overflow of a/", batches[1])
+	}
+	if batches[2].Dir != "b" || len(batches[2].Findings) != 2 {
+		t.Errorf("third batch = %+v", batches[2])
+	}
+}
+
+func TestBatchGoal(t *testing.T) {
+	b := Batch{Dir: "internal/git", Findings: []Finding{{File: "internal/git/git.go", Line: 42, Rule: "errcheck", Message: "unchecked error"}}}
+
+	got := b.Goal([]string{"golangci-lint run ./..."}, "Prefer returning errors over logging")
+	for _, want := range []string{"internal/git/git.go:42: unchecked error (errcheck)", "`golangci-lint run ./...`", "Prefer returning errors"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Goal() missing %q:\n%s", want, got)
+		}
+	}
+	if b.Title() != "Fix 1 lint finding(s) in internal/git" {
+		t.Errorf("Title() = %q", b.Title())
+	}
+}
+
+func TestDefaultCommands(t *testing.T) {
+	dir := t.TempDir()
+	if got := DefaultCommands(dir); len(got) != 0 {
+		t.Errorf("DefaultCommands() of empty dir = %v, want none", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := DefaultCommands(dir); len(got) != 1 || !strings.HasPrefix(got[0], "golangci-lint") {
+		t.Errorf("DefaultCommands() = %v, want golangci-lint", got)
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if _, err := Run(ctx, t.TempDir(), "exec sleep 10"); err == nil {
+		t.Error("Run() of a canceled linter succeeded")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Run() took %s after its context ended", elapsed)
+	}
+}
@@ -115,6 +115,90 @@ func (m *Manager) Validate() error {
 	return nil
 }
 
+// Section returns the body of the notes' "## title" section, and whether
+// they have one. Sections like it hold progress deep-claude tracks in a
+// structured form, such as a checklist, where Claude reads it with the
+// rest of the notes and can update it.
+func (m *Manager) Section(title string) (string, bool, error) {
+	content, err := m.Read()
+	if err != nil {
+		return "", false, err
+	}
+	body, ok := section(content, title)
+	return body, ok, nil
+}
+
+// SetSection replaces the body of the notes' "## title" section, adding
+// the section before the closing rule of the notes if they have none.
+func (m *Manager) SetSection(title, body string) error {
+	content, err := m.Read()
+	if err != nil {
+		return err
+	}
+	return m.Write(setSection(content, title, body))
+}
+
+// sectionBounds returns the lines of content and where the "## title"
+// section's heading and end are; start is -1 without one. A section ends
+// at the next heading of its level or above, or at a rule.
+func sectionBounds(content, title string) (lines []string, start, end int) {
+	lines = strings.Split(content, "\n")
+	start, end = -1, len(lines)
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if start < 0 {
+			if line == "## "+title {
+				start = i
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "# ") || strings.HasPrefix(line, "## ") || line == "---" {
+			end = i
+			break
+		}
+	}
+	return lines, start, end
+}
+
+func section(content, title string) (string, bool) {
+	lines, start, end := sectionBounds(content, title)
+	if start < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(strings.Join(lines[start+1:end], "\n")), true
+}
+
+func setSection(content, title, body string) string {
+	lines, start, end := sectionBounds(content, title)
+	if start < 0 {
+		// A new section goes before the rule the notes close with, if any
+		start = len(lines)
+		for i := len(lines) - 1; i >= 0; i-- {
+			line := strings.TrimSpace(lines[i])
+			if line == "---" {
+				start = i
+				break
+			}
+			if strings.HasPrefix(line, "#") {
+				break
+			}
+		}
+		end = start
+	}
+
+	before := lines[:start]
+	for len(before) > 0 && strings.TrimSpace(before[len(before)-1]) == "" {
+		before = before[:len(before)-1]
+	}
+	out := append([]string{}, before...)
+	if len(out) > 0 {
+		out = append(out, "")
+	}
+	out = append(out, "## "+title, "", strings.TrimSpace(body), "")
+	out = append(out, lines[end:]...)
+	return strings.TrimRight(strings.Join(out, "\n"), "\n") + "\n"
+}
+
 // AppendIteration adds iteration summary to the notes.
 func (m *Manager) AppendIteration(iteration int, summary string) error {
 	content, err := m.Read()
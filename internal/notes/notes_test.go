@@ -0,0 +1,44 @@
+package notes
+
+import "testing"
+
+func TestSetSection(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "replaces the section",
+			content: "# Notes\n\n## Test coverage\n\nold\n\n## Next Steps\n- more\n",
+			want:    "# Notes\n\n## Test coverage\n\nnew\n\n## Next Steps\n- more\n",
+		},
+		{
+			name:    "adds it before the closing rule",
+			content: "# Notes\n\n## Next Steps\n- more\n\n---\n*footer*\n",
+			want:    "# Notes\n\n## Next Steps\n- more\n\n## Test coverage\n\nnew\n\n---\n*footer*\n",
+		},
+		{
+			name:    "adds it at the end",
+			content: "# Notes\n\n---\n\n## Iteration 1 Summary\n\ndone\n",
+			want:    "# Notes\n\n---\n\n## Iteration 1 Summary\n\ndone\n\n## Test coverage\n\nnew\n",
+		},
+		{
+			name:    "to empty notes",
+			content: "",
+			want:    "## Test coverage\n\nnew\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := setSection(tt.content, "Test coverage", "new\n")
+			if got != tt.want {
+				t.Errorf("setSection() = %q, want %q", got, tt.want)
+			}
+			if body, ok := section(got, "Test coverage"); !ok || body != "new" {
+				t.Errorf("section() after setSection() = %q, %v, want new", body, ok)
+			}
+		})
+	}
+}
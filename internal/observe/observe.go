@@ -0,0 +1,130 @@
+// Package observe serves a read-only live view of a session over HTTP, so
+// someone without access to its tmux server can watch it in a browser.
+package observe
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// refreshMillis is how often the page polls for the pane and new events.
+const refreshMillis = 2000
+
+// Source is the session a view shows.
+type Source struct {
+	Session string
+	// Pane returns what the session's terminal shows, with its scrollback
+	Pane func() (string, error)
+	// Events is the path of the run's events.jsonl, "" if it isn't known
+	Events string
+}
+
+// Handler returns the view's routes: the page at /, the terminal as text
+// at /pane and the run's events as JSON at /events. /events?after=n only
+// returns the events after the first n. Nothing but GET is accepted, so
+// the view can't be used to change the session.
+func Handler(src Source) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = page.Execute(w, map[string]any{"Session": src.Session, "Refresh": refreshMillis})
+	})
+	mux.HandleFunc("GET /pane", func(w http.ResponseWriter, r *http.Request) {
+		text, err := src.Pane()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(text))
+	})
+	mux.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		list, err := readEvents(src.Events)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if after, err := strconv.Atoi(r.URL.Query().Get("after")); err == nil && after > 0 {
+			list = list[min(after, len(list)):]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+	})
+	return mux
+}
+
+// readEvents reads the event log, which a run that hasn't started yet may
+// not have written.
+func readEvents(path string) ([]events.Event, error) {
+	if path == "" {
+		return []events.Event{}, nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return []events.Event{}, nil
+	}
+	list, err := events.Read(path, "")
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []events.Event{}
+	}
+	return list, nil
+}
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Session}} - Deep Claude</title>
+<style>
+body { margin: 0; font-family: sans-serif; background: #111; color: #ddd; display: flex; height: 100vh; }
+main { flex: 3; overflow: auto; padding: 1em; }
+aside { flex: 1; overflow: auto; padding: 1em; border-left: 1px solid #333; font-size: 0.85em; }
+pre { margin: 0; font-family: monospace; white-space: pre-wrap; }
+h1 { font-size: 1em; margin: 0 0 1em; color: #888; }
+li { margin-bottom: 0.4em; }
+time { color: #888; }
+</style>
+</head>
+<body>
+<main><h1>{{.Session}} (read-only)</h1><pre id="pane"></pre></main>
+<aside><h1>Events</h1><ol id="events"></ol></aside>
+<script>
+const pane = document.getElementById("pane");
+const list = document.getElementById("events");
+let seen = 0;
+
+async function refresh() {
+  try {
+    const res = await fetch("pane");
+    const text = await res.text();
+    const atBottom = pane.parentNode.scrollTop + pane.parentNode.clientHeight >= pane.parentNode.scrollHeight - 10;
+    pane.textContent = text;
+    if (atBottom) pane.parentNode.scrollTop = pane.parentNode.scrollHeight;
+
+    const added = await (await fetch("events?after=" + seen)).json();
+    for (const e of added) {
+      const item = document.createElement("li");
+      const time = document.createElement("time");
+      time.textContent = new Date(e.time).toLocaleTimeString() + " ";
+      item.append(time, e.type + (e.iteration ? " #" + e.iteration : ""));
+      list.append(item);
+    }
+    seen += added.length;
+  } catch (err) {
+    pane.textContent += "\n[connection lost: " + err + "]";
+  }
+}
+
+refresh();
+setInterval(refresh, {{.Refresh}});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,87 @@
+package observe
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+func TestHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), events.FileName)
+	log, err := events.Create(path, "20250115-143000-ab12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Emit(events.RunStarted, 0, nil)
+	log.Emit(events.IterationStarted, 1, nil)
+	log.Emit(events.ClaudeFinished, 1, nil)
+	_ = log.Close()
+
+	handler := Handler(Source{
+		Session: "dc-20250115-143000-ab12-add-tests",
+		Pane:    func() (string, error) { return "Starting iteration #1\n", nil },
+		Events:  path,
+	})
+
+	tests := []struct {
+		method, target string
+		status         int
+		contains       string
+		events         int
+	}{
+		{"GET", "/", http.StatusOK, "dc-20250115-143000-ab12-add-tests", -1},
+		{"GET", "/pane", http.StatusOK, "Starting iteration #1", -1},
+		{"GET", "/events", http.StatusOK, "", 3},
+		{"GET", "/events?after=2", http.StatusOK, "claude_finished", 1},
+		{"GET", "/events?after=5", http.StatusOK, "", 0},
+		{"POST", "/pane", http.StatusMethodNotAllowed, "", -1},
+		{"GET", "/kill", http.StatusNotFound, "", -1},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s status = %d, want %d", tt.method, tt.target, rec.Code, tt.status)
+			continue
+		}
+		if !strings.Contains(rec.Body.String(), tt.contains) {
+			t.Errorf("%s %s body = %q, want it to contain %q", tt.method, tt.target, rec.Body.String(), tt.contains)
+		}
+		if tt.events >= 0 {
+			var list []events.Event
+			if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+				t.Fatalf("%s %s: %v", tt.method, tt.target, err)
+			}
+			if len(list) != tt.events {
+				t.Errorf("%s %s returned %d events, want %d", tt.method, tt.target, len(list), tt.events)
+			}
+		}
+	}
+}
+
+func TestHandlerWithoutEvents(t *testing.T) {
+	handler := Handler(Source{
+		Session: "dc-manual",
+		Pane:    func() (string, error) { return "", errors.New("session 'dc-manual' does not exist") },
+		Events:  filepath.Join(t.TempDir(), events.FileName),
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/events", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("events = %d %q, want an empty list", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/pane", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("pane of an ended session = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,13 @@
+// Package process tells whether a process recorded in a lock or PID file
+// is still running, so files left by one that exited can be taken over.
+package process
+
+// Alive reports whether a process with the given PID is running. A
+// process that exists but can't be signalled or opened counts as
+// running.
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	return alive(pid)
+}
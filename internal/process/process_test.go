@@ -0,0 +1,23 @@
+package process
+
+import (
+	"os"
+	"testing"
+)
+
+func TestAlive(t *testing.T) {
+	tests := []struct {
+		name string
+		pid  int
+		want bool
+	}{
+		{"this process", os.Getpid(), true},
+		{"no such process", 999999999, false},
+		{"invalid pid", 0, false},
+	}
+	for _, tt := range tests {
+		if got := Alive(tt.pid); got != tt.want {
+			t.Errorf("Alive(%d) for %s = %v, want %v", tt.pid, tt.name, got, tt.want)
+		}
+	}
+}
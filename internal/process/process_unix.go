@@ -0,0 +1,9 @@
+//go:build !windows
+
+package process
+
+import "syscall"
+
+func alive(pid int) bool {
+	return syscall.Kill(pid, 0) != syscall.ESRCH
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+package process
+
+import "syscall"
+
+const (
+	// processQueryLimitedInformation is the least access that allows
+	// reading a process's exit code.
+	processQueryLimitedInformation = 0x1000
+	// stillActive is the exit code of a process that hasn't exited.
+	stillActive = 259
+)
+
+func alive(pid int) bool {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return err == syscall.ERROR_ACCESS_DENIED
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
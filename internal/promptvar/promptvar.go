@@ -0,0 +1,132 @@
+// Package promptvar fills {{name}} placeholders in prompts with values
+// given on the command line, read from a JSON file or printed by a command.
+package promptvar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholderRe matches a {{name}} placeholder, allowing spaces inside the
+// braces, or the {{{{ escape of a literal {{.
+var placeholderRe = regexp.MustCompile(`\{\{\{\{|\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// nameRe matches a valid variable name.
+var nameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// Parse reads name=value assignments, such as --prompt-var values.
+func Parse(assignments []string) (map[string]string, error) {
+	vars := make(map[string]string, len(assignments))
+	for _, a := range assignments {
+		name, value, err := split(a)
+		if err != nil {
+			return nil, err
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// LoadFile reads variables from a JSON object. Strings are used as they
+// are; numbers, booleans, arrays and objects as their JSON text.
+func LoadFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt variables: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt variables in %s: %w", path, err)
+	}
+
+	vars := make(map[string]string, len(raw))
+	for name, value := range raw {
+		if !nameRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid prompt variable name %q in %s", name, path)
+		}
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			vars[name] = s
+		} else {
+			vars[name] = string(value)
+		}
+	}
+	return vars, nil
+}
+
+// Run sets variables to the output of commands, given as name=command
+// assignments run with sh in dir. Trailing newlines are dropped.
+func Run(ctx context.Context, dir string, assignments []string) (map[string]string, error) {
+	vars := make(map[string]string, len(assignments))
+	for _, a := range assignments {
+		name, command, err := split(a)
+		if err != nil {
+			return nil, err
+		}
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Dir = dir
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to run %q for prompt variable %s: %w\n%s", command, name, err, stderr.String())
+		}
+		vars[name] = strings.TrimRight(string(output), "\r\n")
+	}
+	return vars, nil
+}
+
+// Merge combines sets of variables; later sets win.
+func Merge(sets ...map[string]string) map[string]string {
+	vars := make(map[string]string)
+	for _, set := range sets {
+		for name, value := range set {
+			vars[name] = value
+		}
+	}
+	return vars
+}
+
+// Expand replaces the {{name}} placeholders in text with their values, and
+// {{{{ with a literal {{, so {{{{name}} is left as {{name}}. It fails if any
+// placeholder has no value, naming them all.
+func Expand(text string, vars map[string]string) (string, error) {
+	missing := make(map[string]bool)
+	expanded := placeholderRe.ReplaceAllStringFunc(text, func(m string) string {
+		if m == "{{{{" {
+			return "{{"
+		}
+		name := placeholderRe.FindStringSubmatch(m)[1]
+		value, ok := vars[name]
+		if !ok {
+			missing[name] = true
+			return m
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("prompt variable(s) without a value: %s", strings.Join(names, ", "))
+	}
+	return expanded, nil
+}
+
+// split splits a name=value assignment.
+func split(assignment string) (string, string, error) {
+	name, value, ok := strings.Cut(assignment, "=")
+	name = strings.TrimSpace(name)
+	if !ok || !nameRe.MatchString(name) {
+		return "", "", fmt.Errorf("invalid prompt variable %q, want name=value", assignment)
+	}
+	return name, value, nil
+}
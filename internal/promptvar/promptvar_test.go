@@ -0,0 +1,93 @@
+package promptvar
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	vars, err := Parse([]string{"version=1.2.3", "note=a=b", "empty="})
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if want := map[string]string{"version": "1.2.3", "note": "a=b", "empty": ""}; !reflect.DeepEqual(vars, want) {
+		t.Errorf("Parse() = %v, want %v", vars, want)
+	}
+
+	for _, bad := range []string{"version", "=1.2.3", "1st=x", "a b=c"} {
+		if _, err := Parse([]string{bad}); err == nil {
+			t.Errorf("Parse(%q) should fail", bad)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.json")
+	content := `{"version": "1.2.3", "build": 42, "draft": false, "authors": ["ann", "bo"]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	vars, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() unexpected error: %v", err)
+	}
+	want := map[string]string{"version": "1.2.3", "build": "42", "draft": "false", "authors": `["ann", "bo"]`}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("LoadFile() = %v, want %v", vars, want)
+	}
+
+	if err := os.WriteFile(path, []byte(`["not", "an", "object"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("LoadFile() should fail on a JSON array")
+	}
+}
+
+func TestRun(t *testing.T) {
+	vars, err := Run(t.Context(), t.TempDir(), []string{"greeting=echo hello", "dir=basename \"$PWD\""})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if vars["greeting"] != "hello" || vars["dir"] == "" || strings.Contains(vars["dir"], "\n") {
+		t.Errorf("Run() = %q", vars)
+	}
+
+	if _, err := Run(t.Context(), t.TempDir(), []string{"tag=echo oops >&2; exit 3"}); err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("Run() of a failing command = %v, want its stderr", err)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	vars := Merge(map[string]string{"version": "1.0.0", "repo": "widgets"}, map[string]string{"version": "1.2.3"})
+
+	tests := []struct {
+		text    string
+		want    string
+		wantErr string
+	}{
+		{"Write release notes for {{version}} of {{ repo }}.", "Write release notes for 1.2.3 of widgets.", ""},
+		{"Use map[string]{} and ${HOME} as they are", "Use map[string]{} and ${HOME} as they are", ""},
+		{"{{since}} to {{version}}, by {{author}}", "", "author, since"},
+		{"Render {{{{name}} in the {{version}} template", "Render {{name}} in the 1.2.3 template", ""},
+		{"{{{{{{{{ and {{{{ version }}", "{{{{ and {{ version }}", ""},
+		{"{{{version}}}", "{1.2.3}", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := Expand(tt.text, vars)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expand(%q) error = %v, want one naming %s", tt.text, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Expand(%q) = %q, %v, want %q", tt.text, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Package recipe provides bundles of a tuned prompt and flag defaults for
+// routine tasks, selected with --recipe.
+package recipe
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed builtin/*.yaml
+var builtinFS embed.FS
+
+// Recipe is a named prompt plus flag values.
+type Recipe struct {
+	Name        string            `yaml:"-"`
+	Description string            `yaml:"description"`
+	Prompt      string            `yaml:"prompt"`
+	Flags       map[string]string `yaml:"flags"`
+	// Vars are defaults for the prompt's {{name}} variables
+	Vars map[string]string `yaml:"vars"`
+	// Source is "built-in" or the file the recipe was read from
+	Source string `yaml:"-"`
+}
+
+// UserDir returns the directory holding user-defined recipes:
+// $XDG_CONFIG_HOME/deep-claude/recipes, or the OS equivalent.
+func UserDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "deep-claude", "recipes"), nil
+}
+
+// Parse decodes a recipe from YAML.
+func Parse(name string, data []byte) (*Recipe, error) {
+	var r Recipe
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse recipe %s: %w", name, err)
+	}
+	if strings.TrimSpace(r.Prompt) == "" {
+		return nil, fmt.Errorf("recipe %s has no prompt", name)
+	}
+	r.Name = name
+	r.Prompt = strings.TrimSpace(r.Prompt)
+	return &r, nil
+}
+
+// Load returns the named recipe. A user-defined recipe in dir overrides a
+// built-in one of the same name.
+func Load(dir, name string) (*Recipe, error) {
+	if dir != "" {
+		path := filepath.Join(dir, name+".yaml")
+		data, err := os.ReadFile(path)
+		if err == nil {
+			r, err := Parse(name, data)
+			if err != nil {
+				return nil, err
+			}
+			r.Source = path
+			return r, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read recipe %s: %w", path, err)
+		}
+	}
+
+	data, err := builtinFS.ReadFile("builtin/" + name + ".yaml")
+	if err != nil {
+		names, _ := List(dir)
+		var known []string
+		for _, r := range names {
+			known = append(known, r.Name)
+		}
+		return nil, fmt.Errorf("unknown recipe %q (available: %s)", name, strings.Join(known, ", "))
+	}
+	r, err := Parse(name, data)
+	if err != nil {
+		return nil, err
+	}
+	r.Source = "built-in"
+	return r, nil
+}
+
+// List returns the built-in and user-defined recipes, sorted by name.
+// Recipes that fail to parse are skipped.
+func List(dir string) ([]*Recipe, error) {
+	byName := make(map[string]*Recipe)
+
+	entries, _ := builtinFS.ReadDir("builtin")
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".yaml")
+		if r, err := Load("", name); err == nil {
+			byName[name] = r
+		}
+	}
+
+	if dir != "" {
+		files, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read recipes directory: %w", err)
+		}
+		for _, f := range files {
+			if f.IsDir() || filepath.Ext(f.Name()) != ".yaml" {
+				continue
+			}
+			name := strings.TrimSuffix(f.Name(), ".yaml")
+			if r, err := Load(dir, name); err == nil {
+				byName[name] = r
+			}
+		}
+	}
+
+	recipes := make([]*Recipe, 0, len(byName))
+	for _, r := range byName {
+		recipes = append(recipes, r)
+	}
+	sort.Slice(recipes, func(i, j int) bool { return recipes[i].Name < recipes[j].Name })
+	return recipes, nil
+}
+
+// BuildPrompt combines the recipe's prompt with the user's own -p text, if
+// any, which narrows or adds to the task.
+func (r *Recipe) BuildPrompt(extra string) string {
+	extra = strings.TrimSpace(extra)
+	if extra == "" {
+		return r.Prompt
+	}
+	return r.Prompt + "\n\nAdditional instructions:\n" + extra
+}
@@ -0,0 +1,79 @@
+package recipe
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinRecipes(t *testing.T) {
+	for _, name := range []string{"add-tests", "fix-lint", "raise-coverage", "upgrade-deps"} {
+		r, err := Load("", name)
+		if err != nil {
+			t.Errorf("Load(%q) unexpected error: %v", name, err)
+			continue
+		}
+		if r.Description == "" || r.Source != "built-in" {
+			t.Errorf("Load(%q) = %+v, want a described built-in recipe", name, r)
+		}
+		if r.Flags["max-runs"] == "" {
+			t.Errorf("recipe %q should set a run limit", name)
+		}
+	}
+}
+
+func TestLoadUserRecipe(t *testing.T) {
+	dir := t.TempDir()
+	content := "description: Team chores\nprompt: Tidy up the docs for {{version}}\nflags:\n  max-runs: 3\n  dry-run: true\nvars:\n  version: v2\n"
+	if err := os.WriteFile(filepath.Join(dir, "docs.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fix-lint.yaml"), []byte("prompt: Our own lint recipe\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Load(dir, "docs")
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if r.Prompt != "Tidy up the docs for {{version}}" || r.Flags["max-runs"] != "3" || r.Flags["dry-run"] != "true" || r.Vars["version"] != "v2" {
+		t.Errorf("Load() = %+v", r)
+	}
+
+	if r, _ := Load(dir, "fix-lint"); r == nil || r.Prompt != "Our own lint recipe" {
+		t.Error("user recipe should override the built-in one")
+	}
+
+	if _, err := Load(dir, "missing"); err == nil || !strings.Contains(err.Error(), "add-tests") {
+		t.Errorf("Load() of unknown recipe = %v, want error listing available recipes", err)
+	}
+
+	recipes, err := List(dir)
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	var names []string
+	for _, r := range recipes {
+		names = append(names, r.Name)
+	}
+	if got := strings.Join(names, ","); got != "add-tests,docs,fix-lint,raise-coverage,upgrade-deps" {
+		t.Errorf("List() = %s", got)
+	}
+}
+
+func TestParseRequiresPrompt(t *testing.T) {
+	if _, err := Parse("empty", []byte("description: nothing\n")); err == nil {
+		t.Error("Parse() expected error for recipe without prompt")
+	}
+}
+
+func TestBuildPrompt(t *testing.T) {
+	r := &Recipe{Prompt: "Add tests"}
+	if got := r.BuildPrompt(""); got != "Add tests" {
+		t.Errorf("BuildPrompt(\"\") = %q", got)
+	}
+	if got := r.BuildPrompt("Focus on internal/auth"); !strings.HasSuffix(got, "Additional instructions:\nFocus on internal/auth") {
+		t.Errorf("BuildPrompt() = %q", got)
+	}
+}
@@ -0,0 +1,141 @@
+// Package redact scrubs secrets from text before it is printed, logged or
+// published: tokens in well-known formats, and the values of environment
+// variables that hold secrets.
+package redact
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Mask replaces each secret.
+const Mask = "[REDACTED]"
+
+// minValueLen is the shortest environment value redacted; shorter ones
+// match too much ordinary text to be secrets worth hiding.
+const minValueLen = 8
+
+// DefaultEnv are the variables whose values are always redacted: the
+// credentials Deep Claude, Claude Code and gh authenticate with.
+var DefaultEnv = []string{"ANTHROPIC_API_KEY", "ANTHROPIC_AUTH_TOKEN", "CLAUDE_CODE_OAUTH_TOKEN", "GH_TOKEN", "GITHUB_TOKEN"}
+
+// secretSuffixes mark environment variables as holding secrets by name.
+var secretSuffixes = []string{"_TOKEN", "_SECRET", "_PASSWORD", "_API_KEY", "_ACCESS_KEY", "_PRIVATE_KEY"}
+
+// patterns match tokens by their format.
+var patterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{40,}\b`),
+	regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9]{32,}\b`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`),
+	regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9._~+/-]{20,}=*`),
+}
+
+// Redactor scrubs secrets from text. A nil Redactor leaves text as it is.
+type Redactor struct {
+	// values are the secret values, longest first so one that contains
+	// another is masked whole
+	values []string
+}
+
+// New returns a Redactor for the values, as currently set, of DefaultEnv,
+// of the variables whose names mark them as secrets and of env.
+func New(env []string) *Redactor {
+	names := make(map[string]bool)
+	for _, name := range append(append([]string{}, DefaultEnv...), env...) {
+		names[name] = true
+	}
+	for _, kv := range os.Environ() {
+		if name, _, _ := strings.Cut(kv, "="); IsSecret(name, nil) {
+			names[name] = true
+		}
+	}
+
+	r := &Redactor{}
+	seen := make(map[string]bool)
+	for name := range names {
+		value := strings.TrimSpace(os.Getenv(name))
+		if len(value) >= minValueLen && !seen[value] {
+			seen[value] = true
+			r.values = append(r.values, value)
+		}
+	}
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+	return r
+}
+
+// IsSecret tells whether an environment variable holds a secret: it is one
+// of DefaultEnv or env, or its name marks it as one.
+func IsSecret(name string, env []string) bool {
+	if slices.Contains(DefaultEnv, name) || slices.Contains(env, name) {
+		return true
+	}
+	for _, suffix := range secretSuffixes {
+		if strings.HasSuffix(strings.ToUpper(name), suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns s with its secrets masked.
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, value := range r.values {
+		s = strings.ReplaceAll(s, value, Mask)
+	}
+	for _, re := range patterns {
+		s = re.ReplaceAllStringFunc(s, func(m string) string {
+			// Keep the "Bearer " of an authorization header
+			if sub := re.FindStringSubmatch(m); len(sub) > 1 {
+				return sub[1] + Mask
+			}
+			return Mask
+		})
+	}
+	return s
+}
+
+// Data returns a copy of event or audit data with the secrets in its
+// strings masked, including those in nested maps and lists.
+func (r *Redactor) Data(data map[string]any) map[string]any {
+	if r == nil || data == nil {
+		return data
+	}
+	clean := make(map[string]any, len(data))
+	for k, v := range data {
+		clean[k] = r.value(v)
+	}
+	return clean
+}
+
+func (r *Redactor) value(v any) any {
+	switch v := v.(type) {
+	case string:
+		return r.String(v)
+	case []string:
+		clean := make([]string, len(v))
+		for i, s := range v {
+			clean[i] = r.String(s)
+		}
+		return clean
+	case []any:
+		clean := make([]any, len(v))
+		for i, e := range v {
+			clean[i] = r.value(e)
+		}
+		return clean
+	case map[string]any:
+		return r.Data(v)
+	}
+	return v
+}
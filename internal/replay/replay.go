@@ -0,0 +1,210 @@
+// Package replay re-renders a recorded run from its event log and transcripts.
+package replay
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// TranscriptsDir is the run subdirectory holding Claude's output per iteration.
+const TranscriptsDir = "transcripts"
+
+// maxDelay caps the pause between two events so long CI waits don't stall
+// a replay.
+const maxDelay = 3 * time.Second
+
+// Options controls how a run is replayed.
+type Options struct {
+	// Speed divides the real time between events; 0 replays instantly.
+	Speed float64
+	// TranscriptLimit truncates transcripts to this many characters; 0 shows them in full.
+	TranscriptLimit int
+}
+
+// Replay renders the events of the run stored in runDir.
+func Replay(p ui.Printer, runDir string, opts Options) error {
+	list, err := events.Read(filepath.Join(runDir, events.FileName), "")
+	if err != nil {
+		return err
+	}
+
+	for i, e := range list {
+		if i > 0 {
+			time.Sleep(Delay(list[i-1].Time, e.Time, opts.Speed))
+		}
+		render(p, runDir, e, opts)
+	}
+	return nil
+}
+
+// Delay returns how long to pause between events recorded at prev and next.
+func Delay(prev, next time.Time, speed float64) time.Duration {
+	if speed <= 0 || !next.After(prev) {
+		return 0
+	}
+	d := time.Duration(float64(next.Sub(prev)) / speed)
+	if d > maxDelay {
+		return maxDelay
+	}
+	return d
+}
+
+func render(p ui.Printer, runDir string, e events.Event, opts Options) {
+	stamp := e.Time.Local().Format("15:04:05")
+
+	switch e.Type {
+	case events.RunStarted:
+		p.Header("Replay: " + filepath.Base(runDir))
+		p.Info("[%s] Goal: %s", stamp, str(e.Data, "prompt"))
+		p.Info("Repository: %s/%s (base %s)", str(e.Data, "owner"), str(e.Data, "repo"), str(e.Data, "base_branch"))
+	case events.IterationStarted:
+		p.Iteration(e.Iteration, 0)
+		p.Info("[%s] Branch: %s", stamp, str(e.Data, "branch"))
+	case events.ClaudeFinished:
+		p.Cost(num(e.Data, "cost"), num(e.Data, "total_cost"))
+		if name := str(e.Data, "transcript"); name != "" {
+			renderTranscript(p, filepath.Join(runDir, TranscriptsDir, name), opts.TranscriptLimit)
+		}
+		if boolean(e.Data, "complete") {
+			p.Info("Completion signal detected")
+		}
+	case events.CommitCreated:
+		p.Success("[%s] Committed: %s", stamp, str(e.Data, "title"))
+		p.DiffStat(int(num(e.Data, "files_changed")), int(num(e.Data, "insertions")), int(num(e.Data, "deletions")))
+	case events.PushDeferred:
+		p.Warning("[%s] Offline, push of %s deferred (%s queued)", stamp, str(e.Data, "branch"), str(e.Data, "queued"))
+	case events.PatchRecorded:
+		p.Success("[%s] Recorded patch instead of pushing: %s", stamp, str(e.Data, "file"))
+	case events.ResultsRecorded:
+		where := str(e.Data, "url")
+		if where == "" {
+			where = "origin/" + str(e.Data, "branch")
+		}
+		p.Success("[%s] Recorded results: %s", stamp, where)
+	case events.ReportDelivered:
+		for _, where := range []string{str(e.Data, "file"), str(e.Data, "url")} {
+			if where != "" {
+				p.Success("[%s] Report: %s", stamp, where)
+			}
+		}
+	case events.ChecklistUpdated:
+		p.Info("[%s] Migration: %d of %d items done", stamp, int(num(e.Data, "done")), int(num(e.Data, "total")))
+		if claimed := strs(e.Data, "claimed"); len(claimed) > 0 {
+			p.Info("Assigned: %s", strings.Join(claimed, ", "))
+		}
+	case events.PRCreated:
+		p.Success("[%s] Created PR: %s", stamp, str(e.Data, "url"))
+	case events.PRUpdated:
+		p.Success("[%s] Updated existing PR instead of opening a duplicate: %s", stamp, str(e.Data, "url"))
+	case events.PRChecks:
+		p.PRStatus(boolean(e.Data, "passed"), boolean(e.Data, "pending"), boolean(e.Data, "failed"), str(e.Data, "review_decision"))
+	case events.PRMerged:
+		p.Success("[%s] Merged PR #%s", stamp, str(e.Data, "number"))
+	case events.MergePending:
+		p.Info("[%s] PR #%s is waiting for its merge to be approved", stamp, str(e.Data, "number"))
+	case events.PRClosed:
+		p.Error("[%s] Closed PR #%s: %s", stamp, str(e.Data, "number"), str(e.Data, "reason"))
+	case events.IterationFailed:
+		p.Error("[%s] Iteration %d failed: %s", stamp, e.Iteration, str(e.Data, "error"))
+	case events.MainBroken:
+		p.Error("[%s] PR #%s broke %s: %s failed", stamp, str(e.Data, "number"), str(e.Data, "base"), str(e.Data, "checks"))
+		if revert := str(e.Data, "revert"); revert != "" {
+			p.Info("Merge undone: %s", revert)
+		}
+	case events.DeployFinished:
+		if boolean(e.Data, "success") {
+			p.Success("[%s] PR #%s deployed", stamp, str(e.Data, "number"))
+		} else {
+			p.Error("[%s] PR #%s merged but %s", stamp, str(e.Data, "number"), str(e.Data, "error"))
+		}
+	case events.CommandReceived:
+		p.Info("[%s] @%s on PR #%s: /deep-claude %s", stamp, str(e.Data, "author"), str(e.Data, "number"), str(e.Data, "command"))
+	case events.ReviewEscalated:
+		p.Warning("[%s] PR #%s still needs review; re-requested it from %s", stamp, str(e.Data, "number"), str(e.Data, "reviewers"))
+	case events.ReviewTimedOut:
+		p.Warning("[%s] Nobody reviewed PR #%s; %s", stamp, str(e.Data, "number"), str(e.Data, "policy"))
+	case events.RunBlocked:
+		p.Warning("[%s] Claude is blocked: %s", stamp, str(e.Data, "reason"))
+	case events.RunUnblocked:
+		p.Success("[%s] Instructions received, resuming", stamp)
+	case events.RunPaused:
+		p.Warning("[%s] Paused", stamp)
+	case events.RunResumed:
+		p.Success("[%s] Resumed", stamp)
+	case events.RunCrashed:
+		if boolean(e.Data, "gave_up") {
+			p.Error("[%s] Crashed (%s); not restarted again", stamp, str(e.Data, "error"))
+		} else {
+			p.Warning("[%s] Crashed (%s); restart %d", stamp, str(e.Data, "error"), int(num(e.Data, "restarts")))
+		}
+	case events.RunFinished:
+		p.Summary(ui.RunSummary{
+			RunID:      e.RunID,
+			Iterations: int(num(e.Data, "iterations")),
+			TotalCost:  num(e.Data, "total_cost"),
+			Elapsed:    time.Duration(num(e.Data, "elapsed_sec") * float64(time.Second)),
+			Completed:  boolean(e.Data, "completed"),
+
+			FilesChanged: int(num(e.Data, "files_changed")),
+			Insertions:   int(num(e.Data, "insertions")),
+			Deletions:    int(num(e.Data, "deletions")),
+		})
+		if reason := str(e.Data, "stop_reason"); reason != "" {
+			p.Info("Stopped: %s", reason)
+		}
+	default:
+		p.Info("[%s] %s %v", stamp, e.Type, e.Data)
+	}
+}
+
+func renderTranscript(p ui.Printer, path string, limit int) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		p.Warning("Transcript unavailable: %v", err)
+		return
+	}
+
+	text := strings.TrimRight(string(content), "\n")
+	if limit > 0 && len(text) > limit {
+		text = text[:limit] + fmt.Sprintf("\n... (%d more characters, use --full to show)", len(text)-limit)
+	}
+	p.Markdown("Claude Output", text)
+}
+
+// str, strs, num and boolean read JSON-decoded event data, returning zero
+// values for missing keys.
+func str(data map[string]any, key string) string {
+	switch v := data[key].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func strs(data map[string]any, key string) []string {
+	list, _ := data[key].([]any)
+	values := make([]string, 0, len(list))
+	for _, v := range list {
+		values = append(values, fmt.Sprint(v))
+	}
+	return values
+}
+
+func num(data map[string]any, key string) float64 {
+	v, _ := data[key].(float64)
+	return v
+}
+
+func boolean(data map[string]any, key string) bool {
+	v, _ := data[key].(bool)
+	return v
+}
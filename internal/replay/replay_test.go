@@ -0,0 +1,31 @@
+package replay
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	start := time.Date(2025, 1, 15, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		gap   time.Duration
+		speed float64
+		want  time.Duration
+	}{
+		{"instant", 10 * time.Second, 0, 0},
+		{"real time", time.Second, 1, time.Second},
+		{"ten times faster", 10 * time.Second, 10, time.Second},
+		{"capped", 10 * time.Minute, 2, maxDelay},
+		{"out of order", -time.Second, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Delay(start, start.Add(tt.gap), tt.speed); got != tt.want {
+				t.Errorf("Delay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
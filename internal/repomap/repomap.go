@@ -0,0 +1,203 @@
+// Package repomap builds a compact overview of a repository for prompts.
+package repomap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxDirs caps how many directories are listed in the tree section.
+const maxDirs = 40
+
+// entryPointNames are files that usually mark where a project starts.
+var entryPointNames = map[string]bool{
+	"main.go":            true,
+	"main.py":            true,
+	"__main__.py":        true,
+	"index.js":           true,
+	"index.ts":           true,
+	"main.rs":            true,
+	"lib.rs":             true,
+	"go.mod":             true,
+	"package.json":       true,
+	"Cargo.toml":         true,
+	"pyproject.toml":     true,
+	"setup.py":           true,
+	"Makefile":           true,
+	"Dockerfile":         true,
+	"docker-compose.yml": true,
+}
+
+// sourceExts are extensions counted when detecting key packages.
+var sourceExts = map[string]bool{
+	".go": true, ".py": true, ".js": true, ".ts": true, ".tsx": true,
+	".rs": true, ".java": true, ".kt": true, ".rb": true, ".c": true,
+	".cc": true, ".cpp": true, ".h": true, ".swift": true, ".cs": true,
+}
+
+// Map is a generated repository map together with the tree shape it was
+// built from, so it can be reused until the tree changes significantly.
+type Map struct {
+	Text      string   `json:"text"`
+	FileCount int      `json:"file_count"`
+	Dirs      []string `json:"dirs"`
+}
+
+// Build generates a repository map from a list of tracked files.
+func Build(files []string) *Map {
+	dirCounts := make(map[string]int)
+	sourceCounts := make(map[string]int)
+	var entryPoints []string
+
+	for _, f := range files {
+		dir := path.Dir(f)
+		dirCounts[dir]++
+		if sourceExts[path.Ext(f)] {
+			sourceCounts[dir]++
+		}
+		if entryPointNames[path.Base(f)] {
+			entryPoints = append(entryPoints, f)
+		}
+	}
+
+	dirs := make([]string, 0, len(dirCounts))
+	for d := range dirCounts {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d tracked files in %d directories.\n\n", len(files), len(dirs)))
+
+	sb.WriteString("Top-level:\n")
+	for _, line := range topLevel(files) {
+		sb.WriteString("- " + line + "\n")
+	}
+
+	var pkgs []string
+	for d := range sourceCounts {
+		pkgs = append(pkgs, d)
+	}
+	sort.Strings(pkgs)
+	if len(pkgs) > 0 {
+		sb.WriteString("\nKey packages (source files):\n")
+		for i, p := range pkgs {
+			if i >= maxDirs {
+				sb.WriteString(fmt.Sprintf("- ... and %d more\n", len(pkgs)-maxDirs))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("- %s (%d)\n", p, sourceCounts[p]))
+		}
+	}
+
+	if len(entryPoints) > 0 {
+		sort.Strings(entryPoints)
+		sb.WriteString("\nEntry points and manifests:\n")
+		for _, e := range entryPoints {
+			sb.WriteString("- " + e + "\n")
+		}
+	}
+
+	return &Map{
+		Text:      sb.String(),
+		FileCount: len(files),
+		Dirs:      dirs,
+	}
+}
+
+// topLevel lists root files and directories, with file counts for directories.
+func topLevel(files []string) []string {
+	counts := make(map[string]int)
+	var rootFiles []string
+	for _, f := range files {
+		if i := strings.Index(f, "/"); i >= 0 {
+			counts[f[:i]+"/"]++
+		} else {
+			rootFiles = append(rootFiles, f)
+		}
+	}
+
+	var lines []string
+	for d, n := range counts {
+		lines = append(lines, fmt.Sprintf("%s (%d files)", d, n))
+	}
+	sort.Strings(lines)
+	sort.Strings(rootFiles)
+	return append(lines, rootFiles...)
+}
+
+// IsStale reports whether files differ enough from the map's tree that it
+// should be regenerated: any directory added or removed, or the file count
+// changing by more than 10%.
+func (m *Map) IsStale(files []string) bool {
+	dirs := make(map[string]bool)
+	for _, f := range files {
+		dirs[path.Dir(f)] = true
+	}
+	if len(dirs) != len(m.Dirs) {
+		return true
+	}
+	for _, d := range m.Dirs {
+		if !dirs[d] {
+			return true
+		}
+	}
+
+	delta := len(files) - m.FileCount
+	if delta < 0 {
+		delta = -delta
+	}
+	return delta*10 > m.FileCount
+}
+
+// Load reads a cached map from path. It returns nil if no cache exists.
+func Load(cachePath string) (*Map, error) {
+	data, err := os.ReadFile(cachePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repo map cache: %w", err)
+	}
+
+	var m Map
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse repo map cache: %w", err)
+	}
+	return &m, nil
+}
+
+// Save writes the map to the cache at path.
+func (m *Map) Save(cachePath string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode repo map: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return fmt.Errorf("failed to create repo map cache directory: %w", err)
+	}
+	if err := os.WriteFile(cachePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write repo map cache: %w", err)
+	}
+	return nil
+}
+
+// LoadOrBuild returns the cached map for files, rebuilding and re-caching it
+// when it is missing or stale. The boolean reports whether it was rebuilt.
+func LoadOrBuild(cachePath string, files []string) (*Map, bool, error) {
+	cached, err := Load(cachePath)
+	if err == nil && cached != nil && !cached.IsStale(files) {
+		return cached, false, nil
+	}
+
+	m := Build(files)
+	if err := m.Save(cachePath); err != nil {
+		return m, true, err
+	}
+	return m, true, nil
+}
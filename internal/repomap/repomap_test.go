@@ -0,0 +1,79 @@
+package repomap
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var sampleFiles = []string{
+	"README.md",
+	"go.mod",
+	"main.go",
+	"internal/cli/cli.go",
+	"internal/git/git.go",
+	"internal/git/git_test.go",
+	"docs/usage.md",
+}
+
+func TestBuild(t *testing.T) {
+	m := Build(sampleFiles)
+
+	for _, want := range []string{
+		"7 tracked files in 4 directories",
+		"- internal/ (3 files)",
+		"- docs/ (1 files)",
+		"- README.md",
+		"- internal/git (2)",
+		"- go.mod",
+		"- main.go",
+	} {
+		if !strings.Contains(m.Text, want) {
+			t.Errorf("Build() text missing %q:\n%s", want, m.Text)
+		}
+	}
+
+	if strings.Contains(m.Text, "- docs (") {
+		t.Error("Build() should not list directories without source files as packages")
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	m := Build(sampleFiles)
+
+	tests := []struct {
+		name  string
+		files []string
+		want  bool
+	}{
+		{"unchanged", sampleFiles, false},
+		{"new directory", append(append([]string{}, sampleFiles...), "pkg/api/api.go"), true},
+		{"removed directory", sampleFiles[:5], true},
+		{"many new files", append(append([]string{}, sampleFiles...), "internal/git/a.go", "internal/git/b.go"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.IsStale(tt.files); got != tt.want {
+				t.Errorf("IsStale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadOrBuild(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "repomap.json")
+
+	_, rebuilt, err := LoadOrBuild(cachePath, sampleFiles)
+	if err != nil || !rebuilt {
+		t.Fatalf("LoadOrBuild() first call = rebuilt %v, err %v; want rebuilt", rebuilt, err)
+	}
+
+	m, rebuilt, err := LoadOrBuild(cachePath, sampleFiles)
+	if err != nil || rebuilt {
+		t.Fatalf("LoadOrBuild() second call = rebuilt %v, err %v; want cached", rebuilt, err)
+	}
+	if m.FileCount != len(sampleFiles) {
+		t.Errorf("cached FileCount = %d, want %d", m.FileCount, len(sampleFiles))
+	}
+}
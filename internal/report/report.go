@@ -0,0 +1,276 @@
+// Package report formats the record of a finished run.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/ledger"
+)
+
+// PR is a pull request opened during the run.
+type PR struct {
+	Iteration int
+	Number    string
+	URL       string
+	Title     string
+	Outcome   string
+}
+
+// Backport is the result of backporting a change to one branch.
+type Backport struct {
+	Branch  string
+	Number  string
+	URL     string
+	Outcome string
+}
+
+// Patch is the commit of an iteration recorded instead of pushed.
+type Patch struct {
+	Iteration    int
+	File         string
+	Title        string
+	FilesChanged int
+	Insertions   int
+	Deletions    int
+	Transcript   string
+}
+
+// Outcomes of an iteration.
+const (
+	IterationSucceeded   = "succeeded"
+	IterationFailed      = "failed"
+	IterationTimedOut    = "timed out"
+	IterationInterrupted = "interrupted"
+)
+
+// Phases of an iteration whose time is tracked. PhaseOther is the time
+// spent outside them, e.g. creating branches and PRs.
+const (
+	PhaseClaude = "claude"
+	PhaseGates  = "gates"
+	PhasePush   = "push"
+	PhaseChecks = "checks"
+	PhaseReview = "review"
+	PhaseMerge  = "merge"
+	PhaseOther  = "other"
+)
+
+// Iteration is how one iteration of the run went. Its cost and time count
+// toward the run's limits whatever the outcome.
+type Iteration struct {
+	Number  int           `json:"number"`
+	Outcome string        `json:"outcome"`
+	Cost    float64       `json:"cost"`
+	Elapsed time.Duration `json:"elapsed"`
+	// Time spent in each phase; the stop conditions checking the
+	// iteration's work count as its gates
+	Phases map[string]time.Duration `json:"phases,omitempty"`
+	// First line of the error a failed iteration ended with
+	Error string `json:"error,omitempty"`
+}
+
+// PhaseTime is the time spent in one phase.
+type PhaseTime struct {
+	Phase string
+	Time  time.Duration
+}
+
+// Phases lists the time spent in each phase, with the rest of elapsed as
+// PhaseOther, longest first.
+func Phases(phases map[string]time.Duration, elapsed time.Duration) []PhaseTime {
+	var times []PhaseTime
+	other := elapsed
+	for phase, d := range phases {
+		times = append(times, PhaseTime{phase, d})
+		other -= d
+	}
+	if other > 0 {
+		times = append(times, PhaseTime{PhaseOther, other})
+	}
+	sort.Slice(times, func(i, j int) bool {
+		if times[i].Time != times[j].Time {
+			return times[i].Time > times[j].Time
+		}
+		return times[i].Phase < times[j].Phase
+	})
+	return times
+}
+
+// TimeByPhase adds up the phases of the iterations.
+func TimeByPhase(iterations []Iteration) []PhaseTime {
+	total := make(map[string]time.Duration)
+	var elapsed time.Duration
+	for _, it := range iterations {
+		for phase, d := range it.Phases {
+			total[phase] += d
+		}
+		elapsed += it.Elapsed
+	}
+	return Phases(total, elapsed)
+}
+
+// FormatPhases renders phase times with their share of the total, e.g.
+// "checks 8m0s (64%), claude 3m0s (24%), other 1m30s (12%)".
+func FormatPhases(times []PhaseTime) string {
+	var total time.Duration
+	for _, t := range times {
+		total += t.Time
+	}
+	if total == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(times))
+	for _, t := range times {
+		parts = append(parts, fmt.Sprintf("%s %s (%.0f%%)", t.Phase, t.Time.Round(time.Second), 100*float64(t.Time)/float64(total)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Breakdown counts iterations by outcome, e.g. "4 succeeded, 1 failed".
+func Breakdown(iterations []Iteration) string {
+	counts := make(map[string]int)
+	for _, it := range iterations {
+		counts[it.Outcome]++
+	}
+	var parts []string
+	for _, outcome := range []string{IterationSucceeded, IterationFailed, IterationTimedOut, IterationInterrupted} {
+		if counts[outcome] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[outcome], outcome))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Run summarizes a finished run.
+type Run struct {
+	RunID        string
+	Prompt       string
+	Iterations   int
+	TotalCost    float64
+	Elapsed      time.Duration
+	Completed    bool
+	StopReason   string
+	FilesChanged int
+	Insertions   int
+	Deletions    int
+	PRs          []PR
+	Backports    []Backport
+	Tags         map[string]string
+	AuditHead    string
+	// How each iteration went, of an earlier part of a resumed run too
+	History []Iteration
+	// Checklist items of a --migration-cmd run done, of all it listed
+	MigrationDone  int
+	MigrationTotal int
+
+	// Set for --read-only runs, which record patches instead of PRs
+	ReadOnly    bool
+	Patches     []Patch
+	PatchBranch string
+}
+
+// Outcome describes how the run ended.
+func (r *Run) Outcome() string {
+	if r.Completed {
+		return "Completed (project goal reached)"
+	}
+	if r.StopReason != "" {
+		return "Stopped: " + r.StopReason
+	}
+	return "Limit reached"
+}
+
+// Markdown renders the run summary as a Markdown document.
+func (r *Run) Markdown() string {
+	var sb strings.Builder
+
+	sb.WriteString("## Deep Claude run summary\n\n")
+	sb.WriteString(fmt.Sprintf("**Goal:** %s\n\n", strings.TrimSpace(r.Prompt)))
+	sb.WriteString("| | |\n|---|---|\n")
+	if r.RunID != "" {
+		sb.WriteString(fmt.Sprintf("| Run ID | `%s` |\n", r.RunID))
+	}
+	sb.WriteString(fmt.Sprintf("| Outcome | %s |\n", r.Outcome()))
+	if len(r.History) > 0 {
+		sb.WriteString(fmt.Sprintf("| Iterations | %d (%s) |\n", r.Iterations, Breakdown(r.History)))
+	} else {
+		sb.WriteString(fmt.Sprintf("| Iterations | %d |\n", r.Iterations))
+	}
+	sb.WriteString(fmt.Sprintf("| Cost | $%.4f |\n", r.TotalCost))
+	sb.WriteString(fmt.Sprintf("| Duration | %s |\n", r.Elapsed.Round(time.Second)))
+	sb.WriteString(fmt.Sprintf("| Changes | %d files, +%d -%d |\n", r.FilesChanged, r.Insertions, r.Deletions))
+	if r.MigrationTotal > 0 {
+		sb.WriteString(fmt.Sprintf("| Migration | %d of %d items done (%d%%) |\n", r.MigrationDone, r.MigrationTotal, r.MigrationDone*100/r.MigrationTotal))
+	}
+	if len(r.Tags) > 0 {
+		sb.WriteString(fmt.Sprintf("| Cost tags | %s |\n", strings.Join(ledger.FormatTags(r.Tags), ", ")))
+	}
+	if r.AuditHead != "" {
+		sb.WriteString(fmt.Sprintf("| Audit log head | `%s` |\n", r.AuditHead))
+	}
+
+	if len(r.History) > 0 {
+		sb.WriteString("\n### Iterations\n\n")
+		sb.WriteString("| Iteration | Outcome | Cost | Duration |\n|---|---|---|---|\n")
+		for _, it := range r.History {
+			outcome := it.Outcome
+			if it.Error != "" {
+				outcome += ": " + strings.ReplaceAll(it.Error, "|", "\\|")
+			}
+			sb.WriteString(fmt.Sprintf("| %d | %s | $%.4f | %s |\n", it.Number, outcome, it.Cost, it.Elapsed.Round(time.Second)))
+		}
+		if times := TimeByPhase(r.History); len(times) > 0 {
+			sb.WriteString("\n**Where the time went:** " + FormatPhases(times) + "\n")
+		}
+	}
+
+	if len(r.Backports) > 0 {
+		sb.WriteString("\n### Backports\n\n")
+		for _, b := range r.Backports {
+			if b.Number == "" {
+				sb.WriteString(fmt.Sprintf("- `%s`: %s\n", b.Branch, b.Outcome))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("- `%s`: [#%s](%s) (%s)\n", b.Branch, b.Number, b.URL, b.Outcome))
+		}
+	}
+
+	if r.ReadOnly {
+		r.writePatches(&sb)
+		return sb.String()
+	}
+
+	sb.WriteString("\n### Pull requests\n\n")
+	if len(r.PRs) == 0 {
+		sb.WriteString("No pull requests were opened.\n")
+		return sb.String()
+	}
+	for _, pr := range r.PRs {
+		sb.WriteString(fmt.Sprintf("- Iteration %d: [#%s](%s) %s (%s)\n", pr.Iteration, pr.Number, pr.URL, pr.Title, pr.Outcome))
+	}
+	return sb.String()
+}
+
+func (r *Run) writePatches(sb *strings.Builder) {
+	sb.WriteString("\n### Patches\n\n")
+	sb.WriteString("Read-only run: nothing was pushed and no pull requests were opened.\n\n")
+	if len(r.Patches) == 0 {
+		sb.WriteString("No changes were committed.\n")
+		return
+	}
+	sb.WriteString("Each patch builds on the previous one; apply them in order with `git am`.")
+	if r.PatchBranch != "" {
+		sb.WriteString(fmt.Sprintf(" Local branch `%s` has all of them.", r.PatchBranch))
+	}
+	sb.WriteString("\n\n")
+	for _, p := range r.Patches {
+		sb.WriteString(fmt.Sprintf("- Iteration %d: `%s` %s (%d files, +%d -%d)", p.Iteration, p.File, p.Title, p.FilesChanged, p.Insertions, p.Deletions))
+		if p.Transcript != "" {
+			sb.WriteString(fmt.Sprintf(", transcript `%s`", p.Transcript))
+		}
+		sb.WriteString("\n")
+	}
+}
@@ -0,0 +1,156 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarkdown(t *testing.T) {
+	r := &Run{
+		RunID:        "20250115-143000-ab12",
+		Tags:         map[string]string{"ticket": "JIRA-123", "team": "payments"},
+		Prompt:       "Add tests",
+		Iterations:   2,
+		TotalCost:    1.5,
+		Elapsed:      90*time.Second + 400*time.Millisecond,
+		StopReason:   "reached max iterations (2)",
+		FilesChanged: 3,
+		Insertions:   40,
+		Deletions:    2,
+		AuditHead:    "e14c3d8f",
+		History: []Iteration{
+			{Number: 1, Outcome: IterationSucceeded, Cost: 1.2, Elapsed: 61 * time.Second},
+			{Number: 2, Outcome: IterationFailed, Cost: 0.3, Elapsed: 29 * time.Second, Error: "failed to push: rejected | retry"},
+		},
+		PRs: []PR{
+			{Iteration: 1, Number: "12", URL: "https://github.com/o/r/pull/12", Title: "test: add parser tests", Outcome: "merged"},
+			{Iteration: 2, Number: "13", URL: "https://github.com/o/r/pull/13", Title: "test: add cli tests", Outcome: "closed: checks failed"},
+		},
+		MigrationDone:  12,
+		MigrationTotal: 40,
+	}
+
+	md := r.Markdown()
+	for _, want := range []string{
+		"**Goal:** Add tests",
+		"| Run ID | `20250115-143000-ab12` |",
+		"| Outcome | Stopped: reached max iterations (2) |",
+		"| Iterations | 2 (1 succeeded, 1 failed) |",
+		"| Cost | $1.5000 |",
+		"| Duration | 1m30s |",
+		"| Changes | 3 files, +40 -2 |",
+		"| Cost tags | team=payments, ticket=JIRA-123 |",
+		"| Audit log head | `e14c3d8f` |",
+		"| Migration | 12 of 40 items done (30%) |",
+		"- Iteration 1: [#12](https://github.com/o/r/pull/12) test: add parser tests (merged)",
+		"- Iteration 2: [#13](https://github.com/o/r/pull/13) test: add cli tests (closed: checks failed)",
+		"| 1 | succeeded | $1.2000 | 1m1s |",
+		"| 2 | failed: failed to push: rejected \\| retry | $0.3000 | 29s |",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestBreakdown(t *testing.T) {
+	tests := []struct {
+		outcomes []string
+		want     string
+	}{
+		{nil, ""},
+		{[]string{IterationSucceeded, IterationSucceeded}, "2 succeeded"},
+		{[]string{IterationInterrupted, IterationFailed, IterationSucceeded, IterationTimedOut, IterationFailed}, "1 succeeded, 2 failed, 1 timed out, 1 interrupted"},
+	}
+	for _, tt := range tests {
+		var iterations []Iteration
+		for i, outcome := range tt.outcomes {
+			iterations = append(iterations, Iteration{Number: i + 1, Outcome: outcome})
+		}
+		if got := Breakdown(iterations); got != tt.want {
+			t.Errorf("Breakdown(%v) = %q, want %q", tt.outcomes, got, tt.want)
+		}
+	}
+}
+
+func TestTimeByPhase(t *testing.T) {
+	iterations := []Iteration{
+		{Number: 1, Elapsed: 10 * time.Minute, Phases: map[string]time.Duration{PhaseClaude: 2 * time.Minute, PhaseChecks: 7 * time.Minute}},
+		{Number: 2, Elapsed: 10 * time.Minute, Phases: map[string]time.Duration{PhaseClaude: 2 * time.Minute, PhaseChecks: 7 * time.Minute, PhasePush: 30 * time.Second}},
+		{Number: 3, Elapsed: 30 * time.Second},
+	}
+	want := "checks 14m0s (68%), claude 4m0s (20%), other 2m0s (10%), push 30s (2%)"
+	if got := FormatPhases(TimeByPhase(iterations)); got != want {
+		t.Errorf("FormatPhases(TimeByPhase()) = %q, want %q", got, want)
+	}
+	if got := FormatPhases(TimeByPhase(nil)); got != "" {
+		t.Errorf("FormatPhases(TimeByPhase(nil)) = %q, want none", got)
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	if got := (&Run{Completed: true, StopReason: "x"}).Outcome(); got != "Completed (project goal reached)" {
+		t.Errorf("Outcome() = %q", got)
+	}
+	if got := (&Run{}).Outcome(); got != "Limit reached" {
+		t.Errorf("Outcome() = %q", got)
+	}
+	if !strings.Contains((&Run{}).Markdown(), "No pull requests were opened.") {
+		t.Error("Markdown() should note when no PRs were opened")
+	}
+}
+
+func TestMarkdownBackports(t *testing.T) {
+	r := &Run{
+		Prompt: "Backport PR #40",
+		Backports: []Backport{
+			{Branch: "release-1.2", Number: "41", URL: "https://github.com/o/r/pull/41", Outcome: "merged"},
+			{Branch: "release-1.1", Outcome: "failed: conflicts left in api.go"},
+		},
+	}
+
+	md := r.Markdown()
+	for _, want := range []string{
+		"### Backports",
+		"- `release-1.2`: [#41](https://github.com/o/r/pull/41) (merged)",
+		"- `release-1.1`: failed: conflicts left in api.go",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains((&Run{}).Markdown(), "Backports") {
+		t.Error("Markdown() should omit backports when there are none")
+	}
+}
+
+func TestMarkdownReadOnly(t *testing.T) {
+	r := &Run{
+		Prompt:      "Add tests",
+		ReadOnly:    true,
+		PatchBranch: "deep-claude/run/iteration-2-ab12",
+		Patches: []Patch{
+			{Iteration: 1, File: "iteration-001.patch", Title: "test: add parser tests", FilesChanged: 1, Insertions: 30, Transcript: "/state/transcripts/iteration-001.md"},
+			{Iteration: 2, File: "iteration-002.patch", Title: "test: add cli tests", FilesChanged: 2, Insertions: 10, Deletions: 1},
+		},
+	}
+
+	md := r.Markdown()
+	for _, want := range []string{
+		"Read-only run: nothing was pushed",
+		"Local branch `deep-claude/run/iteration-2-ab12` has all of them.",
+		"- Iteration 1: `iteration-001.patch` test: add parser tests (1 files, +30 -0), transcript `/state/transcripts/iteration-001.md`\n",
+		"- Iteration 2: `iteration-002.patch` test: add cli tests (2 files, +10 -1)\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "Pull requests") {
+		t.Errorf("Markdown() of a read-only run should not list pull requests:\n%s", md)
+	}
+	if !strings.Contains((&Run{ReadOnly: true}).Markdown(), "No changes were committed.") {
+		t.Error("Markdown() should note when a read-only run committed nothing")
+	}
+}
@@ -0,0 +1,215 @@
+// Package retry provides the backoff policy shared by everything that
+// retries a failed operation: pushes, gh calls, update downloads and
+// Claude runs.
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy is how often and how patiently a failed operation is retried.
+type Policy struct {
+	// Attempts is the number of tries, including the first
+	Attempts int
+	// Delay is the wait before the first retry; each later wait is
+	// Multiplier times longer, up to MaxDelay (0 for no cap)
+	Delay      time.Duration
+	Multiplier float64
+	MaxDelay   time.Duration
+	// Jitter randomizes each wait by up to this fraction of it, so that
+	// parallel workers don't retry in lockstep
+	Jitter float64
+}
+
+// Policies holds the policy of each subsystem.
+type Policies struct {
+	Push     Policy
+	GitHub   Policy
+	Download Policy
+	Claude   Policy
+}
+
+// Subsystems are the names of the policies in a spec.
+var Subsystems = []string{"push", "gh", "download", "claude"}
+
+// Defaults returns the built-in policies. GitHub asks clients to wait at
+// least a minute after hitting a secondary rate limit.
+func Defaults() Policies {
+	return Policies{
+		Push:     Policy{Attempts: 4, Delay: 2 * time.Second, Multiplier: 2, MaxDelay: time.Minute, Jitter: 0.1},
+		GitHub:   Policy{Attempts: 5, Delay: time.Minute, Multiplier: 2, MaxDelay: 15 * time.Minute},
+		Download: Policy{Attempts: 3, Delay: time.Second, Multiplier: 2, MaxDelay: 30 * time.Second, Jitter: 0.1},
+		Claude:   Policy{Attempts: 3, Delay: 30 * time.Second, Multiplier: 2, MaxDelay: 5 * time.Minute, Jitter: 0.1},
+	}
+}
+
+// sleep is replaced in tests.
+var sleep = Sleep
+
+// Sleep waits for d, or until ctx is done, in which case it returns ctx's
+// error.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Backoff returns the wait before retry number retry (starting at 0),
+// without jitter.
+func (p Policy) Backoff(retry int) time.Duration {
+	delay := float64(p.Delay) * math.Pow(max(p.Multiplier, 1), float64(retry))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		return p.MaxDelay
+	}
+	if delay > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+// Wait returns the wait before retry number retry, with jitter.
+func (p Policy) Wait(retry int) time.Duration {
+	delay := p.Backoff(retry)
+	if p.Jitter <= 0 || delay <= 0 {
+		return delay
+	}
+	spread := float64(delay) * p.Jitter
+	return time.Duration(float64(delay) - spread + rand.Float64()*2*spread)
+}
+
+// Do calls fn until it succeeds, fails with an error retryable rejects, or
+// the attempts run out, returning its last error. A nil retryable retries
+// every error. onRetry, if set, is told about each retry before the wait.
+// Canceling ctx ends a wait early with ctx's error.
+func (p Policy) Do(ctx context.Context, fn func() error, retryable func(error) bool, onRetry func(retry int, wait time.Duration, err error)) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Attempts || (retryable != nil && !retryable(err)) || ctx.Err() != nil {
+			return err
+		}
+		wait := p.Wait(attempt - 1)
+		if onRetry != nil {
+			onRetry(attempt, wait, err)
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Validate checks that the policy makes sense.
+func (p Policy) Validate() error {
+	switch {
+	case p.Attempts < 1:
+		return fmt.Errorf("attempts must be at least 1")
+	case p.Delay < 0 || p.MaxDelay < 0:
+		return fmt.Errorf("delays must be non-negative")
+	case p.Multiplier < 1:
+		return fmt.Errorf("multiplier must be at least 1")
+	case p.Jitter < 0 || p.Jitter > 1:
+		return fmt.Errorf("jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// Apply changes policies from a spec of comma-separated key=value pairs,
+// e.g. "attempts=5,delay=1s". Keys are attempts, delay, multiplier,
+// max-delay and jitter. A "subsystem:" prefix, e.g. "gh:max-delay=30m",
+// changes only that subsystem's policy; without one, the spec applies to
+// all of them.
+func (ps *Policies) Apply(spec string) error {
+	targets := []*Policy{&ps.Push, &ps.GitHub, &ps.Download, &ps.Claude}
+	if name, rest, ok := strings.Cut(spec, ":"); ok {
+		i := indexOf(Subsystems, name)
+		if i < 0 {
+			return fmt.Errorf("invalid --retry %q: unknown subsystem %q (use %s)", spec, name, strings.Join(Subsystems, ", "))
+		}
+		targets, spec = targets[i:i+1], rest
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return fmt.Errorf("invalid --retry %q: want key=value pairs", spec)
+		}
+		for _, p := range targets {
+			if err := p.set(key, value); err != nil {
+				return fmt.Errorf("invalid --retry %q: %w", spec, err)
+			}
+		}
+	}
+	for _, p := range targets {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("invalid --retry %q: %w", spec, err)
+		}
+	}
+	return nil
+}
+
+// set sets one field of the policy from a spec.
+func (p *Policy) set(key, value string) error {
+	var err error
+	switch key {
+	case "attempts":
+		p.Attempts, err = strconv.Atoi(value)
+	case "delay":
+		p.Delay, err = time.ParseDuration(value)
+	case "multiplier":
+		p.Multiplier, err = strconv.ParseFloat(value, 64)
+	case "max-delay":
+		p.MaxDelay, err = time.ParseDuration(value)
+	case "jitter":
+		p.Jitter, err = strconv.ParseFloat(value, 64)
+	default:
+		return fmt.Errorf("unknown key %q (use attempts, delay, multiplier, max-delay, jitter)", key)
+	}
+	if err != nil {
+		return fmt.Errorf("bad %s %q", key, value)
+	}
+	return nil
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// String returns the policy as a spec for Apply.
+func (p Policy) String() string {
+	return fmt.Sprintf("attempts=%d,delay=%s,multiplier=%s,max-delay=%s,jitter=%s",
+		p.Attempts, p.Delay, strconv.FormatFloat(p.Multiplier, 'g', -1, 64), p.MaxDelay, strconv.FormatFloat(p.Jitter, 'g', -1, 64))
+}
+
+// Specs returns the specs that turn Defaults into these policies, one per
+// subsystem that differs.
+func (ps Policies) Specs() []string {
+	defaults := Defaults()
+	current, base := ps.list(), defaults.list()
+	var specs []string
+	for i, p := range current {
+		if p != base[i] {
+			specs = append(specs, Subsystems[i]+":"+p.String())
+		}
+	}
+	return specs
+}
+
+// list returns the policies in the order of Subsystems.
+func (ps Policies) list() []Policy {
+	return []Policy{ps.Push, ps.GitHub, ps.Download, ps.Claude}
+}
@@ -0,0 +1,198 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	p := Policy{Attempts: 5, Delay: time.Second, Multiplier: 2, MaxDelay: 5 * time.Second}
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{3, 5 * time.Second},
+		{60, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.Backoff(tt.retry); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.retry, got, tt.want)
+		}
+	}
+
+	p.MaxDelay = 0
+	if got := p.Backoff(100); got != time.Duration(1<<63-1) {
+		t.Errorf("uncapped Backoff(100) = %v, want it to saturate", got)
+	}
+}
+
+func TestWaitJitter(t *testing.T) {
+	p := Policy{Delay: 10 * time.Second, Multiplier: 1, Jitter: 0.2}
+	for range 100 {
+		if got := p.Wait(0); got < 8*time.Second || got > 12*time.Second {
+			t.Fatalf("Wait(0) = %v, want within 20%% of 10s", got)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	errFatal := errors.New("fatal")
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "succeeds first time", errs: []error{nil}, wantCalls: 1},
+		{name: "succeeds after retries", errs: []error{errFlaky, errFlaky, nil}, wantCalls: 3},
+		{name: "runs out of attempts", errs: []error{errFlaky, errFlaky, errFlaky, errFlaky}, wantCalls: 3, wantErr: errFlaky},
+		{name: "stops on an error it can't retry", errs: []error{errFlaky, errFatal, nil}, wantCalls: 2, wantErr: errFatal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var waits []time.Duration
+			sleep = func(_ context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+			defer func() { sleep = Sleep }()
+
+			p := Policy{Attempts: 3, Delay: time.Second, Multiplier: 3}
+			calls, retries := 0, 0
+			err := p.Do(context.Background(), func() error {
+				calls++
+				return tt.errs[calls-1]
+			}, func(err error) bool { return err == errFlaky }, func(int, time.Duration, error) { retries++ })
+
+			if err != tt.wantErr {
+				t.Errorf("Do() = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Do() called fn %d times, want %d", calls, tt.wantCalls)
+			}
+			if retries != tt.wantCalls-1 || len(waits) != tt.wantCalls-1 {
+				t.Errorf("Do() retried %d times and waited %v, want %d retries", retries, waits, tt.wantCalls-1)
+			}
+			for i, wait := range waits {
+				if want := p.Backoff(i); wait != want {
+					t.Errorf("wait %d = %v, want %v", i, wait, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Policy{Attempts: 5, Delay: time.Hour, Multiplier: 1}
+	calls := 0
+	done := make(chan error)
+	go func() {
+		done <- p.Do(ctx, func() error {
+			calls++
+			return errors.New("flaky")
+		}, nil, func(int, time.Duration, error) { cancel() })
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Do() = %v, want context.Canceled", err)
+		}
+		if calls != 1 {
+			t.Errorf("Do() called fn %d times after cancel, want 1", calls)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Do() kept waiting after its context was canceled")
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		check   func(Policies) bool
+		wantErr string
+	}{
+		{
+			name:  "all subsystems",
+			specs: []string{"attempts=2,delay=5s"},
+			check: func(ps Policies) bool {
+				return ps.Push.Attempts == 2 && ps.GitHub.Attempts == 2 && ps.Download.Delay == 5*time.Second && ps.Claude.Delay == 5*time.Second
+			},
+		},
+		{
+			name:  "one subsystem",
+			specs: []string{"gh:max-delay=30m,multiplier=3"},
+			check: func(ps Policies) bool {
+				return ps.GitHub.MaxDelay == 30*time.Minute && ps.GitHub.Multiplier == 3 && ps.Push == Defaults().Push
+			},
+		},
+		{
+			name:  "later specs win",
+			specs: []string{"jitter=0.5", "claude:jitter=0"},
+			check: func(ps Policies) bool { return ps.Push.Jitter == 0.5 && ps.Claude.Jitter == 0 },
+		},
+		{name: "unknown subsystem", specs: []string{"npm:attempts=2"}, wantErr: `unknown subsystem "npm"`},
+		{name: "unknown key", specs: []string{"tries=2"}, wantErr: `unknown key "tries"`},
+		{name: "not a pair", specs: []string{"attempts"}, wantErr: "want key=value pairs"},
+		{name: "bad duration", specs: []string{"delay=soon"}, wantErr: `bad delay "soon"`},
+		{name: "no attempts", specs: []string{"push:attempts=0"}, wantErr: "attempts must be at least 1"},
+		{name: "shrinking multiplier", specs: []string{"multiplier=0.5"}, wantErr: "multiplier must be at least 1"},
+		{name: "too much jitter", specs: []string{"jitter=2"}, wantErr: "jitter must be between 0 and 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := Defaults()
+			var err error
+			for _, spec := range tt.specs {
+				if err = ps.Apply(spec); err != nil {
+					break
+				}
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Apply() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() unexpected error: %v", err)
+			}
+			if !tt.check(ps) {
+				t.Errorf("Apply() gave %+v", ps)
+			}
+		})
+	}
+}
+
+func TestSpecsRoundTrip(t *testing.T) {
+	if specs := Defaults().Specs(); len(specs) != 0 {
+		t.Errorf("Defaults().Specs() = %v, want none", specs)
+	}
+
+	ps := Defaults()
+	if err := ps.Apply("download:attempts=6,jitter=0.25"); err != nil {
+		t.Fatal(err)
+	}
+	specs := ps.Specs()
+	if len(specs) != 1 || !strings.HasPrefix(specs[0], "download:") {
+		t.Fatalf("Specs() = %v, want one download spec", specs)
+	}
+	parsed := Defaults()
+	if err := parsed.Apply(specs[0]); err != nil {
+		t.Fatalf("Apply(%q) unexpected error: %v", specs[0], err)
+	}
+	if parsed != ps {
+		t.Errorf("Apply(Specs()) = %+v, want %+v", parsed, ps)
+	}
+}
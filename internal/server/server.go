@@ -0,0 +1,577 @@
+// Package server runs dclaude serve --team: a long-running service where
+// authenticated team members submit tasks to a queue, watch their runs on
+// the dashboard and approve merges, each within a budget, with every
+// action they take recorded in the server's audit log.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/audit"
+	"github.com/guzus/deep-claude/internal/control"
+	"github.com/guzus/deep-claude/internal/dashboard"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/history"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// Files in the server directory.
+const (
+	UsersFile = "users.json"
+	TasksFile = "tasks.json"
+)
+
+// OutputFile is where a task's run writes its output, in its run directory.
+const OutputFile = "output.log"
+
+// maxOutput is how much of the end of a run's output the dashboard shows.
+const maxOutput = 64 * 1024
+
+// pollInterval is how often an idle server checks for queued tasks.
+var pollInterval = 5 * time.Second
+
+// Options configure a Server.
+type Options struct {
+	// Dir holds the users, tasks and audit log
+	Dir string
+	// WorkDir is the repository tasks run in
+	WorkDir string
+	// Executable is the dclaude binary runs are started with
+	Executable string
+	// Args are passed to every run
+	Args []string
+	// MergeApproval has runs leave their PRs for a team member to approve
+	MergeApproval bool
+	// Pane returns the terminal of a run not started by the server
+	Pane func(runID string) (string, error)
+}
+
+// Server is the team service.
+type Server struct {
+	opts  Options
+	users *Users
+	tasks *Tasks
+	audit *audit.Log
+	wake  chan struct{}
+
+	// start runs dclaude with args for a task until it ends
+	start func(ctx context.Context, task Task, args []string) error
+	// merge merges a PR of owner/repo
+	merge func(ctx context.Context, owner, repo, number, strategy string) error
+}
+
+// New loads the team and the task queue from opts.Dir.
+func New(opts Options) (*Server, error) {
+	users, err := LoadUsers(filepath.Join(opts.Dir, UsersFile))
+	if err != nil {
+		return nil, err
+	}
+	if len(users.List()) == 0 {
+		return nil, fmt.Errorf("no users yet; add one with dclaude serve user add <name>")
+	}
+	tasks, err := LoadTasks(filepath.Join(opts.Dir, TasksFile))
+	if err != nil {
+		return nil, err
+	}
+	log, err := audit.Open(filepath.Join(opts.Dir, audit.FileName), "server")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{opts: opts, users: users, tasks: tasks, audit: log, wake: make(chan struct{}, 1)}
+	s.start = s.startRun
+	s.merge = func(ctx context.Context, owner, repo, number, strategy string) error {
+		return github.NewClient(owner, repo, opts.WorkDir).MergePR(ctx, number, strategy)
+	}
+	return s, nil
+}
+
+// Close closes the audit log.
+func (s *Server) Close() error {
+	return s.audit.Close()
+}
+
+// userKey is the request context key of the authenticated user.
+type userKey struct{}
+
+// Handler returns the dashboard with the team API added; every /api
+// request needs a user's token as "Authorization: Bearer <token>":
+//
+//	GET  /api/me                                  the user and their budget
+//	GET  /api/tasks                               the queue and past tasks
+//	POST /api/tasks                               submit {"prompt", "max_runs", "max_cost", "priority", "labels"}
+//	POST /api/tasks/{id}/bump                     raise a task's priority, or set it to {"priority"}
+//	POST /api/tasks/{id}/cancel                   cancel a task, stopping its run
+//	POST /api/runs/{id}/prs/{number}/approve      merge a PR awaiting approval
+func (s *Server) Handler() http.Handler {
+	board := dashboard.Handler(dashboard.Options{Pane: s.pane, Team: true})
+	mux := http.NewServeMux()
+	mux.Handle("/", board)
+	mux.HandleFunc("GET /api/me", func(w http.ResponseWriter, r *http.Request) {
+		user := userFrom(r)
+		writeJSON(w, http.StatusOK, map[string]any{"name": user.Name, "budget": user.Budget, "spent": s.spent(user.Name)})
+	})
+	mux.HandleFunc("GET /api/tasks", func(w http.ResponseWriter, r *http.Request) {
+		tasks := s.tasks.List()
+		if tasks == nil {
+			tasks = []Task{}
+		}
+		writeJSON(w, http.StatusOK, tasks)
+	})
+	mux.HandleFunc("POST /api/tasks", s.submit)
+	mux.HandleFunc("POST /api/tasks/{id}/bump", s.bump)
+	mux.HandleFunc("POST /api/tasks/{id}/cancel", s.cancel)
+	mux.HandleFunc("POST /api/runs/{id}/prs/{number}/approve", s.approve)
+	mux.HandleFunc("POST /api/runs/{id}/{action}", func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w}
+		board.ServeHTTP(rec, r)
+		if rec.status == http.StatusNoContent {
+			s.audit.Record(audit.RunControlled, 0, map[string]any{"user": userFrom(r).Name, "run_id": r.PathValue("id"), "action": r.PathValue("action")})
+		}
+	})
+	return s.authenticate(mux)
+}
+
+// authenticate lets only team members use the API.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		user, ok := s.users.Authenticate(token)
+		if token == "" || !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "a valid token is required", http.StatusUnauthorized)
+			return
+		}
+		// A bearer token is never sent by other sites, so it also vouches
+		// that the request came from the dashboard or a script
+		r.Header.Set(dashboard.RequestHeader, "1")
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+func userFrom(r *http.Request) User {
+	user, _ := r.Context().Value(userKey{}).(User)
+	return user
+}
+
+// submit queues a task within the user's budget.
+func (s *Server) submit(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Prompt   string   `json:"prompt"`
+		MaxRuns  int      `json:"max_runs"`
+		MaxCost  float64  `json:"max_cost"`
+		Priority string   `json:"priority"`
+		Labels   []string `json:"labels"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "invalid task: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	user := userFrom(r)
+	req.Prompt = strings.TrimSpace(req.Prompt)
+	priority, err := ParsePriority(req.Priority)
+	for _, label := range req.Labels {
+		if err == nil {
+			err = config.ValidateLabel(label)
+		}
+	}
+	switch {
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case req.Prompt == "":
+		http.Error(w, "prompt is required", http.StatusBadRequest)
+		return
+	case req.MaxRuns < 0 || req.MaxCost < 0:
+		http.Error(w, "max_runs and max_cost must be non-negative", http.StatusBadRequest)
+		return
+	case user.Budget == 0 && req.MaxRuns == 0 && req.MaxCost == 0:
+		http.Error(w, "set max_runs or max_cost", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.limit(user.Name, req.MaxCost, 0); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	task, err := s.tasks.Submit(Task{User: user.Name, Prompt: req.Prompt, MaxRuns: req.MaxRuns, MaxCost: req.MaxCost, Priority: priority, Labels: req.Labels})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.audit.Record(audit.TaskSubmitted, 0, map[string]any{"user": user.Name, "task": task.ID, "prompt": task.Prompt, "max_runs": task.MaxRuns, "max_cost": task.MaxCost, "priority": task.Priority, "labels": task.Labels})
+	s.reschedule()
+	writeJSON(w, http.StatusCreated, task)
+}
+
+// bump raises a task's priority one level, or sets the one asked for.
+func (s *Server) bump(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid task ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Priority string `json:"priority"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Priority != "" {
+		if _, err := ParsePriority(req.Priority); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	task, err := s.tasks.Bump(id, req.Priority)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	s.audit.Record(audit.TaskBumped, 0, map[string]any{"user": userFrom(r).Name, "task": task.ID, "owner": task.User, "priority": task.Priority})
+	s.reschedule()
+	writeJSON(w, http.StatusOK, task)
+}
+
+// reschedule wakes the worker for a change to the queue, first parking
+// the running task if an urgent one now waits. The parked run stops after
+// its current iteration, and resumes once the urgent work is done.
+func (s *Server) reschedule() {
+	if task, ok, err := s.tasks.Preempt(); err == nil && ok {
+		if dir, err := state.RunDir(task.RunID); err == nil {
+			_ = control.Request(dir, control.Stop)
+		}
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// cancel cancels a queued task, or stops the run of a running one after
+// its current iteration.
+func (s *Server) cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid task ID", http.StatusBadRequest)
+		return
+	}
+	task, err := s.tasks.Cancel(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if dir, err := state.RunDir(task.RunID); err == nil {
+		// A parked task's run may still be finishing its iteration
+		if task.Status == Running || task.Status == Parked && control.Active(dir) {
+			_ = control.Request(dir, control.Stop)
+		}
+	}
+	s.audit.Record(audit.TaskCanceled, 0, map[string]any{"user": userFrom(r).Name, "task": task.ID, "owner": task.User, "run_id": task.RunID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// approve merges a PR a run left open for approval, and records the merge
+// in the run's event log so its history shows it.
+func (s *Server) approve(w http.ResponseWriter, r *http.Request) {
+	runID, number := r.PathValue("id"), r.PathValue("number")
+	ids, err := state.ListRuns()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !slices.Contains(ids, runID) {
+		http.Error(w, "run "+runID+" not found", http.StatusNotFound)
+		return
+	}
+	dir, err := state.RunDir(runID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	path := filepath.Join(dir, events.FileName)
+	list, err := events.Read(path, "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	owner, repo, strategy, ok := pendingMerge(history.Summarize(runID, list), list, number)
+	if !ok {
+		http.Error(w, fmt.Sprintf("PR #%s of run %s is not awaiting approval", number, runID), http.StatusConflict)
+		return
+	}
+
+	user := userFrom(r)
+	if err := s.merge(r.Context(), owner, repo, number, strategy); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if log, err := events.Create(path, runID); err == nil {
+		log.Emit(events.PRMerged, 0, map[string]any{"number": number, "strategy": strategy, "approved_by": user.Name})
+		_ = log.Close()
+	}
+	s.audit.Record(audit.MergeApproved, 0, map[string]any{"user": user.Name, "run_id": runID, "number": number, "repo": owner + "/" + repo})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pendingMerge returns the repository and merge strategy of a PR that is
+// awaiting approval in a run.
+func pendingMerge(run history.Run, list []events.Event, number string) (owner, repo, strategy string, ok bool) {
+	for _, pr := range run.PRs {
+		if pr.Number == number && pr.Outcome == "awaiting approval" {
+			ok = true
+		}
+	}
+	if !ok {
+		return "", "", "", false
+	}
+	for _, e := range list {
+		switch e.Type {
+		case events.RunStarted:
+			owner, repo = str(e.Data, "owner"), str(e.Data, "repo")
+		case events.MergePending:
+			if str(e.Data, "number") == number {
+				strategy = str(e.Data, "strategy")
+			}
+		}
+	}
+	if strategy == "" {
+		strategy = "squash"
+	}
+	return owner, repo, strategy, owner != "" && repo != ""
+}
+
+// Work runs the queued tasks one at a time until ctx is canceled.
+func (s *Server) Work(ctx context.Context) {
+	for ctx.Err() == nil {
+		task, ok, err := s.tasks.Next(state.NewRunID())
+		if err != nil || !ok {
+			select {
+			case <-ctx.Done():
+			case <-s.wake:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		s.runTask(ctx, task)
+	}
+}
+
+// runTask runs a task's prompt with the rest of its user's budget, or
+// resumes the run of a task that was parked.
+func (s *Server) runTask(ctx context.Context, task Task) {
+	args := []string{"-p", task.Prompt, "--run-id", task.RunID}
+	// A parked task's run is resumed, counting what it already spent
+	// against --max-cost
+	var spent float64
+	if runStopReason(task.RunID) != "" {
+		args, spent = []string{"--resume", task.RunID}, runCost(task.RunID)
+	}
+	limit, err := s.limit(task.User, task.MaxCost, spent)
+	if err != nil {
+		_ = s.tasks.Finish(task.ID, spent, err, false)
+		return
+	}
+	args = append(args, "--disable-updates", "--cost-tag", "user="+task.User)
+	if task.MaxRuns > 0 {
+		args = append(args, "--max-runs", strconv.Itoa(task.MaxRuns))
+	}
+	if limit > 0 {
+		args = append(args, "--max-cost", strconv.FormatFloat(limit, 'f', -1, 64))
+	}
+	for _, label := range task.Labels {
+		args = append(args, "--label", label)
+	}
+	if s.opts.MergeApproval {
+		args = append(args, "--merge-approval")
+	}
+	args = append(args, s.opts.Args...)
+
+	err = s.start(ctx, task, args)
+	park := err == nil && runStopReason(task.RunID) == control.StopReason
+	if dir, dirErr := state.RunDir(task.RunID); dirErr == nil && !park {
+		// A run can end on its own before it sees it was parked
+		_ = control.Request(dir, "")
+	}
+	_ = s.tasks.Finish(task.ID, runCost(task.RunID), err, park)
+}
+
+// startRun runs dclaude for a task in the work directory, with its output
+// in the run directory. Canceling ctx stops the run as Ctrl-C would.
+func (s *Server) startRun(ctx context.Context, task Task, args []string) error {
+	dir, err := state.RunDir(task.RunID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create run directory: %w", err)
+	}
+	output, err := os.OpenFile(filepath.Join(dir, OutputFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create run output: %w", err)
+	}
+	defer output.Close()
+
+	cmd := exec.Command(s.opts.Executable, args...)
+	cmd.Dir = s.opts.WorkDir
+	cmd.Stdout, cmd.Stderr = output, output
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start run: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		_ = cmd.Process.Signal(os.Interrupt)
+		err = <-done
+	}
+	if err != nil {
+		return fmt.Errorf("run %s failed: %w", task.RunID, err)
+	}
+	return nil
+}
+
+// limit returns what a task may spend: maxCost, capped by what is left of
+// the user's budget, 0 for no cap. It fails once the budget is spent.
+// spent is what the task's run already spent, if it is being resumed.
+func (s *Server) limit(name string, maxCost, spent float64) (float64, error) {
+	var user User
+	var found bool
+	for _, u := range s.users.List() {
+		if u.Name == name {
+			user, found = u, true
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("user %s no longer exists", name)
+	}
+	if user.Budget == 0 {
+		return maxCost, nil
+	}
+	left := user.Budget - s.spent(name) + spent
+	if left <= spent {
+		return 0, fmt.Errorf("budget of $%.2f is spent", user.Budget)
+	}
+	if maxCost == 0 || maxCost > left {
+		return left, nil
+	}
+	return maxCost, nil
+}
+
+// spent returns what a user's tasks have spent so far.
+func (s *Server) spent(name string) float64 {
+	return s.tasks.Spent(name, runCost)
+}
+
+// runStopReason returns why a run last stopped, "" if it never has.
+func runStopReason(runID string) string {
+	dir, err := state.RunDir(runID)
+	if err != nil {
+		return ""
+	}
+	list, err := events.Read(filepath.Join(dir, events.FileName), "")
+	if err != nil {
+		return ""
+	}
+	var reason string
+	for _, e := range list {
+		if e.Type == events.RunFinished {
+			reason = str(e.Data, "stop_reason")
+		}
+	}
+	return reason
+}
+
+// runCost returns what a run has spent so far, from its event log.
+func runCost(runID string) float64 {
+	dir, err := state.RunDir(runID)
+	if err != nil {
+		return 0
+	}
+	list, err := events.Read(filepath.Join(dir, events.FileName), "")
+	if err != nil {
+		return 0
+	}
+	var cost float64
+	for _, e := range list {
+		if e.Type == events.ClaudeFinished || e.Type == events.RunFinished {
+			if v, ok := e.Data["total_cost"].(float64); ok {
+				cost = v
+			}
+		}
+	}
+	return cost
+}
+
+// pane returns the end of the output of a run the server started, or the
+// terminal of one running elsewhere.
+func (s *Server) pane(runID string) (string, error) {
+	dir, err := state.RunDir(runID)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(filepath.Join(dir, OutputFile))
+	if errors.Is(err, fs.ErrNotExist) && s.opts.Pane != nil {
+		return s.opts.Pane(runID)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() > maxOutput {
+		_, _ = f.Seek(-maxOutput, io.SeekEnd)
+	}
+	data, err := io.ReadAll(f)
+	return string(data), err
+}
+
+// statusRecorder notes the status a handler answered with.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func str(data map[string]any, key string) string {
+	switch v := data[key].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
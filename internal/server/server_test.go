@@ -0,0 +1,261 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/audit"
+	"github.com/guzus/deep-claude/internal/control"
+	"github.com/guzus/deep-claude/internal/history"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// newServer returns a server with alice, who has a budget of $2, and bob,
+// who has none.
+func newServer(t *testing.T) (s *Server, alice, bob string) {
+	t.Helper()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir, err := state.ServerDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := LoadUsers(filepath.Join(dir, UsersFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice, err = users.Add("alice", 2); err != nil {
+		t.Fatal(err)
+	}
+	if bob, err = users.Add("bob", 0); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = New(Options{Dir: dir, MergeApproval: true}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s, alice, bob
+}
+
+// request sends a request to the server as the user with token.
+func request(s *Server, token, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+// recordRun writes the events of a run that spent cost.
+func recordRun(t *testing.T, runID string, cost float64, list ...events.Event) {
+	t.Helper()
+	dir, err := state.RunDir(runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, err := events.Create(filepath.Join(dir, events.FileName), runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Emit(events.RunStarted, 0, map[string]any{"prompt": "add tests", "owner": "acme", "repo": "widgets"})
+	for _, e := range list {
+		log.Emit(e.Type, e.Iteration, e.Data)
+	}
+	log.Emit(events.ClaudeFinished, 1, map[string]any{"total_cost": cost})
+	_ = log.Close()
+}
+
+func TestSubmit(t *testing.T) {
+	s, alice, bob := newServer(t)
+
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		status int
+	}{
+		{"no token", "", `{"prompt": "add tests", "max_runs": 2}`, http.StatusUnauthorized},
+		{"wrong token", "dct_nope", `{"prompt": "add tests", "max_runs": 2}`, http.StatusUnauthorized},
+		{"no prompt", alice, `{"max_runs": 2}`, http.StatusBadRequest},
+		{"no limit without a budget", bob, `{"prompt": "add tests"}`, http.StatusBadRequest},
+		{"unknown priority", bob, `{"prompt": "add tests", "max_runs": 2, "priority": "asap"}`, http.StatusBadRequest},
+		{"label unfit for a branch", bob, `{"prompt": "add tests", "max_runs": 2, "labels": ["auth work"]}`, http.StatusBadRequest},
+		{"budget caps the run", alice, `{"prompt": "add tests"}`, http.StatusCreated},
+		{"limits", bob, `{"prompt": "fix lint", "max_runs": 3, "max_cost": 4}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := request(s, tt.token, "POST", "/api/tasks", tt.body); rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+
+	var tasks []Task
+	if err := json.Unmarshal(request(s, bob, "GET", "/api/tasks", "").Body.Bytes(), &tasks); err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || tasks[0].User != "alice" || tasks[1].User != "bob" || tasks[1].MaxRuns != 3 {
+		t.Errorf("tasks = %+v", tasks)
+	}
+
+	// Once alice's budget is spent, she can't submit more
+	_, _, _ = s.tasks.Next("20250115-143000-ab12")
+	recordRun(t, "20250115-143000-ab12", 2.5)
+	if rec := request(s, alice, "POST", "/api/tasks", `{"prompt": "more tests"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("submitting over budget = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	var me map[string]any
+	_ = json.Unmarshal(request(s, alice, "GET", "/api/me", "").Body.Bytes(), &me)
+	if me["name"] != "alice" || me["spent"] != 2.5 {
+		t.Errorf("me = %v", me)
+	}
+}
+
+func TestWork(t *testing.T) {
+	s, alice, bob := newServer(t)
+	var got [][]string
+	s.start = func(ctx context.Context, task Task, args []string) error {
+		got = append(got, args)
+		recordRun(t, task.RunID, 0.5)
+		return nil
+	}
+	request(s, alice, "POST", "/api/tasks", `{"prompt": "add tests", "max_cost": 5}`)
+	request(s, bob, "POST", "/api/tasks", `{"prompt": "fix lint", "max_runs": 3, "labels": ["lint"]}`)
+	request(s, bob, "POST", "/api/tasks", `{"prompt": "update docs", "max_runs": 1}`)
+	request(s, bob, "POST", "/api/tasks/3/cancel", "")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	interval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = interval })
+	go func() {
+		for len(s.tasks.List()) > 0 && s.tasks.List()[1].Status != Done {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	s.Work(ctx)
+
+	tasks := s.tasks.List()
+	if len(got) != 2 {
+		t.Fatalf("ran %d tasks, want 2", len(got))
+	}
+	want := []string{"-p", "add tests", "--run-id", tasks[0].RunID, "--disable-updates", "--cost-tag", "user=alice", "--max-cost", "2", "--merge-approval"}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("args = %v, want %v with the cost capped by the budget", got[0], want)
+	}
+	if !strings.Contains(strings.Join(got[1], " "), "--max-runs 3 --label lint") || strings.Contains(strings.Join(got[1], " "), "--max-cost") {
+		t.Errorf("args = %v, want the iteration limit, the label and no cost cap", got[1])
+	}
+	for i, status := range []string{Done, Done, Canceled} {
+		if tasks[i].Status != status {
+			t.Errorf("task %d is %s, want %s", tasks[i].ID, tasks[i].Status, status)
+		}
+	}
+	if tasks[0].Cost != 0.5 {
+		t.Errorf("task 1 cost = %v, want 0.5", tasks[0].Cost)
+	}
+}
+
+func TestPreempt(t *testing.T) {
+	s, _, bob := newServer(t)
+	var got [][]string
+	s.start = func(ctx context.Context, task Task, args []string) error {
+		got = append(got, args)
+		if len(got) == 1 {
+			// The urgent task arrives while the first one runs, which is
+			// asked to stop after its iteration
+			dir, _ := state.RunDir(task.RunID)
+			_ = os.MkdirAll(dir, 0755)
+			request(s, bob, "POST", "/api/tasks", `{"prompt": "fix the outage", "max_runs": 1, "priority": "urgent"}`)
+			if action := control.Requested(dir); action != control.Stop {
+				t.Errorf("requested %q of the parked run, want %q", action, control.Stop)
+			}
+			recordRun(t, task.RunID, 0.5, events.Event{Type: events.RunFinished, Data: map[string]any{"stop_reason": control.StopReason}})
+			return nil
+		}
+		recordRun(t, task.RunID, 0.25)
+		return nil
+	}
+	request(s, bob, "POST", "/api/tasks", `{"prompt": "add tests", "max_runs": 3}`)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	interval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = interval })
+	go func() {
+		for tasks := s.tasks.List(); len(tasks) < 2 || tasks[0].Status != Done; tasks = s.tasks.List() {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	s.Work(ctx)
+
+	tasks := s.tasks.List()
+	if len(got) != 3 {
+		t.Fatalf("started %d runs, want 3", len(got))
+	}
+	if got[1][1] != "fix the outage" {
+		t.Errorf("second run = %v, want the urgent task", got[1])
+	}
+	want := []string{"--resume", tasks[0].RunID, "--disable-updates", "--cost-tag", "user=bob", "--max-runs", "3", "--merge-approval"}
+	if !reflect.DeepEqual(got[2], want) {
+		t.Errorf("third run = %v, want %v", got[2], want)
+	}
+	for i, task := range tasks {
+		if task.Status != Done {
+			t.Errorf("task %d is %s, want done", i+1, task.Status)
+		}
+	}
+}
+
+func TestApprove(t *testing.T) {
+	s, _, bob := newServer(t)
+	recordRun(t, "20250115-143000-ab12", 1,
+		events.Event{Type: events.PRCreated, Iteration: 1, Data: map[string]any{"number": "7", "url": "https://github.com/acme/widgets/pull/7"}},
+		events.Event{Type: events.MergePending, Iteration: 1, Data: map[string]any{"number": "7", "strategy": "rebase"}},
+	)
+	var merged []string
+	s.merge = func(ctx context.Context, owner, repo, number, strategy string) error {
+		merged = append(merged, owner+"/"+repo+"#"+number+" "+strategy)
+		return nil
+	}
+
+	if rec := request(s, bob, "POST", "/api/runs/20250115-143000-ab12/prs/8/approve", ""); rec.Code != http.StatusConflict {
+		t.Errorf("approving a PR that isn't waiting = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if rec := request(s, bob, "POST", "/api/runs/20250115-143000-ab12/prs/7/approve", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("approve = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := request(s, bob, "POST", "/api/runs/20250115-143000-ab12/prs/7/approve", ""); rec.Code != http.StatusConflict {
+		t.Errorf("approving a merged PR = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if !reflect.DeepEqual(merged, []string{"acme/widgets#7 rebase"}) {
+		t.Errorf("merged %v", merged)
+	}
+
+	dir, _ := state.RunDir("20250115-143000-ab12")
+	list, _ := events.Read(filepath.Join(dir, events.FileName), "")
+	if run := history.Summarize("20250115-143000-ab12", list); run.PRs[0].Outcome != "merged" {
+		t.Errorf("PR outcome = %q, want merged in the run's history", run.PRs[0].Outcome)
+	}
+
+	entries, err := audit.Read(filepath.Join(s.opts.Dir, audit.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(entries); n != 1 || entries[0].Action != audit.MergeApproved || entries[0].Data["user"] != "bob" {
+		t.Errorf("audit = %+v, want the merge attributed to bob", entries)
+	}
+}
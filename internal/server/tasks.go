@@ -0,0 +1,315 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Task statuses. A parked task was running until an urgent one took its
+// turn; it resumes its run when it is next.
+const (
+	Queued   = "queued"
+	Running  = "running"
+	Parked   = "parked"
+	Done     = "done"
+	Failed   = "failed"
+	Canceled = "canceled"
+)
+
+// Priorities, lowest first. Queued tasks run highest priority first, and
+// an urgent task parks a running task that isn't.
+var Priorities = []string{"low", "normal", "high", Urgent}
+
+// Urgent is the priority that preempts running tasks.
+const Urgent = "urgent"
+
+// defaultPriority is the priority of tasks submitted without one.
+const defaultPriority = "normal"
+
+// ParsePriority checks a priority, "" being the default.
+func ParsePriority(s string) (string, error) {
+	if s == "" {
+		return defaultPriority, nil
+	}
+	if !slices.Contains(Priorities, s) {
+		return "", fmt.Errorf("priority must be one of: %s", strings.Join(Priorities, ", "))
+	}
+	return s, nil
+}
+
+// rank orders priorities; tasks loaded from before priorities count as
+// the default.
+func rank(priority string) int {
+	if i := slices.Index(Priorities, priority); i >= 0 {
+		return i
+	}
+	return slices.Index(Priorities, defaultPriority)
+}
+
+// Task is a prompt a team member submitted for the server to run.
+type Task struct {
+	ID       int      `json:"id"`
+	User     string   `json:"user"`
+	Prompt   string   `json:"prompt"`
+	MaxRuns  int      `json:"max_runs,omitempty"`
+	MaxCost  float64  `json:"max_cost,omitempty"`
+	Priority string   `json:"priority"`
+	Labels   []string `json:"labels,omitempty"`
+	Status   string   `json:"status"`
+	// Error says why a failed task failed
+	Error     string    `json:"error,omitempty"`
+	RunID     string    `json:"run_id,omitempty"`
+	Cost      float64   `json:"cost"`
+	Submitted time.Time `json:"submitted"`
+	Started   time.Time `json:"started,omitzero"`
+	Finished  time.Time `json:"finished,omitzero"`
+}
+
+// Tasks is the server's queue and the tasks it ran, kept in a JSON file.
+type Tasks struct {
+	mu   sync.Mutex
+	path string
+	list []Task
+}
+
+// LoadTasks reads the tasks from path. Tasks that were running when the
+// server last stopped are marked failed, since their runs were cut off;
+// they can be continued with --resume.
+func LoadTasks(path string) (*Tasks, error) {
+	t := &Tasks{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tasks: %w", err)
+	}
+	if err := json.Unmarshal(data, &t.list); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for i := range t.list {
+		if t.list[i].Status == Running {
+			t.list[i].Status, t.list[i].Error = Failed, "server stopped during the run"
+		}
+	}
+	return t, nil
+}
+
+// Submit queues a task and returns it with its ID.
+func (t *Tasks) Submit(task Task) (Task, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	task.ID = 1
+	if n := len(t.list); n > 0 {
+		task.ID = t.list[n-1].ID + 1
+	}
+	task.Status, task.Submitted = Queued, time.Now().UTC()
+	if task.Priority == "" {
+		task.Priority = defaultPriority
+	}
+	t.list = append(t.list, task)
+	return task, t.save()
+}
+
+// List returns every task, oldest first.
+func (t *Tasks) List() []Task {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.list)
+}
+
+// Get returns the task with the given ID.
+func (t *Tasks) Get(id int) (Task, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if i := t.index(id); i >= 0 {
+		return t.list[i], true
+	}
+	return Task{}, false
+}
+
+// Next marks the next waiting task in Order running and returns it, or
+// false if none is waiting. A queued task gets the given run ID; a parked
+// one keeps its own.
+func (t *Tasks) Next(runID string) (Task, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	order := Order(t.list)
+	i := slices.IndexFunc(order, func(task Task) bool { return task.waiting() })
+	if i < 0 {
+		return Task{}, false, nil
+	}
+	task := &t.list[t.index(order[i].ID)]
+	if task.Status == Queued {
+		task.RunID, task.Started = runID, time.Now().UTC()
+	}
+	task.Status = Running
+	return *task, true, t.save()
+}
+
+// Order returns tasks in the order they run: the running ones, then the
+// waiting ones by priority, the oldest first, then the rest.
+func Order(tasks []Task) []Task {
+	group := func(task Task) int {
+		switch {
+		case task.Status == Running:
+			return 0
+		case task.waiting():
+			return 1
+		default:
+			return 2
+		}
+	}
+	sorted := slices.Clone(tasks)
+	slices.SortStableFunc(sorted, func(a, b Task) int {
+		if n := group(a) - group(b); n != 0 {
+			return n
+		}
+		if group(a) == 1 {
+			if n := rank(b.Priority) - rank(a.Priority); n != 0 {
+				return n
+			}
+		}
+		return a.ID - b.ID
+	})
+	return sorted
+}
+
+// Pending reports whether a task is yet to finish.
+func (task Task) Pending() bool {
+	return task.Status == Running || task.waiting()
+}
+
+// waiting reports whether a task waits for its turn to run.
+func (task Task) waiting() bool {
+	return task.Status == Queued || task.Status == Parked
+}
+
+// Bump sets the priority of a task that hasn't finished, by default to
+// the one above its own, and returns it.
+func (t *Tasks) Bump(id int, priority string) (Task, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i := t.index(id)
+	if i < 0 {
+		return Task{}, fmt.Errorf("task %d not found", id)
+	}
+	task := &t.list[i]
+	if !task.Finished.IsZero() || task.Status == Canceled {
+		return Task{}, fmt.Errorf("task %d is already %s", id, task.Status)
+	}
+	if priority == "" {
+		if rank(task.Priority) == len(Priorities)-1 {
+			return Task{}, fmt.Errorf("task %d is already %s", id, task.Priority)
+		}
+		priority = Priorities[rank(task.Priority)+1]
+	}
+	task.Priority = priority
+	return *task, t.save()
+}
+
+// Preempt parks the running task if an urgent one is waiting and it isn't
+// urgent itself, and returns it. Stopping its run is up to the caller.
+func (t *Tasks) Preempt() (Task, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	running := slices.IndexFunc(t.list, func(task Task) bool { return task.Status == Running })
+	if running < 0 || t.list[running].Priority == Urgent {
+		return Task{}, false, nil
+	}
+	if !slices.ContainsFunc(t.list, func(task Task) bool { return task.waiting() && task.Priority == Urgent }) {
+		return Task{}, false, nil
+	}
+	t.list[running].Status = Parked
+	return t.list[running], true, t.save()
+}
+
+// Finish records how a task's run ended. If the run stopped because the
+// task was parked, park says so, and the task waits to resume it.
+func (t *Tasks) Finish(id int, cost float64, runErr error, park bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i := t.index(id)
+	if i < 0 {
+		return fmt.Errorf("task %d not found", id)
+	}
+	task := &t.list[i]
+	task.Cost = cost
+	if park && task.Status == Parked {
+		return t.save()
+	}
+	task.Finished = time.Now().UTC()
+	switch {
+	case task.Status == Canceled:
+	case runErr != nil:
+		task.Status, task.Error = Failed, runErr.Error()
+	default:
+		task.Status = Done
+	}
+	return t.save()
+}
+
+// Cancel cancels a task and returns it as it was. A running task is only
+// marked; stopping its run is up to the caller. A parked one is finished
+// with its run stopped where it was parked.
+func (t *Tasks) Cancel(id int) (Task, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i := t.index(id)
+	if i < 0 {
+		return Task{}, fmt.Errorf("task %d not found", id)
+	}
+	task := t.list[i]
+	if task.Status != Queued && task.Status != Running && task.Status != Parked {
+		return Task{}, fmt.Errorf("task %d is already %s", id, task.Status)
+	}
+	t.list[i].Status = Canceled
+	if task.Status == Queued || task.Status == Parked {
+		t.list[i].Finished = time.Now().UTC()
+	}
+	return task, t.save()
+}
+
+// Spent returns what a user's tasks have spent, counting running ones by
+// what they have spent so far as given by cost.
+func (t *Tasks) Spent(user string, cost func(runID string) float64) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var spent float64
+	for _, task := range t.list {
+		if task.User != user || task.RunID == "" {
+			continue
+		}
+		if task.Finished.IsZero() {
+			spent += cost(task.RunID)
+		} else {
+			spent += task.Cost
+		}
+	}
+	return spent
+}
+
+func (t *Tasks) index(id int) int {
+	return slices.IndexFunc(t.list, func(task Task) bool { return task.ID == id })
+}
+
+func (t *Tasks) save() error {
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return fmt.Errorf("failed to create server directory: %w", err)
+	}
+	data, err := json.MarshalIndent(t.list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save tasks: %w", err)
+	}
+	return os.Rename(tmp, t.path)
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTasks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), TasksFile)
+	tasks, err := LoadTasks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prompt := range []string{"add tests", "fix lint", "update docs"} {
+		if _, err := tasks.Submit(Task{User: "alice", Prompt: prompt, MaxRuns: 2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, ok, err := tasks.Next("run-1")
+	if err != nil || !ok || first.ID != 1 || first.Status != Running || first.RunID != "run-1" {
+		t.Fatalf("Next() = %+v, %v, %v, want task 1 running", first, ok, err)
+	}
+	if _, err := tasks.Cancel(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := tasks.Finish(1, 1.25, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	third, _, _ := tasks.Next("run-3")
+	if third.ID != 3 {
+		t.Errorf("Next() = task %d, want 3 after 2 was canceled", third.ID)
+	}
+	if err := tasks.Finish(3, 0.5, errors.New("exit status 1"), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := tasks.Next("run-4"); ok {
+		t.Error("Next() found a task in an empty queue")
+	}
+	if _, err := tasks.Cancel(1); err == nil {
+		t.Error("Cancel() of a finished task should fail")
+	}
+
+	want := []string{Done, Canceled, Failed}
+	for i, task := range tasks.List() {
+		if task.Status != want[i] {
+			t.Errorf("task %d is %s, want %s", task.ID, task.Status, want[i])
+		}
+	}
+	if spent := tasks.Spent("alice", func(string) float64 { return 0 }); spent != 1.75 {
+		t.Errorf("Spent() = %v, want 1.75", spent)
+	}
+}
+
+func TestLoadTasksFailsInterruptedRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), TasksFile)
+	tasks, _ := LoadTasks(path)
+	_, _ = tasks.Submit(Task{User: "alice", Prompt: "add tests"})
+	_, _, _ = tasks.Next("run-1")
+
+	reloaded, err := LoadTasks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task, _ := reloaded.Get(1); task.Status != Failed || task.Error == "" {
+		t.Errorf("task = %+v, want it failed by the restart", task)
+	}
+}
+
+func TestTasksPriority(t *testing.T) {
+	tasks, _ := LoadTasks(filepath.Join(t.TempDir(), TasksFile))
+	for _, task := range []Task{
+		{Prompt: "add tests"},
+		{Prompt: "fix lint", Priority: "low"},
+		{Prompt: "update docs", Priority: "high"},
+	} {
+		if _, err := tasks.Submit(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An urgent task parks the running one, which goes next after it
+	running, _, _ := tasks.Next("run-1")
+	if running.ID != 3 {
+		t.Fatalf("Next() = task %d, want the high priority task 3", running.ID)
+	}
+	if _, ok, _ := tasks.Preempt(); ok {
+		t.Error("Preempt() parked a task with no urgent one waiting")
+	}
+	if bumped, err := tasks.Bump(2, Urgent); err != nil || bumped.Priority != Urgent {
+		t.Fatalf("Bump() = %+v, %v", bumped, err)
+	}
+	if parked, ok, _ := tasks.Preempt(); !ok || parked.ID != 3 {
+		t.Fatalf("Preempt() = %+v, %v, want task 3 parked", parked, ok)
+	}
+	if err := tasks.Finish(3, 0.25, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if task, _ := tasks.Get(3); task.Status != Parked || !task.Finished.IsZero() {
+		t.Errorf("task 3 = %+v, want it parked", task)
+	}
+	var order []int
+	for {
+		task, ok, _ := tasks.Next("run-new")
+		if !ok {
+			break
+		}
+		order = append(order, task.ID)
+		if task.ID == 3 && task.RunID != "run-1" {
+			t.Errorf("parked task 3 got run %s, want its own run-1", task.RunID)
+		}
+		_ = tasks.Finish(task.ID, 0, nil, false)
+	}
+	if !reflect.DeepEqual(order, []int{2, 3, 1}) {
+		t.Errorf("ran tasks %v, want [2 3 1]", order)
+	}
+
+	if _, err := tasks.Bump(1, ""); err == nil {
+		t.Error("Bump() of a finished task should fail")
+	}
+	if _, err := ParsePriority("asap"); err == nil {
+		t.Error("ParsePriority() accepted an unknown priority")
+	}
+}
+
+func TestBumpRaisesOneLevel(t *testing.T) {
+	tasks, _ := LoadTasks(filepath.Join(t.TempDir(), TasksFile))
+	_, _ = tasks.Submit(Task{Prompt: "add tests", Priority: "low"})
+	for _, want := range []string{"normal", "high", Urgent} {
+		if task, err := tasks.Bump(1, ""); err != nil || task.Priority != want {
+			t.Errorf("Bump() = %q, %v, want %q", task.Priority, err, want)
+		}
+	}
+	if _, err := tasks.Bump(1, ""); err == nil {
+		t.Error("Bump() of an urgent task should fail")
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// tokenPrefix marks the tokens team members authenticate with.
+const tokenPrefix = "dct_"
+
+// User is a team member who may use the server.
+type User struct {
+	Name string `json:"name"`
+	// TokenHash is the SHA-256 of the user's token; the token itself is
+	// only shown when the user is added
+	TokenHash string `json:"token_hash"`
+	// Budget caps what the user's tasks may spend in total, in USD; 0 is
+	// no cap
+	Budget float64 `json:"budget,omitempty"`
+}
+
+// Users is the team, kept in a JSON file.
+type Users struct {
+	mu   sync.Mutex
+	path string
+	list []User
+}
+
+// LoadUsers reads the team from path; a missing file is an empty team.
+func LoadUsers(path string) (*Users, error) {
+	u := &Users{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return u, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users: %w", err)
+	}
+	if err := json.Unmarshal(data, &u.list); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return u, nil
+}
+
+// Add adds a user with a new token, which it returns.
+func (u *Users) Add(name string, budget float64) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("user name is required")
+	}
+	if budget < 0 {
+		return "", fmt.Errorf("budget must be non-negative")
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if slices.ContainsFunc(u.list, func(user User) bool { return user.Name == name }) {
+		return "", fmt.Errorf("user %s already exists", name)
+	}
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := tokenPrefix + hex.EncodeToString(secret)
+	u.list = append(u.list, User{Name: name, TokenHash: hashToken(token), Budget: budget})
+	if err := u.save(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Remove removes a user, whose token stops working.
+func (u *Users) Remove(name string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	i := slices.IndexFunc(u.list, func(user User) bool { return user.Name == name })
+	if i < 0 {
+		return fmt.Errorf("user %s not found", name)
+	}
+	u.list = slices.Delete(u.list, i, i+1)
+	return u.save()
+}
+
+// List returns the users in the order they were added.
+func (u *Users) List() []User {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return slices.Clone(u.list)
+}
+
+// Authenticate returns the user a token belongs to.
+func (u *Users) Authenticate(token string) (User, bool) {
+	hash := hashToken(token)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, user := range u.list {
+		if subtle.ConstantTimeCompare([]byte(user.TokenHash), []byte(hash)) == 1 {
+			return user, true
+		}
+	}
+	return User{}, false
+}
+
+// save writes the team, readable only by its owner since it vouches for
+// who may spend on the server.
+func (u *Users) save() error {
+	if err := os.MkdirAll(filepath.Dir(u.path), 0700); err != nil {
+		return fmt.Errorf("failed to create server directory: %w", err)
+	}
+	data, err := json.MarshalIndent(u.list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := u.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to save users: %w", err)
+	}
+	return os.Rename(tmp, u.path)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUsers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), UsersFile)
+	users, err := LoadUsers(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := users.Add("alice", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, tokenPrefix) {
+		t.Errorf("token = %q, want prefix %q", token, tokenPrefix)
+	}
+	if _, err := users.Add("alice", 0); err == nil {
+		t.Error("Add() of an existing user should fail")
+	}
+	if _, err := users.Add("bob", -1); err == nil {
+		t.Error("Add() with a negative budget should fail")
+	}
+
+	// Reloaded from disk, with only the hash of the token kept
+	users, err = LoadUsers(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := users.List(); len(list) != 1 || list[0].TokenHash == token || list[0].Budget != 20 {
+		t.Errorf("users = %+v", list)
+	}
+	if user, ok := users.Authenticate(token); !ok || user.Name != "alice" {
+		t.Errorf("Authenticate() = %v, %v, want alice", user, ok)
+	}
+	if _, ok := users.Authenticate(token + "x"); ok {
+		t.Error("Authenticate() accepted a wrong token")
+	}
+
+	if err := users.Remove("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := users.Authenticate(token); ok {
+		t.Error("Authenticate() accepted the token of a removed user")
+	}
+	if err := users.Remove("alice"); err == nil {
+		t.Error("Remove() of a missing user should fail")
+	}
+}
@@ -0,0 +1,297 @@
+package settings
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaID is where the published JSON schema of the config files lives.
+const SchemaID = "https://raw.githubusercontent.com/guzus/deep-claude/main/config.schema.json"
+
+// Flag describes a flag the config files can set.
+type Flag struct {
+	Name  string
+	Usage string
+	// Type is its value's JSON type: string, boolean, integer or number
+	Type string
+	// Repeatable flags take a list of values too
+	Repeatable bool
+	// Choices are the only values it takes, if it has a few
+	Choices []string
+}
+
+// Schema is a JSON schema, in the subset of draft 2020-12 needed to
+// describe the config files.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+// NewSchema describes the config files that can set the given flags.
+func NewSchema(flags []Flag) *Schema {
+	closed := false
+	properties := make(map[string]*Schema, len(flags))
+	lockable := []string{TelemetryKey}
+	for _, f := range flags {
+		value := &Schema{Type: f.Type, Enum: f.Choices}
+		if f.Repeatable {
+			value = &Schema{AnyOf: []*Schema{value, {Type: "array", Items: value}}}
+		}
+		value.Description = f.Usage
+		properties[f.Name] = value
+		lockable = append(lockable, f.Name)
+	}
+	sort.Strings(lockable)
+
+	return &Schema{
+		Schema:               "https://json-schema.org/draft/2020-12/schema",
+		ID:                   SchemaID,
+		Title:                "Deep Claude config",
+		Description:          "An organization, user (~/.config/deep-claude/config.yaml) or repository (" + RepoFile + ") config file",
+		Type:                 "object",
+		AdditionalProperties: &closed,
+		Properties: map[string]*Schema{
+			"flags": {
+				Description:          "Flag defaults, by the flags' long names",
+				Type:                 "object",
+				Properties:           properties,
+				AdditionalProperties: &closed,
+			},
+			"locked": {
+				Description: "Flags that can't be changed (organization config only)",
+				Type:        "array",
+				Items:       &Schema{Type: "string", Enum: lockable},
+			},
+			"caps": {
+				Description:          "Upper bounds on the run limits (organization config only)",
+				Type:                 "object",
+				AdditionalProperties: &closed,
+				Properties: map[string]*Schema{
+					"max-runs":     {Description: "Most iterations a run may have", Type: "integer"},
+					"max-cost":     {Description: "Most a run may spend, in USD", Type: "number"},
+					"max-duration": {Description: "Longest a run may take, e.g. 4h or 1d", Type: "string"},
+				},
+			},
+			TelemetryKey: {Description: "Send anonymous usage reports", Type: "boolean"},
+		},
+	}
+}
+
+// Problem is a place where a config file doesn't match the schema.
+type Problem struct {
+	Source string
+	Line   int
+	Column int
+	// Path is the key it is at, e.g. flags.exclude[1]
+	Path    string
+	Message string
+}
+
+// String formats the problem as source:line:column: path: message, or
+// leaves out what it doesn't know.
+func (p Problem) String() string {
+	var b strings.Builder
+	b.WriteString(p.Source)
+	if p.Line > 0 {
+		fmt.Fprintf(&b, ":%d", p.Line)
+	}
+	if p.Column > 0 {
+		fmt.Fprintf(&b, ":%d", p.Column)
+	}
+	b.WriteString(": ")
+	if p.Path != "" {
+		b.WriteString(p.Path + ": ")
+	}
+	b.WriteString(p.Message)
+	return b.String()
+}
+
+// yamlErrorLine matches the line number in a YAML syntax error.
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// Check reports the places where a config file doesn't match the schema.
+// Values of string flags may be any scalar, as on the command line.
+func (s *Schema) Check(in Input) []Problem {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(in.Data, &doc); err != nil {
+		problem := Problem{Source: in.Source, Message: err.Error()}
+		if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+			problem.Line, _ = strconv.Atoi(m[1])
+			problem.Message = m[2]
+		}
+		return []Problem{problem}
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	c := &checker{source: in.Source}
+	c.check(s, doc.Content[0], "")
+	return c.problems
+}
+
+// checker collects the problems of one config file.
+type checker struct {
+	source   string
+	problems []Problem
+}
+
+func (c *checker) report(node *yaml.Node, path, format string, args ...any) {
+	c.problems = append(c.problems, Problem{
+		Source:  c.source,
+		Line:    node.Line,
+		Column:  node.Column,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *checker) check(s *Schema, node *yaml.Node, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if len(s.AnyOf) > 0 {
+		// Only a value or a list of values, so the shape picks the one
+		alt := s.AnyOf[0]
+		for _, a := range s.AnyOf {
+			if (a.Type == "array") == (node.Kind == yaml.SequenceNode) {
+				alt = a
+				break
+			}
+		}
+		c.check(alt, node, path)
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			c.report(node, path, "expected a mapping")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			at := key.Value
+			if path != "" {
+				at = path + "." + key.Value
+			}
+			property, ok := s.Properties[key.Value]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					c.report(key, at, "unknown key%s", suggest(key.Value, keys(s.Properties)))
+				}
+				continue
+			}
+			c.check(property, value, at)
+		}
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			c.report(node, path, "expected a list")
+			return
+		}
+		for i, item := range node.Content {
+			c.check(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case "":
+	default:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() == "!!null" {
+			c.report(node, path, "expected %s", describe(s.Type))
+			return
+		}
+		if !scalarIs(s.Type, node.ShortTag()) {
+			c.report(node, path, "expected %s, got %q", describe(s.Type), node.Value)
+			return
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, node.Value) {
+			if len(s.Enum) > 10 {
+				c.report(node, path, "unknown value %q%s", node.Value, suggest(node.Value, s.Enum))
+			} else {
+				c.report(node, path, "%q is not one of: %s", node.Value, strings.Join(s.Enum, ", "))
+			}
+		}
+	}
+}
+
+// scalarIs tells whether a YAML scalar with the given tag has a JSON type.
+func scalarIs(jsonType, tag string) bool {
+	switch jsonType {
+	case "boolean":
+		return tag == "!!bool"
+	case "integer":
+		return tag == "!!int"
+	case "number":
+		return tag == "!!int" || tag == "!!float"
+	}
+	return true
+}
+
+// describe names the values of a JSON type.
+func describe(jsonType string) string {
+	switch jsonType {
+	case "boolean":
+		return "true or false"
+	case "integer":
+		return "a whole number"
+	case "number":
+		return "a number"
+	}
+	return "a value"
+}
+
+func keys(properties map[string]*Schema) []string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// suggest returns a "did you mean" hint for the closest of names, if one
+// is within a typo of word.
+func suggest(word string, names []string) string {
+	best, bestDistance := "", 3
+	for _, name := range names {
+		if d := distance(word, name); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// distance is the Levenshtein distance between two strings.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,136 @@
+package settings
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var testFlags = []Flag{
+	{Name: "merge-strategy", Type: "string", Choices: []string{"squash", "merge", "rebase"}},
+	{Name: "max-cost", Type: "number"},
+	{Name: "max-runs", Type: "integer"},
+	{Name: "verbose", Type: "boolean"},
+	{Name: "verify-cmd", Type: "string"},
+	{Name: "exclude", Type: "string", Repeatable: true},
+}
+
+func TestSchemaCheck(t *testing.T) {
+	schema := NewSchema(testFlags)
+
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "valid",
+			yaml: orgConfig,
+		},
+		{
+			name: "empty",
+			yaml: "",
+		},
+		{
+			name: "string flags take any scalar",
+			yaml: "flags:\n  verify-cmd: 7\n  exclude: [a, 1]\n",
+		},
+		{
+			name: "typo in a nested key",
+			yaml: "flags:\n  merge-stratgy: rebase\n",
+			want: []string{`test.yaml:2:3: flags.merge-stratgy: unknown key (did you mean "merge-strategy"?)`},
+		},
+		{
+			name: "unknown top-level key",
+			yaml: "flag:\n  verbose: true\ncolor: red\n",
+			want: []string{
+				`test.yaml:1:1: flag: unknown key (did you mean "flags"?)`,
+				`test.yaml:3:1: color: unknown key`,
+			},
+		},
+		{
+			name: "wrong types",
+			yaml: "flags:\n  max-cost: lots\n  verbose: yes please\n  max-runs: 1.5\ncaps:\n  max-runs: []\n",
+			want: []string{
+				`test.yaml:2:13: flags.max-cost: expected a number, got "lots"`,
+				`test.yaml:3:12: flags.verbose: expected true or false, got "yes please"`,
+				`test.yaml:4:13: flags.max-runs: expected a whole number, got "1.5"`,
+				`test.yaml:6:13: caps.max-runs: expected a whole number`,
+			},
+		},
+		{
+			name: "choices",
+			yaml: "flags:\n  merge-strategy: fast-forward\n",
+			want: []string{`test.yaml:2:19: flags.merge-strategy: "fast-forward" is not one of: squash, merge, rebase`},
+		},
+		{
+			name: "lists",
+			yaml: "flags:\n  exclude:\n    - a\n    - {b: c}\n  verbose: [true]\nlocked: [exclud]\n",
+			want: []string{
+				`test.yaml:4:7: flags.exclude[1]: expected a value`,
+				`test.yaml:5:12: flags.verbose: expected true or false`,
+				`test.yaml:6:10: locked[0]: "exclud" is not one of: exclude, max-cost, max-runs, merge-strategy, telemetry, verbose, verify-cmd`,
+			},
+		},
+		{
+			name: "not a mapping",
+			yaml: "flags: verbose\n",
+			want: []string{`test.yaml:1:8: flags: expected a mapping`},
+		},
+		{
+			name: "syntax error",
+			yaml: "flags:\n  a: b\n c: d\n",
+			want: []string{`test.yaml:2: did not find expected key`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range schema.Check(Input{Name: "repository", Source: "test.yaml", Data: []byte(tt.yaml)}) {
+				got = append(got, p.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSchema(t *testing.T) {
+	schema := NewSchema(testFlags)
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	if decoded["$id"] != SchemaID || decoded["additionalProperties"] != false {
+		t.Errorf("schema = %s", data)
+	}
+
+	exclude := schema.Properties["flags"].Properties["exclude"]
+	if len(exclude.AnyOf) != 2 || exclude.AnyOf[1].Type != "array" || exclude.AnyOf[1].Items.Type != "string" {
+		t.Errorf("repeatable flag schema = %+v", exclude)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"flags", "flags", 0},
+		{"flag", "flags", 1},
+		{"merge-stratgy", "merge-strategy", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
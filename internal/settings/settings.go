@@ -0,0 +1,445 @@
+// Package settings layers flag defaults from configuration files: an
+// organization's, the user's and the repository's, in increasing priority.
+// The organization can lock flags and cap the run limits.
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// OrgEnv names the environment variable pointing to the organization's
+// config, a file path or an http(s) URL.
+const OrgEnv = "DEEP_CLAUDE_ORG_CONFIG"
+
+// DefaultOrgPath is read when OrgEnv is unset, e.g. a file mounted by IT.
+const DefaultOrgPath = "/etc/deep-claude/config.yaml"
+
+// RepoFile is the repository's config, at the repository root.
+const RepoFile = ".deep-claude.yaml"
+
+// TelemetryKey is the config switch for anonymous usage reports, which
+// the organization can lock like a flag.
+const TelemetryKey = "telemetry"
+
+// Values holds a flag's value, or several for a repeatable flag.
+type Values []string
+
+// UnmarshalYAML accepts a scalar or a list of scalars.
+func (v *Values) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*v = Values{node.Value}
+	case yaml.SequenceNode:
+		values := make(Values, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: list items must be plain values", item.Line)
+			}
+			values = append(values, item.Value)
+		}
+		*v = values
+	default:
+		return fmt.Errorf("line %d: expected a value or a list of values", node.Line)
+	}
+	return nil
+}
+
+// Caps are upper bounds on the run limits.
+type Caps struct {
+	MaxRuns     int     `yaml:"max-runs"`
+	MaxCost     float64 `yaml:"max-cost"`
+	MaxDuration string  `yaml:"max-duration"`
+}
+
+// File is one configuration file. Only the organization's may lock flags
+// and set caps.
+type File struct {
+	Flags     map[string]Values `yaml:"flags"`
+	Locked    []string          `yaml:"locked"`
+	Caps      Caps              `yaml:"caps"`
+	Telemetry *bool             `yaml:"telemetry"`
+}
+
+// Layer is a parsed configuration file and where it came from.
+type Layer struct {
+	Name   string
+	Source string
+	File   File
+}
+
+// Parse decodes a configuration file.
+func Parse(name, source string, data []byte) (*Layer, error) {
+	layer := &Layer{Name: name, Source: source}
+	if err := yaml.Unmarshal(data, &layer.File); err != nil {
+		return nil, fmt.Errorf("failed to parse %s config %s: %w", name, source, err)
+	}
+	if layer.File.Caps.MaxDuration != "" {
+		if _, err := config.ParseDuration(layer.File.Caps.MaxDuration); err != nil {
+			return nil, fmt.Errorf("%s config %s: caps: %w", name, source, err)
+		}
+	}
+	return layer, nil
+}
+
+// Input is a configuration file as read, before parsing.
+type Input struct {
+	Name   string
+	Source string
+	Data   []byte
+}
+
+// Load reads and parses the config files, lowest priority first.
+func Load(workDir string) ([]*Layer, error) {
+	inputs, err := Read(workDir)
+	if err != nil {
+		return nil, err
+	}
+	layers := make([]*Layer, 0, len(inputs))
+	for _, in := range inputs {
+		layer, err := Parse(in.Name, in.Source, in.Data)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+// Read reads the organization's, the user's and the repository's config,
+// those that exist, lowest priority first. An organization config named
+// by OrgEnv must be readable, so its guardrails can't silently vanish.
+func Read(workDir string) ([]Input, error) {
+	var inputs []Input
+
+	if source := os.Getenv(OrgEnv); source != "" {
+		data, err := fetch(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read organization config from %s (%s): %w", OrgEnv, source, err)
+		}
+		inputs = append(inputs, Input{Name: "organization", Source: source, Data: data})
+	} else if in, err := readFile("organization", DefaultOrgPath); err != nil {
+		return nil, err
+	} else if in != nil {
+		inputs = append(inputs, *in)
+	}
+
+	if path, err := UserPath(); err == nil {
+		in, err := readFile("user", path)
+		if err != nil {
+			return nil, err
+		}
+		if in != nil {
+			inputs = append(inputs, *in)
+		}
+	}
+
+	in, err := readFile("repository", filepath.Join(workDir, RepoFile))
+	if err != nil {
+		return nil, err
+	}
+	if in != nil {
+		inputs = append(inputs, *in)
+	}
+	return inputs, nil
+}
+
+// UserPath returns the path of the user's config.
+func UserPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user config directory: %w", err)
+	}
+	return filepath.Join(dir, "deep-claude", "config.yaml"), nil
+}
+
+// SetUserTelemetry turns telemetry on or off in the user's config,
+// keeping the rest of the file as it is.
+func SetUserTelemetry(enabled bool) error {
+	return editUserConfig(func(root *yaml.Node) error {
+		setKey(root, TelemetryKey, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(enabled)})
+		return nil
+	})
+}
+
+// SetUserFlag remembers a flag's value in the user's config, keeping the
+// rest of the file as it is.
+func SetUserFlag(name, value string) error {
+	return editUserConfig(func(root *yaml.Node) error {
+		flags := getKey(root, "flags")
+		if flags == nil || flags.Kind != yaml.MappingNode {
+			flags = &yaml.Node{Kind: yaml.MappingNode}
+			setKey(root, "flags", flags)
+		}
+		if current := getKey(flags, name); current != nil && current.Kind == yaml.ScalarNode && current.Value == value {
+			return errUnchanged
+		}
+		setKey(flags, name, &yaml.Node{Kind: yaml.ScalarNode, Value: value})
+		return nil
+	})
+}
+
+// SetFlags sets flags in the config at path, creating it if needed and
+// keeping the rest of the file as it is. Flags without values are removed.
+func SetFlags(path string, flags map[string]Values) error {
+	return editConfig(path, func(root *yaml.Node) error {
+		section := getKey(root, "flags")
+		if section == nil || section.Kind != yaml.MappingNode {
+			section = &yaml.Node{Kind: yaml.MappingNode}
+			setKey(root, "flags", section)
+		}
+		names := make([]string, 0, len(flags))
+		for name := range flags {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			values := flags[name]
+			switch len(values) {
+			case 0:
+				deleteKey(section, name)
+			case 1:
+				setKey(section, name, &yaml.Node{Kind: yaml.ScalarNode, Value: values[0]})
+			default:
+				list := &yaml.Node{Kind: yaml.SequenceNode}
+				for _, v := range values {
+					list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: v})
+				}
+				setKey(section, name, list)
+			}
+		}
+		return nil
+	})
+}
+
+// errUnchanged tells editConfig there is nothing to write.
+var errUnchanged = errors.New("unchanged")
+
+// editUserConfig applies edit to the user's config, creating it if needed.
+func editUserConfig(edit func(root *yaml.Node) error) error {
+	path, err := UserPath()
+	if err != nil {
+		return err
+	}
+	return editConfig(path, edit)
+}
+
+// editConfig applies edit to the config at path, creating it if needed.
+func editConfig(path string, edit func(root *yaml.Node) error) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config %s is not a mapping", path)
+	}
+
+	if err := edit(root); err == errUnchanged {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// getKey returns the value of key in a mapping node, or nil.
+func getKey(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// deleteKey removes key from a mapping node, if it is there.
+func deleteKey(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// setKey sets key in a mapping node, appending it if missing.
+func setKey(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// readFile reads the config at path, or returns nil if there is none.
+func readFile(name, path string) (*Input, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s config: %w", name, err)
+	}
+	return &Input{Name: name, Source: path, Data: data}, nil
+}
+
+// fetch reads a config from a file or an http(s) URL.
+func fetch(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// Setting is the value a flag gets from the config files.
+type Setting struct {
+	Values Values
+	// Name of the layer it came from
+	From   string
+	Locked bool
+}
+
+// Resolved is the outcome of layering the config files.
+type Resolved struct {
+	Settings map[string]Setting
+	Caps     Caps
+	// Where the caps came from, if any
+	CapsFrom string
+
+	// The telemetry switch, if a config sets it
+	Telemetry       *bool
+	TelemetryFrom   string
+	TelemetryLocked bool
+}
+
+// Merge layers the configs, later ones overriding earlier ones. Locks and
+// caps are only honored in the first, organization layer; other layers may
+// not set a locked flag.
+func Merge(layers []*Layer) (*Resolved, error) {
+	resolved := &Resolved{Settings: make(map[string]Setting)}
+	locked := make(map[string]bool)
+
+	for i, layer := range layers {
+		org := i == 0 && layer.Name == "organization"
+		if !org && (len(layer.File.Locked) > 0 || layer.File.Caps != (Caps{})) {
+			return nil, fmt.Errorf("%s config %s: only the organization config can lock flags or set caps", layer.Name, layer.Source)
+		}
+
+		for name, values := range layer.File.Flags {
+			if locked[name] {
+				return nil, fmt.Errorf("%s config %s sets --%s, which is locked by the organization config", layer.Name, layer.Source, name)
+			}
+			resolved.Settings[name] = Setting{Values: values, From: layer.Name}
+		}
+		if layer.File.Telemetry != nil {
+			if resolved.TelemetryLocked {
+				return nil, fmt.Errorf("%s config %s sets %s, which is locked by the organization config", layer.Name, layer.Source, TelemetryKey)
+			}
+			resolved.Telemetry, resolved.TelemetryFrom = layer.File.Telemetry, layer.Name
+		}
+
+		if org {
+			for _, name := range layer.File.Locked {
+				if name == TelemetryKey {
+					if resolved.Telemetry == nil {
+						return nil, fmt.Errorf("organization config %s locks %s without setting it", layer.Source, TelemetryKey)
+					}
+					// Telemetry is opt-in, so users can always turn it off
+					if *resolved.Telemetry {
+						return nil, fmt.Errorf("organization config %s locks %s on, but it can only be locked off", layer.Source, TelemetryKey)
+					}
+					resolved.TelemetryLocked = true
+					continue
+				}
+				setting, ok := resolved.Settings[name]
+				if !ok {
+					return nil, fmt.Errorf("organization config %s locks --%s without setting it", layer.Source, name)
+				}
+				setting.Locked = true
+				resolved.Settings[name] = setting
+				locked[name] = true
+			}
+			if layer.File.Caps != (Caps{}) {
+				resolved.Caps = layer.File.Caps
+				resolved.CapsFrom = layer.Source
+			}
+		}
+	}
+	return resolved, nil
+}
+
+// ApplyCaps holds the run limits to the caps: an unset limit takes the
+// cap, and one above it is an error.
+func (r *Resolved) ApplyCaps(cfg *config.Config) error {
+	caps := r.Caps
+	if caps.MaxRuns > 0 {
+		if cfg.MaxRuns > caps.MaxRuns {
+			return fmt.Errorf("--max-runs %d is above the organization cap of %d", cfg.MaxRuns, caps.MaxRuns)
+		}
+		if cfg.MaxRuns == 0 {
+			cfg.MaxRuns = caps.MaxRuns
+		}
+	}
+	if caps.MaxCost > 0 {
+		if cfg.MaxCost > caps.MaxCost {
+			return fmt.Errorf("--max-cost $%.2f is above the organization cap of $%.2f", cfg.MaxCost, caps.MaxCost)
+		}
+		if cfg.MaxCost == 0 {
+			cfg.MaxCost = caps.MaxCost
+		}
+	}
+	if caps.MaxDuration != "" {
+		limit, err := config.ParseDuration(caps.MaxDuration)
+		if err != nil {
+			return err
+		}
+		if cfg.MaxDuration > limit {
+			return fmt.Errorf("--max-duration %s is above the organization cap of %s", config.FormatDuration(cfg.MaxDuration), config.FormatDuration(limit))
+		}
+		if cfg.MaxDuration == 0 {
+			cfg.MaxDuration = limit
+		}
+	}
+	return nil
+}
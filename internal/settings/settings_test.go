@@ -0,0 +1,306 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guzus/deep-claude/pkg/config"
+)
+
+func mustParse(t *testing.T, name, yamlText string) *Layer {
+	t.Helper()
+	layer, err := Parse(name, name+".yaml", []byte(yamlText))
+	if err != nil {
+		t.Fatalf("Parse(%s) unexpected error: %v", name, err)
+	}
+	return layer
+}
+
+const orgConfig = `
+flags:
+  merge-strategy: rebase
+  max-cost: 10
+  exclude:
+    - .github/workflows/**
+    - infra/**
+locked: [merge-strategy, exclude]
+caps:
+  max-cost: 50
+  max-duration: 4h
+`
+
+func TestMerge(t *testing.T) {
+	layers := []*Layer{
+		mustParse(t, "organization", orgConfig),
+		mustParse(t, "user", "flags:\n  max-cost: 20\n  verbose: true\n"),
+		mustParse(t, "repository", "flags:\n  verify-cmd: go test ./...\n  max-cost: 5\n"),
+	}
+
+	resolved, err := Merge(layers)
+	if err != nil {
+		t.Fatalf("Merge() unexpected error: %v", err)
+	}
+
+	want := map[string]Setting{
+		"merge-strategy": {Values: Values{"rebase"}, From: "organization", Locked: true},
+		"exclude":        {Values: Values{".github/workflows/**", "infra/**"}, From: "organization", Locked: true},
+		"max-cost":       {Values: Values{"5"}, From: "repository"},
+		"verbose":        {Values: Values{"true"}, From: "user"},
+		"verify-cmd":     {Values: Values{"go test ./..."}, From: "repository"},
+	}
+	if !reflect.DeepEqual(resolved.Settings, want) {
+		t.Errorf("Merge() settings = %+v, want %+v", resolved.Settings, want)
+	}
+	if resolved.Caps.MaxCost != 50 || resolved.Caps.MaxDuration != "4h" || resolved.CapsFrom != "organization.yaml" {
+		t.Errorf("Merge() caps = %+v from %q", resolved.Caps, resolved.CapsFrom)
+	}
+}
+
+func TestMergeRejectsOverrides(t *testing.T) {
+	tests := []struct {
+		name   string
+		layers []*Layer
+		errMsg string
+	}{
+		{
+			"locked flag set by repository",
+			[]*Layer{mustParse(t, "organization", orgConfig), mustParse(t, "repository", "flags:\n  merge-strategy: squash\n")},
+			"locked by the organization",
+		},
+		{
+			"lock outside organization config",
+			[]*Layer{mustParse(t, "user", "flags:\n  verbose: true\nlocked: [verbose]\n")},
+			"only the organization config",
+		},
+		{
+			"caps outside organization config",
+			[]*Layer{mustParse(t, "repository", "caps:\n  max-cost: 100\n")},
+			"only the organization config",
+		},
+		{
+			"lock without a value",
+			[]*Layer{mustParse(t, "organization", "locked: [max-runs]\n")},
+			"without setting it",
+		},
+		{
+			"locked telemetry set by user",
+			[]*Layer{mustParse(t, "organization", "telemetry: false\nlocked: [telemetry]\n"), mustParse(t, "user", "telemetry: true\n")},
+			"locked by the organization",
+		},
+		{
+			"telemetry locked on",
+			[]*Layer{mustParse(t, "organization", "telemetry: true\nlocked: [telemetry]\n")},
+			"can only be locked off",
+		},
+		{
+			"telemetry locked without a value",
+			[]*Layer{mustParse(t, "organization", "locked: [telemetry]\n")},
+			"without setting it",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Merge(tt.layers)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Merge() error = %v, want one containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestParseRejectsNestedValues(t *testing.T) {
+	if _, err := Parse("user", "config.yaml", []byte("flags:\n  exclude:\n    - a: b\n")); err == nil {
+		t.Error("Parse() expected error for a nested list item")
+	}
+	if _, err := Parse("organization", "org.yaml", []byte("caps:\n  max-duration: soon\n")); err == nil {
+		t.Error("Parse() expected error for an invalid cap duration")
+	}
+}
+
+func TestApplyCaps(t *testing.T) {
+	resolved := &Resolved{Caps: Caps{MaxRuns: 10, MaxCost: 50, MaxDuration: "4h"}}
+
+	cfg := &config.Config{MaxCost: 20}
+	if err := resolved.ApplyCaps(cfg); err != nil {
+		t.Fatalf("ApplyCaps() unexpected error: %v", err)
+	}
+	if cfg.MaxRuns != 10 || cfg.MaxCost != 20 || cfg.MaxDuration != 4*time.Hour {
+		t.Errorf("ApplyCaps() = runs %d, cost %v, duration %s", cfg.MaxRuns, cfg.MaxCost, cfg.MaxDuration)
+	}
+
+	for _, over := range []*config.Config{{MaxRuns: 11}, {MaxCost: 51}, {MaxDuration: 5 * time.Hour}} {
+		if err := resolved.ApplyCaps(over); err == nil {
+			t.Errorf("ApplyCaps(%+v) expected error", over)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
+
+	orgPath := filepath.Join(t.TempDir(), "org.yaml")
+	if err := os.WriteFile(orgPath, []byte(orgConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(OrgEnv, orgPath)
+
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, RepoFile), []byte("flags:\n  max-runs: 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	layers, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	var names []string
+	for _, l := range layers {
+		names = append(names, l.Name)
+	}
+	if want := []string{"organization", "repository"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Load() layers = %v, want %v", names, want)
+	}
+
+	t.Setenv(OrgEnv, filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := Load(repo); err == nil {
+		t.Error("Load() expected error when the organization config can't be read")
+	}
+}
+
+func TestMergeTelemetry(t *testing.T) {
+	resolved, err := Merge([]*Layer{
+		mustParse(t, "user", "telemetry: true\n"),
+		mustParse(t, "repository", "telemetry: false\n"),
+	})
+	if err != nil {
+		t.Fatalf("Merge() unexpected error: %v", err)
+	}
+	if resolved.Telemetry == nil || *resolved.Telemetry || resolved.TelemetryFrom != "repository" || resolved.TelemetryLocked {
+		t.Errorf("Merge() telemetry = %v from %q, locked %v", resolved.Telemetry, resolved.TelemetryFrom, resolved.TelemetryLocked)
+	}
+}
+
+func TestSetUserTelemetry(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
+
+	if err := SetUserTelemetry(true); err != nil {
+		t.Fatalf("SetUserTelemetry() unexpected error: %v", err)
+	}
+	path, err := UserPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := "# my settings\nflags:\n  max-runs: 3\ntelemetry: true\n"
+	if err := os.WriteFile(path, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetUserTelemetry(false); err != nil {
+		t.Fatalf("SetUserTelemetry() unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer := mustParse(t, "user", string(data))
+	if layer.File.Telemetry == nil || *layer.File.Telemetry {
+		t.Errorf("telemetry not turned off:\n%s", data)
+	}
+	if !strings.Contains(string(data), "# my settings") || layer.File.Flags["max-runs"][0] != "3" {
+		t.Errorf("rest of the config not kept:\n%s", data)
+	}
+}
+
+func TestSetUserFlag(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
+
+	path, err := UserPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetUserFlag("channel", "beta"); err != nil {
+		t.Fatalf("SetUserFlag() unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layer := mustParse(t, "user", string(data)); layer.File.Flags["channel"][0] != "beta" {
+		t.Errorf("channel not set:\n%s", data)
+	}
+
+	existing := "# my settings\nflags:\n  max-runs: 3\n  channel: beta\n"
+	if err := os.WriteFile(path, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetUserFlag("channel", "nightly"); err != nil {
+		t.Fatalf("SetUserFlag() unexpected error: %v", err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer := mustParse(t, "user", string(data))
+	if layer.File.Flags["channel"][0] != "nightly" {
+		t.Errorf("channel not changed:\n%s", data)
+	}
+	if !strings.Contains(string(data), "# my settings") || layer.File.Flags["max-runs"][0] != "3" {
+		t.Errorf("rest of the config not kept:\n%s", data)
+	}
+}
+
+func TestSetFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), RepoFile)
+	existing := "# team defaults\nflags:\n  verbose: true\n  max-runs: 3\ntelemetry: false\n"
+	if err := os.WriteFile(path, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := SetFlags(path, map[string]Values{
+		"max-cost": {"10"},
+		"max-runs": nil,
+		"exclude":  {"*.log", "infra/**"},
+	})
+	if err != nil {
+		t.Fatalf("SetFlags() unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer := mustParse(t, "repository", string(data))
+	want := map[string]Values{
+		"verbose":  {"true"},
+		"max-cost": {"10"},
+		"exclude":  {"*.log", "infra/**"},
+	}
+	if !reflect.DeepEqual(layer.File.Flags, want) {
+		t.Errorf("flags = %v, want %v", layer.File.Flags, want)
+	}
+	if !strings.Contains(string(data), "# team defaults") || layer.File.Telemetry == nil {
+		t.Errorf("rest of the config not kept:\n%s", data)
+	}
+
+	newPath := filepath.Join(t.TempDir(), "new", "config.yaml")
+	if err := SetFlags(newPath, map[string]Values{"max-runs": {"5"}}); err != nil {
+		t.Fatalf("SetFlags() unexpected error: %v", err)
+	}
+	data, err = os.ReadFile(newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layer := mustParse(t, "user", string(data)); !reflect.DeepEqual(layer.File.Flags, map[string]Values{"max-runs": {"5"}}) {
+		t.Errorf("new config = %s", data)
+	}
+}
@@ -0,0 +1,25 @@
+// Package shell quotes words for POSIX shell command lines.
+package shell
+
+import "strings"
+
+// safe are the characters a word needs no quotes for.
+const safe = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@%+,"
+
+// Quote returns word as the shell reads it back: as is if that is safe,
+// otherwise in single quotes.
+func Quote(word string) string {
+	if word != "" && strings.Trim(word, safe) == "" {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+// Join quotes words as a command line.
+func Join(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = Quote(word)
+	}
+	return strings.Join(quoted, " ")
+}
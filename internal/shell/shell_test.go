@@ -0,0 +1,22 @@
+package shell
+
+import "testing"
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		words    []string
+		expected string
+	}{
+		{[]string{"/usr/bin/dclaude", "--detached-config", "/tmp/run.json"}, "/usr/bin/dclaude --detached-config /tmp/run.json"},
+		{[]string{"/Users/me/My Tools/dclaude"}, "'/Users/me/My Tools/dclaude'"},
+		{[]string{"-p", "don't say \"hi\""}, `-p 'don'\''t say "hi"'`},
+		{[]string{"echo", ""}, "echo ''"},
+		{[]string{"echo", "$HOME"}, "echo '$HOME'"},
+	}
+
+	for _, tt := range tests {
+		if result := Join(tt.words); result != tt.expected {
+			t.Errorf("Join(%q) = %s, want %s", tt.words, result, tt.expected)
+		}
+	}
+}
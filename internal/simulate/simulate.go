@@ -0,0 +1,200 @@
+// Package simulate runs the loop against an in-memory forge and a scripted
+// Claude, described by a YAML scenario file, for --simulate.
+package simulate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/fake"
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/github"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario scripts what Claude does each iteration and how the forge
+// treats the PRs that come of it.
+type Scenario struct {
+	// Repo is owner/name; by default the current repository's
+	Repo string `yaml:"repo"`
+	// Files are the tracked files; by default the current repository's
+	Files []string `yaml:"files"`
+	// Turns are Claude's answers, one per iteration
+	Turns []Turn `yaml:"turns"`
+	// Checks are the outcomes of PRs by the order they're opened: pass,
+	// fail, timeout or changes_requested. PRs past the end pass.
+	Checks []string `yaml:"checks"`
+	// Evaluations answer --completion-mode evaluate in order
+	Evaluations []Evaluation `yaml:"evaluations"`
+	// Issues maps issue numbers to their state, OPEN or CLOSED
+	Issues map[string]string `yaml:"issues"`
+	// PushFailures is how many pushes are rejected before they succeed
+	PushFailures int `yaml:"push_failures"`
+}
+
+// Turn is Claude's answer to one iteration's prompt.
+type Turn struct {
+	Output string   `yaml:"output"`
+	Cost   float64  `yaml:"cost"`
+	Edits  []string `yaml:"edits"`
+	// Commit is the commit message; by default one is made up from the edits
+	Commit string `yaml:"commit"`
+	// Error fails the iteration's Claude run with this message
+	Error string `yaml:"error"`
+}
+
+// Evaluation is the answer to a completion check.
+type Evaluation struct {
+	Complete      bool    `yaml:"complete"`
+	Confidence    float64 `yaml:"confidence"`
+	RemainingWork string  `yaml:"remaining_work"`
+}
+
+var checkStatuses = map[string]func() *github.PRStatus{
+	"pass":              fake.Passing,
+	"fail":              fake.Failing,
+	"changes_requested": fake.ChangesRequested,
+	"timeout":           func() *github.PRStatus { return nil },
+}
+
+// Parse decodes a scenario from YAML, rejecting unknown keys.
+func Parse(data []byte) (*Scenario, error) {
+	var s Scenario
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse scenario: %w", err)
+	}
+
+	if s.Repo != "" {
+		if owner, name, ok := strings.Cut(s.Repo, "/"); !ok || owner == "" || name == "" {
+			return nil, fmt.Errorf("scenario repo must be owner/name, got %q", s.Repo)
+		}
+	}
+	for i, check := range s.Checks {
+		if checkStatuses[check] == nil {
+			return nil, fmt.Errorf("scenario check %d must be one of: pass, fail, timeout, changes_requested", i+1)
+		}
+	}
+	for number, state := range s.Issues {
+		if state != "OPEN" && state != "CLOSED" {
+			return nil, fmt.Errorf("scenario issue %s must be OPEN or CLOSED", number)
+		}
+	}
+	if s.PushFailures < 0 {
+		return nil, fmt.Errorf("scenario push_failures must be non-negative")
+	}
+	return &s, nil
+}
+
+// Load reads a scenario file.
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	return Parse(data)
+}
+
+// Forge is the simulated repository, forge and Claude of a run.
+type Forge struct {
+	Git    *fake.Git
+	GitHub *fake.GitHub
+	Claude *fake.Claude
+}
+
+// NewForge sets up the scenario in dir. owner, repo and files stand in
+// for what the scenario leaves out.
+func (s *Scenario) NewForge(dir, owner, repo string, files []string) *Forge {
+	if s.Repo != "" {
+		owner, repo, _ = strings.Cut(s.Repo, "/")
+	}
+	if len(s.Files) > 0 {
+		files = s.Files
+	}
+
+	var turns []fake.Turn
+	for _, t := range s.Turns {
+		turn := fake.Turn{Output: t.Output, Cost: t.Cost, Edits: t.Edits, CommitMessage: t.Commit}
+		if t.Error != "" {
+			turn.Err = errors.New(t.Error)
+		}
+		turns = append(turns, turn)
+	}
+
+	g := fake.NewGit(dir, owner, repo, files...)
+	for range s.PushFailures {
+		g.PushErrors = append(g.PushErrors, errors.New("simulated push rejection"))
+	}
+	h := fake.NewGitHub(g, owner, repo)
+	for _, check := range s.Checks {
+		h.Statuses = append(h.Statuses, checkStatuses[check]())
+	}
+	for number, state := range s.Issues {
+		h.Issues[strings.TrimPrefix(number, "#")] = state
+	}
+	c := fake.NewClaude(g, turns...)
+	for _, e := range s.Evaluations {
+		c.Evaluations = append(c.Evaluations, claude.Evaluation{Complete: e.Complete, Confidence: e.Confidence, RemainingWork: e.RemainingWork})
+	}
+	return &Forge{Git: g, GitHub: h, Claude: c}
+}
+
+// Workspace copies the files tracked in workDir into dir, so setup and
+// verify commands run against a copy of the code, and returns the files
+// copied: none if workDir isn't a git repository.
+func Workspace(workDir, dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create simulation workspace: %w", err)
+	}
+	client := git.NewClient(workDir)
+	if !client.IsRepo() {
+		return nil, nil
+	}
+	tracked, err := client.ListFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, f := range tracked {
+		copied, err := copyFile(filepath.Join(workDir, f), filepath.Join(dir, f))
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy %s into the simulation workspace: %w", f, err)
+		}
+		if copied {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// copyFile copies a regular file, reporting false for anything else, such
+// as a deleted file or a submodule.
+func copyFile(src, dst string) (bool, error) {
+	info, err := os.Lstat(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !info.Mode().IsRegular() {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(dst, data, info.Mode().Perm())
+}
@@ -0,0 +1,106 @@
+package simulate
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "empty", yaml: ""},
+		{
+			name: "full",
+			yaml: "repo: acme/widgets\nfiles: [main.go]\nturns:\n  - edits: [main.go]\n    commit: Fix it\n    cost: 0.5\n  - error: boom\nchecks: [pass, fail, timeout, changes_requested]\nissues:\n  \"42\": CLOSED\npush_failures: 1\n",
+		},
+		{name: "unknown key", yaml: "turn: []\n", wantErr: "field turn not found"},
+		{name: "bad repo", yaml: "repo: widgets\n", wantErr: "owner/name"},
+		{name: "bad check", yaml: "checks: [pass, flaky]\n", wantErr: "check 2"},
+		{name: "bad issue state", yaml: "issues:\n  \"1\": merged\n", wantErr: "OPEN or CLOSED"},
+		{name: "negative push failures", yaml: "push_failures: -1\n", wantErr: "non-negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.yaml))
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Parse() unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Parse() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewForge(t *testing.T) {
+	s, err := Parse([]byte("turns:\n  - edits: [main.go]\n    commit: Fix it\n  - error: boom\nchecks: [fail, timeout]\nissues:\n  \"#7\": CLOSED\npush_failures: 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forge := s.NewForge(t.TempDir(), "acme", "widgets", []string{"main.go"})
+
+	if owner, repo, _ := forge.Git.DetectGitHubRepo(); owner != "acme" || repo != "widgets" {
+		t.Errorf("repo = %s/%s, want acme/widgets", owner, repo)
+	}
+	if files, _ := forge.Git.ListFiles(); !reflect.DeepEqual(files, []string{"main.go"}) {
+		t.Errorf("files = %v, want [main.go]", files)
+	}
+	if len(forge.Claude.Turns) != 2 || forge.Claude.Turns[0].CommitMessage != "Fix it" || forge.Claude.Turns[1].Err == nil {
+		t.Errorf("turns = %+v", forge.Claude.Turns)
+	}
+	if len(forge.GitHub.Statuses) != 2 || !forge.GitHub.Statuses[0].HasFailedChecks || forge.GitHub.Statuses[1] != nil {
+		t.Errorf("statuses = %+v, want failing then timing out", forge.GitHub.Statuses)
+	}
+	if state, _ := forge.GitHub.GetIssueState(t.Context(), "7"); state != "CLOSED" {
+		t.Errorf("issue 7 = %s, want CLOSED", state)
+	}
+	if len(forge.Git.PushErrors) != 2 {
+		t.Errorf("push errors = %v, want 2", forge.Git.PushErrors)
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	src := t.TempDir()
+	for _, args := range [][]string{{"init", "-q"}, {"config", "user.email", "t@example.com"}, {"config", "user.name", "t"}} {
+		if out, err := exec.Command("git", append([]string{"-C", src}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	for _, f := range []string{"main.go", "cmd/run.sh", "untracked.txt"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(src, f)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(src, f), []byte(f), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err := exec.Command("git", "-C", src, "add", "main.go", "cmd/run.sh").CombinedOutput(); err != nil {
+		t.Fatalf("git add: %v\n%s", err, out)
+	}
+
+	dst := filepath.Join(t.TempDir(), "repo")
+	files, err := Workspace(src, dst)
+	if err != nil {
+		t.Fatalf("Workspace() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(files, []string{"cmd/run.sh", "main.go"}) {
+		t.Errorf("Workspace() = %v, want the tracked files", files)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "cmd", "run.sh")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("cmd/run.sh should be copied with its mode: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "untracked.txt")); !os.IsNotExist(err) {
+		t.Error("untracked files should not be copied")
+	}
+}
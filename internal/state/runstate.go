@@ -0,0 +1,218 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/report"
+	"github.com/guzus/deep-claude/pkg/config"
+)
+
+// RunStateFile is the name of a run's limit accounting in its run directory.
+const RunStateFile = "state.json"
+
+// HeartbeatInterval is how often a going run writes its heartbeat, and
+// StaleAfter how old the heartbeat gets before the run is taken for dead.
+const (
+	HeartbeatInterval = 30 * time.Second
+	StaleAfter        = 4 * HeartbeatInterval
+)
+
+// Liveness is what a run's state file tells about the process running it.
+type Liveness string
+
+const (
+	// Running runs have a fresh heartbeat
+	Running Liveness = "running"
+	// Hung runs have a fresh heartbeat, but Claude has been working on one
+	// request for too long
+	Hung Liveness = "hung"
+	// Dead runs stopped writing their heartbeat without exiting: the
+	// process was killed, crashed or is frozen
+	Dead Liveness = "dead"
+	// Stopped runs were interrupted or blocked, and can be resumed
+	Stopped Liveness = "stopped"
+	// Done runs are finished
+	Done Liveness = "done"
+	// Unknown runs were started by a dclaude that wrote no heartbeat
+	Unknown Liveness = "unknown"
+)
+
+// RunState tracks a run's progress against its limits. It is saved in the
+// run directory after every iteration, so a resumed run, detached or not,
+// continues from the same budget instead of starting a fresh one.
+type RunState struct {
+	RunID       string        `json:"run_id"`
+	Prompt      string        `json:"prompt"`
+	MaxRuns     int           `json:"max_runs,omitempty"`
+	MaxCost     float64       `json:"max_cost,omitempty"`
+	MaxDuration time.Duration `json:"max_duration,omitempty"`
+	Labels      []string      `json:"labels,omitempty"`
+	Iterations  int           `json:"iterations"`
+	TotalCost   float64       `json:"total_cost"`
+	Elapsed     time.Duration `json:"elapsed"`
+	Finished    bool          `json:"finished"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+
+	// History is how each iteration went, failed ones included
+	History []report.Iteration `json:"history,omitempty"`
+
+	// Heartbeat is written every HeartbeatInterval while a process runs
+	// the run, and Exited once it has left it cleanly
+	Heartbeat time.Time `json:"heartbeat,omitempty"`
+	Exited    bool      `json:"exited,omitempty"`
+	// ClaudeSince is when Claude started on the request it is working on
+	ClaudeSince time.Time `json:"claude_since,omitempty"`
+
+	// Elapsed when this process took over the run, and when that was
+	base    time.Duration
+	started time.Time
+
+	// mu guards ClaudeSince and saved, the state as last written, which
+	// Beat rewrites from another goroutine
+	mu    sync.Mutex
+	saved []byte
+}
+
+// LoadRunState reads the saved state of a run.
+func LoadRunState(runID string) (*RunState, error) {
+	dir, err := RunDir(runID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, RunStateFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state of run %s: %w", runID, err)
+	}
+
+	var s RunState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse state of run %s: %w", runID, err)
+	}
+	return &s, nil
+}
+
+// Save writes the state to its run directory, replacing the previous copy
+// atomically. It beats the heartbeat too.
+func (s *RunState) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.UpdatedAt = time.Now().UTC()
+	s.Heartbeat = s.UpdatedAt
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := s.write(data); err != nil {
+		return err
+	}
+	s.saved = data
+	return nil
+}
+
+// Beat writes a fresh heartbeat to the state as last saved. Unlike Save,
+// it may be called while another goroutine updates the state.
+func (s *RunState) Beat() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saved == nil {
+		return nil
+	}
+	var saved RunState
+	if err := json.Unmarshal(s.saved, &saved); err != nil {
+		return err
+	}
+	saved.Heartbeat = time.Now().UTC()
+	saved.ClaudeSince = s.ClaudeSince
+	data, err := json.MarshalIndent(&saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := s.write(data); err != nil {
+		return err
+	}
+	s.saved = data
+	return nil
+}
+
+func (s *RunState) write(data []byte) error {
+	dir, err := RunDir(s.RunID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create run directory: %w", err)
+	}
+	tmp := filepath.Join(dir, RunStateFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save run state: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(dir, RunStateFile))
+}
+
+// StartClaude records that Claude started on a request, until the
+// returned func is called.
+func (s *RunState) StartClaude() (done func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ClaudeSince = time.Now().UTC()
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.ClaudeSince = time.Time{}
+	}
+}
+
+// Liveness tells from the state file whether the process running the run
+// is still going at now. A run is hung once Claude has been working on a
+// request for longer than hungAfter, if it is positive.
+func (s *RunState) Liveness(now time.Time, hungAfter time.Duration) Liveness {
+	switch {
+	case s.Finished:
+		return Done
+	case s.Exited:
+		return Stopped
+	case s.Heartbeat.IsZero():
+		return Unknown
+	case now.Sub(s.Heartbeat) > StaleAfter:
+		return Dead
+	case hungAfter > 0 && !s.ClaudeSince.IsZero() && now.Sub(s.ClaudeSince) > hungAfter:
+		return Hung
+	}
+	return Running
+}
+
+// Start begins counting this process's time towards Elapsed.
+func (s *RunState) Start() {
+	s.base = s.Elapsed
+	s.started = time.Now()
+}
+
+// Tick brings Elapsed up to date. Time is measured on the monotonic clock,
+// so changes to the wall clock don't stretch or shrink the budget, and
+// time between a run stopping and being resumed isn't counted.
+func (s *RunState) Tick() time.Duration {
+	if !s.started.IsZero() {
+		s.Elapsed = s.base + time.Since(s.started)
+	}
+	return s.Elapsed
+}
+
+// LimitReached reports whether the run has used up one of its limits, and
+// which.
+func (s *RunState) LimitReached() (string, bool) {
+	if s.MaxRuns > 0 && s.Iterations >= s.MaxRuns {
+		return fmt.Sprintf("reached max iterations (%d)", s.MaxRuns), true
+	}
+	if s.MaxCost > 0 && s.TotalCost >= s.MaxCost {
+		return fmt.Sprintf("reached max cost ($%.2f)", s.MaxCost), true
+	}
+	if s.MaxDuration > 0 && s.Elapsed >= s.MaxDuration {
+		return fmt.Sprintf("reached max duration (%s)", config.FormatDuration(s.MaxDuration)), true
+	}
+	return "", false
+}
@@ -0,0 +1,125 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunStateLimitReached(t *testing.T) {
+	tests := []struct {
+		name   string
+		state  *RunState
+		reason string
+	}{
+		{"no limits", &RunState{Iterations: 100, TotalCost: 50}, ""},
+		{"iterations", &RunState{MaxRuns: 5, Iterations: 5}, "reached max iterations (5)"},
+		{"under iterations", &RunState{MaxRuns: 5, Iterations: 4}, ""},
+		{"cost", &RunState{MaxCost: 10, TotalCost: 10.5}, "reached max cost ($10.00)"},
+		{"duration", &RunState{MaxDuration: 2 * time.Hour, Elapsed: 2*time.Hour + time.Second}, "reached max duration (2h)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, reached := tt.state.LimitReached()
+			if reason != tt.reason || reached != (tt.reason != "") {
+				t.Errorf("LimitReached() = %q, %v; want %q", reason, reached, tt.reason)
+			}
+		})
+	}
+}
+
+func TestRunStateSaveAndResume(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	s := &RunState{RunID: "20250115-143000-abcd", Prompt: "add tests", MaxCost: 5}
+	s.Start()
+	s.Iterations = 3
+	s.TotalCost = 2.5
+	s.Tick()
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	resumed, err := LoadRunState(s.RunID)
+	if err != nil {
+		t.Fatalf("LoadRunState() unexpected error: %v", err)
+	}
+	if resumed.Prompt != "add tests" || resumed.Iterations != 3 || resumed.TotalCost != 2.5 || resumed.MaxCost != 5 {
+		t.Errorf("LoadRunState() = %+v", resumed)
+	}
+
+	// Time spent before the resume counts towards the same budget
+	resumed.Elapsed = time.Hour
+	resumed.Start()
+	if got := resumed.Tick(); got < time.Hour {
+		t.Errorf("Tick() = %s, want at least the elapsed time carried over", got)
+	}
+
+	if _, err := LoadRunState("missing"); err == nil {
+		t.Error("LoadRunState() expected error for unknown run")
+	}
+}
+
+func TestRunStateBeat(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	s := &RunState{RunID: "20250115-143000-abcd", Prompt: "add tests"}
+	if err := s.Beat(); err != nil {
+		t.Fatalf("Beat() before Save() unexpected error: %v", err)
+	}
+	if _, err := LoadRunState(s.RunID); err == nil {
+		t.Fatalf("Beat() before Save() wrote a state file")
+	}
+
+	s.Iterations = 2
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	saved := s.Heartbeat
+	// Changes after the last Save are not the heartbeat's to write
+	s.Iterations = 3
+	done := s.StartClaude()
+	time.Sleep(time.Millisecond)
+	if err := s.Beat(); err != nil {
+		t.Fatalf("Beat() unexpected error: %v", err)
+	}
+	loaded, err := LoadRunState(s.RunID)
+	if err != nil {
+		t.Fatalf("LoadRunState() unexpected error: %v", err)
+	}
+	if !loaded.Heartbeat.After(saved) || loaded.Iterations != 2 || loaded.ClaudeSince.IsZero() {
+		t.Errorf("after Beat() state = %+v", loaded)
+	}
+
+	done()
+	if err := s.Beat(); err != nil {
+		t.Fatalf("Beat() unexpected error: %v", err)
+	}
+	if loaded, _ = LoadRunState(s.RunID); !loaded.ClaudeSince.IsZero() {
+		t.Errorf("ClaudeSince = %v after Claude finished", loaded.ClaudeSince)
+	}
+}
+
+func TestRunStateLiveness(t *testing.T) {
+	now := time.Date(2025, 1, 15, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		state *RunState
+		want  Liveness
+	}{
+		{"fresh heartbeat", &RunState{Heartbeat: now.Add(-time.Minute)}, Running},
+		{"stale heartbeat", &RunState{Heartbeat: now.Add(-StaleAfter - time.Second)}, Dead},
+		{"long Claude request", &RunState{Heartbeat: now, ClaudeSince: now.Add(-2 * time.Hour)}, Hung},
+		{"short Claude request", &RunState{Heartbeat: now, ClaudeSince: now.Add(-time.Minute)}, Running},
+		{"exited", &RunState{Heartbeat: now.Add(-time.Hour), Exited: true}, Stopped},
+		{"finished", &RunState{Heartbeat: now.Add(-time.Hour), Exited: true, Finished: true}, Done},
+		{"no heartbeat", &RunState{}, Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.state.Liveness(now, time.Hour); got != tt.want {
+				t.Errorf("Liveness() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Package state locates the per-user directory where Deep Claude keeps
+// runtime state shared between processes, such as session inboxes.
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Dir returns the state directory: $XDG_STATE_HOME/deep-claude, falling
+// back to ~/.local/state/deep-claude.
+func Dir() (string, error) {
+	if xdg := os.Getenv("XDG_STATE_HOME"); xdg != "" {
+		return filepath.Join(xdg, "deep-claude"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate state directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "deep-claude"), nil
+}
+
+// SessionDir returns the state directory for a named session.
+func SessionDir(session string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sessions", session), nil
+}
+
+// SessionInboxDir returns the directory holding messages queued for a session.
+func SessionInboxDir(session string) (string, error) {
+	dir, err := SessionDir(session)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "inbox"), nil
+}
+
+// CacheDir returns the download cache shared by all runs and worktrees:
+// $XDG_CACHE_HOME/deep-claude, falling back to the OS user cache directory.
+func CacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	return filepath.Join(dir, "deep-claude"), nil
+}
+
+// WorkspaceDir returns the directory holding the clones of an owner's
+// repositories that org runs work in.
+func WorkspaceDir(owner string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "workspaces", owner), nil
+}
+
+// ServerDir returns the directory of dclaude serve --team: its users,
+// tasks and audit log.
+func ServerDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "server"), nil
+}
+
+// SlotsDir returns the directory tracking resources reserved by workers.
+func SlotsDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "slots"), nil
+}
+
+// ChunksDir returns the directory tracking the chunks of the work on a
+// repository that --partition workers hold.
+func ChunksDir(owner, repo string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chunks", owner, repo), nil
+}
+
+// LedgerPath returns the path of the cost ledger shared by all runs.
+func LedgerPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "costs.jsonl"), nil
+}
+
+// NewRunID returns a unique, time-sortable identifier for a run.
+func NewRunID() string {
+	b := make([]byte, 2)
+	_, _ = rand.Read(b)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// RunsDir returns the directory holding per-run state.
+func RunsDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "runs"), nil
+}
+
+// RunDir returns the state directory for a run.
+func RunDir(runID string) (string, error) {
+	dir, err := RunsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, runID), nil
+}
+
+// ListRuns returns the IDs of recorded runs, oldest first.
+func ListRuns() ([]string, error) {
+	dir, err := RunsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	var runs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			runs = append(runs, entry.Name())
+		}
+	}
+	sort.Strings(runs)
+	return runs, nil
+}
@@ -0,0 +1,61 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirUsesXDGStateHome(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/tmp/xdg-state")
+
+	dir, err := Dir()
+	if err != nil {
+		t.Fatalf("Dir() unexpected error: %v", err)
+	}
+	if dir != filepath.Join("/tmp/xdg-state", "deep-claude") {
+		t.Errorf("Dir() = %q", dir)
+	}
+
+	inbox, _ := SessionInboxDir("dc-250115-1430-add-tests")
+	if inbox != filepath.Join("/tmp/xdg-state", "deep-claude", "sessions", "dc-250115-1430-add-tests", "inbox") {
+		t.Errorf("SessionInboxDir() = %q", inbox)
+	}
+}
+
+func TestDirFallsBackToHome(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/dev")
+
+	dir, err := Dir()
+	if err != nil {
+		t.Fatalf("Dir() unexpected error: %v", err)
+	}
+	if dir != filepath.Join("/home/dev", ".local", "state", "deep-claude") {
+		t.Errorf("Dir() = %q", dir)
+	}
+}
+
+func TestListRuns(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	if runs, err := ListRuns(); err != nil || len(runs) != 0 {
+		t.Fatalf("ListRuns() with no runs = %v, %v", runs, err)
+	}
+
+	first, second := "20250115-143000-ab12", NewRunID()
+	for _, id := range []string{second, first} {
+		dir, _ := RunDir(id)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runs, err := ListRuns()
+	if err != nil {
+		t.Fatalf("ListRuns() unexpected error: %v", err)
+	}
+	if len(runs) != 2 || runs[0] != first || runs[1] != second {
+		t.Errorf("ListRuns() = %v, want [%s %s]", runs, first, second)
+	}
+}
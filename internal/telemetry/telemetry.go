@@ -0,0 +1,220 @@
+// Package telemetry sends anonymous usage reports, when the user opts in:
+// how many iterations and PRs a run had, how it ended, which kinds of
+// failure it hit and which flags were used. Never code, prompts, commands,
+// paths or repository names.
+package telemetry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/settings"
+	"github.com/guzus/deep-claude/internal/state"
+)
+
+// Endpoint receives the reports. It is set at build time; without one,
+// and without EndpointEnv, reports are only kept locally.
+var Endpoint = ""
+
+// EndpointEnv overrides Endpoint, e.g. for a self-hosted collector.
+const EndpointEnv = "DEEP_CLAUDE_TELEMETRY_URL"
+
+// Env turns telemetry on (1) or off (0) over the config files, unless the
+// organization locked it off.
+const Env = "DEEP_CLAUDE_TELEMETRY"
+
+const (
+	idFile   = "telemetry-id"
+	lastFile = "telemetry-last.json"
+)
+
+// Usage is what a run reports about itself.
+type Usage struct {
+	Iterations int            `json:"iterations"`
+	PRsOpened  int            `json:"prs_opened"`
+	PRsMerged  int            `json:"prs_merged"`
+	Outcome    string         `json:"outcome"`
+	Failures   map[string]int `json:"failures,omitempty"`
+}
+
+// Report is a run's usage with what identifies the installation: a random
+// ID, the version and the platform.
+type Report struct {
+	InstallID string    `json:"install_id"`
+	Version   string    `json:"version"`
+	OS        string    `json:"os"`
+	Arch      string    `json:"arch"`
+	Time      time.Time `json:"time"`
+	Usage
+	Features []string `json:"features,omitempty"`
+}
+
+// Status is whether telemetry is on, and why.
+type Status struct {
+	Enabled bool
+	Source  string
+	Locked  bool
+}
+
+// Resolve decides whether telemetry is on: a lock in the organization's
+// config, which can only turn it off, wins, then Env, then the config
+// files. It is off by default.
+func Resolve(resolved *settings.Resolved) Status {
+	if resolved != nil && resolved.TelemetryLocked && !*resolved.Telemetry {
+		return Status{Enabled: *resolved.Telemetry, Source: "organization config", Locked: true}
+	}
+	switch os.Getenv(Env) {
+	case "1", "true":
+		return Status{Enabled: true, Source: Env}
+	case "0", "false":
+		return Status{Enabled: false, Source: Env}
+	}
+	if resolved != nil && resolved.Telemetry != nil {
+		return Status{Enabled: *resolved.Telemetry, Source: resolved.TelemetryFrom + " config"}
+	}
+	return Status{Source: "default"}
+}
+
+// CurrentEndpoint returns where reports are sent, or "" if nowhere.
+func CurrentEndpoint() string {
+	if url := os.Getenv(EndpointEnv); url != "" {
+		return url
+	}
+	return Endpoint
+}
+
+// failureCategories maps the start of an iteration error to its category.
+var failureCategories = []struct {
+	prefix   string
+	category string
+}{
+	{"Claude execution failed", "claude"},
+	{"failed to create branch", "branch"},
+	{"failed to snapshot", "stage"},
+	{"failed to stage", "stage"},
+	{"failed to check for changes", "stage"},
+	{"failed to create commit", "commit"},
+	{"failed to push", "push"},
+	{"failed to create PR", "pr"},
+	{"failed to update PR", "pr"},
+	{"failed to merge", "merge"},
+	{"failed to format patch", "patch"},
+	{"failed to write patch", "patch"},
+	{"setup command failed", "setup"},
+	{"iteration timed out", "timeout"},
+}
+
+// Categorize reduces an error to the subsystem that failed, dropping
+// the details, which may name files or include output.
+func Categorize(err error) string {
+	msg := err.Error()
+	for _, c := range failureCategories {
+		if strings.HasPrefix(msg, c.prefix) {
+			return c.category
+		}
+	}
+	return "other"
+}
+
+// NewReport completes usage into a report. Features are flag names; only
+// the names are kept, sorted.
+func NewReport(version string, usage Usage, features []string) (*Report, error) {
+	id, err := installID()
+	if err != nil {
+		return nil, err
+	}
+	features = append([]string(nil), features...)
+	sort.Strings(features)
+	return &Report{
+		InstallID: id,
+		Version:   version,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Time:      time.Now().UTC().Truncate(time.Hour),
+		Usage:     usage,
+		Features:  features,
+	}, nil
+}
+
+// Send saves the report as the last one, for "dclaude telemetry status",
+// and posts it to the endpoint if there is one.
+func Send(r *Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir, err := state.Dir(); err == nil && os.MkdirAll(dir, 0755) == nil {
+		_ = os.WriteFile(filepath.Join(dir, lastFile), data, 0644)
+	}
+
+	endpoint := CurrentEndpoint()
+	if endpoint == "" {
+		return nil
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to send telemetry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to send telemetry: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Last returns the last report sent, or nil if there is none.
+func Last() (*Report, error) {
+	dir, err := state.Dir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, lastFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read last telemetry report: %w", err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse last telemetry report: %w", err)
+	}
+	return &r, nil
+}
+
+// installID returns the random ID of this installation, creating it on
+// first use. It is not derived from anything about the user or machine.
+func installID() (string, error) {
+	dir, err := state.Dir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, idFile)
+	if data, err := os.ReadFile(path); err == nil && len(bytes.TrimSpace(data)) > 0 {
+		return string(bytes.TrimSpace(data)), nil
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(id+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to save install ID: %w", err)
+	}
+	return id, nil
+}
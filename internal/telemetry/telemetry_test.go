@@ -0,0 +1,106 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guzus/deep-claude/internal/settings"
+)
+
+func TestCategorize(t *testing.T) {
+	tests := []struct {
+		err  string
+		want string
+	}{
+		{"Claude execution failed: exit status 1", "claude"},
+		{"failed to push: git push failed: rejected", "push"},
+		{"failed to create PR: HTTP 422", "pr"},
+		{"failed to update PR #12: conflict", "pr"},
+		{"failed to merge PR: not mergeable", "merge"},
+		{"failed to stage changes: /home/me/secret.txt", "stage"},
+		{"iteration timed out: Claude execution failed: context deadline exceeded", "timeout"},
+		{"something unexpected in /home/me/repo", "other"},
+	}
+	for _, tt := range tests {
+		if got := Categorize(errors.New(tt.err)); got != tt.want {
+			t.Errorf("Categorize(%q) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name     string
+		env      string
+		resolved *settings.Resolved
+		want     Status
+	}{
+		{"off by default", "", nil, Status{Source: "default"}},
+		{"user config", "", &settings.Resolved{Telemetry: &on, TelemetryFrom: "user"}, Status{Enabled: true, Source: "user config"}},
+		{"env over config", "0", &settings.Resolved{Telemetry: &on, TelemetryFrom: "user"}, Status{Source: Env}},
+		{"env on", "1", nil, Status{Enabled: true, Source: Env}},
+		{"env over organization config", "0", &settings.Resolved{Telemetry: &on, TelemetryFrom: "organization"}, Status{Source: Env}},
+		{"user config over organization config", "", &settings.Resolved{Telemetry: &off, TelemetryFrom: "user"}, Status{Source: "user config"}},
+		{"organization lock over env", "1", &settings.Resolved{Telemetry: &off, TelemetryFrom: "organization", TelemetryLocked: true},
+			Status{Source: "organization config", Locked: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(Env, tt.env)
+			if got := Resolve(tt.resolved); got != tt.want {
+				t.Errorf("Resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	var received map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("invalid report: %v", err)
+		}
+	}))
+	defer server.Close()
+	t.Setenv(EndpointEnv, server.URL)
+
+	usage := Usage{Iterations: 3, PRsOpened: 2, PRsMerged: 1, Outcome: "stopped", Failures: map[string]int{"push": 1}}
+	report, err := NewReport("1.2.3", usage, []string{"verify-cmd", "max-runs"})
+	if err != nil {
+		t.Fatalf("NewReport() error: %v", err)
+	}
+	if err := Send(report); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	if received["iterations"] != float64(3) || received["version"] != "1.2.3" || len(received["install_id"].(string)) != 32 {
+		t.Errorf("unexpected report: %v", received)
+	}
+	if !reflect.DeepEqual(received["features"], []any{"max-runs", "verify-cmd"}) {
+		t.Errorf("features = %v, want sorted flag names", received["features"])
+	}
+
+	// The install ID is kept, and the last report can be shown
+	again, err := NewReport("1.2.3", usage, nil)
+	if err != nil || again.InstallID != report.InstallID {
+		t.Errorf("install ID changed: %q, %q (%v)", report.InstallID, again.InstallID, err)
+	}
+	last, err := Last()
+	if err != nil || last == nil || last.Iterations != 3 {
+		t.Errorf("Last() = %+v, %v", last, err)
+	}
+
+	t.Setenv(EndpointEnv, server.URL+"/missing")
+	server.Config.Handler = http.NotFoundHandler()
+	if err := Send(report); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Send() error = %v, want the status", err)
+	}
+}
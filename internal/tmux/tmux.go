@@ -8,6 +8,8 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/guzus/deep-claude/internal/shell"
 )
 
 const (
@@ -31,16 +33,15 @@ func IsAvailable() bool {
 	return err == nil
 }
 
-// GenerateSessionName creates a session name from the current time and prompt.
-// Format: cc-{YYMMDD-HHMM}-{sanitized-prompt}
-func GenerateSessionName(prompt string) string {
-	timestamp := time.Now().Format("060102-1504")
+// GenerateSessionName creates a session name from the run ID and prompt.
+// Format: dc-{run-id}-{sanitized-prompt}
+func GenerateSessionName(runID, prompt string) string {
 	sanitized := sanitizePrompt(prompt)
 
 	if sanitized == "" {
-		return fmt.Sprintf("%s%s", SessionPrefix, timestamp)
+		return fmt.Sprintf("%s%s", SessionPrefix, runID)
 	}
-	return fmt.Sprintf("%s%s-%s", SessionPrefix, timestamp, sanitized)
+	return fmt.Sprintf("%s%s-%s", SessionPrefix, runID, sanitized)
 }
 
 // sanitizePrompt cleans the prompt for use in a session name.
@@ -90,13 +91,15 @@ func CreateSession(name string, cmd []string, workDir string) error {
 
 	// Build tmux command
 	// tmux new-session -d -s <name> -c <workdir> <command>
+	// Older tmux versions join the command's words for the shell, so they
+	// are quoted as one shell command
 	args := []string{
 		"new-session",
 		"-d",
 		"-s", name,
 		"-c", workDir,
+		shell.Join(cmd),
 	}
-	args = append(args, cmd...)
 
 	command := exec.Command("tmux", args...)
 	command.Stdout = os.Stdout
@@ -105,6 +108,19 @@ func CreateSession(name string, cmd []string, workDir string) error {
 	return command.Run()
 }
 
+// CurrentSession returns the name of the tmux session this process runs in,
+// or an empty string when it is not running inside tmux.
+func CurrentSession() string {
+	if os.Getenv("TMUX") == "" {
+		return ""
+	}
+	output, err := exec.Command("tmux", "display-message", "-p", "#S").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
 // SessionExists checks if a tmux session with the given name exists.
 func SessionExists(name string) bool {
 	cmd := exec.Command("tmux", "has-session", "-t", name)
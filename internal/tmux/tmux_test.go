@@ -31,7 +31,7 @@ func TestSanitizePrompt(t *testing.T) {
 }
 
 func TestGenerateSessionName(t *testing.T) {
-	name := GenerateSessionName("Add test coverage")
+	name := GenerateSessionName("20250115-143000-ab12", "Add test coverage")
 
 	// Should start with dc-
 	if !strings.HasPrefix(name, SessionPrefix) {
@@ -43,19 +43,18 @@ func TestGenerateSessionName(t *testing.T) {
 		t.Errorf("session name should contain sanitized prompt, got %q", name)
 	}
 
-	// Should have datetime format (dc-YYMMDD-HHMM-...)
-	parts := strings.Split(name, "-")
-	if len(parts) < 4 {
-		t.Errorf("session name should have at least 4 parts separated by -, got %q", name)
+	// Should lead with the run ID (dc-{run-id}-...)
+	if !strings.HasPrefix(name, SessionPrefix+"20250115-143000-ab12-") {
+		t.Errorf("session name should contain the run ID, got %q", name)
 	}
 }
 
 func TestGenerateSessionNameEmpty(t *testing.T) {
-	name := GenerateSessionName("")
+	name := GenerateSessionName("20250115-143000-ab12", "")
 
-	// Should still generate a valid name with just timestamp
-	if !strings.HasPrefix(name, SessionPrefix) {
-		t.Errorf("session name should start with %q, got %q", SessionPrefix, name)
+	// Should still generate a valid name with just the run ID
+	if name != SessionPrefix+"20250115-143000-ab12" {
+		t.Errorf("session name should be the prefixed run ID, got %q", name)
 	}
 }
 
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// glyphs are the symbols and line-drawing characters the terminal printer
+// prints.
+type glyphs struct {
+	info, success, warning, failure, pending string
+	iteration, budget, cost, diff, elapsed   string
+	// rule and heavyRule draw horizontal lines, and side the left edge of
+	// a box
+	rule, heavyRule, side string
+	bullet, ellipsis      string
+	barFull, barEmpty     string
+	spark                 []rune
+	spinner               []string
+}
+
+var unicodeGlyphs = &glyphs{
+	info: "ℹ", success: "✓", warning: "⚠", failure: "✗", pending: "○",
+	iteration: "🔄", budget: "📈", cost: "💰", diff: "📊", elapsed: "⏱",
+	rule: "─", heavyRule: "═", side: "│",
+	bullet: "•", ellipsis: "…",
+	barFull: "█", barEmpty: "░",
+	spark:   []rune("▁▂▃▄▅▆▇█"),
+	spinner: []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"},
+}
+
+// asciiGlyphs replace the symbols with plain-text prefixes, for screen
+// readers and terminals that can't show them.
+var asciiGlyphs = &glyphs{
+	info: "[INFO]", success: "[OK]", warning: "[WARN]", failure: "[FAIL]", pending: "[WAIT]",
+	iteration: "[ITERATION]", budget: "[BUDGET]", cost: "[COST]", diff: "[DIFF]", elapsed: "[TIME]",
+	rule: "-", heavyRule: "=", side: "|",
+	bullet: "*", ellipsis: "...",
+	barFull: "#", barEmpty: ".",
+	spark:   []rune("_.-=+*#@"),
+	spinner: []string{"|", "/", "-", "\\"},
+}
+
+// detectASCII tells whether the terminal likely can't show the unicode
+// symbols: TERM is dumb, as set by Emacs shells and some screen reader
+// setups, the locale's character set isn't UTF-8, or the run is in the
+// legacy Windows console rather than Windows Terminal.
+func detectASCII() bool {
+	if os.Getenv("TERM") == "dumb" {
+		return true
+	}
+	if runtime.GOOS == "windows" && os.Getenv("WT_SESSION") == "" {
+		return true
+	}
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			locale = strings.ToLower(locale)
+			return !strings.Contains(locale, "utf-8") && !strings.Contains(locale, "utf8")
+		}
+	}
+	return false
+}
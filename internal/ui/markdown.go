@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Block-level markdown: headings, list items, quotes and rules.
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)(\s+#+)?\s*$`)
+	listPattern    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	quotePattern   = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	rulePattern    = regexp.MustCompile(`^\s*((-\s*){3,}|(\*\s*){3,}|(_\s*){3,})$`)
+)
+
+// Inline markdown: code spans, bold text and links.
+var (
+	codeSpanPattern = regexp.MustCompile("`([^`]+)`")
+	boldPattern     = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	linkPattern     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// renderMarkdown formats markdown for a terminal width cells wide, as
+// lines: headings are bold, code blocks indented, list items keep a
+// hanging indent, long lines are wrapped, and inline code, bold text and
+// links are styled.
+func renderMarkdown(text string, width int, g *glyphs) []string {
+	var lines []string
+	var fence string
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+				continue
+			}
+			for _, part := range wrap(line, width-2) {
+				lines = append(lines, "  "+Cyan(part))
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			heading := inline(m[2])
+			if len(m[1]) <= 2 {
+				heading = Cyan(heading)
+			}
+			lines = append(lines, wrap(Bold(heading), width)...)
+			continue
+		}
+		if rulePattern.MatchString(line) {
+			lines = append(lines, Dim(strings.Repeat(g.rule, width)))
+			continue
+		}
+		if m := listPattern.FindStringSubmatch(line); m != nil {
+			indent := strings.ReplaceAll(m[1], "\t", "    ")
+			marker := m[2]
+			if !strings.ContainsAny(marker[len(marker)-1:], ".)") {
+				marker = g.bullet
+			}
+			lines = append(lines, hang(indent+marker+" ", inline(m[3]), width)...)
+			continue
+		}
+		if m := quotePattern.FindStringSubmatch(line); m != nil {
+			for _, part := range wrap(inline(m[1]), width-2) {
+				lines = append(lines, Dim(g.side+" ")+part)
+			}
+			continue
+		}
+		lines = append(lines, wrap(inline(line), width)...)
+	}
+	return lines
+}
+
+// hang wraps text after prefix, indenting the lines after the first to
+// line up with it.
+func hang(prefix, text string, width int) []string {
+	indent := Width(prefix)
+	parts := wrap(text, max(width-indent, 1))
+	lines := []string{prefix + parts[0]}
+	for _, part := range parts[1:] {
+		lines = append(lines, strings.Repeat(" ", indent)+part)
+	}
+	return lines
+}
+
+// inline styles a line's code spans, bold text and links.
+func inline(line string) string {
+	// Code spans first, so that markup inside them is left alone
+	var spans []string
+	line = codeSpanPattern.ReplaceAllStringFunc(line, func(s string) string {
+		spans = append(spans, Cyan(s[1:len(s)-1]))
+		return "\x00"
+	})
+	line = boldPattern.ReplaceAllStringFunc(line, func(s string) string {
+		return Bold(s[2 : len(s)-2])
+	})
+	line = linkPattern.ReplaceAllString(line, "$1 ($2)")
+	for _, span := range spans {
+		line = strings.Replace(line, "\x00", span, 1)
+	}
+	return line
+}
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// progressEvery is how often a progress line repeats while a spinner
+// would be spinning.
+const progressEvery = time.Minute
+
+// progress stands in for the spinner where escape codes would garble the
+// output, as in CI logs: it prints a timestamped line when it starts and
+// repeats it with the time elapsed until it stops.
+type progress struct {
+	mu      sync.Mutex
+	message string
+	started time.Time
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// line returns the progress line for now.
+func (pr *progress) line(now time.Time) string {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	line := fmt.Sprintf("[%s] %s", now.Format("15:04:05"), pr.message)
+	if elapsed := now.Sub(pr.started); elapsed >= time.Second {
+		line += fmt.Sprintf(" (%s elapsed)", formatDuration(elapsed))
+	}
+	return line
+}
+
+func (pr *progress) setMessage(message string) {
+	pr.mu.Lock()
+	pr.message = message
+	pr.mu.Unlock()
+}
+
+// startProgress prints the first progress line and repeats it every
+// p.progressEvery until stopProgress.
+func (p *terminal) startProgress(message string) {
+	p.stopProgress()
+	pr := &progress{message: message, started: time.Now(), stop: make(chan struct{}), done: make(chan struct{})}
+	p.progress = pr
+	fmt.Fprintln(p.out, pr.line(pr.started))
+	go func() {
+		defer close(pr.done)
+		ticker := time.NewTicker(p.progressEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-pr.stop:
+				return
+			case now := <-ticker.C:
+				fmt.Fprintln(p.out, pr.line(now))
+			}
+		}
+	}()
+}
+
+func (p *terminal) stopProgress() {
+	if p.progress == nil {
+		return
+	}
+	close(p.progress.stop)
+	<-p.progress.done
+	p.progress = nil
+}
@@ -0,0 +1,387 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Options choose the printer Open returns.
+type Options struct {
+	// Output is "terminal", "json" or "silent"
+	Output string
+	// LogFile also gets a plain-text log of the output, if set
+	LogFile string
+	Verbose bool
+	// CI has the terminal print progress lines instead of spinners, as
+	// it does anyway when stderr isn't a terminal
+	CI bool
+	// ASCII has the terminal print plain-text symbols, as it does anyway
+	// on terminals that can't show unicode ones
+	ASCII bool
+}
+
+// Open returns the printer for opts.
+func Open(opts Options) (Printer, error) {
+	var printer Printer
+	switch opts.Output {
+	case "", "terminal":
+		printer = newTerminal(opts.Verbose, opts.CI || !term.IsTerminal(int(os.Stderr.Fd())), opts.ASCII || detectASCII())
+	case "json":
+		printer = NewJSON(os.Stdout, opts.Verbose)
+	case "silent":
+		printer = NewSilent()
+	default:
+		return nil, fmt.Errorf("unknown output %q (use one of: terminal, json, silent)", opts.Output)
+	}
+	if opts.LogFile == "" {
+		return printer, nil
+	}
+	f, err := os.OpenFile(opts.LogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	log := newLog(f, opts.Verbose)
+	log.closer = f
+	return Tee(printer, log), nil
+}
+
+// Entry is one thing a non-terminal printer prints: Message is the text
+// the terminal would show, and Data the values behind it.
+type Entry struct {
+	Time    time.Time      `json:"time"`
+	Type    string         `json:"type"`
+	Message string         `json:"message"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// entries is a printer that turns what it prints into entries and hands
+// them to write. Spinners become progress entries, and questions are
+// answered without asking.
+type entries struct {
+	mu      sync.Mutex
+	write   func(Entry)
+	verbose bool
+	redact  func(string) string
+	closer  io.Closer
+}
+
+// NewJSON creates a printer that writes each entry as a line of JSON.
+func NewJSON(w io.Writer, verbose bool) Printer {
+	enc := json.NewEncoder(w)
+	return &entries{verbose: verbose, write: func(e Entry) { _ = enc.Encode(e) }}
+}
+
+// NewLog creates a printer that writes each entry as timestamped plain
+// text, continuing multi-line messages on indented lines.
+func NewLog(w io.Writer, verbose bool) Printer {
+	return newLog(w, verbose)
+}
+
+func newLog(w io.Writer, verbose bool) *entries {
+	return &entries{verbose: verbose, write: func(e Entry) {
+		lines := strings.Split(e.Message, "\n")
+		fmt.Fprintf(w, "%s %-9s %s\n", e.Time.Format(time.RFC3339), strings.ToUpper(e.Type), lines[0])
+		for _, line := range lines[1:] {
+			fmt.Fprintf(w, "%s %s\n", strings.Repeat(" ", 30), line)
+		}
+	}}
+}
+
+// NewSilent creates a printer that prints nothing.
+func NewSilent() Printer {
+	return &entries{write: func(Entry) {}}
+}
+
+func (p *entries) emit(typ, message string, data map[string]any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.write(Entry{Time: time.Now().UTC(), Type: typ, Message: p.clean(message), Data: data})
+}
+
+func (p *entries) clean(text string) string {
+	if p.redact == nil {
+		return text
+	}
+	return p.redact(text)
+}
+
+func (p *entries) SetRedactor(redact func(string) string) {
+	p.redact = redact
+}
+
+func (p *entries) Close() error {
+	if p.closer == nil {
+		return nil
+	}
+	return p.closer.Close()
+}
+
+func (p *entries) Header(text string)    { p.emit("header", text, nil) }
+func (p *entries) SubHeader(text string) { p.emit("subheader", text, nil) }
+
+func (p *entries) Info(format string, args ...interface{}) {
+	p.emit("info", fmt.Sprintf(format, args...), nil)
+}
+
+func (p *entries) Success(format string, args ...interface{}) {
+	p.emit("success", fmt.Sprintf(format, args...), nil)
+}
+
+func (p *entries) Warning(format string, args ...interface{}) {
+	p.emit("warning", fmt.Sprintf(format, args...), nil)
+}
+
+func (p *entries) Error(format string, args ...interface{}) {
+	p.emit("error", fmt.Sprintf(format, args...), nil)
+}
+
+func (p *entries) Debug(format string, args ...interface{}) {
+	if p.verbose {
+		p.emit("debug", fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (p *entries) Iteration(current, max int) {
+	p.emit("iteration", fmt.Sprintf("Starting iteration #%d", current), map[string]any{"iteration": current, "max": max})
+}
+
+func (p *entries) Budget(cost, maxCost float64, elapsed, maxDuration time.Duration) {
+	var parts []string
+	if maxCost > 0 {
+		parts = append(parts, fmt.Sprintf("$%.2f / $%.2f", cost, maxCost))
+	}
+	if maxDuration > 0 {
+		parts = append(parts, fmt.Sprintf("%s / %s", formatDuration(elapsed), formatDuration(maxDuration)))
+	}
+	if len(parts) == 0 {
+		return
+	}
+	p.emit("budget", "Budget: "+strings.Join(parts, ", "), map[string]any{
+		"cost": cost, "max_cost": maxCost, "elapsed": elapsed.Seconds(), "max_duration": maxDuration.Seconds(),
+	})
+}
+
+func (p *entries) Cost(iterationCost, totalCost float64) {
+	p.emit("cost", fmt.Sprintf("Iteration cost: $%.4f | Total: $%.4f", iterationCost, totalCost),
+		map[string]any{"iteration": iterationCost, "total": totalCost})
+}
+
+func (p *entries) DiffStat(files, insertions, deletions int) {
+	p.emit("diffstat", fmt.Sprintf("Changes: %d files | +%d -%d", files, insertions, deletions),
+		map[string]any{"files": files, "insertions": insertions, "deletions": deletions})
+}
+
+func (p *entries) Duration(elapsed, max time.Duration) {
+	message := "Elapsed: " + formatDuration(elapsed)
+	if max > 0 {
+		message += " / " + formatDuration(max)
+	}
+	p.emit("duration", message, map[string]any{"elapsed": elapsed.Seconds(), "max": max.Seconds()})
+}
+
+func (p *entries) Timing(iteration int, elapsed time.Duration, phases string) {
+	p.emit("timing", fmt.Sprintf("Iteration %d took %s: %s", iteration, formatDuration(elapsed), phases),
+		map[string]any{"iteration": iteration, "elapsed": elapsed.Seconds(), "phases": phases})
+}
+
+func (p *entries) PRStatus(checksPassed, hasPending, hasFailed bool, reviewStatus string) {
+	checks := "passed"
+	switch {
+	case hasFailed:
+		checks = "failed"
+	case hasPending:
+		checks = "pending"
+	}
+	review := reviewStatus
+	if review == "" {
+		review = "none"
+	}
+	p.emit("pr_status", fmt.Sprintf("Checks: %s | Review: %s", checks, strings.ToLower(review)),
+		map[string]any{"checks": checks, "review": reviewStatus})
+}
+
+func (p *entries) Box(title, content string) {
+	message := content
+	if title != "" {
+		message = title + "\n" + content
+	}
+	p.emit("box", message, map[string]any{"title": p.clean(title), "content": p.clean(content)})
+}
+
+func (p *entries) Markdown(title, text string) {
+	message := text
+	if title != "" {
+		message = title + "\n" + text
+	}
+	p.emit("markdown", message, map[string]any{"title": p.clean(title), "text": p.clean(text)})
+}
+
+func (p *entries) Page(text string) error { return nil }
+
+func (p *entries) Summary(s RunSummary) {
+	status := "limit reached"
+	if s.Completed {
+		status = "completed"
+	}
+	iterations := fmt.Sprintf("%d iterations", s.Iterations)
+	if s.Breakdown != "" {
+		iterations += " (" + s.Breakdown + ")"
+	}
+	message := fmt.Sprintf("Run summary: %s, $%.4f, %s, %d files +%d -%d, %s",
+		iterations, s.TotalCost, formatDuration(s.Elapsed), s.FilesChanged, s.Insertions, s.Deletions, status)
+	if s.MigrationTotal > 0 {
+		message += fmt.Sprintf(", migration %d of %d items done", s.MigrationDone, s.MigrationTotal)
+	}
+	for _, f := range s.Failures {
+		message += "\n" + f
+	}
+	failures := make([]string, len(s.Failures))
+	for i, f := range s.Failures {
+		failures[i] = p.clean(f)
+	}
+	p.emit("summary", message, map[string]any{
+		"run_id":          s.RunID,
+		"iterations":      s.Iterations,
+		"breakdown":       s.Breakdown,
+		"failures":        failures,
+		"total_cost":      s.TotalCost,
+		"iteration_costs": s.IterationCosts,
+		"elapsed":         s.Elapsed.Seconds(),
+		"phases":          s.Phases,
+		"files_changed":   s.FilesChanged,
+		"insertions":      s.Insertions,
+		"deletions":       s.Deletions,
+		"completed":       s.Completed,
+		"migration_done":  s.MigrationDone,
+		"migration_total": s.MigrationTotal,
+	})
+}
+
+func (p *entries) Table(headers []string, rows [][]string) {
+	clean := make([][]string, len(rows))
+	lines := []string{strings.Join(headers, "\t")}
+	for i, row := range rows {
+		clean[i] = make([]string, len(row))
+		for j, cell := range row {
+			clean[i][j] = p.clean(cell)
+		}
+		lines = append(lines, strings.Join(clean[i], "\t"))
+	}
+	p.emit("table", strings.Join(lines, "\n"), map[string]any{"headers": headers, "rows": clean})
+}
+
+func (p *entries) StartSpinner(message string)  { p.emit("progress", message, nil) }
+func (p *entries) UpdateSpinner(message string) { p.emit("progress", message, nil) }
+func (p *entries) StopSpinner()                 {}
+
+func (p *entries) Prompt(message string) string { return "" }
+func (p *entries) Confirm(message string) bool  { return false }
+
+// tee prints to several printers; the first one answers questions.
+type tee []Printer
+
+// Tee creates a printer that prints to each of printers.
+func Tee(printers ...Printer) Printer {
+	return tee(printers)
+}
+
+func (t tee) each(f func(Printer)) {
+	for _, p := range t {
+		f(p)
+	}
+}
+
+func (t tee) Header(text string)    { t.each(func(p Printer) { p.Header(text) }) }
+func (t tee) SubHeader(text string) { t.each(func(p Printer) { p.SubHeader(text) }) }
+
+func (t tee) Info(format string, args ...interface{}) {
+	t.each(func(p Printer) { p.Info(format, args...) })
+}
+
+func (t tee) Success(format string, args ...interface{}) {
+	t.each(func(p Printer) { p.Success(format, args...) })
+}
+
+func (t tee) Warning(format string, args ...interface{}) {
+	t.each(func(p Printer) { p.Warning(format, args...) })
+}
+
+func (t tee) Error(format string, args ...interface{}) {
+	t.each(func(p Printer) { p.Error(format, args...) })
+}
+
+func (t tee) Debug(format string, args ...interface{}) {
+	t.each(func(p Printer) { p.Debug(format, args...) })
+}
+
+func (t tee) Iteration(current, max int) { t.each(func(p Printer) { p.Iteration(current, max) }) }
+
+func (t tee) Budget(cost, maxCost float64, elapsed, maxDuration time.Duration) {
+	t.each(func(p Printer) { p.Budget(cost, maxCost, elapsed, maxDuration) })
+}
+
+func (t tee) Cost(iterationCost, totalCost float64) {
+	t.each(func(p Printer) { p.Cost(iterationCost, totalCost) })
+}
+
+func (t tee) DiffStat(files, insertions, deletions int) {
+	t.each(func(p Printer) { p.DiffStat(files, insertions, deletions) })
+}
+
+func (t tee) Duration(elapsed, max time.Duration) {
+	t.each(func(p Printer) { p.Duration(elapsed, max) })
+}
+
+func (t tee) Timing(iteration int, elapsed time.Duration, phases string) {
+	t.each(func(p Printer) { p.Timing(iteration, elapsed, phases) })
+}
+
+func (t tee) PRStatus(checksPassed, hasPending, hasFailed bool, reviewStatus string) {
+	t.each(func(p Printer) { p.PRStatus(checksPassed, hasPending, hasFailed, reviewStatus) })
+}
+
+func (t tee) Box(title, content string)   { t.each(func(p Printer) { p.Box(title, content) }) }
+func (t tee) Markdown(title, text string) { t.each(func(p Printer) { p.Markdown(title, text) }) }
+func (t tee) Summary(s RunSummary)        { t.each(func(p Printer) { p.Summary(s) }) }
+
+func (t tee) Page(text string) error {
+	var first error
+	for _, p := range t {
+		if err := p.Page(text); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (t tee) Table(headers []string, rows [][]string) {
+	t.each(func(p Printer) { p.Table(headers, rows) })
+}
+
+func (t tee) StartSpinner(message string)  { t.each(func(p Printer) { p.StartSpinner(message) }) }
+func (t tee) UpdateSpinner(message string) { t.each(func(p Printer) { p.UpdateSpinner(message) }) }
+func (t tee) StopSpinner()                 { t.each(func(p Printer) { p.StopSpinner() }) }
+
+func (t tee) Prompt(message string) string { return t[0].Prompt(message) }
+func (t tee) Confirm(message string) bool  { return t[0].Confirm(message) }
+
+func (t tee) SetRedactor(redact func(string) string) {
+	t.each(func(p Printer) { p.SetRedactor(redact) })
+}
+
+func (t tee) Close() error {
+	var first error
+	for _, p := range t {
+		if err := p.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
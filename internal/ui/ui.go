@@ -3,241 +3,566 @@ package ui
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
+	"github.com/guzus/deep-claude/internal/i18n"
+	"golang.org/x/term"
 )
 
 // Colors
 var (
-	Green   = color.New(color.FgGreen).SprintFunc()
-	Yellow  = color.New(color.FgYellow).SprintFunc()
-	Red     = color.New(color.FgRed).SprintFunc()
-	Blue    = color.New(color.FgBlue).SprintFunc()
-	Cyan    = color.New(color.FgCyan).SprintFunc()
-	Bold    = color.New(color.Bold).SprintFunc()
-	Dim     = color.New(color.Faint).SprintFunc()
+	Green  = color.New(color.FgGreen).SprintFunc()
+	Yellow = color.New(color.FgYellow).SprintFunc()
+	Red    = color.New(color.FgRed).SprintFunc()
+	Blue   = color.New(color.FgBlue).SprintFunc()
+	Cyan   = color.New(color.FgCyan).SprintFunc()
+	Bold   = color.New(color.Bold).SprintFunc()
+	Dim    = color.New(color.Faint).SprintFunc()
 )
 
-// Printer handles formatted output.
-type Printer struct {
+// Printer handles formatted output. NewPrinter prints to the terminal;
+// NewJSON, NewLog and NewSilent are for output read by programs, log
+// files and runs that print nothing, and Open picks one by name.
+type Printer interface {
+	Header(text string)
+	SubHeader(text string)
+	Info(format string, args ...interface{})
+	Success(format string, args ...interface{})
+	Warning(format string, args ...interface{})
+	Error(format string, args ...interface{})
+	Debug(format string, args ...interface{})
+
+	Iteration(current, max int)
+	Budget(cost, maxCost float64, elapsed, maxDuration time.Duration)
+	Cost(iterationCost, totalCost float64)
+	DiffStat(files, insertions, deletions int)
+	Duration(elapsed, max time.Duration)
+	Timing(iteration int, elapsed time.Duration, phases string)
+	PRStatus(checksPassed, hasPending, hasFailed bool, reviewStatus string)
+	Box(title, content string)
+	Markdown(title, text string)
+	// Page shows text in the user's $PAGER; printers that aren't on a
+	// terminal do nothing
+	Page(text string) error
+	Summary(s RunSummary)
+	Table(headers []string, rows [][]string)
+
+	StartSpinner(message string)
+	UpdateSpinner(message string)
+	StopSpinner()
+
+	// Prompt and Confirm ask the user; printers that aren't interactive
+	// answer "" and no
+	Prompt(message string) string
+	Confirm(message string) bool
+
+	// SetRedactor makes the printer pass the text it prints through redact.
+	SetRedactor(redact func(string) string)
+	// Close flushes and closes the files the printer writes to.
+	Close() error
+}
+
+// terminal prints to stdout in color, with a spinner on stderr, or with
+// progress lines instead when progressEvery is set.
+type terminal struct {
+	out     io.Writer
 	verbose bool
+	g       *glyphs
 	spinner *spinner.Spinner
+	// progressEvery is how often progress lines repeat, or 0 to spin
+	progressEvery time.Duration
+	progress      *progress
+	// width is the terminal's width in cells, or 0 to detect it
+	width int
+
+	// redact scrubs secrets from text before it is printed
+	redact func(string) string
+}
+
+// NewPrinter creates a printer for the terminal. When stderr isn't one,
+// as in CI, it prints progress lines instead of spinners, and on terminals
+// that can't show unicode symbols it prints plain-text ones.
+func NewPrinter(verbose bool) Printer {
+	return newTerminal(verbose, !term.IsTerminal(int(os.Stderr.Fd())), detectASCII())
 }
 
-// NewPrinter creates a new printer.
-func NewPrinter(verbose bool) *Printer {
-	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+func newTerminal(verbose, ci, ascii bool) *terminal {
+	g := unicodeGlyphs
+	if ascii {
+		g = asciiGlyphs
+	}
+	s := spinner.New(g.spinner, 100*time.Millisecond)
 	s.Writer = os.Stderr
-	return &Printer{
+	p := &terminal{
+		out:     os.Stdout,
 		verbose: verbose,
+		g:       g,
 		spinner: s,
 	}
+	if ci {
+		p.progressEvery = progressEvery
+	}
+	return p
+}
+
+// SetRedactor makes the printer pass the text it prints through redact.
+func (p *terminal) SetRedactor(redact func(string) string) {
+	p.redact = redact
+}
+
+// clean returns text with its secrets scrubbed.
+func (p *terminal) clean(text string) string {
+	if p.redact == nil {
+		return text
+	}
+	return p.redact(text)
 }
 
 // Header prints a section header.
-func (p *Printer) Header(text string) {
-	fmt.Printf("\n%s %s\n", Bold("==="), Bold(text))
+func (p *terminal) Header(text string) {
+	fmt.Fprintf(p.out, "\n%s %s\n", Bold("==="), Bold(p.clean(i18n.T(text))))
 }
 
 // SubHeader prints a sub-section header.
-func (p *Printer) SubHeader(text string) {
-	fmt.Printf("\n%s %s\n", Bold("---"), text)
+func (p *terminal) SubHeader(text string) {
+	fmt.Fprintf(p.out, "\n%s %s\n", Bold("---"), p.clean(i18n.T(text)))
 }
 
 // Info prints an info message.
-func (p *Printer) Info(format string, args ...interface{}) {
-	fmt.Printf("%s %s\n", Blue("ℹ"), fmt.Sprintf(format, args...))
+func (p *terminal) Info(format string, args ...interface{}) {
+	fmt.Fprintf(p.out, "%s %s\n", Blue(p.g.info), p.clean(i18n.Sprintf(format, args...)))
 }
 
 // Success prints a success message.
-func (p *Printer) Success(format string, args ...interface{}) {
-	fmt.Printf("%s %s\n", Green("✓"), fmt.Sprintf(format, args...))
+func (p *terminal) Success(format string, args ...interface{}) {
+	fmt.Fprintf(p.out, "%s %s\n", Green(p.g.success), p.clean(i18n.Sprintf(format, args...)))
 }
 
 // Warning prints a warning message.
-func (p *Printer) Warning(format string, args ...interface{}) {
-	fmt.Printf("%s %s\n", Yellow("⚠"), fmt.Sprintf(format, args...))
+func (p *terminal) Warning(format string, args ...interface{}) {
+	fmt.Fprintf(p.out, "%s %s\n", Yellow(p.g.warning), p.clean(i18n.Sprintf(format, args...)))
 }
 
 // Error prints an error message.
-func (p *Printer) Error(format string, args ...interface{}) {
-	fmt.Printf("%s %s\n", Red("✗"), fmt.Sprintf(format, args...))
+func (p *terminal) Error(format string, args ...interface{}) {
+	fmt.Fprintf(p.out, "%s %s\n", Red(p.g.failure), p.clean(i18n.Sprintf(format, args...)))
 }
 
 // Debug prints a debug message (only if verbose).
-func (p *Printer) Debug(format string, args ...interface{}) {
+func (p *terminal) Debug(format string, args ...interface{}) {
 	if p.verbose {
-		fmt.Printf("%s %s\n", Dim("[debug]"), fmt.Sprintf(format, args...))
+		fmt.Fprintf(p.out, "%s %s\n", Dim("[debug]"), p.clean(i18n.Sprintf(format, args...)))
 	}
 }
 
 // Iteration prints iteration start.
-func (p *Printer) Iteration(current, max int) {
+func (p *terminal) Iteration(current, max int) {
 	var display string
 	if max > 0 {
 		display = fmt.Sprintf("(%d/%d)", current, max)
 	} else {
 		display = fmt.Sprintf("(%d)", current)
 	}
-	fmt.Printf("\n%s %s Starting iteration %s\n", Blue("🔄"), Bold(display), Cyan(fmt.Sprintf("#%d", current)))
+	fmt.Fprintf(p.out, "\n%s %s %s\n", Blue(p.g.iteration), Bold(display), i18n.Sprintf("Starting iteration %s", Cyan(fmt.Sprintf("#%d", current))))
+}
+
+// barWidth is the number of cells in a budget bar.
+const barWidth = 10
+
+// Budget prints how much of the cost and time caps the run has used, as
+// bars. Caps of 0 are left out; with neither set nothing is printed.
+func (p *terminal) Budget(cost, maxCost float64, elapsed, maxDuration time.Duration) {
+	var parts []string
+	if maxCost > 0 {
+		parts = append(parts, fmt.Sprintf("%s $%.2f / $%.2f", p.bar(cost/maxCost), cost, maxCost))
+	}
+	if maxDuration > 0 {
+		parts = append(parts, fmt.Sprintf("%s %s / %s", p.bar(float64(elapsed)/float64(maxDuration)), formatDuration(elapsed), formatDuration(maxDuration)))
+	}
+	if len(parts) == 0 {
+		return
+	}
+	fmt.Fprintf(p.out, "%s %s\n", Dim(p.g.budget), i18n.Sprintf("Budget: %s", strings.Join(parts, "  ")))
+}
+
+// bar renders the used fraction of a budget, turning yellow past 70% and
+// red past 90%.
+func (p *terminal) bar(used float64) string {
+	used = max(0, min(used, 1))
+	filled := int(used*barWidth + 0.5)
+	cells := strings.Repeat(p.g.barFull, filled) + Dim(strings.Repeat(p.g.barEmpty, barWidth-filled))
+	paint := Green
+	switch {
+	case used > 0.9:
+		paint = Red
+	case used > 0.7:
+		paint = Yellow
+	}
+	return fmt.Sprintf("%s %s", cells, paint(fmt.Sprintf("%3.0f%%", used*100)))
+}
+
+// Sparkline draws values as a line of bars scaled to the largest one.
+func Sparkline(values []float64) string {
+	return sparkline(values, unicodeGlyphs.spark)
+}
+
+// sparkline draws values with sparkChars, from the lowest value to the
+// highest.
+func sparkline(values []float64, sparkChars []rune) string {
+	var top float64
+	for _, v := range values {
+		top = max(top, v)
+	}
+	line := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if top > 0 {
+			level = int(v / top * float64(len(sparkChars)-1))
+		}
+		line[i] = sparkChars[level]
+	}
+	return string(line)
 }
 
 // Cost prints cost information.
-func (p *Printer) Cost(iterationCost, totalCost float64) {
-	fmt.Printf("%s Iteration cost: %s | Total: %s\n",
-		Dim("💰"),
+func (p *terminal) Cost(iterationCost, totalCost float64) {
+	fmt.Fprintf(p.out, "%s %s\n", Dim(p.g.cost), i18n.Sprintf("Iteration cost: %s | Total: %s",
 		Yellow(fmt.Sprintf("$%.4f", iterationCost)),
-		Bold(fmt.Sprintf("$%.4f", totalCost)))
+		Bold(fmt.Sprintf("$%.4f", totalCost))))
+}
+
+// DiffStat prints the size of an iteration's changes.
+func (p *terminal) DiffStat(files, insertions, deletions int) {
+	fmt.Fprintf(p.out, "%s %s\n", Dim(p.g.diff), i18n.Sprintf("Changes: %d files | %s %s", files,
+		Green(fmt.Sprintf("+%d", insertions)),
+		Red(fmt.Sprintf("-%d", deletions))))
 }
 
 // Duration prints duration information.
-func (p *Printer) Duration(elapsed, max time.Duration) {
+func (p *terminal) Duration(elapsed, max time.Duration) {
 	var maxStr string
 	if max > 0 {
 		maxStr = fmt.Sprintf(" / %s", formatDuration(max))
 	}
-	fmt.Printf("%s Elapsed: %s%s\n", Dim("⏱"), formatDuration(elapsed), maxStr)
+	fmt.Fprintf(p.out, "%s %s\n", Dim(p.g.elapsed), i18n.Sprintf("Elapsed: %s%s", formatDuration(elapsed), maxStr))
+}
+
+// Timing prints how long an iteration took and where the time went.
+func (p *terminal) Timing(iteration int, elapsed time.Duration, phases string) {
+	fmt.Fprintf(p.out, "%s %s\n", Dim(p.g.elapsed), i18n.Sprintf("Iteration %d took %s: %s", iteration, formatDuration(elapsed), phases))
 }
 
 // PRStatus prints PR check status.
-func (p *Printer) PRStatus(checksPassed, hasPending, hasFailed bool, reviewStatus string) {
+func (p *terminal) PRStatus(checksPassed, hasPending, hasFailed bool, reviewStatus string) {
 	var checkIcon, checkMsg string
 	if hasFailed {
-		checkIcon = Red("✗")
-		checkMsg = Red("Checks failed")
+		checkIcon = Red(p.g.failure)
+		checkMsg = Red(i18n.T("Checks failed"))
 	} else if hasPending {
-		checkIcon = Yellow("○")
-		checkMsg = Yellow("Checks pending")
+		checkIcon = Yellow(p.g.pending)
+		checkMsg = Yellow(i18n.T("Checks pending"))
 	} else {
-		checkIcon = Green("✓")
-		checkMsg = Green("All checks passed")
+		checkIcon = Green(p.g.success)
+		checkMsg = Green(i18n.T("All checks passed"))
 	}
 
 	var reviewIcon, reviewMsg string
 	switch reviewStatus {
 	case "APPROVED":
-		reviewIcon = Green("✓")
-		reviewMsg = Green("Approved")
+		reviewIcon = Green(p.g.success)
+		reviewMsg = Green(i18n.T("Approved"))
 	case "CHANGES_REQUESTED":
-		reviewIcon = Red("✗")
-		reviewMsg = Red("Changes requested")
+		reviewIcon = Red(p.g.failure)
+		reviewMsg = Red(i18n.T("Changes requested"))
 	case "":
-		reviewIcon = Dim("○")
-		reviewMsg = Dim("No reviews")
+		reviewIcon = Dim(p.g.pending)
+		reviewMsg = Dim(i18n.T("No reviews"))
 	default:
-		reviewIcon = Yellow("○")
-		reviewMsg = Yellow("Review pending")
+		reviewIcon = Yellow(p.g.pending)
+		reviewMsg = Yellow(i18n.T("Review pending"))
 	}
 
-	fmt.Printf("  %s Checks: %s | %s Review: %s\n", checkIcon, checkMsg, reviewIcon, reviewMsg)
+	fmt.Fprintf(p.out, "  %s\n", i18n.Sprintf("%s Checks: %s | %s Review: %s", checkIcon, checkMsg, reviewIcon, reviewMsg))
 }
 
 // StartSpinner starts the spinner with a message.
-func (p *Printer) StartSpinner(message string) {
-	p.spinner.Suffix = " " + message
+func (p *terminal) StartSpinner(message string) {
+	if p.progressEvery > 0 {
+		p.startProgress(p.clean(message))
+		return
+	}
+	p.spinner.Suffix = " " + p.clean(message)
 	p.spinner.Start()
 }
 
 // UpdateSpinner updates the spinner message.
-func (p *Printer) UpdateSpinner(message string) {
-	p.spinner.Suffix = " " + message
+func (p *terminal) UpdateSpinner(message string) {
+	if p.progress != nil {
+		p.progress.setMessage(p.clean(message))
+		return
+	}
+	p.spinner.Suffix = " " + p.clean(message)
 }
 
 // StopSpinner stops the spinner.
-func (p *Printer) StopSpinner() {
+func (p *terminal) StopSpinner() {
+	if p.progressEvery > 0 {
+		p.stopProgress()
+		return
+	}
 	p.spinner.Stop()
 }
 
 // Box prints text in a box.
-func (p *Printer) Box(title, content string) {
-	width := 60
-	fmt.Println()
-	fmt.Println(strings.Repeat("─", width))
+// Lines longer than the terminal is wide are wrapped.
+func (p *terminal) Box(title, content string) {
+	width := p.boxWidth()
+	var lines []string
+	for _, line := range strings.Split(p.clean(content), "\n") {
+		lines = append(lines, wrap(line, width-2)...)
+	}
+	p.frame(title, lines, width)
+}
+
+// Markdown prints markdown text, such as Claude's output, formatted in a
+// box.
+func (p *terminal) Markdown(title, text string) {
+	width := p.boxWidth()
+	p.frame(title, renderMarkdown(p.clean(text), width-2, p.g), width)
+}
+
+// Page shows text in $PAGER, or less, if stdout is a terminal.
+func (p *terminal) Page(text string) error {
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		return nil
+	}
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin = strings.NewReader(p.clean(text))
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", pager, err)
+	}
+	return nil
+}
+
+// frame prints lines in a box width cells wide.
+func (p *terminal) frame(title string, lines []string, width int) {
+	fmt.Fprintln(p.out)
+	fmt.Fprintln(p.out, strings.Repeat(p.g.rule, width))
 	if title != "" {
-		fmt.Printf("│ %s\n", Bold(title))
-		fmt.Println(strings.Repeat("─", width))
+		fmt.Fprintf(p.out, p.g.side+" %s\n", Bold(clip(p.clean(i18n.T(title)), width-2, p.g.ellipsis)))
+		fmt.Fprintln(p.out, strings.Repeat(p.g.rule, width))
 	}
-	for _, line := range strings.Split(content, "\n") {
-		fmt.Printf("│ %s\n", line)
+	for _, line := range lines {
+		fmt.Fprintf(p.out, p.g.side+" %s\n", line)
+	}
+	fmt.Fprintln(p.out, strings.Repeat(p.g.rule, width))
+}
+
+func (p *terminal) boxWidth() int {
+	if w := p.terminalWidth(); w > 0 {
+		return max(min(w, maxBoxWidth), minWidth)
+	}
+	return defaultBoxWidth
+}
+
+// Boxes span the terminal up to maxBoxWidth cells, and defaultBoxWidth
+// when its width is unknown. Output is never fit to fewer than minWidth.
+const (
+	defaultBoxWidth = 60
+	maxBoxWidth     = 100
+	minWidth        = 20
+)
+
+// terminalWidth returns the width to fit output to, or 0 if it is unknown.
+func (p *terminal) terminalWidth() int {
+	if p.width > 0 {
+		return p.width
 	}
-	fmt.Println(strings.Repeat("─", width))
+	return terminalWidth()
 }
 
+// RunSummary holds the totals reported at the end of a run.
+type RunSummary struct {
+	RunID        string
+	Iterations   int
+	TotalCost    float64
+	Elapsed      time.Duration
+	Completed    bool
+	FilesChanged int
+	Insertions   int
+	Deletions    int
+	// Breakdown counts the iterations by outcome, and Failures describes
+	// each that didn't succeed
+	Breakdown string
+	Failures  []string
+	// Phases tells where the iterations' time went
+	Phases string
+	// IterationCosts are the costs of the iterations in order
+	IterationCosts []float64
+	// Checklist items of a --migration-cmd run done, of all it listed
+	MigrationDone  int
+	MigrationTotal int
+}
+
+// sparklineWidth is the number of latest iterations the summary's cost
+// sparkline shows, so it fits the summary's rules.
+const sparklineWidth = 46
+
 // Summary prints a run summary.
-func (p *Printer) Summary(iterations int, totalCost float64, elapsed time.Duration, completed bool) {
-	fmt.Println()
-	fmt.Println(strings.Repeat("═", 50))
-	fmt.Printf("  %s\n", Bold("Run Summary"))
-	fmt.Println(strings.Repeat("─", 50))
-	fmt.Printf("  Iterations completed: %s\n", Cyan(fmt.Sprintf("%d", iterations)))
-	fmt.Printf("  Total cost: %s\n", Yellow(fmt.Sprintf("$%.4f", totalCost)))
-	fmt.Printf("  Total time: %s\n", formatDuration(elapsed))
-
-	if completed {
-		fmt.Printf("  Status: %s\n", Green("Completed (project goal reached)"))
+func (p *terminal) Summary(s RunSummary) {
+	fmt.Fprintln(p.out)
+	fmt.Fprintln(p.out, strings.Repeat(p.g.heavyRule, 50))
+	fmt.Fprintf(p.out, "  %s\n", Bold(i18n.T("Run Summary")))
+	fmt.Fprintln(p.out, strings.Repeat(p.g.rule, 50))
+	if s.RunID != "" {
+		fmt.Fprintf(p.out, "  %s\n", i18n.Sprintf("Run ID: %s", s.RunID))
+	}
+	if s.Breakdown != "" {
+		fmt.Fprintf(p.out, "  %s\n", i18n.Sprintf("Iterations: %s (%s)", Cyan(fmt.Sprintf("%d", s.Iterations)), s.Breakdown))
+		for _, f := range s.Failures {
+			fmt.Fprintf(p.out, "    %s\n", Dim(f))
+		}
 	} else {
-		fmt.Printf("  Status: %s\n", Yellow("Limit reached"))
+		fmt.Fprintf(p.out, "  %s\n", i18n.Sprintf("Iterations completed: %s", Cyan(fmt.Sprintf("%d", s.Iterations))))
+	}
+	fmt.Fprintf(p.out, "  %s\n", i18n.Sprintf("Total cost: %s", Yellow(fmt.Sprintf("$%.4f", s.TotalCost))))
+	if costs := s.IterationCosts; len(costs) > 1 {
+		label := i18n.T("per iteration")
+		if len(costs) > sparklineWidth {
+			costs = costs[len(costs)-sparklineWidth:]
+			label = i18n.Sprintf("in the last %d iterations", sparklineWidth)
+		}
+		fmt.Fprintf(p.out, "    %s\n", i18n.Sprintf("%s %s, up to $%.4f", Cyan(sparkline(costs, p.g.spark)), label, slices.Max(costs)))
+	}
+	fmt.Fprintf(p.out, "  %s\n", i18n.Sprintf("Total time: %s", formatDuration(s.Elapsed)))
+	if s.Phases != "" {
+		fmt.Fprintf(p.out, "    %s\n", Dim(s.Phases))
 	}
-	fmt.Println(strings.Repeat("═", 50))
+	fmt.Fprintf(p.out, "  %s\n", i18n.Sprintf("Changes: %d files, %s %s", s.FilesChanged,
+		Green(fmt.Sprintf("+%d", s.Insertions)), Red(fmt.Sprintf("-%d", s.Deletions))))
+	if s.MigrationTotal > 0 {
+		fmt.Fprintf(p.out, "  %s\n", i18n.Sprintf("Migration: %d of %d items done (%d%%)", s.MigrationDone, s.MigrationTotal, s.MigrationDone*100/s.MigrationTotal))
+	}
+
+	if s.Completed {
+		fmt.Fprintf(p.out, "  %s\n", i18n.Sprintf("Status: %s", Green(i18n.T("Completed (project goal reached)"))))
+	} else {
+		fmt.Fprintf(p.out, "  %s\n", i18n.Sprintf("Status: %s", Yellow(i18n.T("Limit reached"))))
+	}
+	fmt.Fprintln(p.out, strings.Repeat(p.g.heavyRule, 50))
 }
 
-// Table prints a simple table.
-func (p *Printer) Table(headers []string, rows [][]string) {
+// Table prints a simple table. When it is wider than the terminal, the
+// widest columns are narrowed and their cells clipped.
+func (p *terminal) Table(headers []string, rows [][]string) {
+	rows = p.cleanRows(rows)
+
 	// Calculate column widths
 	widths := make([]int, len(headers))
 	for i, h := range headers {
-		widths[i] = len(h)
+		widths[i] = Width(h)
 	}
 	for _, row := range rows {
 		for i, cell := range row {
-			if len(cell) > widths[i] {
-				widths[i] = len(cell)
-			}
+			widths[i] = max(widths[i], Width(cell))
 		}
 	}
+	if w := p.terminalWidth(); w > 0 {
+		fitColumns(widths, max(w, minWidth))
+	}
 
 	// Print header
 	for i, h := range headers {
-		fmt.Printf("%-*s  ", widths[i], Bold(h))
+		fmt.Fprintf(p.out, "%s  ", pad(Bold(clip(h, widths[i], p.g.ellipsis)), widths[i]))
 	}
-	fmt.Println()
+	fmt.Fprintln(p.out)
 
 	// Print separator
 	for i := range headers {
-		fmt.Printf("%s  ", strings.Repeat("─", widths[i]))
+		fmt.Fprintf(p.out, "%s  ", strings.Repeat(p.g.rule, widths[i]))
 	}
-	fmt.Println()
+	fmt.Fprintln(p.out)
 
 	// Print rows
 	for _, row := range rows {
 		for i, cell := range row {
-			fmt.Printf("%-*s  ", widths[i], cell)
+			fmt.Fprintf(p.out, "%s  ", pad(clip(cell, widths[i], p.g.ellipsis), widths[i]))
 		}
-		fmt.Println()
+		fmt.Fprintln(p.out)
 	}
 }
 
+// minColumnWidth is the narrowest fitColumns makes a column.
+const minColumnWidth = 8
+
+// fitColumns narrows the widest columns, two cells apart, until they fit
+// in total cells or are all minColumnWidth wide.
+func fitColumns(widths []int, total int) {
+	for {
+		sum := 2 * len(widths)
+		widest := 0
+		for i, w := range widths {
+			sum += w
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if sum <= total || widths[widest] <= minColumnWidth {
+			return
+		}
+		widths[widest]--
+	}
+}
+
+// cleanRows returns a copy of rows with the secrets in their cells scrubbed.
+func (p *terminal) cleanRows(rows [][]string) [][]string {
+	if p.redact == nil {
+		return rows
+	}
+	clean := make([][]string, len(rows))
+	for i, row := range rows {
+		clean[i] = make([]string, len(row))
+		for j, cell := range row {
+			clean[i][j] = p.redact(cell)
+		}
+	}
+	return clean
+}
+
 // Prompt prints a prompt and waits for input.
-func (p *Printer) Prompt(message string) string {
-	fmt.Printf("%s %s: ", Blue("?"), message)
+func (p *terminal) Prompt(message string) string {
+	fmt.Fprintf(p.out, "%s %s: ", Blue("?"), i18n.T(message))
 	var input string
 	_, _ = fmt.Scanln(&input)
 	return strings.TrimSpace(input)
 }
 
 // Confirm prints a confirmation prompt.
-func (p *Printer) Confirm(message string) bool {
-	fmt.Printf("%s %s [y/N]: ", Yellow("?"), message)
+func (p *terminal) Confirm(message string) bool {
+	fmt.Fprintf(p.out, "%s %s [y/N]: ", Yellow("?"), i18n.T(message))
 	var input string
 	_, _ = fmt.Scanln(&input)
 	input = strings.ToLower(strings.TrimSpace(input))
 	return input == "y" || input == "yes"
 }
 
+// Close does nothing: the terminal stays open.
+func (p *terminal) Close() error {
+	return nil
+}
+
 func formatDuration(d time.Duration) string {
 	if d < time.Minute {
 		return fmt.Sprintf("%ds", int(d.Seconds()))
@@ -0,0 +1,337 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode"
+)
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   string
+	}{
+		{nil, ""},
+		{[]float64{0, 0}, "▁▁"},
+		{[]float64{0.1, 0.8, 0.4, 0}, "▁█▄▁"},
+		{[]float64{2, 2, 2}, "███"},
+	}
+	for _, tt := range tests {
+		if got := Sparkline(tt.values); got != tt.want {
+			t.Errorf("Sparkline(%v) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var b strings.Builder
+	p := NewJSON(&b, false)
+	p.SetRedactor(func(s string) string { return strings.ReplaceAll(s, "hunter2", "[REDACTED]") })
+	p.Info("Pushing %s with hunter2", "main")
+	p.Debug("left out without verbose")
+	p.Cost(0.25, 1.5)
+	p.Table([]string{"PR", "STATE"}, [][]string{{"#7", "hunter2"}})
+
+	var got []Entry
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("line %q is not an entry: %v", line, err)
+		}
+		got = append(got, e)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d entries, want 3:\n%s", len(got), b.String())
+	}
+	if got[0].Type != "info" || got[0].Message != "Pushing main with [REDACTED]" {
+		t.Errorf("entry 0 = %+v", got[0])
+	}
+	if got[1].Type != "cost" || got[1].Data["total"] != 1.5 {
+		t.Errorf("entry 1 = %+v", got[1])
+	}
+	if got[2].Type != "table" || strings.Contains(got[2].Message, "hunter2") || strings.Contains(fmt.Sprint(got[2].Data), "hunter2") {
+		t.Errorf("entry 2 = %+v", got[2])
+	}
+}
+
+func TestLog(t *testing.T) {
+	var b strings.Builder
+	p := NewLog(&b, true)
+	p.Warning("Checks failed")
+	p.Box("Claude", "line one\nline two")
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4:\n%s", len(lines), b.String())
+	}
+	if stamp, rest, _ := strings.Cut(lines[0], " "); len(stamp) != 20 || rest != "WARNING   Checks failed" {
+		t.Errorf("line 0 = %q", lines[0])
+	}
+	if want := strings.Repeat(" ", 31) + "line two"; lines[3] != want {
+		t.Errorf("line 3 = %q, want %q", lines[3], want)
+	}
+}
+
+func TestTee(t *testing.T) {
+	var a, b strings.Builder
+	p := Tee(NewLog(&a, false), NewSilent(), NewJSON(&b, false))
+	p.Success("Merged PR #%d", 7)
+	if !strings.Contains(a.String(), "Merged PR #7") || !strings.Contains(b.String(), `"message":"Merged PR #7"`) {
+		t.Errorf("Tee() printed %q and %q", a.String(), b.String())
+	}
+	if p.Confirm("Proceed?") {
+		t.Error("Confirm() = true from a printer that isn't interactive")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := Open(Options{Output: "fancy"}); err == nil {
+		t.Error("Open(fancy) expected error, got nil")
+	}
+	path := filepath.Join(t.TempDir(), "run.log")
+	p, err := Open(Options{Output: "silent", LogFile: path})
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	p.Info("Starting")
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "INFO      Starting") {
+		t.Errorf("log file = %q", data)
+	}
+}
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"abc", 3},
+		{"héllo", 5},
+		{"e\u0301", 1},
+		{"日本語", 6},
+		{"✓ done", 6},
+		{"🔄 go", 5},
+		{"\x1b[1mbold\x1b[0m", 4},
+	}
+	for _, tt := range tests {
+		if got := Width(tt.s); got != tt.want {
+			t.Errorf("Width(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		line  string
+		width int
+		want  []string
+	}{
+		{"short", 10, []string{"short"}},
+		{"the quick brown fox", 10, []string{"the quick", "brown fox"}},
+		{"abcdefghijkl", 5, []string{"abcde", "fghij", "kl"}},
+		{"日本語のテキスト", 6, []string{"日本語", "のテキ", "スト"}},
+		{"\tindented", 20, []string{"    indented"}},
+	}
+	for _, tt := range tests {
+		if got := wrap(tt.line, tt.width); !slices.Equal(got, tt.want) {
+			t.Errorf("wrap(%q, %d) = %q, want %q", tt.line, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestClip(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"fits", 4, "fits"},
+		{"too long", 5, "too …"},
+		{"日本語", 4, "日…"},
+	}
+	for _, tt := range tests {
+		if got := clip(tt.s, tt.width, "…"); got != tt.want {
+			t.Errorf("clip(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestTerminalFitsWidth(t *testing.T) {
+	var b strings.Builder
+	p := &terminal{out: &b, g: unicodeGlyphs, width: 30}
+	p.Box("Claude Output", "a line that is much too long for a thirty cell terminal\n日本語")
+	p.Table([]string{"ID", "PROMPT"}, [][]string{{"7", "rewrite the whole parser in a single pass"}})
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		if w := Width(line); w > 30 {
+			t.Errorf("line %q is %d cells wide, want at most 30", line, w)
+		}
+	}
+	for _, want := range []string{"│ a line that is much too long", "│ 日本語", "7   rewrite the whole parse…"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, b.String())
+		}
+	}
+}
+
+// syncWriter lets a test read what a printer's goroutines write.
+type syncWriter struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.b.Write(p)
+}
+
+func (w *syncWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.b.String()
+}
+
+func TestProgressLine(t *testing.T) {
+	started := time.Date(2026, 10, 14, 12, 3, 11, 0, time.Local)
+	pr := &progress{message: "Waiting for checks", started: started}
+	if got, want := pr.line(started), "[12:03:11] Waiting for checks"; got != want {
+		t.Errorf("line() = %q, want %q", got, want)
+	}
+	if got, want := pr.line(started.Add(4*time.Minute)), "[12:07:11] Waiting for checks (4m0s elapsed)"; got != want {
+		t.Errorf("line() = %q, want %q", got, want)
+	}
+}
+
+func TestProgressReplacesSpinner(t *testing.T) {
+	var w syncWriter
+	p := &terminal{out: &w, g: unicodeGlyphs, progressEvery: 5 * time.Millisecond}
+	p.StartSpinner("Running Claude")
+	time.Sleep(30 * time.Millisecond)
+	p.UpdateSpinner("Waiting for checks")
+	time.Sleep(30 * time.Millisecond)
+	p.StopSpinner()
+	stopped := w.String()
+	time.Sleep(20 * time.Millisecond)
+
+	if w.String() != stopped {
+		t.Error("progress lines kept coming after StopSpinner")
+	}
+	if strings.Contains(stopped, "\x1b") || strings.Contains(stopped, "\r") {
+		t.Errorf("progress lines contain control characters: %q", stopped)
+	}
+	lines := strings.Split(strings.TrimSpace(stopped), "\n")
+	if len(lines) < 3 || !strings.HasSuffix(lines[0], "] Running Claude") || !strings.Contains(lines[len(lines)-1], "] Waiting for checks") {
+		t.Errorf("progress lines = %q", lines)
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	text := "## Summary\n" +
+		"Fixed the **flaky** test in `parser_test.go`, see [the issue](https://example.com/1).\n" +
+		"\n" +
+		"- first change, which is described at a length that needs wrapping\n" +
+		"  * nested\n" +
+		"2. numbered\n" +
+		"> quoted\n" +
+		"---\n" +
+		"```go\n" +
+		"func **notBold**() {}\n" +
+		"```"
+	got := renderMarkdown(text, 40, unicodeGlyphs)
+	want := []string{
+		"Summary",
+		"Fixed the flaky test in parser_test.go,",
+		"see the issue (https://example.com/1).",
+		"",
+		"• first change, which is described at a",
+		"  length that needs wrapping",
+		"  • nested",
+		"2. numbered",
+		"│ quoted",
+		strings.Repeat("─", 40),
+		"  func **notBold**() {}",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("renderMarkdown() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestASCII(t *testing.T) {
+	var b strings.Builder
+	p := &terminal{out: &b, g: asciiGlyphs, width: 40}
+	p.Success("Merged PR #%d", 7)
+	p.Warning("Checks pending")
+	p.Iteration(2, 5)
+	p.Budget(4, 10, 0, 0)
+	p.PRStatus(false, true, false, "APPROVED")
+	p.Markdown("Claude Output", "- done\n---")
+	p.Table([]string{"PROMPT"}, [][]string{{"a prompt far too long for forty cells of terminal"}})
+	p.Summary(RunSummary{Iterations: 2, IterationCosts: []float64{1, 2}})
+
+	for _, line := range strings.Split(b.String(), "\n") {
+		for _, r := range line {
+			if r > unicode.MaxASCII {
+				t.Errorf("line %q has non-ASCII %q", line, r)
+				break
+			}
+		}
+	}
+	for _, want := range []string{"[OK] Merged PR #7", "[WARN] Checks pending", "[ITERATION] (2/5)", "[BUDGET] Budget: ####......", "[OK] Review", "| * done", "..."} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestSummarySparklineWindow(t *testing.T) {
+	costs := make([]float64, sparklineWidth+4)
+	for i := range costs {
+		costs[i] = float64(i)
+	}
+	var b strings.Builder
+	p := &terminal{out: &b, g: asciiGlyphs}
+	p.Summary(RunSummary{Iterations: len(costs), IterationCosts: costs})
+
+	want := sparkline(costs[4:], asciiGlyphs.spark) + " in the last 46 iterations, up to $49.0000"
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("summary is missing %q:\n%s", want, b.String())
+	}
+}
+
+func TestDetectASCII(t *testing.T) {
+	tests := []struct {
+		term, lang string
+		want       bool
+	}{
+		{"xterm-256color", "en_US.UTF-8", false},
+		{"xterm-256color", "C.utf8", false},
+		{"dumb", "en_US.UTF-8", true},
+		{"xterm", "C", true},
+		{"xterm", "", false},
+	}
+	for _, tt := range tests {
+		t.Setenv("TERM", tt.term)
+		t.Setenv("LC_ALL", "")
+		t.Setenv("LC_CTYPE", "")
+		t.Setenv("LANG", tt.lang)
+		if runtime.GOOS != "windows" {
+			if got := detectASCII(); got != tt.want {
+				t.Errorf("detectASCII() with TERM=%s LANG=%s = %v, want %v", tt.term, tt.lang, got, tt.want)
+			}
+		}
+	}
+}
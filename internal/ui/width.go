@@ -0,0 +1,181 @@
+package ui
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// terminalWidth returns the width of the terminal stdout is attached to,
+// from $COLUMNS when it isn't one, or 0 if it is unknown, e.g. when output
+// is piped to a file.
+func terminalWidth() int {
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+	return 0
+}
+
+// wideRanges are the code points shown two cells wide: East Asian wide and
+// full-width characters, and emoji.
+var wideRanges = []struct{ lo, hi rune }{
+	{0x1100, 0x115F},
+	{0x231A, 0x231B},
+	{0x23E9, 0x23F3},
+	{0x25FD, 0x25FE},
+	{0x2614, 0x2615},
+	{0x26A1, 0x26A1},
+	{0x26AA, 0x26AB},
+	{0x26BD, 0x26BE},
+	{0x26C4, 0x26C5},
+	{0x26D4, 0x26D4},
+	{0x26EA, 0x26EA},
+	{0x26F2, 0x26F5},
+	{0x26FA, 0x26FD},
+	{0x2705, 0x2705},
+	{0x270A, 0x270B},
+	{0x274C, 0x274C},
+	{0x2753, 0x2755},
+	{0x2795, 0x2797},
+	{0x2B1B, 0x2B1C},
+	{0x2E80, 0x303E},
+	{0x3041, 0x33FF},
+	{0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF},
+	{0xA000, 0xA4CF},
+	{0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF},
+	{0xFE30, 0xFE4F},
+	{0xFF00, 0xFF60},
+	{0xFFE0, 0xFFE6},
+	{0x1F300, 0x1F64F},
+	{0x1F680, 0x1F6FF},
+	{0x1F900, 0x1F9FF},
+	{0x1FA70, 0x1FAFF},
+	{0x20000, 0x3FFFD},
+}
+
+// runeWidth returns the number of cells r takes up in a terminal.
+func runeWidth(r rune) int {
+	switch {
+	case r == 0x200D || (r >= 0xFE00 && r <= 0xFE0F):
+		// Zero-width joiner and variation selectors
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf, unicode.Cc):
+		return 0
+	}
+	for _, wide := range wideRanges {
+		if r >= wide.lo && r <= wide.hi {
+			return 2
+		}
+	}
+	return 1
+}
+
+// Width returns the number of cells s takes up in a terminal, leaving out
+// color escape sequences.
+func Width(s string) int {
+	var w int
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w += runeWidth(r)
+		i += size
+	}
+	return w
+}
+
+// escapeLen returns the length of the ANSI escape sequence s starts with,
+// or 0.
+func escapeLen(s string) int {
+	if !strings.HasPrefix(s, "\x1b[") {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7E {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// pad fills s with spaces up to width cells.
+func pad(s string, width int) string {
+	if w := Width(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
+
+// clip cuts s to at most width cells, ending it with ellipsis if it was
+// cut. Text with color escape sequences is left as it is.
+func clip(s string, width int, ellipsis string) string {
+	if Width(s) <= width || strings.Contains(s, "\x1b[") {
+		return s
+	}
+	var b strings.Builder
+	var w int
+	for _, r := range s {
+		if w+runeWidth(r) > width-Width(ellipsis) {
+			break
+		}
+		b.WriteRune(r)
+		w += runeWidth(r)
+	}
+	return b.String() + ellipsis
+}
+
+// wrap breaks a line into lines of at most width cells, at spaces where it
+// can and mid-word where a word is longer than a line. Tabs become four
+// spaces, and color escape sequences take no room.
+func wrap(line string, width int) []string {
+	line = strings.ReplaceAll(line, "\t", "    ")
+	if width < 1 || Width(line) <= width {
+		return []string{line}
+	}
+	var lines []string
+	var current strings.Builder
+	var w int
+	flush := func() {
+		lines = append(lines, strings.TrimRight(current.String(), " "))
+		current.Reset()
+		w = 0
+	}
+	for i, word := range strings.Split(line, " ") {
+		if i > 0 {
+			if w+1+Width(word) <= width {
+				current.WriteByte(' ')
+				w++
+			} else {
+				flush()
+			}
+		}
+		for j := 0; j < len(word); {
+			if n := escapeLen(word[j:]); n > 0 {
+				current.WriteString(word[j : j+n])
+				j += n
+				continue
+			}
+			r, size := utf8.DecodeRuneInString(word[j:])
+			rw := runeWidth(r)
+			if w+rw > width {
+				flush()
+			}
+			current.WriteRune(r)
+			w += rw
+			j += size
+		}
+	}
+	flush()
+	return lines
+}
@@ -0,0 +1,50 @@
+package version
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CheckFile is where the last update check is cached, in the state
+// directory.
+const CheckFile = "update-check.json"
+
+// cachedCheck is the result of an update check.
+type cachedCheck struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Channel   Channel   `json:"channel"`
+	Latest    string    `json:"latest"`
+}
+
+// checkForUpdates is replaced in tests.
+var checkForUpdates = CheckForUpdates
+
+// CheckForUpdatesCached is CheckForUpdates, but reuses the latest version
+// found by a check on the same channel less than interval ago, cached at
+// path. An interval of 0 always checks.
+func CheckForUpdatesCached(currentVersion string, channel Channel, path string, interval time.Duration) (string, bool, error) {
+	var cached cachedCheck
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &cached) == nil {
+		age := time.Since(cached.CheckedAt)
+		if interval > 0 && cached.Channel == channel && cached.Latest != "" && age >= 0 && age < interval {
+			return cached.Latest, ComparePrerelease(cached.Latest, currentVersion) > 0, nil
+		}
+	}
+
+	latestVersion, hasUpdate, err := checkForUpdates(currentVersion, channel)
+	if err != nil {
+		return "", false, err
+	}
+
+	// The cache only saves time, so failing to write it isn't an error
+	cached = cachedCheck{CheckedAt: time.Now(), Channel: channel, Latest: latestVersion}
+	if data, err := json.Marshal(cached); err == nil && os.MkdirAll(filepath.Dir(path), 0755) == nil {
+		tmp := path + ".tmp"
+		if os.WriteFile(tmp, data, 0644) == nil {
+			os.Rename(tmp, path)
+		}
+	}
+	return latestVersion, hasUpdate, nil
+}
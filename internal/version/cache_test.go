@@ -0,0 +1,72 @@
+package version
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckForUpdatesCached(t *testing.T) {
+	calls := 0
+	checkForUpdates = func(currentVersion string, channel Channel) (string, bool, error) {
+		calls++
+		return "v1.2.0", Compare("v1.2.0", currentVersion) > 0, nil
+	}
+	defer func() { checkForUpdates = CheckForUpdates }()
+
+	write := func(path string, check cachedCheck) {
+		data, err := json.Marshal(check)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		cached    *cachedCheck
+		interval  time.Duration
+		wantCheck bool
+		want      string
+	}{
+		{"no cache", nil, time.Hour, true, "v1.2.0"},
+		{"fresh", &cachedCheck{CheckedAt: time.Now().Add(-time.Minute), Channel: ChannelStable, Latest: "v1.1.0"}, time.Hour, false, "v1.1.0"},
+		{"stale", &cachedCheck{CheckedAt: time.Now().Add(-2 * time.Hour), Channel: ChannelStable, Latest: "v1.1.0"}, time.Hour, true, "v1.2.0"},
+		{"other channel", &cachedCheck{CheckedAt: time.Now(), Channel: ChannelBeta, Latest: "v1.1.0-beta.1"}, time.Hour, true, "v1.2.0"},
+		{"from the future", &cachedCheck{CheckedAt: time.Now().Add(time.Hour), Channel: ChannelStable, Latest: "v1.1.0"}, 2 * time.Hour, true, "v1.2.0"},
+		{"interval 0", &cachedCheck{CheckedAt: time.Now(), Channel: ChannelStable, Latest: "v1.1.0"}, 0, true, "v1.2.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), CheckFile)
+			if tt.cached != nil {
+				write(path, *tt.cached)
+			}
+			calls = 0
+
+			latest, hasUpdate, err := CheckForUpdatesCached("v1.0.0", ChannelStable, path, tt.interval)
+			if err != nil {
+				t.Fatalf("CheckForUpdatesCached() unexpected error: %v", err)
+			}
+			if latest != tt.want || !hasUpdate {
+				t.Errorf("CheckForUpdatesCached() = %q, %v, want %q, true", latest, hasUpdate, tt.want)
+			}
+			if (calls > 0) != tt.wantCheck {
+				t.Errorf("CheckForUpdatesCached() checked %d times, want check %v", calls, tt.wantCheck)
+			}
+
+			if tt.wantCheck {
+				var saved cachedCheck
+				data, err := os.ReadFile(path)
+				if err != nil || json.Unmarshal(data, &saved) != nil || saved.Latest != "v1.2.0" {
+					t.Errorf("result not cached: %s", data)
+				}
+			}
+		})
+	}
+}
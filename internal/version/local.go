@@ -0,0 +1,94 @@
+package version
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalUpdate prepares an update from a downloaded release artifact, a
+// binary or a .tar.gz holding one, for machines without internet access.
+// It verifies the artifact's checksum and, if the build has a PublicKey,
+// its signature at sigPath (default: the artifact path plus .minisig),
+// and returns the extracted binary to pass to InstallUpdate.
+func LocalUpdate(path, checksum, sigPath string) (string, error) {
+	if err := VerifyChecksum(path, strings.ToLower(checksum)); err != nil {
+		return "", err
+	}
+
+	if PublicKey != "" {
+		if sigPath == "" {
+			sigPath = path + SignatureSuffix
+		}
+		sig, err := os.ReadFile(sigPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read signature (use --signature): %w", err)
+		}
+		if err := VerifySignature(path, sig, PublicKey); err != nil {
+			return "", err
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	var magic [2]byte
+	if _, err := io.ReadFull(f, magic[:]); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	var binary io.Reader = f
+	if bytes.Equal(magic[:], []byte{0x1f, 0x8b}) {
+		if binary, err = extractBinary(f); err != nil {
+			return "", fmt.Errorf("failed to extract %s: %w", path, err)
+		}
+	}
+
+	tmpFile, err := os.CreateTemp("", "dclaude-update-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	if _, err := io.Copy(tmpFile, binary); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("failed to write update: %w", err)
+	}
+	tmpFile.Close()
+
+	return tmpFile.Name(), nil
+}
+
+// extractBinary returns the dclaude binary in a gzipped tarball.
+func extractBinary(r io.Reader) (io.Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no dclaude binary in the archive")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := filepath.Base(header.Name)
+		if header.Typeflag == tar.TypeReg && strings.HasPrefix(name, "dclaude") &&
+			!strings.HasSuffix(name, ".sha256") && !strings.HasSuffix(name, SignatureSuffix) {
+			return archive, nil
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package version
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tarball returns a .tar.gz holding the named files.
+func tarball(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	for _, name := range []string{"README.md", "dclaude-linux-amd64.sha256", "dclaude"} {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+		if err := archive.WriteHeader(&tar.Header{Name: "deep-claude/" + name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := archive.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLocalUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		artifact []byte
+		checksum string
+		want     string
+		wantErr  string
+	}{
+		{"binary", []byte("new binary"), "", "new binary", ""},
+		{"tarball", tarball(t, map[string]string{"README.md": "docs", "dclaude-linux-amd64.sha256": "abc", "dclaude": "new binary"}), "", "new binary", ""},
+		{"no binary in tarball", tarball(t, map[string]string{"README.md": "docs"}), "", "", "no dclaude binary"},
+		{"wrong checksum", []byte("new binary"), strings.Repeat("0", 64), "", "checksum mismatch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "deep-claude-v0.2.0.tar.gz")
+			if err := os.WriteFile(path, tt.artifact, 0644); err != nil {
+				t.Fatal(err)
+			}
+			checksum := tt.checksum
+			if checksum == "" {
+				sum := sha256.Sum256(tt.artifact)
+				checksum = strings.ToUpper(hex.EncodeToString(sum[:]))
+			}
+
+			tmpPath, err := LocalUpdate(path, checksum, "")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LocalUpdate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LocalUpdate() unexpected error: %v", err)
+			}
+			defer os.Remove(tmpPath)
+			if data, _ := os.ReadFile(tmpPath); string(data) != tt.want {
+				t.Errorf("LocalUpdate() extracted %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalUpdateSignature(t *testing.T) {
+	artifact := []byte("new binary")
+	sum := sha256.Sum256(artifact)
+	checksum := hex.EncodeToString(sum[:])
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dclaude-linux-amd64")
+	if err := os.WriteFile(path, artifact, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	key, sig := sign(t, artifact, "file:dclaude-linux-amd64")
+	PublicKey = key
+	defer func() { PublicKey = "" }()
+
+	if _, err := LocalUpdate(path, checksum, ""); err == nil || !strings.Contains(err.Error(), "--signature") {
+		t.Errorf("LocalUpdate() without a signature error = %v", err)
+	}
+
+	if err := os.WriteFile(path+SignatureSuffix, sig, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmpPath, err := LocalUpdate(path, checksum, "")
+	if err != nil {
+		t.Fatalf("LocalUpdate() unexpected error: %v", err)
+	}
+	os.Remove(tmpPath)
+
+	other, _ := sign(t, artifact, "")
+	PublicKey = other
+	if _, err := LocalUpdate(path, checksum, ""); err == nil {
+		t.Error("LocalUpdate() accepted a signature from another key")
+	}
+}
@@ -0,0 +1,82 @@
+package version
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PublicKey is the minisign public key release binaries are signed with,
+// set at build time with -ldflags "-X .../version.PublicKey=...". Builds
+// without one only verify checksums and never auto-install updates.
+var PublicKey = ""
+
+// SignatureSuffix is appended to an asset's name for its signature.
+const SignatureSuffix = ".minisig"
+
+const trustedCommentPrefix = "trusted comment: "
+
+// VerifySignature checks a minisign signature of a file against a public
+// key, given as the key line or the whole key file. Only legacy
+// (non-prehashed) signatures, made with "minisign -S -l", are supported.
+func VerifySignature(filePath string, signature []byte, publicKey string) error {
+	keyID, key, err := parsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(signature)), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], trustedCommentPrefix) {
+		return fmt.Errorf("invalid signature: expected a minisign signature file")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 74 {
+		return fmt.Errorf("invalid signature: malformed signature line")
+	}
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return fmt.Errorf("invalid signature: malformed trusted comment signature")
+	}
+
+	switch string(sig[:2]) {
+	case "Ed":
+	case "ED":
+		return fmt.Errorf("unsupported signature: prehashed minisign signatures are not supported, sign with minisign -S -l")
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", sig[:2])
+	}
+	if !bytes.Equal(sig[2:10], keyID) {
+		return fmt.Errorf("signature was made with key %X, expected %X", sig[2:10], keyID)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if !ed25519.Verify(key, data, sig[10:]) {
+		return fmt.Errorf("signature verification failed")
+	}
+	comment := strings.TrimSuffix(strings.TrimPrefix(lines[2], trustedCommentPrefix), "\r")
+	if !ed25519.Verify(key, append(append([]byte{}, sig[10:]...), comment...), global) {
+		return fmt.Errorf("signature verification failed: trusted comment was modified")
+	}
+	return nil
+}
+
+// parsePublicKey returns the key ID and Ed25519 key of a minisign key.
+func parsePublicKey(publicKey string) ([]byte, ed25519.PublicKey, error) {
+	line := ""
+	for _, l := range strings.Split(publicKey, "\n") {
+		if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "untrusted comment:") {
+			line = l
+		}
+	}
+	data, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(data) != 42 || string(data[:2]) != "Ed" {
+		return nil, nil, fmt.Errorf("invalid minisign public key")
+	}
+	return data[2:10], ed25519.PublicKey(data[10:]), nil
+}
@@ -0,0 +1,77 @@
+package version
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sign returns a minisign public key and a legacy signature of data.
+func sign(t *testing.T, data []byte, comment string) (string, []byte) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	key := append(append([]byte("Ed"), keyID...), pub...)
+	sig := ed25519.Sign(priv, data)
+	global := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+
+	file := "untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), sig...)) + "\n" +
+		trustedCommentPrefix + comment + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n"
+	return "untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(key), []byte(file)
+}
+
+func TestVerifySignature(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dclaude-linux-amd64")
+	data := []byte("binary contents")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	key, sig := sign(t, data, "timestamp:1760400000\tfile:dclaude-linux-amd64")
+	otherKey, _ := sign(t, data, "")
+
+	tests := []struct {
+		name    string
+		sig     []byte
+		key     string
+		wantErr string
+	}{
+		{"valid", sig, key, ""},
+		{"key line only", sig, strings.Split(key, "\n")[1], ""},
+		{"other key", sig, otherKey, "signature verification failed"},
+		{"modified comment", []byte(strings.Replace(string(sig), "timestamp", "Timestamp", 1)), key, "trusted comment was modified"},
+		{"prehashed", []byte(strings.Replace(string(sig), "\nRW", "\nRU", 1)), key, "prehashed"},
+		{"not a signature", []byte("hello"), key, "invalid signature"},
+		{"bad key", sig, "not a key", "invalid minisign public key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(path, tt.sig, tt.key)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("VerifySignature() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("VerifySignature() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := os.WriteFile(path, []byte("tampered contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySignature(path, sig, key); err == nil {
+		t.Error("VerifySignature() accepted a modified file")
+	}
+}
@@ -2,8 +2,11 @@
 package version
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +15,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/guzus/deep-claude/internal/retry"
 )
 
 const (
@@ -68,62 +73,258 @@ func parseSemver(v string) [3]int {
 	return result
 }
 
-// CheckForUpdates checks if a newer version is available.
-func CheckForUpdates(currentVersion string) (latestVersion string, hasUpdate bool, err error) {
-	// Use GitHub API to get latest release
-	resp, err := http.Get(fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", GitHubOwner, GitHubRepo))
-	if err != nil {
-		return "", false, fmt.Errorf("failed to check for updates: %w", err)
+// Channel is a release channel to update from.
+type Channel string
+
+const (
+	// ChannelStable gets releases tagged vX.Y.Z.
+	ChannelStable Channel = "stable"
+	// ChannelBeta also gets pre-releases tagged vX.Y.Z-beta.N or vX.Y.Z-rc.N.
+	ChannelBeta Channel = "beta"
+	// ChannelNightly also gets builds tagged vX.Y.Z-nightly.YYYYMMDD.
+	ChannelNightly Channel = "nightly"
+)
+
+// ParseChannel validates a channel name.
+func ParseChannel(name string) (Channel, error) {
+	switch channel := Channel(name); channel {
+	case ChannelStable, ChannelBeta, ChannelNightly:
+		return channel, nil
 	}
-	defer resp.Body.Close()
+	return "", fmt.Errorf("invalid update channel %q (must be stable, beta or nightly)", name)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", false, fmt.Errorf("failed to check for updates: status %d", resp.StatusCode)
+// TagChannel returns the channel a release tag belongs to, or "" for
+// other pre-releases.
+func TagChannel(tag string) Channel {
+	pre := prerelease(tag)
+	switch {
+	case pre == "":
+		return ChannelStable
+	case strings.HasPrefix(pre, "beta") || strings.HasPrefix(pre, "rc"):
+		return ChannelBeta
+	case strings.HasPrefix(pre, "nightly"):
+		return ChannelNightly
 	}
+	return ""
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", false, fmt.Errorf("failed to read response: %w", err)
+// includes reports whether the channel gets releases from another one:
+// beta gets stable releases too, and nightly gets everything.
+func (c Channel) includes(other Channel) bool {
+	switch c {
+	case ChannelNightly:
+		return other != ""
+	case ChannelBeta:
+		return other == ChannelStable || other == ChannelBeta
+	}
+	return other == ChannelStable
+}
+
+// AssetName returns the binary asset of a release for a platform. Stable
+// releases ship dclaude-OS-ARCH, pre-releases dclaude-CHANNEL-OS-ARCH.
+func AssetName(tag, osName, arch string) string {
+	if channel := TagChannel(tag); channel != ChannelStable && channel != "" {
+		return fmt.Sprintf("dclaude-%s-%s-%s", channel, osName, arch)
+	}
+	return fmt.Sprintf("dclaude-%s-%s", osName, arch)
+}
+
+// ComparePrerelease compares two versions like Compare, then orders
+// pre-releases of the same version before the release and by their
+// dot-separated identifiers, as in semver.
+func ComparePrerelease(v1, v2 string) int {
+	if c := Compare(v1, v2); c != 0 {
+		return c
+	}
+	p1, p2 := prerelease(v1), prerelease(v2)
+	switch {
+	case p1 == p2:
+		return 0
+	case p1 == "":
+		return 1
+	case p2 == "":
+		return -1
+	}
+
+	ids1, ids2 := strings.Split(p1, "."), strings.Split(p2, ".")
+	for i := 0; i < len(ids1) && i < len(ids2); i++ {
+		n1, err1 := strconv.Atoi(ids1[i])
+		n2, err2 := strconv.Atoi(ids2[i])
+		switch {
+		case err1 == nil && err2 == nil:
+			if n1 != n2 {
+				return cmpInt(n1, n2)
+			}
+		case err1 == nil:
+			return -1
+		case err2 == nil:
+			return 1
+		default:
+			if c := strings.Compare(ids1[i], ids2[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmpInt(len(ids1), len(ids2))
+}
+
+// prerelease returns the part of a version after the first "-".
+func prerelease(v string) string {
+	_, pre, _ := strings.Cut(v, "-")
+	return pre
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	}
+	return 0
+}
+
+// releasesAPI is the GitHub API endpoint for the releases, replaced in tests.
+var releasesAPI = fmt.Sprintf("https://api.github.com/repos/%s/%s/releases", GitHubOwner, GitHubRepo)
+
+// maxReleasePages bounds how far back the releases list is paged.
+const maxReleasePages = 10
+
+// errNotFound is returned by getJSON for a 404.
+var errNotFound = errors.New("not found")
+
+// release is the part of the GitHub releases API response we use.
+type release struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// newestRelease returns the newest tag the channel gets, or "" if none.
+func newestRelease(releases []release, channel Channel) string {
+	newest := ""
+	for _, r := range releases {
+		if r.Draft || !channel.includes(TagChannel(r.TagName)) {
+			continue
+		}
+		if newest == "" || ComparePrerelease(r.TagName, newest) > 0 {
+			newest = r.TagName
+		}
+	}
+	return newest
+}
+
+// CheckForUpdates checks if a newer version is available on the channel.
+func CheckForUpdates(currentVersion string, channel Channel) (latestVersion string, hasUpdate bool, err error) {
+	if channel == ChannelStable {
+		var latest release
+		err := getJSON(releasesAPI+"/latest", &latest, nil)
+		if err != nil && err != errNotFound {
+			return "", false, fmt.Errorf("failed to check for updates: %w", err)
+		}
+		if err == nil && !latest.Draft && TagChannel(latest.TagName) == ChannelStable {
+			latestVersion = latest.TagName
+		}
+	}
+
+	// The latest release endpoint never returns pre-releases, and has
+	// nothing if no release is marked as the latest
+	if latestVersion == "" {
+		releases, err := listReleases()
+		if err != nil {
+			return "", false, fmt.Errorf("failed to check for updates: %w", err)
+		}
+		latestVersion = newestRelease(releases, channel)
+	}
+
+	if latestVersion == "" {
+		return "", false, fmt.Errorf("no %s releases found", channel)
+	}
+	return latestVersion, ComparePrerelease(latestVersion, currentVersion) > 0, nil
+}
+
+// listReleases returns the releases, newest first, following the list's
+// pages up to maxReleasePages.
+func listReleases() ([]release, error) {
+	var releases []release
+	url := releasesAPI + "?per_page=100"
+	for page := 0; page < maxReleasePages && url != ""; page++ {
+		var batch []release
+		var header http.Header
+		if err := getJSON(url, &batch, &header); err != nil {
+			return nil, err
+		}
+		releases = append(releases, batch...)
+		url = nextPage(header.Get("Link"))
 	}
+	return releases, nil
+}
 
-	// Simple JSON parsing for tag_name
-	re := regexp.MustCompile(`"tag_name"\s*:\s*"([^"]+)"`)
-	matches := re.FindSubmatch(body)
-	if matches == nil {
-		return "", false, fmt.Errorf("could not find version in response")
+// nextPage returns the rel="next" URL of a Link header, or "".
+func nextPage(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(part, ";")
+		if !ok {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
 	}
+	return ""
+}
 
-	latestVersion = string(matches[1])
+// getJSON decodes a GitHub API response into v, returning errNotFound for
+// a 404. If header is non-nil, it is set to the response's headers.
+func getJSON(url string, v any, header *http.Header) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
 
-	// Skip pre-release versions
-	if strings.Contains(latestVersion, "-") {
-		return latestVersion, false, nil
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
 	}
+	defer resp.Body.Close()
 
-	hasUpdate = Compare(latestVersion, currentVersion) > 0
-	return latestVersion, hasUpdate, nil
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if header != nil {
+		*header = resp.Header
+	}
+	return nil
 }
 
-// DownloadUpdate downloads the new version binary.
-func DownloadUpdate(version string) (string, error) {
+// DownloadUpdate downloads the new version binary and verifies its
+// checksum and, if the build has a PublicKey, its signature. Canceling ctx
+// stops the download and its retries.
+func DownloadUpdate(ctx context.Context, version string) (string, error) {
 	// Determine architecture
 	arch := getArch()
 	osName := getOS()
 
-	binaryName := fmt.Sprintf("dclaude-%s-%s", osName, arch)
+	binaryName := AssetName(version, osName, arch)
 	url := fmt.Sprintf("%s/download/%s/%s", ReleaseURL, version, binaryName)
 
 	// Download to temp file
-	resp, err := http.Get(url)
+	resp, err := get(ctx, url)
 	if err != nil {
 		return "", fmt.Errorf("failed to download update: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to download update: status %d", resp.StatusCode)
-	}
-
 	tmpFile, err := os.CreateTemp("", "dclaude-update-*")
 	if err != nil {
 		return "", fmt.Errorf("failed to create temp file: %w", err)
@@ -136,9 +337,88 @@ func DownloadUpdate(version string) (string, error) {
 	}
 	tmpFile.Close()
 
+	if err := verifyDownload(ctx, tmpFile.Name(), url); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
 	return tmpFile.Name(), nil
 }
 
+// verifyDownload checks a downloaded asset against the .sha256 and, with a
+// PublicKey, the signature published next to it.
+func verifyDownload(ctx context.Context, path, url string) error {
+	sum, err := fetch(ctx, url+".sha256")
+	if err != nil {
+		return fmt.Errorf("failed to download checksum: %w", err)
+	}
+	fields := strings.Fields(string(sum))
+	if len(fields) == 0 {
+		return fmt.Errorf("failed to download checksum: empty checksum file")
+	}
+	if err := VerifyChecksum(path, fields[0]); err != nil {
+		return err
+	}
+
+	if PublicKey == "" {
+		return nil
+	}
+	sig, err := fetch(ctx, url+SignatureSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to download signature: %w", err)
+	}
+	return VerifySignature(path, sig, PublicKey)
+}
+
+// fetch returns the body of a small release asset.
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	resp, err := get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// DownloadPolicy is how failed downloads of release assets are retried.
+var DownloadPolicy = retry.Defaults().Download
+
+// statusError is a download that got an HTTP error status.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d", e.code)
+}
+
+// get requests a release asset, retrying network and server errors as
+// DownloadPolicy says. The response is always 200 OK.
+func get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp *http.Response
+	err = DownloadPolicy.Do(ctx, func() error {
+		var err error
+		if resp, err = http.DefaultClient.Do(req); err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return &statusError{code: resp.StatusCode}
+		}
+		return nil
+	}, func(err error) bool {
+		var status *statusError
+		if errors.As(err, &status) {
+			return status.code >= 500 || status.code == http.StatusTooManyRequests
+		}
+		return true
+	}, nil)
+	return resp, err
+}
+
 // VerifyChecksum verifies the SHA256 checksum of a file.
 func VerifyChecksum(filePath, expectedChecksum string) error {
 	f, err := os.Open(filePath)
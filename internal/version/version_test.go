@@ -1,7 +1,17 @@
 package version
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/retry"
 )
 
 func TestCompare(t *testing.T) {
@@ -56,3 +66,231 @@ func TestParseSemver(t *testing.T) {
 		})
 	}
 }
+
+func TestComparePrerelease(t *testing.T) {
+	tests := []struct {
+		v1       string
+		v2       string
+		expected int
+	}{
+		{"v1.0.0", "v1.0.0", 0},
+		{"v1.0.1", "v1.0.0", 1},
+		{"v1.0.0", "v1.0.0-beta.1", 1},
+		{"v1.0.0-beta.1", "v1.0.0", -1},
+		{"v1.0.0-beta.2", "v1.0.0-beta.1", 1},
+		{"v1.0.0-beta.10", "v1.0.0-beta.2", 1},
+		{"v1.0.0-rc.1", "v1.0.0-beta.3", 1},
+		{"v1.0.0-beta", "v1.0.0-beta.1", -1},
+		{"v1.0.0-nightly.20261014", "v1.0.0-nightly.20261013", 1},
+		{"v1.1.0-nightly.20261014", "v1.0.0", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.v1+"_vs_"+tt.v2, func(t *testing.T) {
+			result := ComparePrerelease(tt.v1, tt.v2)
+			if result != tt.expected {
+				t.Errorf("ComparePrerelease(%q, %q) = %d, want %d", tt.v1, tt.v2, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseChannel(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{"stable", false},
+		{"beta", false},
+		{"nightly", false},
+		{"", true},
+		{"alpha", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			channel, err := ParseChannel(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseChannel(%q) expected error, got nil", tt.input)
+				}
+				return
+			}
+			if err != nil || string(channel) != tt.input {
+				t.Errorf("ParseChannel(%q) = %q, %v", tt.input, channel, err)
+			}
+		})
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected string
+	}{
+		{"v1.2.0", "dclaude-linux-amd64"},
+		{"v1.2.0-beta.1", "dclaude-beta-linux-amd64"},
+		{"v1.2.0-rc.1", "dclaude-beta-linux-amd64"},
+		{"v1.2.0-nightly.20261014", "dclaude-nightly-linux-amd64"},
+		{"v1.2.0-dev", "dclaude-linux-amd64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if result := AssetName(tt.tag, "linux", "amd64"); result != tt.expected {
+				t.Errorf("AssetName(%q) = %q, want %q", tt.tag, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewestRelease(t *testing.T) {
+	releases := []release{
+		{TagName: "v1.1.0"},
+		{TagName: "v1.2.0-beta.1"},
+		{TagName: "v1.2.0-beta.2", Draft: true},
+		{TagName: "v1.2.0-nightly.20261014"},
+		{TagName: "v1.3.0-dev"},
+	}
+
+	tests := []struct {
+		channel  Channel
+		expected string
+	}{
+		{ChannelStable, "v1.1.0"},
+		{ChannelBeta, "v1.2.0-beta.1"},
+		{ChannelNightly, "v1.2.0-nightly.20261014"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.channel), func(t *testing.T) {
+			if result := newestRelease(releases, tt.channel); result != tt.expected {
+				t.Errorf("newestRelease(%s) = %q, want %q", tt.channel, result, tt.expected)
+			}
+		})
+	}
+
+	if result := newestRelease(append(releases, release{TagName: "v1.2.0"}), ChannelBeta); result != "v1.2.0" {
+		t.Errorf("newestRelease(beta) = %q, want the newer stable release v1.2.0", result)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	tests := []struct {
+		link     string
+		expected string
+	}{
+		{"", ""},
+		{`<https://api.github.com/releases?page=2>; rel="next", <https://api.github.com/releases?page=5>; rel="last"`, "https://api.github.com/releases?page=2"},
+		{`<https://api.github.com/releases?page=1>; rel="prev", <https://api.github.com/releases?page=1>; rel="first"`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if result := nextPage(tt.link); result != tt.expected {
+				t.Errorf("nextPage(%q) = %q, want %q", tt.link, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckForUpdates(t *testing.T) {
+	tests := []struct {
+		name    string
+		latest  string // "" for a 404
+		pages   []string
+		channel Channel
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "latest release",
+			latest:  `{"tag_name": "v1.2.0", "body": "Fixes \"tag_name\": \"v9.9.9\" parsing", "draft": false}`,
+			channel: ChannelStable,
+			want:    "v1.2.0",
+		},
+		{
+			name:    "no latest release",
+			pages:   []string{`[{"tag_name": "v1.1.0-beta.1", "prerelease": true}]`, `[{"tag_name": "v1.1.0", "draft": true}, {"tag_name": "v1.0.0"}]`},
+			channel: ChannelStable,
+			want:    "v1.0.0",
+		},
+		{
+			name:    "beta across pages",
+			pages:   []string{`[{"tag_name": "v1.0.0"}]`, `[{"tag_name": "v1.1.0-beta.2", "draft": true}, {"tag_name": "v1.1.0-beta.1", "prerelease": true}]`},
+			channel: ChannelBeta,
+			want:    "v1.1.0-beta.1",
+		},
+		{
+			name:    "no releases",
+			pages:   []string{`[]`},
+			channel: ChannelNightly,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/latest") {
+					if tt.latest == "" {
+						http.NotFound(w, r)
+						return
+					}
+					fmt.Fprint(w, tt.latest)
+					return
+				}
+				page := 1
+				fmt.Sscanf(r.URL.Query().Get("page"), "%d", &page)
+				if page < len(tt.pages) {
+					w.Header().Set("Link", fmt.Sprintf(`<%s/releases?per_page=100&page=%d>; rel="next"`, server.URL, page+1))
+				}
+				fmt.Fprint(w, tt.pages[page-1])
+			}))
+			defer server.Close()
+
+			defer func(api string) { releasesAPI = api }(releasesAPI)
+			releasesAPI = server.URL + "/releases"
+
+			latest, hasUpdate, err := CheckForUpdates("v0.9.0", tt.channel)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("CheckForUpdates() = %q, expected error", latest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckForUpdates() unexpected error: %v", err)
+			}
+			if latest != tt.want || !hasUpdate {
+				t.Errorf("CheckForUpdates() = %q, %v, want %q, true", latest, hasUpdate, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchStopsOnCancel(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	defer func(policy retry.Policy) { DownloadPolicy = policy }(DownloadPolicy)
+	DownloadPolicy = retry.Policy{Attempts: 5, Delay: time.Hour, Multiplier: 1}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if _, err := fetch(ctx, server.URL+"/dclaude.sha256"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("fetch() error = %v, want the context's", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("fetch() took %s after its context ended", elapsed)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("made %d requests, want 1 before the retry wait was cut short", n)
+	}
+}
@@ -0,0 +1,61 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// cacheVars maps package-manager cache variables to subdirectories of the
+// shared cache.
+var cacheVars = []struct {
+	name   string
+	subdir string
+}{
+	{"GOMODCACHE", "go/mod"},
+	{"GOCACHE", "go/build"},
+	{"npm_config_cache", "npm"},
+	{"PIP_CACHE_DIR", "pip"},
+}
+
+// CacheEnv creates the shared cache layout under dir and returns the
+// variables that point package managers at it, so parallel workers reuse
+// downloads instead of each fetching their own.
+func CacheEnv(dir string) ([]string, error) {
+	var env []string
+	for _, v := range cacheVars {
+		path := filepath.Join(dir, filepath.FromSlash(v.subdir))
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		env = append(env, v.name+"="+path)
+	}
+	return env, nil
+}
+
+// RemoveCache deletes the shared cache under dir. Go writes its module
+// cache read-only, so the directories are made writable first.
+func RemoveCache(dir string) error {
+	_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			_ = os.Chmod(path, 0755)
+		}
+		return nil
+	})
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to clean cache: %w", err)
+	}
+	return nil
+}
+
+// DirSize returns the total size in bytes of the files under dir.
+func DirSize(dir string) int64 {
+	var size int64
+	_ = filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,80 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCacheEnv(t *testing.T) {
+	dir := t.TempDir()
+
+	env, err := CacheEnv(dir)
+	if err != nil {
+		t.Fatalf("CacheEnv() unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"GOMODCACHE":       filepath.Join(dir, "go", "mod"),
+		"npm_config_cache": filepath.Join(dir, "npm"),
+		"PIP_CACHE_DIR":    filepath.Join(dir, "pip"),
+	}
+	got := make(map[string]string)
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		got[name] = value
+	}
+	for name, path := range want {
+		if got[name] != path {
+			t.Errorf("%s = %q, want %q", name, got[name], path)
+		}
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			t.Errorf("CacheEnv() did not create %s", path)
+		}
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "npm"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a"), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "npm", "b"), make([]byte, 50), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := DirSize(dir); got != 150 {
+		t.Errorf("DirSize() = %d, want 150", got)
+	}
+	if got := DirSize(filepath.Join(dir, "missing")); got != 0 {
+		t.Errorf("DirSize() of missing dir = %d, want 0", got)
+	}
+}
+
+func TestRemoveCacheReadOnly(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	mod := filepath.Join(dir, "go", "mod", "example.com", "lib@v1.0.0")
+	if err := os.MkdirAll(mod, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mod, "lib.go"), []byte("package lib\n"), 0444); err != nil {
+		t.Fatal(err)
+	}
+	// As go mod download leaves them
+	for _, d := range []string{mod, filepath.Dir(mod)} {
+		if err := os.Chmod(d, 0555); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := RemoveCache(dir); err != nil {
+		t.Fatalf("RemoveCache() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("RemoveCache() left %s: %v", dir, err)
+	}
+}
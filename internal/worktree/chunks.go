@@ -0,0 +1,53 @@
+package worktree
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Chunks are the chunks of the work, such as directories or checklist
+// items, that one worker holds, so parallel workers on a repository
+// don't take on the same ones. A chunk is held through a lock file under
+// the repository's chunks directory, like a resource slot.
+type Chunks struct {
+	dir  string
+	held map[string]string
+}
+
+// OpenChunks claims chunks under dir for this worker.
+func OpenChunks(dir string) (*Chunks, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create chunks directory: %w", err)
+	}
+	return &Chunks{dir: dir, held: make(map[string]string)}, nil
+}
+
+// Claim takes a chunk unless another worker holds it. Chunks held by
+// processes that no longer exist are reclaimed.
+func (c *Chunks) Claim(chunk string) bool {
+	if _, ok := c.held[chunk]; ok {
+		return true
+	}
+	sum := sha256.Sum256([]byte(chunk))
+	lockPath := filepath.Join(c.dir, hex.EncodeToString(sum[:8])+".lock")
+	if !acquire(lockPath) {
+		return false
+	}
+	c.held[chunk] = lockPath
+	return true
+}
+
+// Release lets other workers claim the chunks this one holds. It is safe
+// to call on a nil Chunks.
+func (c *Chunks) Release() {
+	if c == nil {
+		return
+	}
+	for chunk, lockPath := range c.held {
+		_ = os.Remove(lockPath)
+		delete(c.held, chunk)
+	}
+}
@@ -0,0 +1,31 @@
+package worktree
+
+import "testing"
+
+func TestChunks(t *testing.T) {
+	dir := t.TempDir()
+	first, err := OpenChunks(dir)
+	if err != nil {
+		t.Fatalf("OpenChunks() unexpected error: %v", err)
+	}
+	second, err := OpenChunks(dir)
+	if err != nil {
+		t.Fatalf("OpenChunks() unexpected error: %v", err)
+	}
+
+	if !first.Claim("pkg/db") || !first.Claim("pkg/db") {
+		t.Error("Claim() of a free chunk, or one already held, = false")
+	}
+	if second.Claim("pkg/db") {
+		t.Error("Claim() of a chunk another worker holds = true")
+	}
+	if !second.Claim("pkg/api") {
+		t.Error("Claim() of a free chunk = false")
+	}
+
+	first.Release()
+	if !second.Claim("pkg/db") {
+		t.Error("Claim() after the holder released it = false")
+	}
+	second.Release()
+}
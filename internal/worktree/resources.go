@@ -0,0 +1,145 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/process"
+)
+
+// maxSlots bounds how many workers can hold resources at once.
+const maxSlots = 100
+
+// Resources are the ports and temp directory reserved for one worker, so
+// parallel runs that start servers or tests don't collide.
+type Resources struct {
+	Slot      int
+	PortStart int
+	PortEnd   int
+	TempDir   string
+
+	lockPath string
+}
+
+// Allocate reserves the lowest free slot under slotsDir. Slot n owns ports
+// portBase+n*portsPerSlot up to the next slot's start. Slots held by
+// processes that no longer exist are reclaimed.
+func Allocate(slotsDir string, portBase, portsPerSlot int) (*Resources, error) {
+	if err := os.MkdirAll(slotsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create slots directory: %w", err)
+	}
+
+	for slot := 0; slot < maxSlots; slot++ {
+		lockPath := filepath.Join(slotsDir, fmt.Sprintf("%d.lock", slot))
+		if !acquire(lockPath) {
+			continue
+		}
+
+		tempDir := filepath.Join(slotsDir, fmt.Sprintf("%d.tmp", slot))
+		_ = os.RemoveAll(tempDir)
+		if err := os.MkdirAll(tempDir, 0755); err != nil {
+			os.Remove(lockPath)
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+
+		start := portBase + slot*portsPerSlot
+		return &Resources{
+			Slot:      slot,
+			PortStart: start,
+			PortEnd:   start + portsPerSlot - 1,
+			TempDir:   tempDir,
+			lockPath:  lockPath,
+		}, nil
+	}
+	return nil, fmt.Errorf("all %d resource slots are in use", maxSlots)
+}
+
+// acquire creates the lock file for a slot, taking it over if its owner
+// has exited.
+func acquire(lockPath string) bool {
+	pid := []byte(strconv.Itoa(os.Getpid()))
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = f.Write(pid)
+			f.Close()
+			return err == nil
+		}
+		if !os.IsExist(err) || !reclaim(lockPath) {
+			return false
+		}
+	}
+	return false
+}
+
+// abandonedReclaim is how old a reclaim guard must be to have been left by
+// a worker that exited while reclaiming; reclaiming takes microseconds.
+const abandonedReclaim = time.Minute
+
+// reclaim removes a lock whose holder has exited, and reports whether the
+// lock may be created again. Workers that find the holder gone at the same
+// time would otherwise remove the lock one of them just created, so only
+// the one holding the lock's reclaim guard checks and removes it: no other
+// can remove the stale lock meanwhile, so none can have replaced it.
+func reclaim(lockPath string) bool {
+	guard := lockPath + ".reclaim"
+	f, err := os.OpenFile(guard, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if info, statErr := os.Stat(guard); statErr == nil && time.Since(info.ModTime()) > abandonedReclaim {
+			os.Remove(guard)
+		}
+		return false
+	}
+	f.Close()
+	defer os.Remove(guard)
+
+	content, err := os.ReadFile(lockPath)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	if holderAlive(content) {
+		return false
+	}
+	return os.Remove(lockPath) == nil
+}
+
+// holderAlive reports whether the process recorded in a lock file's
+// content is running.
+func holderAlive(content []byte) bool {
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || pid <= 0 {
+		return false
+	}
+	return process.Alive(pid)
+}
+
+// Env returns the variables that expose the resources to child processes.
+func (r *Resources) Env() []string {
+	return []string{
+		fmt.Sprintf("DEEP_CLAUDE_SLOT=%d", r.Slot),
+		fmt.Sprintf("DEEP_CLAUDE_PORT_START=%d", r.PortStart),
+		fmt.Sprintf("DEEP_CLAUDE_PORT_END=%d", r.PortEnd),
+		"TMPDIR=" + r.TempDir,
+	}
+}
+
+// Describe explains the resources for the prompt.
+func (r *Resources) Describe() string {
+	return fmt.Sprintf("Other workers run tests on this machine at the same time. When starting servers or tests, use only ports %d-%d "+
+		"and keep temporary files in %s. These are also set as DEEP_CLAUDE_PORT_START, DEEP_CLAUDE_PORT_END and TMPDIR.",
+		r.PortStart, r.PortEnd, r.TempDir)
+}
+
+// Release frees the slot and removes its temp directory. It is safe to call
+// on a nil Resources.
+func (r *Resources) Release() {
+	if r == nil {
+		return
+	}
+	_ = os.RemoveAll(r.TempDir)
+	_ = os.Remove(r.lockPath)
+}
@@ -0,0 +1,97 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAllocate(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := Allocate(dir, 20000, 10)
+	if err != nil {
+		t.Fatalf("Allocate() unexpected error: %v", err)
+	}
+	defer first.Release()
+
+	second, err := Allocate(dir, 20000, 10)
+	if err != nil {
+		t.Fatalf("Allocate() unexpected error: %v", err)
+	}
+
+	if first.Slot != 0 || first.PortStart != 20000 || first.PortEnd != 20009 {
+		t.Errorf("first allocation = %+v, want slot 0 ports 20000-20009", first)
+	}
+	if second.Slot != 1 || second.PortStart != 20010 || second.PortEnd != 20019 {
+		t.Errorf("second allocation = %+v, want slot 1 ports 20010-20019", second)
+	}
+	if first.TempDir == second.TempDir {
+		t.Error("Allocate() gave two workers the same temp directory")
+	}
+
+	second.Release()
+	if _, err := os.Stat(second.TempDir); !os.IsNotExist(err) {
+		t.Error("Release() should remove the temp directory")
+	}
+
+	third, err := Allocate(dir, 20000, 10)
+	if err != nil {
+		t.Fatalf("Allocate() unexpected error: %v", err)
+	}
+	defer third.Release()
+	if third.Slot != 1 {
+		t.Errorf("Allocate() after Release() = slot %d, want 1", third.Slot)
+	}
+}
+
+func TestAllocateReclaimsStaleSlot(t *testing.T) {
+	dir := t.TempDir()
+	// A pid that cannot belong to a running process
+	if err := os.WriteFile(filepath.Join(dir, "0.lock"), []byte("999999999"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Allocate(dir, 20000, 10)
+	if err != nil {
+		t.Fatalf("Allocate() unexpected error: %v", err)
+	}
+	defer r.Release()
+	if r.Slot != 0 {
+		t.Errorf("Allocate() = slot %d, want stale slot 0 reclaimed", r.Slot)
+	}
+}
+
+func TestAcquireReclaimsStaleLockOnce(t *testing.T) {
+	for round := 0; round < 100; round++ {
+		lockPath := filepath.Join(t.TempDir(), "0.lock")
+		if err := os.WriteFile(lockPath, []byte("999999999"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// Workers that all find the holder gone reclaim the lock together
+		var wg sync.WaitGroup
+		var won atomic.Int32
+		start := make(chan struct{})
+		for i := 0; i < 32; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				if acquire(lockPath) {
+					won.Add(1)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+		if n := won.Load(); n != 1 {
+			t.Fatalf("%d workers acquired the reclaimed lock, want 1", n)
+		}
+		if matches, _ := filepath.Glob(lockPath + ".*"); len(matches) > 0 {
+			t.Fatalf("reclaiming left %v behind", matches)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package worktree prepares git worktrees for parallel runs.
+package worktree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Path returns where the named worktree lives. A relative baseDir is
+// resolved against the main working tree.
+func Path(workDir, baseDir, name string) string {
+	if !filepath.IsAbs(baseDir) {
+		baseDir = filepath.Join(workDir, baseDir)
+	}
+	return filepath.Join(baseDir, name)
+}
+
+// CopyFiles copies files matching the glob patterns (relative to src) into
+// the same relative paths under dst. It is meant for untracked environment
+// files such as .env that a fresh worktree lacks, so files that already
+// exist in dst are left alone. It returns the relative paths it copied.
+func CopyFiles(src, dst string, patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var copied []string
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(src, pattern))
+		if err != nil {
+			return copied, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+
+		for _, match := range matches {
+			rel, err := filepath.Rel(src, match)
+			if err != nil || seen[rel] {
+				continue
+			}
+			seen[rel] = true
+
+			info, err := os.Stat(match)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			target := filepath.Join(dst, rel)
+			if _, err := os.Stat(target); err == nil {
+				continue
+			}
+
+			if err := copyFile(match, target, info.Mode().Perm()); err != nil {
+				return copied, fmt.Errorf("failed to copy %s: %w", rel, err)
+			}
+			copied = append(copied, rel)
+		}
+	}
+
+	sort.Strings(copied)
+	return copied, nil
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
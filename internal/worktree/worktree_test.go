@@ -0,0 +1,60 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	if got := Path("/repo", "../wt", "docs"); got != "/wt/docs" {
+		t.Errorf("Path() relative = %q, want /wt/docs", got)
+	}
+	if got := Path("/repo", "/tmp/wt", "docs"); got != "/tmp/wt/docs" {
+		t.Errorf("Path() absolute = %q, want /tmp/wt/docs", got)
+	}
+}
+
+func TestCopyFiles(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		".env":               "SECRET=1",
+		".env.local":         "LOCAL=1",
+		"config/local.yaml":  "debug: true",
+		"config/shared.yaml": "shared",
+	}
+	for name, body := range files {
+		path := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Files the worktree already has are not overwritten
+	if err := os.WriteFile(filepath.Join(dst, ".env.local"), []byte("KEEP"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := CopyFiles(src, dst, []string{".env*", "config/local.yaml", "missing.txt", ".env"})
+	if err != nil {
+		t.Fatalf("CopyFiles() unexpected error: %v", err)
+	}
+
+	want := []string{".env", filepath.Join("config", "local.yaml")}
+	if !reflect.DeepEqual(copied, want) {
+		t.Errorf("CopyFiles() = %v, want %v", copied, want)
+	}
+
+	if got, _ := os.ReadFile(filepath.Join(dst, ".env")); string(got) != "SECRET=1" {
+		t.Errorf(".env = %q, want copied content", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, ".env.local")); string(got) != "KEEP" {
+		t.Errorf(".env.local = %q, want existing content kept", got)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "config", "shared.yaml")); !os.IsNotExist(err) {
+		t.Error("CopyFiles() copied a file not in the list")
+	}
+}
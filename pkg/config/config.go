@@ -0,0 +1,748 @@
+// Package config provides configuration management for Continuous Claude.
+// Start from DefaultConfig and Validate the result before passing it to
+// the orchestrator.
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/retry"
+)
+
+// Config holds all configuration for a Continuous Claude run.
+type Config struct {
+	// Core settings
+	Prompt              string
+	MaxRuns             int
+	MaxCost             float64
+	MaxDuration         time.Duration
+	IterationTimeout    time.Duration
+	Owner               string
+	Repo                string
+	MergeStrategy       string
+	GitBranchPrefix     string
+	NotesFile           string
+	DisableCommits      bool
+	StageAll            bool
+	StageExcludes       []string
+	DryRun              bool
+	CompletionThreshold int
+	DirtyTree           string
+	CompletionMode      string
+	MinConfidence       float64
+	StopOnNoProgress    int
+	RepoMapIterations   int
+	PromptTokenBudget   int
+
+	// RecordResults is how an iteration that changes no code records its
+	// results so it counts as progress: none, commit or comment
+	RecordResults string
+
+	// BranchMode is iteration, for a branch and PR per iteration, or
+	// single, for one branch and PR every iteration adds commits to
+	BranchMode string
+	// Squash the commits an iteration makes into one before pushing
+	SquashCommits bool
+	// TaskType is code, for changes shipped through PRs, or analysis, for
+	// an investigation that commits nothing and delivers a report
+	TaskType string
+	// Where to publish an analysis run's report, besides its run directory
+	PublishReport string
+	// MigrationCmd lists the files or modules still to migrate, one per
+	// line, and each iteration is assigned the next MigrationBatch of them
+	MigrationCmd   string
+	MigrationBatch int
+
+	// Any of CompletionSignals, or text matching CompletionPattern, signals
+	// that the project is done; with CompletionInSummary it only counts on
+	// the STATUS line that ends Claude's response
+	CompletionSignals   []string
+	CompletionPattern   string
+	CompletionInSummary bool
+
+	// Run the full loop but push nothing, recording each iteration's
+	// commit as a patch file in PatchDir (the run directory by default)
+	ReadOnly bool
+	PatchDir string
+
+	// Scenario file scripting an in-memory forge and Claude to run against
+	// instead of the real ones
+	Simulate string
+
+	// Cassette files to record the run's git, gh and claude calls to, or
+	// to replay them from instead of making them
+	Record string
+	Replay string
+
+	// Delete branches of closed and merged PRs left by earlier runs
+	GCBranches bool
+
+	// Monorepo projects the work is confined to, relative to the repository root
+	Paths []string
+
+	// Task-specific stop conditions
+	VerifyCmd         string
+	VerifyStreak      int
+	CoverageCmd       string
+	MinCoverage       float64
+	StopOnIssueClosed string
+
+	// Gates that hold back a PR that doesn't raise coverage by
+	// MinCoverageDelta points, or whose MutationCmd reports a score below
+	// MinMutationScore
+	MinCoverageDelta float64
+	MutationCmd      string
+	MinMutationScore float64
+
+	// Have --verify-cmd run only the tests affected by the changes, found by
+	// Go package analysis or a mapping file, and the full suite periodically
+	AffectedTests bool
+	TestMap       string
+	FullTests     string
+	FullTestEvery int
+
+	// ID of the run, shared by its branches, PRs, session, event log and
+	// summary, and of an earlier run to continue, sharing its limits and spend
+	RunID  string
+	Resume string
+
+	// Most PRs opened per hour and per day; iterations wait for a free slot
+	MaxPRsPerHour int
+	MaxPRsPerDay  int
+
+	// Start the next iteration while the last PRs' checks run
+	Pipeline      bool
+	PipelineDepth int
+
+	// Queue pushes while offline, and how long to wait for connectivity at the end
+	DeferPush     bool
+	DeferPushWait time.Duration
+
+	// Merge only PRs Claude is at least MergeConfidence sure of, opening
+	// the rest as drafts down to DraftConfidence and keeping work below it
+	// local; a MergeConfidence of 0 merges everything as usual
+	MergeConfidence float64
+	DraftConfidence float64
+
+	// After merging, watch CI on the base branch and undo a merge that
+	// breaks it: "pr" opens a revert PR, "push" pushes the revert directly
+	RevertOnMainFailure string
+
+	// After merging, wait for the merge commit to deploy to DeployEnvironment
+	// and for DeployCheck to pass on it before the iteration counts as done
+	DeployEnvironment string
+	DeployCheck       string
+	DeployTimeout     time.Duration
+
+	// Publish a check run on each PR with the verdicts of the gates its
+	// changes went through
+	CheckRun bool
+	// Set deep-claude/* commit statuses on each PR's head as its gates,
+	// checks and merge progress
+	CommitStatuses bool
+
+	// Act on "/deep-claude fix|explain|close" comments left on the run's
+	// open PRs by people with write access
+	CommentCommands bool
+	// How a fix asked for in a comment is committed: stack (a new commit),
+	// amend-ci (amend the PR's last commit if its checks are failing) or
+	// amend (always amend)
+	FixCommits string
+
+	// How long a PR whose checks passed may wait for a required review
+	// before review is re-requested; after a second wait OnReviewTimeout
+	// decides whether the run moves on ("continue") or stops ("stop").
+	// 0 leaves such PRs open right away
+	ReviewWait      time.Duration
+	OnReviewTimeout string
+
+	// Leave PRs whose checks passed open until someone approves the merge
+	// in dclaude serve, instead of merging them
+	MergeApproval bool
+
+	// How long to wait for a human's instructions when Claude reports it
+	// is blocked before stopping the run; 0 stops right away
+	BlockedWait time.Duration
+
+	// Tags recorded with the run's cost in the cost ledger, e.g. team=payments
+	CostTags map[string]string
+
+	// Labels of the run's work, added to its branch names, PRs, notices,
+	// cost ledger tags and history, e.g. refactor-auth
+	Labels []string
+
+	// Prices for estimating cost from tokens when a run reports none
+	PricingModel string
+	Pricing      string
+
+	// Where to publish the run summary when the run ends
+	PublishSummary string
+	SummaryIssue   string
+
+	// Worktree settings
+	Worktree        string
+	WorktreeBaseDir string
+	CleanupWorktree bool
+	WorktreeCopy    []string
+
+	// Reserve ports and a temp dir per worker for parallel test runs
+	IsolateResources bool
+	PortBase         int
+	PortsPerWorker   int
+	SharedCache      bool
+	// Partition is how parallel workers split the work to claim disjoint
+	// chunks of it: dir, package or checklist
+	Partition string
+
+	// Dependency upgrade mode: largest semver bump allowed, upgrades per PR
+	// and dependencies to leave alone
+	UpgradeDeps   bool
+	UpgradePolicy string
+	UpgradeBatch  int
+	UpgradeIgnore []string
+
+	// Lint-fix mode: linters to run and findings fixed per PR
+	LintFix   bool
+	LintCmds  []string
+	LintBatch int
+
+	// Backport mode: merged PR to cherry-pick and the branches to backport it to
+	Backport   string
+	BackportTo []string
+
+	// Command that installs dependencies before the first iteration
+	SetupCmd string
+
+	// Commit message settings
+	CommitConvention string
+	CommitPattern    string
+	CommitRetries    int
+
+	// Changelog and release settings
+	UpdateChangelog   bool
+	ChangelogFile     string
+	ReleaseEvery      int
+	ReleaseOnComplete bool
+
+	// Update settings
+	AutoUpdate     bool
+	DisableUpdates bool
+	UpdateInterval time.Duration
+
+	// How pushes, gh calls, update downloads and Claude runs are retried
+	Retry retry.Policies
+
+	// Detach mode
+	Detach bool
+	// Have a detached run's session resume the run each time its process
+	// dies without exiting, up to MaxRestarts times
+	RestartOnCrash bool
+	MaxRestarts    int
+
+	// Print debug output
+	Verbose bool
+
+	// How the run prints what it does: "terminal", "json" (a JSON object
+	// per line) or "silent", and a file to also log it to as plain text.
+	// CI prints progress lines instead of spinners on the terminal, and
+	// ASCII plain-text symbols instead of emoji and unicode ones
+	Output  string
+	LogFile string
+	CI      bool
+	ASCII   bool
+
+	// How many characters of each iteration's output from Claude to print
+	// (0 = all), whether to print it all anyway, and whether to also open
+	// it in full in $PAGER
+	OutputLimit    int
+	ShowFullOutput bool
+	PageOutput     bool
+
+	// Environment variables whose values are redacted, on top of the
+	// credentials and variables named like secrets
+	RedactEnv []string
+
+	// Name of the tmux session the run is attached to, if any
+	SessionName string
+
+	// Extra args to pass to Claude
+	ExtraClaudeArgs []string
+}
+
+// DefaultCompletionSignal is the phrase Claude is told to use when the
+// project is done.
+const DefaultCompletionSignal = "DEEP_CLAUDE_PROJECT_COMPLETE"
+
+// DefaultConfig returns a Config with default values.
+func DefaultConfig() *Config {
+	return &Config{
+		MaxRuns:             0,
+		MaxCost:             0,
+		MaxDuration:         0,
+		MergeStrategy:       "squash",
+		GitBranchPrefix:     "deep-claude/",
+		BranchMode:          "iteration",
+		FixCommits:          "stack",
+		RecordResults:       "none",
+		TaskType:            "code",
+		MigrationBatch:      5,
+		NotesFile:           "SHARED_TASK_NOTES.md",
+		CompletionSignals:   []string{DefaultCompletionSignal},
+		CompletionThreshold: 3,
+		DirtyTree:           "stash",
+		CompletionMode:      "signal",
+		MinConfidence:       0.8,
+		RepoMapIterations:   3,
+		PromptTokenBudget:   30000,
+		VerifyStreak:        1,
+		FullTests:           "./...",
+		FullTestEvery:       5,
+		PipelineDepth:       2,
+		DeferPushWait:       30 * time.Minute,
+		BlockedWait:         24 * time.Hour,
+		DeployTimeout:       30 * time.Minute,
+		OnReviewTimeout:     "continue",
+		DraftConfidence:     0.5,
+		PortBase:            20000,
+		PortsPerWorker:      10,
+		UpgradePolicy:       "minor",
+		UpgradeBatch:        1,
+		LintBatch:           20,
+		WorktreeBaseDir:     "../deep-claude-worktrees",
+		CommitConvention:    "none",
+		CommitRetries:       2,
+		MaxRestarts:         3,
+		Output:              "terminal",
+		OutputLimit:         500,
+		ChangelogFile:       "CHANGELOG.md",
+		UpdateInterval:      24 * time.Hour,
+		Retry:               retry.Defaults(),
+	}
+}
+
+// labelPattern is what a label may look like, so that it fits in branch
+// names and the comma-separated labels cost tag.
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateLabel checks that a label fits in branch names and tags.
+func ValidateLabel(label string) error {
+	if !labelPattern.MatchString(label) {
+		return fmt.Errorf("invalid label %q (use letters, digits, '.', '_' and '-', e.g. refactor-auth)", label)
+	}
+	return nil
+}
+
+// Choices lists the values of the flags that take one of a few, as
+// Validate checks them.
+var Choices = map[string][]string{
+	"merge-strategy":         {"squash", "merge", "rebase"},
+	"dirty-tree":             {"stash", "refuse", "ignore"},
+	"completion-mode":        {"signal", "evaluate"},
+	"revert-on-main-failure": {"pr", "push"},
+	"on-review-timeout":      {"continue", "stop"},
+	"upgrade-policy":         {"patch", "minor", "major"},
+	"publish-summary":        {"gist", "comment"},
+	"commit-convention":      {"none", "conventional"},
+	"output":                 {"terminal", "json", "silent"},
+	"branch-mode":            {"iteration", "single"},
+	"fix-commits":            {"stack", "amend-ci", "amend"},
+	"record-results":         {"none", "commit", "comment"},
+	"task-type":              {"code", "analysis"},
+	"publish-report":         {"issue", "gist"},
+	"partition":              {"dir", "package", "checklist"},
+}
+
+// Validate checks if the configuration is valid.
+func (c *Config) Validate() error {
+	// Queue modes build their prompts from the work they find, and end
+	// when it is done
+	if c.Prompt == "" && !c.QueueMode() {
+		return fmt.Errorf("prompt is required (use -p, --prompt or --recipe)")
+	}
+
+	// At least one limit must be set
+	if c.MaxRuns == 0 && c.MaxCost == 0 && c.MaxDuration == 0 && !c.QueueMode() {
+		return fmt.Errorf("at least one limit must be set: --max-runs, --max-cost, or --max-duration")
+	}
+
+	if c.MaxRuns < 0 {
+		return fmt.Errorf("--max-runs must be non-negative")
+	}
+
+	if c.MaxCost < 0 {
+		return fmt.Errorf("--max-cost must be non-negative")
+	}
+
+	if c.MaxDuration < 0 {
+		return fmt.Errorf("--max-duration must be non-negative")
+	}
+
+	if c.IterationTimeout < 0 {
+		return fmt.Errorf("--max-iteration-duration must be non-negative")
+	}
+
+	if c.UpdateInterval < 0 {
+		return fmt.Errorf("--update-interval must be non-negative")
+	}
+
+	if c.PromptTokenBudget < 0 {
+		return fmt.Errorf("--prompt-token-budget must be non-negative")
+	}
+
+	if c.RepoMapIterations < 0 {
+		return fmt.Errorf("--repo-map-iterations must be non-negative")
+	}
+
+	if c.StopOnNoProgress < 0 {
+		return fmt.Errorf("--stop-on-no-progress must be non-negative")
+	}
+
+	for _, label := range c.Labels {
+		if err := ValidateLabel(label); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range c.Paths {
+		if filepath.IsAbs(p) || p == ".." || strings.HasPrefix(filepath.ToSlash(filepath.Clean(p)), "../") {
+			return fmt.Errorf("--path must be relative to the repository root and inside it: %s", p)
+		}
+	}
+
+	if c.MergeConfidence < 0 || c.MergeConfidence > 1 || c.DraftConfidence < 0 || c.DraftConfidence > 1 {
+		return fmt.Errorf("--merge-confidence and --draft-confidence must be between 0 and 1")
+	}
+	if c.MergeConfidence > 0 && c.DraftConfidence > c.MergeConfidence {
+		return fmt.Errorf("--draft-confidence must not be above --merge-confidence")
+	}
+
+	if c.RevertOnMainFailure != "" && c.RevertOnMainFailure != "pr" && c.RevertOnMainFailure != "push" {
+		return fmt.Errorf("--revert-on-main-failure must be one of: pr, push")
+	}
+
+	if (c.DeployEnvironment != "" || c.DeployCheck != "") && c.DeployTimeout <= 0 {
+		return fmt.Errorf("--deploy-timeout must be positive")
+	}
+
+	if c.ReviewWait < 0 {
+		return fmt.Errorf("--review-wait must be non-negative")
+	}
+
+	if c.OnReviewTimeout != "" && c.OnReviewTimeout != "continue" && c.OnReviewTimeout != "stop" {
+		return fmt.Errorf("--on-review-timeout must be one of: continue, stop")
+	}
+
+	if c.BlockedWait < 0 {
+		return fmt.Errorf("--blocked-wait must be non-negative")
+	}
+
+	if c.CompletionThreshold < 1 {
+		return fmt.Errorf("--completion-threshold must be at least 1")
+	}
+
+	validDirtyTree := map[string]bool{"": true, "stash": true, "refuse": true, "ignore": true}
+	if !validDirtyTree[c.DirtyTree] {
+		return fmt.Errorf("--dirty-tree must be one of: stash, refuse, ignore")
+	}
+
+	if c.CompletionMode != "" && c.CompletionMode != "signal" && c.CompletionMode != "evaluate" {
+		return fmt.Errorf("--completion-mode must be one of: signal, evaluate")
+	}
+
+	if _, err := regexp.Compile(c.CompletionPattern); err != nil {
+		return fmt.Errorf("invalid --completion-regex: %w", err)
+	}
+
+	if c.MinConfidence < 0 || c.MinConfidence > 1 {
+		return fmt.Errorf("--completion-confidence must be between 0 and 1")
+	}
+
+	if c.VerifyStreak < 0 {
+		return fmt.Errorf("--verify-streak must be non-negative")
+	}
+
+	if c.AffectedTests && !strings.Contains(c.VerifyCmd, "{tests}") {
+		return fmt.Errorf("--affected-tests requires a --verify-cmd with a {tests} placeholder, e.g. \"go test {tests}\"")
+	}
+
+	if c.MaxPRsPerHour < 0 || c.MaxPRsPerDay < 0 {
+		return fmt.Errorf("--max-prs-per-hour and --max-prs-per-day must be non-negative")
+	}
+
+	if c.Pipeline && c.PipelineDepth < 1 {
+		return fmt.Errorf("--pipeline-depth must be at least 1")
+	}
+
+	if c.FullTestEvery < 0 {
+		return fmt.Errorf("--full-test-every must be non-negative")
+	}
+
+	if c.CoverageCmd != "" && (c.MinCoverage < 0 || c.MinCoverage > 100 || c.MinCoverage == 0 && c.MinCoverageDelta == 0) {
+		return fmt.Errorf("--min-coverage must be between 0 and 100 when --coverage-cmd is set without --min-coverage-delta")
+	}
+
+	if c.MinCoverageDelta < 0 || c.MinCoverageDelta > 0 && c.CoverageCmd == "" {
+		return fmt.Errorf("--min-coverage-delta must be positive and requires --coverage-cmd")
+	}
+
+	if c.MutationCmd != "" && (c.MinMutationScore <= 0 || c.MinMutationScore > 100) {
+		return fmt.Errorf("--min-mutation-score must be between 0 and 100 when --mutation-cmd is set")
+	}
+
+	if c.IsolateResources && (c.PortsPerWorker <= 0 || c.PortBase < 1024 || c.PortBase+100*c.PortsPerWorker > 65536) {
+		return fmt.Errorf("--port-base and --ports-per-worker must keep every worker's ports between 1024 and 65535")
+	}
+
+	if c.UpgradePolicy != "" && c.UpgradePolicy != "patch" && c.UpgradePolicy != "minor" && c.UpgradePolicy != "major" {
+		return fmt.Errorf("--upgrade-policy must be one of: patch, minor, major")
+	}
+
+	if c.UpgradeBatch < 0 {
+		return fmt.Errorf("--upgrade-batch must be non-negative")
+	}
+
+	modes := 0
+	for _, on := range []bool{c.UpgradeDeps, c.LintFix, c.Backport != ""} {
+		if on {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return fmt.Errorf("only one of --upgrade-deps, --lint-fix and --backport can be used")
+	}
+
+	if c.Backport != "" && len(c.BackportTo) == 0 {
+		return fmt.Errorf("--backport requires at least one --backport-to branch")
+	}
+
+	if c.LintBatch < 0 {
+		return fmt.Errorf("--lint-batch must be non-negative")
+	}
+
+	if c.PublishSummary != "" && c.PublishSummary != "gist" && c.PublishSummary != "comment" {
+		return fmt.Errorf("--publish-summary must be one of: gist, comment")
+	}
+
+	if c.PublishSummary == "comment" && c.SummaryIssue == "" {
+		return fmt.Errorf("--publish-summary comment requires --summary-issue")
+	}
+
+	if c.Output != "" && c.Output != "terminal" && c.Output != "json" && c.Output != "silent" {
+		return fmt.Errorf("--output must be one of: terminal, json, silent")
+	}
+
+	if c.OutputLimit < 0 {
+		return fmt.Errorf("--output-limit must be non-negative")
+	}
+
+	if c.PageOutput && c.Output != "" && c.Output != "terminal" {
+		return fmt.Errorf("--page-output requires --output terminal")
+	}
+
+	if c.CommitConvention != "" && c.CommitConvention != "none" && c.CommitConvention != "conventional" {
+		return fmt.Errorf("--commit-convention must be one of: none, conventional")
+	}
+
+	if c.CommitPattern != "" {
+		if _, err := regexp.Compile(c.CommitPattern); err != nil {
+			return fmt.Errorf("--commit-pattern is not a valid regex: %w", err)
+		}
+	}
+
+	if c.CommitRetries < 0 {
+		return fmt.Errorf("--commit-retries must be non-negative")
+	}
+
+	if c.ReleaseEvery < 0 {
+		return fmt.Errorf("--release-every must be non-negative")
+	}
+
+	if c.ReadOnly {
+		switch {
+		case c.DryRun || c.DisableCommits:
+			return fmt.Errorf("--read-only records commits, so it can't be combined with --dry-run or --disable-commits")
+		case c.GCBranches:
+			return fmt.Errorf("--read-only can't be combined with --gc-branches, which deletes remote branches")
+		case c.PublishSummary != "":
+			return fmt.Errorf("--read-only can't be combined with --publish-summary")
+		case c.Backport != "":
+			return fmt.Errorf("--read-only can't be combined with --backport")
+		}
+	}
+	if c.Simulate != "" && (c.Detach || c.Worktree != "") {
+		return fmt.Errorf("--simulate runs in a scratch copy of the repository, so it can't be combined with --detach or --worktree")
+	}
+	if c.Replay != "" {
+		switch {
+		case c.Record != "" || c.Simulate != "":
+			return fmt.Errorf("--replay can't be combined with --record or --simulate")
+		case c.Detach || c.Worktree != "":
+			return fmt.Errorf("--replay runs in a scratch copy of the repository, so it can't be combined with --detach or --worktree")
+		}
+	}
+	if c.RestartOnCrash && !c.Detach {
+		return fmt.Errorf("--restart-on-crash supervises a detached run's session, so it requires --detach")
+	}
+	if c.MaxRestarts < 0 {
+		return fmt.Errorf("--max-restarts must be non-negative")
+	}
+	if c.PatchDir != "" && !c.ReadOnly {
+		return fmt.Errorf("--patch-dir requires --read-only")
+	}
+	if c.FixCommits != "" && c.FixCommits != "stack" && c.FixCommits != "amend-ci" && c.FixCommits != "amend" {
+		return fmt.Errorf("--fix-commits must be one of: stack, amend-ci, amend")
+	}
+	if c.RecordResults != "" && c.RecordResults != "none" && c.RecordResults != "commit" && c.RecordResults != "comment" {
+		return fmt.Errorf("--record-results must be one of: none, commit, comment")
+	}
+	if c.RecordResults == "comment" && c.SummaryIssue == "" {
+		return fmt.Errorf("--record-results comment requires --summary-issue")
+	}
+	if (c.RecordResults == "commit" || c.RecordResults == "comment") && c.ReadOnly {
+		return fmt.Errorf("--record-results %s records on GitHub, so it can't be combined with --read-only", c.RecordResults)
+	}
+	if c.TaskType != "" && c.TaskType != "code" && c.TaskType != "analysis" {
+		return fmt.Errorf("--task-type must be one of: code, analysis")
+	}
+	if c.PublishReport != "" && c.PublishReport != "issue" && c.PublishReport != "gist" {
+		return fmt.Errorf("--publish-report must be one of: issue, gist")
+	}
+	if c.PublishReport != "" && c.TaskType != "analysis" {
+		return fmt.Errorf("--publish-report requires --task-type analysis")
+	}
+	if c.TaskType == "analysis" && (c.ReadOnly || c.BranchMode == "single" || c.Backport != "" || c.RecordResults == "commit") {
+		return fmt.Errorf("--task-type analysis commits nothing, so it can't be combined with --read-only, --branch-mode single, --backport or --record-results commit")
+	}
+	if c.Partition != "" && c.Partition != "dir" && c.Partition != "package" && c.Partition != "checklist" {
+		return fmt.Errorf("--partition must be one of: dir, package, checklist")
+	}
+	if c.Partition == "checklist" && c.MigrationCmd == "" {
+		return fmt.Errorf("--partition checklist requires --migration-cmd")
+	}
+	if c.MigrationCmd != "" && c.MigrationBatch <= 0 {
+		return fmt.Errorf("--migration-batch must be positive")
+	}
+	if c.MigrationCmd != "" && c.TaskType == "analysis" {
+		return fmt.Errorf("--migration-cmd can't be combined with --task-type analysis, which changes no code")
+	}
+	if c.BranchMode != "" && c.BranchMode != "iteration" && c.BranchMode != "single" {
+		return fmt.Errorf("--branch-mode must be one of: iteration, single")
+	}
+	if c.BranchMode == "single" {
+		switch {
+		case c.ReadOnly || c.DeferPush:
+			return fmt.Errorf("--branch-mode single pushes every iteration to its PR, so it can't be combined with --read-only or --defer-push")
+		case c.Pipeline:
+			return fmt.Errorf("--branch-mode single merges its PR when the run ends, so it can't be combined with --pipeline")
+		case c.MergeConfidence > 0:
+			return fmt.Errorf("--branch-mode single can't be combined with --merge-confidence")
+		case c.Backport != "":
+			return fmt.Errorf("--branch-mode single can't be combined with --backport")
+		}
+	}
+
+	validStrategies := map[string]bool{"squash": true, "merge": true, "rebase": true}
+	if !validStrategies[c.MergeStrategy] {
+		return fmt.Errorf("--merge-strategy must be one of: squash, merge, rebase")
+	}
+
+	return nil
+}
+
+// HasMaxRuns returns true if a max runs limit is set.
+func (c *Config) HasMaxRuns() bool {
+	return c.MaxRuns > 0
+}
+
+// HasMaxCost returns true if a max cost limit is set.
+func (c *Config) HasMaxCost() bool {
+	return c.MaxCost > 0
+}
+
+// HasMaxDuration returns true if a max duration limit is set.
+func (c *Config) HasMaxDuration() bool {
+	return c.MaxDuration > 0
+}
+
+// QueueMode returns true if a mode splits the work into a queue up front
+// (--upgrade-deps, --lint-fix, --backport).
+func (c *Config) QueueMode() bool {
+	return c.UpgradeDeps || c.LintFix || c.Backport != ""
+}
+
+// ParseDuration parses a duration string like "2h", "30m", "1h30m", "7d".
+func ParseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	// Try standard Go duration parsing first
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+
+	// Parse custom format like "1h30m", "2h", "45m", "7d"
+	re := regexp.MustCompile(`^(?:(\d+)d)?(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s)?$`)
+	matches := re.FindStringSubmatch(s)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid duration format: %s (use format like '2h', '30m', '1h30m', '7d')", s)
+	}
+
+	var total time.Duration
+
+	if matches[1] != "" {
+		days, _ := strconv.Atoi(matches[1])
+		total += time.Duration(days) * 24 * time.Hour
+	}
+
+	if matches[2] != "" {
+		hours, _ := strconv.Atoi(matches[2])
+		total += time.Duration(hours) * time.Hour
+	}
+
+	if matches[3] != "" {
+		minutes, _ := strconv.Atoi(matches[3])
+		total += time.Duration(minutes) * time.Minute
+	}
+
+	if matches[4] != "" {
+		seconds, _ := strconv.Atoi(matches[4])
+		total += time.Duration(seconds) * time.Second
+	}
+
+	if total == 0 {
+		return 0, fmt.Errorf("invalid duration: %s", s)
+	}
+
+	return total, nil
+}
+
+// FormatDuration formats a duration in human-readable format.
+func FormatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+
+	var parts []string
+
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	seconds := int(d.Seconds()) % 60
+
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	if seconds > 0 {
+		parts = append(parts, fmt.Sprintf("%ds", seconds))
+	}
+
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,660 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"2h", 2 * time.Hour, false},
+		{"30m", 30 * time.Minute, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"2h30m15s", 2*time.Hour + 30*time.Minute + 15*time.Second, false},
+		{"45s", 45 * time.Second, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"1d12h", 36 * time.Hour, false},
+		{"", 0, false},
+		{"invalid", 0, true},
+		{"abc123", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseDuration(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseDuration(%q) expected error, got nil", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ParseDuration(%q) unexpected error: %v", tt.input, err)
+				return
+			}
+			if result != tt.expected {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		input    time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{30 * time.Second, "30s"},
+		{5 * time.Minute, "5m"},
+		{2 * time.Hour, "2h"},
+		{90 * time.Minute, "1h30m"},
+		{2*time.Hour + 30*time.Minute + 15*time.Second, "2h30m15s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			result := FormatDuration(tt.input)
+			if result != tt.expected {
+				t.Errorf("FormatDuration(%v) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr bool
+	}{
+		{
+			name: "valid config with max runs",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid config with max cost",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxCost:             10.00,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid config with max duration",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxDuration:         2 * time.Hour,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing prompt",
+			config: &Config{
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: true,
+		},
+		{
+			name: "no limits set",
+			config: &Config{
+				Prompt:              "test prompt",
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid merge strategy",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "invalid",
+				CompletionThreshold: 3,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid completion threshold",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 0,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid completion mode",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				CompletionMode:      "phrase",
+			},
+			wantErr: true,
+		},
+		{
+			name: "draft confidence above merge confidence",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				MergeConfidence:     0.6,
+				DraftConfidence:     0.7,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid revert mode",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				RevertOnMainFailure: "force",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid review timeout policy",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				ReviewWait:          time.Hour,
+				OnReviewTimeout:     "merge",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid completion regex",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				CompletionPattern:   "ALL (DONE",
+			},
+			wantErr: true,
+		},
+		{
+			name: "completion confidence out of range",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				CompletionMode:      "evaluate",
+				MinConfidence:       1.5,
+			},
+			wantErr: true,
+		},
+		{
+			name: "upgrade mode without prompt or limits",
+			config: &Config{
+				UpgradeDeps:         true,
+				UpgradePolicy:       "minor",
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid upgrade policy",
+			config: &Config{
+				UpgradeDeps:         true,
+				UpgradePolicy:       "latest",
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: true,
+		},
+		{
+			name: "backport mode without prompt or limits",
+			config: &Config{
+				Backport:            "40",
+				BackportTo:          []string{"release-1.2"},
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "backport without target branches",
+			config: &Config{
+				Backport:            "40",
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: true,
+		},
+		{
+			name: "backport combined with lint-fix",
+			config: &Config{
+				Backport:            "40",
+				BackportTo:          []string{"release-1.2"},
+				LintFix:             true,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: true,
+		},
+		{
+			name: "path outside the repository",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             1,
+				Paths:               []string{"packages/api", "../other"},
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: true,
+		},
+		{
+			name: "affected tests without placeholder",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             1,
+				VerifyCmd:           "go test ./...",
+				AffectedTests:       true,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: true,
+		},
+		{
+			name: "affected tests with placeholder",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             1,
+				VerifyCmd:           "go test {tests}",
+				AffectedTests:       true,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative max runs",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             -1,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+			},
+			wantErr: true,
+		},
+		{
+			name: "read-only with a patch dir",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				ReadOnly:            true,
+				PatchDir:            "audit",
+			},
+			wantErr: false,
+		},
+		{
+			name: "read-only with gc-branches",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				ReadOnly:            true,
+				GCBranches:          true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "read-only with record-results commit",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				ReadOnly:            true,
+				RecordResults:       "commit",
+			},
+			wantErr: true,
+		},
+		{
+			name: "read-only with record-results comment",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				ReadOnly:            true,
+				RecordResults:       "comment",
+				SummaryIssue:        "7",
+			},
+			wantErr: true,
+		},
+		{
+			name: "patch dir without read-only",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				PatchDir:            "audit",
+			},
+			wantErr: true,
+		},
+		{
+			name: "simulate with worktree",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				Simulate:            "scenario.yaml",
+				Worktree:            "feature",
+			},
+			wantErr: true,
+		},
+		{
+			name: "record a simulation",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				Simulate:            "scenario.yaml",
+				Record:              "run.cassette",
+			},
+			wantErr: false,
+		},
+		{
+			name: "replay while recording",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				Record:              "new.cassette",
+				Replay:              "run.cassette",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative update interval",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				UpdateInterval:      -time.Hour,
+			},
+			wantErr: true,
+		},
+		{
+			name: "labels",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				Labels:              []string{"refactor-auth", "q3.security"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "label with a comma",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				Labels:              []string{"auth,billing"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "restart on crash when detached",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				Detach:              true,
+				RestartOnCrash:      true,
+				MaxRestarts:         3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "restart on crash in the foreground",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				RestartOnCrash:      true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative max restarts",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				MaxRestarts:         -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative max iteration duration",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				IterationTimeout:    -time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative output limit",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				OutputLimit:         -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "page output without a terminal",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				Output:              "json",
+				PageOutput:          true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "single branch with pipeline",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				BranchMode:          "single",
+				Pipeline:            true,
+				PipelineDepth:       2,
+			},
+			wantErr: true,
+		},
+		{
+			name: "analysis with read-only",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				TaskType:            "analysis",
+				ReadOnly:            true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "coverage delta without min-coverage",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				CoverageCmd:         "go test -cover ./...",
+				MinCoverageDelta:    1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "coverage delta without coverage-cmd",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				MinCoverageDelta:    1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "migration without a batch",
+			config: &Config{
+				Prompt:              "test prompt",
+				MaxRuns:             5,
+				MergeStrategy:       "squash",
+				CompletionThreshold: 3,
+				MigrationCmd:        "grep -rl oldlib .",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Validate() expected error, got nil")
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestChoices(t *testing.T) {
+	set := map[string]func(c *Config, v string){
+		"merge-strategy":         func(c *Config, v string) { c.MergeStrategy = v },
+		"dirty-tree":             func(c *Config, v string) { c.DirtyTree = v },
+		"completion-mode":        func(c *Config, v string) { c.CompletionMode = v },
+		"revert-on-main-failure": func(c *Config, v string) { c.RevertOnMainFailure = v },
+		"on-review-timeout":      func(c *Config, v string) { c.OnReviewTimeout = v },
+		"upgrade-policy":         func(c *Config, v string) { c.UpgradePolicy = v },
+		"publish-summary":        func(c *Config, v string) { c.PublishSummary, c.SummaryIssue = v, "7" },
+		"commit-convention":      func(c *Config, v string) { c.CommitConvention = v },
+		"output":                 func(c *Config, v string) { c.Output = v },
+		"branch-mode":            func(c *Config, v string) { c.BranchMode = v },
+		"fix-commits":            func(c *Config, v string) { c.FixCommits = v },
+		"record-results":         func(c *Config, v string) { c.RecordResults, c.SummaryIssue = v, "7" },
+		"task-type":              func(c *Config, v string) { c.TaskType = v },
+		"publish-report":         func(c *Config, v string) { c.PublishReport, c.TaskType = v, "analysis" },
+		"partition":              func(c *Config, v string) { c.Partition, c.MigrationCmd = v, "true" },
+	}
+	for name, values := range Choices {
+		for _, value := range append(values, "bogus") {
+			cfg := DefaultConfig()
+			cfg.Prompt, cfg.MaxRuns = "test", 1
+			set[name](cfg, value)
+			err := cfg.Validate()
+			if value == "bogus" && err == nil {
+				t.Errorf("Validate() with --%s %s expected error, got nil", name, value)
+			}
+			if value != "bogus" && err != nil {
+				t.Errorf("Validate() with --%s %s unexpected error: %v", name, value, err)
+			}
+		}
+	}
+}
+
+func TestConfigJSON(t *testing.T) {
+	// Detached runs hand their validated config to their session as JSON
+	cfg := DefaultConfig()
+	cfg.Prompt = "say \"hi\"\nthen 'bye'"
+	cfg.MaxDuration = 90 * time.Minute
+	cfg.MaxCost = 12.5
+	cfg.CostTags = map[string]string{"team": "payments"}
+	cfg.Labels = []string{"auth"}
+	cfg.Retry.Push.Attempts = 7
+	cfg.ExtraClaudeArgs = []string{"--model", "opus"}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	var decoded Config
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, cfg) {
+		t.Errorf("config changed in JSON:\n got %+v\nwant %+v", decoded, *cfg)
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+
+	if cfg.MergeStrategy != "squash" {
+		t.Errorf("default MergeStrategy = %q, want %q", cfg.MergeStrategy, "squash")
+	}
+
+	if cfg.GitBranchPrefix != "deep-claude/" {
+		t.Errorf("default GitBranchPrefix = %q, want %q", cfg.GitBranchPrefix, "deep-claude/")
+	}
+
+	if cfg.NotesFile != "SHARED_TASK_NOTES.md" {
+		t.Errorf("default NotesFile = %q, want %q", cfg.NotesFile, "SHARED_TASK_NOTES.md")
+	}
+
+	if cfg.CompletionThreshold != 3 {
+		t.Errorf("default CompletionThreshold = %d, want %d", cfg.CompletionThreshold, 3)
+	}
+}
@@ -0,0 +1,180 @@
+// Package events records a machine-readable log of orchestrator events
+// and passes them to subscribers, such as tools that embed the loop.
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Event types written by the orchestrator, and RunCrashed by the session
+// supervising a --restart-on-crash run.
+const (
+	RunStarted       = "run_started"
+	IterationStarted = "iteration_started"
+	ClaudeFinished   = "claude_finished"
+	CommitCreated    = "commit_created"
+	PushDeferred     = "push_deferred"
+	PatchRecorded    = "patch_recorded"
+	ResultsRecorded  = "results_recorded"
+	ReportDelivered  = "report_delivered"
+	ChecklistUpdated = "checklist_updated"
+	PRCreated        = "pr_created"
+	PRUpdated        = "pr_updated"
+	PRChecks         = "pr_checks"
+	PRMerged         = "pr_merged"
+	MergePending     = "merge_pending"
+	PRClosed         = "pr_closed"
+	MainBroken       = "main_broken"
+	DeployFinished   = "deploy_finished"
+	CommandReceived  = "command_received"
+	ReviewEscalated  = "review_escalated"
+	ReviewTimedOut   = "review_timed_out"
+	IterationFailed  = "iteration_failed"
+	RunBlocked       = "run_blocked"
+	RunUnblocked     = "run_unblocked"
+	RunPaused        = "run_paused"
+	RunResumed       = "run_resumed"
+	RunCrashed       = "run_crashed"
+	RunFinished      = "run_finished"
+)
+
+// FileName is the name of the event log inside a run directory.
+const FileName = "events.jsonl"
+
+// Event is a single timestamped, typed entry in the log.
+type Event struct {
+	Time      time.Time      `json:"time"`
+	RunID     string         `json:"run_id,omitempty"`
+	Type      string         `json:"type"`
+	Iteration int            `json:"iteration,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// Subscriber receives each event as it is emitted.
+type Subscriber func(Event)
+
+// Log appends events to a JSONL file and passes them to its subscribers.
+// A nil *Log discards events, so callers don't need to check whether
+// logging could be set up.
+type Log struct {
+	mu          sync.Mutex
+	file        *os.File
+	runID       string
+	subscribers []Subscriber
+
+	// redact scrubs secrets from event data before it is written
+	redact func(map[string]any) map[string]any
+}
+
+// New returns a log that writes no file and only passes events on to its
+// subscribers.
+func New(runID string) *Log {
+	return &Log{runID: runID}
+}
+
+// Create opens the event log at path for appending, creating its directory.
+// Every event is tagged with runID so logs of several runs can be merged.
+func Create(path, runID string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create event log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	return &Log{file: file, runID: runID}, nil
+}
+
+// Subscribe registers s to receive every later event. Subscribers are
+// called one event at a time, in order, from the goroutine that emitted
+// it, so they should return quickly.
+func (l *Log) Subscribe(s Subscriber) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.subscribers = append(l.subscribers, s)
+}
+
+// SetRedactor makes the log pass the data of every later event through
+// redact before writing it or passing it to the subscribers.
+func (l *Log) SetRedactor(redact func(map[string]any) map[string]any) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.redact = redact
+}
+
+// Emit writes an event and passes it to the subscribers. Write errors are
+// ignored: the event log must never interrupt a run.
+func (l *Log) Emit(eventType string, iteration int, data map[string]any) {
+	if l == nil {
+		return
+	}
+
+	e := Event{
+		Time:      time.Now().UTC(),
+		RunID:     l.runID,
+		Type:      eventType,
+		Iteration: iteration,
+		Data:      data,
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.redact != nil {
+		e.Data = l.redact(e.Data)
+	}
+	if l.file != nil {
+		if line, err := json.Marshal(e); err == nil {
+			_, _ = l.file.Write(append(line, '\n'))
+		}
+	}
+	for _, s := range l.subscribers {
+		s(e)
+	}
+}
+
+// Close closes the underlying file.
+func (l *Log) Close() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// Read returns the events in the log at path, keeping only those of the
+// given type when eventType is non-empty.
+func Read(path, eventType string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer file.Close()
+
+	var result []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse event log line %d: %w", lineNum, err)
+		}
+		if eventType == "" || e.Type == eventType {
+			result = append(result, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+	return result, nil
+}
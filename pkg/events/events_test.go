@@ -0,0 +1,84 @@
+package events
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEmitAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", FileName)
+
+	log, err := Create(path, "20250115-143000-ab12")
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	log.Emit(RunStarted, 0, map[string]any{"prompt": "add tests"})
+	log.Emit(IterationStarted, 1, nil)
+	log.Emit(PRCreated, 1, map[string]any{"number": "12"})
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	all, err := Read(path, "")
+	if err != nil {
+		t.Fatalf("Read() unexpected error: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("Read() returned %d events, want 3", len(all))
+	}
+	if all[0].Type != RunStarted || all[0].Data["prompt"] != "add tests" || all[0].Time.IsZero() || all[0].RunID != "20250115-143000-ab12" {
+		t.Errorf("first event = %+v", all[0])
+	}
+
+	prs, err := Read(path, PRCreated)
+	if err != nil {
+		t.Fatalf("Read() unexpected error: %v", err)
+	}
+	if len(prs) != 1 || prs[0].Iteration != 1 || prs[0].Data["number"] != "12" {
+		t.Errorf("Read(%q) = %+v", PRCreated, prs)
+	}
+}
+
+func TestNilLogDiscards(t *testing.T) {
+	var log *Log
+	log.Emit(RunStarted, 0, nil)
+	if err := log.Close(); err != nil {
+		t.Errorf("Close() on nil log = %v, want nil", err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	log, err := Create(path, "20250115-143000-ab12")
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	var got []Event
+	log.Subscribe(func(e Event) { got = append(got, e) })
+	log.Emit(PRCreated, 2, map[string]any{"number": "12"})
+	log.Emit(PRMerged, 2, nil)
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	if len(got) != 2 || got[0].Type != PRCreated || got[0].Iteration != 2 || got[0].RunID != "20250115-143000-ab12" || got[1].Type != PRMerged {
+		t.Errorf("subscriber got %+v", got)
+	}
+	written, err := Read(path, "")
+	if err != nil {
+		t.Fatalf("Read() unexpected error: %v", err)
+	}
+	if len(written) != 2 {
+		t.Errorf("log has %d events, want 2", len(written))
+	}
+
+	memory := New("20250115-143000-ab12")
+	memory.Subscribe(func(e Event) { got = append(got, e) })
+	memory.Emit(RunFinished, 0, nil)
+	if len(got) != 3 || got[2].Type != RunFinished {
+		t.Errorf("New() log did not pass the event on: %+v", got)
+	}
+	if err := memory.Close(); err != nil {
+		t.Errorf("Close() on a log without a file = %v, want nil", err)
+	}
+}
@@ -0,0 +1,170 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/affected"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/guzus/deep-claude/pkg/config"
+)
+
+// affectedTestsCondition is a verify command that runs only the tests
+// affected by the files changed since the last full run, when the suite
+// last known to pass makes that safe. The full suite runs on the first
+// check, every fullEvery checks, and to confirm a pass while the last full
+// run failed.
+type affectedTestsCondition struct {
+	command   string
+	full      []string
+	fullEvery int
+	workDir   string
+	dirs      []string
+	env       []string
+	git       GitRunner
+	mapping   affected.Mapping
+	ui        ui.Printer
+
+	checks int
+	// HEAD when the whole suite was last known to pass or fail
+	baseline  string
+	lastGreen bool
+}
+
+// newVerifyCondition creates the condition for --verify-cmd.
+func newVerifyCondition(cfg *config.Config, gitClient GitRunner, workDir string, env []string, printer ui.Printer) (StopCondition, error) {
+	if !cfg.AffectedTests {
+		return &commandCondition{command: cfg.VerifyCmd, workDir: workDir, dirs: cfg.Paths, env: env}, nil
+	}
+
+	c := &affectedTestsCondition{
+		command:   cfg.VerifyCmd,
+		full:      strings.Fields(cfg.FullTests),
+		fullEvery: cfg.FullTestEvery,
+		workDir:   workDir,
+		dirs:      cfg.Paths,
+		env:       env,
+		git:       gitClient,
+		ui:        printer,
+	}
+	if cfg.TestMap != "" {
+		path := cfg.TestMap
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workDir, path)
+		}
+		mapping, err := affected.LoadMapping(path)
+		if err != nil {
+			return nil, err
+		}
+		c.mapping = mapping
+	}
+	return c, nil
+}
+
+func (c *affectedTestsCondition) Name() string {
+	name := fmt.Sprintf("`%s` succeeds on affected tests", c.command)
+	if len(c.dirs) > 0 {
+		name += " in " + strings.Join(c.dirs, ", ")
+	}
+	return name
+}
+
+func (c *affectedTestsCondition) Command() string { return c.command }
+
+func (c *affectedTestsCondition) Met(ctx context.Context) (bool, error) {
+	c.checks++
+	head, err := c.git.HeadSHA()
+	if err != nil {
+		return false, err
+	}
+
+	if c.baseline == "" || (c.fullEvery > 0 && c.checks%c.fullEvery == 0) {
+		return c.runFull(ctx, head)
+	}
+
+	changed, err := c.git.ChangedFilesSince(c.baseline)
+	if err != nil {
+		return false, err
+	}
+
+	ran := false
+	for _, dir := range c.runDirs() {
+		targets, err := c.targets(ctx, dir, changed)
+		if err != nil {
+			c.ui.Warning("Could not work out affected tests, running the full suite: %v", err)
+			return c.runFull(ctx, head)
+		}
+		if len(targets) == 0 {
+			continue
+		}
+		ran = true
+		c.ui.Info("Running %d affected test target(s) in %s", len(targets), c.relDir(dir))
+		if met, err := c.run(ctx, dir, targets); err != nil || !met {
+			return false, err
+		}
+	}
+
+	switch {
+	case !ran:
+		// Nothing that tests cover changed since the last full run
+		return c.lastGreen, nil
+	case c.lastGreen:
+		c.baseline = head
+		return true, nil
+	default:
+		c.ui.Info("Affected tests pass; confirming with the full suite")
+		return c.runFull(ctx, head)
+	}
+}
+
+// runFull runs the whole suite in every directory and makes head the new
+// baseline.
+func (c *affectedTestsCondition) runFull(ctx context.Context, head string) (bool, error) {
+	c.ui.Info("Running the full test suite")
+	c.baseline = head
+	c.lastGreen = false
+	for _, dir := range c.runDirs() {
+		if met, err := c.run(ctx, dir, c.full); err != nil || !met {
+			return false, err
+		}
+	}
+	c.lastGreen = true
+	return true, nil
+}
+
+func (c *affectedTestsCondition) run(ctx context.Context, dir string, targets []string) (bool, error) {
+	return (&commandCondition{command: affected.Expand(c.command, targets), workDir: dir, env: c.env}).Met(ctx)
+}
+
+// targets selects the tests in dir affected by the changed files, from the
+// mapping if there is one, otherwise from the Go packages in dir.
+func (c *affectedTestsCondition) targets(ctx context.Context, dir string, changed []string) ([]string, error) {
+	if c.mapping != nil {
+		return c.mapping.Targets(changed), nil
+	}
+	pkgs, err := affected.ListGo(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	return affected.GoAffected(pkgs, c.workDir, changed), nil
+}
+
+func (c *affectedTestsCondition) runDirs() []string {
+	if len(c.dirs) == 0 {
+		return []string{c.workDir}
+	}
+	var dirs []string
+	for _, d := range c.dirs {
+		dirs = append(dirs, filepath.Join(c.workDir, d))
+	}
+	return dirs
+}
+
+func (c *affectedTestsCondition) relDir(dir string) string {
+	if rel, err := filepath.Rel(c.workDir, dir); err == nil {
+		return rel
+	}
+	return dir
+}
@@ -0,0 +1,70 @@
+package orchestrator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guzus/deep-claude/internal/affected"
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/ui"
+)
+
+func TestAffectedTestsCondition(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+
+	mapping, err := affected.ParseMapping([]byte(`"api/**": api-tests`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := filepath.Join(t.TempDir(), "runs.log")
+	c := &affectedTestsCondition{
+		command: "echo {tests} >> " + log,
+		full:    []string{"all"},
+		workDir: dir,
+		git:     git.NewClient(dir),
+		mapping: mapping,
+		ui:      ui.NewPrinter(false),
+	}
+
+	check := func(step string) {
+		t.Helper()
+		if met, err := c.Met(t.Context()); err != nil || !met {
+			t.Fatalf("%s: Met() = %v, %v; want true", step, met, err)
+		}
+	}
+	check("first check runs the full suite")
+	check("no changes runs nothing")
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("docs\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check("unmapped change runs nothing")
+	if err := os.MkdirAll(filepath.Join(dir, "api"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "api", "handler.go"), []byte("package api\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check("mapped change runs its tests")
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Fields(string(data)), []string{"all", "api-tests"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ran %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,132 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/audit"
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/replay"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// analysisReportFile is the name of an analysis run's report in its run
+// directory.
+const analysisReportFile = "REPORT.md"
+
+// analysis tells whether the run investigates rather than changes the
+// code, per --task-type analysis.
+func (o *Orchestrator) analysis() bool {
+	return o.config.TaskType == "analysis"
+}
+
+// recordFinding keeps what an analysis iteration found for the report.
+func (o *Orchestrator) recordFinding(iteration int, output string) {
+	if strings.TrimSpace(output) == "" {
+		return
+	}
+	o.findings = append(o.findings, claude.Finding{Iteration: iteration, Output: truncateOutput(output, maxResultsLength)})
+}
+
+// resumeFindings picks up, from their transcripts, the findings of the
+// iterations a resumed analysis run made before it stopped.
+func (o *Orchestrator) resumeFindings() {
+	if o.config.Resume == "" || o.runDir == "" {
+		return
+	}
+	finished, err := events.Read(filepath.Join(o.runDir, events.FileName), events.ClaudeFinished)
+	if err != nil {
+		return
+	}
+	for _, e := range finished {
+		name := str(e.Data, "transcript")
+		if name == "" {
+			continue
+		}
+		if output, err := os.ReadFile(filepath.Join(o.runDir, replay.TranscriptsDir, name)); err == nil {
+			o.recordFinding(e.Iteration, string(output))
+		}
+	}
+}
+
+// deliverReport has Claude write the report of an analysis run from its
+// notes and findings, saves it in the run directory and publishes it per
+// --publish-report. If Claude can't write it, the findings are the report.
+func (o *Orchestrator) deliverReport(ctx context.Context) {
+	if len(o.findings) == 0 {
+		o.ui.Warning("No findings to report")
+		return
+	}
+
+	notes, _ := o.notes.Read()
+	prompt := claude.ReportPrompt(o.runPrompt(), notes, o.findings)
+	o.ui.StartSpinner("Writing the report...")
+	result, err := o.claude.Run(ctx, prompt)
+	o.ui.StopSpinner()
+	o.recordClaude("report", prompt, err != nil || result.IsError)
+	o.addCost(result)
+
+	var body string
+	switch {
+	case err != nil:
+		o.ui.Warning("Could not write the report, reporting the findings instead: %v", err)
+	case result.IsError || strings.TrimSpace(result.Output) == "":
+		o.ui.Warning("Could not write the report, reporting the findings instead")
+	default:
+		body = strings.TrimSpace(result.Output)
+	}
+	if body == "" {
+		body = "# " + o.reportTitle("") + "\n\n" + claude.FormatFindings(o.findings)
+	}
+	body = o.redactor.String(body) + "\n"
+
+	data := map[string]any{"findings": len(o.findings)}
+	if o.runDir != "" {
+		path := filepath.Join(o.runDir, analysisReportFile)
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			o.ui.Warning("Could not save the report: %v", err)
+		} else {
+			o.ui.Success("Report: %s", path)
+			data["file"] = path
+		}
+	}
+
+	if o.config.PublishReport != "" {
+		var url string
+		var err error
+		o.ui.StartSpinner("Publishing the report...")
+		switch o.config.PublishReport {
+		case "issue":
+			url, err = o.github.CreateIssue(ctx, o.reportTitle(body), body)
+		case "gist":
+			url, err = o.github.CreateGist(ctx, "deep-claude-report.md", o.reportTitle(body), body)
+		}
+		o.ui.StopSpinner()
+		if err != nil {
+			o.ui.Warning("Could not publish the report: %v", err)
+		} else {
+			o.ui.Success("Published the report: %s", url)
+			o.audit.Record(audit.ReportPublished, 0, map[string]any{"target": o.config.PublishReport, "url": url})
+			data["url"] = url
+		}
+	}
+	o.events.Emit(events.ReportDelivered, 0, data)
+}
+
+// reportTitle is the title of an analysis report: the heading it starts
+// with, or one naming the run's goal.
+func (o *Orchestrator) reportTitle(report string) string {
+	first, _, _ := strings.Cut(strings.TrimSpace(report), "\n")
+	if title, ok := strings.CutPrefix(first, "# "); ok && strings.TrimSpace(title) != "" {
+		return strings.TrimSpace(title)
+	}
+	goal, _, _ := strings.Cut(strings.TrimSpace(o.runPrompt()), "\n")
+	const maxGoal = 72
+	if len([]rune(goal)) > maxGoal {
+		goal = string([]rune(goal)[:maxGoal-1]) + "…"
+	}
+	return fmt.Sprintf("Analysis: %s", goal)
+}
@@ -0,0 +1,62 @@
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+
+	"github.com/guzus/deep-claude/internal/audit"
+)
+
+// openAuditLog opens the run's audit log next to its event log. Failure
+// only disables the log.
+func (o *Orchestrator) openAuditLog() {
+	if o.runDir == "" {
+		return
+	}
+	log, err := audit.Open(filepath.Join(o.runDir, audit.FileName), o.run.RunID)
+	if err != nil {
+		o.ui.Warning("Could not open audit log: %v", err)
+		return
+	}
+	log.SetRedactor(o.redactor.Data)
+	o.audit = log
+}
+
+// recordClaude audits a Claude invocation. The prompt, if given, is
+// recorded by hash.
+func (o *Orchestrator) recordClaude(purpose, prompt string, failed bool) {
+	data := map[string]any{
+		"command": "claude",
+		"purpose": purpose,
+		"args":    o.config.ExtraClaudeArgs,
+		"failed":  failed,
+	}
+	if prompt != "" {
+		sum := sha256.Sum256([]byte(prompt))
+		data["prompt_sha256"] = hex.EncodeToString(sum[:])
+	}
+	o.audit.Record(audit.CommandRun, o.iteration, data)
+}
+
+// recordCommand audits a shell command run by the tool.
+func (o *Orchestrator) recordCommand(purpose, command string, failed bool) {
+	o.audit.Record(audit.CommandRun, o.iteration, map[string]any{
+		"command": command,
+		"purpose": purpose,
+		"failed":  failed,
+	})
+}
+
+// recordCommit audits the commit at HEAD.
+func (o *Orchestrator) recordCommit(title string) {
+	sha, _ := o.git.HeadSHA()
+	branch, _ := o.git.CurrentBranch()
+	o.audit.Record(audit.CommitCreated, o.iteration, map[string]any{"sha": sha, "branch": branch, "title": title})
+}
+
+// recordPush audits a push of HEAD to branch on origin.
+func (o *Orchestrator) recordPush(branch string, force bool) {
+	sha, _ := o.git.HeadSHA()
+	o.audit.Record(audit.BranchPushed, o.iteration, map[string]any{"branch": branch, "sha": sha, "force": force})
+}
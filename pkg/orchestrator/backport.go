@@ -0,0 +1,217 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/report"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// loadBackports looks up the PR to backport and queues one backport per
+// target branch.
+func (o *Orchestrator) loadBackports(ctx context.Context) error {
+	pr, err := o.github.GetPR(ctx, o.config.Backport)
+	if err != nil {
+		return err
+	}
+	if pr.State != "MERGED" || pr.MergeCommit.Oid == "" {
+		return fmt.Errorf("PR #%s is %s; only merged PRs can be backported", o.config.Backport, strings.ToLower(pr.State))
+	}
+
+	// The merge commit has to be available locally to cherry-pick it
+	if err := o.git.Fetch(ctx, pr.BaseRefName); err != nil {
+		return err
+	}
+
+	o.backportPR = pr
+	o.ui.Info("Backporting #%d %s (%s)", pr.Number, pr.Title, shortSHA(pr.MergeCommit.Oid))
+	for _, branch := range o.config.BackportTo {
+		o.queue = append(o.queue, workItem{title: fmt.Sprintf("[%s] %s", branch, pr.Title), target: branch})
+	}
+	return nil
+}
+
+// runBackport cherry-picks the PR onto this iteration's target branch,
+// has Claude resolve any conflicts, and opens a PR against the target.
+func (o *Orchestrator) runBackport(ctx context.Context) error {
+	pr := o.backportPR
+	target := o.current.target
+	o.backports = append(o.backports, report.Backport{Branch: target})
+	record := &o.backports[len(o.backports)-1]
+
+	if err := o.git.Fetch(ctx, target); err != nil {
+		record.Outcome = "failed: could not fetch branch"
+		return err
+	}
+
+	branchName := backportBranch(o.config.GitBranchPrefix, o.config.Backport, target)
+	o.ui.Info("Creating branch: %s (from origin/%s)", branchName, target)
+	if err := o.git.CreateBranchFrom(branchName, "origin/"+target); err != nil {
+		record.Outcome = "failed: could not create branch"
+		return err
+	}
+	o.events.Emit(events.IterationStarted, o.iteration, map[string]any{"branch": branchName, "backport_to": target})
+
+	conflicts, err := o.git.CherryPick(pr.MergeCommit.Oid)
+	if err != nil {
+		return o.abandonBackport(record, branchName, "cherry-pick failed", err)
+	}
+	if len(conflicts) == 0 {
+		o.ui.Success("Cherry-picked cleanly onto %s", target)
+	} else {
+		o.ui.Warning("Cherry-pick stopped with conflicts in %s", strings.Join(conflicts, ", "))
+		if err := o.resolveConflicts(ctx, conflicts); err != nil {
+			_ = o.git.CherryPickAbort()
+			return o.abandonBackport(record, branchName, "conflicts not resolved", err)
+		}
+		o.ui.Success("Conflicts resolved")
+	}
+
+	commitTitle, _ := o.git.GetLastCommitTitle()
+	o.recordCommit(commitTitle)
+	o.events.Emit(events.CommitCreated, o.iteration, map[string]any{"title": commitTitle, "conflicts": len(conflicts)})
+
+	o.ui.StartSpinner("Pushing branch...")
+	done := o.timer.start(report.PhasePush)
+	err = o.git.PushWithRetry(ctx, branchName, o.config.Retry.Push)
+	done()
+	o.ui.StopSpinner()
+	if err != nil {
+		return o.abandonBackport(record, branchName, "push failed", err)
+	}
+	o.ui.Success("Pushed to origin/%s", branchName)
+	o.recordPush(branchName, false)
+
+	if err := o.shipBranch(ctx, o.current.title, formatBackportBody(pr, target, conflicts), target, false); err != nil {
+		record.Outcome = "failed: could not open PR"
+		return err
+	}
+	opened := o.prs[len(o.prs)-1]
+	record.Number, record.URL, record.Outcome = opened.Number, opened.URL, opened.Outcome
+	return nil
+}
+
+// resolveConflicts has Claude resolve the conflicts of the in-progress
+// cherry-pick, then completes it.
+func (o *Orchestrator) resolveConflicts(ctx context.Context, conflicts []string) error {
+	o.current.goal = backportGoal(o.backportPR, o.current.target, conflicts, o.config.Prompt)
+	notesContent, _ := o.notes.Read()
+	prompt, _ := claude.BuildPromptWithBudget(o.config.PromptTokenBudget, o.goal(), notesContent, claude.Signal{}, o.iteration)
+
+	headBefore, err := o.git.HeadSHA()
+	if err != nil {
+		return err
+	}
+
+	o.ui.StartSpinner("Claude is resolving conflicts...")
+	result, err := o.claude.Run(ctx, prompt)
+	o.ui.StopSpinner()
+	o.recordClaude("resolve conflicts", prompt, err != nil || result.IsError)
+	o.addCost(result)
+	if err != nil {
+		return fmt.Errorf("Claude execution failed: %w", err)
+	}
+
+	transcript := o.saveTranscript(result.Output)
+	o.events.Emit(events.ClaudeFinished, o.iteration, map[string]any{
+		"cost":       result.Cost,
+		"estimated":  result.CostEstimated,
+		"total_cost": o.run.TotalCost,
+		"is_error":   result.IsError,
+		"transcript": transcript,
+	})
+	o.showOutput(result.Output, transcript)
+
+	if unresolved := o.git.UnresolvedConflicts(conflicts); len(unresolved) > 0 {
+		return fmt.Errorf("conflict markers left in %s", strings.Join(unresolved, ", "))
+	}
+
+	// Claude was asked not to commit, but may have finished the pick itself
+	if headAfter, _ := o.git.HeadSHA(); headAfter != headBefore {
+		return nil
+	}
+	if err := o.git.StagePaths(conflicts, nil); err != nil {
+		return err
+	}
+	return o.git.CherryPickContinue()
+}
+
+// abandonBackport records a failed backport and drops its branch.
+func (o *Orchestrator) abandonBackport(record *report.Backport, branch, reason string, err error) error {
+	record.Outcome = "failed: " + reason
+	_ = o.git.SwitchBranch(o.homeBranch)
+	_ = o.git.DeleteBranch(branch)
+	return err
+}
+
+// printBackports lists how each backport ended.
+func (o *Orchestrator) printBackports() {
+	o.ui.SubHeader("Backports")
+	for _, b := range o.backports {
+		switch {
+		case b.Outcome == "merged":
+			o.ui.Success("%s: #%s merged", b.Branch, b.Number)
+		case b.Number != "":
+			o.ui.Info("%s: #%s %s", b.Branch, b.Number, b.Outcome)
+		default:
+			o.ui.Error("%s: %s", b.Branch, b.Outcome)
+		}
+	}
+}
+
+// backportBranch names the branch backporting PR number to target.
+func backportBranch(prefix, number, target string) string {
+	return fmt.Sprintf("%sbackport/%s-%s", prefix, number, strings.ReplaceAll(target, "/", "-"))
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+// backportGoal asks Claude to resolve the conflicts of a cherry-pick.
+func backportGoal(pr *github.PullRequest, target string, conflicts []string, instructions string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Backport PR #%d (%q) to the `%s` branch.\n\n", pr.Number, pr.Title, target))
+	sb.WriteString(fmt.Sprintf("Cherry-picking its merge commit %s onto %s stopped with conflicts in:\n", shortSHA(pr.MergeCommit.Oid), target))
+	for _, f := range conflicts {
+		sb.WriteString("- " + f + "\n")
+	}
+	sb.WriteString(fmt.Sprintf(`
+Resolve every conflict so the change does on %s what it did in the original PR, adapted to the code on this branch. Don't bring in unrelated changes from newer branches. Remove all conflict markers and make sure the code still builds. `+"`git show %s`"+` shows the original change.
+
+Do NOT stage, commit, or run git cherry-pick, merge or rebase; leave the resolved files in the working tree.
+`, target, pr.MergeCommit.Oid))
+
+	if body := strings.TrimSpace(pr.Body); body != "" {
+		sb.WriteString("\nOriginal PR description:\n\n" + claude.TruncateTokens(body, 2000) + "\n")
+	}
+	if instructions != "" {
+		sb.WriteString("\nAdditional instructions:\n" + instructions + "\n")
+	}
+	return sb.String()
+}
+
+func formatBackportBody(pr *github.PullRequest, target string, conflicts []string) string {
+	resolved := "The cherry-pick applied cleanly."
+	if len(conflicts) > 0 {
+		resolved = fmt.Sprintf("Conflicts were resolved by Claude in: %s. Please review these files carefully.", strings.Join(conflicts, ", "))
+	}
+	return fmt.Sprintf(`## Backport of #%d to %s
+
+%s
+
+Cherry-picked from %s.
+
+%s
+
+---
+*This PR was created automatically by Continuous Claude.*
+`, pr.Number, target, pr.Title, pr.MergeCommit.Oid, resolved)
+}
@@ -0,0 +1,21 @@
+package orchestrator
+
+import "testing"
+
+func TestBackportBranch(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"release-1.2", "deep-claude/backport/40-release-1.2"},
+		{"release/v2", "deep-claude/backport/40-release-v2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := backportBranch("deep-claude/", "40", tt.target); got != tt.want {
+				t.Errorf("backportBranch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
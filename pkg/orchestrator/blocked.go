@@ -0,0 +1,81 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/retry"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// blockedPollInterval is how often a blocked run checks for instructions.
+var blockedPollInterval = 10 * time.Second
+
+// waitForHuman pauses a run Claude reported blocked until someone queues
+// instructions, which the next prompt then includes. It returns false and
+// the stop reason if none arrive within --blocked-wait.
+func (o *Orchestrator) waitForHuman(ctx context.Context) (bool, string) {
+	o.ui.Warning("Claude is blocked: %s", o.blocked)
+	o.notifyBlocked(ctx)
+	stopReason := "blocked: " + o.blocked
+	if o.config.BlockedWait == 0 {
+		return false, stopReason
+	}
+
+	o.ui.Info("Waiting up to %s for instructions; %s", config.FormatDuration(o.config.BlockedWait), o.answerHint())
+	deadline := time.Now().Add(o.config.BlockedWait)
+	for !o.hasInstructions() {
+		wait := min(blockedPollInterval, time.Until(deadline))
+		if wait <= 0 {
+			return false, stopReason
+		}
+		if err := retry.Sleep(ctx, wait); err != nil {
+			return false, "interrupted"
+		}
+	}
+
+	o.ui.Success("Instructions received, resuming")
+	o.events.Emit(events.RunUnblocked, 0, nil)
+	o.blocked = ""
+	return true, ""
+}
+
+// hasInstructions reports whether any inbox has instructions queued.
+func (o *Orchestrator) hasInstructions() bool {
+	for _, box := range o.inboxes {
+		if messages, err := box.Pending(); err == nil && len(messages) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// answerHint tells a human how to unblock the run.
+func (o *Orchestrator) answerHint() string {
+	hint := fmt.Sprintf("add a file to %s", o.inboxes[0].Dir())
+	if o.config.SessionName != "" {
+		hint = fmt.Sprintf("run dclaude tell %s \"<answer>\" or %s", o.config.SessionName, hint)
+	}
+	return hint
+}
+
+// block notes that the run needs a human before it can go on.
+func (o *Orchestrator) block(reason string) {
+	o.blocked = reason
+	o.events.Emit(events.RunBlocked, o.iteration, map[string]any{"reason": reason})
+}
+
+// notifyBlocked tells whoever watches the summary issue what the run needs.
+func (o *Orchestrator) notifyBlocked(ctx context.Context) {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Deep Claude run `%s` is blocked and needs a human:\n\n> %s\n\n", o.run.RunID, o.blocked)
+	if o.config.BlockedWait > 0 {
+		fmt.Fprintf(&body, "It waits up to %s for instructions: %s.\n", config.FormatDuration(o.config.BlockedWait), o.answerHint())
+	} else {
+		fmt.Fprintf(&body, "It has stopped; continue it with `dclaude --resume %s` once this is sorted out.\n", o.run.RunID)
+	}
+	o.notify(ctx, body.String())
+}
@@ -0,0 +1,328 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/audit"
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/report"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// commandPrefix starts the line of a comment that gives the run a command.
+const commandPrefix = "/deep-claude"
+
+// handledMarker tags the reply to a command, so each command is acted on
+// once, even by a resumed run.
+const handledMarker = "<!-- deep-claude:handled %d -->"
+
+// maxExplainDiff is how much of a PR's diff an explanation gets to see.
+const maxExplainDiff = 30000
+
+// commandHelp lists the commands in replies to unknown ones.
+const commandHelp = "I understand these commands, on a line of their own:\n\n" +
+	"- `/deep-claude fix <what to change>` pushes a change to this PR in the next iteration\n" +
+	"- `/deep-claude explain [question]` explains the changes in this PR\n" +
+	"- `/deep-claude close [reason]` closes this PR and deletes its branch"
+
+// followUp is a fix a reviewer asked for on one of the run's PRs.
+type followUp struct {
+	index        int
+	instructions string
+	comment      github.Comment
+}
+
+// parseCommand finds the command in a comment: the first line of the form
+// "/deep-claude <name> [args]". The lines after it add to the arguments.
+func parseCommand(body string) (name, args string, ok bool) {
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		rest, found := strings.CutPrefix(strings.TrimSpace(line), commandPrefix)
+		if !found || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return "", "", true
+		}
+		args = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), fields[0]))
+		if more := strings.TrimSpace(strings.Join(lines[i+1:], "\n")); more != "" {
+			args = strings.TrimSpace(args + "\n" + more)
+		}
+		return strings.ToLower(fields[0]), args, true
+	}
+	return "", "", false
+}
+
+// pendingCommands returns the comments that give a command and have no
+// reply from the run yet.
+func pendingCommands(comments []github.Comment) []github.Comment {
+	handled := make(map[int64]bool)
+	for _, c := range comments {
+		if i := strings.Index(c.Body, "<!-- deep-claude:handled "); i >= 0 {
+			var id int64
+			if _, err := fmt.Sscanf(c.Body[i:], handledMarker, &id); err == nil {
+				handled[id] = true
+			}
+		}
+	}
+
+	var pending []github.Comment
+	for _, c := range comments {
+		if _, _, ok := parseCommand(c.Body); ok && !handled[c.ID] && !strings.Contains(c.Body, "<!-- deep-claude:handled ") {
+			pending = append(pending, c)
+		}
+	}
+	return pending
+}
+
+// pollCommands acts on the commands reviewers left on the run's open PRs.
+// close and explain are answered right away; fixes are queued for
+// iterations of their own.
+func (o *Orchestrator) pollCommands(ctx context.Context) {
+	for i := range o.prs {
+		if !strings.HasPrefix(o.prs[i].Outcome, "open") || o.tracking(i) {
+			continue
+		}
+		comments, err := o.github.ListComments(ctx, o.prs[i].Number)
+		if err != nil {
+			o.ui.Warning("Could not read comments on PR #%s: %v", o.prs[i].Number, err)
+			continue
+		}
+		for _, c := range pendingCommands(comments) {
+			o.handleCommand(ctx, i, c)
+		}
+	}
+}
+
+// tracking reports whether the background tracker is watching o.prs[index].
+func (o *Orchestrator) tracking(index int) bool {
+	for _, f := range o.inFlight {
+		if f.index == index {
+			return true
+		}
+	}
+	return false
+}
+
+// handleCommand carries out a command left on o.prs[index]. Only people
+// who can push to the repository may give commands.
+func (o *Orchestrator) handleCommand(ctx context.Context, index int, c github.Comment) {
+	pr := &o.prs[index]
+	name, args, _ := parseCommand(c.Body)
+	if !c.CanWrite() {
+		o.ui.Warning("Ignoring %s %s from @%s on PR #%s: no write access", commandPrefix, name, c.Author, pr.Number)
+		o.reply(ctx, pr.Number, c, fmt.Sprintf("Sorry @%s, only people with write access to the repository can give me commands.", c.Author))
+		return
+	}
+
+	o.ui.Info("@%s on PR #%s: %s %s", c.Author, pr.Number, commandPrefix, name)
+	o.events.Emit(events.CommandReceived, pr.Iteration, map[string]any{"number": pr.Number, "author": c.Author, "command": name})
+	switch name {
+	case "fix":
+		if args == "" {
+			o.reply(ctx, pr.Number, c, "Say what to change, e.g. `/deep-claude fix handle an empty config file`.")
+			return
+		}
+		o.followUps = append(o.followUps, followUp{index: index, instructions: args, comment: c})
+		o.reply(ctx, pr.Number, c, "On it: the next iteration pushes a fix to this PR.")
+	case "explain":
+		o.explain(ctx, pr.Number, pr.Title, c, args)
+	case "close":
+		o.reply(ctx, pr.Number, c, "Closing this PR as requested.")
+		reason := "closed by @" + c.Author
+		if err := o.github.ClosePR(ctx, pr.Number, true); err != nil {
+			o.ui.Warning("Could not close PR #%s: %v", pr.Number, err)
+			return
+		}
+		pr.Outcome = "closed: by @" + c.Author
+		o.events.Emit(events.PRClosed, pr.Iteration, map[string]any{"number": pr.Number, "reason": reason})
+		o.audit.Record(audit.PRClosed, o.iteration, map[string]any{"number": pr.Number, "reason": reason, "branch_deleted": true})
+		o.ui.Success("Closed PR #%s for @%s", pr.Number, c.Author)
+	default:
+		o.reply(ctx, pr.Number, c, commandHelp)
+	}
+}
+
+// reply answers a command, marking it handled.
+func (o *Orchestrator) reply(ctx context.Context, number string, c github.Comment, text string) {
+	body := fmt.Sprintf("%s\n\n"+handledMarker+"\n", text, c.ID)
+	if _, err := o.github.CommentOnIssue(ctx, number, body); err != nil {
+		o.ui.Warning("Could not reply on PR #%s: %v", number, err)
+	}
+}
+
+// explain has Claude answer a reviewer's question about a PR's changes.
+func (o *Orchestrator) explain(ctx context.Context, number, title string, c github.Comment, question string) {
+	diff, err := o.github.GetPRDiff(ctx, number)
+	if err != nil {
+		o.reply(ctx, number, c, fmt.Sprintf("I couldn't read the diff of this PR: %v", err))
+		return
+	}
+
+	prompt := explainPrompt(title, truncateOutput(diff, maxExplainDiff), question)
+	o.ui.StartSpinner(fmt.Sprintf("Claude is explaining PR #%s...", number))
+	result, err := o.claude.Run(ctx, prompt)
+	o.ui.StopSpinner()
+	o.recordClaude("explain", prompt, err != nil || result.IsError)
+	o.addCost(result)
+	if err != nil {
+		o.reply(ctx, number, c, fmt.Sprintf("I couldn't explain this PR: %v", err))
+		return
+	}
+	o.reply(ctx, number, c, strings.TrimSpace(result.Output))
+}
+
+// explainPrompt asks Claude to explain a PR without changing anything.
+func explainPrompt(title, diff, question string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "A reviewer asked you to explain the changes in the pull request %q. Do not modify any files; only answer in your response, ", title)
+	sb.WriteString("which is posted as a comment on the pull request. Be concise and use Markdown.\n\n")
+	if question != "" {
+		fmt.Fprintf(&sb, "The reviewer's question:\n\n%s\n\n", question)
+	} else {
+		sb.WriteString("Explain what the changes do and why, and anything a reviewer should look at closely.\n\n")
+	}
+	fmt.Fprintf(&sb, "The diff:\n\n```diff\n%s\n```\n", diff)
+	return sb.String()
+}
+
+// runFollowUp pushes the fix a reviewer asked for to their PR's branch,
+// then settles the PR again.
+func (o *Orchestrator) runFollowUp(ctx context.Context) error {
+	f := o.followUps[0]
+	o.followUps = o.followUps[1:]
+	record := &o.prs[f.index]
+	pr, err := o.github.GetPR(ctx, record.Number)
+	if err != nil {
+		return err
+	}
+	if pr.State != "OPEN" {
+		o.ui.Info("PR #%s is %s; skipping the fix @%s asked for", record.Number, strings.ToLower(pr.State), f.comment.Author)
+		return nil
+	}
+
+	branch := pr.HeadRefName
+	o.ui.Info("Fixing PR #%s for @%s", record.Number, f.comment.Author)
+	if err := o.git.Fetch(ctx, branch); err != nil {
+		return err
+	}
+	if err := o.git.CreateBranchFrom(branch, "origin/"+branch); err != nil {
+		return err
+	}
+	o.events.Emit(events.IterationStarted, o.iteration, map[string]any{"branch": branch, "follow_up": record.Number})
+
+	// With --fix-commits, the fix replaces the PR's last commit rather than
+	// stacking another on it
+	var failing []string
+	if status, err := o.github.GetPRStatus(ctx, record.Number); err == nil && status.HasFailedChecks {
+		failing = failedChecks(status)
+	}
+	amend := o.config.FixCommits == "amend" || (o.config.FixCommits == "amend-ci" && len(failing) > 0)
+	lease, err := o.git.HeadSHA()
+	if err != nil {
+		return err
+	}
+
+	notesContent, _ := o.notes.Read()
+	prompt, _ := claude.BuildPromptWithBudget(o.config.PromptTokenBudget, followUpGoal(pr, f, o.runPrompt(), failing), notesContent, claude.Signal{}, o.iteration)
+	snapshot, err := o.git.TakeSnapshot()
+	if err != nil {
+		return fmt.Errorf("failed to snapshot working tree: %w", err)
+	}
+
+	o.ui.StartSpinner("Running Claude...")
+	result, err := o.claude.Run(ctx, prompt)
+	o.ui.StopSpinner()
+	o.recordClaude("follow-up", prompt, err != nil || result.IsError)
+	o.addCost(result)
+	if err != nil {
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return fmt.Errorf("Claude execution failed: %w", err)
+	}
+	transcript := o.saveTranscript(result.Output)
+	o.events.Emit(events.ClaudeFinished, o.iteration, map[string]any{
+		"cost":       result.Cost,
+		"estimated":  result.CostEstimated,
+		"total_cost": o.run.TotalCost,
+		"is_error":   result.IsError,
+		"transcript": transcript,
+	})
+	o.showOutput(result.Output, transcript)
+
+	if err := o.stageChanges(snapshot); err != nil {
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return fmt.Errorf("failed to stage changes: %w", err)
+	}
+	if staged, err := o.git.HasStagedChanges(); err != nil || !staged {
+		o.reply(ctx, record.Number, f.comment, "Claude made no changes for this request.")
+		_ = o.git.SwitchBranch(o.homeBranch)
+		_ = o.git.DeleteBranch(branch)
+		return err
+	}
+	if amend {
+		err = o.git.AmendCommit()
+	} else {
+		err = o.createCommit(ctx)
+	}
+	if err != nil {
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return fmt.Errorf("failed to create commit: %w", err)
+	}
+	title, _ := o.git.GetLastCommitTitle()
+	o.recordCommit(title)
+	o.events.Emit(events.CommitCreated, o.iteration, map[string]any{"title": title, "follow_up": record.Number, "amended": amend})
+
+	o.ui.StartSpinner("Pushing branch...")
+	done := o.timer.start(report.PhasePush)
+	if amend {
+		err = o.git.ForcePushWithLease(ctx, branch, lease)
+	} else {
+		err = o.git.PushWithRetry(ctx, branch, o.config.Retry.Push)
+	}
+	done()
+	o.ui.StopSpinner()
+	if err != nil {
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return fmt.Errorf("failed to push: %w", err)
+	}
+	o.recordPush(branch, amend)
+	sha, _ := o.git.HeadSHA()
+	if amend {
+		o.ui.Success("Amended the last commit of PR #%s with a fix", record.Number)
+		o.reply(ctx, record.Number, f.comment, fmt.Sprintf("Amended %s into %s: %s", shortSHA(lease), shortSHA(sha), title))
+	} else {
+		o.ui.Success("Pushed a fix to PR #%s", record.Number)
+		o.reply(ctx, record.Number, f.comment, fmt.Sprintf("Pushed %s: %s", shortSHA(sha), title))
+	}
+
+	if pr.IsDraft {
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return nil
+	}
+	o.ui.StartSpinner("Waiting for PR checks...")
+	done = o.timer.start(report.PhaseChecks)
+	status, err := o.github.WaitForChecks(ctx, record.Number, 30*time.Minute, nil)
+	done()
+	o.ui.StopSpinner()
+	return o.settlePR(ctx, f.index, pr.BaseRefName, status, err)
+}
+
+// followUpGoal asks Claude to make the change a reviewer asked for.
+func followUpGoal(pr *github.PullRequest, f followUp, task string, failing []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "A reviewer (@%s) asked for a change to the pull request %q (#%d), which is checked out.\n\n", f.comment.Author, pr.Title, pr.Number)
+	fmt.Fprintf(&sb, "Their request:\n\n%s\n\n", f.instructions)
+	if len(failing) > 0 {
+		fmt.Fprintf(&sb, "These checks are failing on the pull request: %s.\n\n", strings.Join(failing, ", "))
+	}
+	sb.WriteString("Make that change and nothing else, keeping the rest of the pull request as it is.")
+	if task != "" {
+		fmt.Fprintf(&sb, "\n\nFor context, the pull request is part of this task: %s", task)
+	}
+	return sb.String()
+}
@@ -0,0 +1,46 @@
+package orchestrator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/guzus/deep-claude/internal/github"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		body       string
+		name, args string
+		ok         bool
+	}{
+		{"/deep-claude fix handle empty input", "fix", "handle empty input", true},
+		{"Thanks!\n\n  /deep-claude Explain why the retry?\n", "explain", "why the retry?", true},
+		{"/deep-claude fix\n- rename it\n- add a test", "fix", "- rename it\n- add a test", true},
+		{"/deep-claude", "", "", true},
+		{"/deep-claudefix it", "", "", false},
+		{"Use `/deep-claude fix` next time", "", "", false},
+		{"LGTM", "", "", false},
+	}
+
+	for _, tt := range tests {
+		name, args, ok := parseCommand(tt.body)
+		if name != tt.name || args != tt.args || ok != tt.ok {
+			t.Errorf("parseCommand(%q) = %q, %q, %v, want %q, %q, %v", tt.body, name, args, ok, tt.name, tt.args, tt.ok)
+		}
+	}
+}
+
+func TestPendingCommands(t *testing.T) {
+	comments := []github.Comment{
+		{ID: 1, Body: "/deep-claude fix the typo"},
+		{ID: 2, Body: "On it.\n\n" + fmt.Sprintf(handledMarker, 1)},
+		{ID: 3, Body: "/deep-claude close"},
+		{ID: 4, Body: commandHelp + "\n\n" + fmt.Sprintf(handledMarker, 9)},
+		{ID: 5, Body: "Nice work"},
+	}
+
+	got := pendingCommands(comments)
+	if len(got) != 1 || got[0].ID != 3 {
+		t.Errorf("pendingCommands() = %+v, want only comment 3", got)
+	}
+}
@@ -0,0 +1,163 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/commitmsg"
+	"github.com/guzus/deep-claude/internal/git"
+)
+
+// createCommit commits the staged changes. Claude writes the commit first;
+// if that fails or produces no commit, a locally generated message is used
+// so the iteration's work isn't left stranded on the branch.
+func (o *Orchestrator) createCommit(ctx context.Context) error {
+	headBefore, err := o.git.HeadSHA()
+	if err != nil {
+		return err
+	}
+
+	claudeErr := o.runClaudeCommit(ctx, headBefore)
+	if claudeErr == nil {
+		return nil
+	}
+
+	o.ui.Warning("Claude commit failed (%v), using a generated commit message", claudeErr)
+	return o.commitWithFallback(headBefore)
+}
+
+// runClaudeCommit has Claude commit the staged changes, re-prompting with
+// feedback while the message violates the configured commit convention.
+func (o *Orchestrator) runClaudeCommit(ctx context.Context, headBefore string) error {
+	guidance := o.commitConvention.Instructions()
+
+	for attempt := 0; ; attempt++ {
+		o.ui.StartSpinner("Creating commit...")
+		_, err := o.claude.RunCommit(ctx, guidance)
+		o.ui.StopSpinner()
+		o.recordClaude("commit", "", err != nil)
+		if err != nil {
+			return err
+		}
+
+		headAfter, _ := o.git.HeadSHA()
+		if headAfter == headBefore {
+			return fmt.Errorf("Claude did not create a commit")
+		}
+
+		message, err := o.git.GetLastCommitMessage()
+		if err != nil {
+			return err
+		}
+
+		verr := o.commitConvention.Validate(message)
+		if verr == nil {
+			return nil
+		}
+
+		// Drop the rejected commit, keeping its changes staged
+		if err := o.git.UndoLastCommit(); err != nil {
+			return err
+		}
+
+		if attempt >= o.config.CommitRetries {
+			return fmt.Errorf("commit message rejected after %d attempts: %w", attempt+1, verr)
+		}
+
+		o.ui.Warning("Commit message rejected: %v", verr)
+		guidance = o.commitConvention.Instructions() +
+			fmt.Sprintf("\nYour previous commit message was rejected: %v\nCommit again with a message that satisfies these rules.", verr)
+	}
+}
+
+// commitWithFallback commits whatever is staged with a generated message
+// and verifies that a new commit exists.
+func (o *Orchestrator) commitWithFallback(headBefore string) error {
+	staged, err := o.git.HasStagedChanges()
+	if err != nil {
+		return err
+	}
+	if !staged {
+		// Claude may have committed everything before reporting a failure
+		if head, _ := o.git.HeadSHA(); head != headBefore {
+			return nil
+		}
+		return fmt.Errorf("no commit was created and nothing is staged")
+	}
+
+	diffStat, _ := o.git.GetStagedDiffStat()
+	message := commitmsg.Fallback(o.iteration, o.goalTitle(), diffStat)
+	if err := o.commitConvention.Validate(message); err != nil {
+		return fmt.Errorf("generated commit message does not satisfy the commit convention: %w", err)
+	}
+
+	if err := o.git.Commit(message); err != nil {
+		return err
+	}
+
+	if head, _ := o.git.HeadSHA(); head == headBefore {
+		return fmt.Errorf("commit did not advance HEAD")
+	}
+	return nil
+}
+
+// squashIteration folds the commits the iteration made since start, such
+// as Claude's own and the changelog entry, into one with the iteration's
+// commit message, listing the titles of the others below it.
+func (o *Orchestrator) squashIteration(start, message string) error {
+	titles, err := o.git.CommitTitlesSince(start)
+	if err != nil || len(titles) < 2 {
+		return err
+	}
+	title, _, _ := strings.Cut(message, "\n")
+	message = strings.TrimRight(message, "\n") + "\n\nSquashed commits:"
+	for _, t := range titles {
+		if t != title {
+			message += "\n- " + t
+		}
+	}
+	if err := o.git.SquashSince(start, message); err != nil {
+		return err
+	}
+	o.ui.Info("Squashed %d commits into one", len(titles))
+	return nil
+}
+
+// stageChanges stages the files Claude modified since the snapshot was
+// taken, or everything when --stage-all is set. Paths matching the
+// configured exclude patterns and the prompts directory are never staged,
+// nor, with --path, files outside the projects and the notes file.
+func (o *Orchestrator) stageChanges(snapshot git.Snapshot) error {
+	excludes := append([]string{"/" + promptsDir + "/"}, o.config.StageExcludes...)
+	scope := o.config.Paths
+	if len(scope) > 0 && !filepath.IsAbs(o.config.NotesFile) {
+		if _, err := os.Stat(filepath.Join(o.workDir, o.config.NotesFile)); err == nil {
+			scope = append(append([]string(nil), scope...), o.config.NotesFile)
+		}
+	}
+
+	if o.config.StageAll {
+		if len(scope) > 0 {
+			return o.git.StagePaths(scope, excludes)
+		}
+		return o.git.StageAllExcept(excludes)
+	}
+
+	paths, err := o.git.ChangedSince(snapshot)
+	if err != nil {
+		return err
+	}
+	if len(scope) > 0 {
+		kept := git.FilterPaths(paths, scope)
+		skipped := outside(paths, kept)
+		if len(skipped) > 0 {
+			o.ui.Warning("Not staging %d file(s) changed outside --path", len(skipped))
+		}
+		o.recordGate(scopeGate(skipped))
+		paths = kept
+	}
+	return o.git.StagePaths(paths, excludes)
+}
@@ -0,0 +1,92 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/report"
+)
+
+// mergePath is what --merge-confidence does with an iteration's work.
+type mergePath int
+
+const (
+	mergeAsUsual mergePath = iota
+	openDraft
+	keepLocal
+)
+
+// mergePath decides whether to merge the iteration's work as usual, open
+// a draft PR for a human to review, or keep the branch local and ask for
+// guidance: by the confidence Claude gave it, and work that failed a
+// blocking gate gets a draft at best.
+func (o *Orchestrator) mergePath() mergePath {
+	path := o.confidencePath()
+	if _, failed := o.failedBlockingGate(); failed && path == mergeAsUsual {
+		return openDraft
+	}
+	return path
+}
+
+// confidencePath is the mergePath by confidence alone. Work without a
+// rating gets a draft.
+func (o *Orchestrator) confidencePath() mergePath {
+	switch {
+	case o.config.MergeConfidence == 0:
+		return mergeAsUsual
+	case !o.hasConfidence:
+		return openDraft
+	case o.confidence >= o.config.MergeConfidence:
+		return mergeAsUsual
+	case o.confidence >= o.config.DraftConfidence:
+		return openDraft
+	default:
+		return keepLocal
+	}
+}
+
+// confidenceValue is the confidence for the event log, nil if none was given.
+func (o *Orchestrator) confidenceValue() any {
+	if !o.hasConfidence {
+		return nil
+	}
+	return o.confidence
+}
+
+// confidenceLabel describes the confidence for messages.
+func (o *Orchestrator) confidenceLabel() string {
+	if !o.hasConfidence {
+		return "no confidence given"
+	}
+	return fmt.Sprintf("confidence %.0f%%", o.confidence*100)
+}
+
+// confidenceGate reports whether Claude was confident enough in its work
+// to merge it without a review.
+func (o *Orchestrator) confidenceGate() gate {
+	if o.confidencePath() == mergeAsUsual {
+		return gate{name: "Confidence", passed: true, detail: fmt.Sprintf("%s, at least --merge-confidence %.0f%%", o.confidenceLabel(), o.config.MergeConfidence*100)}
+	}
+	return gate{name: "Confidence", detail: fmt.Sprintf("%s, below --merge-confidence %.0f%%; opened as a draft for review", o.confidenceLabel(), o.config.MergeConfidence*100)}
+}
+
+// leaveDraft leaves a draft PR open for review instead of merging it.
+func (o *Orchestrator) leaveDraft(ctx context.Context, pr *report.PR) {
+	label, reason := o.confidenceLabel(), fmt.Sprintf("%s, below --merge-confidence %.0f%%", o.confidenceLabel(), o.config.MergeConfidence*100)
+	if g, failed := o.failedBlockingGate(); failed && o.confidencePath() == mergeAsUsual {
+		label = strings.ToLower(g.name) + " gate failed"
+		reason = label + ": " + g.detail
+	}
+	pr.Outcome = "open: draft, " + label
+	o.ui.Info("Opened PR #%s as a draft for review (%s)", pr.Number, reason)
+	o.notify(ctx, fmt.Sprintf("Deep Claude run `%s` opened draft PR %s for review (%s): %s", o.run.RunID, pr.URL, label, pr.Title))
+}
+
+// keepBranchLocal leaves work Claude isn't confident in on its local
+// branch and asks for guidance before the next iteration.
+func (o *Orchestrator) keepBranchLocal(branch, title string) {
+	o.ui.Warning("Keeping %s local (%s, below --draft-confidence %.0f%%)", branch, o.confidenceLabel(), o.config.DraftConfidence*100)
+	_ = o.git.SwitchBranch(o.startBranch())
+	o.block(fmt.Sprintf("not confident enough to open a PR for %q (%s); the work is on local branch %s", title, o.confidenceLabel(), branch))
+}
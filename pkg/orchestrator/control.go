@@ -0,0 +1,62 @@
+package orchestrator
+
+import (
+	"context"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/control"
+	"github.com/guzus/deep-claude/internal/retry"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// controlPollInterval is how often a paused run checks whether it may go on.
+var controlPollInterval = 5 * time.Second
+
+// stopRequested is the stop reason of a run stopped with dclaude serve.
+const stopRequested = control.StopReason
+
+// claimRunDir marks the run as going on, so it can be paused and stopped
+// from dclaude serve, and returns the function that releases it.
+func (o *Orchestrator) claimRunDir() func() {
+	if o.runDir == "" {
+		return func() {}
+	}
+	release, err := control.Claim(o.runDir)
+	if err != nil {
+		o.ui.Warning("Could not claim the run directory: %v", err)
+		return func() {}
+	}
+	return release
+}
+
+// checkControl acts on a pause or stop requested between iterations. A
+// paused run waits until it is resumed. It returns false and the stop
+// reason if the run should stop.
+func (o *Orchestrator) checkControl(ctx context.Context) (bool, string) {
+	if o.runDir == "" {
+		return true, ""
+	}
+	switch control.Requested(o.runDir) {
+	case control.Stop:
+		_ = control.Request(o.runDir, "")
+		return false, stopRequested
+	case control.Pause:
+	default:
+		return true, ""
+	}
+
+	o.ui.Warning("Paused; resume the run from dclaude serve")
+	o.events.Emit(events.RunPaused, o.iteration, nil)
+	for control.Requested(o.runDir) == control.Pause {
+		if err := retry.Sleep(ctx, controlPollInterval); err != nil {
+			return false, "interrupted"
+		}
+	}
+	if control.Requested(o.runDir) == control.Stop {
+		_ = control.Request(o.runDir, "")
+		return false, stopRequested
+	}
+	o.ui.Success("Resumed")
+	o.events.Emit(events.RunResumed, o.iteration, nil)
+	return true, ""
+}
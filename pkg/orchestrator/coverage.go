@@ -0,0 +1,196 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// coverageSection is the section of the notes that tracks the coverage
+// of each package through a --min-coverage-delta run.
+const coverageSection = "Test coverage"
+
+// maxCoverageRows caps the packages listed in the notes, lowest coverage
+// first.
+const maxCoverageRows = 30
+
+// coverage is what --coverage-cmd reported: the total, and the coverage
+// of each package it listed.
+type coverage struct {
+	total    float64
+	packages map[string]float64
+}
+
+// parseCoverage reads a coverage tool's output. A line with a percentage
+// gives that of the package its first field names, after go test's ok or
+// FAIL, as go test -cover and coverage.py's report print them; the total
+// is the last percentage, on a line of its own, or else the packages'
+// average, as go test -cover prints none.
+func parseCoverage(output string) (coverage, bool) {
+	total, ok := ParseCoverage(output)
+	if !ok {
+		return coverage{}, false
+	}
+	c := coverage{total: total, packages: make(map[string]float64)}
+	lastIsPackage := false
+	for _, line := range strings.Split(output, "\n") {
+		matches := percentRe.FindAllStringSubmatch(line, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		lastIsPackage = false
+		fields := strings.Fields(line)
+		name := fields[0]
+		if (name == "ok" || name == "FAIL") && len(fields) > 1 {
+			name = fields[1]
+		}
+		if len(fields) < 2 || strings.HasSuffix(name, ":") || strings.Contains(name, "%") || strings.EqualFold(name, "total") {
+			continue
+		}
+		if value, err := strconv.ParseFloat(matches[len(matches)-1][1], 64); err == nil {
+			c.packages[name] = value
+			lastIsPackage = true
+		}
+	}
+	if lastIsPackage {
+		sum := 0.0
+		for _, value := range c.packages {
+			sum += value
+		}
+		c.total = sum / float64(len(c.packages))
+	}
+	return c, true
+}
+
+// measureCoverage runs --coverage-cmd on the working tree.
+func (o *Orchestrator) measureCoverage(ctx context.Context, purpose string) (coverage, error) {
+	output, err := runCommand(ctx, o.config.CoverageCmd, o.workDir, o.env)
+	o.recordCommand(purpose, o.config.CoverageCmd, err != nil)
+	if err != nil {
+		return coverage{}, fmt.Errorf("coverage command failed: %w", err)
+	}
+	c, ok := parseCoverage(output)
+	if !ok {
+		return coverage{}, fmt.Errorf("no coverage percentage found in output of %q", o.config.CoverageCmd)
+	}
+	return c, nil
+}
+
+// coverageBaseline measures coverage before Claude works on the
+// iteration, for its coverage gate, and brings the notes' table of
+// package coverage up to date so Claude goes for the packages that need
+// tests most rather than ones covered already. It returns nil if
+// coverage couldn't be measured.
+func (o *Orchestrator) coverageBaseline(ctx context.Context) *coverage {
+	c, err := o.measureCoverage(ctx, "coverage baseline")
+	if err != nil {
+		o.ui.Warning("Could not measure coverage: %v", err)
+		return nil
+	}
+	table, _, _ := o.notes.Section(coverageSection)
+	if err := o.notes.SetSection(coverageSection, o.coverageTable(c, firstMeasured(table))); err != nil {
+		o.ui.Warning("Could not update the notes: %v", err)
+	}
+	return &c
+}
+
+// coverageTable lists the packages by coverage, lowest first, with what
+// they had when first measured.
+func (o *Orchestrator) coverageTable(c coverage, first map[string]float64) string {
+	names := make([]string, 0, len(c.packages))
+	for name := range c.packages {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if c.packages[names[i]] != c.packages[names[j]] {
+			return c.packages[names[i]] < c.packages[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Maintained by deep-claude: coverage as `%s` reported it before iteration %d, %.1f%% in total. ", o.config.CoverageCmd, o.iteration, c.total)
+	sb.WriteString("Add tests to the packages at the top first; those whose coverage rose since first measured were worked on already.\n\n")
+	sb.WriteString("| Package | Coverage | First measured |\n|---|---|---|\n")
+	for i, name := range names {
+		if i == maxCoverageRows {
+			fmt.Fprintf(&sb, "\n%d more packages have at least %.1f%%.\n", len(names)-i, c.packages[name])
+			break
+		}
+		start, ok := first[name]
+		if !ok {
+			start = c.packages[name]
+		}
+		fmt.Fprintf(&sb, "| %s | %.1f%% | %.1f%% |\n", name, c.packages[name], start)
+	}
+	return sb.String()
+}
+
+// firstMeasured reads the coverage each package had when first measured
+// from the notes' coverage table.
+func firstMeasured(table string) map[string]float64 {
+	first := make(map[string]float64)
+	for _, line := range strings.Split(table, "\n") {
+		cells := strings.Split(strings.Trim(strings.TrimSpace(line), "|"), "|")
+		if len(cells) != 3 {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(cells[2]), "%"), 64)
+		if err == nil {
+			first[strings.TrimSpace(cells[0])] = value
+		}
+	}
+	return first
+}
+
+// coverageGate measures coverage with the iteration's changes, passing
+// if they raised it by at least --min-coverage-delta points.
+func (o *Orchestrator) coverageGate(ctx context.Context, before *coverage) gate {
+	g := gate{name: "Coverage", blocking: true}
+	if before == nil {
+		g.detail = "Coverage couldn't be measured before the changes"
+		return g
+	}
+	after, err := o.measureCoverage(ctx, "coverage gate")
+	if err != nil {
+		g.detail = "Coverage couldn't be measured with the changes: " + err.Error()
+		return g
+	}
+
+	delta := after.total - before.total
+	g.passed = delta >= o.config.MinCoverageDelta
+	g.detail = fmt.Sprintf("Coverage went from %.1f%% to %.1f%% (%+.1f points, --min-coverage-delta %g)", before.total, after.total, delta, o.config.MinCoverageDelta)
+	var raised []string
+	for name, value := range after.packages {
+		if value > before.packages[name] {
+			raised = append(raised, fmt.Sprintf("%s %+.1f", name, value-before.packages[name]))
+		}
+	}
+	sort.Strings(raised)
+	if len(raised) > 0 {
+		g.detail += "; raised " + strings.Join(raised, ", ")
+	}
+	return g
+}
+
+// mutationGate runs --mutation-cmd with the iteration's changes, passing
+// if the score it reports, its last percentage, is at least
+// --min-mutation-score.
+func (o *Orchestrator) mutationGate(ctx context.Context) gate {
+	g := gate{name: "Mutation score", blocking: true}
+	output, err := runCommand(ctx, o.config.MutationCmd, o.workDir, o.env)
+	o.recordCommand("mutation gate", o.config.MutationCmd, err != nil)
+	score, ok := ParseCoverage(output)
+	switch {
+	case !ok && err != nil:
+		g.detail = fmt.Sprintf("The mutation command failed: %v", err)
+	case !ok:
+		g.detail = "No mutation score found in the mutation command's output"
+	default:
+		g.passed = score >= o.config.MinMutationScore
+		g.detail = fmt.Sprintf("Mutation score %.1f%% (--min-mutation-score %g%%)", score, o.config.MinMutationScore)
+	}
+	return g
+}
@@ -0,0 +1,59 @@
+package orchestrator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guzus/deep-claude/pkg/config"
+)
+
+func TestParsePackageCoverage(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		total    float64
+		packages map[string]float64
+	}{
+		{
+			name: "go test",
+			output: "ok  \texample.com/a\t0.01s\tcoverage: 72.5% of statements\n" +
+				"?   \texample.com/b\t[no test files]\n" +
+				"\texample.com/c\t\tcoverage: 0.0% of statements\n" +
+				"FAIL\texample.com/d\t0.02s\tcoverage: 10.0% of statements\n",
+			total:    27.5,
+			packages: map[string]float64{"example.com/a": 72.5, "example.com/c": 0, "example.com/d": 10},
+		},
+		{
+			name:     "coverage.py",
+			output:   "Name          Stmts   Miss  Cover\n---------------------------------\napp/db.py        40     10    75%\napp/web.py       20     20     0%\n---------------------------------\nTOTAL            60     30    50%\n",
+			total:    50,
+			packages: map[string]float64{"app/db.py": 75, "app/web.py": 0},
+		},
+		{
+			name:     "total only",
+			output:   "pkg/a.go:10: Foo 100.0%\ntotal:\t(statements)\t81.3%\n",
+			total:    81.3,
+			packages: map[string]float64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := parseCoverage(tt.output)
+			if !ok || c.total != tt.total || !reflect.DeepEqual(c.packages, tt.packages) {
+				t.Errorf("parseCoverage() = %+v, %v; want total %v and %v", c, ok, tt.total, tt.packages)
+			}
+		})
+	}
+}
+
+func TestCoverageTable(t *testing.T) {
+	o := &Orchestrator{config: &config.Config{CoverageCmd: "go test -cover ./..."}, iteration: 3}
+	before := map[string]float64{"a": 0}
+	table := o.coverageTable(coverage{total: 40, packages: map[string]float64{"a": 20, "b": 60, "c": 5}}, before)
+
+	first := firstMeasured(table)
+	if want := map[string]float64{"a": 0, "b": 60, "c": 5}; !reflect.DeepEqual(first, want) {
+		t.Errorf("firstMeasured(coverageTable()) = %v, want %v\n%s", first, want, table)
+	}
+}
@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/audit"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// maxDuplicateCandidates caps how many open PRs' diffs are compared with
+// a new one.
+const maxDuplicateCandidates = 10
+
+var taskMarkerPattern = regexp.MustCompile(`<!-- deep-claude-task: ([0-9a-f]+) -->`)
+
+// taskKey identifies the work of this iteration against base. A resumed
+// run that redoes an iteration whose PR was already opened gets the same
+// key, while later iterations and other runs get new ones.
+func (o *Orchestrator) taskKey(base string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%d\n%s\n%s", o.run.RunID, o.iteration, base, o.goal())))
+	return hex.EncodeToString(sum[:8])
+}
+
+// taskMarker embeds a task key in a PR body without showing it.
+func taskMarker(key string) string {
+	return fmt.Sprintf("<!-- deep-claude-task: %s -->\n", key)
+}
+
+// parseTaskMarker returns the task key in a PR body, or "".
+func parseTaskMarker(body string) string {
+	if m := taskMarkerPattern.FindStringSubmatch(body); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// duplicateCandidates returns the open PRs against base from the branch
+// prefix that weren't opened earlier in this process, with a PR carrying
+// the task key first.
+func duplicateCandidates(prs []github.PullRequest, prefix, base, key string, own map[string]bool) []github.PullRequest {
+	var marked, rest []github.PullRequest
+	for _, pr := range prs {
+		if pr.BaseRefName != base || !strings.HasPrefix(pr.HeadRefName, prefix) || own[strconv.Itoa(pr.Number)] {
+			continue
+		}
+		if parseTaskMarker(pr.Body) == key {
+			marked = append(marked, pr)
+		} else {
+			rest = append(rest, pr)
+		}
+	}
+	return append(marked, rest...)
+}
+
+// findDuplicatePR looks for an open PR from the tool with the same task
+// key or an equivalent diff, and says which matched. Lookup failures only
+// mean a new PR is opened.
+func (o *Orchestrator) findDuplicatePR(ctx context.Context, key, base string) (*github.PullRequest, string) {
+	open, err := o.github.ListOpenPRs(ctx, 100)
+	if err != nil {
+		o.ui.Debug("Could not check for duplicate PRs: %v", err)
+		return nil, ""
+	}
+	own := make(map[string]bool, len(o.prs))
+	for _, pr := range o.prs {
+		own[pr.Number] = true
+	}
+	candidates := duplicateCandidates(open, o.config.GitBranchPrefix, base, key, own)
+	if len(candidates) == 0 {
+		return nil, ""
+	}
+	if parseTaskMarker(candidates[0].Body) == key {
+		return &candidates[0], "task"
+	}
+
+	diff, err := o.git.BranchDiff(base)
+	if err != nil {
+		return nil, ""
+	}
+	patchID, err := o.git.PatchID(diff)
+	if err != nil || patchID == "" {
+		return nil, ""
+	}
+	for i := range candidates[:min(len(candidates), maxDuplicateCandidates)] {
+		prDiff, err := o.github.GetPRDiff(ctx, strconv.Itoa(candidates[i].Number))
+		if err != nil {
+			continue
+		}
+		if id, err := o.git.PatchID(prDiff); err == nil && id == patchID {
+			return &candidates[i], "diff"
+		}
+	}
+	return nil, ""
+}
+
+// updateDuplicatePR puts the current branch's work on an existing PR in
+// place of opening a new one, and removes the branch pushed for it. The
+// push leases the PR's head as it was listed, so commits someone pushed
+// to it since aren't lost.
+func (o *Orchestrator) updateDuplicatePR(ctx context.Context, dup *github.PullRequest, matched, title, body string) (string, error) {
+	branch, err := o.git.CurrentBranch()
+	if err != nil {
+		return "", err
+	}
+
+	number := strconv.Itoa(dup.Number)
+	if dup.HeadRefOid == "" {
+		return "", fmt.Errorf("head of %s is unknown", dup.HeadRefName)
+	}
+	o.ui.StartSpinner(fmt.Sprintf("Updating PR #%s...", number))
+	err = o.git.ForcePushWithLease(ctx, dup.HeadRefName, dup.HeadRefOid)
+	if err == nil {
+		err = o.github.EditPR(ctx, number, title, body)
+	}
+	o.ui.StopSpinner()
+	if err != nil {
+		return "", err
+	}
+	o.recordPush(dup.HeadRefName, true)
+	o.audit.Record(audit.PRUpdated, o.iteration, map[string]any{"number": number, "url": dup.URL, "title": title, "matched": matched})
+	if branch != dup.HeadRefName {
+		if err := o.git.DeleteRemoteBranches(ctx, branch); err == nil {
+			o.audit.Record(audit.BranchDeleted, o.iteration, map[string]any{"branch": branch})
+		}
+	}
+
+	if matched == "task" {
+		o.ui.Success("Updated PR #%s opened earlier for this iteration: %s", number, dup.URL)
+	} else {
+		o.ui.Success("Updated PR #%s with the same changes instead of opening a duplicate: %s", number, dup.URL)
+	}
+	o.events.Emit(events.PRUpdated, o.iteration, map[string]any{"number": number, "url": dup.URL, "title": title, "matched": matched})
+	return dup.URL, nil
+}
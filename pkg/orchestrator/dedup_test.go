@@ -0,0 +1,101 @@
+package orchestrator
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/guzus/deep-claude/internal/fake"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/ui"
+)
+
+func TestTaskMarker(t *testing.T) {
+	body := withRunID("## Iteration 3\n\nAdd tests\n", "20250115-143000-ab12") + taskMarker("0123456789abcdef")
+	if got := parseTaskMarker(body); got != "0123456789abcdef" {
+		t.Errorf("parseTaskMarker() = %q, want %q", got, "0123456789abcdef")
+	}
+	if got := parseTaskMarker("A PR written by hand"); got != "" {
+		t.Errorf("parseTaskMarker() = %q, want empty", got)
+	}
+}
+
+func TestDuplicateCandidates(t *testing.T) {
+	open := func(number int, base, head, body string) github.PullRequest {
+		return github.PullRequest{Number: number, BaseRefName: base, HeadRefName: head, Body: body}
+	}
+	prs := []github.PullRequest{
+		open(20, "main", "deep-claude/run-b/iteration-1-aaaa", ""),
+		open(19, "main", "deep-claude/run-a/iteration-3-bbbb", taskMarker("abc")),
+		open(18, "main", "feature/by-hand", taskMarker("abc")),
+		open(17, "release-1.2", "deep-claude/backport-40-to-release-1.2", ""),
+		open(16, "main", "deep-claude/run-a/iteration-2-cccc", ""),
+	}
+
+	got := duplicateCandidates(prs, "deep-claude/", "main", "abc", map[string]bool{"16": true})
+	var numbers []int
+	for _, pr := range got {
+		numbers = append(numbers, pr.Number)
+	}
+	if want := []int{19, 20}; !reflect.DeepEqual(numbers, want) {
+		t.Errorf("duplicateCandidates() = %v, want %v", numbers, want)
+	}
+}
+
+func TestUpdateDuplicatePRLeasesHead(t *testing.T) {
+	ctx := t.Context()
+	g := fake.NewGit(filepath.Join(t.TempDir(), "repo"), "acme", "widgets", "main.go")
+	gh := fake.NewGitHub(g, "acme", "widgets")
+	commitAndPush := func(branch, message string) {
+		t.Helper()
+		if err := g.CommitEmpty(message); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Push(ctx, branch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := g.CreateBranch("deep-claude/first"); err != nil {
+		t.Fatal(err)
+	}
+	commitAndPush("deep-claude/first", "Add tests")
+	if _, err := gh.CreatePR(ctx, "Add tests", "", "main"); err != nil {
+		t.Fatal(err)
+	}
+	open, err := gh.ListOpenPRs(ctx, 10)
+	if err != nil || len(open) != 1 {
+		t.Fatalf("ListOpenPRs() = %v, %v; want one PR", open, err)
+	}
+	dup := open[0]
+
+	// Someone pushes to the PR after it was listed
+	commitAndPush("deep-claude/first", "Fix a test")
+	pushed := g.RemoteLog("deep-claude/first")
+
+	if err := g.CreateBranch("deep-claude/second"); err != nil {
+		t.Fatal(err)
+	}
+	commitAndPush("deep-claude/second", "Add tests")
+	o := &Orchestrator{git: g, github: gh, ui: ui.NewSilent()}
+	if _, err := o.updateDuplicatePR(ctx, &dup, "diff", "Add tests", ""); err == nil {
+		t.Fatal("updateDuplicatePR() succeeded over a commit pushed since the PR was listed")
+	}
+	if got := g.RemoteLog("deep-claude/first"); !reflect.DeepEqual(got, pushed) {
+		t.Errorf("PR branch = %v, want it untouched at %v", got, pushed)
+	}
+	if len(g.RemoteLog("deep-claude/second")) == 0 {
+		t.Error("updateDuplicatePR() deleted the branch whose push failed")
+	}
+
+	open, _ = gh.ListOpenPRs(ctx, 10)
+	if _, err := o.updateDuplicatePR(ctx, &open[0], "diff", "Add tests", ""); err != nil {
+		t.Fatalf("updateDuplicatePR() unexpected error: %v", err)
+	}
+	if got, want := g.RemoteLog("deep-claude/first"), g.Log("deep-claude/second"); !reflect.DeepEqual(got, want) {
+		t.Errorf("PR branch = %v, want %v", got, want)
+	}
+	if len(g.RemoteLog("deep-claude/second")) != 0 {
+		t.Error("updateDuplicatePR() kept the branch it moved onto the PR")
+	}
+}
@@ -0,0 +1,137 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/report"
+	"github.com/guzus/deep-claude/internal/retry"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// deployPollInterval is how often a merge's deployment is polled.
+var deployPollInterval = 15 * time.Second
+
+// deployGated reports whether merges wait for a deployment or smoke check.
+func (o *Orchestrator) deployGated() bool {
+	return o.config.DeployEnvironment != "" || o.config.DeployCheck != ""
+}
+
+// waitForDeploy waits for a merged PR to deploy to --deploy-environment
+// and pass --deploy-check. It returns an error, failing the iteration, if
+// either fails or doesn't finish within --deploy-timeout.
+func (o *Orchestrator) waitForDeploy(ctx context.Context, pr *report.PR) error {
+	sha, err := o.mergeCommit(ctx, pr)
+	if err != nil {
+		return err
+	}
+
+	o.ui.StartSpinner(fmt.Sprintf("Waiting for %s of %s...", o.deployGate(), shortSHA(sha)))
+	err = o.pollDeploy(ctx, sha)
+	o.ui.StopSpinner()
+	data := map[string]any{
+		"number":      pr.Number,
+		"sha":         sha,
+		"environment": o.config.DeployEnvironment,
+		"check":       o.config.DeployCheck,
+		"success":     err == nil,
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	o.events.Emit(events.DeployFinished, pr.Iteration, data)
+
+	if err != nil {
+		pr.Outcome = "merged: " + err.Error()
+		return fmt.Errorf("PR #%s merged but %w", pr.Number, err)
+	}
+	o.ui.Success("PR #%s passed %s", pr.Number, o.deployGate())
+	return nil
+}
+
+// deployGate describes what merges wait for.
+func (o *Orchestrator) deployGate() string {
+	var gates []string
+	if o.config.DeployEnvironment != "" {
+		gates = append(gates, "deployment to "+o.config.DeployEnvironment)
+	}
+	if o.config.DeployCheck != "" {
+		gates = append(gates, "check "+o.config.DeployCheck)
+	}
+	return strings.Join(gates, " and ")
+}
+
+// pollDeploy polls the deployment and check of a commit until both pass,
+// one fails or the timeout passes.
+func (o *Orchestrator) pollDeploy(ctx context.Context, sha string) error {
+	deadline := time.Now().Add(o.config.DeployTimeout)
+	envDone, checkDone := o.config.DeployEnvironment == "", o.config.DeployCheck == ""
+	for {
+		if !envDone {
+			state, err := o.github.GetDeploymentState(ctx, sha, o.config.DeployEnvironment)
+			if err != nil {
+				return err
+			}
+			switch state {
+			case "success", "inactive":
+				// An inactive deployment succeeded and was superseded since
+				envDone = true
+			case "failure", "error":
+				return fmt.Errorf("deployment to %s failed", o.config.DeployEnvironment)
+			}
+		}
+		if !checkDone {
+			state, err := o.checkState(ctx, sha, o.config.DeployCheck)
+			if err != nil {
+				return err
+			}
+			switch state {
+			case "SUCCESS", "NEUTRAL", "SKIPPED":
+				checkDone = true
+			case "FAILURE", "ERROR", "CANCELLED", "TIMED_OUT", "ACTION_REQUIRED":
+				return fmt.Errorf("check %s failed", o.config.DeployCheck)
+			}
+		}
+		if envDone && checkDone {
+			return nil
+		}
+
+		wait := min(deployPollInterval, time.Until(deadline))
+		if wait <= 0 {
+			return fmt.Errorf("%s did not finish within %s", o.deployGate(), config.FormatDuration(o.config.DeployTimeout))
+		}
+		if err := retry.Sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// checkState returns the state of the check named name on a commit, ""
+// if it hasn't started.
+func (o *Orchestrator) checkState(ctx context.Context, sha, name string) (string, error) {
+	status, err := o.github.GetCommitStatus(ctx, sha)
+	if err != nil {
+		return "", err
+	}
+	for _, check := range status.Checks {
+		if strings.EqualFold(check.Name, name) {
+			return check.State, nil
+		}
+	}
+	return "", nil
+}
+
+// mergeCommit returns the SHA a merged PR landed as on its base branch.
+func (o *Orchestrator) mergeCommit(ctx context.Context, pr *report.PR) (string, error) {
+	merged, err := o.github.GetPR(ctx, pr.Number)
+	if err != nil {
+		return "", err
+	}
+	if merged.MergeCommit.Oid == "" {
+		return "", fmt.Errorf("PR #%s has no merge commit", pr.Number)
+	}
+	return merged.MergeCommit.Oid, nil
+}
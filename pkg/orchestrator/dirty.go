@@ -0,0 +1,78 @@
+package orchestrator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ownFiles are the files runs keep in the working tree, the notes and the
+// .deep-claude directory, as gitignore-style patterns. They carry context
+// from one run to the next, so they are not the user's changes to set
+// aside.
+func (o *Orchestrator) ownFiles() []string {
+	files := []string{".deep-claude/"}
+	notes := o.config.NotesFile
+	if filepath.IsAbs(notes) {
+		rel, err := filepath.Rel(o.workDir, notes)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			return files
+		}
+		notes = rel
+	}
+	return append(files, filepath.ToSlash(notes))
+}
+
+// preserveDirtyTree handles uncommitted changes present when the run starts,
+// according to the dirty-tree setting. The returned function restores
+// stashed changes and must be called when the run ends.
+func (o *Orchestrator) preserveDirtyTree() (func(), error) {
+	noop := func() {}
+
+	dirty, err := o.git.HasChanges(o.ownFiles())
+	if err != nil {
+		return noop, err
+	}
+	if !dirty {
+		return noop, nil
+	}
+
+	switch o.config.DirtyTree {
+	case "refuse":
+		status, _ := o.git.GetStatus()
+		return noop, fmt.Errorf("working tree has uncommitted changes\n\n%s\nCommit or stash them first, or use --dirty-tree stash to have Deep Claude stash and restore them", status)
+	case "ignore":
+		o.ui.Warning("Working tree has uncommitted changes; they may be included in commits")
+		return noop, nil
+	}
+
+	startBranch, err := o.git.CurrentBranch()
+	if err != nil {
+		return noop, err
+	}
+
+	message := fmt.Sprintf("deep-claude: pre-run changes %s", time.Now().Format("2006-01-02 15:04:05"))
+	stashed, err := o.git.Stash(message, o.ownFiles())
+	if err != nil {
+		return noop, err
+	}
+	if !stashed {
+		return noop, nil
+	}
+	o.ui.Info("Stashed uncommitted changes (restored when the run ends)")
+
+	return func() {
+		if current, _ := o.git.CurrentBranch(); current != startBranch {
+			if err := o.git.SwitchBranch(startBranch); err != nil {
+				o.ui.Warning("Could not switch back to %s: %v", startBranch, err)
+			}
+		}
+		if err := o.git.StashPop(message); err != nil {
+			o.ui.Warning("Could not restore your uncommitted changes: %v", err)
+			o.ui.Info("They are saved in the stash as %q; restore them with 'git stash pop'", message)
+			return
+		}
+		o.ui.Success("Restored uncommitted changes")
+	}, nil
+}
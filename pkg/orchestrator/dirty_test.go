@@ -0,0 +1,77 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/guzus/deep-claude/pkg/config"
+)
+
+func TestPreserveDirtyTreeKeepsRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, kv := range [][2]string{{"GIT_AUTHOR_NAME", "test"}, {"GIT_AUTHOR_EMAIL", "test@example.com"}, {"GIT_COMMITTER_NAME", "test"}, {"GIT_COMMITTER_EMAIL", "test@example.com"}} {
+		t.Setenv(kv[0], kv[1])
+	}
+	client := git.NewClient(dir)
+	if err := client.InitRepo(); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	write("main.go", "package main\n")
+	if out, err := exec.Command("git", "-C", dir, "add", "main.go").CombinedOutput(); err != nil {
+		t.Fatalf("git add: %v\n%s", err, out)
+	}
+	if err := client.Commit("Initial commit"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.DirtyTree = "stash"
+	o := &Orchestrator{config: cfg, git: client, ui: ui.NewSilent(), workDir: dir}
+
+	// Each run rewrites the notes and prompts the previous one left, while
+	// the user's own change is set aside and restored
+	write("main.go", "package main // edited\n")
+	for run := 1; run <= 2; run++ {
+		restore, err := o.preserveDirtyTree()
+		if err != nil {
+			t.Fatalf("run %d: preserveDirtyTree() unexpected error: %v", run, err)
+		}
+		if got := read("main.go"); got != "package main\n" {
+			t.Errorf("run %d: main.go = %q, want the user's change stashed", run, got)
+		}
+		if run == 2 && read(cfg.NotesFile) != "notes of run 1\n" {
+			t.Errorf("run 2: notes = %q, want those of run 1", read(cfg.NotesFile))
+		}
+		write(cfg.NotesFile, fmt.Sprintf("notes of run %d\n", run))
+		write(filepath.Join(promptsDir, "iteration-1.md"), "prompt\n")
+		restore()
+		if got := read("main.go"); got != "package main // edited\n" {
+			t.Errorf("run %d: main.go = %q after the run, want the user's change restored", run, got)
+		}
+	}
+	if out, _ := exec.Command("git", "-C", dir, "stash", "list").Output(); len(out) != 0 {
+		t.Errorf("left in the stash: %s", out)
+	}
+}
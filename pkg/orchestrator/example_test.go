@@ -0,0 +1,50 @@
+package orchestrator_test
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/events"
+	"github.com/guzus/deep-claude/pkg/orchestrator"
+)
+
+// loggingClaude logs each prompt before handing it to Claude.
+type loggingClaude struct {
+	orchestrator.ClaudeRunner
+}
+
+func (c loggingClaude) Run(ctx context.Context, prompt string) (*orchestrator.ClaudeResult, error) {
+	log.Printf("running Claude with a %d byte prompt", len(prompt))
+	return c.ClaudeRunner.Run(ctx, prompt)
+}
+
+// Embedding the loop in another tool, with its own Claude client.
+func Example() {
+	cfg := config.DefaultConfig()
+	cfg.Prompt = "add tests for the parser"
+	cfg.MaxRuns = 3
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	workDir := "/srv/checkouts/widgets"
+	git, newGitHub, claude := orchestrator.Runners(cfg, workDir)
+	o, err := orchestrator.NewWithRunners(cfg, workDir, git, newGitHub, loggingClaude{claude})
+	if err != nil {
+		log.Fatal(err)
+	}
+	o.Subscribe(func(e events.Event) {
+		if e.Type == events.PRMerged {
+			log.Printf("merged PR #%v", e.Data["number"])
+		}
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := o.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
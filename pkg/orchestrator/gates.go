@@ -0,0 +1,109 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/github"
+)
+
+// checkRunName is the name of the check run --check-run publishes.
+const checkRunName = "deep-claude"
+
+// gate is the verdict of one check an iteration's changes went through
+// before their PR was opened.
+type gate struct {
+	name        string
+	passed      bool
+	detail      string
+	annotations []github.Annotation
+	// A failed blocking gate keeps the PR from merging
+	blocking bool
+}
+
+// recordGate notes a gate's verdict for the iteration's check run.
+func (o *Orchestrator) recordGate(g gate) {
+	o.gates = append(o.gates, g)
+}
+
+// failedBlockingGate returns the first blocking gate the iteration failed.
+func (o *Orchestrator) failedBlockingGate() (gate, bool) {
+	for _, g := range o.gates {
+		if g.blocking && !g.passed {
+			return g, true
+		}
+	}
+	return gate{}, false
+}
+
+// scopeGate reports the files changed outside --path, which were left
+// out of the PR.
+func scopeGate(skipped []string) gate {
+	g := gate{name: "Scope", passed: len(skipped) == 0, detail: "All changes are inside --path"}
+	if len(skipped) > 0 {
+		g.detail = fmt.Sprintf("%d file(s) changed outside --path were left out of this PR", len(skipped))
+	}
+	for _, p := range skipped {
+		g.annotations = append(g.annotations, github.Annotation{
+			Path: p, StartLine: 1, EndLine: 1, Level: "warning",
+			Title: "Changed outside --path", Message: "Claude changed this file, but it is outside the projects the run works on, so the change was not committed.",
+		})
+	}
+	return g
+}
+
+// publishCheckRun publishes the iteration's gate verdicts as a check run
+// on the head of the current branch. Failed gates make it neutral rather
+// than failing, since they didn't keep the PR from being opened.
+func (o *Orchestrator) publishCheckRun(ctx context.Context, prNumber string) {
+	if len(o.gates) == 0 {
+		return
+	}
+	sha, err := o.git.HeadSHA()
+	if err != nil {
+		o.ui.Warning("Could not publish check run: %v", err)
+		return
+	}
+
+	run := github.CheckRun{Name: checkRunName, HeadSHA: sha, Conclusion: "success"}
+	passed := 0
+	var summary strings.Builder
+	summary.WriteString("| Gate | Verdict | Details |\n|---|---|---|\n")
+	for _, g := range o.gates {
+		verdict := "✅ passed"
+		if g.passed {
+			passed++
+		} else {
+			verdict = "⚠️ failed"
+			run.Conclusion = "neutral"
+		}
+		fmt.Fprintf(&summary, "| %s | %s | %s |\n", g.name, verdict, g.detail)
+		run.Annotations = append(run.Annotations, g.annotations...)
+	}
+	fmt.Fprintf(&summary, "\nIteration %d of Deep Claude run `%s`.\n", o.iteration, o.run.RunID)
+	run.Title = fmt.Sprintf("%d of %d gates passed", passed, len(o.gates))
+	run.Summary = summary.String()
+
+	url, err := o.github.CreateCheckRun(ctx, run)
+	if err != nil {
+		o.ui.Warning("Could not publish check run on PR #%s: %v", prNumber, err)
+		return
+	}
+	o.ui.Info("Published check run: %s", url)
+}
+
+// outside returns the paths that aren't in kept.
+func outside(paths, kept []string) []string {
+	in := make(map[string]bool, len(kept))
+	for _, p := range kept {
+		in[p] = true
+	}
+	var skipped []string
+	for _, p := range paths {
+		if !in[p] {
+			skipped = append(skipped, p)
+		}
+	}
+	return skipped
+}
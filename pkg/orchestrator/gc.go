@@ -0,0 +1,42 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/audit"
+	"github.com/guzus/deep-claude/internal/gc"
+)
+
+// collectBranches deletes the remote branches earlier runs left behind
+// once their PRs were closed or merged. Failure only leaves them in place.
+func (o *Orchestrator) collectBranches(ctx context.Context) {
+	if strings.TrimSpace(o.config.GitBranchPrefix) == "" {
+		o.ui.Warning("Skipping branch cleanup: no branch prefix")
+		return
+	}
+
+	o.ui.StartSpinner("Finding stale branches...")
+	stale, err := gc.Find(ctx, o.git, o.github, o.config.GitBranchPrefix)
+	o.ui.StopSpinner()
+	if err != nil {
+		o.ui.Warning("Could not find stale branches: %v", err)
+		return
+	}
+	if len(stale) == 0 {
+		return
+	}
+
+	if o.config.DryRun {
+		o.ui.Info("Dry run, %d stale branch(es) not deleted", len(stale))
+		return
+	}
+	if err := gc.Delete(ctx, o.git, stale); err != nil {
+		o.ui.Warning("Could not delete stale branches: %v", err)
+		return
+	}
+	for _, b := range stale {
+		o.audit.Record(audit.BranchDeleted, 0, map[string]any{"branch": b.Name, "pr": b.PR, "pr_state": b.State})
+	}
+	o.ui.Success("Deleted %d stale branch(es) of closed or merged PRs", len(stale))
+}
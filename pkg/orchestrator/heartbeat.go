@@ -0,0 +1,73 @@
+package orchestrator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/report"
+	"github.com/guzus/deep-claude/internal/state"
+)
+
+// heartbeatInterval is how often a going run writes its heartbeat.
+var heartbeatInterval = state.HeartbeatInterval
+
+// startHeartbeat writes a heartbeat to the run's state file until the
+// returned func is called, so dclaude status can tell a dead or hung run
+// from one that is going. The func then records that the run exited
+// cleanly.
+func (o *Orchestrator) startHeartbeat() func() {
+	ticker := time.NewTicker(heartbeatInterval)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := o.run.Beat(); err != nil {
+					o.ui.Debug("Could not write heartbeat: %v", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(stop)
+		wg.Wait()
+		o.run.Exited = true
+		o.saveRunState()
+	}
+}
+
+// timedClaude records in the run state since when Claude has been working
+// on a request, so that one that hangs shows in dclaude status, and times
+// requests as the iteration's Claude phase.
+type timedClaude struct {
+	ClaudeRunner
+	run   *state.RunState
+	timer *phaseTimer
+}
+
+func (c timedClaude) Run(ctx context.Context, prompt string) (*claude.Result, error) {
+	defer c.run.StartClaude()()
+	defer c.timer.start(report.PhaseClaude)()
+	return c.ClaudeRunner.Run(ctx, prompt)
+}
+
+func (c timedClaude) RunCommit(ctx context.Context, guidance string) (string, error) {
+	defer c.run.StartClaude()()
+	defer c.timer.start(report.PhaseClaude)()
+	return c.ClaudeRunner.RunCommit(ctx, guidance)
+}
+
+func (c timedClaude) Evaluate(ctx context.Context, goal string) (*claude.Evaluation, error) {
+	defer c.run.StartClaude()()
+	defer c.timer.start(report.PhaseClaude)()
+	return c.ClaudeRunner.Evaluate(ctx, goal)
+}
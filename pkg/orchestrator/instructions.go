@@ -0,0 +1,43 @@
+package orchestrator
+
+import (
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/inbox"
+)
+
+// instructionsSection collects pending human instructions from every inbox
+// into a prompt section. The returned func archives them once the
+// iteration they were given to succeeds, so each instruction is delivered
+// until one acts on it.
+func (o *Orchestrator) instructionsSection() (claude.Section, func()) {
+	section := claude.Section{Title: "ADDITIONAL INSTRUCTIONS"}
+
+	pending := make(map[*inbox.Inbox][]inbox.Message)
+	var all []inbox.Message
+	for _, box := range o.inboxes {
+		messages, err := box.Pending()
+		if err != nil {
+			o.ui.Warning("Could not read queued instructions: %v", err)
+			continue
+		}
+		if len(messages) > 0 {
+			pending[box] = messages
+			all = append(all, messages...)
+		}
+	}
+
+	if len(all) == 0 {
+		return section, func() {}
+	}
+
+	o.ui.Info("Including %d queued instruction(s)", len(all))
+	section.Body = "A human added these instructions while the run was in progress. They take priority over the notes:\n\n" + inbox.Format(all)
+
+	return section, func() {
+		for box, messages := range pending {
+			if err := box.Archive(messages); err != nil {
+				o.ui.Warning("Could not archive instructions in %s: %v", box.Dir(), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,1307 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/audit"
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/control"
+	"github.com/guzus/deep-claude/internal/fake"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/inbox"
+	"github.com/guzus/deep-claude/internal/ledger"
+	"github.com/guzus/deep-claude/internal/replay"
+	"github.com/guzus/deep-claude/internal/report"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/worktree"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+var (
+	_ GitRunner    = (*fake.Git)(nil)
+	_ GhRunner     = (*fake.GitHub)(nil)
+	_ ClaudeRunner = (*fake.Claude)(nil)
+)
+
+// harness runs the orchestrator against a fake repository, forge and
+// Claude.
+type harness struct {
+	t      *testing.T
+	dir    string
+	cfg    *config.Config
+	git    *fake.Git
+	github *fake.GitHub
+	claude *fake.Claude
+	// subscriber, if set, gets the run's events
+	subscriber events.Subscriber
+}
+
+func newHarness(t *testing.T, turns ...fake.Turn) *harness {
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	cfg := config.DefaultConfig()
+	cfg.Prompt = "improve the tests"
+	cfg.MaxRuns = len(turns)
+	cfg.RepoMapIterations = 0
+
+	g := fake.NewGit(filepath.Join(dir, "repo"), "acme", "widgets", "main.go", "main_test.go")
+	return &harness{
+		t:      t,
+		dir:    dir,
+		cfg:    cfg,
+		git:    g,
+		github: fake.NewGitHub(g, "acme", "widgets"),
+		claude: fake.NewClaude(g, turns...),
+	}
+}
+
+// run runs the orchestrator to completion.
+func (h *harness) run() *Orchestrator {
+	h.t.Helper()
+	return h.runWith(h.t.Context(), h.claude)
+}
+
+// runWith runs the orchestrator with ctx and the given Claude.
+func (h *harness) runWith(ctx context.Context, c ClaudeRunner) *Orchestrator {
+	h.t.Helper()
+	newGitHub := func(owner, repo string) GhRunner { return h.github }
+	o, err := NewWithRunners(h.cfg, h.dir, h.git, newGitHub, c)
+	if err != nil {
+		h.t.Fatalf("NewWithRunners() unexpected error: %v", err)
+	}
+	if h.subscriber != nil {
+		o.Subscribe(h.subscriber)
+	}
+	if err := o.Run(ctx); err != nil {
+		h.t.Fatalf("Run() unexpected error: %v", err)
+	}
+	return o
+}
+
+// titles returns the commit titles on origin's main after the initial one.
+func (h *harness) titles() []string {
+	var titles []string
+	for _, c := range h.git.RemoteLog("main")[1:] {
+		titles = append(titles, c.Title())
+	}
+	return titles
+}
+
+func TestRunShipsPRs(t *testing.T) {
+	tests := []struct {
+		name     string
+		turns    []fake.Turn
+		statuses []*github.PRStatus
+		states   []string
+		outcomes []string
+		merged   []string
+	}{
+		{
+			name: "checks pass",
+			turns: []fake.Turn{
+				{Edits: []string{"main.go"}, CommitMessage: "Handle empty input", Cost: 0.5},
+				{Edits: []string{"main_test.go"}, CommitMessage: "Test empty input", Cost: 0.25},
+			},
+			states:   []string{"MERGED", "MERGED"},
+			outcomes: []string{"merged", "merged"},
+			merged:   []string{"Handle empty input", "Test empty input"},
+		},
+		{
+			name:     "checks fail",
+			turns:    []fake.Turn{{Edits: []string{"main.go"}, CommitMessage: "Break the build"}},
+			statuses: []*github.PRStatus{fake.Failing()},
+			states:   []string{"CLOSED"},
+			outcomes: []string{"closed: checks failed"},
+		},
+		{
+			name:     "changes requested",
+			turns:    []fake.Turn{{Edits: []string{"main.go"}, CommitMessage: "Rename everything"}},
+			statuses: []*github.PRStatus{fake.ChangesRequested()},
+			states:   []string{"OPEN"},
+			outcomes: []string{"open: not mergeable"},
+		},
+		{
+			name: "checks time out",
+			turns: []fake.Turn{
+				{Edits: []string{"main.go"}, CommitMessage: "Slow tests"},
+				{Edits: []string{"main_test.go"}, CommitMessage: "Fast tests"},
+			},
+			statuses: []*github.PRStatus{nil},
+			states:   []string{"OPEN", "MERGED"},
+			outcomes: []string{"open: timed out waiting for checks", "merged"},
+			merged:   []string{"Fast tests"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, tt.turns...)
+			h.github.Statuses = tt.statuses
+			o := h.run()
+
+			var states, outcomes []string
+			for _, pr := range h.github.PRs() {
+				states = append(states, pr.State)
+			}
+			for _, pr := range o.prs {
+				outcomes = append(outcomes, pr.Outcome)
+			}
+			if !reflect.DeepEqual(states, tt.states) {
+				t.Errorf("PR states = %v, want %v", states, tt.states)
+			}
+			if !reflect.DeepEqual(outcomes, tt.outcomes) {
+				t.Errorf("PR outcomes = %v, want %v", outcomes, tt.outcomes)
+			}
+			if titles := h.titles(); !reflect.DeepEqual(titles, tt.merged) {
+				t.Errorf("merged into main = %v, want %v", titles, tt.merged)
+			}
+			if branch, _ := h.git.CurrentBranch(); branch != "main" {
+				t.Errorf("run ended on branch %s, want main", branch)
+			}
+		})
+	}
+}
+
+func TestRunStopsWithoutProgress(t *testing.T) {
+	h := newHarness(t, fake.Turn{}, fake.Turn{}, fake.Turn{})
+	h.cfg.StopOnNoProgress = 2
+	o := h.run()
+
+	if o.run.Iterations != 2 || len(h.github.PRs()) != 0 {
+		t.Errorf("ran %d iterations and opened %d PRs, want 2 and none", o.run.Iterations, len(h.github.PRs()))
+	}
+	if branches := h.git.Branches(); !reflect.DeepEqual(branches, []string{"main"}) {
+		t.Errorf("branches left = %v, want [main]", branches)
+	}
+}
+
+func TestRunRecordsResults(t *testing.T) {
+	findings := fake.Turn{Output: "The cache misses come from the session key including a timestamp."}
+	for _, via := range []string{"commit", "comment"} {
+		t.Run(via, func(t *testing.T) {
+			h := newHarness(t, findings, findings, findings)
+			h.cfg.StopOnNoProgress = 2
+			h.cfg.RecordResults = via
+			h.cfg.SummaryIssue = "#7"
+			o := h.run()
+
+			if o.run.Iterations != 3 || len(h.github.PRs()) != 0 {
+				t.Errorf("ran %d iterations and opened %d PRs, want 3 and none", o.run.Iterations, len(h.github.PRs()))
+			}
+			recorded := h.github.Comments()
+			if via == "commit" {
+				recorded = nil
+				branches := h.git.Branches()
+				if len(branches) != 4 {
+					t.Fatalf("branches left = %v, want main and one per iteration", branches)
+				}
+				for _, branch := range branches[:3] {
+					log := h.git.RemoteLog(branch)
+					if len(log) == 0 || len(log[len(log)-1].Files) != 0 {
+						t.Fatalf("origin/%s = %v, want it to end with an empty commit", branch, log)
+					}
+					recorded = append(recorded, log[len(log)-1].Message)
+				}
+			}
+			if len(recorded) != 3 || !strings.Contains(recorded[0], "of iteration 1") || !strings.Contains(recorded[0], "session key including a timestamp") {
+				t.Errorf("recorded results = %q, want one per iteration with Claude's findings", recorded)
+			}
+		})
+	}
+}
+
+func TestRunAnalysis(t *testing.T) {
+	h := newHarness(t,
+		fake.Turn{Output: "The cache is keyed by a timestamp.", Edits: []string{"cache.go"}},
+		fake.Turn{Output: "Eviction never runs under load."},
+		fake.Turn{Output: "# Cache audit\n\nThe cache never hits."},
+	)
+	h.cfg.MaxRuns = 2
+	h.cfg.TaskType = "analysis"
+	h.cfg.PublishReport = "issue"
+	o := h.run()
+
+	if len(h.github.PRs()) != 0 || !reflect.DeepEqual(h.git.Branches(), []string{"main"}) || len(h.git.Log("main")) != 1 {
+		t.Errorf("analysis run committed: PRs %v, branches %v", h.github.PRs(), h.git.Branches())
+	}
+	if len(h.claude.Prompts) != 3 || !strings.Contains(h.claude.Prompts[0], "## ANALYSIS TASK") {
+		t.Fatalf("Claude was prompted %d times, want 2 iterations told it's an analysis and the report", len(h.claude.Prompts))
+	}
+	for _, want := range []string{"### Iteration 1\n\nThe cache is keyed by a timestamp.", "### Iteration 2\n\nEviction never runs under load."} {
+		if !strings.Contains(h.claude.Prompts[2], want) {
+			t.Errorf("report prompt is missing %q:\n%s", want, h.claude.Prompts[2])
+		}
+	}
+	if issues := h.github.OpenedIssues(); len(issues) != 1 || issues[0] != "# Cache audit\n\nThe cache never hits.\n" {
+		t.Errorf("opened issues = %q, want the report", issues)
+	}
+	if report, err := os.ReadFile(filepath.Join(o.runDir, analysisReportFile)); err != nil || !strings.HasPrefix(string(report), "# Cache audit") {
+		t.Errorf("saved report = %q, %v", report, err)
+	}
+}
+
+func TestRunCoverageDelta(t *testing.T) {
+	tests := []struct {
+		name        string
+		step        int
+		wantOutcome string
+	}{
+		{name: "coverage rises", step: 5, wantOutcome: "merged"},
+		{name: "coverage flat", step: 0, wantOutcome: "open: draft, coverage gate failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, fake.Turn{Edits: []string{"main_test.go"}})
+			count := filepath.Join(t.TempDir(), "count")
+			h.cfg.CoverageCmd = fmt.Sprintf(`n=$(cat %[1]s 2>/dev/null || echo 40); echo $((n+%[2]d)) > %[1]s; printf 'ok  \texample.com/a\t0.1s\tcoverage: %%s.0%%%% of statements\n' "$n"`, count, tt.step)
+			h.cfg.MinCoverageDelta = 2
+			h.cfg.BlockedWait = 0
+			o := h.run()
+
+			if len(o.prs) != 1 || o.prs[0].Outcome != tt.wantOutcome {
+				t.Fatalf("prs = %+v, want one %q", o.prs, tt.wantOutcome)
+			}
+			notes, err := o.notes.Read()
+			if err != nil || !strings.Contains(notes, "## Test coverage") || !strings.Contains(notes, "| example.com/a | 40.0% | 40.0% |") {
+				t.Errorf("notes = %q, %v, want the coverage table", notes, err)
+			}
+		})
+	}
+}
+
+func TestRunMigration(t *testing.T) {
+	h := newHarness(t, fake.Turn{Edits: []string{"main.go"}})
+	h.cfg.MigrationCmd = `printf 'a.go\nb.go\nc.go\n'`
+	h.cfg.MigrationBatch = 2
+	notes := "# Notes\n\n## Migration checklist\n\n- [ ] `old.go` (iteration 1)\n- [ ] `a.go`\n"
+	if err := os.WriteFile(filepath.Join(h.dir, h.cfg.NotesFile), []byte(notes), 0644); err != nil {
+		t.Fatal(err)
+	}
+	o := h.run()
+
+	if prompt := h.claude.Prompts[0]; !strings.Contains(prompt, "## MIGRATION") || !strings.Contains(prompt, "- `a.go`\n- `b.go`\n\n") {
+		t.Errorf("prompt doesn't assign a.go and b.go:\n%s", prompt)
+	}
+	content, err := o.notes.Read()
+	if err != nil || !strings.Contains(content, "1 of 4 items done (25%)") || !strings.Contains(content, "- [x] `old.go`\n- [ ] `a.go`\n- [ ] `b.go`\n- [ ] `c.go`\n") {
+		t.Errorf("notes = %q, %v, want the checklist with old.go done", content, err)
+	}
+	if run := o.runReport(); run.MigrationDone != 1 || run.MigrationTotal != 4 {
+		t.Errorf("report migration = %d of %d, want 1 of 4", run.MigrationDone, run.MigrationTotal)
+	}
+
+	// Once nothing is left to migrate, the run is done
+	h = newHarness(t, fake.Turn{Edits: []string{"main.go"}}, fake.Turn{Edits: []string{"main_test.go"}})
+	h.cfg.MigrationCmd = "true"
+	o = h.run()
+	if o.run.Iterations != 1 || !strings.Contains(o.stopReason, "lists nothing left to migrate") {
+		t.Errorf("ran %d iterations, stopped with %q; want 1 and the migration done", o.run.Iterations, o.stopReason)
+	}
+}
+
+func TestRunPartition(t *testing.T) {
+	h := newHarness(t)
+	h.git = fake.NewGit(filepath.Join(h.dir, "repo"), "acme", "widgets", "go.mod", "api/api.go", "db/db.go", "web/web.go")
+	h.github = fake.NewGitHub(h.git, "acme", "widgets")
+	h.claude = fake.NewClaude(h.git, fake.Turn{Edits: []string{"db/db.go"}}, fake.Turn{Edits: []string{"web/web.go"}}, fake.Turn{Edits: []string{"db/db.go"}})
+	h.cfg.MaxRuns = 3
+	h.cfg.Partition = "dir"
+
+	// Another worker is on the API
+	dir, err := state.ChunksDir("acme", "widgets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := worktree.OpenChunks(dir)
+	if err != nil || !other.Claim("api") {
+		t.Fatalf("failed to claim api for another worker: %v", err)
+	}
+	h.run()
+
+	var chunks []string
+	for _, prompt := range h.claude.Prompts {
+		_, rest, _ := strings.Cut(prompt, "This iteration's part is `")
+		chunk, _, _ := strings.Cut(rest, "`")
+		chunks = append(chunks, chunk)
+	}
+	if want := []string{"db", "web", "db"}; !reflect.DeepEqual(chunks, want) {
+		t.Errorf("iterations worked on %v, want %v", chunks, want)
+	}
+	if !other.Claim("db") || !other.Claim("web") {
+		t.Error("the run didn't release its chunks when it ended")
+	}
+	other.Release()
+}
+
+func TestRunStopsOnCompletionSignal(t *testing.T) {
+	done := fake.Turn{Output: "All done. DEEP_CLAUDE_PROJECT_COMPLETE"}
+	h := newHarness(t, done, done, done, done)
+	h.cfg.CompletionThreshold = 2
+	o := h.run()
+
+	if o.run.Iterations != 2 || o.stopReason != "project completion signal detected" {
+		t.Errorf("stopped after %d iterations (%q), want 2 on the completion signal", o.run.Iterations, o.stopReason)
+	}
+	if len(h.claude.Prompts) != 2 {
+		t.Errorf("Claude was prompted %d times, want 2", len(h.claude.Prompts))
+	}
+}
+
+func TestRunCompletionSignalInSummary(t *testing.T) {
+	quoted := fake.Turn{Output: "Not done, so no DEEP_CLAUDE_PROJECT_COMPLETE yet.\n\nSTATUS: in progress"}
+	done := fake.Turn{Output: "Coverage is at 95%.\n\nSTATUS: ALL TESTS WRITTEN"}
+	h := newHarness(t, quoted, done, quoted, done, done)
+	h.cfg.MaxRuns = 6
+	h.cfg.CompletionThreshold = 2
+	h.cfg.CompletionInSummary = true
+	h.cfg.CompletionPattern = `^ALL \w+ WRITTEN$`
+	o := h.run()
+
+	if o.run.Iterations != 5 || o.stopReason != "project completion signal detected" {
+		t.Errorf("stopped after %d iterations (%q), want 5 on the completion signal", o.run.Iterations, o.stopReason)
+	}
+	if prompt := h.claude.Prompts[0]; !strings.Contains(prompt, `"STATUS: DEEP_CLAUDE_PROJECT_COMPLETE"`) {
+		t.Errorf("prompt doesn't ask for the status line:\n%s", prompt)
+	}
+}
+
+func TestRunContinuesAfterFailedIterations(t *testing.T) {
+	h := newHarness(t,
+		fake.Turn{Err: errors.New("process exited with status 1")},
+		fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Push fails"},
+		fake.Turn{Edits: []string{"main_test.go"}, CommitMessage: "Ships"},
+	)
+	h.git.PushErrors = []error{errors.New("remote rejected")}
+	o := h.run()
+
+	if titles := h.titles(); !reflect.DeepEqual(titles, []string{"Ships"}) {
+		t.Errorf("merged into main = %v, want [Ships]", titles)
+	}
+	if o.failures["claude"] != 1 || o.failures["push"] != 1 {
+		t.Errorf("failures = %v, want one claude and one push failure", o.failures)
+	}
+
+	failed, err := events.Read(filepath.Join(o.runDir, events.FileName), events.IterationFailed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 2 {
+		t.Errorf("logged %d iteration_failed events, want 2", len(failed))
+	}
+}
+
+// cancelingClaude cancels the run once Claude has finished its first turn.
+type cancelingClaude struct {
+	*fake.Claude
+	cancel context.CancelFunc
+}
+
+func (c *cancelingClaude) Run(ctx context.Context, prompt string) (*claude.Result, error) {
+	defer c.cancel()
+	return c.Claude.Run(ctx, prompt)
+}
+
+func TestRunStopsWhenCanceled(t *testing.T) {
+	h := newHarness(t,
+		fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Handle empty input"},
+		fake.Turn{Edits: []string{"main_test.go"}, CommitMessage: "Add a test"},
+		fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Tidy up"},
+	)
+	h.github.Statuses = []*github.PRStatus{fake.Passing()}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	o := h.runWith(ctx, &cancelingClaude{Claude: h.claude, cancel: cancel})
+
+	if len(h.claude.Prompts) != 1 {
+		t.Errorf("Claude ran %d times, want 1", len(h.claude.Prompts))
+	}
+	if o.stopReason != "interrupted" {
+		t.Errorf("stopReason = %q, want interrupted", o.stopReason)
+	}
+	if len(o.prs) != 1 || o.prs[0].Outcome != "open: interrupted while waiting for checks" {
+		t.Errorf("prs = %+v, want the first PR left open", o.prs)
+	}
+	if o.run.Finished {
+		t.Error("an interrupted run should be resumable")
+	}
+}
+
+func TestRunNotifiesSubscribers(t *testing.T) {
+	h := newHarness(t,
+		fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Handle empty input"},
+		fake.Turn{Edits: []string{"main_test.go"}, CommitMessage: "Break the build"},
+	)
+	h.github.Statuses = []*github.PRStatus{fake.Passing(), fake.Failing()}
+	var got []string
+	h.subscriber = func(e events.Event) {
+		if e.Type != events.PRChecks {
+			got = append(got, e.Type)
+		}
+	}
+	o := h.run()
+
+	want := []string{
+		events.RunStarted,
+		events.IterationStarted, events.ClaudeFinished, events.CommitCreated, events.PRCreated, events.PRMerged,
+		events.IterationStarted, events.ClaudeFinished, events.CommitCreated, events.PRCreated, events.PRClosed,
+		events.RunFinished,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("subscriber got %v, want %v", got, want)
+	}
+	written, err := events.Read(filepath.Join(o.runDir, events.FileName), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) < len(want) {
+		t.Errorf("event log has %d events, want at least %d", len(written), len(want))
+	}
+}
+
+// answeringClaude queues an answer in the run's inbox after each blocked
+// response.
+type answeringClaude struct {
+	*fake.Claude
+	inbox *inbox.Inbox
+}
+
+func (c *answeringClaude) Run(ctx context.Context, prompt string) (*claude.Result, error) {
+	result, err := c.Claude.Run(ctx, prompt)
+	if _, blocked := claude.Blocked(result.Output); blocked {
+		if _, err := c.inbox.Add("Use the sandbox key in .env.example"); err != nil {
+			return nil, err
+		}
+	}
+	return result, err
+}
+
+func TestRunWaitsWhenBlocked(t *testing.T) {
+	blocked := fake.Turn{Output: "I need credentials.\n\nSTATUS: BLOCKED: need a STRIPE_API_KEY"}
+
+	t.Run("answered", func(t *testing.T) {
+		h := newHarness(t, blocked, fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Charge cards"})
+		var got []events.Event
+		h.subscriber = func(e events.Event) {
+			if e.Type == events.RunBlocked || e.Type == events.RunUnblocked {
+				got = append(got, e)
+			}
+		}
+		box := inbox.New(filepath.Join(h.dir, promptsDir))
+		o := h.runWith(t.Context(), &answeringClaude{Claude: h.claude, inbox: box})
+
+		if o.run.Iterations != 2 || !o.run.Finished {
+			t.Errorf("ran %d iterations (finished %v), want 2 to the end", o.run.Iterations, o.run.Finished)
+		}
+		if len(got) != 2 || got[0].Data["reason"] != "need a STRIPE_API_KEY" || got[1].Type != events.RunUnblocked {
+			t.Errorf("events = %+v, want blocked then unblocked", got)
+		}
+		if !strings.Contains(h.claude.Prompts[1], "Use the sandbox key in .env.example") {
+			t.Error("the answer should be in the next prompt")
+		}
+		if titles := h.titles(); !reflect.DeepEqual(titles, []string{"Charge cards"}) {
+			t.Errorf("merged %v", titles)
+		}
+	})
+
+	t.Run("no wait", func(t *testing.T) {
+		h := newHarness(t, blocked, blocked, blocked)
+		h.cfg.BlockedWait = 0
+		h.cfg.PublishSummary = "comment"
+		h.cfg.SummaryIssue = "#7"
+		o := h.run()
+
+		if o.run.Iterations != 1 || o.stopReason != "blocked: need a STRIPE_API_KEY" {
+			t.Errorf("stopped after %d iterations (%q), want 1 on the blocked signal", o.run.Iterations, o.stopReason)
+		}
+		if o.run.Finished {
+			t.Error("a blocked run should be resumable")
+		}
+		if comments := h.github.Comments(); len(comments) == 0 || !strings.Contains(comments[0], "need a STRIPE_API_KEY") {
+			t.Errorf("comments = %q, want a blocked notice", comments)
+		}
+	})
+}
+
+func TestRunRedeliversInstructionsAfterFailure(t *testing.T) {
+	h := newHarness(t,
+		fake.Turn{Err: errors.New("claude crashed")},
+		fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Use the sandbox key"},
+	)
+	box := inbox.New(filepath.Join(h.dir, promptsDir))
+	if _, err := box.Add("Use the sandbox key in .env.example"); err != nil {
+		t.Fatal(err)
+	}
+	h.run()
+
+	if len(h.claude.Prompts) != 2 {
+		t.Fatalf("claude ran %d times, want 2", len(h.claude.Prompts))
+	}
+	for i, prompt := range h.claude.Prompts {
+		if !strings.Contains(prompt, "Use the sandbox key in .env.example") {
+			t.Errorf("prompt %d lacks the instruction", i+1)
+		}
+	}
+	if pending, err := box.Pending(); err != nil || len(pending) != 0 {
+		t.Errorf("pending = %v (%v), want the instruction archived once acted on", pending, err)
+	}
+}
+
+func TestRunMergeConfidence(t *testing.T) {
+	turn := func(title, confidence string) fake.Turn {
+		return fake.Turn{Edits: []string{"main.go"}, CommitMessage: title, Output: "Done.\nCONFIDENCE: " + confidence}
+	}
+	h := newHarness(t,
+		turn("Sure thing", "0.9"),
+		turn("Probably fine", "0.6"),
+		fake.Turn{Edits: []string{"main_test.go"}, CommitMessage: "Unrated"},
+		turn("Wild guess", "0.2"),
+	)
+	h.cfg.MaxRuns = 5
+	h.cfg.MergeConfidence = 0.8
+	h.cfg.BlockedWait = 0
+	o := h.run()
+
+	if titles := h.titles(); !reflect.DeepEqual(titles, []string{"Sure thing"}) {
+		t.Errorf("merged %v, want only the confident PR", titles)
+	}
+	var drafts []string
+	for _, pr := range h.github.PRs() {
+		if pr.IsDraft {
+			drafts = append(drafts, pr.Title)
+		}
+	}
+	if !reflect.DeepEqual(drafts, []string{"Probably fine", "Unrated"}) {
+		t.Errorf("drafts = %v", drafts)
+	}
+	if len(o.prs) != 3 || o.prs[1].Outcome != "open: draft, confidence 60%" || o.prs[2].Outcome != "open: draft, no confidence given" {
+		t.Errorf("prs = %+v", o.prs)
+	}
+	if !strings.HasPrefix(o.stopReason, `blocked: not confident enough to open a PR for "Wild guess" (confidence 20%)`) {
+		t.Errorf("stopReason = %q", o.stopReason)
+	}
+	if !strings.Contains(h.claude.Prompts[0], "CONFIDENCE: <0.0-1.0>") {
+		t.Error("prompt should ask for a confidence")
+	}
+}
+
+func TestRunRevertsMergeThatBreaksMain(t *testing.T) {
+	grace := mainCheckGrace
+	mainCheckGrace = 0
+	t.Cleanup(func() { mainCheckGrace = grace })
+
+	for _, mode := range []string{"pr", "push"} {
+		t.Run(mode, func(t *testing.T) {
+			h := newHarness(t,
+				fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Add parser"},
+				fake.Turn{Edits: []string{"main_test.go"}, CommitMessage: "Test parser"},
+			)
+			h.cfg.RevertOnMainFailure = mode
+			h.github.MainStatuses = []*github.PRStatus{fake.Failing()}
+			var broken []events.Event
+			h.subscriber = func(e events.Event) {
+				if e.Type == events.MainBroken {
+					broken = append(broken, e)
+				}
+			}
+			o := h.run()
+
+			if len(broken) != 1 || broken[0].Data["checks"] != "test" {
+				t.Fatalf("main_broken events = %+v, want one for the failed test check", broken)
+			}
+			if !strings.Contains(h.claude.Prompts[1], "BROKEN MAIN BRANCH") || !strings.Contains(h.claude.Prompts[1], `PR #1 "Add parser"`) {
+				t.Error("the next prompt should say which merge broke main")
+			}
+			if strings.Contains(h.claude.Prompts[0], "BROKEN MAIN BRANCH") {
+				t.Error("the first prompt has nothing to report")
+			}
+
+			switch mode {
+			case "pr":
+				prs := h.github.PRs()
+				if len(prs) != 3 || prs[1].Title != `Revert "Add parser"` || prs[1].State != "OPEN" {
+					t.Fatalf("PRs = %+v, want an open revert PR between the iterations", prs)
+				}
+				if o.prs[0].Outcome != "merged: broke main, revert PR #2 opened" {
+					t.Errorf("outcome = %q", o.prs[0].Outcome)
+				}
+			case "push":
+				var titles []string
+				for _, c := range h.git.RemoteLog("main")[1:] {
+					titles = append(titles, c.Title())
+				}
+				if !reflect.DeepEqual(titles, []string{"Add parser", `Revert "Add parser"`, "Test parser"}) {
+					t.Errorf("main = %v, want the merge reverted before the next one", titles)
+				}
+				if o.prs[0].Outcome != "merged: broke main, reverted on main" {
+					t.Errorf("outcome = %q", o.prs[0].Outcome)
+				}
+			}
+		})
+	}
+}
+
+func TestRunWaitsForDeploy(t *testing.T) {
+	interval := deployPollInterval
+	deployPollInterval = time.Millisecond
+	t.Cleanup(func() { deployPollInterval = interval })
+
+	smoke := func(state string) *github.PRStatus {
+		return &github.PRStatus{Checks: []github.PRCheck{{Name: "smoke", State: state}}}
+	}
+	tests := []struct {
+		name        string
+		environment string
+		check       string
+		deployments []string
+		statuses    []*github.PRStatus
+		wantOutcome string
+	}{
+		{name: "deployment fails", environment: "staging", deployments: []string{"failure"}, wantOutcome: "merged: deployment to staging failed"},
+		{name: "deployment never finishes", environment: "staging", deployments: []string{"in_progress"}, wantOutcome: "merged: deployment to staging did not finish"},
+		{name: "smoke check fails", check: "smoke", statuses: []*github.PRStatus{smoke("FAILURE")}, wantOutcome: "merged: check smoke failed"},
+		{name: "both pass", environment: "staging", check: "smoke", statuses: []*github.PRStatus{smoke("SUCCESS")}, wantOutcome: "merged"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Add parser"})
+			h.cfg.DeployEnvironment = tt.environment
+			h.cfg.DeployCheck = tt.check
+			h.cfg.DeployTimeout = 50 * time.Millisecond
+			h.github.Deployments = tt.deployments
+			h.github.MainStatuses = tt.statuses
+			var failed, deployed []events.Event
+			h.subscriber = func(e events.Event) {
+				switch e.Type {
+				case events.IterationFailed:
+					failed = append(failed, e)
+				case events.DeployFinished:
+					deployed = append(deployed, e)
+				}
+			}
+			o := h.run()
+
+			if !strings.HasPrefix(o.prs[0].Outcome, tt.wantOutcome) {
+				t.Errorf("outcome = %q, want %q", o.prs[0].Outcome, tt.wantOutcome)
+			}
+			if len(deployed) != 1 || deployed[0].Data["success"] != (tt.wantOutcome == "merged") {
+				t.Errorf("deploy_finished events = %+v", deployed)
+			}
+			if wantFailed := tt.wantOutcome != "merged"; (len(failed) == 1) != wantFailed {
+				t.Errorf("iteration_failed events = %+v, want the iteration failed: %v", failed, wantFailed)
+			}
+		})
+	}
+}
+
+func TestRunPublishesCheckRun(t *testing.T) {
+	h := newHarness(t,
+		fake.Turn{Edits: []string{"api/handler.go", "main.go"}, CommitMessage: "feat: add handler", Output: "Done.\nCONFIDENCE: 0.9"},
+		fake.Turn{Edits: []string{"api/handler_test.go"}, CommitMessage: "test: cover handler", Output: "Done.\nCONFIDENCE: 0.5"},
+	)
+	h.cfg.CheckRun = true
+	h.cfg.Paths = []string{"api"}
+	if err := os.Mkdir(filepath.Join(h.dir, "api"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	h.cfg.CommitConvention = "conventional"
+	h.cfg.MergeConfidence = 0.8
+	h.run()
+
+	runs := h.github.CheckRuns()
+	if len(runs) != 2 {
+		t.Fatalf("published %d check runs, want one per PR", len(runs))
+	}
+	first, second := runs[0], runs[1]
+	if first.Name != "deep-claude" || first.Conclusion != "neutral" || first.Title != "3 of 4 gates passed" {
+		t.Errorf("first check run = %q %q %q, want neutral with 3 of 4 gates passed", first.Name, first.Conclusion, first.Title)
+	}
+	if len(first.Annotations) != 1 || first.Annotations[0].Path != "main.go" {
+		t.Errorf("annotations = %+v, want main.go changed outside --path", first.Annotations)
+	}
+	if !strings.Contains(first.Summary, "| Confidence | ✅ passed | confidence 90%") {
+		t.Errorf("summary = %s", first.Summary)
+	}
+	if second.Title != "3 of 4 gates passed" || !strings.Contains(second.Summary, "| Scope | ✅ passed") || !strings.Contains(second.Summary, "opened as a draft") {
+		t.Errorf("second check run = %q\n%s", second.Title, second.Summary)
+	}
+	if head := h.git.RemoteLog(h.github.PRs()[1].HeadRefName); len(head) == 0 || second.HeadSHA != head[len(head)-1].SHA {
+		t.Error("the check run should be on the head of the PR branch")
+	}
+}
+
+func TestRunSingleBranch(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []*github.PRStatus
+		state    string
+		outcome  string
+	}{
+		{name: "checks pass", state: "MERGED", outcome: "merged"},
+		{name: "checks fail", statuses: []*github.PRStatus{fake.Failing()}, state: "OPEN", outcome: "open: checks failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t,
+				fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Handle empty input"},
+				fake.Turn{},
+				fake.Turn{Edits: []string{"main_test.go"}, CommitMessage: "Test empty input"},
+			)
+			h.cfg.MaxRuns = 3
+			h.cfg.BranchMode = "single"
+			h.github.Statuses = tt.statuses
+			o := h.run()
+
+			prs := h.github.PRs()
+			if len(prs) != 1 {
+				t.Fatalf("opened %d PRs, want one for the whole run", len(prs))
+			}
+			pr := prs[0]
+			if pr.State != tt.state || o.prs[0].Outcome != tt.outcome {
+				t.Errorf("PR is %s with outcome %q, want %s and %q", pr.State, o.prs[0].Outcome, tt.state, tt.outcome)
+			}
+			if pr.Title != "Test empty input (+1 earlier iterations)" || !strings.Contains(pr.Body, "Handle empty input") || !strings.Contains(pr.Body, "Test empty input") {
+				t.Errorf("PR should describe every iteration's commit: %q\n%s", pr.Title, pr.Body)
+			}
+			if branch, _ := h.git.CurrentBranch(); branch != "main" {
+				t.Errorf("run ended on %s, want main", branch)
+			}
+		})
+	}
+}
+
+func TestRunSquashesCommits(t *testing.T) {
+	for _, squash := range []bool{false, true} {
+		t.Run(fmt.Sprintf("squash=%v", squash), func(t *testing.T) {
+			h := newHarness(t, fake.Turn{
+				Edits:         []string{"parser.go", "parser_test.go", "README.md"},
+				Commits:       []string{"wip: parser", "wip: tests"},
+				CommitMessage: "Add a parser\n\nParses the config file.",
+			})
+			h.cfg.SquashCommits = squash
+			// Leave the PR open to look at its branch
+			h.github.Statuses = []*github.PRStatus{fake.ChangesRequested()}
+			h.run()
+
+			commits := h.git.RemoteLog(h.github.PRs()[0].HeadRefName)[1:]
+			if !squash {
+				if len(commits) != 3 {
+					t.Errorf("pushed %d commits, want Claude's 3", len(commits))
+				}
+				return
+			}
+			if len(commits) != 1 {
+				t.Fatalf("pushed %d commits, want them squashed into one", len(commits))
+			}
+			want := "Add a parser\n\nParses the config file.\n\nSquashed commits:\n- wip: parser\n- wip: tests"
+			if commits[0].Message != want || len(commits[0].Files) != 3 {
+				t.Errorf("squashed commit = %q with %v, want %q with all 3 files", commits[0].Message, commits[0].Files, want)
+			}
+		})
+	}
+}
+
+func TestRunSetsCommitStatuses(t *testing.T) {
+	h := newHarness(t,
+		fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Handle empty input"},
+		fake.Turn{Edits: []string{"main_test.go"}, CommitMessage: "Break the build", IsError: true},
+	)
+	h.github.Statuses = []*github.PRStatus{fake.Passing(), fake.Failing()}
+	h.cfg.CommitStatuses = true
+	h.run()
+
+	var got []string
+	for _, s := range h.github.CommitStatuses() {
+		got = append(got, fmt.Sprintf("%s %s: %s", s.Context, s.State, s.Description))
+	}
+	want := []string{
+		"deep-claude/gates success: 1 of 1 gates passed",
+		"deep-claude/checks pending: Waiting for checks",
+		"deep-claude/merge pending: Merges once its checks pass",
+		"deep-claude/checks success: All checks passed",
+		"deep-claude/merge success: Merged",
+		"deep-claude/gates failure: 0 of 1 gates passed; failed: Claude",
+		"deep-claude/checks pending: Waiting for checks",
+		"deep-claude/merge pending: Merges once its checks pass",
+		"deep-claude/checks failure: Checks failed",
+		"deep-claude/merge failure: Closed: checks failed",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("commit statuses:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	statuses := h.github.CommitStatuses()
+	if first, second := statuses[0].SHA, statuses[len(statuses)-1].SHA; first == "" || first != statuses[4].SHA || first == second {
+		t.Error("each PR's statuses should be on the commit it was opened at")
+	}
+}
+
+// commentingClaude has reviewers comment on the run's PRs after each turn.
+type commentingClaude struct {
+	*fake.Claude
+	github *fake.GitHub
+	// after maps a turn, from 1, to the comments left after it
+	after map[int][]github.Comment
+	turns int
+}
+
+func (c *commentingClaude) Run(ctx context.Context, prompt string) (*claude.Result, error) {
+	result, err := c.Claude.Run(ctx, prompt)
+	c.turns++
+	for _, comment := range c.after[c.turns] {
+		c.github.AddComment("1", comment.Author, comment.Association, comment.Body)
+	}
+	return result, err
+}
+
+func TestRunAmendsFixes(t *testing.T) {
+	tests := []struct {
+		policy  string
+		commits []string
+	}{
+		{policy: "stack", commits: []string{"Add retries", "Retry on timeouts too"}},
+		{policy: "amend-ci", commits: []string{"Add retries"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			h := newHarness(t,
+				fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Add retries"},
+				fake.Turn{Edits: []string{"main_test.go"}, CommitMessage: "Retry on timeouts too"},
+			)
+			h.cfg.MaxRuns = 2
+			h.cfg.CommentCommands = true
+			h.cfg.FixCommits = tt.policy
+			// The checks are still running when the iteration looks, have
+			// failed by the time of the fix, and pass once it's pushed
+			status := &github.PRStatus{HasPendingChecks: true}
+			h.github.Statuses = []*github.PRStatus{status}
+			h.subscriber = func(e events.Event) {
+				if _, ok := e.Data["follow_up"]; !ok {
+					return
+				}
+				switch e.Type {
+				case events.IterationStarted:
+					*status = *fake.Failing()
+				case events.CommitCreated:
+					*status = *fake.Passing()
+				}
+			}
+			o := h.runWith(t.Context(), &commentingClaude{Claude: h.claude, github: h.github, after: map[int][]github.Comment{
+				1: {{Author: "alice", Association: "MEMBER", Body: "/deep-claude fix the flaky test"}},
+			}})
+
+			if !strings.Contains(h.claude.Prompts[1], "These checks are failing on the pull request: test.") {
+				t.Errorf("follow-up prompt should name the failing checks:\n%s", h.claude.Prompts[1])
+			}
+			var titles []string
+			for _, c := range h.git.Log(h.github.PRs()[0].HeadRefName)[1:] {
+				titles = append(titles, c.Title())
+			}
+			if !reflect.DeepEqual(titles, tt.commits) {
+				t.Errorf("PR commits = %v, want %v", titles, tt.commits)
+			}
+			if o.prs[0].Outcome != "merged" {
+				t.Errorf("outcome = %q, want the fixed PR merged", o.prs[0].Outcome)
+			}
+		})
+	}
+}
+
+func TestRunFailedAmendReturnsHome(t *testing.T) {
+	h := newHarness(t,
+		fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Add retries"},
+		fake.Turn{Edits: []string{"main_test.go"}, CommitMessage: "Retry on timeouts too"},
+	)
+	h.cfg.MaxRuns = 2
+	h.cfg.CommentCommands = true
+	h.cfg.FixCommits = "amend"
+	h.git.AmendErr = errors.New("index.lock exists")
+	// The checks are still running, so the PR is open for the follow-up
+	h.github.Statuses = []*github.PRStatus{{HasPendingChecks: true}}
+	var failure, branch string
+	h.subscriber = func(e events.Event) {
+		if e.Type == events.IterationFailed {
+			failure, _ = e.Data["error"].(string)
+			branch, _ = h.git.CurrentBranch()
+		}
+	}
+	h.runWith(t.Context(), &commentingClaude{Claude: h.claude, github: h.github, after: map[int][]github.Comment{
+		1: {{Author: "alice", Association: "MEMBER", Body: "/deep-claude fix the flaky test"}},
+	}})
+
+	if !strings.HasPrefix(failure, "failed to create commit: index.lock exists") {
+		t.Errorf("follow-up failed with %q, want the amend's error wrapped", failure)
+	}
+	if branch != "main" {
+		t.Errorf("failed follow-up left %q checked out, want main", branch)
+	}
+}
+
+func TestRunCommentCommands(t *testing.T) {
+	h := newHarness(t,
+		fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Add retries"},
+		fake.Turn{Edits: []string{"main_test.go"}, CommitMessage: "Cap retries at three"},
+		fake.Turn{Output: "It retries failed requests up to three times."},
+		fake.Turn{Edits: []string{"README.md"}, CommitMessage: "Document retries"},
+	)
+	h.cfg.MaxRuns = 3
+	h.cfg.CommentCommands = true
+	h.github.Statuses = []*github.PRStatus{fake.ChangesRequested()}
+	var got []events.Event
+	h.subscriber = func(e events.Event) {
+		if e.Type == events.CommandReceived {
+			got = append(got, e)
+		}
+	}
+	o := h.runWith(t.Context(), &commentingClaude{Claude: h.claude, github: h.github, after: map[int][]github.Comment{
+		1: {
+			{Author: "mallory", Association: "NONE", Body: "/deep-claude close"},
+			{Author: "alice", Association: "MEMBER", Body: "Close, but:\n/deep-claude fix cap retries at three"},
+		},
+		2: {
+			{Author: "alice", Association: "MEMBER", Body: "/deep-claude explain"},
+			{Author: "bob", Association: "COLLABORATOR", Body: "/deep-claude close superseded"},
+		},
+	}})
+
+	if len(got) != 3 || got[0].Data["command"] != "fix" || got[1].Data["command"] != "explain" || got[2].Data["command"] != "close" {
+		t.Errorf("commands = %+v, want fix, explain and close from members", got)
+	}
+	prs := h.github.PRs()
+	if len(prs) != 2 || prs[0].State != "CLOSED" {
+		t.Fatalf("PRs = %+v, want #1 closed and one more opened", prs)
+	}
+	if !strings.Contains(h.claude.Prompts[1], "cap retries at three") {
+		t.Errorf("follow-up prompt = %s", h.claude.Prompts[1])
+	}
+	if o.prs[0].Outcome != "closed: by @bob" {
+		t.Errorf("outcome = %q", o.prs[0].Outcome)
+	}
+
+	replies := strings.Join(h.github.Comments(), "\n---\n")
+	for _, want := range []string{"Sorry @mallory", "On it", ": Cap retries at three", "It retries failed requests up to three times.", "Closing this PR"} {
+		if !strings.Contains(replies, want) {
+			t.Errorf("replies should contain %q:\n%s", want, replies)
+		}
+	}
+	if n := len(h.github.Comments()); n != 5 {
+		t.Errorf("posted %d replies, want one per command and one for the push", n)
+	}
+}
+
+func TestRunEscalatesUnreviewedPR(t *testing.T) {
+	interval := reviewPollInterval
+	reviewPollInterval = time.Millisecond
+	t.Cleanup(func() { reviewPollInterval = interval })
+
+	t.Run("approved after re-request", func(t *testing.T) {
+		h := newHarness(t, fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Add retries"})
+		h.cfg.ReviewWait = 20 * time.Millisecond
+		h.cfg.PublishSummary = "comment"
+		h.cfg.SummaryIssue = "#7"
+		h.github.Statuses = []*github.PRStatus{fake.ReviewRequired()}
+		h.github.OnReRequestReview = func(string) { h.github.Statuses[0] = fake.Passing() }
+		h.run()
+
+		if got := h.github.ReRequested(); !reflect.DeepEqual(got, []string{"1"}) {
+			t.Errorf("re-requested review of %v, want PR #1", got)
+		}
+		if titles := h.titles(); !reflect.DeepEqual(titles, []string{"Add retries"}) {
+			t.Errorf("merged %v, want the PR once approved", titles)
+		}
+		if comments := h.github.Comments(); len(comments) == 0 || !strings.Contains(comments[0], "re-requested review from @octocat") {
+			t.Errorf("notices = %q", comments)
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		h := newHarness(t, fake.Turn{Edits: []string{"main.go"}}, fake.Turn{Edits: []string{"main_test.go"}})
+		h.cfg.ReviewWait = 5 * time.Millisecond
+		h.cfg.OnReviewTimeout = "stop"
+		h.github.Statuses = []*github.PRStatus{fake.ReviewRequired()}
+		var got []string
+		h.subscriber = func(e events.Event) {
+			if e.Type == events.ReviewEscalated || e.Type == events.ReviewTimedOut {
+				got = append(got, e.Type)
+			}
+		}
+		o := h.run()
+
+		if o.run.Iterations != 1 || !strings.HasPrefix(o.stopReason, "PR #1 was not reviewed within") {
+			t.Errorf("stopped after %d iterations (%q), want 1 on the unreviewed PR", o.run.Iterations, o.stopReason)
+		}
+		if o.prs[0].Outcome != "open: review timed out" {
+			t.Errorf("outcome = %q", o.prs[0].Outcome)
+		}
+		if !reflect.DeepEqual(got, []string{events.ReviewEscalated, events.ReviewTimedOut}) {
+			t.Errorf("events = %v, want escalated then timed out", got)
+		}
+	})
+
+	t.Run("continue", func(t *testing.T) {
+		h := newHarness(t, fake.Turn{Edits: []string{"main.go"}}, fake.Turn{Edits: []string{"main_test.go"}, CommitMessage: "Test retries"})
+		h.cfg.ReviewWait = 5 * time.Millisecond
+		h.github.Statuses = []*github.PRStatus{fake.ReviewRequired()}
+		o := h.run()
+
+		if o.run.Iterations != 2 || o.prs[0].Outcome != "open: review timed out" || o.prs[1].Outcome != "merged" {
+			t.Errorf("ran %d iterations with outcomes %+v, want the second PR merged", o.run.Iterations, o.prs)
+		}
+	})
+}
+
+func TestRunRedactsSecrets(t *testing.T) {
+	const secret = "s3cr3t-deploy-value"
+	h := newHarness(t, fake.Turn{
+		Output:        "Deployed with DEPLOY_KEY=" + secret,
+		Edits:         []string{"main.go"},
+		CommitMessage: "Deploy with " + secret + "\n\nThe key is " + secret + ".",
+	})
+	t.Setenv("DEPLOY_KEY", secret)
+	h.cfg.RedactEnv = []string{"DEPLOY_KEY"}
+	var leaked []string
+	h.subscriber = func(e events.Event) {
+		if strings.Contains(fmt.Sprint(e.Data), secret) {
+			leaked = append(leaked, e.Type)
+		}
+	}
+	o := h.run()
+
+	if len(leaked) > 0 {
+		t.Errorf("events %v leaked the secret", leaked)
+	}
+	pr := h.github.PRs()[0]
+	if strings.Contains(pr.Title+pr.Body, secret) || !strings.Contains(pr.Title, "[REDACTED]") {
+		t.Errorf("PR = %q\n%s\nwant the secret redacted", pr.Title, pr.Body)
+	}
+	for _, name := range []string{events.FileName, audit.FileName, filepath.Join(replay.TranscriptsDir, "iteration-001.md")} {
+		data, err := os.ReadFile(filepath.Join(o.runDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), secret) {
+			t.Errorf("%s leaked the secret:\n%s", name, data)
+		}
+	}
+}
+
+func TestRunControl(t *testing.T) {
+	interval := controlPollInterval
+	controlPollInterval = time.Millisecond
+	t.Cleanup(func() { controlPollInterval = interval })
+
+	// request asks for action once the first iteration has started
+	request := func(t *testing.T, action string, got *[]string) events.Subscriber {
+		return func(e events.Event) {
+			dir, err := state.RunDir(e.RunID)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			switch e.Type {
+			case events.IterationStarted:
+				if e.Iteration == 1 {
+					if err := control.Request(dir, action); err != nil {
+						t.Error(err)
+					}
+				}
+			case events.RunPaused:
+				*got = append(*got, e.Type)
+				// Resume right away
+				_ = control.Request(dir, "")
+			case events.RunResumed:
+				*got = append(*got, e.Type)
+			}
+		}
+	}
+
+	t.Run("stop", func(t *testing.T) {
+		h := newHarness(t, fake.Turn{Edits: []string{"main.go"}}, fake.Turn{Edits: []string{"main_test.go"}})
+		var got []string
+		h.subscriber = request(t, control.Stop, &got)
+		o := h.run()
+
+		if o.run.Iterations != 1 || o.stopReason != stopRequested {
+			t.Errorf("stopped after %d iterations (%q), want 1 on request", o.run.Iterations, o.stopReason)
+		}
+		if control.Requested(o.runDir) != "" || control.Active(o.runDir) {
+			t.Error("the stop request and the claim should be gone once the run stopped")
+		}
+	})
+
+	t.Run("pause", func(t *testing.T) {
+		h := newHarness(t, fake.Turn{Edits: []string{"main.go"}}, fake.Turn{Edits: []string{"main_test.go"}})
+		var got []string
+		h.subscriber = request(t, control.Pause, &got)
+		o := h.run()
+
+		if o.run.Iterations != 2 {
+			t.Errorf("ran %d iterations, want 2 after resuming", o.run.Iterations)
+		}
+		if !reflect.DeepEqual(got, []string{events.RunPaused, events.RunResumed}) {
+			t.Errorf("events = %v, want paused then resumed", got)
+		}
+	})
+}
+
+// stalledClaude looks at the run's state file while Claude works.
+type stalledClaude struct {
+	ClaudeRunner
+	runID string
+	seen  *state.RunState
+}
+
+func (c *stalledClaude) Run(ctx context.Context, prompt string) (*claude.Result, error) {
+	time.Sleep(20 * time.Millisecond)
+	c.seen, _ = state.LoadRunState(c.runID)
+	return c.ClaudeRunner.Run(ctx, prompt)
+}
+
+func TestRunHeartbeat(t *testing.T) {
+	interval := heartbeatInterval
+	heartbeatInterval = time.Millisecond
+	t.Cleanup(func() { heartbeatInterval = interval })
+
+	h := newHarness(t, fake.Turn{Edits: []string{"main.go"}})
+	h.cfg.RunID = "20250115-143000-beat"
+	c := &stalledClaude{ClaudeRunner: h.claude, runID: h.cfg.RunID}
+	h.runWith(t.Context(), c)
+
+	if c.seen == nil || c.seen.ClaudeSince.IsZero() || c.seen.Liveness(time.Now(), time.Hour) != state.Running {
+		t.Errorf("state while Claude works = %+v, want a running run with Claude busy", c.seen)
+	}
+	after, err := state.LoadRunState(h.cfg.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.Exited || !after.ClaudeSince.IsZero() || after.Liveness(time.Now(), time.Hour) != state.Done {
+		t.Errorf("state after the run = %+v, want it done and exited", after)
+	}
+}
+
+// wedgedClaude hangs on its first request until the iteration is cut
+// short, having spent $0.25 by then.
+type wedgedClaude struct {
+	ClaudeRunner
+	calls int
+}
+
+func (c *wedgedClaude) Run(ctx context.Context, prompt string) (*claude.Result, error) {
+	c.calls++
+	if c.calls == 1 {
+		<-ctx.Done()
+		return &claude.Result{Cost: 0.25, IsError: true}, ctx.Err()
+	}
+	return c.ClaudeRunner.Run(ctx, prompt)
+}
+
+func TestRunMaxIterationDuration(t *testing.T) {
+	h := newHarness(t, fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Handle empty input", Cost: 0.5})
+	h.cfg.MaxRuns = 2
+	h.cfg.IterationTimeout = 200 * time.Millisecond
+	var failed []events.Event
+	h.subscriber = func(e events.Event) {
+		if e.Type == events.IterationFailed {
+			failed = append(failed, e)
+		}
+	}
+	o := h.runWith(t.Context(), &wedgedClaude{ClaudeRunner: h.claude})
+
+	if len(failed) != 1 || failed[0].Iteration != 1 || failed[0].Data["timed_out"] != true {
+		t.Errorf("iteration_failed events = %+v, want iteration 1 timed out", failed)
+	}
+	if got := h.titles(); !reflect.DeepEqual(got, []string{"Handle empty input"}) {
+		t.Errorf("merged %q, want the second iteration's work", got)
+	}
+
+	// The cut-short request's cost still counts
+	if o.run.TotalCost != 0.75 {
+		t.Errorf("total cost = %v, want 0.75", o.run.TotalCost)
+	}
+	var outcomes []string
+	for _, it := range o.run.History {
+		outcomes = append(outcomes, fmt.Sprintf("%d %s $%.2f", it.Number, it.Outcome, it.Cost))
+	}
+	if want := []string{"1 timed out $0.25", "2 succeeded $0.50"}; !reflect.DeepEqual(outcomes, want) {
+		t.Errorf("history = %q, want %q", outcomes, want)
+	}
+}
+
+func TestRunTiming(t *testing.T) {
+	h := newHarness(t, fake.Turn{Edits: []string{"main.go"}})
+	h.cfg.RunID = "20250115-143000-time"
+	o := h.runWith(t.Context(), &stalledClaude{ClaudeRunner: h.claude, runID: h.cfg.RunID})
+
+	if len(o.run.History) != 1 {
+		t.Fatalf("history = %+v, want one iteration", o.run.History)
+	}
+	it := o.run.History[0]
+	var timed time.Duration
+	for _, d := range it.Phases {
+		timed += d
+	}
+	if it.Phases[report.PhaseClaude] < 20*time.Millisecond || it.Phases[report.PhasePush] == 0 || timed > it.Elapsed {
+		t.Errorf("phases %v in %s, want Claude's 20ms and the push, within the iteration", it.Phases, it.Elapsed)
+	}
+}
+
+func TestRunMergeApproval(t *testing.T) {
+	h := newHarness(t, fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Handle empty input"})
+	h.cfg.MergeApproval = true
+	var pending []string
+	h.subscriber = func(e events.Event) {
+		if e.Type == events.MergePending {
+			pending = append(pending, fmt.Sprint(e.Data["number"]))
+		}
+	}
+	o := h.run()
+
+	if got := h.github.PRs()[0].State; got != "OPEN" || o.prs[0].Outcome != "open: awaiting approval" {
+		t.Errorf("PR is %s (%q), want it left open for approval", got, o.prs[0].Outcome)
+	}
+	if !reflect.DeepEqual(pending, []string{"1"}) {
+		t.Errorf("merge_pending events for %v, want PR 1", pending)
+	}
+	if titles := h.titles(); len(titles) != 0 {
+		t.Errorf("merged %v before approval", titles)
+	}
+}
+
+func TestRunLabels(t *testing.T) {
+	h := newHarness(t, fake.Turn{Edits: []string{"main.go"}, CommitMessage: "Use the new auth client"})
+	h.cfg.Labels = []string{"refactor-auth", "q3"}
+	var started []any
+	h.subscriber = func(e events.Event) {
+		if e.Type == events.RunStarted {
+			started = append(started, e.Data["labels"])
+		}
+	}
+	h.run()
+
+	pr := h.github.PRs()[0]
+	if !strings.HasPrefix(pr.HeadRefName, "deep-claude/refactor-auth+q3/") {
+		t.Errorf("branch = %s, want the labels after the prefix", pr.HeadRefName)
+	}
+	if got := h.github.Labels("1"); !reflect.DeepEqual(got, []string{"refactor-auth", "q3"}) {
+		t.Errorf("PR labels = %v", got)
+	}
+	if !reflect.DeepEqual(started, []any{[]string{"refactor-auth", "q3"}}) {
+		t.Errorf("run_started labels = %v", started)
+	}
+	path, _ := state.LedgerPath()
+	entries, err := ledger.Read(path)
+	if err != nil || len(ledger.WithLabel(entries, "q3")) != 1 {
+		t.Errorf("ledger = %+v, %v, want the run under its labels", entries, err)
+	}
+}
@@ -0,0 +1,52 @@
+package orchestrator
+
+import (
+	"context"
+	"maps"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/ledger"
+)
+
+// branchPrefix is the prefix of the run's branches: --git-branch-prefix
+// followed by the run's labels, if any, so branches group by label.
+func (o *Orchestrator) branchPrefix() string {
+	if len(o.config.Labels) == 0 {
+		return o.config.GitBranchPrefix
+	}
+	return o.config.GitBranchPrefix + strings.Join(o.config.Labels, "+") + "/"
+}
+
+// costTags are the run's cost tags with its labels added, so the ledger
+// can be filtered by label.
+func (o *Orchestrator) costTags() map[string]string {
+	if len(o.config.Labels) == 0 {
+		return o.config.CostTags
+	}
+	tags := maps.Clone(o.config.CostTags)
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	tags[ledger.LabelsTag] = strings.Join(o.config.Labels, ",")
+	return tags
+}
+
+// labelPR adds the run's labels to a PR, creating them in the repository
+// the first time.
+func (o *Orchestrator) labelPR(ctx context.Context, prNumber string) {
+	if len(o.config.Labels) == 0 {
+		return
+	}
+	if !o.labelsCreated {
+		for _, label := range o.config.Labels {
+			if err := o.github.CreateLabel(ctx, label); err != nil {
+				o.ui.Warning("Could not label PR #%s: %v", prNumber, err)
+				return
+			}
+		}
+		o.labelsCreated = true
+	}
+	if err := o.github.AddLabels(ctx, prNumber, o.config.Labels); err != nil {
+		o.ui.Warning("Could not label PR #%s: %v", prNumber, err)
+	}
+}
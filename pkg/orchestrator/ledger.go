@@ -0,0 +1,30 @@
+package orchestrator
+
+import (
+	"time"
+
+	"github.com/guzus/deep-claude/internal/ledger"
+	"github.com/guzus/deep-claude/internal/report"
+	"github.com/guzus/deep-claude/internal/state"
+)
+
+// recordCost adds what this process spent on the run to the cost ledger.
+// A resumed run adds an entry per part, so entries can simply be summed.
+func (o *Orchestrator) recordCost(run *report.Run) {
+	path, err := state.LedgerPath()
+	if err == nil {
+		err = ledger.Append(path, ledger.Entry{
+			RunID:      run.RunID,
+			Repo:       o.github.Owner() + "/" + o.github.Repo(),
+			Prompt:     run.Prompt,
+			Started:    o.started.UTC(),
+			Finished:   time.Now().UTC(),
+			Iterations: run.Iterations - o.startIterations,
+			Cost:       run.TotalCost - o.startCost,
+			Tags:       o.costTags(),
+		})
+	}
+	if err != nil {
+		o.ui.Warning("Could not record cost in the ledger: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/guzus/deep-claude/internal/lint"
+)
+
+// defaultLintTask describes a lint-fix run given no prompt.
+const defaultLintTask = "Fix linter findings"
+
+// lintCommands returns the configured linters, or ones detected from the
+// project files.
+func (o *Orchestrator) lintCommands() []string {
+	if len(o.config.LintCmds) > 0 {
+		return o.config.LintCmds
+	}
+	return lint.DefaultCommands(o.workDir)
+}
+
+// loadLintFindings runs the linters and queues their findings in batches
+// per directory.
+func (o *Orchestrator) loadLintFindings(ctx context.Context) error {
+	commands := o.lintCommands()
+	if len(commands) == 0 {
+		return fmt.Errorf("no linter detected; set one with --lint-cmd")
+	}
+
+	var findings []lint.Finding
+	for _, command := range commands {
+		o.ui.StartSpinner(fmt.Sprintf("Running %s...", command))
+		found, err := lint.Run(ctx, o.workDir, command)
+		o.ui.StopSpinner()
+		if err != nil {
+			return err
+		}
+		o.ui.Info("%s: %d finding(s)", command, len(found))
+		findings = append(findings, found...)
+	}
+
+	batches := lint.Batches(findings, o.config.LintBatch)
+	for _, batch := range batches {
+		o.queue = append(o.queue, workItem{title: batch.Title(), goal: batch.Goal(commands, o.config.Prompt)})
+	}
+	o.ui.Info("Queued %d batch(es) of at most %d finding(s)", len(batches), o.config.LintBatch)
+	return nil
+}
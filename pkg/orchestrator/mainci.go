@@ -0,0 +1,189 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/audit"
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/report"
+	"github.com/guzus/deep-claude/internal/retry"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// mainCheckInterval is how often the checks of a merge commit are polled.
+var mainCheckInterval = 15 * time.Second
+
+// mainCheckGrace is how long a merge commit may show no checks before the
+// base branch is taken to have no CI.
+var mainCheckGrace = 2 * time.Minute
+
+// mainCheckTimeout is how long to wait for the checks of a merge commit.
+const mainCheckTimeout = 30 * time.Minute
+
+// brokenMerge is a merged PR whose merge commit failed CI on the base branch.
+type brokenMerge struct {
+	number string
+	title  string
+	sha    string
+	checks []string
+	// revert says how the merge was undone, "" if it couldn't be
+	revert string
+}
+
+// watchMain waits for CI to finish with a PR's merge commit on the base
+// branch and, if it fails, reverts the merge and tells the next iteration.
+// It reports whether the merge broke the base branch.
+func (o *Orchestrator) watchMain(ctx context.Context, pr *report.PR) bool {
+	sha, err := o.mergeCommit(ctx, pr)
+	if err != nil {
+		o.ui.Warning("Could not find the merge commit of PR #%s; not watching %s: %v", pr.Number, o.baseBranch, err)
+		return false
+	}
+
+	o.ui.StartSpinner(fmt.Sprintf("Waiting for checks on %s (%s)...", o.baseBranch, shortSHA(sha)))
+	done := o.timer.start(report.PhaseChecks)
+	status, err := o.waitForCommitChecks(ctx, sha)
+	done()
+	o.ui.StopSpinner()
+	if err != nil {
+		o.ui.Warning("Could not check CI on %s: %v", o.baseBranch, err)
+		return false
+	}
+	if !status.HasFailedChecks {
+		o.ui.Success("Checks passed on %s", o.baseBranch)
+		return false
+	}
+
+	broken := brokenMerge{number: pr.Number, title: pr.Title, sha: sha, checks: failedChecks(status)}
+	o.ui.Error("PR #%s broke %s: %s failed", pr.Number, o.baseBranch, strings.Join(broken.checks, ", "))
+	if revert, err := o.revertMerge(ctx, broken); err != nil {
+		o.ui.Warning("Could not revert PR #%s: %v", pr.Number, err)
+		pr.Outcome = "merged: broke " + o.baseBranch + ", revert failed"
+	} else {
+		broken.revert = revert
+		pr.Outcome = "merged: broke " + o.baseBranch + ", " + revert
+	}
+
+	o.events.Emit(events.MainBroken, pr.Iteration, map[string]any{
+		"number": pr.Number,
+		"base":   o.baseBranch,
+		"sha":    sha,
+		"checks": strings.Join(broken.checks, ", "),
+		"revert": broken.revert,
+	})
+	o.brokenMerges = append(o.brokenMerges, broken)
+	o.notify(ctx, fmt.Sprintf("Deep Claude run `%s` merged PR %s, which then failed %s on `%s`: %s.",
+		o.run.RunID, pr.URL, strings.Join(broken.checks, ", "), o.baseBranch, brokenOutcome(broken)))
+	return true
+}
+
+// waitForCommitChecks polls the checks of a commit until they finish.
+func (o *Orchestrator) waitForCommitChecks(ctx context.Context, sha string) (*github.PRStatus, error) {
+	start := time.Now()
+	for {
+		status, err := o.github.GetCommitStatus(ctx, sha)
+		if err != nil {
+			return nil, err
+		}
+		// CI may not have picked up the commit yet
+		starting := len(status.Checks) == 0 && time.Since(start) < mainCheckGrace
+		if !status.HasPendingChecks && !starting {
+			return status, nil
+		}
+		if time.Since(start) > mainCheckTimeout {
+			return nil, fmt.Errorf("timeout waiting for checks after %s", mainCheckTimeout)
+		}
+		if err := retry.Sleep(ctx, mainCheckInterval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// revertMerge undoes a merge on the base branch as --revert-on-main-failure
+// says, and returns how it did.
+func (o *Orchestrator) revertMerge(ctx context.Context, broken brokenMerge) (string, error) {
+	if err := o.git.Fetch(ctx, o.baseBranch); err != nil {
+		return "", err
+	}
+	branch := fmt.Sprintf("%srevert/%s", o.config.GitBranchPrefix, broken.number)
+	if err := o.git.CreateBranchFrom(branch, "origin/"+o.baseBranch); err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = o.git.SwitchBranch(o.homeBranch)
+		_ = o.git.DeleteBranch(branch)
+	}()
+	if err := o.git.Revert(broken.sha); err != nil {
+		return "", err
+	}
+
+	if o.config.RevertOnMainFailure == "push" {
+		if err := o.git.PushTo(ctx, o.baseBranch); err != nil {
+			return "", err
+		}
+		o.recordPush(o.baseBranch, false)
+		o.ui.Success("Pushed a revert of PR #%s to %s", broken.number, o.baseBranch)
+		_ = o.git.SwitchBranch(o.homeBranch)
+		_ = o.git.Pull(ctx, o.baseBranch)
+		return "reverted on " + o.baseBranch, nil
+	}
+
+	done := o.timer.start(report.PhasePush)
+	err := o.git.PushWithRetry(ctx, branch, o.config.Retry.Push)
+	done()
+	if err != nil {
+		return "", err
+	}
+	o.recordPush(branch, false)
+	title := fmt.Sprintf("Revert %q", broken.title)
+	body := fmt.Sprintf("Reverts #%s: after it merged, these checks failed on `%s` (%s):\n\n", broken.number, o.baseBranch, shortSHA(broken.sha))
+	for _, check := range broken.checks {
+		body += "- " + check + "\n"
+	}
+	url, err := o.github.CreatePR(ctx, title, withRunID(body, o.run.RunID), o.baseBranch)
+	if err != nil {
+		return "", err
+	}
+	o.ui.Success("Opened revert PR: %s", url)
+	o.labelPR(ctx, github.GetPRNumber(url))
+	o.audit.Record(audit.PRCreated, o.iteration, map[string]any{"number": github.GetPRNumber(url), "url": url, "title": title, "base": o.baseBranch})
+	return "revert PR #" + github.GetPRNumber(url) + " opened", nil
+}
+
+// failedChecks lists the names of the checks that failed.
+func failedChecks(status *github.PRStatus) []string {
+	var names []string
+	for _, check := range status.Checks {
+		switch check.State {
+		case "FAILURE", "ERROR", "CANCELLED", "TIMED_OUT", "ACTION_REQUIRED":
+			names = append(names, check.Name)
+		}
+	}
+	if len(names) == 0 {
+		names = []string{"checks"}
+	}
+	return names
+}
+
+// brokenOutcome says what became of a broken merge.
+func brokenOutcome(broken brokenMerge) string {
+	if broken.revert == "" {
+		return "it could not be reverted automatically"
+	}
+	return broken.revert
+}
+
+// brokenMainSection tells Claude which merged changes broke the base branch.
+func (o *Orchestrator) brokenMainSection() claude.Section {
+	var body strings.Builder
+	fmt.Fprintf(&body, "These merged changes broke CI on the `%s` branch:\n\n", o.baseBranch)
+	for _, broken := range o.brokenMerges {
+		fmt.Fprintf(&body, "- PR #%s %q (merge commit %s) failed %s; %s\n", broken.number, broken.title, shortSHA(broken.sha), strings.Join(broken.checks, ", "), brokenOutcome(broken))
+	}
+	body.WriteString("\nFind out why before building on these changes again, and fix the base branch first if it is still broken.")
+	return claude.Section{Title: "BROKEN MAIN BRANCH", Body: body.String()}
+}
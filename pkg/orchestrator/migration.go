@@ -0,0 +1,185 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// migrationSection is the section of the notes that holds the checklist
+// of a --migration-cmd run.
+const migrationSection = "Migration checklist"
+
+// checklistItemRe matches an item of the checklist, such as
+// "- [ ] `pkg/db/db.go` (iteration 3)".
+var checklistItemRe = regexp.MustCompile("^- \\[([ x])\\] `(.+)`(?: \\(iteration (\\d+)\\))?$")
+
+// checklistItem is a file or module to migrate. iteration is the one
+// assigned it, while it isn't done.
+type checklistItem struct {
+	name      string
+	done      bool
+	iteration int
+}
+
+// listMigration runs --migration-cmd and returns what it lists, one item
+// per line. A command that lists nothing may exit non-zero, as grep -l
+// does.
+func listMigration(ctx context.Context, command, dir string, env []string) ([]string, error) {
+	output, err := runCommand(ctx, command, dir, env)
+	var items []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !seen[line] {
+			seen[line] = true
+			items = append(items, line)
+		}
+	}
+	var exitErr *exec.ExitError
+	if err != nil && (len(items) > 0 || !errors.As(err, &exitErr)) {
+		return nil, fmt.Errorf("migration command failed: %w\n%s", err, truncateOutput(output, 500))
+	}
+	return items, nil
+}
+
+// parseChecklist reads the items of the notes' checklist.
+func parseChecklist(section string) []checklistItem {
+	var items []checklistItem
+	for _, line := range strings.Split(section, "\n") {
+		m := checklistItemRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		iteration, _ := strconv.Atoi(m[3])
+		items = append(items, checklistItem{name: m[2], done: m[1] == "x", iteration: iteration})
+	}
+	return items
+}
+
+// updateChecklist checks off the items no longer left to migrate, and
+// adds those that are new at the end.
+func updateChecklist(items []checklistItem, remaining []string) []checklistItem {
+	left := make(map[string]bool, len(remaining))
+	for _, name := range remaining {
+		left[name] = true
+	}
+	listed := make(map[string]bool, len(items))
+	updated := make([]checklistItem, 0, len(items)+len(remaining))
+	for _, item := range items {
+		listed[item.name] = true
+		item.done = !left[item.name]
+		if item.done {
+			item.iteration = 0
+		}
+		updated = append(updated, item)
+	}
+	for _, name := range remaining {
+		if !listed[name] {
+			updated = append(updated, checklistItem{name: name})
+		}
+	}
+	return updated
+}
+
+// claimItems assigns the first n items left to migrate that take allows
+// to the iteration, items an earlier iteration left unfinished first as
+// they come first, and takes the claims of earlier iterations off the
+// rest.
+func claimItems(items []checklistItem, iteration, n int, take func(name string) bool) []string {
+	var claimed []string
+	for i := range items {
+		if items[i].done {
+			continue
+		}
+		items[i].iteration = 0
+		if len(claimed) < n && take(items[i].name) {
+			items[i].iteration = iteration
+			claimed = append(claimed, items[i].name)
+		}
+	}
+	return claimed
+}
+
+// checklistProgress counts the items done, of all of them.
+func checklistProgress(items []checklistItem) (done, total int) {
+	for _, item := range items {
+		if item.done {
+			done++
+		}
+	}
+	return done, len(items)
+}
+
+// checklistMarkdown renders the checklist for the notes.
+func (o *Orchestrator) checklistMarkdown(items []checklistItem) string {
+	done, total := checklistProgress(items)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Maintained by deep-claude from what `%s` lists as left to migrate: %d of %d items done (%d%%). ", o.config.MigrationCmd, done, total, done*100/max(total, 1))
+	sb.WriteString("An item marked with an iteration is that iteration's to migrate, and is checked off once the command no longer lists it.\n\n")
+	for _, item := range items {
+		mark := " "
+		if item.done {
+			mark = "x"
+		}
+		fmt.Fprintf(&sb, "- [%s] `%s`", mark, item.name)
+		if item.iteration > 0 {
+			fmt.Fprintf(&sb, " (iteration %d)", item.iteration)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// updateMigration brings the notes' migration checklist up to date with
+// what --migration-cmd lists, assigns the next batch items to the
+// iteration and returns them. With batch 0 it only takes progress, as
+// when the run ends.
+func (o *Orchestrator) updateMigration(ctx context.Context, batch int) []string {
+	remaining, err := listMigration(ctx, o.config.MigrationCmd, o.workDir, o.env)
+	o.recordCommand("migration checklist", o.config.MigrationCmd, err != nil)
+	if err != nil {
+		o.ui.Warning("Could not list what's left to migrate: %v", err)
+		return nil
+	}
+
+	section, _, _ := o.notes.Section(migrationSection)
+	items := updateChecklist(parseChecklist(section), remaining)
+	// With --partition checklist, items other workers hold are left to them
+	take := func(string) bool { return true }
+	if o.config.Partition == "checklist" {
+		o.chunks.Release()
+		take = o.chunks.Claim
+	}
+	claimed := claimItems(items, o.iteration, batch, take)
+	if err := o.notes.SetSection(migrationSection, o.checklistMarkdown(items)); err != nil {
+		o.ui.Warning("Could not update the notes: %v", err)
+	}
+
+	o.migrationDone, o.migrationTotal = checklistProgress(items)
+	if o.migrationTotal > 0 {
+		o.ui.Info("Migration: %d of %d items done (%d%%)", o.migrationDone, o.migrationTotal, o.migrationDone*100/o.migrationTotal)
+	}
+	o.events.Emit(events.ChecklistUpdated, o.iteration, map[string]any{"done": o.migrationDone, "total": o.migrationTotal, "claimed": claimed})
+	return claimed
+}
+
+// migrationPromptSection tells Claude which checklist items are the
+// iteration's to migrate.
+func (o *Orchestrator) migrationPromptSection(claimed []string) claude.Section {
+	var sb strings.Builder
+	sb.WriteString("This run is a migration, tracked by the \"" + migrationSection + "\" checklist in the notes. This iteration's items are:\n\n")
+	for _, name := range claimed {
+		sb.WriteString("- `" + name + "`\n")
+	}
+	fmt.Fprintf(&sb, "\nMigrate these completely, and only these: the other items are left to later iterations. "+
+		"An item is done once `%s` no longer lists it, and deep-claude checks it off then, so don't edit the checklist yourself; "+
+		"note in the notes anything an item needs that you couldn't finish.", o.config.MigrationCmd)
+	return claude.Section{Title: "MIGRATION", Body: sb.String()}
+}
@@ -0,0 +1,39 @@
+package orchestrator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/guzus/deep-claude/pkg/config"
+)
+
+func TestUpdateChecklist(t *testing.T) {
+	o := &Orchestrator{config: &config.Config{MigrationCmd: "grep -rl pkg/errors ."}}
+	section := o.checklistMarkdown([]checklistItem{
+		{name: "a.go", done: true},
+		{name: "b.go", iteration: 2},
+		{name: "c.go", iteration: 2},
+		{name: "d.go"},
+	})
+
+	// b.go was migrated, c.go wasn't, and e.go turned up along the way
+	items := updateChecklist(parseChecklist(section), []string{"c.go", "d.go", "e.go"})
+	// Another worker holds d.go
+	claimed := claimItems(items, 3, 2, func(name string) bool { return name != "d.go" })
+	if want := []string{"c.go", "e.go"}; !reflect.DeepEqual(claimed, want) {
+		t.Errorf("claimItems() = %v, want %v", claimed, want)
+	}
+	want := []checklistItem{
+		{name: "a.go", done: true},
+		{name: "b.go", done: true},
+		{name: "c.go", iteration: 3},
+		{name: "d.go"},
+		{name: "e.go", iteration: 3},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("checklist = %+v, want %+v", items, want)
+	}
+	if done, total := checklistProgress(items); done != 2 || total != 5 {
+		t.Errorf("checklistProgress() = %d, %d; want 2, 5", done, total)
+	}
+}
@@ -0,0 +1,122 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/retry"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// offlineRetryInterval is how often the end of a run retries a deferred push.
+const offlineRetryInterval = time.Minute
+
+// deferredWork is committed work whose push failed for lack of network.
+// Later iterations branch off its latest branch, so when connectivity
+// returns a single push of that branch ships everything in one PR.
+type deferredWork struct {
+	branch   string
+	titles   []string
+	messages []string
+	diff     git.DiffStat
+	// Any of the iterations was only confident enough for a draft
+	draft bool
+	gates []gate
+}
+
+// startBranch is the branch new iterations start from and return to.
+func (o *Orchestrator) startBranch() string {
+	if o.deferred != nil {
+		return o.deferred.branch
+	}
+	if o.readOnly != nil && o.readOnly.branch != "" {
+		return o.readOnly.branch
+	}
+	return o.homeBranch
+}
+
+// deferPush queues an iteration's committed branch until it can be pushed.
+func (o *Orchestrator) deferPush(branch, title, message string, stat git.DiffStat, draft bool) {
+	if o.deferred == nil {
+		o.deferred = &deferredWork{}
+	}
+	o.deferred.branch = branch
+	o.deferred.titles = append(o.deferred.titles, title)
+	o.deferred.messages = append(o.deferred.messages, message)
+	o.deferred.diff = o.deferred.diff.Add(stat)
+	o.deferred.draft = o.deferred.draft || draft
+	o.deferred.gates = append(o.deferred.gates, o.gates...)
+
+	o.ui.Warning("Network unavailable, keeping %s locally (%d iteration(s) awaiting push)", branch, len(o.deferred.titles))
+	o.events.Emit(events.PushDeferred, o.iteration, map[string]any{"branch": branch, "queued": len(o.deferred.titles)})
+}
+
+// flushDeferred tries to push the deferred work and ship it as a PR. It
+// reports false if the network is still unavailable.
+func (o *Orchestrator) flushDeferred(ctx context.Context) bool {
+	d := o.deferred
+	if err := o.git.SwitchBranch(d.branch); err != nil {
+		o.ui.Warning("Could not switch to deferred branch: %v", err)
+		return false
+	}
+
+	o.ui.StartSpinner("Pushing deferred work...")
+	err := o.git.Push(ctx, d.branch)
+	o.ui.StopSpinner()
+	if err != nil {
+		if git.IsNetworkError(err) {
+			o.ui.Info("Still offline, %d iteration(s) awaiting push", len(d.titles))
+			return false
+		}
+		o.ui.Warning("Could not push deferred work: %v", err)
+		return false
+	}
+	o.ui.Success("Pushed deferred work to origin/%s", d.branch)
+	o.recordPush(d.branch, false)
+
+	o.deferred = nil
+	o.gates = d.gates
+	if err := o.shipBranch(ctx, stackedTitle(d.titles), formatPRBody(stackedBody(d.messages), o.iteration, d.diff), o.baseBranch, d.draft); err != nil {
+		o.ui.Warning("Could not ship deferred work: %v", err)
+		_ = o.git.SwitchBranch(o.homeBranch)
+	}
+	return true
+}
+
+// waitForDeferred keeps retrying the deferred push at the end of the run,
+// for up to --defer-push-wait.
+func (o *Orchestrator) waitForDeferred(ctx context.Context) {
+	deadline := time.Now().Add(o.config.DeferPushWait)
+	for ctx.Err() == nil {
+		if o.flushDeferred(ctx) {
+			return
+		}
+		if time.Now().Add(offlineRetryInterval).After(deadline) {
+			break
+		}
+		o.ui.Info("Retrying deferred push in %s", config.FormatDuration(offlineRetryInterval))
+		_ = retry.Sleep(ctx, offlineRetryInterval)
+	}
+
+	o.ui.Warning("Gave up waiting for connectivity; %d unpushed iteration(s) remain on branch %s",
+		len(o.deferred.titles), o.deferred.branch)
+}
+
+// stackedTitle summarizes iterations stacked on one branch as a PR title,
+// from their commit titles.
+func stackedTitle(titles []string) string {
+	last := titles[len(titles)-1]
+	if len(titles) == 1 {
+		return last
+	}
+	return fmt.Sprintf("%s (+%d earlier iterations)", last, len(titles)-1)
+}
+
+// stackedBody joins the commit messages of iterations stacked on one branch.
+func stackedBody(messages []string) string {
+	return strings.Join(messages, "\n\n---\n\n")
+}
@@ -0,0 +1,1572 @@
+// Package orchestrator provides the main loop logic for Continuous Claude.
+//
+// It is the library the dclaude CLI is built on: other tools can embed the
+// loop by filling in a config.Config and calling New and Run, or
+// NewWithRunners to drive their own git, GitHub and Claude clients. The
+// exported API of this package and of config is kept stable.
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/guzus/deep-claude/internal/audit"
+	"github.com/guzus/deep-claude/internal/auth"
+	"github.com/guzus/deep-claude/internal/changelog"
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/commitmsg"
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/inbox"
+	"github.com/guzus/deep-claude/internal/ledger"
+	"github.com/guzus/deep-claude/internal/notes"
+	"github.com/guzus/deep-claude/internal/redact"
+	"github.com/guzus/deep-claude/internal/replay"
+	"github.com/guzus/deep-claude/internal/repomap"
+	"github.com/guzus/deep-claude/internal/report"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/internal/telemetry"
+	"github.com/guzus/deep-claude/internal/ui"
+	"github.com/guzus/deep-claude/internal/worktree"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// promptsDir is where humans can drop instruction files for the next iteration.
+const promptsDir = ".deep-claude/prompts"
+
+// Orchestrator manages the continuous development loop.
+type Orchestrator struct {
+	config  *config.Config
+	git     GitRunner
+	github  GhRunner
+	claude  ClaudeRunner
+	notes   *notes.Manager
+	ui      ui.Printer
+	workDir string
+
+	// redactor scrubs secrets from logs, transcripts and GitHub text
+	redactor *redact.Redactor
+
+	commitConvention *commitmsg.Convention
+	inboxes          []*inbox.Inbox
+	stopConditions   []StopCondition
+	resources        *worktree.Resources
+	env              []string
+
+	// The chunks of the work this worker holds per --partition, and the
+	// directory it last claimed
+	chunks *worktree.Chunks
+	chunk  string
+
+	// State
+	run                   *state.RunState
+	runDir                string
+	events                *events.Log
+	subscribers           []events.Subscriber
+	audit                 *audit.Log
+	iteration             int
+	completion            claude.Signal
+	completionSignalCount int
+	goalReached           bool
+	noProgressCount       int
+	totalDiff             git.DiffStat
+	baseBranch            string
+	homeBranch            string
+
+	// What each iteration of an analysis run found
+	findings []claude.Finding
+
+	// Checklist items of a migration run done, of all it listed
+	migrationDone  int
+	migrationTotal int
+
+	// What Claude said it needs to go on, while it is blocked
+	blocked string
+
+	// How sure Claude is of this iteration's work, for --merge-confidence
+	confidence    float64
+	hasConfidence bool
+
+	// Merges that broke CI on the base branch, for the next prompt
+	brokenMerges []brokenMerge
+
+	// Verdicts of the gates this iteration's changes went through
+	gates []gate
+	// The branch and PR of --branch-mode single, once it has commits
+	single *singleBranch
+
+	// Head commits of the PRs --commit-statuses sets statuses on, by number
+	prHeads map[string]string
+
+	// Why the run stops because a PR went unreviewed, for
+	// --on-review-timeout stop
+	reviewStalled string
+
+	// Fixes reviewers asked for on the run's PRs, for --comment-commands
+	followUps []followUp
+
+	// Work split up front by a queue mode, and this iteration's item
+	queue   []workItem
+	current *workItem
+
+	// The PR being backported and how each backport went
+	backportPR *github.PullRequest
+	backports  []report.Backport
+
+	// PRs whose checks are awaited in the background (--pipeline)
+	tracker  *github.Tracker
+	inFlight []*inFlightPR
+
+	// When this process took over the run and what the run had used by
+	// then, for the cost ledger
+	started         time.Time
+	startIterations int
+	startCost       float64
+
+	// PRs opened recently, when their rate is limited
+	prWindow *prWindow
+
+	// Whether the run's labels exist in the repository yet
+	labelsCreated bool
+
+	// Committed work waiting for connectivity to be pushed
+	deferred *deferredWork
+
+	// Patches recorded instead of pushed (--read-only)
+	readOnly *readOnlyWork
+
+	// PRs opened during the run and why the run stopped, for the summary
+	prs        []report.PR
+	stopReason string
+
+	// Failed iterations by subsystem, for telemetry
+	failures map[string]int
+
+	// Time spent in each phase of the iteration, and whether the last
+	// one's is yet to be printed
+	timer         *phaseTimer
+	timingPending bool
+
+	// Merged PRs not yet included in a release
+	unreleasedEntries []string
+	unreleasedTitles  []string
+}
+
+// New creates a new orchestrator.
+func New(cfg *config.Config, workDir string) (*Orchestrator, error) {
+	gitClient, newGitHub, claudeClient := Runners(cfg, workDir)
+	return NewWithRunners(cfg, workDir, gitClient, newGitHub, claudeClient)
+}
+
+// Runners returns the clients New drives, which run the git, gh and
+// claude binaries in workDir.
+func Runners(cfg *config.Config, workDir string) (GitRunner, func(owner, repo string) GhRunner, ClaudeRunner) {
+	newGitHub := func(owner, repo string) GhRunner {
+		return github.NewClient(owner, repo, workDir)
+	}
+	return git.NewClient(workDir), newGitHub, claude.NewClient(workDir, cfg.ExtraClaudeArgs)
+}
+
+// NewWithRunners creates an orchestrator that drives the given clients
+// instead of the git, gh and claude binaries. The GitHub client is made
+// once the repository is known.
+func NewWithRunners(cfg *config.Config, workDir string, gitClient GitRunner, newGitHub func(owner, repo string) GhRunner, claudeClient ClaudeRunner) (*Orchestrator, error) {
+	// Check if we're in a git repository first
+	if !gitClient.IsRepo() {
+		return nil, fmt.Errorf("not in a git repository\n\nDeep Claude requires a local git repository to work in.\nPlease run from inside a cloned repository:\n  git clone https://github.com/OWNER/REPO.git\n  cd REPO\n  dclaude -p \"your task\"")
+	}
+
+	// Detect owner/repo if not provided
+	owner := cfg.Owner
+	repo := cfg.Repo
+	if owner == "" || repo == "" {
+		detectedOwner, detectedRepo, err := gitClient.DetectGitHubRepo()
+		if err != nil {
+			return nil, fmt.Errorf("could not detect GitHub repository: %w\nPlease provide --owner and --repo flags", err)
+		}
+		if owner == "" {
+			owner = detectedOwner
+		}
+		if repo == "" {
+			repo = detectedRepo
+		}
+	}
+
+	// Get default branch (main/master) to use as base for PRs
+	baseBranch, err := gitClient.DefaultBranch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default branch: %w\n\nThis usually means the repository has no commits yet or no remote is configured.\nPlease make an initial commit and push first:\n  git add . && git commit -m \"Initial commit\" && git push -u origin main", err)
+	}
+
+	// The base branch is usually checked out in the main tree, so a
+	// worktree runs from its own branch and pulls the base into it
+	homeBranch := baseBranch
+	if cfg.Worktree != "" {
+		if homeBranch, err = gitClient.CurrentBranch(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range cfg.Paths {
+		if info, err := os.Stat(filepath.Join(workDir, p)); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("--path %s is not a directory in the repository", p)
+		}
+	}
+
+	convention, err := commitmsg.NewConvention(cfg.CommitConvention, cfg.CommitPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	pricing, err := claude.ResolvePricing(cfg.PricingModel, cfg.Pricing)
+	if err != nil {
+		return nil, err
+	}
+
+	completion := claude.Signal{InSummary: cfg.CompletionInSummary}
+	for _, phrase := range cfg.CompletionSignals {
+		if phrase != "" {
+			completion.Phrases = append(completion.Phrases, phrase)
+		}
+	}
+	if cfg.CompletionPattern != "" {
+		if completion.Pattern, err = regexp.Compile(cfg.CompletionPattern); err != nil {
+			return nil, fmt.Errorf("invalid --completion-regex: %w", err)
+		}
+	}
+
+	// Humans steer a run by dropping files in the prompts directory or,
+	// for detached sessions, with "dclaude tell"
+	inboxes := []*inbox.Inbox{inbox.New(filepath.Join(workDir, promptsDir))}
+	if cfg.SessionName != "" {
+		dir, err := state.SessionInboxDir(cfg.SessionName)
+		if err != nil {
+			return nil, err
+		}
+		inboxes = append(inboxes, inbox.New(dir))
+	}
+
+	// Keep notes with the tree being worked on, which differs from the
+	// current directory when running in a worktree
+	notesPath := cfg.NotesFile
+	if !filepath.IsAbs(notesPath) {
+		notesPath = filepath.Join(workDir, notesPath)
+	}
+
+	// Extra environment seen by Claude, the setup command and the stop
+	// condition commands
+	var env []string
+	if cfg.SharedCache {
+		dir, err := state.CacheDir()
+		if err != nil {
+			return nil, err
+		}
+		if env, err = worktree.CacheEnv(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	// Parallel workers get their own ports and temp dir
+	var resources *worktree.Resources
+	if cfg.IsolateResources {
+		dir, err := state.SlotsDir()
+		if err != nil {
+			return nil, err
+		}
+		if resources, err = worktree.Allocate(dir, cfg.PortBase, cfg.PortsPerWorker); err != nil {
+			return nil, err
+		}
+		env = append(env, resources.Env()...)
+	}
+
+	// Secrets are scrubbed from everything the run prints, logs or publishes
+	redactor := redact.New(cfg.RedactEnv)
+	printer, err := ui.Open(ui.Options{Output: cfg.Output, LogFile: cfg.LogFile, Verbose: cfg.Verbose, CI: cfg.CI, ASCII: cfg.ASCII})
+	if err != nil {
+		return nil, err
+	}
+	printer.SetRedactor(redactor.String)
+	var ghClient GhRunner = newGitHub(owner, repo)
+	ghClient.SetLogger(printer)
+	ghClient.SetCheckScope(checkScope(cfg.Paths))
+	ghClient.SetRetryPolicy(cfg.Retry.GitHub)
+	ghClient = redactedGitHub{GhRunner: ghClient, r: redactor}
+
+	// Parallel workers claim disjoint chunks of the work
+	var chunks *worktree.Chunks
+	if cfg.Partition != "" {
+		dir, err := state.ChunksDir(owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		if chunks, err = worktree.OpenChunks(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	var verify StopCondition
+	if cfg.VerifyCmd != "" {
+		if verify, err = newVerifyCondition(cfg, gitClient, workDir, env, printer); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.ReadOnly {
+		claudeClient.SetEnv(append(append([]string{}, env...), readOnlyEnv()...))
+	} else {
+		claudeClient.SetEnv(env)
+	}
+	claudeClient.SetPricing(pricing)
+	claudeClient.SetRetryPolicy(cfg.Retry.Claude)
+
+	run, err := loadRunState(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var window *prWindow
+	if cfg.MaxPRsPerHour > 0 || cfg.MaxPRsPerDay > 0 {
+		window = &prWindow{perHour: cfg.MaxPRsPerHour, perDay: cfg.MaxPRsPerDay}
+	}
+
+	timer := &phaseTimer{}
+	return &Orchestrator{
+		config:     cfg,
+		completion: completion,
+		git:        gitClient,
+		github:     ghClient,
+		claude:     timedClaude{claudeClient, run, timer},
+		timer:      timer,
+		notes:      notes.NewManager(notesPath),
+		ui:         printer,
+		redactor:   redactor,
+		workDir:    workDir,
+		baseBranch: baseBranch,
+		homeBranch: homeBranch,
+		run:        run,
+		iteration:  run.Iterations,
+		prWindow:   window,
+		failures:   make(map[string]int),
+
+		commitConvention: convention,
+		inboxes:          inboxes,
+		stopConditions:   buildStopConditions(cfg, ghClient, verify, workDir, env),
+		resources:        resources,
+		env:              env,
+		chunks:           chunks,
+	}, nil
+}
+
+// Run starts the main orchestration loop. Canceling ctx stops the run
+// promptly: Claude and gh are killed, waits for checks and rate limits
+// end, PRs still waiting for checks are left open, and the summary is
+// still reported.
+func (o *Orchestrator) Run(ctx context.Context) error {
+	o.run.Start()
+	o.started = time.Now()
+	o.startIterations, o.startCost = o.run.Iterations, o.run.TotalCost
+	defer o.ui.Close()
+
+	// Validate requirements
+	if err := o.validateRequirements(ctx); err != nil {
+		return err
+	}
+
+	defer o.resources.Release()
+	defer o.chunks.Release()
+
+	// Keep pre-existing local changes out of the AI's commits
+	restore, err := o.preserveDirtyTree()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	// Initialize notes file
+	if err := o.notes.Initialize(o.runPrompt()); err != nil {
+		o.ui.Warning("Could not initialize notes file: %v", err)
+	}
+
+	o.openEventLog()
+	defer o.events.Close()
+	o.openAuditLog()
+	defer o.audit.Close()
+	release := o.claimRunDir()
+	defer release()
+	stopHeartbeat := o.startHeartbeat()
+	defer stopHeartbeat()
+	o.seedPRWindow()
+	if o.config.Pipeline {
+		// On the run's context: PRs stay in flight across iterations, which
+		// --max-iteration-duration may cut short
+		o.tracker = github.NewTracker(ctx, o.github, trackerInterval)
+	}
+	if o.config.ReadOnly {
+		if err := o.openPatchDir(); err != nil {
+			return err
+		}
+	}
+	if o.analysis() {
+		o.resumeFindings()
+	}
+
+	if o.config.GCBranches {
+		o.collectBranches(ctx)
+	}
+
+	// Install dependencies once so Claude doesn't start in a tree that can't build
+	if o.config.SetupCmd != "" {
+		if err := o.runSetup(ctx); err != nil {
+			return err
+		}
+	}
+
+	if o.config.UpgradeDeps {
+		if err := o.loadUpgrades(ctx); err != nil {
+			return err
+		}
+	}
+	if o.config.LintFix {
+		if err := o.loadLintFindings(ctx); err != nil {
+			return err
+		}
+	}
+	if o.config.Backport != "" {
+		if err := o.loadBackports(ctx); err != nil {
+			return err
+		}
+	}
+
+	o.ui.Header("Continuous Claude")
+	o.ui.Info("Starting continuous development loop")
+	o.printConfig()
+
+	// Main loop
+	for {
+		o.run.Iterations = o.iteration
+		o.saveRunState()
+		o.iteration++
+
+		// Check stopping conditions
+		stop, reason := o.checkStopConditions(ctx)
+		o.endIteration()
+		if stop {
+			o.ui.Info("Stopping: %s", reason)
+			o.stopReason = reason
+			break
+		}
+		if ok, reason := o.checkControl(ctx); !ok {
+			o.ui.Info("Stopping: %s", reason)
+			o.stopReason = reason
+			break
+		}
+		if ok, reason := o.waitForPRWindow(ctx); !ok {
+			o.ui.Info("Stopping: %s", reason)
+			o.stopReason = reason
+			break
+		}
+		if o.blocked != "" {
+			if ok, reason := o.waitForHuman(ctx); !ok {
+				o.ui.Info("Stopping: %s", reason)
+				o.stopReason = reason
+				break
+			}
+		}
+
+		// Run iteration
+		started, startCost := time.Now(), o.run.TotalCost
+		iterationCtx, cancel := o.iterationContext(ctx)
+		err := o.runIteration(iterationCtx)
+		timedOut := ctx.Err() == nil && errors.Is(iterationCtx.Err(), context.DeadlineExceeded)
+		cancel()
+		if timedOut {
+			o.ui.Warning("Iteration %d reached --max-iteration-duration (%s)", o.iteration, config.FormatDuration(o.config.IterationTimeout))
+			if err != nil {
+				err = fmt.Errorf("iteration timed out: %w", err)
+			}
+		}
+		o.recordIteration(ctx, started, startCost, timedOut, err)
+		if err != nil {
+			if ctx.Err() != nil {
+				o.ui.Warning("Iteration %d interrupted: %v", o.iteration, err)
+				continue
+			}
+			o.ui.Error("Iteration %d failed: %v", o.iteration, err)
+			o.events.Emit(events.IterationFailed, o.iteration, map[string]any{"error": err.Error(), "timed_out": timedOut})
+			o.failures[telemetry.Categorize(err)]++
+			// Continue to next iteration on error
+			continue
+		}
+	}
+
+	if o.tracker != nil {
+		o.drainInFlight(ctx)
+		o.endIteration()
+	}
+	if o.single != nil && o.single.pr >= 0 {
+		o.settleSingle(ctx)
+		o.endIteration()
+	}
+	// An interrupted or blocked run can be resumed
+	o.run.Finished = ctx.Err() == nil && o.blocked == ""
+	o.saveRunState()
+	if o.deferred != nil {
+		o.waitForDeferred(ctx)
+	}
+
+	if o.config.ReleaseOnComplete && len(o.unreleasedEntries) > 0 && ctx.Err() == nil {
+		o.publishRelease(ctx)
+	}
+	if o.analysis() {
+		// Report an interrupted run too
+		o.deliverReport(context.WithoutCancel(ctx))
+	}
+	if o.config.MigrationCmd != "" && o.run.Iterations > 0 {
+		// Count the last iteration's work too
+		o.updateMigration(context.WithoutCancel(ctx), 0)
+	}
+
+	// Print summary
+	run := o.runReport()
+	o.events.Emit(events.RunFinished, 0, map[string]any{
+		"iterations":    run.Iterations,
+		"total_cost":    run.TotalCost,
+		"elapsed_sec":   run.Elapsed.Seconds(),
+		"completed":     run.Completed,
+		"stop_reason":   run.StopReason,
+		"files_changed": run.FilesChanged,
+		"insertions":    run.Insertions,
+		"deletions":     run.Deletions,
+		"audit_head":    run.AuditHead,
+	})
+	o.ui.Summary(ui.RunSummary{
+		RunID:        run.RunID,
+		Iterations:   run.Iterations,
+		TotalCost:    run.TotalCost,
+		Elapsed:      run.Elapsed,
+		Completed:    run.Completed,
+		FilesChanged: run.FilesChanged,
+		Insertions:   run.Insertions,
+		Deletions:    run.Deletions,
+		Breakdown:    report.Breakdown(run.History),
+		Failures:     iterationFailures(run.History),
+		Phases:       report.FormatPhases(report.TimeByPhase(run.History)),
+
+		IterationCosts: iterationCosts(run.History),
+		MigrationDone:  run.MigrationDone,
+		MigrationTotal: run.MigrationTotal,
+	})
+	if len(o.backports) > 0 {
+		o.printBackports()
+	}
+
+	if o.readOnly != nil {
+		o.writeReadOnlyReport(run)
+	}
+
+	o.recordCost(run)
+	if o.config.PublishSummary != "" {
+		// Report an interrupted run too
+		o.publishSummary(context.WithoutCancel(ctx), run)
+	}
+
+	return nil
+}
+
+// iterationContext bounds one iteration by --max-iteration-duration.
+func (o *Orchestrator) iterationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.config.IterationTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, o.config.IterationTimeout)
+}
+
+func (o *Orchestrator) validateRequirements(ctx context.Context) error {
+	// Check Claude Code
+	if err := o.claude.CheckAvailable(); err != nil {
+		return err
+	}
+
+	// Check credentials up front rather than failing mid-iteration
+	if key, ok := auth.Anthropic.FromEnv(); ok {
+		if err := auth.ValidateAnthropic(key); err != nil {
+			var unreachable *auth.UnreachableError
+			if !errors.As(err, &unreachable) {
+				return fmt.Errorf("%w\n\nUpdate the key with: dclaude auth set anthropic\n(or fix the %s environment variable)", err, auth.Anthropic.EnvVar)
+			}
+			o.ui.Warning("Could not verify Anthropic API key: %v", err)
+		}
+	}
+
+	// Check GitHub auth
+	if err := o.github.CheckAuth(ctx); err != nil {
+		return fmt.Errorf("%w\nLog in with: gh auth login\n(or store a token with: dclaude auth set github)", err)
+	}
+
+	// Check git repo
+	if !o.git.IsRepo() {
+		return fmt.Errorf("not in a git repository")
+	}
+
+	return nil
+}
+
+func (o *Orchestrator) printConfig() {
+	o.ui.SubHeader("Configuration")
+
+	if o.config.HasMaxRuns() {
+		o.ui.Info("Max iterations: %d", o.config.MaxRuns)
+	}
+	if o.config.HasMaxCost() {
+		o.ui.Info("Max cost: $%.2f", o.config.MaxCost)
+	}
+	if len(o.config.CostTags) > 0 {
+		o.ui.Info("Cost tags: %s", strings.Join(ledger.FormatTags(o.config.CostTags), ", "))
+	}
+	if len(o.config.Labels) > 0 {
+		o.ui.Info("Labels: %s", strings.Join(o.config.Labels, ", "))
+	}
+	if o.config.Pricing != "" || o.config.PricingModel != "" {
+		o.ui.Info("Unreported costs estimated at: %s", o.claude.Pricing())
+	}
+	if o.config.HasMaxDuration() {
+		o.ui.Info("Max duration: %s", config.FormatDuration(o.config.MaxDuration))
+	}
+	if o.config.IterationTimeout > 0 {
+		o.ui.Info("Max iteration duration: %s", config.FormatDuration(o.config.IterationTimeout))
+	}
+	if len(o.config.Paths) > 0 {
+		o.ui.Info("Scope: %s", strings.Join(o.config.Paths, ", "))
+	}
+	for _, condition := range o.stopConditions {
+		o.ui.Info("Stop when: %s", condition.Name())
+	}
+	if o.config.Partition != "" {
+		o.ui.Info("Partition: by %s, claiming chunks no other worker holds", o.config.Partition)
+	}
+	if o.resources != nil {
+		o.ui.Info("Worker slot: %d (ports %d-%d)", o.resources.Slot, o.resources.PortStart, o.resources.PortEnd)
+	}
+	if o.readOnly != nil {
+		o.ui.Info("Read-only: patches are recorded in %s, nothing is pushed", o.readOnly.dir)
+	} else {
+		o.ui.Info("Merge strategy: %s", o.config.MergeStrategy)
+	}
+	if o.config.MaxPRsPerHour > 0 {
+		o.ui.Info("Max PRs per hour: %d", o.config.MaxPRsPerHour)
+	}
+	if o.config.MaxPRsPerDay > 0 {
+		o.ui.Info("Max PRs per day: %d", o.config.MaxPRsPerDay)
+	}
+	if o.config.Pipeline {
+		o.ui.Info("Pipelined: up to %d PR(s) awaiting checks while iterating", o.config.PipelineDepth)
+	}
+	o.ui.Info("Notes file: %s", o.notes.GetPath())
+	o.ui.Info("Run ID: %s", o.run.RunID)
+}
+
+// Subscribe registers s to receive the events of the run as they happen,
+// the same ones written to its events.jsonl. Call it before Run. s is
+// called one event at a time from the goroutine that emitted it, so it
+// should return quickly.
+func (o *Orchestrator) Subscribe(s events.Subscriber) {
+	o.subscribers = append(o.subscribers, s)
+}
+
+// openEventLog assigns the run ID, unless one was given, and opens the
+// run's events.jsonl in the state directory. Failure only disables the
+// file; subscribers still get the events.
+func (o *Orchestrator) openEventLog() {
+	if o.run.RunID == "" {
+		o.run.RunID = state.NewRunID()
+	}
+
+	dir, err := state.RunDir(o.run.RunID)
+	if err == nil {
+		o.runDir = dir
+		o.events, err = events.Create(filepath.Join(dir, events.FileName), o.run.RunID)
+	}
+	if err != nil {
+		o.ui.Warning("Could not open event log: %v", err)
+		o.events = events.New(o.run.RunID)
+	}
+	o.events.SetRedactor(o.redactor.Data)
+	for _, s := range o.subscribers {
+		o.events.Subscribe(s)
+	}
+
+	o.events.Emit(events.RunStarted, 0, map[string]any{
+		"prompt":      o.runPrompt(),
+		"owner":       o.github.Owner(),
+		"repo":        o.github.Repo(),
+		"base_branch": o.baseBranch,
+		"work_dir":    o.workDir,
+		"session":     o.config.SessionName,
+		"cost_tags":   o.config.CostTags,
+		"labels":      o.config.Labels,
+		"resumed":     o.config.Resume != "",
+	})
+}
+
+func (o *Orchestrator) checkStopConditions(ctx context.Context) (bool, string) {
+	if ctx.Err() != nil {
+		return true, "interrupted"
+	}
+
+	// Check max runs, cost and duration, including any earlier part of a
+	// resumed run
+	o.run.Tick()
+	if reason, reached := o.run.LimitReached(); reached {
+		return true, reason
+	}
+
+	// Check for stalled progress
+	if o.config.StopOnNoProgress > 0 && o.noProgressCount >= o.config.StopOnNoProgress {
+		return true, fmt.Sprintf("no changes in %d consecutive iterations", o.noProgressCount)
+	}
+
+	if o.reviewStalled != "" {
+		return true, o.reviewStalled
+	}
+
+	// Check for remaining queued work
+	if o.config.QueueMode() && len(o.queue) == 0 {
+		o.goalReached = true
+		return true, "no queued work left"
+	}
+
+	// Check completion signal
+	if o.completionSignalCount >= o.config.CompletionThreshold {
+		return true, "project completion signal detected"
+	}
+
+	// Check task-specific conditions once there is work to check
+	if o.iteration > 1 {
+		done := o.timer.start(report.PhaseGates)
+		name, met := o.checkTaskConditions(ctx)
+		done()
+		if met {
+			o.goalReached = true
+			return true, fmt.Sprintf("stop condition met: %s", name)
+		}
+	}
+
+	return false, ""
+}
+
+func (o *Orchestrator) runIteration(ctx context.Context) (err error) {
+	o.ui.Iteration(o.iteration, o.config.MaxRuns)
+	o.ui.Budget(o.run.TotalCost, o.config.MaxCost, o.run.Tick(), o.config.MaxDuration)
+
+	// Ship work queued while offline before stacking more on top of it
+	if o.deferred != nil {
+		o.flushDeferred(ctx)
+	}
+
+	// Merge PRs whose checks finished meanwhile, so this iteration starts
+	// from the latest base
+	if len(o.inFlight) > 0 {
+		o.reconcile(ctx)
+	}
+
+	o.gates = nil
+	if o.config.CommentCommands {
+		o.pollCommands(ctx)
+		if len(o.followUps) > 0 {
+			return o.runFollowUp(ctx)
+		}
+	}
+	if o.config.QueueMode() {
+		o.nextItem()
+	}
+	if o.config.Backport != "" {
+		return o.runBackport(ctx)
+	}
+
+	// Create feature branch
+	branchName, err := o.iterationBranch()
+	if err != nil {
+		return err
+	}
+	startSHA, _ := o.git.HeadSHA()
+	o.events.Emit(events.IterationStarted, o.iteration, map[string]any{"branch": branchName})
+
+	// Measure coverage without Claude's changes for the coverage gate
+	var coverageBefore *coverage
+	if o.config.MinCoverageDelta > 0 {
+		done := o.timer.start(report.PhaseGates)
+		coverageBefore = o.coverageBaseline(ctx)
+		done()
+	}
+
+	// Claim a part of the work no other worker holds
+	var chunk string
+	if o.config.Partition == "dir" || o.config.Partition == "package" {
+		chunk = o.claimChunk()
+	}
+
+	// Assign the iteration its share of the migration checklist
+	var migrationItems []string
+	if o.config.MigrationCmd != "" {
+		migrationItems = o.updateMigration(ctx, o.config.MigrationBatch)
+	}
+
+	// Read notes for context
+	notesContent, _ := o.notes.Read()
+
+	// Pick up instructions queued by a human since the last iteration
+	instructions, archiveInstructions := o.instructionsSection()
+	sections := []claude.Section{instructions}
+	if len(o.config.Paths) > 0 {
+		sections = append(sections, claude.Section{Title: "PROJECT SCOPE", Body: scopeDescription(o.config.Paths)})
+	}
+	if o.resources != nil {
+		sections = append(sections, claude.Section{Title: "TEST RESOURCES", Body: o.resources.Describe()})
+	}
+
+	// Build prompt
+	if o.iteration <= o.config.RepoMapIterations {
+		sections = append(sections, o.repoMapSection())
+	}
+	if o.config.MergeConfidence > 0 {
+		sections = append(sections, claude.ConfidenceSection())
+	}
+	if o.analysis() {
+		sections = append(sections, claude.AnalysisSection())
+	}
+	if len(migrationItems) > 0 {
+		sections = append(sections, o.migrationPromptSection(migrationItems))
+	}
+	if chunk != "" {
+		sections = append(sections, chunkSection(chunk))
+	}
+	if len(o.brokenMerges) > 0 {
+		sections = append(sections, o.brokenMainSection())
+	}
+	// In evaluate mode completion is judged by a separate call, and in
+	// queue modes by the queue, so Claude isn't told about the phrase at all
+	completionSignal := o.completion
+	if o.config.CompletionMode == "evaluate" || o.config.QueueMode() {
+		completionSignal = claude.Signal{}
+	}
+	prompt, trimmed := claude.BuildPromptWithBudget(
+		o.config.PromptTokenBudget,
+		o.goal(),
+		notesContent,
+		completionSignal,
+		o.iteration,
+		sections...,
+	)
+	if trimmed {
+		o.ui.Info("Trimmed prompt context to fit the %d token budget", o.config.PromptTokenBudget)
+	}
+
+	// Broken merges are delivered once, and instructions once an iteration
+	// acting on them succeeds, so a failed one leaves them for the next
+	o.brokenMerges = nil
+	defer func() {
+		if err == nil {
+			archiveInstructions()
+		}
+	}()
+
+	// Remember the pre-existing dirty state so only Claude's edits get staged
+	snapshot, err := o.git.TakeSnapshot()
+	if err != nil {
+		return fmt.Errorf("failed to snapshot working tree: %w", err)
+	}
+
+	// Run Claude
+	o.ui.StartSpinner("Running Claude...")
+	result, err := o.claude.Run(ctx, prompt)
+	o.ui.StopSpinner()
+	o.recordClaude("iteration", prompt, err != nil || result.IsError)
+	o.addCost(result)
+
+	if err != nil {
+		return fmt.Errorf("Claude execution failed: %w", err)
+	}
+
+	if result.Retries > 0 {
+		o.ui.Warning("Claude hit a transient API error; retried %d time(s)", result.Retries)
+	}
+
+	if result.CostEstimated {
+		o.ui.Debug("Cost estimated from %d input / %d output tokens", result.Usage.InputTokens, result.Usage.OutputTokens)
+	}
+
+	// A blocked Claude waits for a human rather than signaling completion
+	complete := false
+	o.confidence, o.hasConfidence = claude.Confidence(result.Output)
+	if reason, ok := claude.Blocked(result.Output); ok {
+		o.block(reason)
+	} else {
+		complete = o.isComplete(ctx, result.Output)
+	}
+	if complete {
+		o.completionSignalCount++
+		o.ui.Info("Completion signal detected (%d/%d)", o.completionSignalCount, o.config.CompletionThreshold)
+	} else {
+		o.completionSignalCount = 0
+	}
+	transcript := o.saveTranscript(result.Output)
+	o.events.Emit(events.ClaudeFinished, o.iteration, map[string]any{
+		"cost":       result.Cost,
+		"estimated":  result.CostEstimated,
+		"total_cost": o.run.TotalCost,
+		"is_error":   result.IsError,
+		"complete":   complete,
+		"transcript": transcript,
+		"confidence": o.confidenceValue(),
+	})
+
+	// Check for errors
+	if result.IsError {
+		o.ui.Warning("Claude reported an error in output")
+		o.recordGate(gate{name: "Claude", detail: "Claude reported an error in its output"})
+	} else {
+		o.recordGate(gate{name: "Claude", passed: true, detail: "Finished without reporting an error"})
+	}
+
+	// Print output summary
+	o.showOutput(result.Output, transcript)
+
+	if o.analysis() {
+		o.recordFinding(o.iteration, result.Output)
+		return nil
+	}
+
+	// Check for changes
+	if o.config.DisableCommits {
+		o.ui.Info("Commits disabled, skipping PR workflow")
+		return nil
+	}
+
+	if o.config.DryRun {
+		o.ui.Info("Dry run mode, skipping commit and PR")
+		// Switch back to base branch and delete feature branch
+		_ = o.git.SwitchBranch(o.homeBranch)
+		_ = o.git.DeleteBranch(branchName)
+		return nil
+	}
+
+	// Stage and check for changes
+	if err := o.stageChanges(snapshot); err != nil {
+		return fmt.Errorf("failed to stage changes: %w", err)
+	}
+
+	hasChanges, err := o.git.HasStagedChanges()
+	if err != nil {
+		return fmt.Errorf("failed to check for changes: %w", err)
+	}
+
+	if !hasChanges {
+		o.ui.Info("No changes to commit")
+		recorded := o.recordResults(ctx, branchName, result.Output, transcript)
+		if recorded {
+			o.noProgressCount = 0
+		} else {
+			o.noProgressCount++
+		}
+		if o.single != nil {
+			// The single branch holds earlier iterations' commits
+			return nil
+		}
+		_ = o.git.SwitchBranch(o.startBranch())
+		if !recorded || o.config.RecordResults != "commit" {
+			_ = o.git.DeleteBranch(branchName)
+		}
+		return nil
+	}
+
+	o.noProgressCount = 0
+	diffStat, _ := o.git.GetStagedShortStat()
+	o.totalDiff = o.totalDiff.Add(diffStat)
+	o.ui.DiffStat(diffStat.FilesChanged, diffStat.Insertions, diffStat.Deletions)
+
+	// Have Claude create commit
+	if err := o.createCommit(ctx); err != nil {
+		return fmt.Errorf("failed to create commit: %w", err)
+	}
+
+	commitTitle, _ := o.git.GetLastCommitTitle()
+	commitMsg, _ := o.git.GetLastCommitMessage()
+	o.ui.Success("Committed: %s", commitTitle)
+	o.recordCommit(commitTitle)
+	if o.commitConvention.IsEnforced() {
+		if err := o.commitConvention.Validate(commitMsg); err != nil {
+			o.recordGate(gate{name: "Commit convention", detail: err.Error()})
+		} else {
+			o.recordGate(gate{name: "Commit convention", passed: true, detail: "The commit message follows the convention"})
+		}
+	}
+	if o.config.MinCoverageDelta > 0 || o.config.MutationCmd != "" {
+		done := o.timer.start(report.PhaseGates)
+		if o.config.MinCoverageDelta > 0 {
+			o.recordGate(o.coverageGate(ctx, coverageBefore))
+		}
+		if o.config.MutationCmd != "" {
+			o.recordGate(o.mutationGate(ctx))
+		}
+		done()
+	}
+	o.events.Emit(events.CommitCreated, o.iteration, map[string]any{
+		"title":         commitTitle,
+		"files_changed": diffStat.FilesChanged,
+		"insertions":    diffStat.Insertions,
+		"deletions":     diffStat.Deletions,
+	})
+
+	if o.config.UpdateChangelog {
+		if err := o.addChangelogEntry(commitTitle); err != nil {
+			o.ui.Warning("Could not update changelog: %v", err)
+		}
+	}
+	if o.config.SquashCommits {
+		if err := o.squashIteration(startSHA, commitMsg); err != nil {
+			o.ui.Warning("Could not squash the iteration's commits: %v", err)
+		}
+	}
+
+	if o.readOnly != nil {
+		if err := o.recordPatch(branchName, commitTitle, diffStat, transcript); err != nil {
+			return err
+		}
+		o.ui.Duration(o.run.Tick(), o.config.MaxDuration)
+		return nil
+	}
+
+	path := o.mergePath()
+	if path == keepLocal {
+		o.keepBranchLocal(branchName, commitTitle)
+		return nil
+	}
+	if o.config.MergeConfidence > 0 {
+		o.recordGate(o.confidenceGate())
+	}
+
+	// PRs still in flight when the iteration started may have merged since
+	if len(o.inFlight) > 0 {
+		conflicts, err := o.rebaseOnMerged(ctx, branchName)
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			o.ui.Warning("Keeping %s local: it conflicts with PRs merged while it ran (%s)", branchName, strings.Join(conflicts, ", "))
+			_ = o.git.SwitchBranch(o.homeBranch)
+			return nil
+		}
+	}
+
+	// Push branch
+	o.ui.StartSpinner("Pushing branch...")
+	done := o.timer.start(report.PhasePush)
+	err = o.git.PushWithRetry(ctx, branchName, o.config.Retry.Push)
+	done()
+	o.ui.StopSpinner()
+	if err != nil {
+		if o.config.DeferPush && git.IsNetworkError(err) {
+			o.deferPush(branchName, commitTitle, commitMsg, diffStat, path == openDraft)
+			return nil
+		}
+		return fmt.Errorf("failed to push: %w", err)
+	}
+	o.ui.Success("Pushed to origin/%s", branchName)
+	o.recordPush(branchName, false)
+
+	if o.config.BranchMode == "single" {
+		if err := o.shipSingle(ctx, branchName, commitTitle, commitMsg, diffStat); err != nil {
+			return err
+		}
+		o.ui.Duration(o.run.Tick(), o.config.MaxDuration)
+		return nil
+	}
+	if err := o.shipBranch(ctx, commitTitle, formatPRBody(commitMsg, o.iteration, diffStat), o.baseBranch, path == openDraft); err != nil {
+		return err
+	}
+
+	o.ui.Duration(o.run.Tick(), o.config.MaxDuration)
+	if o.config.Verbose {
+		o.github.LogRateLimit(ctx)
+	}
+
+	return nil
+}
+
+// shipBranch opens a PR against base for the pushed current branch, waits
+// for its checks and merges it when they pass, then returns to the home
+// branch. In pipelined mode, PRs against the base branch are left to a
+// background tracker instead and settled in a later iteration. A draft PR
+// is left for a human to review.
+func (o *Orchestrator) shipBranch(ctx context.Context, commitTitle, body, base string, draft bool) error {
+	key := o.taskKey(base)
+	body = withRunID(body, o.run.RunID) + taskMarker(key)
+
+	// Update an open PR with the same work instead of opening a duplicate
+	var prURL string
+	if dup, matched := o.findDuplicatePR(ctx, key, base); dup != nil {
+		url, err := o.updateDuplicatePR(ctx, dup, matched, commitTitle, body)
+		if err != nil {
+			return fmt.Errorf("failed to update PR #%d: %w", dup.Number, err)
+		}
+		prURL = url
+	} else {
+		// Create PR
+		o.ui.StartSpinner("Creating PR...")
+		create := o.github.CreatePR
+		if draft {
+			create = o.github.CreateDraftPR
+		}
+		url, err := create(ctx, commitTitle, body, base)
+		o.ui.StopSpinner()
+
+		if err != nil {
+			return fmt.Errorf("failed to create PR: %w", err)
+		}
+		o.ui.Success("Created PR: %s", url)
+		o.events.Emit(events.PRCreated, o.iteration, map[string]any{"number": github.GetPRNumber(url), "url": url, "title": commitTitle})
+		o.audit.Record(audit.PRCreated, o.iteration, map[string]any{"number": github.GetPRNumber(url), "url": url, "title": commitTitle, "base": base})
+		o.prWindow.record(time.Now())
+		prURL = url
+	}
+
+	prNumber := github.GetPRNumber(prURL)
+	o.labelPR(ctx, prNumber)
+	if o.config.CheckRun {
+		o.publishCheckRun(ctx, prNumber)
+	}
+	o.markHead(prNumber)
+	o.publishGatesStatus(ctx, prNumber)
+	o.prs = append(o.prs, report.PR{
+		Iteration: o.iteration,
+		Number:    prNumber,
+		URL:       prURL,
+		Title:     commitTitle,
+		Outcome:   "open",
+	})
+
+	if draft {
+		o.leaveDraft(ctx, &o.prs[len(o.prs)-1])
+		o.publishOutcome(ctx, &o.prs[len(o.prs)-1])
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return nil
+	}
+
+	o.setStatus(ctx, prNumber, checksStatus, "pending", "Waiting for checks")
+	o.setStatus(ctx, prNumber, mergeStatus, "pending", "Merges once its checks pass")
+
+	if o.config.Pipeline && base == o.baseBranch {
+		o.trackInBackground(len(o.prs)-1, base)
+		_ = o.git.SwitchBranch(o.homeBranch)
+		o.limitInFlight(ctx)
+		return nil
+	}
+
+	// Wait for checks
+	o.ui.StartSpinner("Waiting for PR checks...")
+	done := o.timer.start(report.PhaseChecks)
+	status, err := o.github.WaitForChecks(ctx, prNumber, 30*time.Minute, func(s *github.PRStatus) {
+		o.ui.StopSpinner()
+		o.ui.PRStatus(s.AllChecksPassed, s.HasPendingChecks, s.HasFailedChecks, s.ReviewDecision)
+		if s.HasPendingChecks {
+			o.ui.StartSpinner("Waiting for PR checks...")
+		}
+	})
+	done()
+	o.ui.StopSpinner()
+	return o.settlePR(ctx, len(o.prs)-1, base, status, err)
+}
+
+// settlePR acts on the checks of a PR opened earlier: merges it if they
+// passed, closes it if they failed, and otherwise leaves it open. It ends
+// on the home branch, updated with the base when the PR was merged.
+func (o *Orchestrator) settlePR(ctx context.Context, index int, base string, status *github.PRStatus, waitErr error) error {
+	pr := &o.prs[index]
+	prNumber := pr.Number
+	o.publishChecksStatus(ctx, prNumber, status, waitErr)
+	defer o.publishOutcome(ctx, pr)
+	if status != nil {
+		o.events.Emit(events.PRChecks, pr.Iteration, map[string]any{
+			"number":          prNumber,
+			"passed":          status.AllChecksPassed,
+			"failed":          status.HasFailedChecks,
+			"pending":         status.HasPendingChecks,
+			"review_decision": status.ReviewDecision,
+		})
+	}
+
+	if errors.Is(waitErr, context.Canceled) {
+		o.ui.Warning("Stopped waiting for checks of PR #%s; leaving it open", prNumber)
+		pr.Outcome = "open: interrupted while waiting for checks"
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return nil
+	}
+	if errors.Is(waitErr, context.DeadlineExceeded) {
+		o.ui.Warning("Iteration ran out of time waiting for checks of PR #%s; leaving it open", prNumber)
+		pr.Outcome = "open: iteration timed out waiting for checks"
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return nil
+	}
+	if waitErr != nil {
+		o.ui.Warning("Timeout waiting for checks: %v", waitErr)
+		pr.Outcome = "open: timed out waiting for checks"
+		// Can't determine check status, skip merge and continue to next iteration
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return nil
+	}
+
+	// Handle check results
+	if (status == nil || status.HasFailedChecks) && o.single != nil && index == o.single.pr {
+		o.ui.Error("Checks failed on PR #%s; leaving it open with the run's work", prNumber)
+		pr.Outcome = "open: checks failed"
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return nil
+	}
+	if status == nil || status.HasFailedChecks {
+		o.ui.Error("Checks failed, closing PR #%s", prNumber)
+		pr.Outcome = "closed: checks failed"
+		o.events.Emit(events.PRClosed, pr.Iteration, map[string]any{"number": prNumber, "reason": "checks failed"})
+		if err := o.github.ClosePR(ctx, prNumber, true); err == nil {
+			o.audit.Record(audit.PRClosed, pr.Iteration, map[string]any{"number": prNumber, "reason": "checks failed", "branch_deleted": true})
+		}
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return nil
+	}
+
+	if !status.IsMergeable && status.ReviewDecision == "REVIEW_REQUIRED" && o.config.ReviewWait > 0 {
+		done := o.timer.start(report.PhaseReview)
+		status = o.awaitReview(ctx, pr)
+		done()
+		if status == nil {
+			_ = o.git.SwitchBranch(o.homeBranch)
+			return nil
+		}
+	}
+	if !status.IsMergeable {
+		o.ui.Warning("PR #%s not mergeable (review required?)", prNumber)
+		pr.Outcome = "open: not mergeable"
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return nil
+	}
+	if o.config.MergeApproval {
+		o.ui.Info("PR #%s is ready; leaving it open until its merge is approved", prNumber)
+		pr.Outcome = "open: awaiting approval"
+		o.events.Emit(events.MergePending, pr.Iteration, map[string]any{"number": prNumber, "url": pr.URL, "strategy": o.config.MergeStrategy})
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return nil
+	}
+
+	// Merge PR
+	o.ui.StartSpinner("Merging PR...")
+	done := o.timer.start(report.PhaseMerge)
+	err := o.github.MergePR(ctx, prNumber, o.config.MergeStrategy)
+	done()
+	if err != nil {
+		o.ui.StopSpinner()
+		pr.Outcome = "open: merge failed"
+		return fmt.Errorf("failed to merge PR: %w", err)
+	}
+	o.ui.StopSpinner()
+	o.ui.Success("Merged PR #%s", prNumber)
+	pr.Outcome = "merged"
+	o.events.Emit(events.PRMerged, pr.Iteration, map[string]any{"number": prNumber, "strategy": o.config.MergeStrategy})
+	o.audit.Record(audit.PRMerged, pr.Iteration, map[string]any{"number": prNumber, "strategy": o.config.MergeStrategy, "base": base})
+
+	// Pull changes to base branch. Merges into other branches (backports)
+	// don't count towards releases.
+	_ = o.git.SwitchBranch(o.homeBranch)
+	if base != o.baseBranch {
+		return nil
+	}
+	done = o.timer.start(report.PhaseMerge)
+	_ = o.git.Pull(ctx, o.baseBranch)
+	done()
+	if o.config.RevertOnMainFailure != "" && o.watchMain(ctx, pr) {
+		return nil
+	}
+	if o.deployGated() {
+		done := o.timer.start(report.PhaseMerge)
+		err := o.waitForDeploy(ctx, pr)
+		done()
+		if err != nil {
+			return err
+		}
+	}
+
+	o.unreleasedEntries = append(o.unreleasedEntries, changelog.FormatEntry(pr.Title, prNumber))
+	o.unreleasedTitles = append(o.unreleasedTitles, pr.Title)
+	if o.config.ReleaseEvery > 0 && len(o.unreleasedEntries) >= o.config.ReleaseEvery {
+		o.publishRelease(ctx)
+	}
+
+	return nil
+}
+
+// saveTranscript stores Claude's output for the iteration in the run
+// directory for replays and returns its file name, or "" if it wasn't saved.
+func (o *Orchestrator) saveTranscript(output string) string {
+	if o.runDir == "" {
+		return ""
+	}
+
+	dir := filepath.Join(o.runDir, replay.TranscriptsDir)
+	name := fmt.Sprintf("iteration-%03d.md", o.iteration)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		o.ui.Warning("Could not save transcript: %v", err)
+		return ""
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(o.redactor.String(output)), 0644); err != nil {
+		o.ui.Warning("Could not save transcript: %v", err)
+		return ""
+	}
+	return name
+}
+
+// runReport collects the totals and PRs of the run.
+func (o *Orchestrator) runReport() *report.Run {
+	run := &report.Run{
+		RunID:        o.run.RunID,
+		Prompt:       o.runPrompt(),
+		Iterations:   o.run.Iterations,
+		TotalCost:    o.run.TotalCost,
+		Elapsed:      o.run.Tick(),
+		Completed:    o.goalReached || o.completionSignalCount >= o.config.CompletionThreshold,
+		StopReason:   o.stopReason,
+		FilesChanged: o.totalDiff.FilesChanged,
+		Insertions:   o.totalDiff.Insertions,
+		Deletions:    o.totalDiff.Deletions,
+		PRs:          o.prs,
+		Backports:    o.backports,
+		Tags:         o.costTags(),
+		AuditHead:    o.audit.Head(),
+		History:      o.run.History,
+
+		MigrationDone:  o.migrationDone,
+		MigrationTotal: o.migrationTotal,
+	}
+	if o.readOnly != nil {
+		run.ReadOnly = true
+		run.Patches = o.readOnly.patches
+		run.PatchBranch = o.readOnly.branch
+	}
+	return run
+}
+
+// Usage returns the anonymous usage of the run for telemetry.
+func (o *Orchestrator) Usage() telemetry.Usage {
+	usage := telemetry.Usage{
+		Iterations: o.run.Iterations,
+		PRsOpened:  len(o.prs),
+		Outcome:    "stopped",
+		Failures:   o.failures,
+	}
+	for _, pr := range o.prs {
+		if pr.Outcome == "merged" {
+			usage.PRsMerged++
+		}
+	}
+	// The stop reason itself may name a user's command
+	if _, reached := o.run.LimitReached(); reached {
+		usage.Outcome = "limit reached"
+	}
+	if o.goalReached || o.completionSignalCount >= o.config.CompletionThreshold {
+		usage.Outcome = "completed"
+	}
+	return usage
+}
+
+// notify comments on the summary issue, if there is one, so that someone
+// who isn't watching the terminal learns the run needs them.
+func (o *Orchestrator) notify(ctx context.Context, body string) {
+	if o.config.PublishSummary != "comment" {
+		return
+	}
+	if len(o.config.Labels) > 0 {
+		body += "\n\nLabels: `" + strings.Join(o.config.Labels, "`, `") + "`"
+	}
+	url, err := o.github.CommentOnIssue(ctx, strings.TrimPrefix(o.config.SummaryIssue, "#"), body)
+	if err != nil {
+		o.ui.Warning("Could not post notice to %s: %v", o.config.SummaryIssue, err)
+		return
+	}
+	o.ui.Info("Posted notice: %s", url)
+}
+
+// publishSummary posts the run summary to GitHub so the record outlives
+// the terminal session.
+func (o *Orchestrator) publishSummary(ctx context.Context, run *report.Run) {
+	var url string
+	var err error
+
+	o.ui.StartSpinner("Publishing run summary...")
+	switch o.config.PublishSummary {
+	case "gist":
+		url, err = o.github.CreateGist(ctx, "deep-claude-run.md",
+			fmt.Sprintf("Deep Claude run summary for %s/%s", o.github.Owner(), o.github.Repo()), run.Markdown())
+	case "comment":
+		url, err = o.github.CommentOnIssue(ctx, strings.TrimPrefix(o.config.SummaryIssue, "#"), run.Markdown())
+	}
+	o.ui.StopSpinner()
+
+	if err != nil {
+		o.ui.Warning("Could not publish run summary: %v", err)
+		return
+	}
+	o.ui.Success("Published run summary: %s", url)
+	o.audit.Record(audit.SummaryPublished, 0, map[string]any{"target": o.config.PublishSummary, "url": url})
+}
+
+// addChangelogEntry records the iteration's change in the changelog as a
+// separate commit on the feature branch, so it lands together with the PR.
+func (o *Orchestrator) addChangelogEntry(title string) error {
+	path := filepath.Join(o.workDir, o.config.ChangelogFile)
+	if err := changelog.AddEntry(path, changelog.FormatEntry(title, "")); err != nil {
+		return err
+	}
+	if err := o.git.StagePaths([]string{o.config.ChangelogFile}, nil); err != nil {
+		return err
+	}
+	message := "docs: update " + o.config.ChangelogFile
+	if err := o.git.Commit(message); err != nil {
+		return err
+	}
+	o.recordCommit(message)
+	return nil
+}
+
+// publishRelease creates a GitHub release covering the PRs merged since the last one.
+func (o *Orchestrator) publishRelease(ctx context.Context) {
+	// Without the latest release the next version can't be told, and
+	// guessing could tag one that already exists
+	latest, err := o.github.GetLatestRelease(ctx, o.github.Owner(), o.github.Repo())
+	if err != nil {
+		o.ui.Warning("Skipping the release: %v", err)
+		return
+	}
+	tag := changelog.NextVersion(latest, changelog.DetermineBump(o.unreleasedTitles))
+
+	o.ui.StartSpinner(fmt.Sprintf("Creating release %s...", tag))
+	url, err := o.github.CreateRelease(ctx, tag, tag, changelog.ReleaseNotes(o.unreleasedEntries), o.baseBranch)
+	o.ui.StopSpinner()
+	if err != nil {
+		o.ui.Warning("Could not create release: %v", err)
+		return
+	}
+
+	o.ui.Success("Created release %s: %s", tag, url)
+	o.audit.Record(audit.ReleaseCreated, 0, map[string]any{"tag": tag, "url": url, "target": o.baseBranch})
+	o.unreleasedEntries = nil
+	o.unreleasedTitles = nil
+}
+
+// showOutput prints Claude's output, cut to --output-limit characters
+// unless --show-full-output, pointing to its transcript for the rest, and
+// opens all of it in $PAGER with --page-output.
+func (o *Orchestrator) showOutput(output, transcript string) {
+	shown := output
+	if limit := o.config.OutputLimit; limit > 0 && !o.config.ShowFullOutput && len(output) > limit {
+		shown = truncateOutput(output, limit)
+		if transcript != "" {
+			shown += "\nFull output: " + filepath.Join(o.runDir, replay.TranscriptsDir, transcript)
+		}
+	}
+	o.ui.Markdown("Claude Output", shown)
+	if o.config.PageOutput {
+		if err := o.ui.Page(output); err != nil {
+			o.ui.Warning("Could not open the pager: %v", err)
+		}
+	}
+}
+
+func truncateOutput(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	// Don't cut a multi-byte character in half
+	for maxLen > 0 && !utf8.RuneStart(s[maxLen]) {
+		maxLen--
+	}
+	return s[:maxLen] + "\n...[truncated]"
+}
+
+// isComplete decides whether this iteration signals that the project is done:
+// either the completion phrase appears in the output, or in evaluate mode a
+// separate self-evaluation call reports completion with enough confidence.
+func (o *Orchestrator) isComplete(ctx context.Context, output string) bool {
+	if o.config.QueueMode() {
+		return false
+	}
+	if o.config.CompletionMode != "evaluate" {
+		return o.completion.Matches(output)
+	}
+
+	o.ui.StartSpinner("Evaluating progress...")
+	eval, err := o.claude.Evaluate(ctx, o.goal())
+	o.ui.StopSpinner()
+	o.recordClaude("evaluate", o.goal(), err != nil)
+	if err != nil {
+		o.ui.Warning("Self-evaluation failed: %v", err)
+		return false
+	}
+
+	o.run.TotalCost += eval.Cost
+	if eval.Complete {
+		o.ui.Info("Self-evaluation: complete (confidence %.0f%%)", eval.Confidence*100)
+	} else {
+		o.ui.Info("Self-evaluation: incomplete (confidence %.0f%%), remaining: %s", eval.Confidence*100, eval.RemainingWork)
+	}
+	return eval.Complete && eval.Confidence >= o.config.MinConfidence
+}
+
+// repoMapSection returns the repository map prompt section, reusing the
+// map cached in the git directory until the tree changes significantly.
+func (o *Orchestrator) repoMapSection() claude.Section {
+	section := claude.Section{Title: "REPOSITORY MAP"}
+
+	files, err := o.git.ListFiles()
+	if err != nil {
+		o.ui.Warning("Could not build repo map: %v", err)
+		return section
+	}
+	gitDir, err := o.git.GitDir()
+	if err != nil {
+		o.ui.Warning("Could not build repo map: %v", err)
+		return section
+	}
+
+	m, rebuilt, err := repomap.LoadOrBuild(filepath.Join(gitDir, "deep-claude", "repomap.json"), files)
+	if err != nil {
+		o.ui.Warning("Could not cache repo map: %v", err)
+	}
+	if rebuilt {
+		o.ui.Info("Generated repo map (%d files)", m.FileCount)
+	}
+
+	section.Body = "An overview of the repository layout, so you don't need to rediscover it:\n\n```\n" +
+		strings.TrimRight(m.Text, "\n") + "\n```"
+	return section
+}
+
+// withRunID tags a PR body with the run that opened it.
+func withRunID(body, runID string) string {
+	return fmt.Sprintf("%s\n<sub>Run ID: `%s`</sub>\n", strings.TrimRight(body, "\n"), runID)
+}
+
+func formatPRBody(commitMsg string, iteration int, diffStat git.DiffStat) string {
+	return fmt.Sprintf(`## Continuous Claude - Iteration %d
+
+%s
+
+**Changes:** %s
+
+---
+*This PR was created automatically by Continuous Claude.*
+`, iteration, commitMsg, diffStat)
+}
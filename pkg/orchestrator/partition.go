@@ -0,0 +1,96 @@
+package orchestrator
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/git"
+)
+
+// partitionChunks splits the tracked files into the chunks --partition
+// dir or package claims: the directories at the top of the repository, or
+// of each --path project, or every directory that holds files. Files
+// outside any directory belong to no chunk.
+func partitionChunks(files, paths []string, by string) []string {
+	if len(paths) > 0 {
+		files = git.FilterPaths(files, paths)
+	}
+	seen := make(map[string]bool)
+	var chunks []string
+	for _, file := range files {
+		chunk := path.Dir(file)
+		if by == "dir" {
+			chunk = topDir(file, paths)
+		}
+		if chunk != "" && chunk != "." && !seen[chunk] {
+			seen[chunk] = true
+			chunks = append(chunks, chunk)
+		}
+	}
+	sort.Strings(chunks)
+	return chunks
+}
+
+// topDir returns the directory at the top of the repository, or of the
+// --path project, that file is in.
+func topDir(file string, paths []string) string {
+	base := ""
+	for _, p := range paths {
+		p = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(p)), "/")
+		if p != "." && strings.HasPrefix(file, p+"/") {
+			base = p + "/"
+			break
+		}
+	}
+	first, _, ok := strings.Cut(strings.TrimPrefix(file, base), "/")
+	if !ok {
+		return ""
+	}
+	return base + first
+}
+
+// claimChunk releases the last iteration's chunk and claims the next one
+// no other worker holds, going round the chunks so the worker moves on
+// through the repository. It returns "" if there is none to claim.
+func (o *Orchestrator) claimChunk() string {
+	o.chunks.Release()
+	files, err := o.git.ListFiles()
+	if err != nil {
+		o.ui.Warning("Could not list the files to partition: %v", err)
+		return ""
+	}
+	chunks := partitionChunks(files, o.config.Paths, o.config.Partition)
+	if len(chunks) == 0 {
+		o.ui.Warning("No directories to partition the work by")
+		return ""
+	}
+
+	start := sort.SearchStrings(chunks, o.chunk)
+	if start < len(chunks) && chunks[start] == o.chunk {
+		start++
+	}
+	for i := range chunks {
+		chunk := chunks[(start+i)%len(chunks)]
+		if o.chunks.Claim(chunk) {
+			o.chunk = chunk
+			o.ui.Info("Working on %s; other workers leave it alone", chunk)
+			return chunk
+		}
+	}
+	o.ui.Warning("Other workers hold all %d chunks of the work, so this iteration isn't confined to one", len(chunks))
+	return ""
+}
+
+// chunkSection confines Claude to the iteration's chunk of the work.
+func chunkSection(chunk string) claude.Section {
+	return claude.Section{
+		Title: "WORK CHUNK",
+		Body: fmt.Sprintf("Other workers are working on this repository at the same time, each in its own part of it. "+
+			"This iteration's part is `%s`: make your changes there and leave the rest of the repository to the other workers, so your pull requests don't conflict. "+
+			"Change files outside it only where a change in it can't work otherwise, such as in a dependency manifest.", chunk),
+	}
+}
@@ -0,0 +1,29 @@
+package orchestrator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPartitionChunks(t *testing.T) {
+	files := []string{"go.mod", "cmd/app/main.go", "pkg/db/db.go", "pkg/db/db_test.go", "pkg/db/sql/query.go", "services/api/main.go", "services/api/handlers/user.go"}
+	tests := []struct {
+		name  string
+		by    string
+		paths []string
+		want  []string
+	}{
+		{name: "by dir", by: "dir", want: []string{"cmd", "pkg", "services"}},
+		{name: "by package", by: "package", want: []string{"cmd/app", "pkg/db", "pkg/db/sql", "services/api", "services/api/handlers"}},
+		{name: "by dir of a project", by: "dir", paths: []string{"services/api/"}, want: []string{"services/api/handlers"}},
+		{name: "by package of a project", by: "package", paths: []string{"pkg"}, want: []string{"pkg/db", "pkg/db/sql"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := partitionChunks(files, tt.paths, tt.by); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("partitionChunks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
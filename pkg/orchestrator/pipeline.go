@@ -0,0 +1,137 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/report"
+)
+
+// trackerInterval is how often the checks of in-flight PRs are polled.
+var trackerInterval = 10 * time.Second
+
+// inFlightPR is a PR whose checks are being watched while the next
+// iteration runs.
+type inFlightPR struct {
+	index int
+	base  string
+}
+
+// trackInBackground has the run's tracker watch the checks of o.prs[index].
+// Only the polling happens in the background; PRs are settled on the main
+// goroutine by reconcile, so the working tree and the run state have a
+// single owner. The next iteration runs in that same tree rather than a
+// worktree of its own, and rebaseOnMerged catches it up with the PRs that
+// merged while it ran.
+func (o *Orchestrator) trackInBackground(index int, base string) {
+	number := o.prs[index].Number
+	o.tracker.Watch(number, 30*time.Minute)
+
+	o.inFlight = append(o.inFlight, &inFlightPR{index: index, base: base})
+	o.ui.Info("Waiting for checks of PR #%s in the background (%d in flight)", number, len(o.inFlight))
+}
+
+// reconcile settles the in-flight PRs whose checks have finished, pulling
+// the base branch when one is merged, without waiting for the others.
+func (o *Orchestrator) reconcile(ctx context.Context) {
+	for {
+		select {
+		case u := <-o.tracker.Updates():
+			o.handleUpdate(ctx, u)
+		default:
+			return
+		}
+	}
+}
+
+// rebaseOnMerged settles the in-flight PRs whose checks finished while the
+// iteration ran, then rebases its branch onto the home branch, so that its
+// PR builds on those that merged rather than on the base the iteration
+// started from. PRs still in flight stay out of it. It returns the files
+// that conflict with the merged PRs, leaving the branch as it was.
+func (o *Orchestrator) rebaseOnMerged(ctx context.Context, branch string) ([]string, error) {
+	o.reconcile(ctx)
+	if err := o.git.SwitchBranch(branch); err != nil {
+		return nil, fmt.Errorf("failed to switch to branch: %w", err)
+	}
+	return o.git.Rebase(o.homeBranch)
+}
+
+// limitInFlight blocks on the oldest PRs while more than --pipeline-depth
+// are waiting for checks, until the iteration's ctx is done.
+func (o *Orchestrator) limitInFlight(ctx context.Context) {
+	o.reconcile(ctx)
+	for len(o.inFlight) > o.config.PipelineDepth {
+		if !o.waitOldest(ctx, ctx.Done()) {
+			return
+		}
+	}
+}
+
+// drainInFlight waits for and settles every in-flight PR, then stops the
+// tracker.
+func (o *Orchestrator) drainInFlight(ctx context.Context) {
+	for len(o.inFlight) > 0 {
+		o.waitOldest(ctx, nil)
+	}
+	o.tracker.Close()
+	o.tracker = nil
+}
+
+// waitOldest blocks until the oldest in-flight PR is settled, settling any
+// other PR that finishes first. It returns false if stop is closed first;
+// the tracker itself only stops with the run.
+func (o *Orchestrator) waitOldest(ctx context.Context, stop <-chan struct{}) bool {
+	oldest := o.inFlight[0]
+	number := o.prs[oldest.index].Number
+
+	o.ui.StartSpinner("Waiting for checks of PR #" + number + "...")
+	for o.isInFlight(oldest) {
+		done := o.timer.start(report.PhaseChecks)
+		select {
+		case u := <-o.tracker.Updates():
+			done()
+			o.ui.StopSpinner()
+			o.handleUpdate(ctx, u)
+		case <-stop:
+			done()
+			o.ui.StopSpinner()
+			return false
+		}
+		if o.isInFlight(oldest) {
+			o.ui.StartSpinner("Waiting for checks of PR #" + number + "...")
+		}
+	}
+	return true
+}
+
+// handleUpdate settles a PR once the tracker reports its checks done.
+func (o *Orchestrator) handleUpdate(ctx context.Context, u github.Update) {
+	if !u.Done {
+		return
+	}
+	for i, f := range o.inFlight {
+		if o.prs[f.index].Number != u.Number {
+			continue
+		}
+		o.inFlight = append(o.inFlight[:i], o.inFlight[i+1:]...)
+		if u.Status != nil && u.Err == nil {
+			o.ui.PRStatus(u.Status.AllChecksPassed, u.Status.HasPendingChecks, u.Status.HasFailedChecks, u.Status.ReviewDecision)
+		}
+		if err := o.settlePR(ctx, f.index, f.base, u.Status, u.Err); err != nil {
+			o.ui.Error("PR #%s: %v", u.Number, err)
+		}
+		return
+	}
+}
+
+func (o *Orchestrator) isInFlight(f *inFlightPR) bool {
+	for _, g := range o.inFlight {
+		if g == f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/fake"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// newPipelineHarness returns a harness for a pipelined run that polls the
+// in-flight PRs' checks without waiting between polls.
+func newPipelineHarness(t *testing.T, depth int, turns ...fake.Turn) *harness {
+	interval := trackerInterval
+	trackerInterval = time.Millisecond
+	t.Cleanup(func() { trackerInterval = interval })
+
+	h := newHarness(t, turns...)
+	h.cfg.Pipeline = true
+	h.cfg.PipelineDepth = depth
+	return h
+}
+
+// recordPRs has the harness list when iterations start and PRs are opened,
+// merged and closed, such as "merged #1".
+func (h *harness) recordPRs() *[]string {
+	var got []string
+	h.subscriber = func(e events.Event) {
+		switch e.Type {
+		case events.IterationStarted:
+			got = append(got, fmt.Sprintf("iteration %d", e.Iteration))
+		case events.PRCreated:
+			got = append(got, fmt.Sprintf("opened #%v", e.Data["number"]))
+		case events.PRMerged:
+			got = append(got, fmt.Sprintf("merged #%v", e.Data["number"]))
+		case events.PRClosed:
+			got = append(got, fmt.Sprintf("closed #%v", e.Data["number"]))
+		}
+	}
+	return &got
+}
+
+func TestPipelineWaitsBeyondDepth(t *testing.T) {
+	h := newPipelineHarness(t, 1,
+		fake.Turn{Edits: []string{"a.go"}, CommitMessage: "Add a"},
+		fake.Turn{Edits: []string{"b.go"}, CommitMessage: "Add b"},
+		fake.Turn{Edits: []string{"c.go"}, CommitMessage: "Add c"},
+	)
+	h.github.PendingPolls = []int{1, 1, 1}
+	got := h.recordPRs()
+	h.run()
+
+	// Each iteration starts with at most one PR awaiting its checks
+	inFlight, merged := 0, 0
+	for _, e := range *got {
+		switch {
+		case strings.HasPrefix(e, "iteration") && inFlight > 1:
+			t.Errorf("%s started with %d PRs in flight (run went %v)", e, inFlight, *got)
+		case strings.HasPrefix(e, "opened"):
+			inFlight++
+		case strings.HasPrefix(e, "merged"):
+			inFlight--
+			merged++
+		}
+	}
+	if merged != 3 {
+		t.Errorf("merged %d PRs, want 3 (run went %v)", merged, *got)
+	}
+}
+
+func TestPipelineSettlesChecksFinishingOutOfOrder(t *testing.T) {
+	h := newPipelineHarness(t, 2,
+		fake.Turn{Edits: []string{"a.go"}, CommitMessage: "Add a"},
+		fake.Turn{Edits: []string{"b.go"}, CommitMessage: "Add b"},
+	)
+	// The first PR's checks take longer than the second's
+	h.github.PendingPolls = []int{3, 0}
+	got := h.recordPRs()
+	o := h.run()
+
+	want := []string{"iteration 1", "opened #1", "iteration 2", "opened #2", "merged #2", "merged #1"}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("run went %v, want %v", *got, want)
+	}
+	for _, pr := range o.prs {
+		if pr.Outcome != "merged" {
+			t.Errorf("PR #%s outcome = %q, want merged", pr.Number, pr.Outcome)
+		}
+	}
+	if titles := h.titles(); len(titles) != 2 {
+		t.Errorf("main has %v, want both PRs merged", titles)
+	}
+}
+
+func TestPipelineDrainsInFlightPRsAtRunEnd(t *testing.T) {
+	h := newPipelineHarness(t, 2,
+		fake.Turn{Edits: []string{"a.go"}, CommitMessage: "Add a"},
+		fake.Turn{Edits: []string{"b.go"}, CommitMessage: "Break b"},
+	)
+	h.github.Statuses = []*github.PRStatus{fake.Passing(), fake.Failing()}
+	h.github.PendingPolls = []int{1, 1}
+	var finished []string
+	got := h.recordPRs()
+	record := h.subscriber
+	h.subscriber = func(e events.Event) {
+		record(e)
+		if e.Type == events.RunFinished {
+			finished = slices.Clone(*got)
+		}
+	}
+	o := h.run()
+
+	// The two PRs' checks may finish in either order
+	want := []string{"iteration 1", "opened #1", "iteration 2", "opened #2", "closed #2", "merged #1"}
+	if len(finished) > 4 {
+		slices.Sort(finished[4:])
+	}
+	if !reflect.DeepEqual(finished, want) {
+		t.Errorf("run finished after %v, want %v", finished, want)
+	}
+	if len(o.inFlight) != 0 || o.tracker != nil {
+		t.Errorf("run ended with %d PRs in flight", len(o.inFlight))
+	}
+	if o.prs[0].Outcome != "merged" || o.prs[1].Outcome != "closed: checks failed" {
+		t.Errorf("outcomes = %q, %q; want merged and closed", o.prs[0].Outcome, o.prs[1].Outcome)
+	}
+}
+
+// settlingClaude holds its second turn until the first PR's checks have
+// been polled, so that PR finishes while the iteration runs.
+type settlingClaude struct {
+	*fake.Claude
+	github *fake.GitHub
+	runs   int
+}
+
+func (c *settlingClaude) Run(ctx context.Context, prompt string) (*claude.Result, error) {
+	if c.runs++; c.runs == 2 {
+		for c.github.Polls("1") == 0 && ctx.Err() == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+		// Let the tracker deliver the update
+		time.Sleep(100 * time.Millisecond)
+	}
+	return c.Claude.Run(ctx, prompt)
+}
+
+func TestPipelineRebasesOnPRsMergedMeanwhile(t *testing.T) {
+	tests := []struct {
+		name       string
+		second     string
+		wantPRs    int
+		wantRebase bool
+	}{
+		{"other files", "b.go", 2, true},
+		{"same file", "a.go", 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Checks pass at their first poll, so the tracker needn't poll
+			// again; ticking less often keeps its loop ready to deliver
+			h := newHarness(t,
+				fake.Turn{Edits: []string{"a.go"}, CommitMessage: "Add a"},
+				fake.Turn{Edits: []string{tt.second}, CommitMessage: "Change " + tt.second},
+			)
+			h.cfg.Pipeline = true
+			h.cfg.PipelineDepth = 2
+			got := h.recordPRs()
+			h.runWith(t.Context(), &settlingClaude{Claude: h.claude, github: h.github})
+
+			prs := h.github.PRs()
+			if len(prs) != tt.wantPRs {
+				t.Fatalf("opened %d PRs, want %d (run went %v)", len(prs), tt.wantPRs, *got)
+			}
+			if !slices.Contains(*got, "merged #1") {
+				t.Errorf("run went %v, want PR #1 merged", *got)
+			}
+			if !tt.wantRebase {
+				return
+			}
+			// The second PR merged on top of the first, whose commit its
+			// branch now holds
+			first := h.git.RemoteLog("main")[1]
+			var branch []string
+			for _, b := range h.git.Branches() {
+				holds := func(c fake.Commit) bool { return c.SHA == first.SHA }
+				if b != "main" && slices.ContainsFunc(h.git.Log(b), holds) {
+					branch = append(branch, b)
+				}
+			}
+			if len(branch) != 1 {
+				t.Errorf("branches holding PR #1's merge = %v, want the second iteration's", branch)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/retry"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// prWindow caps how many PRs are opened per hour and per day, over a
+// sliding window.
+type prWindow struct {
+	perHour int
+	perDay  int
+	opened  []time.Time
+}
+
+// record notes that a PR was opened at t.
+func (w *prWindow) record(t time.Time) {
+	if w != nil {
+		w.opened = append(w.opened, t)
+	}
+}
+
+// wait returns how long after now until another PR may be opened, 0 if
+// one may be opened right away.
+func (w *prWindow) wait(now time.Time) time.Duration {
+	if w == nil {
+		return 0
+	}
+
+	// Only the last day matters
+	kept := w.opened[:0]
+	for _, t := range w.opened {
+		if now.Sub(t) < 24*time.Hour {
+			kept = append(kept, t)
+		}
+	}
+	w.opened = kept
+
+	var wait time.Duration
+	for _, limit := range []struct {
+		max    int
+		period time.Duration
+	}{{w.perHour, time.Hour}, {w.perDay, 24 * time.Hour}} {
+		if limit.max <= 0 {
+			continue
+		}
+		var inPeriod []time.Time
+		for _, t := range w.opened {
+			if now.Sub(t) < limit.period {
+				inPeriod = append(inPeriod, t)
+			}
+		}
+		if len(inPeriod) < limit.max {
+			continue
+		}
+		// A slot frees up when the oldest PR that fills the limit leaves
+		// the period
+		if d := inPeriod[len(inPeriod)-limit.max].Add(limit.period).Sub(now); d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// seedPRWindow counts the PRs a resumed run opened before, from its
+// event log.
+func (o *Orchestrator) seedPRWindow() {
+	if o.prWindow == nil || o.config.Resume == "" || o.runDir == "" {
+		return
+	}
+	created, err := events.Read(filepath.Join(o.runDir, events.FileName), events.PRCreated)
+	if err != nil {
+		return
+	}
+	for _, e := range created {
+		o.prWindow.record(e.Time)
+	}
+}
+
+// waitForPRWindow holds off the next iteration until it may open a PR
+// under --max-prs-per-hour and --max-prs-per-day. It returns false, with
+// the reason, if the wait would outlast --max-duration.
+func (o *Orchestrator) waitForPRWindow(ctx context.Context) (bool, string) {
+	wait := o.prWindow.wait(time.Now())
+	if wait <= 0 {
+		return true, ""
+	}
+	wait = wait.Round(time.Second)
+
+	if o.config.HasMaxDuration() && o.run.Tick()+wait >= o.config.MaxDuration {
+		return false, fmt.Sprintf("PR rate limit would delay the next iteration past max duration (%s)", config.FormatDuration(o.config.MaxDuration))
+	}
+
+	o.ui.Info("PR rate limit reached, waiting %s before the next iteration", config.FormatDuration(wait))
+	if err := retry.Sleep(ctx, wait); err != nil {
+		return false, "interrupted"
+	}
+	return true, ""
+}
@@ -0,0 +1,36 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPRWindowWait(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+
+	tests := []struct {
+		name   string
+		window *prWindow
+		opened []time.Time
+		want   time.Duration
+	}{
+		{"no limits", nil, []time.Time{ago(time.Minute)}, 0},
+		{"under hourly limit", &prWindow{perHour: 3}, []time.Time{ago(10 * time.Minute), ago(5 * time.Minute)}, 0},
+		{"hourly limit", &prWindow{perHour: 2}, []time.Time{ago(50 * time.Minute), ago(20 * time.Minute), ago(5 * time.Minute)}, 40 * time.Minute},
+		{"older PRs left the hour", &prWindow{perHour: 2}, []time.Time{ago(90 * time.Minute), ago(70 * time.Minute)}, 0},
+		{"daily limit", &prWindow{perHour: 10, perDay: 2}, []time.Time{ago(20 * time.Hour), ago(2 * time.Hour)}, 4 * time.Hour},
+		{"longest wait wins", &prWindow{perHour: 1, perDay: 2}, []time.Time{ago(23 * time.Hour), ago(30 * time.Minute)}, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, opened := range tt.opened {
+				tt.window.record(opened)
+			}
+			if got := tt.window.wait(now); got != tt.want {
+				t.Errorf("wait() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package orchestrator
+
+import "strings"
+
+// workItem is one iteration's share of a task that a mode (dependency
+// upgrades, lint fixes, backports) splits up before the run starts. Each
+// item gets its own iteration and PR.
+type workItem struct {
+	title string
+	goal  string
+	// Branch the item's PR targets, for backports
+	target string
+}
+
+// nextItem takes this iteration's work item off the queue.
+func (o *Orchestrator) nextItem() {
+	o.current = &o.queue[0]
+	o.queue = o.queue[1:]
+	o.ui.Info("%s (%d more queued)", o.current.title, len(o.queue))
+}
+
+// runPrompt is the task of the whole run.
+func (o *Orchestrator) runPrompt() string {
+	if o.config.Prompt != "" {
+		return o.config.Prompt
+	}
+	switch {
+	case o.config.UpgradeDeps:
+		return defaultUpgradeTask
+	case o.config.LintFix:
+		return defaultLintTask
+	case o.config.Backport != "":
+		return "Backport PR #" + o.config.Backport
+	}
+	return ""
+}
+
+// goal is what Claude is asked to do this iteration.
+func (o *Orchestrator) goal() string {
+	if o.current != nil {
+		return o.current.goal
+	}
+	return o.config.Prompt
+}
+
+// goalTitle is a one-line summary of this iteration's goal.
+func (o *Orchestrator) goalTitle() string {
+	if o.current != nil {
+		return o.current.title
+	}
+	return strings.TrimSpace(o.runPrompt())
+}
@@ -0,0 +1,147 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/replay"
+	"github.com/guzus/deep-claude/internal/report"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// reportFile is the read-only report written next to the patches.
+const reportFile = "REPORT.md"
+
+// readOnlyWork is what a --read-only run would have shipped. Iterations
+// stack on the branch of the latest patch, as if its PR had merged.
+type readOnlyWork struct {
+	dir     string
+	branch  string
+	patches []report.Patch
+}
+
+// readOnlyEnv keeps Claude from publishing anything itself: pushes to
+// origin go to an unusable URL and gh gets an invalid token.
+func readOnlyEnv() []string {
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=remote.origin.pushurl",
+		"GIT_CONFIG_VALUE_0=read-only://pushing-is-disabled",
+		"GH_TOKEN=read-only-pushing-is-disabled",
+	}
+}
+
+// openPatchDir creates the directory patches are recorded in and, when
+// resuming, picks up the patches recorded before.
+func (o *Orchestrator) openPatchDir() error {
+	dir := o.config.PatchDir
+	if dir == "" {
+		if o.runDir == "" {
+			return fmt.Errorf("--read-only needs --patch-dir when the run directory is unavailable")
+		}
+		dir = filepath.Join(o.runDir, "patches")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create patch directory: %w", err)
+	}
+	o.readOnly = &readOnlyWork{dir: dir}
+
+	if o.config.Resume == "" || o.runDir == "" {
+		return nil
+	}
+	recorded, err := events.Read(filepath.Join(o.runDir, events.FileName), events.PatchRecorded)
+	if err != nil {
+		return nil
+	}
+	for _, e := range recorded {
+		o.readOnly.branch = str(e.Data, "branch")
+		o.readOnly.patches = append(o.readOnly.patches, report.Patch{
+			Iteration:    e.Iteration,
+			File:         str(e.Data, "file"),
+			Title:        str(e.Data, "title"),
+			FilesChanged: count(e.Data, "files_changed"),
+			Insertions:   count(e.Data, "insertions"),
+			Deletions:    count(e.Data, "deletions"),
+			Transcript:   str(e.Data, "transcript"),
+		})
+	}
+	if o.readOnly.branch != "" {
+		if err := o.git.SwitchBranch(o.readOnly.branch); err != nil {
+			return fmt.Errorf("failed to continue from the last patch's branch: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordPatch writes the iteration's commit to the patch directory in
+// place of pushing it, and keeps its branch for the next iteration.
+func (o *Orchestrator) recordPatch(branch, title string, stat git.DiffStat, transcript string) error {
+	// Everything since the iteration branched, including a changelog commit
+	patch, err := o.git.FormatPatch(o.startBranch())
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("iteration-%03d.patch", o.iteration)
+	path := filepath.Join(o.readOnly.dir, name)
+	if err := os.WriteFile(path, []byte(patch), 0644); err != nil {
+		return fmt.Errorf("failed to write patch: %w", err)
+	}
+	if transcript != "" {
+		transcript = filepath.Join(o.runDir, replay.TranscriptsDir, transcript)
+	}
+
+	// The new branch contains the old one
+	if previous := o.readOnly.branch; previous != "" {
+		_ = o.git.DeleteBranch(previous)
+	}
+	o.readOnly.branch = branch
+	o.readOnly.patches = append(o.readOnly.patches, report.Patch{
+		Iteration:    o.iteration,
+		File:         name,
+		Title:        title,
+		FilesChanged: stat.FilesChanged,
+		Insertions:   stat.Insertions,
+		Deletions:    stat.Deletions,
+		Transcript:   transcript,
+	})
+
+	o.ui.Success("Read-only, recorded patch instead of pushing: %s", path)
+	o.events.Emit(events.PatchRecorded, o.iteration, map[string]any{
+		"file":          name,
+		"title":         title,
+		"branch":        branch,
+		"files_changed": stat.FilesChanged,
+		"insertions":    stat.Insertions,
+		"deletions":     stat.Deletions,
+		"transcript":    transcript,
+	})
+	return nil
+}
+
+// writeReadOnlyReport saves the run summary and the list of patches next
+// to them, and leaves the tree on the home branch.
+func (o *Orchestrator) writeReadOnlyReport(run *report.Run) {
+	_ = o.git.SwitchBranch(o.homeBranch)
+
+	path := filepath.Join(o.readOnly.dir, reportFile)
+	if err := os.WriteFile(path, []byte(o.redactor.String(run.Markdown())), 0644); err != nil {
+		o.ui.Warning("Could not write read-only report: %v", err)
+		return
+	}
+	o.ui.Info("Read-only report: %s", path)
+}
+
+// str returns a string field of event data, or "".
+func str(data map[string]any, key string) string {
+	s, _ := data[key].(string)
+	return s
+}
+
+// count returns a number field of event data, which JSON decodes as a
+// float, as an int.
+func count(data map[string]any, key string) int {
+	n, _ := data[key].(float64)
+	return int(n)
+}
@@ -0,0 +1,61 @@
+package orchestrator
+
+import (
+	"context"
+
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/redact"
+)
+
+// redactedGitHub scrubs secrets from the text a run publishes on GitHub:
+// PR titles and bodies, comments, gists, release notes and check runs.
+type redactedGitHub struct {
+	GhRunner
+	r *redact.Redactor
+}
+
+func (g redactedGitHub) CreatePR(ctx context.Context, title, body, base string) (string, error) {
+	return g.GhRunner.CreatePR(ctx, g.r.String(title), g.r.String(body), base)
+}
+
+func (g redactedGitHub) CreateDraftPR(ctx context.Context, title, body, base string) (string, error) {
+	return g.GhRunner.CreateDraftPR(ctx, g.r.String(title), g.r.String(body), base)
+}
+
+func (g redactedGitHub) EditPR(ctx context.Context, prNumber, title, body string) error {
+	return g.GhRunner.EditPR(ctx, prNumber, g.r.String(title), g.r.String(body))
+}
+
+func (g redactedGitHub) CommentOnIssue(ctx context.Context, number, body string) (string, error) {
+	return g.GhRunner.CommentOnIssue(ctx, number, g.r.String(body))
+}
+
+func (g redactedGitHub) CreateIssue(ctx context.Context, title, body string) (string, error) {
+	return g.GhRunner.CreateIssue(ctx, g.r.String(title), g.r.String(body))
+}
+
+func (g redactedGitHub) CreateGist(ctx context.Context, filename, description, content string) (string, error) {
+	return g.GhRunner.CreateGist(ctx, filename, g.r.String(description), g.r.String(content))
+}
+
+func (g redactedGitHub) CreateRelease(ctx context.Context, tag, title, notes, target string) (string, error) {
+	return g.GhRunner.CreateRelease(ctx, tag, g.r.String(title), g.r.String(notes), target)
+}
+
+func (g redactedGitHub) CreateCheckRun(ctx context.Context, run github.CheckRun) (string, error) {
+	run.Title = g.r.String(run.Title)
+	run.Summary = g.r.String(run.Summary)
+	annotations := make([]github.Annotation, len(run.Annotations))
+	for i, a := range run.Annotations {
+		a.Title = g.r.String(a.Title)
+		a.Message = g.r.String(a.Message)
+		annotations[i] = a
+	}
+	run.Annotations = annotations
+	return g.GhRunner.CreateCheckRun(ctx, run)
+}
+
+func (g redactedGitHub) SetCommitStatus(ctx context.Context, status github.CommitStatus) error {
+	status.Description = g.r.String(status.Description)
+	return g.GhRunner.SetCommitStatus(ctx, status)
+}
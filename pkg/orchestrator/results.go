@@ -0,0 +1,58 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// maxResultsLength caps how much of Claude's output a record of an
+// iteration's results quotes; the transcript has all of it.
+const maxResultsLength = 4000
+
+// recordResults records the results of an iteration that changed no
+// code, as research and analysis iterations don't, per --record-results:
+// as an empty commit on the iteration's branch, which is pushed and kept,
+// or as a comment on the summary issue. It reports whether they were
+// recorded, in which case the iteration counts as progress.
+func (o *Orchestrator) recordResults(ctx context.Context, branch, output, transcript string) bool {
+	findings := truncateOutput(strings.TrimSpace(o.redactor.String(output)), maxResultsLength)
+	data := map[string]any{"via": o.config.RecordResults, "transcript": transcript}
+
+	switch o.config.RecordResults {
+	case "commit":
+		title := fmt.Sprintf("Record results of iteration %d", o.iteration)
+		if err := o.git.CommitEmpty(title + "\n\n" + findings); err != nil {
+			o.ui.Warning("Could not record results: %v", err)
+			return false
+		}
+		o.recordCommit(title)
+		if err := o.git.PushWithRetry(ctx, branch, o.config.Retry.Push); err != nil {
+			o.ui.Warning("Could not push results: %v", err)
+			return false
+		}
+		o.recordPush(branch, false)
+		if o.config.BranchMode == "single" && o.single == nil {
+			// Later iterations continue on the branch the results are on
+			o.single = &singleBranch{branch: branch, pr: -1}
+		}
+		o.ui.Success("Recorded results on origin/%s", branch)
+		data["branch"] = branch
+	case "comment":
+		body := fmt.Sprintf("### Results of iteration %d\n\n%s", o.iteration, findings)
+		url, err := o.github.CommentOnIssue(ctx, strings.TrimPrefix(o.config.SummaryIssue, "#"), body)
+		if err != nil {
+			o.ui.Warning("Could not record results on %s: %v", o.config.SummaryIssue, err)
+			return false
+		}
+		o.ui.Success("Recorded results: %s", url)
+		data["url"] = url
+	default:
+		return false
+	}
+
+	o.events.Emit(events.ResultsRecorded, o.iteration, data)
+	return true
+}
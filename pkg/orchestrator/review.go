@@ -0,0 +1,101 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/report"
+	"github.com/guzus/deep-claude/internal/retry"
+	"github.com/guzus/deep-claude/pkg/config"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// reviewPollInterval is how often a PR awaiting review is polled.
+var reviewPollInterval = time.Minute
+
+// awaitReview waits --review-wait for a PR whose checks passed to be
+// reviewed. If nobody does, it re-requests review, pings the notifier and
+// waits once more. It returns the PR's status once reviewed, or nil with
+// the PR's outcome set if the wait ends without a review.
+func (o *Orchestrator) awaitReview(ctx context.Context, pr *report.PR) *github.PRStatus {
+	for round := 1; round <= 2; round++ {
+		o.ui.StartSpinner(fmt.Sprintf("Waiting up to %s for a review of PR #%s...", config.FormatDuration(o.config.ReviewWait), pr.Number))
+		status, err := o.pollReview(ctx, pr.Number)
+		o.ui.StopSpinner()
+		if errors.Is(err, context.Canceled) {
+			pr.Outcome = "open: interrupted while waiting for review"
+			return nil
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			o.ui.Warning("Iteration ran out of time waiting for a review of PR #%s; leaving it open", pr.Number)
+			pr.Outcome = "open: iteration timed out waiting for review"
+			return nil
+		}
+		if err != nil {
+			o.ui.Warning("Could not check the review of PR #%s: %v", pr.Number, err)
+			pr.Outcome = "open: not mergeable"
+			return nil
+		}
+		if status != nil {
+			return status
+		}
+		if round == 1 {
+			o.escalateReview(ctx, pr)
+		}
+	}
+
+	waited := config.FormatDuration(2 * o.config.ReviewWait)
+	o.ui.Warning("Nobody reviewed PR #%s within %s", pr.Number, waited)
+	pr.Outcome = "open: review timed out"
+	policy := "leaving it open and moving on"
+	if o.config.OnReviewTimeout == "stop" {
+		policy = "stopping the run"
+		o.reviewStalled = fmt.Sprintf("PR #%s was not reviewed within %s", pr.Number, waited)
+	}
+	o.events.Emit(events.ReviewTimedOut, pr.Iteration, map[string]any{"number": pr.Number, "waited": waited, "policy": policy})
+	o.notify(ctx, fmt.Sprintf("Deep Claude run `%s` waited %s for a review of PR %s without one; %s.", o.run.RunID, waited, pr.URL, policy))
+	return nil
+}
+
+// pollReview polls a PR until it is no longer awaiting review, returning
+// its status then, or nil once --review-wait passes.
+func (o *Orchestrator) pollReview(ctx context.Context, prNumber string) (*github.PRStatus, error) {
+	deadline := time.Now().Add(o.config.ReviewWait)
+	for {
+		wait := min(reviewPollInterval, time.Until(deadline))
+		if wait <= 0 {
+			return nil, nil
+		}
+		if err := retry.Sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		status, err := o.github.GetPRStatus(ctx, prNumber)
+		if err != nil {
+			return nil, err
+		}
+		if status.ReviewDecision != "REVIEW_REQUIRED" || status.HasFailedChecks {
+			return status, nil
+		}
+	}
+}
+
+// escalateReview re-requests review of a PR nobody has reviewed and tells
+// the notifier.
+func (o *Orchestrator) escalateReview(ctx context.Context, pr *report.PR) {
+	reviewers, err := o.github.ReRequestReview(ctx, pr.Number)
+	if err != nil {
+		o.ui.Warning("Could not re-request review of PR #%s: %v", pr.Number, err)
+	}
+	asked := "nobody (no reviewers are requested)"
+	if len(reviewers) > 0 {
+		asked = "@" + strings.Join(reviewers, ", @")
+	}
+	o.ui.Warning("PR #%s still needs review after %s; re-requested it from %s", pr.Number, config.FormatDuration(o.config.ReviewWait), asked)
+	o.events.Emit(events.ReviewEscalated, pr.Iteration, map[string]any{"number": pr.Number, "reviewers": asked})
+	o.notify(ctx, fmt.Sprintf("Deep Claude run `%s` has been waiting %s for a review of PR %s; re-requested review from %s.",
+		o.run.RunID, config.FormatDuration(o.config.ReviewWait), pr.URL, asked))
+}
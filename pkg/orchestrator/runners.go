@@ -0,0 +1,145 @@
+package orchestrator
+
+import (
+	"context"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/retry"
+)
+
+// The types the runners exchange, so that clients can be written outside
+// this module.
+type (
+	Snapshot     = git.Snapshot
+	DiffStat     = git.DiffStat
+	PullRequest  = github.PullRequest
+	PRStatus     = github.PRStatus
+	PRCheck      = github.PRCheck
+	GitHubLogger = github.Logger
+	ClaudeResult = claude.Result
+	TokenUsage   = claude.Usage
+	Pricing      = claude.Pricing
+	Evaluation   = claude.Evaluation
+	RetryPolicy  = retry.Policy
+)
+
+// GitRunner is the git client a run drives. *git.Client runs the git
+// binary; tests use the in-memory one from the fake package.
+type GitRunner interface {
+	IsRepo() bool
+	DetectGitHubRepo() (owner, repo string, err error)
+	DefaultBranch() (string, error)
+	GitDir() (string, error)
+	ListFiles() ([]string, error)
+
+	CurrentBranch() (string, error)
+	GenerateBranchName(prefix, runID string, iteration int) string
+	CreateBranch(name string) error
+	CreateBranchFrom(name, startPoint string) error
+	SwitchBranch(name string) error
+	DeleteBranch(name string) error
+
+	HasChanges(excludes []string) (bool, error)
+	GetStatus() (string, error)
+	Stash(message string, excludes []string) (bool, error)
+	StashPop(message string) error
+	TakeSnapshot() (git.Snapshot, error)
+	ChangedSince(before git.Snapshot) ([]string, error)
+	ChangedFilesSince(ref string) ([]string, error)
+	StagePaths(paths, excludes []string) error
+	StageAllExcept(excludes []string) error
+	HasStagedChanges() (bool, error)
+	GetStagedShortStat() (git.DiffStat, error)
+	GetStagedDiffStat() (string, error)
+
+	Commit(message string) error
+	UndoLastCommit() error
+	AmendCommit() error
+	CommitEmpty(message string) error
+	CommitTitlesSince(ref string) ([]string, error)
+	SquashSince(ref, message string) error
+	HeadSHA() (string, error)
+	GetLastCommitTitle() (string, error)
+	GetLastCommitMessage() (string, error)
+	BranchDiff(base string) (string, error)
+	PatchID(diff string) (string, error)
+	FormatPatch(base string) (string, error)
+
+	CherryPick(sha string) ([]string, error)
+	CherryPickContinue() error
+	CherryPickAbort() error
+	Revert(sha string) error
+	UnresolvedConflicts(paths []string) []string
+	Rebase(onto string) ([]string, error)
+
+	Push(ctx context.Context, branch string) error
+	PushWithRetry(ctx context.Context, branch string, policy retry.Policy) error
+	ForcePushWithLease(ctx context.Context, branch, expected string) error
+	PushTo(ctx context.Context, branch string) error
+	Pull(ctx context.Context, branch string) error
+	Fetch(ctx context.Context, branch string) error
+	RemoteBranches(ctx context.Context, prefix string) ([]string, error)
+	DeleteRemoteBranches(ctx context.Context, names ...string) error
+}
+
+// GhRunner is the GitHub client a run drives. *github.Client runs the gh
+// binary; tests use the in-memory one from the fake package.
+type GhRunner interface {
+	SetLogger(l github.Logger)
+	SetCheckScope(names []string)
+	SetRetryPolicy(p retry.Policy)
+	Owner() string
+	Repo() string
+	CheckAuth(ctx context.Context) error
+	LogRateLimit(ctx context.Context)
+
+	CreatePR(ctx context.Context, title, body, base string) (string, error)
+	CreateDraftPR(ctx context.Context, title, body, base string) (string, error)
+	EditPR(ctx context.Context, prNumber, title, body string) error
+	GetPR(ctx context.Context, prNumber string) (*github.PullRequest, error)
+	GetPRDiff(ctx context.Context, prNumber string) (string, error)
+	GetPRStatus(ctx context.Context, prNumber string) (*github.PRStatus, error)
+	GetCommitStatus(ctx context.Context, sha string) (*github.PRStatus, error)
+	GetDeploymentState(ctx context.Context, sha, environment string) (string, error)
+	CreateCheckRun(ctx context.Context, run github.CheckRun) (string, error)
+	SetCommitStatus(ctx context.Context, status github.CommitStatus) error
+	ListOpenPRs(ctx context.Context, limit int) ([]github.PullRequest, error)
+	ListPRs(ctx context.Context, limit int) ([]github.PullRequest, error)
+	WaitForChecks(ctx context.Context, prNumber string, timeout time.Duration, onStatusChange func(*github.PRStatus)) (*github.PRStatus, error)
+	MergePR(ctx context.Context, prNumber, strategy string) error
+	ClosePR(ctx context.Context, prNumber string, deleteBranch bool) error
+	CreateLabel(ctx context.Context, name string) error
+	AddLabels(ctx context.Context, prNumber string, labels []string) error
+
+	GetIssueState(ctx context.Context, number string) (string, error)
+	CommentOnIssue(ctx context.Context, number, body string) (string, error)
+	CreateIssue(ctx context.Context, title, body string) (string, error)
+	ListComments(ctx context.Context, number string) ([]github.Comment, error)
+	ReRequestReview(ctx context.Context, prNumber string) ([]string, error)
+	CreateGist(ctx context.Context, filename, description, content string) (string, error)
+	GetLatestRelease(ctx context.Context, owner, repo string) (string, error)
+	CreateRelease(ctx context.Context, tag, title, notes, target string) (string, error)
+}
+
+// ClaudeRunner is the Claude Code client a run drives. *claude.Client
+// runs the claude binary; tests use the scripted one from the fake package.
+type ClaudeRunner interface {
+	CheckAvailable() error
+	SetEnv(env []string)
+	SetPricing(p *claude.Pricing)
+	SetRetryPolicy(p retry.Policy)
+	Pricing() *claude.Pricing
+
+	Run(ctx context.Context, prompt string) (*claude.Result, error)
+	RunCommit(ctx context.Context, guidance string) (string, error)
+	Evaluate(ctx context.Context, goal string) (*claude.Evaluation, error)
+}
+
+var (
+	_ GitRunner    = (*git.Client)(nil)
+	_ GhRunner     = (*github.Client)(nil)
+	_ ClaudeRunner = (*claude.Client)(nil)
+)
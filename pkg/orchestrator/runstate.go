@@ -0,0 +1,110 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/claude"
+	"github.com/guzus/deep-claude/internal/report"
+	"github.com/guzus/deep-claude/internal/state"
+	"github.com/guzus/deep-claude/pkg/config"
+)
+
+// loadRunState starts the run's limit accounting, or picks up where the
+// run being resumed left off. The limits are always the configured ones,
+// so a resume can raise them, but one that is already used up fails early
+// instead of running an iteration past it.
+func loadRunState(cfg *config.Config) (*state.RunState, error) {
+	run := &state.RunState{RunID: cfg.RunID}
+	if cfg.Resume != "" {
+		var err error
+		if run, err = state.LoadRunState(cfg.Resume); err != nil {
+			return nil, err
+		}
+		run.Finished = false
+		run.Exited = false
+	}
+
+	run.Prompt = cfg.Prompt
+	run.MaxRuns = cfg.MaxRuns
+	run.MaxCost = cfg.MaxCost
+	run.MaxDuration = cfg.MaxDuration
+	run.Labels = cfg.Labels
+
+	if reason, reached := run.LimitReached(); reached {
+		return nil, fmt.Errorf("run %s already %s\nRaise the limit to resume it, e.g. --max-runs, --max-cost or --max-duration", run.RunID, reason)
+	}
+	return run, nil
+}
+
+// saveRunState persists the limit accounting so the run can be resumed.
+// Failure only means a resume starts from the last saved state.
+func (o *Orchestrator) saveRunState() {
+	o.run.Tick()
+	if err := o.run.Save(); err != nil {
+		o.ui.Warning("Could not save run state: %v", err)
+	}
+}
+
+// addCost counts a Claude request's cost toward the run's, also when the
+// request failed or was cut short: what it spent is spent.
+func (o *Orchestrator) addCost(result *claude.Result) {
+	if result == nil {
+		return
+	}
+	o.run.TotalCost += result.Cost
+	o.ui.Cost(result.Cost, o.run.TotalCost)
+}
+
+// recordIteration adds how the iteration went to the run's history, with
+// what it cost since the run had spent startCost.
+func (o *Orchestrator) recordIteration(ctx context.Context, started time.Time, startCost float64, timedOut bool, err error) {
+	it := report.Iteration{
+		Number:  o.iteration,
+		Outcome: report.IterationSucceeded,
+		Cost:    o.run.TotalCost - startCost,
+		Elapsed: time.Since(started),
+	}
+	switch {
+	case ctx.Err() != nil:
+		it.Outcome = report.IterationInterrupted
+	case timedOut:
+		it.Outcome = report.IterationTimedOut
+	case err != nil:
+		it.Outcome = report.IterationFailed
+	}
+	if err != nil {
+		line, _, _ := strings.Cut(err.Error(), "\n")
+		it.Error = o.redactor.String(line)
+	}
+	it.Phases = o.timer.take()
+	o.run.History = append(o.run.History, it)
+	o.timingPending = true
+}
+
+func iterationCosts(history []report.Iteration) []float64 {
+	costs := make([]float64, len(history))
+	for i, it := range history {
+		costs[i] = it.Cost
+	}
+	return costs
+}
+
+// iterationFailures describes the iterations that didn't succeed, for the
+// terminal summary.
+func iterationFailures(history []report.Iteration) []string {
+	var lines []string
+	for _, it := range history {
+		if it.Outcome == report.IterationSucceeded {
+			continue
+		}
+		line := fmt.Sprintf("Iteration %d %s after %s ($%.4f)", it.Number, it.Outcome, config.FormatDuration(it.Elapsed.Round(time.Second)), it.Cost)
+		if it.Error != "" {
+			line += ": " + it.Error
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
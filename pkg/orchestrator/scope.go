@@ -0,0 +1,29 @@
+package orchestrator
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// scopeDescription tells Claude which monorepo projects it may change.
+func scopeDescription(paths []string) string {
+	var sb strings.Builder
+	sb.WriteString("This is a monorepo. Work only in these projects:\n\n")
+	for _, p := range paths {
+		sb.WriteString("- " + p + "\n")
+	}
+	sb.WriteString("\nRead the rest of the repository for context if you need to, but don't change files outside these directories: those changes won't be committed. Build and test from the project directory.")
+	return sb.String()
+}
+
+// checkScope derives the names that pick a project's CI checks from its
+// path, e.g. "api" for packages/api.
+func checkScope(paths []string) []string {
+	var names []string
+	for _, p := range paths {
+		if name := filepath.Base(filepath.Clean(p)); name != "." && name != "/" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,23 @@
+package orchestrator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckScope(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  []string
+	}{
+		{[]string{"packages/api", "services/billing/"}, []string{"api", "billing"}},
+		{[]string{"."}, nil},
+		{nil, nil},
+	}
+
+	for _, tt := range tests {
+		if got := checkScope(tt.paths); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("checkScope(%v) = %v, want %v", tt.paths, got, tt.want)
+		}
+	}
+}
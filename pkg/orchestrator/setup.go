@@ -0,0 +1,46 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// setupMarker records, inside the git dir, the setup command that last
+// succeeded. Each worktree has its own git dir, so setup runs once per
+// worktree and again only when the command changes.
+const setupMarker = "setup-done"
+
+// runSetup runs --setup-cmd before the first iteration unless it already
+// succeeded in this worktree.
+func (o *Orchestrator) runSetup(ctx context.Context) error {
+	gitDir, err := o.git.GitDir()
+	if err != nil {
+		return err
+	}
+	marker := filepath.Join(gitDir, "deep-claude", setupMarker)
+	if done, err := os.ReadFile(marker); err == nil && strings.TrimSpace(string(done)) == o.config.SetupCmd {
+		o.ui.Info("Setup already done: %s", o.config.SetupCmd)
+		return nil
+	}
+
+	o.ui.StartSpinner(fmt.Sprintf("Running setup: %s", o.config.SetupCmd))
+	cmd := exec.CommandContext(ctx, "sh", "-c", o.config.SetupCmd)
+	cmd.Dir = o.workDir
+	cmd.Env = commandEnv(o.env)
+	output, err := cmd.CombinedOutput()
+	o.ui.StopSpinner()
+	o.recordCommand("setup", o.config.SetupCmd, err != nil)
+	if err != nil {
+		return fmt.Errorf("setup command failed: %w\n%s", err, truncateOutput(string(output), 2000))
+	}
+	o.ui.Success("Setup complete")
+
+	if err := os.MkdirAll(filepath.Dir(marker), 0755); err == nil {
+		_ = os.WriteFile(marker, []byte(o.config.SetupCmd+"\n"), 0644)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/guzus/deep-claude/internal/audit"
+	"github.com/guzus/deep-claude/internal/git"
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/report"
+	"github.com/guzus/deep-claude/pkg/events"
+)
+
+// singleBranch is the branch and PR that every iteration of
+// --branch-mode single adds its commit to.
+type singleBranch struct {
+	branch string
+	// pr is the index of the branch's PR in o.prs
+	pr       int
+	titles   []string
+	messages []string
+	diff     git.DiffStat
+}
+
+// iterationBranch switches to the branch the iteration commits to: the
+// run's single branch once it has commits, or a new one.
+func (o *Orchestrator) iterationBranch() (string, error) {
+	if o.analysis() {
+		// Nothing is committed, so the run stays where it started
+		return o.homeBranch, nil
+	}
+	if o.single != nil {
+		o.ui.Info("Continuing on branch: %s", o.single.branch)
+		if err := o.git.SwitchBranch(o.single.branch); err != nil {
+			return "", fmt.Errorf("failed to switch to branch: %w", err)
+		}
+		return o.single.branch, nil
+	}
+
+	name := o.git.GenerateBranchName(o.branchPrefix(), o.run.RunID, o.iteration)
+	if o.config.BranchMode == "single" {
+		name = fmt.Sprintf("%s%s/task", o.branchPrefix(), o.run.RunID)
+	}
+	o.ui.Info("Creating branch: %s", name)
+	if err := o.git.CreateBranch(name); err != nil {
+		return "", fmt.Errorf("failed to create branch: %w", err)
+	}
+	return name, nil
+}
+
+// shipSingle adds the iteration's pushed commit to the single PR: it opens
+// the PR after the first iteration and brings its title and description
+// up to date after later ones. The run stays on the branch; the PR's
+// checks are waited for once the run ends.
+func (o *Orchestrator) shipSingle(ctx context.Context, branch, title, message string, stat git.DiffStat) error {
+	if o.single == nil {
+		o.single = &singleBranch{branch: branch, pr: -1}
+	}
+	s := o.single
+	s.titles = append(s.titles, title)
+	s.messages = append(s.messages, message)
+	s.diff = s.diff.Add(stat)
+	title = stackedTitle(s.titles)
+	body := withRunID(formatPRBody(stackedBody(s.messages), o.iteration, s.diff), o.run.RunID)
+
+	if s.pr < 0 {
+		o.ui.StartSpinner("Creating PR...")
+		url, err := o.github.CreatePR(ctx, title, body, o.baseBranch)
+		o.ui.StopSpinner()
+		if err != nil {
+			return fmt.Errorf("failed to create PR: %w", err)
+		}
+		o.ui.Success("Created PR: %s", url)
+		o.events.Emit(events.PRCreated, o.iteration, map[string]any{"number": github.GetPRNumber(url), "url": url, "title": title})
+		o.audit.Record(audit.PRCreated, o.iteration, map[string]any{"number": github.GetPRNumber(url), "url": url, "title": title, "base": o.baseBranch})
+		o.prWindow.record(time.Now())
+		o.prs = append(o.prs, report.PR{Iteration: o.iteration, Number: github.GetPRNumber(url), URL: url, Title: title, Outcome: "open"})
+		s.pr = len(o.prs) - 1
+		o.labelPR(ctx, o.prs[s.pr].Number)
+	} else {
+		pr := &o.prs[s.pr]
+		o.ui.StartSpinner(fmt.Sprintf("Updating PR #%s...", pr.Number))
+		err := o.github.EditPR(ctx, pr.Number, title, body)
+		o.ui.StopSpinner()
+		if err != nil {
+			return fmt.Errorf("failed to update PR #%s: %w", pr.Number, err)
+		}
+		pr.Title = title
+		o.ui.Success("Updated PR #%s: %s", pr.Number, pr.URL)
+		o.events.Emit(events.PRUpdated, o.iteration, map[string]any{"number": pr.Number, "url": pr.URL, "title": title})
+		o.audit.Record(audit.PRUpdated, o.iteration, map[string]any{"number": pr.Number, "url": pr.URL, "title": title})
+	}
+
+	prNumber := o.prs[s.pr].Number
+	if o.config.CheckRun {
+		o.publishCheckRun(ctx, prNumber)
+	}
+	o.markHead(prNumber)
+	o.publishGatesStatus(ctx, prNumber)
+	return nil
+}
+
+// settleSingle waits for the checks of the single PR once the run is
+// done with it and merges it if they pass. Unlike a PR per iteration, it
+// is left open if they fail, since it holds all of the run's work.
+func (o *Orchestrator) settleSingle(ctx context.Context) {
+	index := o.single.pr
+	prNumber := o.prs[index].Number
+	if ctx.Err() != nil {
+		_ = o.git.SwitchBranch(o.homeBranch)
+		return
+	}
+	o.setStatus(ctx, prNumber, checksStatus, "pending", "Waiting for checks")
+
+	o.ui.StartSpinner("Waiting for PR checks...")
+	done := o.timer.start(report.PhaseChecks)
+	status, err := o.github.WaitForChecks(ctx, prNumber, 30*time.Minute, func(s *github.PRStatus) {
+		o.ui.StopSpinner()
+		o.ui.PRStatus(s.AllChecksPassed, s.HasPendingChecks, s.HasFailedChecks, s.ReviewDecision)
+		if s.HasPendingChecks {
+			o.ui.StartSpinner("Waiting for PR checks...")
+		}
+	})
+	done()
+	o.ui.StopSpinner()
+	if err := o.settlePR(ctx, index, o.baseBranch, status, err); err != nil {
+		o.ui.Warning("Could not merge PR #%s: %v", prNumber, err)
+	}
+}
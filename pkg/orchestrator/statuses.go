@@ -0,0 +1,99 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/guzus/deep-claude/internal/github"
+	"github.com/guzus/deep-claude/internal/report"
+)
+
+// Contexts of the commit statuses --commit-statuses sets on each PR.
+const (
+	gatesStatus  = github.StatusPrefix + "gates"
+	checksStatus = github.StatusPrefix + "checks"
+	mergeStatus  = github.StatusPrefix + "merge"
+)
+
+// markHead remembers the commit a PR was opened at, which its statuses
+// are set on even once the run has moved to another branch.
+func (o *Orchestrator) markHead(prNumber string) {
+	if !o.config.CommitStatuses {
+		return
+	}
+	sha, err := o.git.HeadSHA()
+	if err != nil {
+		o.ui.Warning("Could not set commit statuses on PR #%s: %v", prNumber, err)
+		return
+	}
+	if o.prHeads == nil {
+		o.prHeads = make(map[string]string)
+	}
+	o.prHeads[prNumber] = sha
+}
+
+// setStatus sets a commit status on the head of a PR, warning rather than
+// failing if GitHub won't take it.
+func (o *Orchestrator) setStatus(ctx context.Context, prNumber, context, state, description string) {
+	sha, ok := o.prHeads[prNumber]
+	if !o.config.CommitStatuses || !ok {
+		return
+	}
+	status := github.CommitStatus{SHA: sha, Context: context, State: state, Description: description}
+	if err := o.github.SetCommitStatus(ctx, status); err != nil {
+		o.ui.Warning("Could not set %s on PR #%s: %v", context, prNumber, err)
+	}
+}
+
+// publishGatesStatus sets the verdicts of the iteration's gates on its PR.
+func (o *Orchestrator) publishGatesStatus(ctx context.Context, prNumber string) {
+	if len(o.gates) == 0 {
+		return
+	}
+	state := "success"
+	passed := 0
+	var failed []string
+	for _, g := range o.gates {
+		if g.passed {
+			passed++
+		} else {
+			state = "failure"
+			failed = append(failed, g.name)
+		}
+	}
+	description := fmt.Sprintf("%d of %d gates passed", passed, len(o.gates))
+	if len(failed) > 0 {
+		description += "; failed: " + strings.Join(failed, ", ")
+	}
+	o.setStatus(ctx, prNumber, gatesStatus, state, description)
+}
+
+// publishChecksStatus sets the outcome of waiting for a PR's checks.
+func (o *Orchestrator) publishChecksStatus(ctx context.Context, prNumber string, status *github.PRStatus, waitErr error) {
+	switch {
+	case waitErr != nil:
+		o.setStatus(ctx, prNumber, checksStatus, "error", "Stopped waiting for checks")
+	case status == nil || status.HasFailedChecks:
+		o.setStatus(ctx, prNumber, checksStatus, "failure", "Checks failed")
+	default:
+		o.setStatus(ctx, prNumber, checksStatus, "success", "All checks passed")
+	}
+}
+
+// publishOutcome sets what became of a PR: merged, closed, or left open
+// and why.
+func (o *Orchestrator) publishOutcome(ctx context.Context, pr *report.PR) {
+	if pr.Outcome == "" {
+		return
+	}
+	state := "pending"
+	switch {
+	case pr.Outcome == "merged":
+		state = "success"
+	case strings.HasPrefix(pr.Outcome, "closed"), strings.HasSuffix(pr.Outcome, "failed"):
+		state = "failure"
+	}
+	description := strings.ToUpper(pr.Outcome[:1]) + pr.Outcome[1:]
+	o.setStatus(ctx, pr.Number, mergeStatus, state, description)
+}
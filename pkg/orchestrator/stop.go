@@ -0,0 +1,241 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/guzus/deep-claude/pkg/config"
+)
+
+// StopCondition is a task-specific goal checked before each iteration,
+// in addition to the cost, run and duration limits.
+type StopCondition interface {
+	// Name describes the condition in log output.
+	Name() string
+	// Met reports whether the condition currently holds.
+	Met(ctx context.Context) (bool, error)
+}
+
+// commandRunner is implemented by stop conditions that run a shell
+// command, which is audited.
+type commandRunner interface {
+	Command() string
+}
+
+// commandCondition is met when a shell command exits 0. With dirs set,
+// it runs in each of them (relative to workDir) and must succeed in all.
+type commandCondition struct {
+	command string
+	workDir string
+	dirs    []string
+	env     []string
+}
+
+func (c *commandCondition) Name() string {
+	if len(c.dirs) > 0 {
+		return fmt.Sprintf("`%s` succeeds in %s", c.command, strings.Join(c.dirs, ", "))
+	}
+	return fmt.Sprintf("`%s` succeeds", c.command)
+}
+
+func (c *commandCondition) Command() string { return c.command }
+
+func (c *commandCondition) Met(ctx context.Context) (bool, error) {
+	dirs := []string{c.workDir}
+	if len(c.dirs) > 0 {
+		dirs = nil
+		for _, d := range c.dirs {
+			dirs = append(dirs, filepath.Join(c.workDir, d))
+		}
+	}
+
+	for _, dir := range dirs {
+		cmd := exec.CommandContext(ctx, "sh", "-c", c.command)
+		cmd.Dir = dir
+		cmd.Env = commandEnv(c.env)
+		if err := cmd.Run(); err != nil {
+			if _, ok := err.(*exec.ExitError); ok {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to run %q: %w", c.command, err)
+		}
+	}
+	return true, nil
+}
+
+// percentRe matches a percentage such as "coverage: 81.3% of statements".
+var percentRe = regexp.MustCompile(`(\d+(?:\.\d+)?)%`)
+
+// coverageCondition is met when a command reports coverage of at least min percent.
+type coverageCondition struct {
+	command string
+	workDir string
+	env     []string
+	min     float64
+}
+
+func (c *coverageCondition) Name() string {
+	return fmt.Sprintf("coverage >= %g%%", c.min)
+}
+
+func (c *coverageCondition) Command() string { return c.command }
+
+func (c *coverageCondition) Met(ctx context.Context) (bool, error) {
+	output, err := runCommand(ctx, c.command, c.workDir, c.env)
+	if err != nil {
+		return false, fmt.Errorf("coverage command failed: %w\n%s", err, truncateOutput(output, 500))
+	}
+
+	coverage, ok := ParseCoverage(output)
+	if !ok {
+		return false, fmt.Errorf("no coverage percentage found in output of %q", c.command)
+	}
+	return coverage >= c.min, nil
+}
+
+// ParseCoverage returns the last percentage in a coverage tool's output,
+// which is the total for tools that print per-package lines first.
+func ParseCoverage(output string) (float64, bool) {
+	matches := percentRe.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(matches[len(matches)-1][1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// issueClosedCondition is met when a GitHub issue is closed.
+type issueClosedCondition struct {
+	github GhRunner
+	number string
+}
+
+func (c *issueClosedCondition) Name() string {
+	return fmt.Sprintf("issue #%s closed", c.number)
+}
+
+func (c *issueClosedCondition) Met(ctx context.Context) (bool, error) {
+	state, err := c.github.GetIssueState(ctx, c.number)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(state, "CLOSED"), nil
+}
+
+// migrationCondition is met when --migration-cmd lists nothing left to
+// migrate.
+type migrationCondition struct {
+	command string
+	workDir string
+	env     []string
+}
+
+func (c *migrationCondition) Name() string {
+	return fmt.Sprintf("`%s` lists nothing left to migrate", c.command)
+}
+
+func (c *migrationCondition) Command() string { return c.command }
+
+func (c *migrationCondition) Met(ctx context.Context) (bool, error) {
+	items, err := listMigration(ctx, c.command, c.workDir, c.env)
+	return err == nil && len(items) == 0, err
+}
+
+// streakCondition is met once its inner condition has held for the given
+// number of consecutive checks.
+type streakCondition struct {
+	inner    StopCondition
+	required int
+	count    int
+}
+
+func (c *streakCondition) Name() string {
+	if c.required <= 1 {
+		return c.inner.Name()
+	}
+	return fmt.Sprintf("%s for %d consecutive iterations", c.inner.Name(), c.required)
+}
+
+func (c *streakCondition) Command() string {
+	if r, ok := c.inner.(commandRunner); ok {
+		return r.Command()
+	}
+	return ""
+}
+
+func (c *streakCondition) Met(ctx context.Context) (bool, error) {
+	met, err := c.inner.Met(ctx)
+	if err != nil || !met {
+		c.count = 0
+		return false, err
+	}
+	c.count++
+	return c.count >= c.required, nil
+}
+
+// runCommand runs a shell command in dir, with env added to its
+// environment, and returns its combined output.
+func runCommand(ctx context.Context, command, dir string, env []string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Env = commandEnv(env)
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// commandEnv returns the environment for condition commands, adding env to
+// ours, or nil to inherit it unchanged.
+func commandEnv(env []string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+	return append(os.Environ(), env...)
+}
+
+// buildStopConditions creates the stop conditions enabled in the config.
+// Commands run in workDir with env added to their environment. verify is
+// the --verify-cmd condition, if set.
+func buildStopConditions(cfg *config.Config, gh GhRunner, verify StopCondition, workDir string, env []string) []StopCondition {
+	var conditions []StopCondition
+	if verify != nil {
+		conditions = append(conditions, &streakCondition{inner: verify, required: cfg.VerifyStreak})
+	}
+	if cfg.CoverageCmd != "" && cfg.MinCoverage > 0 {
+		conditions = append(conditions, &coverageCondition{command: cfg.CoverageCmd, workDir: workDir, env: env, min: cfg.MinCoverage})
+	}
+	if cfg.MigrationCmd != "" {
+		conditions = append(conditions, &migrationCondition{command: cfg.MigrationCmd, workDir: workDir, env: env})
+	}
+	if cfg.StopOnIssueClosed != "" {
+		conditions = append(conditions, &issueClosedCondition{github: gh, number: strings.TrimPrefix(cfg.StopOnIssueClosed, "#")})
+	}
+	return conditions
+}
+
+// checkTaskConditions evaluates the stop conditions, returning the name of
+// the first one that is met.
+func (o *Orchestrator) checkTaskConditions(ctx context.Context) (string, bool) {
+	for _, condition := range o.stopConditions {
+		met, err := condition.Met(ctx)
+		if c, ok := condition.(commandRunner); ok && c.Command() != "" {
+			o.recordCommand("stop condition", c.Command(), err != nil)
+		}
+		if err != nil {
+			o.ui.Warning("Could not check stop condition %s: %v", condition.Name(), err)
+			continue
+		}
+		if met {
+			return condition.Name(), true
+		}
+	}
+	return "", false
+}
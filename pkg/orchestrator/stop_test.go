@@ -0,0 +1,88 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseCoverage(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   float64
+		ok     bool
+	}{
+		{"go cover func", "pkg/a.go:10: Foo 100.0%\ntotal:\t(statements)\t81.3%\n", 81.3, true},
+		{"go test", "ok  \tpkg\t0.01s\tcoverage: 72% of statements\n", 72, true},
+		{"no percentage", "ok\n", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseCoverage(tt.output)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("ParseCoverage() = %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// fakeCondition returns a fixed sequence of results.
+type fakeCondition struct {
+	results []bool
+	calls   int
+}
+
+func (f *fakeCondition) Name() string { return "fake" }
+
+func (f *fakeCondition) Met(ctx context.Context) (bool, error) {
+	met := f.results[f.calls]
+	f.calls++
+	return met, nil
+}
+
+func TestStreakCondition(t *testing.T) {
+	inner := &fakeCondition{results: []bool{true, false, true, true, true}}
+	c := &streakCondition{inner: inner, required: 2}
+
+	want := []bool{false, false, false, true, true}
+	for i, w := range want {
+		got, err := c.Met(t.Context())
+		if err != nil {
+			t.Fatalf("Met() unexpected error: %v", err)
+		}
+		if got != w {
+			t.Errorf("check %d: Met() = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestCommandCondition(t *testing.T) {
+	dir := t.TempDir()
+
+	if met, err := (&commandCondition{command: "true", workDir: dir}).Met(t.Context()); err != nil || !met {
+		t.Errorf("Met() for passing command = %v, %v; want true", met, err)
+	}
+	if met, err := (&commandCondition{command: "exit 3", workDir: dir}).Met(t.Context()); err != nil || met {
+		t.Errorf("Met() for failing command = %v, %v; want false", met, err)
+	}
+}
+
+func TestMigrationCondition(t *testing.T) {
+	tests := []struct {
+		command string
+		met     bool
+		wantErr bool
+	}{
+		{command: "printf 'a.go\\nb.go\\n'", met: false},
+		{command: "true", met: true},
+		{command: "grep -rl oldlib .", met: true},
+		{command: "echo 'no such tool' >&2; exit 127", wantErr: true},
+	}
+	for _, tt := range tests {
+		met, err := (&migrationCondition{command: tt.command, workDir: t.TempDir()}).Met(t.Context())
+		if met != tt.met || (err != nil) != tt.wantErr {
+			t.Errorf("Met() for %q = %v, %v; want %v, error %v", tt.command, met, err, tt.met, tt.wantErr)
+		}
+	}
+}
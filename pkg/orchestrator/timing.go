@@ -0,0 +1,53 @@
+package orchestrator
+
+import (
+	"time"
+
+	"github.com/guzus/deep-claude/internal/report"
+)
+
+// phaseTimer adds up the time spent in each phase of an iteration, such
+// as running Claude or waiting for checks.
+type phaseTimer struct {
+	phases map[string]time.Duration
+}
+
+// start times a phase until the returned func is called.
+func (t *phaseTimer) start(phase string) (stop func()) {
+	began := time.Now()
+	return func() {
+		if t.phases == nil {
+			t.phases = make(map[string]time.Duration)
+		}
+		t.phases[phase] += time.Since(began)
+	}
+}
+
+// take returns the phases timed so far, and starts over.
+func (t *phaseTimer) take() map[string]time.Duration {
+	phases := t.phases
+	t.phases = nil
+	return phases
+}
+
+// endIteration adds the phases timed since the last iteration ended, such
+// as the stop conditions checking its work or PRs settled after it, to
+// that iteration, and prints where its time went if it hasn't yet.
+func (o *Orchestrator) endIteration() {
+	phases := o.timer.take()
+	if len(o.run.History) == 0 {
+		return
+	}
+	it := &o.run.History[len(o.run.History)-1]
+	for phase, d := range phases {
+		if it.Phases == nil {
+			it.Phases = make(map[string]time.Duration)
+		}
+		it.Phases[phase] += d
+		it.Elapsed += d
+	}
+	if o.timingPending {
+		o.timingPending = false
+		o.ui.Timing(it.Number, it.Elapsed, report.FormatPhases(report.Phases(it.Phases, it.Elapsed)))
+	}
+}
@@ -0,0 +1,33 @@
+package orchestrator
+
+import (
+	"context"
+
+	"github.com/guzus/deep-claude/internal/deps"
+)
+
+// defaultUpgradeTask describes an upgrade-mode run given no prompt.
+const defaultUpgradeTask = "Upgrade outdated dependencies"
+
+// loadUpgrades lists the outdated dependencies the upgrade policy allows
+// and queues them in batches. The user's prompt, if any, is added to each
+// upgrade as instructions.
+func (o *Orchestrator) loadUpgrades(ctx context.Context) error {
+	o.ui.StartSpinner("Checking for outdated dependencies...")
+	outdated, err := deps.Outdated(ctx, o.workDir)
+	o.ui.StopSpinner()
+	if err != nil {
+		return err
+	}
+
+	allowed := deps.Filter(outdated, o.config.UpgradePolicy, o.config.UpgradeIgnore)
+	o.ui.Info("Outdated dependencies: %d, within %s policy: %d", len(outdated), o.config.UpgradePolicy, len(allowed))
+	for _, d := range allowed {
+		o.ui.Info("  %s", d)
+	}
+
+	for _, batch := range deps.Batches(allowed, o.config.UpgradeBatch) {
+		o.queue = append(o.queue, workItem{title: deps.Title(batch), goal: deps.Goal(batch, o.config.Prompt)})
+	}
+	return nil
+}